@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/services"
+)
+
+// runBacktestCommand implements `main backtest (-scorecard N | -file F) [-from
+// YYYY-MM-DD] [-to YYYY-MM-DD] [-json]`. It replays repaid and defaulted
+// invoices through a stored scorecard version or a candidate read from a JSON
+// file in the create-scorecard format, and exits with 0 when the report was
+// printed and 2 when the run failed.
+func runBacktestCommand(analyticsService *services.AnalyticsService, gradingService *services.GradingService, args []string) int {
+	fs := flag.NewFlagSet("backtest", flag.ContinueOnError)
+	version := fs.Int("scorecard", 0, "stored scorecard version to replay")
+	file := fs.String("file", "", "JSON file with a candidate scorecard that is not stored")
+	from := fs.String("from", "", "only invoices due on or after this date (YYYY-MM-DD)")
+	to := fs.String("to", "", "only invoices due on or before this date (YYYY-MM-DD)")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if (*version == 0) == (*file == "") {
+		fmt.Fprintln(os.Stderr, "backtest: give exactly one of -scorecard or -file")
+		return 2
+	}
+
+	var filter models.OutcomeFilter
+	for _, d := range []struct {
+		value string
+		into  **time.Time
+	}{{*from, &filter.DueFrom}, {*to, &filter.DueTo}} {
+		if d.value == "" {
+			continue
+		}
+		t, err := time.Parse("2006-01-02", d.value)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "backtest: invalid date %q\n", d.value)
+			return 2
+		}
+		*d.into = &t
+	}
+
+	var scorecard *models.Scorecard
+	var err error
+	if *file != "" {
+		scorecard, err = readCandidateScorecard(*file)
+	} else {
+		scorecard, err = gradingService.FindScorecardVersion(*version)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "backtest: %v\n", err)
+		return 2
+	}
+
+	report, err := analyticsService.Backtest(scorecard, &filter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "backtest: %v\n", err)
+		return 2
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "backtest: %v\n", err)
+			return 2
+		}
+		return 0
+	}
+
+	name := report.ScorecardName
+	if report.ScorecardVersion > 0 {
+		name = fmt.Sprintf("v%d %s", report.ScorecardVersion, name)
+	}
+	fmt.Printf("Scorecard %s: replayed %d invoices, %d regraded\n\n", name, report.Invoices, report.Regraded)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "GRADING\tGRADE\tINVOICES\tDEFAULTS\tDEFAULT RATE\tLGD\tAVG DAYS LATE\tREALIZED YIELD")
+	for _, side := range []struct {
+		label   string
+		stats   []models.OutcomeStats
+		ordered bool
+	}{{"current", report.Current, report.CurrentOrdered}, {"candidate", report.Candidate, report.CandidateOrdered}} {
+		for _, s := range side.stats {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%.2f%%\t%.2f%%\t%.1f\t%.2f%%\n",
+				side.label, s.Group, s.Invoices, s.Defaults, s.DefaultRate, s.LossGivenDefault, s.AvgDaysLate, s.RealizedYield)
+		}
+		if !side.ordered {
+			fmt.Fprintf(w, "%s\t(default rates do not rise from A to C)\t\t\t\t\t\t\n", side.label)
+		}
+	}
+	w.Flush()
+
+	if len(report.Migrations) > 0 {
+		fmt.Println()
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "FROM\tTO\tINVOICES\tDEFAULTS")
+		for _, m := range report.Migrations {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\n", m.From, m.To, m.Invoices, m.Defaults)
+		}
+		w.Flush()
+	}
+	return 0
+}
+
+// readCandidateScorecard reads and validates a scorecard in the format of
+// POST /admin/grading/scorecards
+func readCandidateScorecard(path string) (*models.Scorecard, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var req models.CreateScorecardRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if req.Name == "" {
+		req.Name = path
+	}
+	return services.CandidateScorecard(&req)
+}
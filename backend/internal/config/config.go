@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/joho/godotenv"
+	"github.com/vessel/backend/internal/money"
 )
 
 type Config struct {
@@ -77,6 +78,73 @@ type Config struct {
 
 	// Currency Conversion Settings
 	DefaultBufferRate float64 // Default 1.5% buffer for currency conversion
+
+	// Idempotency-Key retention for money-moving endpoints
+	IdempotencyKeyTTLHours int
+
+	// Funding pool deadline expiry
+	PoolExpiryPolicy            string  // "refund" (refund all investors) or "partial" (disburse if above minimum)
+	PoolMinFundingPercentage    float64 // Minimum funded percentage of target for the partial policy
+	PoolExpiryCheckIntervalMins int
+
+	// Payment gateway
+	PaymentGatewayProvider     string // Only "simulator" is built in
+	PaymentWebhookSecret       string // HMAC-SHA256 key for provider webhooks
+	PaymentSimulatorAutoSettle bool   // Simulator confirms deposits and importer payments immediately
+
+	// On-chain outbox worker
+	OnchainOutboxPollSeconds     int
+	OnchainOutboxMaxAttempts     int // Attempts before an entry is parked as failed
+	OnchainOutboxBaseBackoffSecs int // First retry delay, doubled on every further attempt
+
+	// On-chain transaction sending
+	OnchainConfirmations     int // Blocks on top of a receipt before it counts as final
+	OnchainGasLimitBufferPct int // Added to the gas estimate
+	OnchainTxStuckMinutes    int // Unmined transactions are replaced after this long
+	OnchainGasPriceBumpPct   int // Gas price increase per replacement (nodes require at least 10)
+	OnchainMaxGasPriceGwei   int64
+
+	// Contract event indexer
+	OnchainIndexerStartBlock  int64 // First block to backfill, usually the contract deployment block
+	OnchainIndexerBatchBlocks int64 // Blocks per eth_getLogs request
+	OnchainIndexerPollSeconds int
+
+	// On-chain reconciliation job
+	OnchainReconcileIntervalMins int  // 0 disables the job; the CLI subcommand still works
+	OnchainReconcileAutoEnqueue  bool // Queue corrective writes instead of only reporting
+
+	// On-chain amount units
+	OnchainTokenDecimals           int            // Decimals of the token amounts are recorded in (IDRX)
+	OnchainTokenDecimalsByCurrency map[string]int // Overrides per pool currency, e.g. USD=6
+
+	// Wallet ownership proof
+	WalletChallengeTTLMinutes int
+
+	// Signed investment consent (EIP-712)
+	InvestConsentSignatureRequired bool   // Reject investments without a signed consent payload
+	InvestConsentTTLMinutes        int    // How long a consent payload can be signed and submitted
+	TncVersion                     string // Terms & Conditions version investors agree to
+
+	// Default workflow
+	DefaultGracePeriodDays   int // Days after the due date before an unpaid pool is flagged overdue
+	DefaultCheckIntervalMins int
+
+	// Interest accrual
+	InterestDayCount money.DayCount // Convention used to accrue the p.a tranche rates
+
+	// Late payment charges
+	LateChargeRules                map[string]LateChargeRule // Per invoice grade (A, B, C)
+	LateChargePlatformSharePercent float64                   // Share of late charges kept by the platform; investors get the rest
+	LateChargeAccrualIntervalMins  int
+
+	// Country risk tiers
+	CountryTierCacheTTLMinutes int // Tiers are reloaded from the database after this long
+}
+
+// LateChargeRule is what an invoice grade is charged once repayment is past the due date
+type LateChargeRule struct {
+	LateFeePercent float64 `json:"late_fee_percent"` // One-off fee on the overdue balance
+	PenaltyRate    float64 `json:"penalty_rate"`     // Annual rate accrued daily on the overdue balance
 }
 
 func Load() (*Config, error) {
@@ -123,6 +191,53 @@ func Load() (*Config, error) {
 	otpExpiry, _ := strconv.Atoi(getEnv("OTP_EXPIRY_MINUTES", "5"))
 	otpMaxAttempts, _ := strconv.Atoi(getEnv("OTP_MAX_ATTEMPTS", "5"))
 	bufferRate, _ := strconv.ParseFloat(getEnv("DEFAULT_BUFFER_RATE", "0.015"), 64)
+	idempotencyTTL, _ := strconv.Atoi(getEnv("IDEMPOTENCY_KEY_TTL_HOURS", "24"))
+	poolMinFunding, _ := strconv.ParseFloat(getEnv("POOL_MIN_FUNDING_PERCENTAGE", "80.0"), 64)
+	poolExpiryInterval, _ := strconv.Atoi(getEnv("POOL_EXPIRY_CHECK_INTERVAL_MINUTES", "5"))
+	simulatorAutoSettle, _ := strconv.ParseBool(getEnv("PAYMENT_SIMULATOR_AUTO_SETTLE", "true"))
+	outboxPoll, _ := strconv.Atoi(getEnv("ONCHAIN_OUTBOX_POLL_SECONDS", "5"))
+	outboxMaxAttempts, _ := strconv.Atoi(getEnv("ONCHAIN_OUTBOX_MAX_ATTEMPTS", "10"))
+	outboxBackoff, _ := strconv.Atoi(getEnv("ONCHAIN_OUTBOX_BASE_BACKOFF_SECONDS", "30"))
+	confirmations, _ := strconv.Atoi(getEnv("ONCHAIN_CONFIRMATIONS", "3"))
+	gasLimitBuffer, _ := strconv.Atoi(getEnv("ONCHAIN_GAS_LIMIT_BUFFER_PERCENT", "20"))
+	txStuckMinutes, _ := strconv.Atoi(getEnv("ONCHAIN_TX_STUCK_MINUTES", "10"))
+	gasPriceBump, _ := strconv.Atoi(getEnv("ONCHAIN_GAS_PRICE_BUMP_PERCENT", "20"))
+	maxGasPrice, _ := strconv.ParseInt(getEnv("ONCHAIN_MAX_GAS_PRICE_GWEI", "500"), 10, 64)
+	indexerStart, _ := strconv.ParseInt(getEnv("ONCHAIN_INDEXER_START_BLOCK", "0"), 10, 64)
+	indexerBatch, _ := strconv.ParseInt(getEnv("ONCHAIN_INDEXER_BATCH_BLOCKS", "2000"), 10, 64)
+	indexerPoll, _ := strconv.Atoi(getEnv("ONCHAIN_INDEXER_POLL_SECONDS", "15"))
+	reconcileInterval, _ := strconv.Atoi(getEnv("ONCHAIN_RECONCILE_INTERVAL_MINUTES", "60"))
+	reconcileEnqueue, _ := strconv.ParseBool(getEnv("ONCHAIN_RECONCILE_AUTO_ENQUEUE", "false"))
+	walletChallengeTTL, _ := strconv.Atoi(getEnv("WALLET_CHALLENGE_TTL_MINUTES", "10"))
+	consentRequired, _ := strconv.ParseBool(getEnv("INVEST_CONSENT_SIGNATURE_REQUIRED", "false"))
+	consentTTL, _ := strconv.Atoi(getEnv("INVEST_CONSENT_TTL_MINUTES", "15"))
+	defaultGraceDays, _ := strconv.Atoi(getEnv("DEFAULT_GRACE_PERIOD_DAYS", "14"))
+	defaultCheckInterval, _ := strconv.Atoi(getEnv("DEFAULT_CHECK_INTERVAL_MINUTES", "60"))
+	tokenDecimals, err := strconv.Atoi(getEnv("ONCHAIN_TOKEN_DECIMALS", "2"))
+	if err != nil || tokenDecimals < 0 {
+		return nil, fmt.Errorf("invalid ONCHAIN_TOKEN_DECIMALS: %q", getEnv("ONCHAIN_TOKEN_DECIMALS", "2"))
+	}
+	tokenDecimalsByCurrency, err := parseDecimalsByCurrency(getEnv("ONCHAIN_TOKEN_DECIMALS_BY_CURRENCY", ""))
+	if err != nil {
+		return nil, err
+	}
+	dayCount, err := money.ParseDayCount(getEnv("INTEREST_DAY_COUNT", "actual/365"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid INTEREST_DAY_COUNT: %w", err)
+	}
+	lateChargeRules, err := parseLateChargeRules(
+		getEnv("LATE_FEE_PERCENT_BY_GRADE", "A=1,B=2,C=3"),
+		getEnv("PENALTY_RATE_BY_GRADE", "A=12,B=18,C=24"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	lateChargePlatformShare, err := strconv.ParseFloat(getEnv("LATE_CHARGE_PLATFORM_SHARE_PERCENT", "20"), 64)
+	if err != nil || lateChargePlatformShare < 0 || lateChargePlatformShare > 100 {
+		return nil, fmt.Errorf("invalid LATE_CHARGE_PLATFORM_SHARE_PERCENT: %q", getEnv("LATE_CHARGE_PLATFORM_SHARE_PERCENT", "20"))
+	}
+	lateChargeInterval, _ := strconv.Atoi(getEnv("LATE_CHARGE_ACCRUAL_INTERVAL_MINUTES", "60"))
+	countryTierCacheTTL, _ := strconv.Atoi(getEnv("COUNTRY_TIER_CACHE_TTL_MINUTES", "5"))
 
 	return &Config{
 		Port:    getEnv("PORT", "8080"),
@@ -180,9 +295,134 @@ func Load() (*Config, error) {
 
 		// Currency Settings
 		DefaultBufferRate: bufferRate,
+
+		IdempotencyKeyTTLHours: idempotencyTTL,
+
+		// Pool Expiry Settings
+		PoolExpiryPolicy:            getEnv("POOL_EXPIRY_POLICY", "refund"),
+		PoolMinFundingPercentage:    poolMinFunding,
+		PoolExpiryCheckIntervalMins: poolExpiryInterval,
+
+		// Payment Gateway Settings
+		PaymentGatewayProvider:     getEnv("PAYMENT_GATEWAY_PROVIDER", "simulator"),
+		PaymentWebhookSecret:       getEnv("PAYMENT_WEBHOOK_SECRET", ""),
+		PaymentSimulatorAutoSettle: simulatorAutoSettle,
+
+		// On-chain Outbox Settings
+		OnchainOutboxPollSeconds:     outboxPoll,
+		OnchainOutboxMaxAttempts:     outboxMaxAttempts,
+		OnchainOutboxBaseBackoffSecs: outboxBackoff,
+
+		// On-chain Transaction Settings
+		OnchainConfirmations:     confirmations,
+		OnchainGasLimitBufferPct: gasLimitBuffer,
+		OnchainTxStuckMinutes:    txStuckMinutes,
+		OnchainGasPriceBumpPct:   gasPriceBump,
+		OnchainMaxGasPriceGwei:   maxGasPrice,
+
+		// Contract Indexer Settings
+		OnchainIndexerStartBlock:  indexerStart,
+		OnchainIndexerBatchBlocks: indexerBatch,
+		OnchainIndexerPollSeconds: indexerPoll,
+
+		// On-chain Reconciliation Settings
+		OnchainReconcileIntervalMins: reconcileInterval,
+		OnchainReconcileAutoEnqueue:  reconcileEnqueue,
+
+		// On-chain Unit Settings
+		OnchainTokenDecimals:           tokenDecimals,
+		OnchainTokenDecimalsByCurrency: tokenDecimalsByCurrency,
+
+		// Wallet Settings
+		WalletChallengeTTLMinutes: walletChallengeTTL,
+
+		// Investment Consent Settings
+		InvestConsentSignatureRequired: consentRequired,
+		InvestConsentTTLMinutes:        consentTTL,
+		TncVersion:                     getEnv("TNC_VERSION", "1.0"),
+
+		// Default Workflow Settings
+		DefaultGracePeriodDays:   defaultGraceDays,
+		DefaultCheckIntervalMins: defaultCheckInterval,
+
+		// Interest Settings
+		InterestDayCount: dayCount,
+
+		// Late Charge Settings
+		LateChargeRules:                lateChargeRules,
+		LateChargePlatformSharePercent: lateChargePlatformShare,
+		LateChargeAccrualIntervalMins:  lateChargeInterval,
+
+		// Country Tier Settings
+		CountryTierCacheTTLMinutes: countryTierCacheTTL,
 	}, nil
 }
 
+// parseLateChargeRules combines the per-grade lists such as "A=1,B=2,C=3" of late
+// fees and penalty rates. A grade missing from either list is charged nothing for it.
+func parseLateChargeRules(lateFees, penaltyRates string) (map[string]LateChargeRule, error) {
+	fees, err := parsePercentByGrade("LATE_FEE_PERCENT_BY_GRADE", lateFees)
+	if err != nil {
+		return nil, err
+	}
+	rates, err := parsePercentByGrade("PENALTY_RATE_BY_GRADE", penaltyRates)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make(map[string]LateChargeRule)
+	for grade, fee := range fees {
+		rules[grade] = LateChargeRule{LateFeePercent: fee, PenaltyRate: rates[grade]}
+	}
+	for grade, rate := range rates {
+		if _, ok := fees[grade]; !ok {
+			rules[grade] = LateChargeRule{PenaltyRate: rate}
+		}
+	}
+	return rules, nil
+}
+
+func parsePercentByGrade(key, value string) (map[string]float64, error) {
+	result := make(map[string]float64)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid %s entry: %q", key, entry)
+		}
+		percent, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil || percent < 0 {
+			return nil, fmt.Errorf("invalid %s entry: %q", key, entry)
+		}
+		result[strings.ToUpper(strings.TrimSpace(parts[0]))] = percent
+	}
+	return result, nil
+}
+
+// parseDecimalsByCurrency reads a list such as "USD=6,EUR=6"
+func parseDecimalsByCurrency(value string) (map[string]int, error) {
+	result := make(map[string]int)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid ONCHAIN_TOKEN_DECIMALS_BY_CURRENCY entry: %q", entry)
+		}
+		decimals, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || decimals < 0 {
+			return nil, fmt.Errorf("invalid ONCHAIN_TOKEN_DECIMALS_BY_CURRENCY entry: %q", entry)
+		}
+		result[strings.ToUpper(strings.TrimSpace(parts[0]))] = decimals
+	}
+	return result, nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -518,6 +518,593 @@ func RunMigrations(db *sql.DB) error {
 		`ALTER TABLE mitra_applications ADD COLUMN IF NOT EXISTS year_founded INTEGER;`,
 		`ALTER TABLE mitra_applications ADD COLUMN IF NOT EXISTS key_products TEXT;`,
 		`ALTER TABLE mitra_applications ADD COLUMN IF NOT EXISTS export_markets TEXT;`,
+
+		// Transaction types used by the payment and repayment flows
+		`ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_type_check;`,
+		`ALTER TABLE transactions ADD CONSTRAINT transactions_type_check CHECK (type IN (
+			'investment', 'advance_payment', 'buyer_repayment', 'investor_return',
+			'platform_fee', 'refund', 'deposit', 'withdrawal', 'repayment_excess'
+		));`,
+
+		// Double-entry ledger: balances are derived from journal lines (debit +, credit -)
+		`CREATE TABLE IF NOT EXISTS ledger_accounts (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			code VARCHAR(100) UNIQUE NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			account_type VARCHAR(20) NOT NULL CHECK (account_type IN ('asset', 'liability', 'equity', 'revenue')),
+			owner_type VARCHAR(20),
+			owner_id UUID,
+			currency VARCHAR(10) NOT NULL DEFAULT 'IDR',
+			allow_negative BOOLEAN NOT NULL DEFAULT false,
+			created_at TIMESTAMP DEFAULT NOW()
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_ledger_accounts_owner ON ledger_accounts(owner_type, owner_id);`,
+		`CREATE TABLE IF NOT EXISTS journal_entries (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			reference_type VARCHAR(50) NOT NULL,
+			reference_id UUID,
+			description TEXT,
+			created_at TIMESTAMP DEFAULT NOW()
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_journal_entries_reference ON journal_entries(reference_type, reference_id);`,
+		`CREATE TABLE IF NOT EXISTS journal_lines (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			entry_id UUID NOT NULL REFERENCES journal_entries(id) ON DELETE RESTRICT,
+			account_id UUID NOT NULL REFERENCES ledger_accounts(id) ON DELETE RESTRICT,
+			amount DECIMAL(20,2) NOT NULL CHECK (amount <> 0),
+			created_at TIMESTAMP DEFAULT NOW()
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_journal_lines_entry ON journal_lines(entry_id);`,
+		`CREATE INDEX IF NOT EXISTS idx_journal_lines_account ON journal_lines(account_id);`,
+
+		// Seed platform accounts and move existing users.balance_idr into the ledger as opening balances.
+		// Wallet money is cash held in escrow, so opening balances are booked against escrow like deposits.
+		`INSERT INTO ledger_accounts (code, name, account_type, currency, allow_negative) VALUES
+			('escrow', 'Escrow cash', 'asset', 'IDR', true),
+			('platform_revenue', 'Platform revenue', 'revenue', 'IDR', true),
+			('platform_equity', 'Platform equity', 'equity', 'IDR', true)
+		ON CONFLICT (code) DO NOTHING;`,
+		`INSERT INTO ledger_accounts (code, name, account_type, owner_type, owner_id, currency, allow_negative)
+		SELECT 'user:' || id, 'User wallet', 'liability', 'user', id, 'IDR', false
+		FROM users WHERE COALESCE(balance_idr, 0) <> 0
+		ON CONFLICT (code) DO NOTHING;`,
+		`WITH opening AS (
+			INSERT INTO journal_entries (reference_type, description)
+			SELECT 'opening_balance', 'Opening balances migrated from users.balance_idr'
+			WHERE NOT EXISTS (SELECT 1 FROM journal_entries WHERE reference_type = 'opening_balance')
+			  AND EXISTS (SELECT 1 FROM users WHERE COALESCE(balance_idr, 0) <> 0)
+			RETURNING id
+		)
+		INSERT INTO journal_lines (entry_id, account_id, amount)
+		SELECT opening.id, a.id, -u.balance_idr
+		FROM opening, users u
+		JOIN ledger_accounts a ON a.code = 'user:' || u.id
+		WHERE COALESCE(u.balance_idr, 0) <> 0
+		UNION ALL
+		SELECT opening.id, es.id, SUM(u.balance_idr)
+		FROM opening, users u, ledger_accounts es
+		WHERE es.code = 'escrow' AND COALESCE(u.balance_idr, 0) <> 0
+		GROUP BY opening.id, es.id;`,
+		// Pools still holding investor funds carry their funded amount into the ledger
+		`INSERT INTO ledger_accounts (code, name, account_type, owner_type, owner_id, currency, allow_negative)
+		SELECT 'pool:' || id, 'Funding pool', 'liability', 'pool', id, 'IDR', false
+		FROM funding_pools WHERE status IN ('open', 'filled') AND funded_amount > 0
+		ON CONFLICT (code) DO NOTHING;`,
+		`WITH opening AS (
+			INSERT INTO journal_entries (reference_type, description)
+			SELECT 'opening_pool_balance', 'Opening pool balances migrated from funding_pools.funded_amount'
+			WHERE NOT EXISTS (SELECT 1 FROM journal_entries WHERE reference_type = 'opening_pool_balance')
+			  AND EXISTS (SELECT 1 FROM funding_pools WHERE status IN ('open', 'filled') AND funded_amount > 0)
+			RETURNING id
+		)
+		INSERT INTO journal_lines (entry_id, account_id, amount)
+		SELECT opening.id, a.id, -p.funded_amount
+		FROM opening, funding_pools p
+		JOIN ledger_accounts a ON a.code = 'pool:' || p.id
+		WHERE p.status IN ('open', 'filled') AND p.funded_amount > 0
+		UNION ALL
+		SELECT opening.id, es.id, SUM(p.funded_amount)
+		FROM opening, funding_pools p, ledger_accounts es
+		WHERE es.code = 'escrow' AND p.status IN ('open', 'filled') AND p.funded_amount > 0
+		GROUP BY opening.id, es.id;`,
+		// Idempotency keys for money-moving endpoints
+		`CREATE TABLE IF NOT EXISTS idempotency_keys (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			scope VARCHAR(64) NOT NULL,
+			idempotency_key VARCHAR(255) NOT NULL,
+			method VARCHAR(10) NOT NULL,
+			path TEXT NOT NULL,
+			request_hash VARCHAR(64) NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'processing' CHECK (status IN ('processing', 'completed')),
+			response_status INT,
+			response_content_type VARCHAR(255),
+			response_body BYTEA,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			completed_at TIMESTAMP,
+			expires_at TIMESTAMP NOT NULL,
+			UNIQUE (scope, idempotency_key)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires ON idempotency_keys(expires_at);`,
+
+		// Pool deadline expiry: expired pools refund their investors
+		`ALTER TABLE funding_pools DROP CONSTRAINT IF EXISTS funding_pools_status_check;`,
+		`ALTER TABLE funding_pools ADD CONSTRAINT funding_pools_status_check CHECK (status IN (
+			'open', 'filled', 'disbursed', 'closed', 'expired'
+		));`,
+		`ALTER TABLE investments DROP CONSTRAINT IF EXISTS investments_status_check;`,
+		`ALTER TABLE investments ADD CONSTRAINT investments_status_check CHECK (status IN (
+			'active', 'repaid', 'defaulted', 'refunded'
+		));`,
+		`ALTER TABLE invoices DROP CONSTRAINT IF EXISTS invoices_status_check;`,
+		`ALTER TABLE invoices ADD CONSTRAINT invoices_status_check CHECK (status IN (
+			'draft', 'pending_review', 'approved', 'rejected',
+			'tokenized', 'funding', 'funded', 'matured', 'repaid', 'defaulted', 'expired'
+		));`,
+		`CREATE INDEX IF NOT EXISTS idx_funding_pools_open_deadline ON funding_pools(deadline) WHERE status = 'open';`,
+
+		// Virtual accounts: at most one pending VA per pool, VA numbers unique while pending
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_virtual_accounts_active_pool ON virtual_accounts(pool_id) WHERE status = 'pending';`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_virtual_accounts_active_number ON virtual_accounts(bank_code, va_number) WHERE status = 'pending';`,
+		`CREATE INDEX IF NOT EXISTS idx_virtual_accounts_pending_expiry ON virtual_accounts(expires_at) WHERE status = 'pending';`,
+
+		// Payment gateway charges and deduplicated webhook callbacks
+		`CREATE TABLE IF NOT EXISTS gateway_payments (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			provider VARCHAR(50) NOT NULL,
+			external_id VARCHAR(255) NOT NULL,
+			purpose VARCHAR(30) NOT NULL CHECK (purpose IN ('deposit', 'mitra_repayment', 'importer_payment')),
+			reference_id UUID NOT NULL,
+			user_id UUID REFERENCES users(id),
+			method VARCHAR(30) NOT NULL CHECK (method IN ('virtual_account', 'payment_link')),
+			amount DECIMAL(20,2) NOT NULL,
+			currency VARCHAR(10) NOT NULL DEFAULT 'IDR',
+			status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'paid', 'expired', 'failed', 'refunded')),
+			va_number VARCHAR(50),
+			payment_url TEXT,
+			expires_at TIMESTAMP,
+			paid_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT NOW(),
+			updated_at TIMESTAMP DEFAULT NOW(),
+			UNIQUE (provider, external_id)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_gateway_payments_reference ON gateway_payments(purpose, reference_id);`,
+		`CREATE TABLE IF NOT EXISTS payment_webhook_events (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			provider VARCHAR(50) NOT NULL,
+			event_id VARCHAR(255) NOT NULL,
+			payload JSONB NOT NULL,
+			received_at TIMESTAMP DEFAULT NOW(),
+			processed_at TIMESTAMP,
+			UNIQUE (provider, event_id)
+		);`,
+
+		// On-chain outbox: InvoicePool writes recorded in the business transaction
+		`CREATE TABLE IF NOT EXISTS onchain_outbox (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			seq BIGSERIAL NOT NULL UNIQUE,
+			action VARCHAR(40) NOT NULL CHECK (action IN ('create_pool', 'record_investment', 'record_disbursement', 'record_repayment', 'record_mitra_credit')),
+			invoice_id UUID NOT NULL REFERENCES invoices(id),
+			pool_id UUID REFERENCES funding_pools(id),
+			payload JSONB NOT NULL DEFAULT '{}',
+			status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'processing', 'submitted', 'failed')),
+			attempts INT NOT NULL DEFAULT 0,
+			next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			locked_until TIMESTAMP,
+			last_error TEXT,
+			tx_hash VARCHAR(66),
+			submitted_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT NOW(),
+			updated_at TIMESTAMP DEFAULT NOW()
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_onchain_outbox_due ON onchain_outbox(next_attempt_at) WHERE status IN ('pending', 'processing');`,
+		`CREATE INDEX IF NOT EXISTS idx_onchain_outbox_invoice ON onchain_outbox(invoice_id, seq) WHERE status <> 'submitted';`,
+
+		// On-chain receipt tracking: send parameters for replacement, confirmation and linked transactions
+		`ALTER TABLE onchain_outbox ADD COLUMN IF NOT EXISTS transaction_ids UUID[] NOT NULL DEFAULT '{}';`,
+		`ALTER TABLE onchain_outbox ADD COLUMN IF NOT EXISTS nonce BIGINT;`,
+		`ALTER TABLE onchain_outbox ADD COLUMN IF NOT EXISTS gas_price NUMERIC(78,0);`,
+		`ALTER TABLE onchain_outbox ADD COLUMN IF NOT EXISTS gas_limit BIGINT;`,
+		`ALTER TABLE onchain_outbox ADD COLUMN IF NOT EXISTS previous_tx_hashes TEXT[] NOT NULL DEFAULT '{}';`,
+		`ALTER TABLE onchain_outbox ADD COLUMN IF NOT EXISTS block_number BIGINT;`,
+		`ALTER TABLE onchain_outbox ADD COLUMN IF NOT EXISTS gas_used BIGINT;`,
+		`ALTER TABLE onchain_outbox ADD COLUMN IF NOT EXISTS confirmed_at TIMESTAMP;`,
+		`ALTER TABLE onchain_outbox DROP CONSTRAINT IF EXISTS onchain_outbox_status_check;`,
+		`ALTER TABLE onchain_outbox ADD CONSTRAINT onchain_outbox_status_check CHECK (status IN ('pending', 'processing', 'submitted', 'confirmed', 'failed', 'cancelled'));`,
+		`DROP INDEX IF EXISTS idx_onchain_outbox_invoice;`,
+		`CREATE INDEX IF NOT EXISTS idx_onchain_outbox_open ON onchain_outbox(invoice_id, seq) WHERE status NOT IN ('submitted', 'confirmed');`,
+		`CREATE INDEX IF NOT EXISTS idx_onchain_outbox_submitted ON onchain_outbox(submitted_at) WHERE status = 'submitted';`,
+
+		// Contract event indexer: decoded InvoiceNFT/InvoicePool logs and the indexed chain tip
+		`CREATE TABLE IF NOT EXISTS onchain_events (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			chain_id BIGINT NOT NULL,
+			contract VARCHAR(30) NOT NULL,
+			contract_address VARCHAR(42) NOT NULL,
+			event_name VARCHAR(100) NOT NULL,
+			token_id NUMERIC(78,0),
+			block_number BIGINT NOT NULL,
+			block_hash VARCHAR(66) NOT NULL,
+			tx_hash VARCHAR(66) NOT NULL,
+			log_index INT NOT NULL,
+			args JSONB NOT NULL DEFAULT '{}',
+			created_at TIMESTAMP DEFAULT NOW(),
+			UNIQUE (block_hash, log_index)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_onchain_events_token ON onchain_events(chain_id, token_id, block_number, log_index);`,
+		`CREATE INDEX IF NOT EXISTS idx_onchain_events_block ON onchain_events(chain_id, block_number);`,
+		`CREATE TABLE IF NOT EXISTS onchain_indexer_cursors (
+			name VARCHAR(50) PRIMARY KEY,
+			chain_id BIGINT NOT NULL,
+			last_block BIGINT NOT NULL,
+			updated_at TIMESTAMP DEFAULT NOW()
+		);`,
+		`CREATE TABLE IF NOT EXISTS onchain_indexed_blocks (
+			name VARCHAR(50) NOT NULL,
+			block_number BIGINT NOT NULL,
+			block_hash VARCHAR(66) NOT NULL,
+			PRIMARY KEY (name, block_number)
+		);`,
+
+		// Invoice NFT token ids are trusted only once read from the mint's InvoiceMinted
+		// event; ids stored before that were placeholders
+		`ALTER TABLE invoice_nfts ADD COLUMN IF NOT EXISTS token_verified BOOLEAN NOT NULL DEFAULT FALSE;`,
+		`CREATE INDEX IF NOT EXISTS idx_nfts_mint_tx_hash ON invoice_nfts(LOWER(mint_tx_hash));`,
+
+		// Wallet ownership proof: one-time challenges and the history of linked wallets
+		`CREATE TABLE IF NOT EXISTS wallet_challenges (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			wallet_address VARCHAR(42) NOT NULL,
+			method VARCHAR(10) NOT NULL CHECK (method IN ('eip191', 'eip712')),
+			nonce VARCHAR(64) NOT NULL UNIQUE,
+			message TEXT NOT NULL,
+			issued_at TIMESTAMPTZ NOT NULL, -- Signed as Unix seconds in EIP-712 challenges
+			expires_at TIMESTAMPTZ NOT NULL,
+			used_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT NOW()
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_wallet_challenges_user ON wallet_challenges(user_id, created_at DESC);`,
+		`CREATE TABLE IF NOT EXISTS user_wallet_links (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			wallet_address VARCHAR(42) NOT NULL,
+			challenge_id UUID REFERENCES wallet_challenges(id),
+			method VARCHAR(10) NOT NULL,
+			signature VARCHAR(132) NOT NULL,
+			linked_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			unlinked_at TIMESTAMP
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_user_wallet_links_user ON user_wallet_links(user_id, linked_at DESC);`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_user_wallet_links_active ON user_wallet_links(LOWER(wallet_address)) WHERE unlinked_at IS NULL;`,
+
+		// Signed investment consent: EIP-712 proof of what the investor agreed to
+		`CREATE TABLE IF NOT EXISTS investment_consents (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			investment_id UUID NOT NULL UNIQUE REFERENCES investments(id),
+			investor_id UUID NOT NULL REFERENCES users(id),
+			signer_address VARCHAR(42) NOT NULL,
+			tnc_version VARCHAR(50) NOT NULL,
+			consent_texts_hash VARCHAR(66) NOT NULL,
+			typed_data JSONB NOT NULL,
+			digest VARCHAR(66) NOT NULL UNIQUE,
+			signature VARCHAR(132) NOT NULL,
+			expires_at TIMESTAMPTZ NOT NULL,
+			created_at TIMESTAMP DEFAULT NOW()
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_investment_consents_investor ON investment_consents(investor_id, created_at DESC);`,
+
+		// Default workflow: overdue detection, declared defaults, loss allocation and recoveries
+		`ALTER TABLE funding_pools DROP CONSTRAINT IF EXISTS funding_pools_status_check;`,
+		`ALTER TABLE funding_pools ADD CONSTRAINT funding_pools_status_check CHECK (status IN (
+			'open', 'filled', 'disbursed', 'closed', 'expired', 'defaulted'
+		));`,
+		`ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_type_check;`,
+		`ALTER TABLE transactions ADD CONSTRAINT transactions_type_check CHECK (type IN (
+			'investment', 'advance_payment', 'buyer_repayment', 'investor_return',
+			'platform_fee', 'refund', 'deposit', 'withdrawal', 'repayment_excess', 'default_recovery'
+		));`,
+		`ALTER TABLE onchain_outbox DROP CONSTRAINT IF EXISTS onchain_outbox_action_check;`,
+		`ALTER TABLE onchain_outbox ADD CONSTRAINT onchain_outbox_action_check CHECK (action IN (
+			'create_pool', 'record_investment', 'record_disbursement', 'record_repayment', 'record_mitra_credit', 'mark_defaulted'
+		));`,
+		`CREATE TABLE IF NOT EXISTS pool_defaults (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			pool_id UUID NOT NULL UNIQUE REFERENCES funding_pools(id),
+			invoice_id UUID NOT NULL REFERENCES invoices(id),
+			status VARCHAR(20) NOT NULL DEFAULT 'overdue' CHECK (status IN ('overdue', 'declared', 'recovered')),
+			currency VARCHAR(10) NOT NULL,
+			due_date TIMESTAMP NOT NULL,
+			overdue_since TIMESTAMP NOT NULL,
+			detected_at TIMESTAMP,
+			declared_at TIMESTAMP,
+			declared_by UUID REFERENCES users(id),
+			reason TEXT,
+			total_claim DECIMAL(20,2) NOT NULL DEFAULT 0,
+			total_recovered DECIMAL(20,2) NOT NULL DEFAULT 0,
+			total_loss DECIMAL(20,2) NOT NULL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT NOW(),
+			updated_at TIMESTAMP DEFAULT NOW()
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_pool_defaults_status ON pool_defaults(status, overdue_since);`,
+		`CREATE TABLE IF NOT EXISTS default_allocations (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			default_id UUID NOT NULL REFERENCES pool_defaults(id) ON DELETE CASCADE,
+			investment_id UUID NOT NULL UNIQUE REFERENCES investments(id),
+			investor_id UUID NOT NULL REFERENCES users(id),
+			tranche VARCHAR(20) NOT NULL,
+			principal DECIMAL(20,2) NOT NULL,
+			claim DECIMAL(20,2) NOT NULL,
+			recovered DECIMAL(20,2) NOT NULL DEFAULT 0,
+			loss DECIMAL(20,2) NOT NULL DEFAULT 0
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_default_allocations_default ON default_allocations(default_id);`,
+		`CREATE TABLE IF NOT EXISTS default_recoveries (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			default_id UUID NOT NULL REFERENCES pool_defaults(id) ON DELETE CASCADE,
+			amount DECIMAL(20,2) NOT NULL,
+			to_investors DECIMAL(20,2) NOT NULL,
+			excess_to_mitra DECIMAL(20,2) NOT NULL DEFAULT 0,
+			note TEXT,
+			recorded_by UUID REFERENCES users(id),
+			created_at TIMESTAMP DEFAULT NOW()
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_default_recoveries_default ON default_recoveries(default_id, created_at);`,
+		// Installment repayments: partially paid importer payments and the waterfall of each installment
+		`ALTER TABLE importer_payments DROP CONSTRAINT IF EXISTS importer_payments_payment_status_check;`,
+		`ALTER TABLE importer_payments ADD CONSTRAINT importer_payments_payment_status_check CHECK (payment_status IN (
+			'pending', 'partial', 'paid', 'overdue', 'canceled'
+		));`,
+		`ALTER TABLE onchain_outbox DROP CONSTRAINT IF EXISTS onchain_outbox_action_check;`,
+		`ALTER TABLE onchain_outbox ADD CONSTRAINT onchain_outbox_action_check CHECK (action IN (
+			'create_pool', 'record_investment', 'record_disbursement', 'record_repayment', 'record_mitra_credit', 'mark_defaulted',
+			'burn_nft'
+		));`,
+		`CREATE TABLE IF NOT EXISTS repayment_installments (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			pool_id UUID NOT NULL REFERENCES funding_pools(id),
+			invoice_id UUID NOT NULL REFERENCES invoices(id),
+			importer_payment_id UUID REFERENCES importer_payments(id),
+			amount DECIMAL(20,2) NOT NULL,
+			platform_fee DECIMAL(20,2) NOT NULL DEFAULT 0,
+			to_priority DECIMAL(20,2) NOT NULL DEFAULT 0,
+			to_catalyst DECIMAL(20,2) NOT NULL DEFAULT 0,
+			excess_to_mitra DECIMAL(20,2) NOT NULL DEFAULT 0,
+			settled BOOLEAN NOT NULL DEFAULT FALSE,
+			created_at TIMESTAMP DEFAULT NOW()
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_repayment_installments_pool ON repayment_installments(pool_id, created_at);`,
+		// Late payment charges: late fee and penalty interest accrued per pool after the due date
+		`CREATE TABLE IF NOT EXISTS late_charges (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			pool_id UUID NOT NULL UNIQUE REFERENCES funding_pools(id),
+			invoice_id UUID NOT NULL REFERENCES invoices(id),
+			grade VARCHAR(5) NOT NULL,
+			currency VARCHAR(10) NOT NULL DEFAULT 'IDR',
+			due_date TIMESTAMP NOT NULL,
+			late_fee_percent DECIMAL(7,4) NOT NULL DEFAULT 0,
+			penalty_rate DECIMAL(7,4) NOT NULL DEFAULT 0,
+			late_fee DECIMAL(20,2) NOT NULL DEFAULT 0,
+			penalty_interest DECIMAL(20,2) NOT NULL DEFAULT 0,
+			paid DECIMAL(20,2) NOT NULL DEFAULT 0,
+			accrued_through TIMESTAMP NOT NULL,
+			created_at TIMESTAMP DEFAULT NOW(),
+			updated_at TIMESTAMP DEFAULT NOW()
+		);`,
+		`ALTER TABLE repayment_installments ADD COLUMN IF NOT EXISTS late_charges DECIMAL(20,2) NOT NULL DEFAULT 0;`,
+		// Penalty interest is recomputed from the day the overdue balance last changed,
+		// so accruing daily adds up to accruing the whole period at once
+		`ALTER TABLE late_charges ADD COLUMN IF NOT EXISTS accrual_balance DECIMAL(20,2) NOT NULL DEFAULT 0;`,
+		`ALTER TABLE late_charges ADD COLUMN IF NOT EXISTS accrual_start TIMESTAMP;`,
+		`UPDATE late_charges SET accrual_start = accrued_through WHERE accrual_start IS NULL;`,
+		// Pool tranches: any number of tranches per pool in seniority order (1 = most senior)
+		`CREATE TABLE IF NOT EXISTS pool_tranches (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			pool_id UUID NOT NULL REFERENCES funding_pools(id),
+			name VARCHAR(30) NOT NULL,
+			seniority INT NOT NULL CHECK (seniority > 0),
+			target_amount DECIMAL(20,2) NOT NULL,
+			funded_amount DECIMAL(20,2) NOT NULL DEFAULT 0,
+			interest_rate DECIMAL(7,4) NOT NULL,
+			requires_risk_consent BOOLEAN NOT NULL DEFAULT FALSE,
+			requires_risk_profile BOOLEAN NOT NULL DEFAULT FALSE,
+			min_investment DECIMAL(20,2) NOT NULL DEFAULT 0,
+			max_investment DECIMAL(20,2) NOT NULL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT NOW(),
+			UNIQUE (pool_id, name),
+			UNIQUE (pool_id, seniority)
+		);`,
+		`INSERT INTO pool_tranches (pool_id, name, seniority, target_amount, funded_amount, interest_rate, requires_risk_consent)
+		SELECT fp.id, t.name, t.seniority,
+		       CASE t.name WHEN 'priority' THEN COALESCE(fp.priority_target, 0) ELSE COALESCE(fp.catalyst_target, 0) END,
+		       CASE t.name WHEN 'priority' THEN COALESCE(fp.priority_funded, 0) ELSE COALESCE(fp.catalyst_funded, 0) END,
+		       CASE t.name WHEN 'priority' THEN COALESCE(fp.priority_interest_rate, 0) ELSE COALESCE(fp.catalyst_interest_rate, 0) END,
+		       t.risk_consent
+		FROM funding_pools fp
+		CROSS JOIN (VALUES ('priority', 1, FALSE), ('catalyst', 2, TRUE)) AS t(name, seniority, risk_consent)
+		WHERE NOT EXISTS (SELECT 1 FROM pool_tranches pt WHERE pt.pool_id = fp.id);`,
+		`ALTER TABLE investments ALTER COLUMN tranche TYPE VARCHAR(30);`,
+		`ALTER TABLE default_allocations ALTER COLUMN tranche TYPE VARCHAR(30);`,
+		`ALTER TABLE repayment_installments ADD COLUMN IF NOT EXISTS to_tranches JSONB NOT NULL DEFAULT '{}';`,
+		// Secondary market: investors sell all or part of an active position to another investor
+		`CREATE TABLE IF NOT EXISTS secondary_listings (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			investment_id UUID NOT NULL REFERENCES investments(id),
+			seller_id UUID NOT NULL REFERENCES users(id),
+			pool_id UUID NOT NULL REFERENCES funding_pools(id),
+			tranche VARCHAR(30) NOT NULL,
+			principal DECIMAL(20,2) NOT NULL CHECK (principal > 0),
+			price DECIMAL(20,2) NOT NULL CHECK (price > 0),
+			currency VARCHAR(10) NOT NULL DEFAULT 'IDR',
+			status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'sold', 'cancelled')),
+			buyer_id UUID REFERENCES users(id),
+			buyer_investment_id UUID REFERENCES investments(id),
+			sold_at TIMESTAMP,
+			cancelled_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT NOW(),
+			updated_at TIMESTAMP DEFAULT NOW()
+		);`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_secondary_listings_open ON secondary_listings(investment_id) WHERE status = 'open';`,
+		`CREATE INDEX IF NOT EXISTS idx_secondary_listings_status ON secondary_listings(status, created_at);`,
+		`CREATE INDEX IF NOT EXISTS idx_secondary_listings_seller ON secondary_listings(seller_id, created_at);`,
+		`ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_type_check;`,
+		`ALTER TABLE transactions ADD CONSTRAINT transactions_type_check CHECK (type IN (
+			'investment', 'advance_payment', 'buyer_repayment', 'investor_return',
+			'platform_fee', 'refund', 'deposit', 'withdrawal', 'repayment_excess', 'default_recovery',
+			'secondary_purchase', 'secondary_sale'
+		));`,
+		`ALTER TABLE onchain_outbox DROP CONSTRAINT IF EXISTS onchain_outbox_action_check;`,
+		`ALTER TABLE onchain_outbox ADD CONSTRAINT onchain_outbox_action_check CHECK (action IN (
+			'create_pool', 'record_investment', 'record_disbursement', 'record_repayment', 'record_mitra_credit', 'mark_defaulted',
+			'burn_nft', 'transfer_investment'
+		));`,
+		// Grading engine: versioned scorecards of weighted factors, one active and any
+		// number in shadow, and the score breakdown of every invoice under each of them
+		`CREATE TABLE IF NOT EXISTS grading_scorecards (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			version INT NOT NULL UNIQUE,
+			name VARCHAR(100) NOT NULL,
+			description TEXT,
+			status VARCHAR(20) NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'active', 'shadow', 'retired')),
+			grade_thresholds JSONB NOT NULL,
+			created_by UUID REFERENCES users(id),
+			activated_at TIMESTAMP,
+			retired_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT NOW(),
+			updated_at TIMESTAMP DEFAULT NOW()
+		);`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_grading_scorecards_active ON grading_scorecards(status) WHERE status = 'active';`,
+		`CREATE TABLE IF NOT EXISTS grading_scorecard_factors (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			scorecard_id UUID NOT NULL REFERENCES grading_scorecards(id) ON DELETE CASCADE,
+			factor VARCHAR(50) NOT NULL,
+			weight DECIMAL(8,4) NOT NULL CHECK (weight > 0),
+			bands JSONB NOT NULL,
+			position INT NOT NULL DEFAULT 0,
+			UNIQUE (scorecard_id, factor)
+		);`,
+		`CREATE TABLE IF NOT EXISTS invoice_grade_scores (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			invoice_id UUID NOT NULL REFERENCES invoices(id) ON DELETE CASCADE,
+			scorecard_id UUID NOT NULL REFERENCES grading_scorecards(id),
+			scorecard_version INT NOT NULL,
+			mode VARCHAR(20) NOT NULL CHECK (mode IN ('active', 'shadow')),
+			score DECIMAL(6,2) NOT NULL,
+			grade VARCHAR(5) NOT NULL,
+			factors JSONB NOT NULL,
+			graded_at TIMESTAMP DEFAULT NOW(),
+			UNIQUE (invoice_id, scorecard_id)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_invoice_grade_scores_scorecard ON invoice_grade_scores(scorecard_id);`,
+		// Scorecard v1 reproduces the risk matrix the admin grade suggestion used before
+		`INSERT INTO grading_scorecards (version, name, description, status, grade_thresholds, activated_at)
+		VALUES (1, 'Risk matrix', 'Buyer country tier, repeat buyer and document count',
+			'active', '[{"grade":"A","min_score":80},{"grade":"B","min_score":50},{"grade":"C","min_score":0}]', NOW())
+		ON CONFLICT (version) DO NOTHING;`,
+		`INSERT INTO grading_scorecard_factors (scorecard_id, factor, weight, bands, position)
+		SELECT sc.id, f.factor, 1, f.bands::jsonb, f.position
+		FROM grading_scorecards sc
+		CROSS JOIN (VALUES
+			('buyer_country_tier', '[{"max":1,"points":40,"label":"Tier 1"},{"max":2,"points":25,"label":"Tier 2"},{"points":10,"label":"Tier 3"}]', 1),
+			('repeat_buyer', '[{"min":1,"points":30,"label":"Repeat buyer"},{"points":20,"label":"New buyer"}]', 2),
+			('document_count', '[{"min":3,"points":30,"label":"3 or more documents"},{"min":1,"points":20,"label":"1-2 documents"},{"points":5,"label":"No documents"}]', 3)
+		) AS f(factor, bands, position)
+		WHERE sc.version = 1
+		ON CONFLICT (scorecard_id, factor) DO NOTHING;`,
+		// Country tiers are the single source of buyer country risk, keyed by ISO 3166-1
+		// alpha-3 code and managed by admins with every change kept
+		`ALTER TABLE country_tiers ADD COLUMN IF NOT EXISTS created_at TIMESTAMP DEFAULT NOW();`,
+		`ALTER TABLE country_tiers ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP DEFAULT NOW();`,
+		// Buyer countries entered as names are stored as their ISO code
+		`UPDATE invoices i SET buyer_country = ct.country_code
+		FROM country_tiers ct
+		WHERE LOWER(TRIM(i.buyer_country)) = LOWER(ct.country_name);`,
+		`UPDATE country_tiers SET country_name = 'United Arab Emirates' WHERE country_code = 'ARE' AND country_name = 'UAE';`,
+		`CREATE TABLE IF NOT EXISTS country_tier_history (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			country_code VARCHAR(3) NOT NULL,
+			action VARCHAR(10) NOT NULL CHECK (action IN ('create', 'update', 'delete')),
+			old_data JSONB,
+			new_data JSONB,
+			reason TEXT,
+			changed_by UUID REFERENCES users(id),
+			created_at TIMESTAMP DEFAULT NOW()
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_country_tier_history_code ON country_tier_history(country_code, created_at);`,
+		// Credit scores move on repayment outcomes; each event counts once per invoice
+		`INSERT INTO credit_scores (user_id) SELECT id FROM users WHERE role = 'mitra' ON CONFLICT (user_id) DO NOTHING;`,
+		`ALTER TABLE credit_score_history ADD COLUMN IF NOT EXISTS event VARCHAR(30);`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_credit_score_history_event ON credit_score_history(user_id, invoice_id, event);`,
+		`CREATE INDEX IF NOT EXISTS idx_credit_score_history_user ON credit_score_history(user_id, created_at);`,
+		// Buyer registry: one buyer per normalized name and country, so "PT ABC" and
+		// "ABC Ltd." are the same importer. Lookups normalize in SQL with this function.
+		`CREATE OR REPLACE FUNCTION normalize_buyer_name(name TEXT) RETURNS TEXT AS $$
+			SELECT COALESCE(
+				NULLIF(TRIM(regexp_replace(regexp_replace(regexp_replace(LOWER(name), '[[:space:][:punct:]]+', ' ', 'g'),
+					'\m(pt|cv|ud|tbk|persero|ltd|limited|llc|inc|incorporated|co|corp|corporation|company|plc|gmbh|ag|kg|sa|sas|sarl|srl|spa|bv|nv|pte|pty|kk)\M', ' ', 'g'),
+					'\s+', ' ', 'g')), ''),
+				TRIM(regexp_replace(LOWER(name), '[[:space:][:punct:]]+', ' ', 'g'))
+			)
+		$$ LANGUAGE SQL IMMUTABLE;`,
+		`CREATE TABLE IF NOT EXISTS buyers (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			name VARCHAR(255) NOT NULL,
+			normalized_name VARCHAR(255) NOT NULL,
+			country VARCHAR(100) NOT NULL,
+			merged_into UUID REFERENCES buyers(id),
+			created_at TIMESTAMP DEFAULT NOW(),
+			updated_at TIMESTAMP DEFAULT NOW(),
+			UNIQUE(normalized_name, country)
+		);`,
+		`CREATE TABLE IF NOT EXISTS buyer_aliases (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			buyer_id UUID REFERENCES buyers(id) ON DELETE CASCADE NOT NULL,
+			alias VARCHAR(255) NOT NULL,
+			normalized_alias VARCHAR(255) NOT NULL,
+			created_at TIMESTAMP DEFAULT NOW(),
+			UNIQUE(buyer_id, normalized_alias)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_buyer_aliases_normalized ON buyer_aliases(normalized_alias);`,
+		`CREATE TABLE IF NOT EXISTS buyer_contacts (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			buyer_id UUID REFERENCES buyers(id) ON DELETE CASCADE NOT NULL,
+			email VARCHAR(255) NOT NULL,
+			created_at TIMESTAMP DEFAULT NOW(),
+			UNIQUE(buyer_id, email)
+		);`,
+		`ALTER TABLE invoices ADD COLUMN IF NOT EXISTS buyer_id UUID REFERENCES buyers(id);`,
+		`ALTER TABLE importer_payments ADD COLUMN IF NOT EXISTS buyer_id UUID REFERENCES buyers(id);`,
+		`CREATE INDEX IF NOT EXISTS idx_invoices_buyer ON invoices(buyer_id);`,
+		`CREATE INDEX IF NOT EXISTS idx_importer_payments_buyer ON importer_payments(buyer_id);`,
+		// Existing invoices and importer payments are linked to their buyer
+		`INSERT INTO buyers (name, normalized_name, country)
+		SELECT DISTINCT ON (normalize_buyer_name(buyer_name), buyer_country) TRIM(buyer_name), normalize_buyer_name(buyer_name), buyer_country
+		FROM invoices
+		WHERE buyer_id IS NULL AND normalize_buyer_name(buyer_name) <> ''
+		ORDER BY normalize_buyer_name(buyer_name), buyer_country, created_at
+		ON CONFLICT (normalized_name, country) DO NOTHING;`,
+		`UPDATE invoices i SET buyer_id = COALESCE(b.merged_into, b.id)
+		FROM buyers b
+		WHERE i.buyer_id IS NULL AND b.normalized_name = normalize_buyer_name(i.buyer_name) AND b.country = i.buyer_country;`,
+		`UPDATE importer_payments ip SET buyer_id = i.buyer_id
+		FROM invoices i
+		WHERE ip.buyer_id IS NULL AND ip.invoice_id = i.id;`,
+		`INSERT INTO buyer_contacts (buyer_id, email)
+		SELECT DISTINCT buyer_id, LOWER(TRIM(buyer_email)) FROM invoices
+		WHERE buyer_id IS NOT NULL AND TRIM(COALESCE(buyer_email, '')) <> ''
+		UNION
+		SELECT DISTINCT buyer_id, LOWER(TRIM(buyer_email)) FROM importer_payments
+		WHERE buyer_id IS NOT NULL AND TRIM(buyer_email) <> ''
+		ON CONFLICT (buyer_id, email) DO NOTHING;`,
+
+		// Single-use nonces signed into investment consents
+		`CREATE TABLE IF NOT EXISTS investment_consent_nonces (
+			nonce UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			investor_id UUID NOT NULL REFERENCES users(id),
+			expires_at TIMESTAMPTZ NOT NULL,
+			consumed_at TIMESTAMPTZ,
+			created_at TIMESTAMP DEFAULT NOW()
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_investment_consent_nonces_investor ON investment_consent_nonces(investor_id, expires_at);`,
 	}
 
 	for i, migration := range migrations {
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/services"
+	"github.com/vessel/backend/internal/utils"
+)
+
+type AnalyticsHandler struct {
+	analyticsService *services.AnalyticsService
+}
+
+func NewAnalyticsHandler(analyticsService *services.AnalyticsService) *AnalyticsHandler {
+	return &AnalyticsHandler{analyticsService: analyticsService}
+}
+
+// GetLossRates godoc
+// @Summary Get realized loss rates (Admin)
+// @Description Default rate, loss given default, average days late and realized yield of every repaid or defaulted invoice,
+// @Description by grade, buyer country tier and tranche
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param due_from query string false "Only invoices due on or after this date (YYYY-MM-DD)"
+// @Param due_to query string false "Only invoices due on or before this date (YYYY-MM-DD)"
+// @Success 200 {object} models.LossRateReport
+// @Router /admin/analytics/loss-rates [get]
+func (h *AnalyticsHandler) GetLossRates(c *gin.Context) {
+	var filter models.OutcomeFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		utils.BadRequestError(c, "Invalid query parameters")
+		return
+	}
+
+	report, err := h.analyticsService.LossRates(&filter)
+	if err != nil {
+		utils.InternalServerError(c, "Failed to compute loss rates")
+		return
+	}
+
+	utils.SuccessResponse(c, report)
+}
+
+// BacktestScorecard godoc
+// @Summary Backtest a grading scorecard (Admin)
+// @Description Replays every repaid or defaulted invoice through the scorecard and compares outcomes by its grades with
+// @Description outcomes by the grades the invoices were funded with. Nothing is stored.
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Scorecard ID"
+// @Param due_from query string false "Only invoices due on or after this date (YYYY-MM-DD)"
+// @Param due_to query string false "Only invoices due on or before this date (YYYY-MM-DD)"
+// @Success 200 {object} models.BacktestReport
+// @Router /admin/grading/scorecards/{id}/backtest [get]
+func (h *AnalyticsHandler) BacktestScorecard(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequestError(c, "Invalid scorecard ID")
+		return
+	}
+
+	var filter models.OutcomeFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		utils.BadRequestError(c, "Invalid query parameters")
+		return
+	}
+
+	report, err := h.analyticsService.BacktestScorecard(id, &filter)
+	if err != nil {
+		if errors.Is(err, services.ErrScorecardNotFound) {
+			utils.NotFoundError(c, err.Error())
+			return
+		}
+		utils.InternalServerError(c, "Failed to backtest scorecard")
+		return
+	}
+
+	utils.SuccessResponse(c, report)
+}
@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/services"
+	"github.com/vessel/backend/internal/utils"
+)
+
+type BuyerHandler struct {
+	buyerService   *services.BuyerService
+	invoiceService *services.InvoiceService
+}
+
+func NewBuyerHandler(buyerService *services.BuyerService, invoiceService *services.InvoiceService) *BuyerHandler {
+	return &BuyerHandler{buyerService: buyerService, invoiceService: invoiceService}
+}
+
+// ListBuyers godoc
+// @Summary List registered buyers (Admin)
+// @Description Buyers that were not merged away, by name. search matches the name or an alias, ignoring case, punctuation and legal forms.
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param search query string false "Name or alias"
+// @Param country query string false "ISO 3166 alpha-2 or alpha-3 code, or English name"
+// @Param page query int false "Page number"
+// @Param per_page query int false "Items per page"
+// @Success 200 {object} models.BuyerListResponse
+// @Router /admin/buyers [get]
+func (h *BuyerHandler) ListBuyers(c *gin.Context) {
+	var filter models.BuyerFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		utils.BadRequestError(c, err.Error())
+		return
+	}
+	params := models.PaginationParams{Page: filter.Page, PerPage: filter.PerPage}
+	params.Normalize()
+	filter.Page, filter.PerPage = params.Page, params.PerPage
+
+	response, err := h.buyerService.List(&filter)
+	if err != nil {
+		h.handleBuyerError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, response)
+}
+
+// GetBuyerDuplicates godoc
+// @Summary List possible duplicate buyers (Admin)
+// @Description Pairs of buyers in the same country with similar names, most similar first. Merge the ones that are the same importer.
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param country query string false "ISO 3166 alpha-2 or alpha-3 code, or English name"
+// @Success 200 {array} models.BuyerDuplicate
+// @Router /admin/buyers/duplicates [get]
+func (h *BuyerHandler) GetBuyerDuplicates(c *gin.Context) {
+	duplicates, err := h.buyerService.Duplicates(c.Query("country"))
+	if err != nil {
+		h.handleBuyerError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, duplicates)
+}
+
+// GetBuyer godoc
+// @Summary Get a buyer (Admin)
+// @Description The buyer with its aliases, contact emails and payment history across every mitra
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Buyer ID"
+// @Success 200 {object} models.Buyer
+// @Router /admin/buyers/{id} [get]
+func (h *BuyerHandler) GetBuyer(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequestError(c, "Invalid buyer ID")
+		return
+	}
+
+	buyer, err := h.buyerService.Get(id)
+	if err != nil {
+		h.handleBuyerError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, buyer)
+}
+
+// GetBuyerInvoices godoc
+// @Summary List a buyer's invoices (Admin)
+// @Description Invoices of the buyer from every mitra, newest first
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Buyer ID"
+// @Param page query int false "Page number"
+// @Param per_page query int false "Items per page"
+// @Success 200 {object} models.InvoiceListResponse
+// @Router /admin/buyers/{id}/invoices [get]
+func (h *BuyerHandler) GetBuyerInvoices(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequestError(c, "Invalid buyer ID")
+		return
+	}
+	var params models.PaginationParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		params = models.PaginationParams{Page: 1, PerPage: 10}
+	}
+	params.Normalize()
+
+	response, err := h.invoiceService.GetBuyerInvoices(id, params.Page, params.PerPage)
+	if err != nil {
+		utils.InternalServerError(c, "Failed to get buyer invoices")
+		return
+	}
+
+	utils.SuccessResponse(c, response)
+}
+
+// AddBuyerAlias godoc
+// @Summary Add a buyer alias (Admin)
+// @Description Invoices naming the buyer by the alias are linked to it. A name that belongs to another buyer is refused; merge the two instead.
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Buyer ID"
+// @Param request body models.AddBuyerAliasRequest true "Alias"
+// @Success 200 {object} models.Buyer
+// @Router /admin/buyers/{id}/aliases [post]
+func (h *BuyerHandler) AddBuyerAlias(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequestError(c, "Invalid buyer ID")
+		return
+	}
+
+	var req models.AddBuyerAliasRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestError(c, err.Error())
+		return
+	}
+
+	buyer, err := h.buyerService.AddAlias(id, req.Alias)
+	if err != nil {
+		h.handleBuyerError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, buyer)
+}
+
+// AddBuyerContact godoc
+// @Summary Add a buyer contact email (Admin)
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Buyer ID"
+// @Param request body models.AddBuyerContactRequest true "Email"
+// @Success 200 {object} models.Buyer
+// @Router /admin/buyers/{id}/contacts [post]
+func (h *BuyerHandler) AddBuyerContact(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequestError(c, "Invalid buyer ID")
+		return
+	}
+
+	var req models.AddBuyerContactRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestError(c, err.Error())
+		return
+	}
+
+	buyer, err := h.buyerService.AddContact(id, req.Email)
+	if err != nil {
+		h.handleBuyerError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, buyer)
+}
+
+// MergeBuyer godoc
+// @Summary Merge a duplicate buyer (Admin)
+// @Description Moves the buyer's invoices, importer payments, aliases and contacts to the buyer that is kept and records its name as an alias there.
+// @Description Both buyers must be in the same country.
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "ID of the duplicate buyer"
+// @Param request body models.MergeBuyerRequest true "Buyer to keep"
+// @Success 200 {object} models.Buyer
+// @Router /admin/buyers/{id}/merge [post]
+func (h *BuyerHandler) MergeBuyer(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequestError(c, "Invalid buyer ID")
+		return
+	}
+
+	var req models.MergeBuyerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestError(c, err.Error())
+		return
+	}
+
+	buyer, err := h.buyerService.Merge(id, req.IntoID)
+	if err != nil {
+		h.handleBuyerError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, buyer)
+}
+
+func (h *BuyerHandler) handleBuyerError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUnknownCountry),
+		errors.Is(err, services.ErrInvalidBuyerName),
+		errors.Is(err, services.ErrBuyerMergeSelf),
+		errors.Is(err, services.ErrBuyerCountryMismatch):
+		utils.BadRequestError(c, err.Error())
+	case errors.Is(err, services.ErrBuyerNotFound):
+		utils.NotFoundError(c, err.Error())
+	case errors.Is(err, services.ErrBuyerMerged),
+		errors.Is(err, services.ErrBuyerAliasTaken):
+		utils.ConflictError(c, err.Error())
+	default:
+		utils.InternalServerError(c, "Failed to update buyer registry")
+	}
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/services"
+	"github.com/vessel/backend/internal/utils"
+)
+
+type CountryTierHandler struct {
+	countryTierService *services.CountryTierService
+}
+
+func NewCountryTierHandler(countryTierService *services.CountryTierService) *CountryTierHandler {
+	return &CountryTierHandler{countryTierService: countryTierService}
+}
+
+// ListCountryTiers godoc
+// @Summary List country risk tiers (Admin)
+// @Description Every rated buyer country, lowest risk first. Countries without a tier are graded as tier 2 (medium).
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.CountryTier
+// @Router /admin/country-tiers [get]
+func (h *CountryTierHandler) ListCountryTiers(c *gin.Context) {
+	tiers, err := h.countryTierService.List()
+	if err != nil {
+		utils.InternalServerError(c, "Failed to list country tiers")
+		return
+	}
+	if tiers == nil {
+		tiers = []models.CountryTier{}
+	}
+
+	utils.SuccessResponse(c, tiers)
+}
+
+// GetCountryTier godoc
+// @Summary Get a country risk tier (Admin)
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param code path string true "ISO 3166 alpha-2 or alpha-3 code, or English name"
+// @Success 200 {object} models.CountryTier
+// @Router /admin/country-tiers/{code} [get]
+func (h *CountryTierHandler) GetCountryTier(c *gin.Context) {
+	tier, err := h.countryTierService.Get(c.Param("code"))
+	if err != nil {
+		h.handleCountryTierError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, tier)
+}
+
+// CreateCountryTier godoc
+// @Summary Rate a country (Admin)
+// @Description Adds a tier for an ISO 3166 country. The code is stored as alpha-3; name and flag default to the ISO country's.
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body models.CreateCountryTierRequest true "Country and tier"
+// @Success 201 {object} models.CountryTier
+// @Router /admin/country-tiers [post]
+func (h *CountryTierHandler) CreateCountryTier(c *gin.Context) {
+	adminID := c.MustGet("user_id").(uuid.UUID)
+
+	var req models.CreateCountryTierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestError(c, err.Error())
+		return
+	}
+
+	tier, err := h.countryTierService.Create(adminID, &req)
+	if err != nil {
+		h.handleCountryTierError(c, err)
+		return
+	}
+
+	utils.CreatedResponse(c, tier)
+}
+
+// UpdateCountryTier godoc
+// @Summary Update a country risk tier (Admin)
+// @Description Changes the fields that are set. Grading picks up the new tier immediately.
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param code path string true "ISO 3166 alpha-2 or alpha-3 code, or English name"
+// @Param request body models.UpdateCountryTierRequest true "Fields to change"
+// @Success 200 {object} models.CountryTier
+// @Router /admin/country-tiers/{code} [put]
+func (h *CountryTierHandler) UpdateCountryTier(c *gin.Context) {
+	adminID := c.MustGet("user_id").(uuid.UUID)
+
+	var req models.UpdateCountryTierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestError(c, err.Error())
+		return
+	}
+
+	tier, err := h.countryTierService.Update(adminID, c.Param("code"), &req)
+	if err != nil {
+		h.handleCountryTierError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, tier)
+}
+
+// DeleteCountryTier godoc
+// @Summary Remove a country risk tier (Admin)
+// @Description The country's buyers are graded as tier 2 (medium) afterwards
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param code path string true "ISO 3166 alpha-2 or alpha-3 code, or English name"
+// @Param reason query string false "Why the tier was removed"
+// @Success 200 {object} map[string]string
+// @Router /admin/country-tiers/{code} [delete]
+func (h *CountryTierHandler) DeleteCountryTier(c *gin.Context) {
+	adminID := c.MustGet("user_id").(uuid.UUID)
+
+	var reason *string
+	if r := c.Query("reason"); r != "" {
+		reason = &r
+	}
+
+	if err := h.countryTierService.Delete(adminID, c.Param("code"), reason); err != nil {
+		h.handleCountryTierError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"message": "Country tier removed"})
+}
+
+// GetCountryTierHistory godoc
+// @Summary Get a country tier's change history (Admin)
+// @Description Every create, update and delete with the tier before and after, who made it and why, newest first
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param code path string true "ISO 3166 alpha-2 or alpha-3 code, or English name"
+// @Param page query int false "Page number"
+// @Param per_page query int false "Items per page"
+// @Success 200 {object} models.CountryTierHistoryResponse
+// @Router /admin/country-tiers/{code}/history [get]
+func (h *CountryTierHandler) GetCountryTierHistory(c *gin.Context) {
+	var params models.PaginationParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		params = models.PaginationParams{Page: 1, PerPage: 10}
+	}
+	params.Normalize()
+
+	history, err := h.countryTierService.History(c.Param("code"), params.Page, params.PerPage)
+	if err != nil {
+		h.handleCountryTierError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, history)
+}
+
+func (h *CountryTierHandler) handleCountryTierError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUnknownCountry):
+		utils.BadRequestError(c, err.Error())
+	case errors.Is(err, services.ErrCountryTierNotFound):
+		utils.NotFoundError(c, err.Error())
+	case errors.Is(err, services.ErrCountryTierExists):
+		utils.ConflictError(c, err.Error())
+	default:
+		utils.InternalServerError(c, "Failed to update country tier")
+	}
+}
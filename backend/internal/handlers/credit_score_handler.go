@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/services"
+	"github.com/vessel/backend/internal/utils"
+)
+
+type CreditScoreHandler struct {
+	creditScoreService *services.CreditScoreService
+}
+
+func NewCreditScoreHandler(creditScoreService *services.CreditScoreService) *CreditScoreHandler {
+	return &CreditScoreHandler{creditScoreService: creditScoreService}
+}
+
+// GetMyCreditScore godoc
+// @Summary Get my credit score
+// @Description The mitra's credit score with its latest changes and their reasons, its percentile among mitras and its risk level
+// @Tags MITRA
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.CreditScoreResponse
+// @Router /mitra/credit-score [get]
+func (h *CreditScoreHandler) GetMyCreditScore(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	creditScore, err := h.creditScoreService.GetCreditScore(userID)
+	if err != nil {
+		utils.InternalServerError(c, "Failed to get credit score")
+		return
+	}
+
+	utils.SuccessResponse(c, creditScore)
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/money"
 	"github.com/vessel/backend/internal/services"
 	"github.com/vessel/backend/internal/utils"
 )
@@ -73,6 +74,6 @@ func (h *CurrencyHandler) CalculateEstimatedDisbursement(c *gin.Context) {
 		return
 	}
 
-	result := h.currencyService.CalculateEstimatedDisbursement(params.Amount)
+	result := h.currencyService.CalculateEstimatedDisbursement(money.FromFloat(params.Amount))
 	utils.SuccessResponse(c, result)
 }
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/services"
+	"github.com/vessel/backend/internal/utils"
+)
+
+type DefaultHandler struct {
+	defaultService *services.DefaultService
+}
+
+func NewDefaultHandler(defaultService *services.DefaultService) *DefaultHandler {
+	return &DefaultHandler{defaultService: defaultService}
+}
+
+// ListDefaults godoc
+// @Summary List default cases (Admin)
+// @Description Pools flagged overdue after the grace period, declared in default, or recovered
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "overdue, declared or recovered"
+// @Success 200 {array} models.PoolDefault
+// @Router /admin/defaults [get]
+func (h *DefaultHandler) ListDefaults(c *gin.Context) {
+	status := c.Query("status")
+	switch models.DefaultStatus(status) {
+	case "", models.DefaultStatusOverdue, models.DefaultStatusDeclared, models.DefaultStatusRecovered:
+	default:
+		utils.BadRequestError(c, "Invalid status")
+		return
+	}
+
+	defaults, err := h.defaultService.ListDefaults(status)
+	if err != nil {
+		utils.InternalServerError(c, "Failed to list defaults")
+		return
+	}
+
+	utils.SuccessResponse(c, defaults)
+}
+
+// GetDefault godoc
+// @Summary Get a pool's default case (Admin)
+// @Description Default case with the per-investment loss allocation and every recovery
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Pool ID"
+// @Success 200 {object} models.PoolDefault
+// @Router /admin/pools/{id}/default [get]
+func (h *DefaultHandler) GetDefault(c *gin.Context) {
+	poolID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequestError(c, "Invalid pool ID")
+		return
+	}
+
+	d, err := h.defaultService.GetDefault(poolID)
+	if err != nil {
+		if errors.Is(err, services.ErrDefaultNotFound) {
+			utils.NotFoundError(c, err.Error())
+			return
+		}
+		utils.InternalServerError(c, "Failed to get default")
+		return
+	}
+
+	utils.SuccessResponse(c, d)
+}
+
+// DeclareDefault godoc
+// @Summary Declare a pool in default (Admin)
+// @Description Marks every active investment defaulted with the principal not yet repaid as the loss, marks the pool defaulted on-chain and notifies investors. Later recoveries are paid to the most senior tranche first.
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Pool ID"
+// @Param request body models.DeclareDefaultRequest true "Reason for the default"
+// @Success 200 {object} models.PoolDefault
+// @Router /admin/pools/{id}/default [post]
+func (h *DefaultHandler) DeclareDefault(c *gin.Context) {
+	adminID := c.MustGet("user_id").(uuid.UUID)
+	poolID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequestError(c, "Invalid pool ID")
+		return
+	}
+
+	var req models.DeclareDefaultRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestError(c, err.Error())
+		return
+	}
+
+	d, err := h.defaultService.DeclareDefault(poolID, adminID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrPoolNotFound):
+			utils.NotFoundError(c, err.Error())
+		case errors.Is(err, services.ErrPoolNotDefaultable), errors.Is(err, services.ErrInvoiceNotPastDue):
+			utils.ConflictError(c, err.Error())
+		default:
+			utils.InternalServerError(c, "Failed to declare default")
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, d)
+}
+
+// RecordRecovery godoc
+// @Summary Record a recovery on a defaulted pool (Admin)
+// @Description Distributes money collected after a default: down the tranches in seniority order, pro-rata by claim within a tranche; anything beyond every claim goes to the exporter
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Pool ID"
+// @Param request body models.RecordRecoveryRequest true "Amount recovered"
+// @Success 201 {object} models.DefaultRecovery
+// @Router /admin/pools/{id}/recoveries [post]
+func (h *DefaultHandler) RecordRecovery(c *gin.Context) {
+	adminID := c.MustGet("user_id").(uuid.UUID)
+	poolID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequestError(c, "Invalid pool ID")
+		return
+	}
+
+	var req models.RecordRecoveryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestError(c, err.Error())
+		return
+	}
+
+	recovery, err := h.defaultService.RecordRecovery(poolID, adminID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrPoolNotFound):
+			utils.NotFoundError(c, err.Error())
+		case errors.Is(err, services.ErrDefaultNotDeclared):
+			utils.ConflictError(c, err.Error())
+		default:
+			utils.InternalServerError(c, "Failed to record recovery")
+		}
+		return
+	}
+
+	utils.CreatedResponse(c, recovery)
+}
@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"errors"
+	"io"
+
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/vessel/backend/internal/models"
@@ -74,6 +77,7 @@ func (h *FundingHandler) GetPool(c *gin.Context) {
 // @Security BearerAuth
 // @Produce json
 // @Param id path string true "Invoice ID"
+// @Param request body models.CreatePoolRequest false "Tranche layout, most senior first"
 // @Success 201 {object} models.FundingPool
 // @Router /invoices/{id}/pool [post]
 func (h *FundingHandler) CreatePool(c *gin.Context) {
@@ -83,7 +87,14 @@ func (h *FundingHandler) CreatePool(c *gin.Context) {
 		return
 	}
 
-	pool, err := h.fundingService.CreatePool(invoiceID)
+	// The body is optional; without it the pool gets the invoice's tranche split
+	var req models.CreatePoolRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.BadRequestError(c, err.Error())
+		return
+	}
+
+	pool, err := h.fundingService.CreatePool(invoiceID, &req)
 	if err != nil {
 		utils.HandleAppError(c, err)
 		return
@@ -109,6 +120,10 @@ func (h *FundingHandler) CreatePool(c *gin.Context) {
 // @Description    - risk_loss_consent: "Saya siap menanggung risiko kehilangan modal"
 // @Description    - not_bank_consent: "Saya paham ini bukan produk bank"
 // @Description 4. Submit
+// @Description
+// @Description **Signed consent (optional, required when INVEST_CONSENT_SIGNATURE_REQUIRED=true):**
+// @Description Get the typed data from POST /investments/consent, sign it with the linked wallet
+// @Description (eth_signTypedData_v4) and send consent_signature and consent_expires_at
 // @Tags Funding
 // @Security BearerAuth
 // @Accept json
@@ -134,6 +149,63 @@ func (h *FundingHandler) Invest(c *gin.Context) {
 	utils.CreatedResponse(c, investment)
 }
 
+// PrepareConsent godoc
+// @Summary Get investment consent typed data
+// @Description Returns the EIP-712 payload (pool, tranche, amount, consent texts hash, T&C version) to sign with the linked wallet before investing
+// @Tags Funding
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body models.InvestmentConsentRequest true "Investment to consent to"
+// @Success 200 {object} models.InvestmentConsentResponse
+// @Router /investments/consent [post]
+func (h *FundingHandler) PrepareConsent(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req models.InvestmentConsentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestError(c, err.Error())
+		return
+	}
+
+	response, err := h.fundingService.BuildInvestmentConsent(userID, &req)
+	if err != nil {
+		utils.HandleAppError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, response)
+}
+
+// GetInvestmentConsent godoc
+// @Summary Get signed investment consent (Admin)
+// @Description Returns the stored EIP-712 consent proof for an investment
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Investment ID"
+// @Success 200 {object} models.InvestmentConsent
+// @Router /admin/investments/{id}/consent [get]
+func (h *FundingHandler) GetInvestmentConsent(c *gin.Context) {
+	investmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequestError(c, "Invalid investment ID")
+		return
+	}
+
+	consent, err := h.fundingService.GetInvestmentConsent(investmentID)
+	if err != nil {
+		if errors.Is(err, services.ErrConsentNotFound) {
+			utils.NotFoundError(c, err.Error())
+			return
+		}
+		utils.InternalServerError(c, "Failed to get investment consent")
+		return
+	}
+
+	utils.SuccessResponse(c, consent)
+}
+
 // GetMyInvestments godoc
 // @Summary Get my investments
 // @Description Get all investments made by the current investor
@@ -188,13 +260,13 @@ func (h *FundingHandler) Disburse(c *gin.Context) {
 
 // ProcessRepayment godoc
 // @Summary Process invoice repayment (Admin)
-// @Description Process buyer repayment and distribute to investors
+// @Description Process a buyer repayment installment and distribute it to investors. The pool closes once every investor claim is met, or when final is set.
 // @Tags Admin
 // @Security BearerAuth
 // @Accept json
 // @Produce json
 // @Param id path string true "Invoice ID"
-// @Param request body map[string]float64 true "Repayment amount"
+// @Param request body models.ProcessRepaymentRequest true "Repayment amount"
 // @Success 200 {object} map[string]string
 // @Router /admin/invoices/{id}/repay [post]
 func (h *FundingHandler) ProcessRepayment(c *gin.Context) {
@@ -204,15 +276,17 @@ func (h *FundingHandler) ProcessRepayment(c *gin.Context) {
 		return
 	}
 
-	var req struct {
-		Amount float64 `json:"amount" binding:"required"`
-	}
+	var req models.ProcessRepaymentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.BadRequestError(c, "Amount is required")
 		return
 	}
 
-	if err := h.fundingService.ProcessRepayment(invoiceID, req.Amount); err != nil {
+	if err := h.fundingService.ProcessRepayment(invoiceID, req.Amount, req.Final); err != nil {
+		if errors.Is(err, services.ErrPoolNotRepayable) {
+			utils.ConflictError(c, err.Error())
+			return
+		}
 		utils.HandleAppError(c, err)
 		return
 	}
@@ -220,6 +294,35 @@ func (h *FundingHandler) ProcessRepayment(c *gin.Context) {
 	utils.SuccessResponse(c, gin.H{"message": "Repayment processed successfully"})
 }
 
+// GetRepaymentSummary godoc
+// @Summary Get a pool's repayment progress (Admin)
+// @Description Investor claims, what has been paid so far and every installment received
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Pool ID"
+// @Success 200 {object} models.RepaymentSummary
+// @Router /admin/pools/{id}/repayments [get]
+func (h *FundingHandler) GetRepaymentSummary(c *gin.Context) {
+	poolID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequestError(c, "Invalid pool ID")
+		return
+	}
+
+	summary, err := h.fundingService.GetRepaymentSummary(poolID)
+	if err != nil {
+		if errors.Is(err, services.ErrPoolNotFound) {
+			utils.NotFoundError(c, err.Error())
+			return
+		}
+		utils.InternalServerError(c, "Failed to get repayment summary")
+		return
+	}
+
+	utils.SuccessResponse(c, summary)
+}
+
 // GetPortfolio godoc
 // @Summary Get investor portfolio summary
 // @Description Get a summary of investor's portfolio including total funding, gains, and allocation
@@ -327,7 +430,7 @@ func (h *FundingHandler) GetMarketplace(c *gin.Context) {
 
 // ExporterDisbursement godoc
 // @Summary Exporter disburse funds to investors
-// @Description Exporter pays back investors via escrow. Priority tranche paid first, catalyst gets remainder.
+// @Description Exporter pays back investors via escrow as one installment. Senior tranches are paid first; a short payment leaves the rest owed.
 // @Tags Exporter
 // @Security BearerAuth
 // @Accept json
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/services"
+	"github.com/vessel/backend/internal/utils"
+)
+
+type GradingHandler struct {
+	gradingService *services.GradingService
+}
+
+func NewGradingHandler(gradingService *services.GradingService) *GradingHandler {
+	return &GradingHandler{gradingService: gradingService}
+}
+
+// ListFactors godoc
+// @Summary List grading factors (Admin)
+// @Description Signals a scorecard factor can score, with the category its contribution counts towards
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.GradingFactorInfo
+// @Router /admin/grading/factors [get]
+func (h *GradingHandler) ListFactors(c *gin.Context) {
+	utils.SuccessResponse(c, h.gradingService.Factors())
+}
+
+// ListScorecards godoc
+// @Summary List grading scorecards (Admin)
+// @Description Every scorecard version with its factors and grade thresholds, newest first
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.Scorecard
+// @Router /admin/grading/scorecards [get]
+func (h *GradingHandler) ListScorecards(c *gin.Context) {
+	scorecards, err := h.gradingService.ListScorecards()
+	if err != nil {
+		utils.InternalServerError(c, "Failed to list scorecards")
+		return
+	}
+
+	utils.SuccessResponse(c, scorecards)
+}
+
+// GetScorecard godoc
+// @Summary Get a grading scorecard (Admin)
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Scorecard ID"
+// @Success 200 {object} models.Scorecard
+// @Router /admin/grading/scorecards/{id} [get]
+func (h *GradingHandler) GetScorecard(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequestError(c, "Invalid scorecard ID")
+		return
+	}
+
+	scorecard, err := h.gradingService.GetScorecard(id)
+	if err != nil {
+		h.handleScorecardError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, scorecard)
+}
+
+// CreateScorecard godoc
+// @Summary Create a grading scorecard version (Admin)
+// @Description Stores the next scorecard version as a draft. Factors must be known and used once, the best case must score at most 100,
+// @Description and thresholds run from the highest min_score down to 0. Scorecards cannot be edited; create a new version instead.
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body models.CreateScorecardRequest true "Factors, bands, weights and grade thresholds"
+// @Success 201 {object} models.Scorecard
+// @Router /admin/grading/scorecards [post]
+func (h *GradingHandler) CreateScorecard(c *gin.Context) {
+	adminID := c.MustGet("user_id").(uuid.UUID)
+
+	var req models.CreateScorecardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestError(c, err.Error())
+		return
+	}
+
+	scorecard, err := h.gradingService.CreateScorecard(adminID, &req)
+	if err != nil {
+		h.handleScorecardError(c, err)
+		return
+	}
+
+	utils.CreatedResponse(c, scorecard)
+}
+
+// ActivateScorecard godoc
+// @Summary Activate a grading scorecard (Admin)
+// @Description The scorecard grades invoices from now on and the previously active one is retired. Activating a retired version rolls back to it.
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Scorecard ID"
+// @Success 200 {object} models.Scorecard
+// @Router /admin/grading/scorecards/{id}/activate [post]
+func (h *GradingHandler) ActivateScorecard(c *gin.Context) {
+	h.transition(c, h.gradingService.ActivateScorecard)
+}
+
+// ShadowScorecard godoc
+// @Summary Run a grading scorecard in shadow (Admin)
+// @Description Invoices are also scored by this scorecard and the breakdown stored, without affecting the suggested grade
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Scorecard ID"
+// @Success 200 {object} models.Scorecard
+// @Router /admin/grading/scorecards/{id}/shadow [post]
+func (h *GradingHandler) ShadowScorecard(c *gin.Context) {
+	h.transition(c, h.gradingService.ShadowScorecard)
+}
+
+// RetireScorecard godoc
+// @Summary Retire a grading scorecard (Admin)
+// @Description Stops evaluating a draft or shadow scorecard. The active scorecard is retired by activating another.
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Scorecard ID"
+// @Success 200 {object} models.Scorecard
+// @Router /admin/grading/scorecards/{id}/retire [post]
+func (h *GradingHandler) RetireScorecard(c *gin.Context) {
+	h.transition(c, h.gradingService.RetireScorecard)
+}
+
+func (h *GradingHandler) transition(c *gin.Context, move func(uuid.UUID) (*models.Scorecard, error)) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequestError(c, "Invalid scorecard ID")
+		return
+	}
+
+	scorecard, err := move(id)
+	if err != nil {
+		h.handleScorecardError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, scorecard)
+}
+
+func (h *GradingHandler) handleScorecardError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrScorecardNotFound):
+		utils.NotFoundError(c, err.Error())
+	case errors.Is(err, services.ErrScorecardTransition):
+		utils.ConflictError(c, err.Error())
+	case errors.Is(err, services.ErrInvalidScorecard):
+		utils.BadRequestError(c, err.Error())
+	default:
+		utils.InternalServerError(c, "Failed to update scorecard")
+	}
+}
+
+// GetInvoiceScores godoc
+// @Summary Get an invoice's grade score breakdowns (Admin)
+// @Description The invoice's latest score under the active scorecard and every scorecard run in shadow, with each factor's contribution
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Invoice ID"
+// @Success 200 {array} models.InvoiceGradeScore
+// @Router /admin/invoices/{id}/grade-scores [get]
+func (h *GradingHandler) GetInvoiceScores(c *gin.Context) {
+	invoiceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequestError(c, "Invalid invoice ID")
+		return
+	}
+
+	scores, err := h.gradingService.GetInvoiceScores(invoiceID)
+	if err != nil {
+		utils.InternalServerError(c, "Failed to get grade scores")
+		return
+	}
+
+	utils.SuccessResponse(c, scores)
+}
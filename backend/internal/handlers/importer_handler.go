@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"errors"
+
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/vessel/backend/internal/models"
@@ -10,23 +12,23 @@ import (
 )
 
 type ImporterHandler struct {
-	paymentRepo    *repository.ImporterPaymentRepository
-	fundingService *services.FundingService
-	fundingRepo    repository.FundingRepositoryInterface
-	invoiceRepo    repository.InvoiceRepositoryInterface
+	paymentRepo            *repository.ImporterPaymentRepository
+	importerPaymentService *services.ImporterPaymentService
+	fundingRepo            repository.FundingRepositoryInterface
+	invoiceRepo            repository.InvoiceRepositoryInterface
 }
 
 func NewImporterHandler(
 	paymentRepo *repository.ImporterPaymentRepository,
-	fundingService *services.FundingService,
+	importerPaymentService *services.ImporterPaymentService,
 	fundingRepo repository.FundingRepositoryInterface,
 	invoiceRepo repository.InvoiceRepositoryInterface,
 ) *ImporterHandler {
 	return &ImporterHandler{
-		paymentRepo:    paymentRepo,
-		fundingService: fundingService,
-		fundingRepo:    fundingRepo,
-		invoiceRepo:    invoiceRepo,
+		paymentRepo:            paymentRepo,
+		importerPaymentService: importerPaymentService,
+		fundingRepo:            fundingRepo,
+		invoiceRepo:            invoiceRepo,
 	}
 }
 
@@ -72,7 +74,7 @@ func (h *ImporterHandler) GetPaymentInfo(c *gin.Context) {
 
 // Pay godoc
 // @Summary Process payment from importer (PUBLIC)
-// @Description Process payment from importer. No authentication required. This is for non-user importers.
+// @Description Pay an importer invoice through the payment gateway, in full or as one of several installments. No authentication required. This is for non-user importers.
 // @Tags Public
 // @Accept json
 // @Produce json
@@ -93,60 +95,17 @@ func (h *ImporterHandler) Pay(c *gin.Context) {
 		return
 	}
 
-	// Get payment record
-	payment, err := h.paymentRepo.FindByID(paymentID)
+	// Collect through the payment gateway; each installment is distributed to
+	// investors (most senior tranche first) when the gateway confirms it
+	response, err := h.importerPaymentService.Pay(paymentID, req.Amount)
 	if err != nil {
-		utils.InternalServerError(c, "Failed to get payment info")
-		return
-	}
-	if payment == nil {
-		utils.NotFoundError(c, "Payment not found")
-		return
-	}
-
-	// Check if already paid
-	if payment.PaymentStatus == models.ImporterPaymentStatusPaid {
-		utils.BadRequestError(c, "This invoice has already been paid")
-		return
-	}
-
-	// Validate amount
-	if req.Amount < payment.AmountDue {
-		utils.BadRequestError(c, "Payment amount is less than amount due")
-		return
-	}
-
-	// Process repayment through funding service
-	// This distributes funds to investors (priority-first)
-	if err := h.fundingService.ProcessRepayment(payment.InvoiceID, req.Amount); err != nil {
+		if errors.Is(err, services.ErrPoolNotRepayable) {
+			utils.ConflictError(c, err.Error())
+			return
+		}
 		utils.HandleAppError(c, err)
 		return
 	}
 
-	// Generate simulated tx hash (in production, this comes from blockchain)
-	txHash := generateTxHash()
-
-	// Update payment record
-	if err := h.paymentRepo.UpdatePayment(paymentID, req.Amount, txHash); err != nil {
-		utils.InternalServerError(c, "Failed to update payment record")
-		return
-	}
-
-	// Get updated payment
-	updatedPayment, _ := h.paymentRepo.FindByID(paymentID)
-
-	utils.SuccessResponse(c, models.ImporterPaymentResponse{
-		PaymentID:  paymentID,
-		Status:     "paid",
-		AmountPaid: req.Amount,
-		TxHash:     &txHash,
-		Message:    "Payment processed successfully. Funds have been distributed to investors.",
-		PaidAt:     updatedPayment.PaidAt,
-	})
-}
-
-// generateTxHash generates a simulated transaction hash for prototype
-func generateTxHash() string {
-	id := uuid.New()
-	return "0x" + id.String()[:40] + id.String()[24:]
+	utils.SuccessResponse(c, response)
 }
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"io"
 
 	"github.com/gin-gonic/gin"
@@ -29,7 +30,7 @@ func NewInvoiceHandler(invoiceService *services.InvoiceService, blockchainServic
 // @Security BearerAuth
 // @Accept json
 // @Produce json
-// @Param request body models.RepeatBuyerCheckRequest true "Buyer company name"
+// @Param request body models.RepeatBuyerCheckRequest true "Buyer company name and country"
 // @Success 200 {object} models.RepeatBuyerCheckResponse
 // @Router /invoices/check-repeat-buyer [post]
 func (h *InvoiceHandler) CheckRepeatBuyer(c *gin.Context) {
@@ -41,7 +42,7 @@ func (h *InvoiceHandler) CheckRepeatBuyer(c *gin.Context) {
 		return
 	}
 
-	response, err := h.invoiceService.CheckRepeatBuyer(userID, req.BuyerCompanyName)
+	response, err := h.invoiceService.CheckRepeatBuyer(userID, req.BuyerCompanyName, req.BuyerCountry)
 	if err != nil {
 		utils.HandleAppError(c, err)
 		return
@@ -417,6 +418,10 @@ func (h *InvoiceHandler) Approve(c *gin.Context) {
 
 	// Approve with grade
 	if err := h.invoiceService.ApproveWithGrade(invoiceID, &req); err != nil {
+		if errors.Is(err, services.ErrNoActiveScorecard) {
+			utils.ConflictError(c, err.Error())
+			return
+		}
 		utils.HandleAppError(c, err)
 		return
 	}
@@ -441,7 +446,7 @@ func (h *InvoiceHandler) Approve(c *gin.Context) {
 
 // GetGradeSuggestion godoc
 // @Summary Get grade suggestion for invoice (Admin) - BE-ADM-1
-// @Description Get AI-suggested grade based on risk matrix
+// @Description Grade suggested by the active grading scorecard, with each factor's contribution. Nothing is stored; the breakdown is saved when the invoice is approved.
 // @Tags Admin
 // @Security BearerAuth
 // @Produce json
@@ -457,6 +462,10 @@ func (h *InvoiceHandler) GetGradeSuggestion(c *gin.Context) {
 
 	suggestion, err := h.invoiceService.GetGradeSuggestion(invoiceID)
 	if err != nil {
+		if errors.Is(err, services.ErrNoActiveScorecard) {
+			utils.ConflictError(c, err.Error())
+			return
+		}
 		utils.HandleAppError(c, err)
 		return
 	}
@@ -482,6 +491,10 @@ func (h *InvoiceHandler) GetInvoiceReviewData(c *gin.Context) {
 
 	reviewData, err := h.invoiceService.GetInvoiceReviewData(invoiceID)
 	if err != nil {
+		if errors.Is(err, services.ErrNoActiveScorecard) {
+			utils.ConflictError(c, err.Error())
+			return
+		}
 		utils.HandleAppError(c, err)
 		return
 	}
@@ -489,6 +502,31 @@ func (h *InvoiceHandler) GetInvoiceReviewData(c *gin.Context) {
 	utils.SuccessResponse(c, reviewData)
 }
 
+// GetInvoiceCreditScore godoc
+// @Summary Get the exporter credit score of an invoice (Admin)
+// @Description Credit score, latest score changes, percentile and risk level of the mitra that submitted the invoice
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Invoice ID"
+// @Success 200 {object} models.CreditScoreResponse
+// @Router /admin/invoices/{id}/credit-score [get]
+func (h *InvoiceHandler) GetInvoiceCreditScore(c *gin.Context) {
+	invoiceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequestError(c, "Invalid invoice ID")
+		return
+	}
+
+	creditScore, err := h.invoiceService.GetExporterCreditScore(invoiceID)
+	if err != nil {
+		utils.HandleAppError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, creditScore)
+}
+
 // GetPendingInvoices godoc
 // @Summary Get pending invoices for admin review
 // @Description Get all invoices pending admin review
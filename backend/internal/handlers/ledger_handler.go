@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/vessel/backend/internal/services"
+	"github.com/vessel/backend/internal/utils"
+)
+
+type LedgerHandler struct {
+	ledgerService *services.LedgerService
+}
+
+func NewLedgerHandler(ledgerService *services.LedgerService) *LedgerHandler {
+	return &LedgerHandler{ledgerService: ledgerService}
+}
+
+// GetTrialBalance godoc
+// @Summary Get ledger trial balance (Admin Only)
+// @Description Per-account debit/credit totals with checks that every journal entry nets to zero and user balances match the ledger
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.TrialBalance
+// @Router /admin/ledger/trial-balance [get]
+func (h *LedgerHandler) GetTrialBalance(c *gin.Context) {
+	trialBalance, err := h.ledgerService.GetTrialBalance()
+	if err != nil {
+		utils.InternalServerError(c, "Failed to compute trial balance")
+		return
+	}
+
+	utils.SuccessResponse(c, trialBalance)
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/services"
+	"github.com/vessel/backend/internal/utils"
+)
+
+type OnchainEventHandler struct {
+	indexerService *services.ContractIndexerService
+}
+
+func NewOnchainEventHandler(indexerService *services.ContractIndexerService) *OnchainEventHandler {
+	return &OnchainEventHandler{indexerService: indexerService}
+}
+
+// GetPoolEvents godoc
+// @Summary Get a pool's on-chain events
+// @Description InvoiceNFT and InvoicePool events for the pool's invoice token, as indexed from the chain. Each event carries its tx hash, block and log index so it can be checked on a block explorer.
+// @Tags Funding
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Pool ID"
+// @Success 200 {object} models.PoolOnchainEventsResponse
+// @Router /pools/{id}/onchain-events [get]
+func (h *OnchainEventHandler) GetPoolEvents(c *gin.Context) {
+	poolID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequestError(c, "Invalid pool ID")
+		return
+	}
+
+	response, err := h.indexerService.GetPoolEvents(poolID)
+	if err != nil {
+		if errors.Is(err, services.ErrPoolNotFound) {
+			utils.NotFoundError(c, err.Error())
+			return
+		}
+		utils.InternalServerError(c, "Failed to get on-chain events")
+		return
+	}
+
+	utils.SuccessResponse(c, response)
+}
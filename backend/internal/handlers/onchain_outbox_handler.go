@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/services"
+	"github.com/vessel/backend/internal/utils"
+)
+
+type OnchainOutboxHandler struct {
+	outboxService *services.OnchainOutboxService
+}
+
+func NewOnchainOutboxHandler(outboxService *services.OnchainOutboxService) *OnchainOutboxHandler {
+	return &OnchainOutboxHandler{outboxService: outboxService}
+}
+
+// ListEntries godoc
+// @Summary List on-chain outbox entries (Admin Only)
+// @Description Queued InvoicePool writes. Defaults to stuck entries: not yet on-chain after stuck_minutes, including failed ones
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "pending, processing, submitted, confirmed, failed, cancelled or stuck (default)"
+// @Param stuck_minutes query int false "Age in minutes after which an entry counts as stuck (default 15)"
+// @Param page query int false "Page number"
+// @Param per_page query int false "Items per page"
+// @Success 200 {object} models.OnchainOutboxListResponse
+// @Router /admin/onchain-outbox [get]
+func (h *OnchainOutboxHandler) ListEntries(c *gin.Context) {
+	var filter models.OnchainOutboxFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		utils.BadRequestError(c, "Invalid query parameters")
+		return
+	}
+
+	response, err := h.outboxService.ListEntries(&filter)
+	if err != nil {
+		utils.HandleAppError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, response)
+}
+
+// RetryEntry godoc
+// @Summary Retry a failed on-chain outbox entry (Admin Only)
+// @Description Puts a failed entry back in the queue with a fresh attempt budget
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Outbox entry ID"
+// @Success 200 {object} models.OnchainOutboxEntry
+// @Router /admin/onchain-outbox/{id}/retry [post]
+func (h *OnchainOutboxHandler) RetryEntry(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequestError(c, "Invalid outbox entry ID")
+		return
+	}
+
+	entry, err := h.outboxService.RetryEntry(id)
+	if err != nil {
+		if errors.Is(err, services.ErrOutboxEntryNotFound) {
+			utils.NotFoundError(c, "Outbox entry not found")
+			return
+		}
+		utils.HandleAppError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, entry)
+}
+
+// CancelEntry godoc
+// @Summary Cancel a failed on-chain outbox entry (Admin Only)
+// @Description Skips a failed entry that cannot succeed so later entries of the same invoice are submitted again
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Outbox entry ID"
+// @Param request body models.CancelOutboxEntryRequest true "Reason for skipping the entry"
+// @Success 200 {object} models.OnchainOutboxEntry
+// @Router /admin/onchain-outbox/{id}/cancel [post]
+func (h *OnchainOutboxHandler) CancelEntry(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequestError(c, "Invalid outbox entry ID")
+		return
+	}
+
+	var req models.CancelOutboxEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestError(c, err.Error())
+		return
+	}
+
+	entry, err := h.outboxService.CancelEntry(id, req.Reason)
+	if err != nil {
+		if errors.Is(err, services.ErrOutboxEntryNotFound) {
+			utils.NotFoundError(c, "Outbox entry not found")
+			return
+		}
+		utils.HandleAppError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, entry)
+}
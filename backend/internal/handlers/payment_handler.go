@@ -6,6 +6,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/money"
 	"github.com/vessel/backend/internal/repository"
 	"github.com/vessel/backend/internal/services"
 	"github.com/vessel/backend/internal/utils"
@@ -24,8 +25,8 @@ func NewPaymentHandler(paymentService *services.PaymentService, txRepo *reposito
 }
 
 // Deposit godoc
-// @Summary Deposit funds to user balance (PROTOTYPE)
-// @Description Simulate depositing funds. This is a prototype - no real payment gateway integration.
+// @Summary Deposit funds to user balance
+// @Description Create a gateway payment link for a deposit. The balance is credited when the gateway confirms payment (immediately with the simulator's auto-settle).
 // @Tags Payments
 // @Security BearerAuth
 // @Accept json
@@ -42,7 +43,7 @@ func (h *PaymentHandler) Deposit(c *gin.Context) {
 		return
 	}
 
-	response, err := h.paymentService.SimulateDeposit(userID, req.Amount)
+	response, err := h.paymentService.Deposit(userID, req.Amount)
 	if err != nil {
 		utils.HandleAppError(c, err)
 		return
@@ -133,7 +134,7 @@ func (h *PaymentHandler) AdminGrantBalance(c *gin.Context) {
 
 // PlatformRevenueResponse represents the platform revenue data for admin dashboard
 type PlatformRevenueResponse struct {
-	TotalRevenue     float64              `json:"total_revenue"`
+	TotalRevenue     money.Amount         `json:"total_revenue"`
 	Currency         string               `json:"currency"`
 	FeePercentage    float64              `json:"fee_percentage"`
 	TransactionCount int                  `json:"transaction_count"`
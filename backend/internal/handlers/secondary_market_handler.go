@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/repository"
+	"github.com/vessel/backend/internal/services"
+	"github.com/vessel/backend/internal/utils"
+)
+
+type SecondaryMarketHandler struct {
+	secondaryService *services.SecondaryMarketService
+}
+
+func NewSecondaryMarketHandler(secondaryService *services.SecondaryMarketService) *SecondaryMarketHandler {
+	return &SecondaryMarketHandler{secondaryService: secondaryService}
+}
+
+// ListListings godoc
+// @Summary List positions for sale
+// @Description Open secondary market listings whose position is still active and whose pool has not been settled, newest first. expected_return is the share of the position's expected return that comes with the principal.
+// @Tags Secondary Market
+// @Security BearerAuth
+// @Produce json
+// @Param pool_id query string false "Pool ID"
+// @Param tranche query string false "Tranche name"
+// @Param page query int false "Page number"
+// @Param per_page query int false "Items per page"
+// @Success 200 {object} models.SecondaryListingListResponse
+// @Router /secondary-market/listings [get]
+func (h *SecondaryMarketHandler) ListListings(c *gin.Context) {
+	var filter models.SecondaryListingFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		utils.BadRequestError(c, err.Error())
+		return
+	}
+	params := models.PaginationParams{Page: filter.Page, PerPage: filter.PerPage}
+	params.Normalize()
+
+	var poolID *uuid.UUID
+	if filter.PoolID != "" {
+		id, err := uuid.Parse(filter.PoolID)
+		if err != nil {
+			utils.BadRequestError(c, "Invalid pool ID")
+			return
+		}
+		poolID = &id
+	}
+
+	response, err := h.secondaryService.ListOpen(poolID, filter.Tranche, params.Page, params.PerPage)
+	if err != nil {
+		utils.InternalServerError(c, "Failed to list listings")
+		return
+	}
+
+	utils.SuccessResponse(c, response)
+}
+
+// ListMyListings godoc
+// @Summary List my listings
+// @Description The investor's own secondary market listings in every status
+// @Tags Secondary Market
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number"
+// @Param per_page query int false "Items per page"
+// @Success 200 {object} models.SecondaryListingListResponse
+// @Router /secondary-market/my-listings [get]
+func (h *SecondaryMarketHandler) ListMyListings(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var params models.PaginationParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		params = models.PaginationParams{Page: 1, PerPage: 10}
+	}
+	params.Normalize()
+
+	response, err := h.secondaryService.ListBySeller(userID, params.Page, params.PerPage)
+	if err != nil {
+		utils.InternalServerError(c, "Failed to list listings")
+		return
+	}
+
+	utils.SuccessResponse(c, response)
+}
+
+// CreateListing godoc
+// @Summary List a position for sale
+// @Description Offers all or part of an active investment in a filled or disbursed pool at a price. Principal defaults to the whole investment; one open listing per investment.
+// @Tags Secondary Market
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body models.CreateListingRequest true "Investment, principal and price"
+// @Success 201 {object} models.SecondaryListing
+// @Router /secondary-market/listings [post]
+func (h *SecondaryMarketHandler) CreateListing(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req models.CreateListingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestError(c, err.Error())
+		return
+	}
+
+	listing, err := h.secondaryService.CreateListing(userID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrPositionNotFound), errors.Is(err, services.ErrPoolNotFound):
+			utils.NotFoundError(c, err.Error())
+		case errors.Is(err, services.ErrPositionNotTransferable), errors.Is(err, services.ErrPositionAlreadyListed):
+			utils.ConflictError(c, err.Error())
+		case errors.Is(err, services.ErrListingPrincipal):
+			utils.BadRequestError(c, err.Error())
+		default:
+			utils.InternalServerError(c, "Failed to create listing")
+		}
+		return
+	}
+
+	utils.CreatedResponse(c, listing)
+}
+
+// CancelListing godoc
+// @Summary Cancel a listing
+// @Description Withdraws the investor's open listing
+// @Tags Secondary Market
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Listing ID"
+// @Success 200 {object} map[string]string
+// @Router /secondary-market/listings/{id}/cancel [post]
+func (h *SecondaryMarketHandler) CancelListing(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	listingID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequestError(c, "Invalid listing ID")
+		return
+	}
+
+	if err := h.secondaryService.CancelListing(userID, listingID); err != nil {
+		switch {
+		case errors.Is(err, services.ErrListingNotFound):
+			utils.NotFoundError(c, err.Error())
+		case errors.Is(err, services.ErrListingNotOpen):
+			utils.ConflictError(c, err.Error())
+		default:
+			utils.InternalServerError(c, "Failed to cancel listing")
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"message": "Listing cancelled"})
+}
+
+// BuyListing godoc
+// @Summary Buy a listed position
+// @Description Pays the price from the buyer's balance to the seller and moves the listed principal to the buyer, with its share of the expected return.
+// @Description A partial listing splits the investment. Junior tranches require a completed risk questionnaire that unlocked them,
+// @Description and catalyst_consents where the tranche takes risk statements. The new holder is recorded on-chain.
+// @Tags Secondary Market
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Listing ID"
+// @Param request body models.BuyListingRequest true "T&C and risk consents"
+// @Success 201 {object} models.SecondaryPurchaseResponse
+// @Router /secondary-market/listings/{id}/buy [post]
+func (h *SecondaryMarketHandler) BuyListing(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	listingID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequestError(c, "Invalid listing ID")
+		return
+	}
+
+	var req models.BuyListingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestError(c, err.Error())
+		return
+	}
+
+	response, err := h.secondaryService.Buy(userID, listingID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrListingNotFound), errors.Is(err, services.ErrPoolNotFound):
+			utils.NotFoundError(c, err.Error())
+		case errors.Is(err, services.ErrListingNotOpen), errors.Is(err, services.ErrPositionNotTransferable):
+			utils.ConflictError(c, err.Error())
+		case errors.Is(err, services.ErrTrancheRiskConsent), errors.Is(err, services.ErrTrancheRiskProfile):
+			utils.ForbiddenError(c, err.Error())
+		case errors.Is(err, services.ErrListingOwnPosition), errors.Is(err, services.ErrSecondaryTncRequired),
+			errors.Is(err, services.ErrTrancheNotFound), errors.Is(err, services.ErrTrancheBelowMinimum),
+			errors.Is(err, services.ErrTrancheAboveMaximum):
+			utils.BadRequestError(c, err.Error())
+		case errors.Is(err, repository.ErrInsufficientBalance):
+			utils.BadRequestError(c, "Insufficient balance")
+		default:
+			utils.InternalServerError(c, "Failed to buy listing")
+		}
+		return
+	}
+
+	utils.CreatedResponse(c, response)
+}
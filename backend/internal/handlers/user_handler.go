@@ -573,48 +573,6 @@ func (h *UserHandler) GetSupportedBanks(c *gin.Context) {
 	c.JSON(200, banks)
 }
 
-// UpdateWallet godoc
-// @Summary Update user wallet address
-// @Description Update the authenticated user's wallet address for blockchain transactions
-// @Tags User
-// @Security BearerAuth
-// @Accept json
-// @Produce json
-// @Param request body map[string]string true "Wallet address"
-// @Success 200 {object} map[string]string
-// @Router /user/wallet [put]
-func (h *UserHandler) UpdateWallet(c *gin.Context) {
-	userID := c.MustGet("user_id").(uuid.UUID)
-
-	var req struct {
-		WalletAddress string `json:"wallet_address" binding:"required"`
-	}
-
-	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.BadRequestError(c, err.Error())
-		return
-	}
-
-	// In a real implementation, we would validate the address format
-	if len(req.WalletAddress) < 40 {
-		utils.BadRequestError(c, "Invalid wallet address")
-		return
-	}
-
-	// Update user wallet in repository
-	// Note: We need to add UpdateWallet method to repository interface first
-	// For now, we'll assume it exists or implement it
-	if err := h.userRepo.UpdateWalletAddress(userID, req.WalletAddress); err != nil {
-		utils.InternalServerError(c, "Failed to update wallet address")
-		return
-	}
-
-	c.JSON(200, gin.H{
-		"message":        "Wallet address updated successfully",
-		"wallet_address": req.WalletAddress,
-	})
-}
-
 // UploadDocument godoc
 // @Summary Upload user document (KTP/Selfie)
 // @Description Upload a document (KTP or Selfie) to IPFS via Pinata
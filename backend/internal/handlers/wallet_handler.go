@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/services"
+	"github.com/vessel/backend/internal/utils"
+)
+
+type WalletHandler struct {
+	walletService *services.WalletService
+}
+
+func NewWalletHandler(walletService *services.WalletService) *WalletHandler {
+	return &WalletHandler{walletService: walletService}
+}
+
+// RequestChallenge godoc
+// @Summary Request a wallet ownership challenge
+// @Description Issue a one-time nonce for the wallet. Sign `message` with personal_sign (eip191) or `typed_data` with eth_signTypedData_v4 (eip712), then submit it to PUT /user/wallet before it expires.
+// @Tags User
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body models.WalletChallengeRequest true "Wallet address and signing method"
+// @Success 200 {object} models.WalletChallengeResponse
+// @Router /user/wallet/challenge [post]
+func (h *WalletHandler) RequestChallenge(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req models.WalletChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestError(c, err.Error())
+		return
+	}
+
+	response, err := h.walletService.IssueChallenge(userID, &req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidWalletAddress) {
+			utils.BadRequestError(c, err.Error())
+			return
+		}
+		utils.InternalServerError(c, "Failed to create wallet challenge")
+		return
+	}
+
+	utils.SuccessResponse(c, response)
+}
+
+// UpdateWallet godoc
+// @Summary Link a wallet with a signed challenge
+// @Description Verify the signed challenge and make the recovered wallet the user's wallet for on-chain records. The previous wallet is kept in the history.
+// @Tags User
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body models.LinkWalletRequest true "Challenge ID and signature"
+// @Success 200 {object} models.WalletLink
+// @Router /user/wallet [put]
+func (h *WalletHandler) UpdateWallet(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req models.LinkWalletRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestError(c, err.Error())
+		return
+	}
+
+	link, err := h.walletService.LinkWallet(userID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrWalletChallengeNotFound):
+			utils.NotFoundError(c, err.Error())
+		case errors.Is(err, services.ErrWalletChallengeExpired),
+			errors.Is(err, services.ErrInvalidSignature),
+			errors.Is(err, services.ErrWalletSignatureMismatch):
+			utils.BadRequestError(c, err.Error())
+		case errors.Is(err, services.ErrWalletInUse):
+			utils.ConflictError(c, err.Error())
+		default:
+			utils.InternalServerError(c, "Failed to link wallet")
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, link)
+}
+
+// GetWalletHistory godoc
+// @Summary List linked wallets
+// @Description Every wallet the user has proven ownership of, current one first, with the signature that linked it
+// @Tags User
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.WalletLink
+// @Router /user/wallet/history [get]
+func (h *WalletHandler) GetWalletHistory(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	links, err := h.walletService.GetWalletHistory(userID)
+	if err != nil {
+		utils.InternalServerError(c, "Failed to get wallet history")
+		return
+	}
+
+	utils.SuccessResponse(c, links)
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vessel/backend/internal/services"
+	"github.com/vessel/backend/internal/utils"
+)
+
+// WebhookSignatureHeader carries hex(HMAC-SHA256(secret, raw body))
+const WebhookSignatureHeader = "X-Webhook-Signature"
+
+type WebhookHandler struct {
+	gatewayService *services.PaymentGatewayService
+}
+
+func NewWebhookHandler(gatewayService *services.PaymentGatewayService) *WebhookHandler {
+	return &WebhookHandler{gatewayService: gatewayService}
+}
+
+// PaymentWebhook godoc
+// @Summary Payment provider webhook
+// @Description Receives signed payment callbacks. The raw body must be signed with HMAC-SHA256 in the X-Webhook-Signature header. Redelivered event IDs are acknowledged without being processed again; failures return 5xx so the provider retries.
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param provider path string true "Payment provider (e.g. simulator)"
+// @Success 200 {object} map[string]interface{}
+// @Router /webhooks/payments/{provider} [post]
+func (h *WebhookHandler) PaymentWebhook(c *gin.Context) {
+	provider := c.Param("provider")
+
+	payload, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		utils.BadRequestError(c, "Failed to read request body")
+		return
+	}
+
+	err = h.gatewayService.HandleWebhook(provider, payload, c.GetHeader(WebhookSignatureHeader))
+	switch {
+	case err == nil:
+		utils.SuccessResponse(c, gin.H{"received": true})
+	case errors.Is(err, services.ErrDuplicateWebhookEvent):
+		utils.SuccessResponse(c, gin.H{"received": true, "duplicate": true})
+	case errors.Is(err, services.ErrUnknownPaymentProvider):
+		utils.NotFoundError(c, "Unknown payment provider")
+	case errors.Is(err, services.ErrInvalidWebhookSignature):
+		utils.UnauthorizedError(c, "Invalid webhook signature")
+	default:
+		fmt.Printf("[WEBHOOK] %s payment webhook failed: %v\n", provider, err)
+		utils.InternalServerError(c, "Failed to process webhook")
+	}
+}
@@ -0,0 +1,60 @@
+// Package iso3166 resolves country codes and names to ISO 3166-1 countries.
+package iso3166
+
+import "strings"
+
+// Country is an ISO 3166-1 country
+type Country struct {
+	Alpha2 string `json:"alpha2"`
+	Alpha3 string `json:"alpha3"`
+	Name   string `json:"name"`
+}
+
+var (
+	byAlpha2 = make(map[string]Country, len(countries))
+	byAlpha3 = make(map[string]Country, len(countries))
+	byName   = make(map[string]Country, len(countries)+len(aliases))
+)
+
+func init() {
+	for _, c := range countries {
+		byAlpha2[c.Alpha2] = c
+		byAlpha3[c.Alpha3] = c
+		byName[nameKey(c.Name)] = c
+	}
+	for alias, alpha3 := range aliases {
+		byName[nameKey(alias)] = byAlpha3[alpha3]
+	}
+}
+
+// nameKey folds case and spacing so "south  Korea" finds "South Korea"
+func nameKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// Lookup finds a country by its alpha-2 or alpha-3 code or its English name,
+// ignoring case and surrounding spaces
+func Lookup(s string) (Country, bool) {
+	s = strings.TrimSpace(s)
+	switch code := strings.ToUpper(s); len(code) {
+	case 2:
+		if c, ok := byAlpha2[code]; ok {
+			return c, true
+		}
+	case 3:
+		if c, ok := byAlpha3[code]; ok {
+			return c, true
+		}
+	}
+	c, ok := byName[nameKey(s)]
+	return c, ok
+}
+
+// Flag is the emoji flag of the country, built from its alpha-2 code
+func (c Country) Flag() string {
+	var flag strings.Builder
+	for _, r := range c.Alpha2 {
+		flag.WriteRune(0x1F1E6 + r - 'A')
+	}
+	return flag.String()
+}
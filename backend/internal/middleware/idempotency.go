@@ -0,0 +1,251 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/repository"
+	"github.com/vessel/backend/internal/utils"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	idempotencyRedisKeyPrefix = "idempotency"
+)
+
+// IdempotencyMiddleware makes money-moving endpoints safe to retry. Requests that
+// carry an Idempotency-Key header are executed once per key; retries with the same
+// body replay the stored response and reuse with a different body is rejected.
+// Postgres is the source of truth, Redis (when available) caches completed responses.
+type IdempotencyMiddleware struct {
+	repo   repository.IdempotencyRepositoryInterface
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewIdempotencyMiddleware creates a new IdempotencyMiddleware; client may be nil
+func NewIdempotencyMiddleware(repo repository.IdempotencyRepositoryInterface, client *redis.Client, ttl time.Duration) *IdempotencyMiddleware {
+	return &IdempotencyMiddleware{
+		repo:   repo,
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+// cachedResponse is the Redis copy of a completed idempotency record
+type cachedResponse struct {
+	RequestHash string `json:"request_hash"`
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
+
+// responseRecorder tees the handler's response body so it can be stored
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (m *IdempotencyMiddleware) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			utils.BadRequestError(c, fmt.Sprintf("%s must be at most %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength))
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			utils.BadRequestError(c, "Failed to read request body")
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		scope := publicScope(c)
+		if userID, exists := c.Get("user_id"); exists {
+			scope = userID.(uuid.UUID).String()
+		}
+		requestHash := fingerprint(c.Request.Method, c.Request.URL.Path, body)
+		cacheKey := fmt.Sprintf("%s:%s:%s", idempotencyRedisKeyPrefix, scope, key)
+
+		// Fast path: completed responses cached in Redis
+		if cached := m.getCached(cacheKey); cached != nil {
+			if cached.RequestHash != requestHash {
+				rejectKeyReuse(c)
+				return
+			}
+			replay(c, cached.Status, cached.ContentType, cached.Body)
+			return
+		}
+
+		record := &models.IdempotencyKey{
+			Scope:       scope,
+			Key:         key,
+			Method:      c.Request.Method,
+			Path:        c.Request.URL.Path,
+			RequestHash: requestHash,
+			ExpiresAt:   time.Now().Add(m.ttl),
+		}
+		existing, err := m.repo.Reserve(record)
+		if err != nil {
+			utils.InternalServerError(c, "Failed to check idempotency key")
+			c.Abort()
+			return
+		}
+		if existing != nil {
+			if existing.RequestHash != requestHash {
+				rejectKeyReuse(c)
+				return
+			}
+			if existing.Status != models.IdempotencyStatusCompleted || existing.ResponseStatus == nil {
+				utils.ErrorResponse(c, http.StatusConflict, "IDEMPOTENCY_REQUEST_IN_PROGRESS", "A request with this Idempotency-Key is still being processed")
+				c.Abort()
+				return
+			}
+			contentType := ""
+			if existing.ResponseContentType != nil {
+				contentType = *existing.ResponseContentType
+			}
+			m.setCached(cacheKey, existing.ExpiresAt, &cachedResponse{
+				RequestHash: existing.RequestHash,
+				Status:      *existing.ResponseStatus,
+				ContentType: contentType,
+				Body:        existing.ResponseBody,
+			})
+			replay(c, *existing.ResponseStatus, contentType, existing.ResponseBody)
+			return
+		}
+
+		// A panicking handler produced no response; free the key before re-panicking
+		defer func() {
+			if r := recover(); r != nil {
+				m.repo.Release(record.ID)
+				panic(r)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// Server errors are not replayed: release the key so the client can retry
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			if err := m.repo.Release(record.ID); err != nil {
+				fmt.Printf("[IDEMPOTENCY] Failed to release key %s: %v\n", key, err)
+			}
+			return
+		}
+
+		contentType := recorder.Header().Get("Content-Type")
+		responseBody := recorder.body.Bytes()
+		if err := m.repo.Complete(record.ID, status, contentType, responseBody); err != nil {
+			fmt.Printf("[IDEMPOTENCY] Failed to store response for key %s: %v\n", key, err)
+			return
+		}
+		m.setCached(cacheKey, record.ExpiresAt, &cachedResponse{
+			RequestHash: requestHash,
+			Status:      status,
+			ContentType: contentType,
+			Body:        responseBody,
+		})
+	}
+}
+
+func (m *IdempotencyMiddleware) getCached(cacheKey string) *cachedResponse {
+	if m.client == nil {
+		return nil
+	}
+	data, err := m.client.Get(context.Background(), cacheKey).Bytes()
+	if err != nil {
+		return nil
+	}
+	var cached cachedResponse
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil
+	}
+	return &cached
+}
+
+func (m *IdempotencyMiddleware) setCached(cacheKey string, expiresAt time.Time, cached *cachedResponse) {
+	if m.client == nil {
+		return
+	}
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return
+	}
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return
+	}
+	m.client.Set(context.Background(), cacheKey, data, ttl)
+}
+
+// publicScope keys unauthenticated requests by route and the resources its path
+// names (the payment on the public pay endpoint). A client retrying from a new
+// network still finds its key, and requests for different payments never share one.
+func publicScope(c *gin.Context) string {
+	h := sha256.New()
+	h.Write([]byte("public"))
+	h.Write([]byte{'\n'})
+	h.Write([]byte(c.Request.Method))
+	h.Write([]byte{'\n'})
+	h.Write([]byte(c.FullPath()))
+	for _, param := range c.Params {
+		h.Write([]byte{'\n'})
+		h.Write([]byte(param.Key + "=" + param.Value))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// fingerprint identifies a request by method, path and exact body
+func fingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{'\n'})
+	h.Write([]byte(path))
+	h.Write([]byte{'\n'})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func replay(c *gin.Context, status int, contentType string, body []byte) {
+	c.Header(IdempotentReplayedHeader, "true")
+	c.Data(status, contentType, body)
+	c.Abort()
+}
+
+func rejectKeyReuse(c *gin.Context) {
+	utils.ErrorResponse(c, http.StatusUnprocessableEntity, "IDEMPOTENCY_KEY_REUSED", "Idempotency-Key was already used for a different request")
+	c.Abort()
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/money"
+)
+
+// OutcomeFilter limits analytics to invoices due within [DueFrom, DueTo]; a
+// missing bound is open
+type OutcomeFilter struct {
+	DueFrom *time.Time `json:"due_from,omitempty" form:"due_from" time_format:"2006-01-02"`
+	DueTo   *time.Time `json:"due_to,omitempty" form:"due_to" time_format:"2006-01-02"`
+}
+
+// InvoiceOutcome is how a funded invoice ended: its pool closed repaid or it
+// was declared defaulted. Invoices still outstanding have no outcome yet.
+type InvoiceOutcome struct {
+	InvoiceID    uuid.UUID
+	ExporterID   uuid.UUID
+	PoolID       uuid.UUID
+	Grade        *string
+	BuyerCountry string
+	DueDate      time.Time
+	CreatedAt    time.Time
+	Defaulted    bool
+	SettledAt    *time.Time // When the pool closed, for repaid invoices
+	Tranches     []TrancheOutcome
+}
+
+// ReplayInvoice is a past invoice, with its documents, and the exporter history
+// and credit score it had when it was created, for replaying it through a scorecard
+type ReplayInvoice struct {
+	Invoice        Invoice
+	PriorInvoices  int  // Exporter's other invoices created before this one
+	RepaidInvoices int  // Those of them whose pool had closed repaid by then
+	CreditScore    *int // Exporter's score just before; nil when it had not changed by then
+}
+
+// TrancheOutcome is what the investors of one tranche of a pool put in and got back
+type TrancheOutcome struct {
+	Tranche   string
+	Invested  money.Amount
+	Returned  money.Amount // Repayments, late charges and recoveries
+	Exposure  money.Amount // Principal outstanding when the default was declared
+	Loss      money.Amount // Principal not recovered
+	Defaulted bool
+}
+
+// OutcomeStats are the realized outcomes of a group of invoices (a grade, a
+// country tier or a tranche). Rates are percentages.
+type OutcomeStats struct {
+	Group            string       `json:"group"`
+	Invoices         int          `json:"invoices"`
+	Defaults         int          `json:"defaults"`
+	DefaultRate      float64      `json:"default_rate"`
+	Exposure         money.Amount `json:"exposure_at_default"`
+	Loss             money.Amount `json:"loss"`
+	LossGivenDefault float64      `json:"loss_given_default"` // Share of the exposure at default that was lost
+	AvgDaysLate      float64      `json:"avg_days_late"`      // Over repaid invoices, from the due date to the pool closing
+	Invested         money.Amount `json:"invested"`
+	Returned         money.Amount `json:"returned"`
+	RealizedYield    float64      `json:"realized_yield"` // (returned - invested) / invested, not annualized
+}
+
+// LossRateReport compares realized outcomes by grade, buyer country tier and tranche
+type LossRateReport struct {
+	Filter        OutcomeFilter  `json:"filter"`
+	Overall       OutcomeStats   `json:"overall"`
+	ByGrade       []OutcomeStats `json:"by_grade"`
+	ByCountryTier []OutcomeStats `json:"by_country_tier"` // Tiers as they are now, not when the invoice was graded
+	ByTranche     []OutcomeStats `json:"by_tranche"`
+	GeneratedAt   time.Time      `json:"generated_at"`
+}
+
+// GradeMigration counts the invoices that move from one grade to another
+// under a candidate scorecard
+type GradeMigration struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Invoices int    `json:"invoices"`
+	Defaults int    `json:"defaults"`
+}
+
+// BacktestReport replays invoices with a known outcome through a candidate
+// scorecard and compares outcomes by the grade they were given with outcomes
+// by the grade the candidate gives them. A grading is ordered when default
+// rates do not fall from A to C.
+type BacktestReport struct {
+	ScorecardID      *uuid.UUID       `json:"scorecard_id,omitempty"` // Unset for a candidate that is not stored
+	ScorecardVersion int              `json:"scorecard_version,omitempty"`
+	ScorecardName    string           `json:"scorecard_name"`
+	Filter           OutcomeFilter    `json:"filter"`
+	Invoices         int              `json:"invoices"`
+	Regraded         int              `json:"regraded"` // Invoices whose grade changes
+	Current          []OutcomeStats   `json:"current"`
+	Candidate        []OutcomeStats   `json:"candidate"`
+	CurrentOrdered   bool             `json:"current_ordered"`
+	CandidateOrdered bool             `json:"candidate_ordered"`
+	Migrations       []GradeMigration `json:"migrations"`
+	GeneratedAt      time.Time        `json:"generated_at"`
+}
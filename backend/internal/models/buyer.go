@@ -0,0 +1,97 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/money"
+)
+
+// Buyer is an importer in the buyer registry. A buyer is identified by its
+// normalized name and country, so spellings that differ only in case,
+// punctuation or legal form ("PT ABC", "ABC Ltd.") are the same buyer.
+type Buyer struct {
+	ID             uuid.UUID  `json:"id"`
+	Name           string     `json:"name"`
+	NormalizedName string     `json:"normalized_name"`
+	Country        string     `json:"country"`               // ISO 3166-1 alpha-3
+	MergedInto     *uuid.UUID `json:"merged_into,omitempty"` // Set once the buyer is merged into another
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	// Relations
+	Aliases  []BuyerAlias         `json:"aliases,omitempty"`
+	Contacts []BuyerContact       `json:"contacts,omitempty"`
+	History  *BuyerPaymentHistory `json:"payment_history,omitempty"`
+}
+
+// BuyerAlias is another name the buyer trades under
+type BuyerAlias struct {
+	ID              uuid.UUID `json:"id"`
+	BuyerID         uuid.UUID `json:"buyer_id"`
+	Alias           string    `json:"alias"`
+	NormalizedAlias string    `json:"normalized_alias"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// BuyerContact is an email address invoices and payment links went to
+type BuyerContact struct {
+	ID        uuid.UUID `json:"id"`
+	BuyerID   uuid.UUID `json:"buyer_id"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BuyerPaymentHistory is how a buyer has paid, across every mitra it bought from
+type BuyerPaymentHistory struct {
+	Invoices     int          `json:"invoices"` // Submitted invoices, drafts and rejections excluded
+	Outstanding  int          `json:"outstanding"`
+	Repaid       int          `json:"repaid"`
+	Defaulted    int          `json:"defaulted"`
+	Mitras       int          `json:"mitras"`
+	Volume       money.Amount `json:"volume"`
+	RepaidVolume money.Amount `json:"repaid_volume"`
+	RepaidLate   int          `json:"repaid_late"`   // Pool closed after the due date
+	AvgDaysLate  float64      `json:"avg_days_late"` // Over repaid invoices, from the due date to the pool closing
+	LastRepaidAt *time.Time   `json:"last_repaid_at,omitempty"`
+}
+
+// BuyerDuplicate is a pair of buyers in the same country whose names are
+// similar enough that they may be the same importer
+type BuyerDuplicate struct {
+	Buyer      Buyer   `json:"buyer"`
+	Candidate  Buyer   `json:"candidate"`
+	Similarity float64 `json:"similarity"` // 0-1
+}
+
+// BuyerFilter lists buyers by name or alias and country
+type BuyerFilter struct {
+	Search  string `form:"search"`
+	Country string `form:"country"`
+	Page    int    `form:"page"`
+	PerPage int    `form:"per_page"`
+}
+
+// BuyerListResponse is a page of buyers
+type BuyerListResponse struct {
+	Buyers     []Buyer `json:"buyers"`
+	Total      int     `json:"total"`
+	Page       int     `json:"page"`
+	PerPage    int     `json:"per_page"`
+	TotalPages int     `json:"total_pages"`
+}
+
+// AddBuyerAliasRequest records another name of a buyer
+type AddBuyerAliasRequest struct {
+	Alias string `json:"alias" binding:"required,max=255"`
+}
+
+// AddBuyerContactRequest records a contact email of a buyer
+type AddBuyerContactRequest struct {
+	Email string `json:"email" binding:"required,email,max=255"`
+}
+
+// MergeBuyerRequest merges a duplicate buyer into the buyer that is kept
+type MergeBuyerRequest struct {
+	IntoID uuid.UUID `json:"into_id" binding:"required"`
+}
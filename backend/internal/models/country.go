@@ -0,0 +1,79 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultCountryTier is the risk tier of a buyer country without a country tier
+const DefaultCountryTier = 2
+
+// CountryTier rates the payment risk of a buyer country
+type CountryTier struct {
+	CountryCode string    `json:"country_code"` // ISO 3166-1 alpha-3
+	CountryName string    `json:"country_name"`
+	Tier        int       `json:"tier"` // 1 (low risk) to 3 (high risk)
+	FlagEmoji   *string   `json:"flag_emoji,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// CountryRiskLevel names a country risk tier: low, medium or high
+func CountryRiskLevel(tier int) string {
+	switch tier {
+	case 1:
+		return "low"
+	case 3:
+		return "high"
+	}
+	return "medium"
+}
+
+// CountryTierAction is what a change did to a country tier
+type CountryTierAction string
+
+const (
+	CountryTierCreated CountryTierAction = "create"
+	CountryTierUpdated CountryTierAction = "update"
+	CountryTierDeleted CountryTierAction = "delete"
+)
+
+// CountryTierChange records one admin change to a country tier
+type CountryTierChange struct {
+	ID          uuid.UUID         `json:"id"`
+	CountryCode string            `json:"country_code"`
+	Action      CountryTierAction `json:"action"`
+	OldData     *CountryTier      `json:"old_data,omitempty"`
+	NewData     *CountryTier      `json:"new_data,omitempty"`
+	Reason      *string           `json:"reason,omitempty"`
+	ChangedBy   *uuid.UUID        `json:"changed_by,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+// CreateCountryTierRequest rates a country. The code may be ISO alpha-2,
+// alpha-3 or the English name; name and flag default to the ISO country's.
+type CreateCountryTierRequest struct {
+	CountryCode string  `json:"country_code" binding:"required"`
+	CountryName string  `json:"country_name" binding:"max=100"`
+	Tier        int     `json:"tier" binding:"required,oneof=1 2 3"`
+	FlagEmoji   *string `json:"flag_emoji,omitempty" binding:"omitempty,max=10"`
+	Reason      *string `json:"reason,omitempty"`
+}
+
+// UpdateCountryTierRequest changes the fields that are set
+type UpdateCountryTierRequest struct {
+	CountryName *string `json:"country_name,omitempty" binding:"omitempty,min=1,max=100"`
+	Tier        *int    `json:"tier,omitempty" binding:"omitempty,oneof=1 2 3"`
+	FlagEmoji   *string `json:"flag_emoji,omitempty" binding:"omitempty,max=10"`
+	Reason      *string `json:"reason,omitempty"`
+}
+
+// CountryTierHistoryResponse is a page of country tier changes
+type CountryTierHistoryResponse struct {
+	Changes    []CountryTierChange `json:"changes"`
+	Total      int                 `json:"total"`
+	Page       int                 `json:"page"`
+	PerPage    int                 `json:"per_page"`
+	TotalPages int                 `json:"total_pages"`
+}
@@ -1,41 +1,59 @@
 package models
 
 import (
+	"math"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/money"
 )
 
 type CreditScore struct {
-	ID                 uuid.UUID `json:"id"`
-	UserID             uuid.UUID `json:"user_id"`
-	Score              int       `json:"score"`
-	TotalInvoices      int       `json:"total_invoices"`
-	SuccessfulInvoices int       `json:"successful_invoices"`
-	DefaultedInvoices  int       `json:"defaulted_invoices"`
-	TotalVolume        float64   `json:"total_volume"`
-	AvgPaymentDelay    int       `json:"avg_payment_delay"`
-	LastUpdated        time.Time `json:"last_updated"`
-	CreatedAt          time.Time `json:"created_at"`
+	ID                 uuid.UUID    `json:"id"`
+	UserID             uuid.UUID    `json:"user_id"`
+	Score              int          `json:"score"`
+	TotalInvoices      int          `json:"total_invoices"`
+	SuccessfulInvoices int          `json:"successful_invoices"`
+	DefaultedInvoices  int          `json:"defaulted_invoices"`
+	TotalVolume        money.Amount `json:"total_volume"`
+	AvgPaymentDelay    int          `json:"avg_payment_delay"`
+	LastUpdated        time.Time    `json:"last_updated"`
+	CreatedAt          time.Time    `json:"created_at"`
 }
 
 type CreditScoreHistory struct {
-	ID            uuid.UUID  `json:"id"`
-	UserID        uuid.UUID  `json:"user_id"`
-	PreviousScore int        `json:"previous_score"`
-	NewScore      int        `json:"new_score"`
-	Reason        string     `json:"reason"`
-	InvoiceID     *uuid.UUID `json:"invoice_id,omitempty"`
-	CreatedAt     time.Time  `json:"created_at"`
+	ID            uuid.UUID    `json:"id"`
+	UserID        uuid.UUID    `json:"user_id"`
+	PreviousScore int          `json:"previous_score"`
+	NewScore      int          `json:"new_score"`
+	Reason        string       `json:"reason"`
+	Event         *CreditEvent `json:"event,omitempty"`
+	InvoiceID     *uuid.UUID   `json:"invoice_id,omitempty"`
+	CreatedAt     time.Time    `json:"created_at"`
 }
 
+// DefaultCreditScore is the score of a mitra without any repayment history
+const DefaultCreditScore = 50
+
+// CreditEvent is a repayment outcome that moves a mitra's credit score. Each
+// event counts once per invoice.
+type CreditEvent string
+
+const (
+	CreditEventRepaid      CreditEvent = "repaid"       // Settled by the due date
+	CreditEventRepaidLate  CreditEvent = "repaid_late"  // Settled after the due date
+	CreditEventLatePayment CreditEvent = "late_payment" // Past due, late charges started
+	CreditEventDefault     CreditEvent = "default"      // Declared defaulted
+)
+
 type CreditScoreResponse struct {
-	Score       CreditScore          `json:"score"`
-	History     []CreditScoreHistory `json:"history"`
-	Percentile  float64              `json:"percentile"`
-	RiskLevel   string               `json:"risk_level"`
+	Score      CreditScore          `json:"score"`
+	History    []CreditScoreHistory `json:"history"`
+	Percentile float64              `json:"percentile"`
+	RiskLevel  string               `json:"risk_level"`
 }
 
+// CalculateRiskLevel names the risk of a credit score
 func CalculateRiskLevel(score int) string {
 	switch {
 	case score >= 80:
@@ -50,3 +68,21 @@ func CalculateRiskLevel(score int) string {
 		return "high"
 	}
 }
+
+// CreditFundingLimit adjusts the advance limit of a buyer relationship (60% for
+// a new buyer, 100% for a repeat buyer) by the mitra's credit score: a good
+// record raises it, a poor one caps it.
+func CreditFundingLimit(score int, base float64) float64 {
+	switch {
+	case score >= 80:
+		return math.Min(base+20, 100)
+	case score >= 60:
+		return math.Min(base+10, 100)
+	case score >= 40:
+		return base
+	case score >= 20:
+		return math.Min(base, 50)
+	default:
+		return math.Min(base, 40)
+	}
+}
@@ -1,21 +1,23 @@
 package models
 
+import "github.com/vessel/backend/internal/money"
+
 // CurrencyConversionRequest is the request for getting locked exchange rate
 type CurrencyConversionRequest struct {
-	OriginalCurrency string  `json:"original_currency" binding:"required"` // USD, EUR, etc.
-	Amount           float64 `json:"amount" binding:"required,gt=0"`
+	OriginalCurrency string       `json:"original_currency" binding:"required"` // USD, EUR, etc.
+	Amount           money.Amount `json:"amount" binding:"required,gt=0"`
 }
 
 // CurrencyConversionResponse contains the locked exchange rate with buffer
 type CurrencyConversionResponse struct {
-	OriginalCurrency string  `json:"original_currency"`
-	OriginalAmount   float64 `json:"original_amount"`
-	TargetCurrency   string  `json:"target_currency"` // IDR
-	RealTimeRate     float64 `json:"realtime_rate"`   // Current rate before buffer
-	BufferPercentage float64 `json:"buffer_percentage"`
-	LockedRate       float64 `json:"locked_rate"`      // Rate after buffer: rate * (1 - buffer)
-	ConvertedAmount  float64 `json:"converted_amount"` // Amount in IDR
-	Microcopy        string  `json:"microcopy"`
+	OriginalCurrency string       `json:"original_currency"`
+	OriginalAmount   money.Amount `json:"original_amount"`
+	TargetCurrency   string       `json:"target_currency"` // IDR
+	RealTimeRate     float64      `json:"realtime_rate"`   // Current rate before buffer
+	BufferPercentage float64      `json:"buffer_percentage"`
+	LockedRate       float64      `json:"locked_rate"`      // Rate after buffer: rate * (1 - buffer)
+	ConvertedAmount  money.Amount `json:"converted_amount"` // Amount in IDR
+	Microcopy        string       `json:"microcopy"`
 }
 
 // SupportedCurrency represents a supported currency
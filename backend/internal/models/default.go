@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/money"
+)
+
+// DefaultStatus tracks a disbursed pool whose invoice was not repaid
+type DefaultStatus string
+
+const (
+	DefaultStatusOverdue   DefaultStatus = "overdue"   // Past due date plus grace period, waiting for an admin decision
+	DefaultStatusDeclared  DefaultStatus = "declared"  // Declared defaulted, losses allocated to investors
+	DefaultStatusRecovered DefaultStatus = "recovered" // Recoveries covered every investor claim
+)
+
+// PoolDefault is the default case of one pool. Amounts are in the pool currency.
+type PoolDefault struct {
+	ID             uuid.UUID     `json:"id"`
+	PoolID         uuid.UUID     `json:"pool_id"`
+	InvoiceID      uuid.UUID     `json:"invoice_id"`
+	Status         DefaultStatus `json:"status"`
+	Currency       string        `json:"currency"`
+	DueDate        time.Time     `json:"due_date"`
+	OverdueSince   time.Time     `json:"overdue_since"` // Due date plus grace period
+	DetectedAt     *time.Time    `json:"detected_at,omitempty"`
+	DeclaredAt     *time.Time    `json:"declared_at,omitempty"`
+	DeclaredBy     *uuid.UUID    `json:"declared_by,omitempty"`
+	Reason         *string       `json:"reason,omitempty"`
+	TotalClaim     money.Amount  `json:"total_claim"`     // Returns still owed on the defaulted investments
+	TotalRecovered money.Amount  `json:"total_recovered"` // Recoveries distributed to investors
+	TotalLoss      money.Amount  `json:"total_loss"`      // Principal not recovered
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+
+	// Relations
+	Allocations []DefaultAllocation `json:"allocations,omitempty"`
+	Recoveries  []DefaultRecovery   `json:"recoveries,omitempty"`
+}
+
+// DefaultAllocation is one investment's share of a default. Recoveries pay the
+// most senior tranche first, so losses fall on the most junior tranche first.
+type DefaultAllocation struct {
+	ID           uuid.UUID    `json:"id"`
+	DefaultID    uuid.UUID    `json:"default_id"`
+	InvestmentID uuid.UUID    `json:"investment_id"`
+	InvestorID   uuid.UUID    `json:"investor_id"`
+	Tranche      TrancheType  `json:"tranche"`
+	Principal    money.Amount `json:"principal"` // Principal not repaid when declared
+	Claim        money.Amount `json:"claim"`     // Return still owed when declared
+	Recovered    money.Amount `json:"recovered"` // Recoveries paid since the declaration
+	Loss         money.Amount `json:"loss"`      // Principal not recovered
+}
+
+// DefaultRecovery is money collected after a default and how it was paid out
+type DefaultRecovery struct {
+	ID            uuid.UUID    `json:"id"`
+	DefaultID     uuid.UUID    `json:"default_id"`
+	Amount        money.Amount `json:"amount"`
+	ToInvestors   money.Amount `json:"to_investors"`
+	ExcessToMitra money.Amount `json:"excess_to_mitra"` // Collected beyond every investor claim
+	Note          *string      `json:"note,omitempty"`
+	RecordedBy    *uuid.UUID   `json:"recorded_by,omitempty"`
+	CreatedAt     time.Time    `json:"created_at"`
+}
+
+// DeclareDefaultRequest is the admin declaration of a pool default
+type DeclareDefaultRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// RecordRecoveryRequest records money collected on a defaulted pool
+type RecordRecoveryRequest struct {
+	Amount money.Amount `json:"amount" binding:"required,gt=0"`
+	Note   string       `json:"note"`
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/money"
 )
 
 type PoolStatus string
@@ -13,9 +14,15 @@ const (
 	PoolStatusFilled    PoolStatus = "filled"
 	PoolStatusDisbursed PoolStatus = "disbursed"
 	PoolStatusClosed    PoolStatus = "closed"
+	PoolStatusExpired   PoolStatus = "expired"   // Deadline passed unfilled, investors refunded
+	PoolStatusDefaulted PoolStatus = "defaulted" // Invoice not repaid, default declared
 )
 
-// TrancheType represents the type of investment tranche
+// DefaultPoolCurrency is the currency pools are funded in (IDRX on-chain)
+const DefaultPoolCurrency = "IDR"
+
+// TrancheType is the name of a pool tranche. Pools default to a priority and a
+// catalyst tranche; admins can lay out others such as senior/mezzanine/junior.
 type TrancheType string
 
 const (
@@ -24,28 +31,31 @@ const (
 )
 
 type FundingPool struct {
-	ID            uuid.UUID  `json:"id"`
-	InvoiceID     uuid.UUID  `json:"invoice_id"`
-	TargetAmount  float64    `json:"target_amount"`
-	FundedAmount  float64    `json:"funded_amount"`
-	InvestorCount int        `json:"investor_count"`
-	Status        PoolStatus `json:"status"`
-	OpenedAt      *time.Time `json:"opened_at,omitempty"`
-	Deadline      *time.Time `json:"deadline,omitempty"` // Pool funding deadline
-	FilledAt      *time.Time `json:"filled_at,omitempty"`
-	DisbursedAt   *time.Time `json:"disbursed_at,omitempty"`
-	ClosedAt      *time.Time `json:"closed_at,omitempty"`
-	CreatedAt     time.Time  `json:"created_at"`
-	UpdatedAt     time.Time  `json:"updated_at"`
-
-	// Tranche fields
-	PriorityTarget       float64 `json:"priority_target"`
-	PriorityFunded       float64 `json:"priority_funded"`
-	CatalystTarget       float64 `json:"catalyst_target"`
-	CatalystFunded       float64 `json:"catalyst_funded"`
-	PriorityInterestRate float64 `json:"priority_interest_rate"`
-	CatalystInterestRate float64 `json:"catalyst_interest_rate"`
-	PoolCurrency         string  `json:"pool_currency"`
+	ID            uuid.UUID    `json:"id"`
+	InvoiceID     uuid.UUID    `json:"invoice_id"`
+	TargetAmount  money.Amount `json:"target_amount"`
+	FundedAmount  money.Amount `json:"funded_amount"`
+	InvestorCount int          `json:"investor_count"`
+	Status        PoolStatus   `json:"status"`
+	OpenedAt      *time.Time   `json:"opened_at,omitempty"`
+	Deadline      *time.Time   `json:"deadline,omitempty"` // Pool funding deadline
+	FilledAt      *time.Time   `json:"filled_at,omitempty"`
+	DisbursedAt   *time.Time   `json:"disbursed_at,omitempty"`
+	ClosedAt      *time.Time   `json:"closed_at,omitempty"`
+	CreatedAt     time.Time    `json:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+
+	// Tranches, most senior first
+	Tranches []PoolTranche `json:"tranches,omitempty"`
+
+	// Priority/catalyst columns, kept in step with the tranches of that name
+	PriorityTarget       money.Amount `json:"priority_target"`
+	PriorityFunded       money.Amount `json:"priority_funded"`
+	CatalystTarget       money.Amount `json:"catalyst_target"`
+	CatalystFunded       money.Amount `json:"catalyst_funded"`
+	PriorityInterestRate float64      `json:"priority_interest_rate"`
+	CatalystInterestRate float64      `json:"catalyst_interest_rate"`
+	PoolCurrency         string       `json:"pool_currency"`
 
 	// Relations
 	Invoice     *Invoice     `json:"invoice,omitempty"`
@@ -58,15 +68,16 @@ const (
 	InvestmentStatusActive    InvestmentStatus = "active"
 	InvestmentStatusRepaid    InvestmentStatus = "repaid"
 	InvestmentStatusDefaulted InvestmentStatus = "defaulted"
+	InvestmentStatusRefunded  InvestmentStatus = "refunded"
 )
 
 type Investment struct {
 	ID             uuid.UUID        `json:"id"`
 	PoolID         uuid.UUID        `json:"pool_id"`
 	InvestorID     uuid.UUID        `json:"investor_id"`
-	Amount         float64          `json:"amount"`
-	ExpectedReturn float64          `json:"expected_return"`
-	ActualReturn   *float64         `json:"actual_return,omitempty"`
+	Amount         money.Amount     `json:"amount"`
+	ExpectedReturn money.Amount     `json:"expected_return"`
+	ActualReturn   *money.Amount    `json:"actual_return,omitempty"`
 	Status         InvestmentStatus `json:"status"`
 	Tranche        TrancheType      `json:"tranche"`
 	TxHash         *string          `json:"tx_hash,omitempty"`
@@ -76,25 +87,33 @@ type Investment struct {
 	UpdatedAt      time.Time        `json:"updated_at"`
 
 	// Relations
-	Pool     *FundingPool `json:"pool,omitempty"`
-	Investor *User        `json:"investor,omitempty"`
+	Pool     *FundingPool       `json:"pool,omitempty"`
+	Investor *User              `json:"investor,omitempty"`
+	Consent  *InvestmentConsent `json:"consent,omitempty"`
 }
 
 type InvestRequest struct {
-	PoolID  uuid.UUID   `json:"pool_id" binding:"required"`
-	Amount  float64     `json:"amount" binding:"required,gt=0"`
-	Tranche TrancheType `json:"tranche" binding:"required,oneof=priority catalyst"`
+	PoolID  uuid.UUID    `json:"pool_id" binding:"required"`
+	Amount  money.Amount `json:"amount" binding:"required,gt=0"`
+	Tranche TrancheType  `json:"tranche" binding:"required,max=30"`
 
 	// Consent fields - inline per investment
 	// For Priority: Only tnc_accepted required
 	// For Catalyst: All 3 catalyst_consents + tnc_accepted required
 	TncAccepted bool `json:"tnc_accepted" binding:"required"` // "Saya menyetujui Syarat & Ketentuan"
 
-	// Catalyst-specific consents (required only when tranche=catalyst)
+	// Risk consents, required for catalyst and any other tranche that requires risk consent
 	// consent_1: "Saya sadar dana ini menjadi jaminan pertama jika gagal bayar."
 	// consent_2: "Saya siap menanggung risiko kehilangan modal."
 	// consent_3: "Saya paham ini bukan produk bank."
 	CatalystConsents *CatalystConsents `json:"catalyst_consents,omitempty"`
+
+	// Signed consent (optional unless INVEST_CONSENT_SIGNATURE_REQUIRED):
+	// EIP-712 signature over the payload from POST /investments/consent,
+	// made by the investor's linked wallet
+	ConsentSignature *string    `json:"consent_signature,omitempty"`
+	ConsentNonce     *uuid.UUID `json:"consent_nonce,omitempty"`      // nonce returned with the typed data
+	ConsentExpiresAt *int64     `json:"consent_expires_at,omitempty"` // expires_at returned with the typed data
 }
 
 // CatalystConsents contains the 3 required consents for Catalyst tranche
@@ -113,14 +132,14 @@ func (c *CatalystConsents) AllAccepted() bool {
 }
 
 type FundingPoolResponse struct {
-	Pool                     FundingPool `json:"pool"`
-	RemainingAmount          float64     `json:"remaining_amount"`
-	PercentageFunded         float64     `json:"percentage_funded"`
-	PriorityRemaining        float64     `json:"priority_remaining"`
-	CatalystRemaining        float64     `json:"catalyst_remaining"`
-	PriorityPercentageFunded float64     `json:"priority_percentage_funded"`
-	CatalystPercentageFunded float64     `json:"catalyst_percentage_funded"`
-	Invoice                  *Invoice    `json:"invoice,omitempty"`
+	Pool                     FundingPool  `json:"pool"`
+	RemainingAmount          money.Amount `json:"remaining_amount"`
+	PercentageFunded         float64      `json:"percentage_funded"`
+	PriorityRemaining        money.Amount `json:"priority_remaining"`
+	CatalystRemaining        money.Amount `json:"catalyst_remaining"`
+	PriorityPercentageFunded float64      `json:"priority_percentage_funded"`
+	CatalystPercentageFunded float64      `json:"catalyst_percentage_funded"`
+	Invoice                  *Invoice     `json:"invoice,omitempty"`
 }
 
 type PoolListResponse struct {
@@ -182,11 +201,11 @@ type MarketplacePoolResponse struct {
 	TenorDisplay string `json:"tenor_display"` // "60 Hari"
 
 	// Progress info
-	FundingProgress float64 `json:"funding_progress"` // Percentage funded
-	RemainingAmount float64 `json:"remaining_amount"`
-	RemainingTime   string  `json:"remaining_time"` // Human readable
-	RemainingHours  int     `json:"remaining_hours"`
-	IsFullyFunded   bool    `json:"is_fully_funded"` // For overlay display
+	FundingProgress float64      `json:"funding_progress"` // Percentage funded
+	RemainingAmount money.Amount `json:"remaining_amount"`
+	RemainingTime   string       `json:"remaining_time"` // Human readable
+	RemainingHours  int          `json:"remaining_hours"`
+	IsFullyFunded   bool         `json:"is_fully_funded"` // For overlay display
 
 	// Tranche info
 	PriorityProgress float64 `json:"priority_progress"` // % of priority filled
@@ -205,42 +224,42 @@ type MarketplaceListResponse struct {
 // InvestorPortfolio represents the portfolio summary for an investor (Flow 9)
 type InvestorPortfolio struct {
 	// Summary Cards
-	TotalFunding      float64 `json:"total_funding"`       // Total Pembiayaan (Saldo Dana yang Sedang Disalurkan)
-	TotalExpectedGain float64 `json:"total_expected_gain"` // Total imbal hasil yang diharapkan
-	TotalRealizedGain float64 `json:"total_realized_gain"` // Akumulasi profit yang sudah terealisasi
+	TotalFunding      money.Amount `json:"total_funding"`       // Total Pembiayaan (Saldo Dana yang Sedang Disalurkan)
+	TotalExpectedGain money.Amount `json:"total_expected_gain"` // Total imbal hasil yang diharapkan
+	TotalRealizedGain money.Amount `json:"total_realized_gain"` // Akumulasi profit yang sudah terealisasi
 
 	// Donut Chart: Sebaran Dana
-	PriorityAllocation float64 `json:"priority_allocation"` // Biru: Prioritas (Senior)
-	CatalystAllocation float64 `json:"catalyst_allocation"` // Oranye: Katalis (Junior)
+	PriorityAllocation money.Amount `json:"priority_allocation"` // Biru: Prioritas (Senior)
+	CatalystAllocation money.Amount `json:"catalyst_allocation"` // Oranye: Katalis (Junior)
 
 	// Counts
 	ActiveInvestments int `json:"active_investments"`
 	CompletedDeals    int `json:"completed_deals"`
 
 	// Balance info
-	AvailableBalance float64 `json:"available_balance"` // Saldo tersedia untuk funding
+	AvailableBalance money.Amount `json:"available_balance"` // Saldo tersedia untuk funding
 }
 
 // InvestorActiveInvestment represents a single active investment for listing (Flow 10)
 type InvestorActiveInvestment struct {
-	InvestmentID    uuid.UUID `json:"investment_id"`
-	ProjectName     string    `json:"project_name"` // e.g., "Kopi Gayo #12"
-	InvoiceNumber   string    `json:"invoice_number"`
-	BuyerName       string    `json:"buyer_name"`
-	BuyerCountry    string    `json:"buyer_country"`
-	BuyerFlag       string    `json:"buyer_flag"`       // Flag emoji
-	Tranche         string    `json:"tranche"`          // priority / catalyst
-	TrancheDisplay  string    `json:"tranche_display"`  // Prioritas / Katalis
-	Principal       float64   `json:"principal"`        // Modal Disalurkan (Rp)
-	InterestRate    float64   `json:"interest_rate"`    // Interest rate
-	EstimatedReturn float64   `json:"estimated_return"` // Estimasi Hasil (Rp)
-	TotalExpected   float64   `json:"total_expected"`   // Principal + Return
-	DueDate         time.Time `json:"due_date"`
-	DaysRemaining   int       `json:"days_remaining"`
-	Status          string    `json:"status"`         // lancar, perhatian, gagal_bayar
-	StatusDisplay   string    `json:"status_display"` // Lancar, Perhatian, Gagal Bayar
-	StatusColor     string    `json:"status_color"`   // green, yellow, red
-	InvestedAt      time.Time `json:"invested_at"`
+	InvestmentID    uuid.UUID    `json:"investment_id"`
+	ProjectName     string       `json:"project_name"` // e.g., "Kopi Gayo #12"
+	InvoiceNumber   string       `json:"invoice_number"`
+	BuyerName       string       `json:"buyer_name"`
+	BuyerCountry    string       `json:"buyer_country"`
+	BuyerFlag       string       `json:"buyer_flag"`       // Flag emoji
+	Tranche         string       `json:"tranche"`          // priority / catalyst
+	TrancheDisplay  string       `json:"tranche_display"`  // Prioritas / Katalis
+	Principal       money.Amount `json:"principal"`        // Modal Disalurkan (Rp)
+	InterestRate    float64      `json:"interest_rate"`    // Interest rate
+	EstimatedReturn money.Amount `json:"estimated_return"` // Estimasi Hasil (Rp)
+	TotalExpected   money.Amount `json:"total_expected"`   // Principal + Return
+	DueDate         time.Time    `json:"due_date"`
+	DaysRemaining   int          `json:"days_remaining"`
+	Status          string       `json:"status"`         // lancar, perhatian, gagal_bayar
+	StatusDisplay   string       `json:"status_display"` // Lancar, Perhatian, Gagal Bayar
+	StatusColor     string       `json:"status_color"`   // green, yellow, red
+	InvestedAt      time.Time    `json:"invested_at"`
 }
 
 // InvestorActiveInvestmentList is the response for investor active investments (Flow 10)
@@ -255,8 +274,8 @@ type InvestorActiveInvestmentList struct {
 
 // MitraDashboard represents the dashboard data for a mitra (Flow 8)
 type MitraDashboard struct {
-	TotalActiveFinancing  float64            `json:"total_active_financing"`  // Total pembiayaan aktif (Rp)
-	TotalOwedToInvestors  float64            `json:"total_owed_to_investors"` // Total hutang ke investor (termasuk bunga)
+	TotalActiveFinancing  money.Amount       `json:"total_active_financing"`  // Total pembiayaan aktif (Rp)
+	TotalOwedToInvestors  money.Amount       `json:"total_owed_to_investors"` // Total hutang ke investor (termasuk bunga)
 	AverageRemainingTenor int                `json:"average_remaining_tenor"` // Sisa tenor rata-rata (hari)
 	ActiveInvoices        []InvoiceDashboard `json:"active_invoices"`
 	TimelineStatus        TimelineStatus     `json:"timeline_status"`
@@ -271,17 +290,17 @@ type TimelineStatus struct {
 }
 
 type InvoiceDashboard struct {
-	InvoiceID     uuid.UUID `json:"invoice_id"`
-	InvoiceNumber string    `json:"invoice_number"`
-	BuyerName     string    `json:"buyer_name"`
-	BuyerCountry  string    `json:"buyer_country"`
-	DueDate       time.Time `json:"due_date"`
-	Amount        float64   `json:"amount"`
-	Status        string    `json:"status"`       // Aktif, Dalam Pengawasan
-	StatusColor   string    `json:"status_color"` // green, yellow, red
-	DaysRemaining int       `json:"days_remaining"`
-	FundedAmount  float64   `json:"funded_amount"`
-	TotalOwed     float64   `json:"total_owed"` // Termasuk bunga
+	InvoiceID     uuid.UUID    `json:"invoice_id"`
+	InvoiceNumber string       `json:"invoice_number"`
+	BuyerName     string       `json:"buyer_name"`
+	BuyerCountry  string       `json:"buyer_country"`
+	DueDate       time.Time    `json:"due_date"`
+	Amount        money.Amount `json:"amount"`
+	Status        string       `json:"status"`       // Aktif, Dalam Pengawasan
+	StatusColor   string       `json:"status_color"` // green, yellow, red
+	DaysRemaining int          `json:"days_remaining"`
+	FundedAmount  money.Amount `json:"funded_amount"`
+	TotalOwed     money.Amount `json:"total_owed"` // Termasuk bunga
 }
 
 // ActiveInvestmentListResponse is the paginated response for active investments
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ScorecardStatus is where a grading scorecard is in its lifecycle
+type ScorecardStatus string
+
+const (
+	ScorecardStatusDraft   ScorecardStatus = "draft"   // Created, not yet grading invoices
+	ScorecardStatusActive  ScorecardStatus = "active"  // Grades invoices; only one at a time
+	ScorecardStatusShadow  ScorecardStatus = "shadow"  // Scored and stored next to the active one, never suggested
+	ScorecardStatusRetired ScorecardStatus = "retired" // No longer evaluated
+)
+
+// Scorecard is one version of the invoice risk model: each factor turns a
+// signal about the invoice into points through its bands, the weighted points
+// add up to the score (0-100) and the thresholds turn the score into a grade.
+type Scorecard struct {
+	ID              uuid.UUID         `json:"id"`
+	Version         int               `json:"version"`
+	Name            string            `json:"name"`
+	Description     *string           `json:"description,omitempty"`
+	Status          ScorecardStatus   `json:"status"`
+	GradeThresholds []GradeThreshold  `json:"grade_thresholds"`
+	Factors         []ScorecardFactor `json:"factors"`
+	CreatedBy       *uuid.UUID        `json:"created_by,omitempty"`
+	ActivatedAt     *time.Time        `json:"activated_at,omitempty"`
+	RetiredAt       *time.Time        `json:"retired_at,omitempty"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+}
+
+// GradeThreshold gives Grade to scores of at least MinScore. Thresholds are
+// ordered from the highest; the last one starts at 0.
+type GradeThreshold struct {
+	Grade    string  `json:"grade" binding:"required,oneof=A B C"`
+	MinScore float64 `json:"min_score" binding:"gte=0,lte=100"`
+}
+
+// ScorecardFactor weights the points a signal earns in a scorecard
+type ScorecardFactor struct {
+	ID          uuid.UUID   `json:"id"`
+	ScorecardID uuid.UUID   `json:"scorecard_id"`
+	Factor      string      `json:"factor"` // Signal name, see GET /admin/grading/factors
+	Weight      float64     `json:"weight"`
+	Bands       []ScoreBand `json:"bands"`
+	Position    int         `json:"position"`
+}
+
+// ScoreBand awards Points when the signal lies within [Min, Max]. A missing
+// bound is open; the first matching band of a factor wins.
+type ScoreBand struct {
+	Min    *float64 `json:"min,omitempty"`
+	Max    *float64 `json:"max,omitempty"`
+	Points float64  `json:"points" binding:"gte=0"`
+	Label  string   `json:"label,omitempty"`
+}
+
+// Matches reports whether value lies within the band
+func (b ScoreBand) Matches(value float64) bool {
+	if b.Min != nil && value < *b.Min {
+		return false
+	}
+	if b.Max != nil && value > *b.Max {
+		return false
+	}
+	return true
+}
+
+// FactorContribution is how one factor scored an invoice
+type FactorContribution struct {
+	Factor       string  `json:"factor"`
+	Category     string  `json:"category"` // country, history or documents
+	Value        float64 `json:"value"`    // The signal read from the invoice
+	Band         string  `json:"band,omitempty"`
+	Points       float64 `json:"points"`
+	Weight       float64 `json:"weight"`
+	Contribution float64 `json:"contribution"` // Points x weight
+}
+
+// InvoiceGradeScore is an invoice's score under one scorecard, with each
+// factor's contribution. It is replaced whenever the invoice is graded again.
+type InvoiceGradeScore struct {
+	ID               uuid.UUID            `json:"id"`
+	InvoiceID        uuid.UUID            `json:"invoice_id"`
+	ScorecardID      uuid.UUID            `json:"scorecard_id"`
+	ScorecardVersion int                  `json:"scorecard_version"`
+	Mode             ScorecardStatus      `json:"mode"` // active or shadow
+	Score            float64              `json:"score"`
+	Grade            string               `json:"grade"`
+	Factors          []FactorContribution `json:"factors"`
+	GradedAt         time.Time            `json:"graded_at"`
+}
+
+// CategoryScore adds up the contributions of the factors in category
+func (s *InvoiceGradeScore) CategoryScore(category string) float64 {
+	var total float64
+	for _, f := range s.Factors {
+		if f.Category == category {
+			total += f.Contribution
+		}
+	}
+	return total
+}
+
+// GradingFactorInfo describes a signal a scorecard factor can use
+type GradingFactorInfo struct {
+	Name        string `json:"name"`
+	Category    string `json:"category"`
+	Description string `json:"description"`
+}
+
+// CreateScorecardRequest creates the next scorecard version as a draft.
+// A scorecard cannot be edited; changes are made in a new version.
+type CreateScorecardRequest struct {
+	Name            string                   `json:"name" binding:"required,max=100"`
+	Description     *string                  `json:"description,omitempty"`
+	GradeThresholds []GradeThreshold         `json:"grade_thresholds" binding:"required,min=1,dive"`
+	Factors         []ScorecardFactorRequest `json:"factors" binding:"required,min=1,dive"`
+}
+
+// ScorecardFactorRequest is one factor of a new scorecard
+type ScorecardFactorRequest struct {
+	Factor string      `json:"factor" binding:"required"`
+	Weight float64     `json:"weight" binding:"required,gt=0"`
+	Bands  []ScoreBand `json:"bands" binding:"required,min=1,dive"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type IdempotencyStatus string
+
+const (
+	IdempotencyStatusProcessing IdempotencyStatus = "processing"
+	IdempotencyStatusCompleted  IdempotencyStatus = "completed"
+)
+
+// IdempotencyKey records a client-supplied Idempotency-Key together with the
+// fingerprint of the request it was first used for and the response returned
+type IdempotencyKey struct {
+	ID                  uuid.UUID         `json:"id"`
+	Scope               string            `json:"scope"` // user ID, or "public" for unauthenticated routes
+	Key                 string            `json:"key"`
+	Method              string            `json:"method"`
+	Path                string            `json:"path"`
+	RequestHash         string            `json:"request_hash"`
+	Status              IdempotencyStatus `json:"status"`
+	ResponseStatus      *int              `json:"response_status,omitempty"`
+	ResponseContentType *string           `json:"response_content_type,omitempty"`
+	ResponseBody        []byte            `json:"response_body,omitempty"`
+	CreatedAt           time.Time         `json:"created_at"`
+	CompletedAt         *time.Time        `json:"completed_at,omitempty"`
+	ExpiresAt           time.Time         `json:"expires_at"`
+}
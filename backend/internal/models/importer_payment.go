@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/money"
 )
 
 // ImporterPaymentStatus represents the payment status from importer
@@ -11,6 +12,7 @@ type ImporterPaymentStatus string
 
 const (
 	ImporterPaymentStatusPending  ImporterPaymentStatus = "pending"
+	ImporterPaymentStatusPartial  ImporterPaymentStatus = "partial" // Some installments received, balance outstanding
 	ImporterPaymentStatusPaid     ImporterPaymentStatus = "paid"
 	ImporterPaymentStatusOverdue  ImporterPaymentStatus = "overdue"
 	ImporterPaymentStatusCanceled ImporterPaymentStatus = "canceled"
@@ -22,14 +24,15 @@ type ImporterPayment struct {
 	ID            uuid.UUID             `json:"id"`
 	InvoiceID     uuid.UUID             `json:"invoice_id"`
 	PoolID        uuid.UUID             `json:"pool_id"`
+	BuyerID       *uuid.UUID            `json:"buyer_id,omitempty"` // Buyer registry entry of the invoice
 	BuyerEmail    string                `json:"buyer_email"`
 	BuyerName     string                `json:"buyer_name"`
-	AmountDue     float64               `json:"amount_due"` // Total (target + interest)
-	AmountPaid    float64               `json:"amount_paid"`
+	AmountDue     money.Amount          `json:"amount_due"`  // Total (target + interest)
+	AmountPaid    money.Amount          `json:"amount_paid"` // Running total of the installments received
 	Currency      string                `json:"currency"`
 	PaymentStatus ImporterPaymentStatus `json:"payment_status"`
 	DueDate       time.Time             `json:"due_date"`
-	PaidAt        *time.Time            `json:"paid_at,omitempty"`
+	PaidAt        *time.Time            `json:"paid_at,omitempty"` // When the balance was paid in full
 	TxHash        *string               `json:"tx_hash,omitempty"` // Blockchain tx hash after payment
 	CreatedAt     time.Time             `json:"created_at"`
 	UpdatedAt     time.Time             `json:"updated_at"`
@@ -41,17 +44,19 @@ type ImporterPayment struct {
 
 // ImporterPaymentRequest is request body for importer to pay
 type ImporterPaymentRequest struct {
-	Amount float64 `json:"amount" binding:"required,gt=0"`
+	Amount money.Amount `json:"amount" binding:"required,gt=0"`
 }
 
 // ImporterPaymentResponse is the response after payment
 type ImporterPaymentResponse struct {
-	PaymentID  uuid.UUID  `json:"payment_id"`
-	Status     string     `json:"status"`
-	AmountPaid float64    `json:"amount_paid"`
-	TxHash     *string    `json:"tx_hash,omitempty"`
-	Message    string     `json:"message"`
-	PaidAt     *time.Time `json:"paid_at,omitempty"`
+	PaymentID   uuid.UUID    `json:"payment_id"`
+	Status      string       `json:"status"`
+	AmountPaid  money.Amount `json:"amount_paid"`
+	Outstanding money.Amount `json:"outstanding"` // Amount due not yet paid
+	TxHash      *string      `json:"tx_hash,omitempty"`
+	PaymentURL  *string      `json:"payment_url,omitempty"` // Set while the gateway payment is pending
+	Message     string       `json:"message"`
+	PaidAt      *time.Time   `json:"paid_at,omitempty"`
 }
 
 // PaymentNotificationData is data for email notification to importer
@@ -60,7 +65,7 @@ type PaymentNotificationData struct {
 	InvoiceNumber string
 	BuyerName     string
 	ExporterName  string
-	AmountDue     float64
+	AmountDue     money.Amount
 	Currency      string
 	DueDate       time.Time
 	PaymentLink   string
@@ -74,10 +79,10 @@ type ExporterPaymentNotificationData struct {
 	ExporterName    string
 	BuyerName       string
 	BuyerEmail      string
-	PrincipalAmount float64 // Original invoice amount funded
-	TotalInterest   float64 // Total interest to be paid by importer
-	PlatformFee     float64 // Platform fee for the application (2%)
-	TotalAmountDue  float64 // Principal + Total Interest + Platform Fee
+	PrincipalAmount money.Amount // Original invoice amount funded
+	TotalInterest   money.Amount // Total interest to be paid by importer
+	PlatformFee     money.Amount // Platform fee for the application (2%)
+	TotalAmountDue  money.Amount // Principal + Total Interest + Platform Fee
 	Currency        string
 	DueDate         time.Time // Invoice due date
 	InvestorDetails []InvestorPaymentDetail
@@ -88,9 +93,9 @@ type ExporterPaymentNotificationData struct {
 // InvestorPaymentDetail contains details for each investor's share
 type InvestorPaymentDetail struct {
 	InvestorID     string
-	Amount         float64
+	Amount         money.Amount
 	InterestRate   float64
-	ExpectedReturn float64
+	ExpectedReturn money.Amount
 	Tranche        string
 }
 
@@ -108,18 +113,18 @@ const (
 
 // VirtualAccount represents a VA for Mitra to pay back investors
 type VirtualAccount struct {
-	ID        uuid.UUID  `json:"id"`
-	PoolID    uuid.UUID  `json:"pool_id"`
-	UserID    uuid.UUID  `json:"user_id"` // Mitra user ID
-	VANumber  string     `json:"va_number"`
-	BankCode  string     `json:"bank_code"`
-	BankName  string     `json:"bank_name"`
-	Amount    float64    `json:"amount"` // Total amount due (principal + interest)
-	Status    VAStatus   `json:"status"`
-	ExpiresAt time.Time  `json:"expires_at"`
-	PaidAt    *time.Time `json:"paid_at,omitempty"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
+	ID        uuid.UUID    `json:"id"`
+	PoolID    uuid.UUID    `json:"pool_id"`
+	UserID    uuid.UUID    `json:"user_id"` // Mitra user ID
+	VANumber  string       `json:"va_number"`
+	BankCode  string       `json:"bank_code"`
+	BankName  string       `json:"bank_name"`
+	Amount    money.Amount `json:"amount"` // Total amount due (principal + interest)
+	Status    VAStatus     `json:"status"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	PaidAt    *time.Time   `json:"paid_at,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
 }
 
 // CreateVARequest is request to create VA for mitra repayment
@@ -153,26 +158,51 @@ type MitraRepaymentBreakdown struct {
 	DueDate   time.Time `json:"due_date"`
 
 	// Principal amounts
-	PriorityPrincipal float64 `json:"priority_principal"`
-	CatalystPrincipal float64 `json:"catalyst_principal"`
-	TotalPrincipal    float64 `json:"total_principal"`
-
-	// Interest amounts (flat rate: principal × rate/100)
-	PriorityInterestRate float64 `json:"priority_interest_rate"` // e.g., 10%
-	CatalystInterestRate float64 `json:"catalyst_interest_rate"` // e.g., 15%
-	PriorityInterest     float64 `json:"priority_interest"`
-	CatalystInterest     float64 `json:"catalyst_interest"`
-	TotalInterest        float64 `json:"total_interest"`
-
-	// Total amounts
-	PriorityTotal float64 `json:"priority_total"` // Priority Principal + Interest
-	CatalystTotal float64 `json:"catalyst_total"` // Catalyst Principal + Interest
-	PlatformFee   float64 `json:"platform_fee"`   // Platform fee for the application (2%)
-	GrandTotal    float64 `json:"grand_total"`    // Total to pay (including platform fee)
+	PriorityPrincipal money.Amount `json:"priority_principal"`
+	CatalystPrincipal money.Amount `json:"catalyst_principal"`
+	TotalPrincipal    money.Amount `json:"total_principal"`
+
+	// Interest amounts (annual rate accrued from disbursement to the payment date, at the latest the due date)
+	PriorityInterestRate float64      `json:"priority_interest_rate"` // e.g., 10%
+	CatalystInterestRate float64      `json:"catalyst_interest_rate"` // e.g., 15%
+	PriorityInterest     money.Amount `json:"priority_interest"`
+	CatalystInterest     money.Amount `json:"catalyst_interest"`
+	TotalInterest        money.Amount `json:"total_interest"`
+
+	// Already paid by earlier installments
+	TotalPaid money.Amount `json:"total_paid"`
+
+	// Total amounts still owed
+	PriorityTotal money.Amount `json:"priority_total"` // Priority Principal + Interest - Paid
+	CatalystTotal money.Amount `json:"catalyst_total"` // Catalyst Principal + Interest - Paid
+
+	// Every tranche of the pool, most senior first
+	Tranches []MitraTrancheBreakdown `json:"tranches"`
+
+	// Late payment charges once past the due date (grade late fee plus daily penalty interest)
+	DaysOverdue     int          `json:"days_overdue,omitempty"`
+	LateFee         money.Amount `json:"late_fee"`
+	PenaltyRate     float64      `json:"penalty_rate,omitempty"` // Annual, on the overdue balance
+	PenaltyInterest money.Amount `json:"penalty_interest"`
+	LateChargesPaid money.Amount `json:"late_charges_paid"`
+	LateCharges     money.Amount `json:"late_charges"` // Still to pay: late fee + penalty interest - paid
+	PlatformFee     money.Amount `json:"platform_fee"` // Platform fee for the application (2%)
+	GrandTotal      money.Amount `json:"grand_total"`  // Total still to pay (including late charges and platform fee)
 
 	Currency string `json:"currency"`
 }
 
+// MitraTrancheBreakdown is what the mitra owes one tranche
+type MitraTrancheBreakdown struct {
+	Tranche      TrancheType  `json:"tranche"`
+	Seniority    int          `json:"seniority"`
+	InterestRate float64      `json:"interest_rate"`
+	Principal    money.Amount `json:"principal"`
+	Interest     money.Amount `json:"interest"`
+	Paid         money.Amount `json:"paid"`  // Already paid by earlier installments
+	Total        money.Amount `json:"total"` // Principal + Interest - Paid
+}
+
 // VAPaymentResponse is the response after creating VA
 type VAPaymentResponse struct {
 	VA             VirtualAccount          `json:"virtual_account"`
@@ -187,7 +217,7 @@ type VAPaymentPageResponse struct {
 	VANumber        string                  `json:"va_number"`
 	BankCode        string                  `json:"bank_code"`
 	BankName        string                  `json:"bank_name"`
-	Amount          float64                 `json:"amount"`           // Closed amount, user cannot change
+	Amount          money.Amount            `json:"amount"`           // Closed amount, user cannot change
 	AmountFormatted string                  `json:"amount_formatted"` // e.g., "Rp 55.000.000"
 	Status          VAStatus                `json:"status"`
 	ExpiresAt       time.Time               `json:"expires_at"`
@@ -198,14 +228,14 @@ type VAPaymentPageResponse struct {
 
 // MitraActiveInvoice is for displaying active invoices with pay button
 type MitraActiveInvoice struct {
-	InvoiceID     uuid.UUID `json:"invoice_id"`
-	PoolID        uuid.UUID `json:"pool_id"`
-	InvoiceNumber string    `json:"invoice_number"`
-	BuyerName     string    `json:"buyer_name"`
-	Amount        float64   `json:"amount"`    // Original invoice amount
-	TotalDue      float64   `json:"total_due"` // Principal + Interest
-	DueDate       time.Time `json:"due_date"`
-	Status        string    `json:"status"`
-	DaysUntilDue  int       `json:"days_until_due"` // Negative if overdue
-	CanPay        bool      `json:"can_pay"`        // True if pool is filled and can pay
+	InvoiceID     uuid.UUID    `json:"invoice_id"`
+	PoolID        uuid.UUID    `json:"pool_id"`
+	InvoiceNumber string       `json:"invoice_number"`
+	BuyerName     string       `json:"buyer_name"`
+	Amount        money.Amount `json:"amount"`    // Original invoice amount
+	TotalDue      money.Amount `json:"total_due"` // Principal + Interest
+	DueDate       time.Time    `json:"due_date"`
+	Status        string       `json:"status"`
+	DaysUntilDue  int          `json:"days_until_due"` // Negative if overdue
+	CanPay        bool         `json:"can_pay"`        // True if pool is filled and can pay
 }
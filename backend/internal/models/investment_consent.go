@@ -0,0 +1,71 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/money"
+)
+
+// Consent statements shown to the investor. Their hash is part of the signed
+// investment payload, so changing any text changes what investors sign.
+const (
+	ConsentTextTnc       = "Saya menyetujui Syarat & Ketentuan"
+	ConsentTextFirstLoss = "Saya sadar dana ini menjadi jaminan pertama jika gagal bayar."
+	ConsentTextRiskLoss  = "Saya siap menanggung risiko kehilangan modal."
+	ConsentTextNotBank   = "Saya paham ini bukan produk bank."
+)
+
+// ConsentTexts returns the statements an investor accepts, in the order they are
+// displayed. Tranches that require risk consent add the first-loss statements.
+func ConsentTexts(riskConsent bool) []string {
+	texts := []string{ConsentTextTnc}
+	if riskConsent {
+		texts = append(texts, ConsentTextFirstLoss, ConsentTextRiskLoss, ConsentTextNotBank)
+	}
+	return texts
+}
+
+// ConsentTextsDocument is the exact byte sequence hashed into consentTextsHash
+func ConsentTextsDocument(riskConsent bool) string {
+	return strings.Join(ConsentTexts(riskConsent), "\n")
+}
+
+// InvestmentConsent is the stored proof that an investor signed the investment
+// payload. TypedData is kept verbatim so the signature can be re-verified
+// independently of this backend.
+type InvestmentConsent struct {
+	ID               uuid.UUID       `json:"id"`
+	InvestmentID     uuid.UUID       `json:"investment_id"`
+	InvestorID       uuid.UUID       `json:"investor_id"`
+	SignerAddress    string          `json:"signer_address"`
+	TncVersion       string          `json:"tnc_version"`
+	ConsentTextsHash string          `json:"consent_texts_hash"`
+	TypedData        json.RawMessage `json:"typed_data"`
+	Digest           string          `json:"digest"` // EIP-712 hash that was signed
+	Signature        string          `json:"signature"`
+	ExpiresAt        time.Time       `json:"expires_at"`
+	CreatedAt        time.Time       `json:"created_at"`
+}
+
+// InvestmentConsentRequest asks for the typed data to sign before investing
+type InvestmentConsentRequest struct {
+	PoolID  uuid.UUID    `json:"pool_id" binding:"required"`
+	Amount  money.Amount `json:"amount" binding:"required,gt=0"`
+	Tranche TrancheType  `json:"tranche" binding:"required,max=30"`
+}
+
+// InvestmentConsentResponse is the payload the investor's wallet signs with
+// eth_signTypedData_v4. Nonce and ExpiresAt go back in InvestRequest.ConsentNonce
+// and InvestRequest.ConsentExpiresAt; the nonce is accepted once.
+type InvestmentConsentResponse struct {
+	TypedData        json.RawMessage `json:"typed_data"`
+	Digest           string          `json:"digest"`
+	ConsentTexts     []string        `json:"consent_texts"`
+	ConsentTextsHash string          `json:"consent_texts_hash"`
+	TncVersion       string          `json:"tnc_version"`
+	Nonce            uuid.UUID       `json:"nonce"`
+	ExpiresAt        int64           `json:"expires_at"` // Unix seconds
+}
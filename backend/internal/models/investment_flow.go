@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/money"
 )
 
 // PoolDetailResponse is the response for pool detail page (Flow 6 - Project Detail)
@@ -19,10 +20,10 @@ type PoolDetailResponse struct {
 	IsInsured  bool   `json:"is_insured"`
 
 	// Amounts
-	TargetAmount    float64 `json:"target_amount"`
-	FundedAmount    float64 `json:"funded_amount"`
-	RemainingAmount float64 `json:"remaining_amount"`
-	FundingProgress float64 `json:"funding_progress"`
+	TargetAmount    money.Amount `json:"target_amount"`
+	FundedAmount    money.Amount `json:"funded_amount"`
+	RemainingAmount money.Amount `json:"remaining_amount"`
+	FundingProgress float64      `json:"funding_progress"`
 
 	// Tenor
 	TenorDays      int        `json:"tenor_days"`
@@ -42,8 +43,9 @@ type PoolDetailResponse struct {
 	Documents    []DocumentInfo     `json:"documents"`
 
 	// Tranche Info
-	PriorityTranche TrancheInfo `json:"priority_tranche"`
-	CatalystTranche TrancheInfo `json:"catalyst_tranche"`
+	PriorityTranche TrancheInfo   `json:"priority_tranche"`
+	CatalystTranche TrancheInfo   `json:"catalyst_tranche"`
+	Tranches        []TrancheInfo `json:"tranches"` // Every tranche, most senior first
 }
 
 // BuyerDetailInfo contains buyer info for detail page
@@ -59,12 +61,12 @@ type BuyerDetailInfo struct {
 
 // ExporterDetailInfo contains exporter info for detail page
 type ExporterDetailInfo struct {
-	CompanyName     string  `json:"company_name"`
-	IsVerified      bool    `json:"is_verified"`
-	CreditLimit     float64 `json:"credit_limit"`
-	AvailableCredit float64 `json:"available_credit"`
-	TotalInvoices   int     `json:"total_invoices"`
-	SuccessRate     float64 `json:"success_rate"`
+	CompanyName     string       `json:"company_name"`
+	IsVerified      bool         `json:"is_verified"`
+	CreditLimit     money.Amount `json:"credit_limit"`
+	AvailableCredit money.Amount `json:"available_credit"`
+	TotalInvoices   int          `json:"total_invoices"`
+	SuccessRate     float64      `json:"success_rate"`
 }
 
 // DocumentInfo contains document info for preview/download
@@ -77,51 +79,58 @@ type DocumentInfo struct {
 
 // TrancheInfo contains tranche-specific info for UI
 type TrancheInfo struct {
-	Type                string  `json:"type"`
-	TypeDisplay         string  `json:"type_display"`
-	Description         string  `json:"description"`
-	TargetAmount        float64 `json:"target_amount"`
-	FundedAmount        float64 `json:"funded_amount"`
-	RemainingAmount     float64 `json:"remaining_amount"`
-	ProgressPercent     float64 `json:"progress_percent"`
-	InterestRate        float64 `json:"interest_rate"`
-	InterestRateDisplay string  `json:"interest_rate_display"`
-	RiskLevel           string  `json:"risk_level"`
-	RiskLevelDisplay    string  `json:"risk_level_display"`
-	InfoBox             string  `json:"info_box"`
+	Type                string       `json:"type"`
+	TypeDisplay         string       `json:"type_display"`
+	Description         string       `json:"description"`
+	TargetAmount        money.Amount `json:"target_amount"`
+	FundedAmount        money.Amount `json:"funded_amount"`
+	RemainingAmount     money.Amount `json:"remaining_amount"`
+	ProgressPercent     float64      `json:"progress_percent"`
+	InterestRate        float64      `json:"interest_rate"`
+	InterestRateDisplay string       `json:"interest_rate_display"`
+	RiskLevel           string       `json:"risk_level"`
+	RiskLevelDisplay    string       `json:"risk_level_display"`
+	InfoBox             string       `json:"info_box"`
+
+	// Set for the entries of PoolDetailResponse.Tranches
+	Seniority           int          `json:"seniority,omitempty"` // 1 is paid first
+	RequiresRiskConsent bool         `json:"requires_risk_consent"`
+	RequiresRiskProfile bool         `json:"requires_risk_profile"`
+	MinInvestment       money.Amount `json:"min_investment,omitempty"`
+	MaxInvestment       money.Amount `json:"max_investment,omitempty"`
 }
 
 // InvestmentCalculatorRequest is the request for calculating investment returns
 type InvestmentCalculatorRequest struct {
-	PoolID  uuid.UUID `json:"pool_id" binding:"required"`
-	Amount  float64   `json:"amount" binding:"required,gt=0"`
-	Tranche string    `json:"tranche" binding:"required,oneof=priority catalyst"`
+	PoolID  uuid.UUID    `json:"pool_id" binding:"required"`
+	Amount  money.Amount `json:"amount" binding:"required,gt=0"`
+	Tranche string       `json:"tranche" binding:"required,max=30"`
 }
 
 // InvestmentCalculatorResponse is the response for investment calculator
 type InvestmentCalculatorResponse struct {
-	PoolID         uuid.UUID `json:"pool_id"`
-	Tranche        string    `json:"tranche"`
-	TrancheDisplay string    `json:"tranche_display"`
-	Principal      float64   `json:"principal"`
-	InterestRate   float64   `json:"interest_rate"`
-	TenorDays      int       `json:"tenor_days"`
-	GrossInterest  float64   `json:"gross_interest"`
-	PlatformFee    float64   `json:"platform_fee"`
-	NetInterest    float64   `json:"net_interest"`
-	TotalReturn    float64   `json:"total_return"`
-	NetTotalReturn float64   `json:"net_total_return"`
-	EffectiveRate  float64   `json:"effective_rate"`
-	MaxInvestable  float64   `json:"max_investable"`
-	CanInvest      bool      `json:"can_invest"`
-	Message        string    `json:"message,omitempty"`
+	PoolID         uuid.UUID    `json:"pool_id"`
+	Tranche        string       `json:"tranche"`
+	TrancheDisplay string       `json:"tranche_display"`
+	Principal      money.Amount `json:"principal"`
+	InterestRate   float64      `json:"interest_rate"`
+	TenorDays      int          `json:"tenor_days"`
+	GrossInterest  money.Amount `json:"gross_interest"`
+	PlatformFee    money.Amount `json:"platform_fee"`
+	NetInterest    money.Amount `json:"net_interest"`
+	TotalReturn    money.Amount `json:"total_return"`
+	NetTotalReturn money.Amount `json:"net_total_return"`
+	EffectiveRate  float64      `json:"effective_rate"`
+	MaxInvestable  money.Amount `json:"max_investable"`
+	CanInvest      bool         `json:"can_invest"`
+	Message        string       `json:"message,omitempty"`
 }
 
 // InvestConfirmationRequest is the request for confirming investment (Flow 6)
 type InvestConfirmationRequest struct {
-	PoolID  uuid.UUID `json:"pool_id" binding:"required"`
-	Amount  float64   `json:"amount" binding:"required,gt=0"`
-	Tranche string    `json:"tranche" binding:"required,oneof=priority catalyst"`
+	PoolID  uuid.UUID    `json:"pool_id" binding:"required"`
+	Amount  money.Amount `json:"amount" binding:"required,gt=0"`
+	Tranche string       `json:"tranche" binding:"required,max=30"`
 
 	// For Priority Tranche
 	TermsAccepted bool `json:"terms_accepted"`
@@ -133,15 +142,15 @@ type InvestConfirmationRequest struct {
 
 // InvestmentConfirmationData contains data for confirmation UI
 type InvestmentConfirmationData struct {
-	PoolID          uuid.UUID `json:"pool_id"`
-	ProjectTitle    string    `json:"project_title"`
-	Amount          float64   `json:"amount"`
-	Tranche         string    `json:"tranche"`
-	TrancheDisplay  string    `json:"tranche_display"`
-	InterestRate    float64   `json:"interest_rate"`
-	EstimatedReturn float64   `json:"estimated_return"`
-	TotalExpected   float64   `json:"total_expected"`
-	DueDate         time.Time `json:"due_date"`
+	PoolID          uuid.UUID    `json:"pool_id"`
+	ProjectTitle    string       `json:"project_title"`
+	Amount          money.Amount `json:"amount"`
+	Tranche         string       `json:"tranche"`
+	TrancheDisplay  string       `json:"tranche_display"`
+	InterestRate    float64      `json:"interest_rate"`
+	EstimatedReturn money.Amount `json:"estimated_return"`
+	TotalExpected   money.Amount `json:"total_expected"`
+	DueDate         time.Time    `json:"due_date"`
 
 	// UI guidance
 	RequiredCheckboxes  []string `json:"required_checkboxes"`
@@ -167,29 +176,29 @@ type CreatePaymentRequest struct {
 
 // PaymentResponse is the response after creating payment
 type PaymentResponse struct {
-	InvestmentID   uuid.UUID `json:"investment_id"`
-	PaymentID      string    `json:"payment_id"`
-	PaymentURL     string    `json:"payment_url"` // Midtrans redirect URL
-	VirtualAccount string    `json:"virtual_account,omitempty"`
-	QRCodeURL      string    `json:"qr_code_url,omitempty"`
-	Amount         float64   `json:"amount"`
-	ExpiresAt      time.Time `json:"expires_at"`
-	Status         string    `json:"status"`
+	InvestmentID   uuid.UUID    `json:"investment_id"`
+	PaymentID      string       `json:"payment_id"`
+	PaymentURL     string       `json:"payment_url"` // Midtrans redirect URL
+	VirtualAccount string       `json:"virtual_account,omitempty"`
+	QRCodeURL      string       `json:"qr_code_url,omitempty"`
+	Amount         money.Amount `json:"amount"`
+	ExpiresAt      time.Time    `json:"expires_at"`
+	Status         string       `json:"status"`
 }
 
 // BalanceInfo represents user balance information (Flow 3)
 type BalanceInfo struct {
-	UserID     uuid.UUID `json:"user_id"`
-	Role       string    `json:"role"` // investor or mitra
-	BalanceIDR float64   `json:"balance_idr"`
-	Currency   string    `json:"currency"`
+	UserID     uuid.UUID    `json:"user_id"`
+	Role       string       `json:"role"` // investor or mitra
+	BalanceIDR money.Amount `json:"balance_idr"`
+	Currency   string       `json:"currency"`
 
 	// For Investor: funds in active funding
-	ActiveFunding float64 `json:"active_funding,omitempty"`
+	ActiveFunding money.Amount `json:"active_funding,omitempty"`
 
 	// For Mitra: amount owed to investors
-	TotalOwed     float64 `json:"total_owed,omitempty"`
-	TotalInterest float64 `json:"total_interest,omitempty"`
+	TotalOwed     money.Amount `json:"total_owed,omitempty"`
+	TotalInterest money.Amount `json:"total_interest,omitempty"`
 
 	Description string `json:"description"`
 }
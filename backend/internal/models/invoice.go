@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/money"
 )
 
 type InvoiceStatus string
@@ -19,25 +20,27 @@ const (
 	StatusMatured       InvoiceStatus = "matured"
 	StatusRepaid        InvoiceStatus = "repaid"
 	StatusDefaulted     InvoiceStatus = "defaulted"
+	StatusExpired       InvoiceStatus = "expired" // Funding pool expired without enough funding
 )
 
 type Invoice struct {
 	ID         uuid.UUID `json:"id"`
 	ExporterID uuid.UUID `json:"exporter_id"`
-	// Buyer Details (Flattened)
-	BuyerName    string `json:"buyer_name"`
-	BuyerCountry string `json:"buyer_country"`
+	// Buyer Details (Flattened, as entered on the invoice)
+	BuyerID      *uuid.UUID `json:"buyer_id,omitempty"` // Buyer registry entry
+	BuyerName    string     `json:"buyer_name"`
+	BuyerCountry string     `json:"buyer_country"`
 
 	InvoiceNumber     string        `json:"invoice_number"`
 	Currency          string        `json:"currency"`
-	Amount            float64       `json:"amount"`
+	Amount            money.Amount  `json:"amount"`
 	IssueDate         time.Time     `json:"issue_date"`
 	DueDate           time.Time     `json:"due_date"`
 	Description       *string       `json:"description,omitempty"`
 	Status            InvoiceStatus `json:"status"`
 	InterestRate      *float64      `json:"interest_rate,omitempty"`
 	AdvancePercentage float64       `json:"advance_percentage"`
-	AdvanceAmount     *float64      `json:"advance_amount,omitempty"`
+	AdvanceAmount     *money.Amount `json:"advance_amount,omitempty"`
 	DocumentHash      *string       `json:"document_hash,omitempty"`
 	CreatedAt         time.Time     `json:"created_at"`
 	UpdatedAt         time.Time     `json:"updated_at"`
@@ -58,11 +61,11 @@ type Invoice struct {
 	CatalystInterestRate *float64 `json:"catalyst_interest_rate,omitempty"` // e.g., 15%
 
 	// VESSEL Currency Conversion Fields
-	OriginalCurrency *string  `json:"original_currency,omitempty"`
-	OriginalAmount   *float64 `json:"original_amount,omitempty"`
-	IDRAmount        *float64 `json:"idr_amount,omitempty"`
-	ExchangeRate     *float64 `json:"exchange_rate,omitempty"`
-	BufferRate       float64  `json:"buffer_rate"` // Default 1.5%
+	OriginalCurrency *string       `json:"original_currency,omitempty"`
+	OriginalAmount   *money.Amount `json:"original_amount,omitempty"`
+	IDRAmount        *money.Amount `json:"idr_amount,omitempty"`
+	ExchangeRate     *float64      `json:"exchange_rate,omitempty"`
+	BufferRate       float64       `json:"buffer_rate"` // Default 1.5%
 
 	// VESSEL Additional Fields
 	FundingDurationDays int     `json:"funding_duration_days"` // Default 14 days
@@ -104,6 +107,7 @@ type InvoiceNFT struct {
 	ID              uuid.UUID  `json:"id"`
 	InvoiceID       uuid.UUID  `json:"invoice_id"`
 	TokenID         *int64     `json:"token_id,omitempty"`
+	TokenVerified   bool       `json:"token_verified"` // TokenID was read from the mint's InvoiceMinted event
 	ContractAddress *string    `json:"contract_address,omitempty"`
 	ChainID         int        `json:"chain_id"`
 	OwnerAddress    *string    `json:"owner_address,omitempty"`
@@ -126,13 +130,13 @@ type CreateInvoiceFundingRequest struct {
 	BuyerEmail       string `json:"buyer_email" binding:"required,email"`  // Email utama importir
 
 	// UI Group 2: Nilai Pengajuan
-	InvoiceNumber       string  `json:"invoice_number" binding:"required"`
-	OriginalCurrency    string  `json:"original_currency" binding:"required"`    // Mata Uang Invoice (USD, EUR, etc.)
-	OriginalAmount      float64 `json:"original_amount" binding:"required,gt=0"` // Nominal Invoice
-	LockedExchangeRate  float64 `json:"locked_exchange_rate" binding:"required"` // Kurs konversi yang dikunci
-	IDRAmount           float64 `json:"idr_amount" binding:"required,gt=0"`      // Nominal dalam IDR
-	DueDate             string  `json:"due_date" binding:"required"`             // Tanggal Jatuh Tempo (Tenor)
-	FundingDurationDays int     `json:"funding_duration_days"`                   // Funding Duration (Default 14 Hari)
+	InvoiceNumber       string       `json:"invoice_number" binding:"required"`
+	OriginalCurrency    string       `json:"original_currency" binding:"required"`    // Mata Uang Invoice (USD, EUR, etc.)
+	OriginalAmount      money.Amount `json:"original_amount" binding:"required,gt=0"` // Nominal Invoice
+	LockedExchangeRate  float64      `json:"locked_exchange_rate" binding:"required"` // Kurs konversi yang dikunci
+	IDRAmount           money.Amount `json:"idr_amount" binding:"required,gt=0"`      // Nominal dalam IDR
+	DueDate             string       `json:"due_date" binding:"required"`             // Tanggal Jatuh Tempo (Tenor)
+	FundingDurationDays int          `json:"funding_duration_days"`                   // Funding Duration (Default 14 Hari)
 
 	// Tranche Configuration
 	PriorityRatio        float64 `json:"priority_ratio"`                                         // Default 80%
@@ -152,22 +156,22 @@ type CreateInvoiceFundingRequest struct {
 
 // CreateInvoiceRequest - legacy request, kept for backward compatibility
 type CreateInvoiceRequest struct {
-	BuyerID       uuid.UUID `json:"buyer_id" binding:"required"`
-	InvoiceNumber string    `json:"invoice_number" binding:"required"`
-	Currency      string    `json:"currency"`
-	Amount        float64   `json:"amount" binding:"required,gt=0"`
-	IssueDate     string    `json:"issue_date" binding:"required"`
-	DueDate       string    `json:"due_date" binding:"required"`
-	Description   *string   `json:"description,omitempty"`
+	BuyerID       uuid.UUID    `json:"buyer_id" binding:"required"`
+	InvoiceNumber string       `json:"invoice_number" binding:"required"`
+	Currency      string       `json:"currency"`
+	Amount        money.Amount `json:"amount" binding:"required,gt=0"`
+	IssueDate     string       `json:"issue_date" binding:"required"`
+	DueDate       string       `json:"due_date" binding:"required"`
+	Description   *string      `json:"description,omitempty"`
 }
 
 type UpdateInvoiceRequest struct {
-	InvoiceNumber string  `json:"invoice_number"`
-	Currency      string  `json:"currency"`
-	Amount        float64 `json:"amount"`
-	IssueDate     string  `json:"issue_date"`
-	DueDate       string  `json:"due_date"`
-	Description   *string `json:"description,omitempty"`
+	InvoiceNumber string       `json:"invoice_number"`
+	Currency      string       `json:"currency"`
+	Amount        money.Amount `json:"amount"`
+	IssueDate     string       `json:"issue_date"`
+	DueDate       string       `json:"due_date"`
+	Description   *string      `json:"description,omitempty"`
 }
 
 type SubmitInvoiceRequest struct {
@@ -189,11 +193,16 @@ type AdminGradeSuggestionResponse struct {
 	GradeScore        int     `json:"grade_score"`     // 0-100
 	CountryRisk       string  `json:"country_risk"`    // low, medium, high
 	CountryScore      int     `json:"country_score"`   // Score contribution from country
-	HistoryScore      int     `json:"history_score"`   // Score contribution from repeat buyer
+	HistoryScore      int     `json:"history_score"`   // Score contribution from trade history
 	DocumentScore     int     `json:"document_score"`  // Score from document completeness
 	IsRepeatBuyer     bool    `json:"is_repeat_buyer"`
 	DocumentsComplete bool    `json:"documents_complete"`
-	FundingLimit      float64 `json:"funding_limit"` // 60% for new, 100% for repeat
+	FundingLimit      float64 `json:"funding_limit"` // 60% for new, 100% for repeat, adjusted by credit score
+	CreditScore       int     `json:"credit_score"`  // The exporter's credit score, 0-100
+	CreditRiskLevel   string  `json:"credit_risk_level"`
+
+	ScorecardVersion int                  `json:"scorecard_version"` // Active scorecard that produced the suggestion
+	Factors          []FactorContribution `json:"factors"`           // Each factor's contribution to grade_score
 }
 
 // ApproveInvoiceRequest - legacy
@@ -224,6 +233,7 @@ type InvoiceReviewData struct {
 	Exporter        *UserProfile                 `json:"exporter"`
 	Documents       []DocumentValidationStatus   `json:"documents"`
 	GradeSuggestion AdminGradeSuggestionResponse `json:"grade_suggestion"`
+	CreditScore     *CreditScoreResponse         `json:"credit_score,omitempty"` // The exporter's credit record
 }
 
 // ValidateDocumentRequest is the request to validate/revise a document
@@ -256,20 +266,22 @@ type InvoiceFilter struct {
 // RepeatBuyerCheckRequest is for checking if buyer is repeat buyer
 type RepeatBuyerCheckRequest struct {
 	BuyerCompanyName string `json:"buyer_company_name" binding:"required"`
+	BuyerCountry     string `json:"buyer_country,omitempty"` // Without it the buyer is not looked up
 }
 
 // RepeatBuyerCheckResponse contains the result of repeat buyer check
 type RepeatBuyerCheckResponse struct {
-	IsRepeatBuyer        bool    `json:"is_repeat_buyer"`
-	Message              string  `json:"message"`
-	PreviousTransactions int     `json:"previous_transactions,omitempty"`
-	FundingLimit         float64 `json:"funding_limit"` // 60% for new, 100% for repeat
+	IsRepeatBuyer        bool       `json:"is_repeat_buyer"`
+	Message              string     `json:"message"`
+	PreviousTransactions int        `json:"previous_transactions,omitempty"`
+	FundingLimit         float64    `json:"funding_limit"`      // 60% for new, 100% for repeat
+	BuyerID              *uuid.UUID `json:"buyer_id,omitempty"` // Set when the buyer is in the registry
 }
 
 // EstimatedDisbursement contains the net disbursement calculation
 type EstimatedDisbursement struct {
-	GrossAmount     float64 `json:"gross_amount"`     // Total IDR amount
-	PlatformFee     float64 `json:"platform_fee"`     // 2%
-	NetDisbursement float64 `json:"net_disbursement"` // After fee
-	Currency        string  `json:"currency"`         // IDR
+	GrossAmount     money.Amount `json:"gross_amount"`     // Total IDR amount
+	PlatformFee     money.Amount `json:"platform_fee"`     // 2%
+	NetDisbursement money.Amount `json:"net_disbursement"` // After fee
+	Currency        string       `json:"currency"`         // IDR
 }
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/money"
+)
+
+// LateCharge is what a pool's invoice owes for being repaid after its due date:
+// a one-off late fee plus penalty interest accrued daily on the overdue balance.
+// The grade's rule is fixed when the pool first falls overdue.
+type LateCharge struct {
+	ID              uuid.UUID    `json:"id"`
+	PoolID          uuid.UUID    `json:"pool_id"`
+	InvoiceID       uuid.UUID    `json:"invoice_id"`
+	Grade           string       `json:"grade"`
+	Currency        string       `json:"currency"`
+	DueDate         time.Time    `json:"due_date"`
+	LateFeePercent  float64      `json:"late_fee_percent"`
+	PenaltyRate     float64      `json:"penalty_rate"` // Annual, accrued daily
+	LateFee         money.Amount `json:"late_fee"`
+	PenaltyInterest money.Amount `json:"penalty_interest"`
+	Paid            money.Amount `json:"paid"`
+	AccruedThrough  time.Time    `json:"accrued_through"` // Penalty interest is accrued up to this day
+	AccrualBalance  money.Amount `json:"accrual_balance"` // Overdue balance penalty interest currently accrues on
+	AccrualStart    time.Time    `json:"accrual_start"`   // Day the overdue balance last changed
+	CreatedAt       time.Time    `json:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at"`
+}
+
+// Total is every late charge accrued so far
+func (c *LateCharge) Total() money.Amount {
+	return c.LateFee + c.PenaltyInterest
+}
+
+// Outstanding is what is accrued but not yet paid
+func (c *LateCharge) Outstanding() money.Amount {
+	return money.Max(c.Total()-c.Paid, 0)
+}
@@ -0,0 +1,150 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/money"
+)
+
+// LedgerAccountType classifies a ledger account for double-entry bookkeeping
+type LedgerAccountType string
+
+const (
+	LedgerAccountAsset     LedgerAccountType = "asset"     // Cash held in escrow
+	LedgerAccountLiability LedgerAccountType = "liability" // Funds owed to users and pools
+	LedgerAccountEquity    LedgerAccountType = "equity"    // Opening balances and admin adjustments
+	LedgerAccountRevenue   LedgerAccountType = "revenue"   // Platform fees
+)
+
+// DebitNormal reports whether the account's balance grows with debits
+func (t LedgerAccountType) DebitNormal() bool {
+	return t == LedgerAccountAsset
+}
+
+// Fixed platform account codes
+const (
+	LedgerCodeEscrow          = "escrow"
+	LedgerCodePlatformRevenue = "platform_revenue"
+	LedgerCodePlatformEquity  = "platform_equity"
+)
+
+// Journal entry reference types
+const (
+	LedgerRefDeposit        = "deposit"
+	LedgerRefWithdrawal     = "withdrawal"
+	LedgerRefAdjustment     = "admin_adjustment"
+	LedgerRefInvestment     = "investment"
+	LedgerRefDisbursement   = "disbursement"
+	LedgerRefRepayment      = "repayment"
+	LedgerRefInvestorReturn = "investor_return"
+	LedgerRefRefund         = "refund"
+	LedgerRefRecovery       = "default_recovery"
+	LedgerRefSecondaryTrade = "secondary_trade"
+)
+
+type LedgerAccount struct {
+	ID            uuid.UUID         `json:"id"`
+	Code          string            `json:"code"`
+	Name          string            `json:"name"`
+	Type          LedgerAccountType `json:"type"`
+	OwnerType     *string           `json:"owner_type,omitempty"`
+	OwnerID       *uuid.UUID        `json:"owner_id,omitempty"`
+	Currency      string            `json:"currency"`
+	AllowNegative bool              `json:"allow_negative"`
+	CreatedAt     time.Time         `json:"created_at"`
+}
+
+// JournalEntry is a single balanced money movement; the sum of its lines must be zero
+type JournalEntry struct {
+	ID            uuid.UUID     `json:"id"`
+	ReferenceType string        `json:"reference_type"`
+	ReferenceID   *uuid.UUID    `json:"reference_id,omitempty"`
+	Description   string        `json:"description"`
+	Lines         []JournalLine `json:"lines"`
+	CreatedAt     time.Time     `json:"created_at"`
+}
+
+// JournalLine posts an amount to one account. Debits are positive, credits are negative.
+type JournalLine struct {
+	ID        uuid.UUID      `json:"id"`
+	EntryID   uuid.UUID      `json:"entry_id"`
+	AccountID uuid.UUID      `json:"account_id"`
+	Amount    money.Amount   `json:"amount"`
+	Account   *LedgerAccount `json:"account,omitempty"`
+}
+
+// Debit builds a debit line against the given account
+func Debit(account *LedgerAccount, amount money.Amount) JournalLine {
+	return JournalLine{Account: account, Amount: amount}
+}
+
+// Credit builds a credit line against the given account
+func Credit(account *LedgerAccount, amount money.Amount) JournalLine {
+	return JournalLine{Account: account, Amount: -amount}
+}
+
+// EscrowAccount is the platform's cash held at the escrow bank
+func EscrowAccount() *LedgerAccount {
+	return &LedgerAccount{Code: LedgerCodeEscrow, Name: "Escrow cash", Type: LedgerAccountAsset, Currency: "IDR", AllowNegative: true}
+}
+
+// PlatformRevenueAccount collects platform fees
+func PlatformRevenueAccount() *LedgerAccount {
+	return &LedgerAccount{Code: LedgerCodePlatformRevenue, Name: "Platform revenue", Type: LedgerAccountRevenue, Currency: "IDR", AllowNegative: true}
+}
+
+// PlatformEquityAccount absorbs admin adjustments
+func PlatformEquityAccount() *LedgerAccount {
+	return &LedgerAccount{Code: LedgerCodePlatformEquity, Name: "Platform equity", Type: LedgerAccountEquity, Currency: "IDR", AllowNegative: true}
+}
+
+// UserWalletAccount is the spendable balance the platform owes a user
+func UserWalletAccount(userID uuid.UUID) *LedgerAccount {
+	ownerType := "user"
+	return &LedgerAccount{
+		Code:      fmt.Sprintf("user:%s", userID),
+		Name:      "User wallet",
+		Type:      LedgerAccountLiability,
+		OwnerType: &ownerType,
+		OwnerID:   &userID,
+		Currency:  "IDR",
+	}
+}
+
+// PoolAccount holds investor funds committed to a pool until disbursement
+func PoolAccount(poolID uuid.UUID) *LedgerAccount {
+	ownerType := "pool"
+	return &LedgerAccount{
+		Code:      fmt.Sprintf("pool:%s", poolID),
+		Name:      "Funding pool",
+		Type:      LedgerAccountLiability,
+		OwnerType: &ownerType,
+		OwnerID:   &poolID,
+		Currency:  "IDR",
+	}
+}
+
+// TrialBalanceLine is one account row in the trial balance
+type TrialBalanceLine struct {
+	AccountID   uuid.UUID         `json:"account_id"`
+	Code        string            `json:"code"`
+	Name        string            `json:"name"`
+	Type        LedgerAccountType `json:"type"`
+	TotalDebit  money.Amount      `json:"total_debit"`
+	TotalCredit money.Amount      `json:"total_credit"`
+	Balance     money.Amount      `json:"balance"`
+}
+
+// TrialBalance proves the books balance: total debits equal total credits,
+// every journal entry nets to zero and cached user balances match the ledger
+type TrialBalance struct {
+	Accounts          []TrialBalanceLine `json:"accounts"`
+	TotalDebit        money.Amount       `json:"total_debit"`
+	TotalCredit       money.Amount       `json:"total_credit"`
+	UnbalancedEntries int                `json:"unbalanced_entries"`
+	BalanceMismatches int                `json:"balance_mismatches"`
+	Balanced          bool               `json:"balanced"`
+	GeneratedAt       time.Time          `json:"generated_at"`
+}
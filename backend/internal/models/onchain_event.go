@@ -0,0 +1,47 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/money"
+)
+
+// OnchainEvent is a decoded InvoiceNFT or InvoicePool log
+type OnchainEvent struct {
+	ID              uuid.UUID       `json:"id"`
+	ChainID         int64           `json:"chain_id"`
+	Contract        string          `json:"contract"` // InvoiceNFT or InvoicePool
+	ContractAddress string          `json:"contract_address"`
+	EventName       string          `json:"event_name"`
+	TokenID         *string         `json:"token_id,omitempty"`
+	BlockNumber     int64           `json:"block_number"`
+	BlockHash       string          `json:"block_hash"`
+	TxHash          string          `json:"tx_hash"`
+	LogIndex        int             `json:"log_index"`
+	Args            json.RawMessage `json:"args"` // Event arguments; integers as decimal strings, addresses and bytes as hex
+	CreatedAt       time.Time       `json:"created_at"`
+
+	Amounts map[string]money.Amount `json:"amounts,omitempty"` // Token amount args converted to the pool currency (InvoiceMinted: the invoice currency)
+}
+
+// IndexedBlock is a block hash the indexer saw, kept to detect reorgs
+type IndexedBlock struct {
+	Number int64
+	Hash   string
+}
+
+// PoolOnchainEventsResponse lists a pool's contract events so investors can
+// check them against the chain
+type PoolOnchainEventsResponse struct {
+	PoolID       uuid.UUID      `json:"pool_id"`
+	InvoiceID    uuid.UUID      `json:"invoice_id"`
+	TokenID      *int64         `json:"token_id"` // Null until the indexer has seen the mint
+	ChainID      int64          `json:"chain_id"`
+	Currency     string         `json:"currency"`
+	NFTContract  string         `json:"nft_contract"`
+	PoolContract string         `json:"pool_contract"`
+	IndexedBlock int64          `json:"indexed_block"` // Events up to this block are included
+	Events       []OnchainEvent `json:"events"`
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/money"
+)
+
+// OnchainAction is an InvoicePool contract write queued in the outbox
+type OnchainAction string
+
+const (
+	OnchainActionCreatePool         OnchainAction = "create_pool"
+	OnchainActionRecordInvestment   OnchainAction = "record_investment"
+	OnchainActionRecordDisbursement OnchainAction = "record_disbursement"
+	OnchainActionRecordRepayment    OnchainAction = "record_repayment"
+	OnchainActionRecordMitraCredit  OnchainAction = "record_mitra_credit"
+	OnchainActionMarkDefaulted      OnchainAction = "mark_defaulted"
+	OnchainActionBurnNFT            OnchainAction = "burn_nft"            // InvoiceNFT.burnInvoice once the pool is repaid
+	OnchainActionTransferInvestment OnchainAction = "transfer_investment" // Secondary market sale of a position
+)
+
+type OutboxStatus string
+
+const (
+	OutboxStatusPending    OutboxStatus = "pending"    // Waiting for (re)submission
+	OutboxStatusProcessing OutboxStatus = "processing" // Claimed by a worker
+	OutboxStatusSubmitted  OutboxStatus = "submitted"  // Sent to the chain, waiting for confirmations
+	OutboxStatusConfirmed  OutboxStatus = "confirmed"  // Mined with enough confirmations
+	OutboxStatusFailed     OutboxStatus = "failed"     // Gave up after max attempts, needs admin retry
+	OutboxStatusCancelled  OutboxStatus = "cancelled"  // Failed entry skipped by an admin, no longer blocks the invoice
+)
+
+// OnchainOutboxEntry is an on-chain write recorded in the same database
+// transaction as the business change it mirrors. Entries of one invoice are
+// submitted strictly in sequence order.
+type OnchainOutboxEntry struct {
+	ID               uuid.UUID       `json:"id"`
+	Seq              int64           `json:"seq"`
+	Action           OnchainAction   `json:"action"`
+	InvoiceID        uuid.UUID       `json:"invoice_id"`
+	PoolID           *uuid.UUID      `json:"pool_id,omitempty"`
+	TransactionIDs   []uuid.UUID     `json:"transaction_ids"` // Transactions whose block info is filled in on confirmation
+	Payload          json.RawMessage `json:"payload"`
+	Status           OutboxStatus    `json:"status"`
+	Attempts         int             `json:"attempts"`
+	NextAttemptAt    time.Time       `json:"next_attempt_at"`
+	LockedUntil      *time.Time      `json:"locked_until,omitempty"`
+	LastError        *string         `json:"last_error,omitempty"`
+	TxHash           *string         `json:"tx_hash,omitempty"`
+	Nonce            *int64          `json:"nonce,omitempty"`
+	GasPrice         *string         `json:"gas_price,omitempty"` // Wei, decimal
+	GasLimit         *int64          `json:"gas_limit,omitempty"`
+	PreviousTxHashes []string        `json:"previous_tx_hashes"` // Hashes replaced with a higher gas price
+	BlockNumber      *int64          `json:"block_number,omitempty"`
+	GasUsed          *int64          `json:"gas_used,omitempty"`
+	SubmittedAt      *time.Time      `json:"submitted_at,omitempty"`
+	ConfirmedAt      *time.Time      `json:"confirmed_at,omitempty"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+}
+
+// OnchainSubmission is a broadcast transaction for an outbox entry. The send
+// parameters are empty when the chain is simulated.
+type OnchainSubmission struct {
+	TxHash   string
+	Nonce    *int64
+	GasPrice *string
+	GasLimit *int64
+}
+
+// OnchainPayload carries the arguments of the contract call, captured when the
+// entry is written so a retry sends what was committed
+type OnchainPayload struct {
+	Wallet     string       `json:"wallet,omitempty"` // Investor or mitra wallet; the buyer for transfer_investment
+	Amount     money.Amount `json:"amount,omitempty"`
+	FromWallet string       `json:"from_wallet,omitempty"` // transfer_investment: seller wallet
+	Position   money.Amount `json:"position,omitempty"`    // transfer_investment: seller principal before the sale
+}
+
+// OnchainOutboxFilter selects outbox entries for the admin view
+type OnchainOutboxFilter struct {
+	Status       string `form:"status"`        // pending, processing, submitted, confirmed, failed, cancelled, or "stuck"
+	StuckMinutes int    `form:"stuck_minutes"` // Age after which an unsubmitted entry counts as stuck (default 15)
+	Page         int    `form:"page"`
+	PerPage      int    `form:"per_page"`
+}
+
+// CancelOutboxEntryRequest skips a failed outbox entry
+type CancelOutboxEntryRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// OnchainOutboxListResponse is the admin view of the outbox
+type OnchainOutboxListResponse struct {
+	Entries    []OnchainOutboxEntry `json:"entries"`
+	Counts     map[string]int       `json:"counts"` // Entries per status
+	Total      int                  `json:"total"`
+	Page       int                  `json:"page"`
+	PerPage    int                  `json:"per_page"`
+	TotalPages int                  `json:"total_pages"`
+}
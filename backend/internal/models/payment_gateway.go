@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/money"
+)
+
+// GatewayPaymentPurpose identifies which flow settles a gateway payment
+type GatewayPaymentPurpose string
+
+const (
+	GatewayPurposeDeposit         GatewayPaymentPurpose = "deposit"          // User balance top-up, references a transaction
+	GatewayPurposeMitraRepayment  GatewayPaymentPurpose = "mitra_repayment"  // Mitra repayment VA, references a virtual account
+	GatewayPurposeImporterPayment GatewayPaymentPurpose = "importer_payment" // Importer invoice payment, references an importer payment
+)
+
+type GatewayPaymentStatus string
+
+const (
+	GatewayPaymentPending  GatewayPaymentStatus = "pending"
+	GatewayPaymentPaid     GatewayPaymentStatus = "paid"
+	GatewayPaymentExpired  GatewayPaymentStatus = "expired"
+	GatewayPaymentFailed   GatewayPaymentStatus = "failed"
+	GatewayPaymentRefunded GatewayPaymentStatus = "refunded"
+)
+
+type GatewayPaymentMethod string
+
+const (
+	GatewayMethodVirtualAccount GatewayPaymentMethod = "virtual_account"
+	GatewayMethodPaymentLink    GatewayPaymentMethod = "payment_link"
+)
+
+// GatewayPayment is a charge created with a payment provider and the local
+// record it settles once the provider confirms payment
+type GatewayPayment struct {
+	ID          uuid.UUID             `json:"id"`
+	Provider    string                `json:"provider"`
+	ExternalID  string                `json:"external_id"` // Provider's charge ID
+	Purpose     GatewayPaymentPurpose `json:"purpose"`
+	ReferenceID uuid.UUID             `json:"reference_id"`
+	UserID      *uuid.UUID            `json:"user_id,omitempty"`
+	Method      GatewayPaymentMethod  `json:"method"`
+	Amount      money.Amount          `json:"amount"`
+	Currency    string                `json:"currency"`
+	Status      GatewayPaymentStatus  `json:"status"`
+	VANumber    *string               `json:"va_number,omitempty"`
+	PaymentURL  *string               `json:"payment_url,omitempty"`
+	ExpiresAt   *time.Time            `json:"expires_at,omitempty"`
+	PaidAt      *time.Time            `json:"paid_at,omitempty"`
+	CreatedAt   time.Time             `json:"created_at"`
+	UpdatedAt   time.Time             `json:"updated_at"`
+}
+
+// PaymentWebhookEvent records a provider callback so redeliveries are processed once
+type PaymentWebhookEvent struct {
+	ID          uuid.UUID  `json:"id"`
+	Provider    string     `json:"provider"`
+	EventID     string     `json:"event_id"`
+	Payload     []byte     `json:"payload"`
+	ReceivedAt  time.Time  `json:"received_at"`
+	ProcessedAt *time.Time `json:"processed_at,omitempty"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReconciliationField names what a reconciliation difference is about
+type ReconciliationField string
+
+const (
+	ReconcileTokenUnverified   ReconciliationField = "token_unverified"   // Token id not yet read from the mint, the invoice is not compared
+	ReconcileNFTMissing        ReconciliationField = "nft_missing"        // Token does not exist on-chain
+	ReconcileNFTOwner          ReconciliationField = "nft_owner"          // ownerOf differs from invoice_nfts.owner_address
+	ReconcileNFTStatus         ReconciliationField = "nft_status"         // InvoiceNFT status does not follow the pool
+	ReconcilePoolMissing       ReconciliationField = "pool_missing"       // Pool exists in Postgres only
+	ReconcilePoolStatus        ReconciliationField = "pool_status"        // InvoicePool status differs
+	ReconcilePoolFundedAmount  ReconciliationField = "pool_funded_amount" // getPool fundedAmount vs funding_pools.funded_amount
+	ReconcilePoolInvestorCount ReconciliationField = "pool_investor_count"
+	ReconcileInvestmentMissing ReconciliationField = "investment_missing" // Investment exists in Postgres only
+	ReconcileInvestmentUnknown ReconciliationField = "investment_unknown" // Investment exists on-chain only
+	ReconcileInvestorReturn    ReconciliationField = "investor_return"    // Recorded return differs from investments.actual_return
+)
+
+// ReconciliationDiff is one disagreement between Postgres and the contracts.
+// Amounts are token base units, as stored on-chain.
+type ReconciliationDiff struct {
+	InvoiceID    uuid.UUID           `json:"invoice_id"`
+	PoolID       *uuid.UUID          `json:"pool_id,omitempty"`
+	TokenID      int64               `json:"token_id"` // 0 for token_unverified
+	InvestmentID *uuid.UUID          `json:"investment_id,omitempty"`
+	Field        ReconciliationField `json:"field"`
+	Database     string              `json:"database"`
+	Chain        string              `json:"chain"`
+	Correction   *OnchainAction      `json:"correction,omitempty"` // Outbox action that brings the chain in line, if any
+	Enqueued     bool                `json:"enqueued"`
+	Note         string              `json:"note,omitempty"`
+}
+
+// ReconciliationReport is the result of one reconciliation run
+type ReconciliationReport struct {
+	StartedAt          time.Time            `json:"started_at"`
+	FinishedAt         time.Time            `json:"finished_at"`
+	ChainID            int64                `json:"chain_id"`
+	InvoicesChecked    int                  `json:"invoices_checked"`
+	InvoicesWithDiffs  int                  `json:"invoices_with_diffs"`
+	InvoicesUnverified int                  `json:"invoices_unverified"` // Skipped: token id not verified on-chain
+	Enqueued           int                  `json:"enqueued"`
+	Diffs              []ReconciliationDiff `json:"diffs"`
+	Errors             []string             `json:"errors,omitempty"` // Invoices that could not be read
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/money"
+)
+
+// RepaymentInstallment is one payment received against a disbursed pool and how
+// it went through the waterfall. Amounts are in the pool currency.
+type RepaymentInstallment struct {
+	ID                uuid.UUID                    `json:"id"`
+	PoolID            uuid.UUID                    `json:"pool_id"`
+	InvoiceID         uuid.UUID                    `json:"invoice_id"`
+	ImporterPaymentID *uuid.UUID                   `json:"importer_payment_id,omitempty"` // Set when the importer paid through the gateway
+	Amount            money.Amount                 `json:"amount"`
+	PlatformFee       money.Amount                 `json:"platform_fee"`
+	ToPriority        money.Amount                 `json:"to_priority"`
+	ToCatalyst        money.Amount                 `json:"to_catalyst"`
+	ToTranches        map[TrancheType]money.Amount `json:"to_tranches,omitempty"` // Paid to each tranche by name
+	LateCharges       money.Amount                 `json:"late_charges"`          // Late fee and penalty interest collected
+	ExcessToMitra     money.Amount                 `json:"excess_to_mitra"`       // Left after every investor claim was met
+	Settled           bool                         `json:"settled"`               // This installment closed the pool
+	CreatedAt         time.Time                    `json:"created_at"`
+}
+
+// ProcessRepaymentRequest records a repayment received outside the gateway.
+// Final closes the pool with this payment even when investors are still short.
+type ProcessRepaymentRequest struct {
+	Amount money.Amount `json:"amount" binding:"required,gt=0"`
+	Final  bool         `json:"final"`
+}
+
+// RepaymentSummary is the repayment progress of a pool
+type RepaymentSummary struct {
+	PoolID       uuid.UUID              `json:"pool_id"`
+	Currency     string                 `json:"currency"`
+	TotalClaim   money.Amount           `json:"total_claim"`  // Expected returns of every investment
+	TotalPaid    money.Amount           `json:"total_paid"`   // Received so far, platform fees included
+	TotalRepaid  money.Amount           `json:"total_repaid"` // Paid out to investors so far
+	Outstanding  money.Amount           `json:"outstanding"`  // Investor claims not yet met
+	Settled      bool                   `json:"settled"`
+	LateCharge   *LateCharge            `json:"late_charge,omitempty"` // Set once the pool fell overdue
+	Installments []RepaymentInstallment `json:"installments"`
+}
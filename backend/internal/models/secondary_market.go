@@ -0,0 +1,92 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/money"
+)
+
+// ListingStatus tracks a secondary market offer
+type ListingStatus string
+
+const (
+	ListingStatusOpen      ListingStatus = "open"      // Waiting for a buyer
+	ListingStatusSold      ListingStatus = "sold"      // Bought; the position changed hands
+	ListingStatusCancelled ListingStatus = "cancelled" // Withdrawn by the seller
+)
+
+// SecondaryListing offers all or part of an active investment to other
+// investors. Principal is the part of the position for sale; a buyer takes it
+// with the same share of the expected return.
+type SecondaryListing struct {
+	ID                uuid.UUID     `json:"id"`
+	InvestmentID      uuid.UUID     `json:"investment_id"`
+	SellerID          uuid.UUID     `json:"seller_id"`
+	PoolID            uuid.UUID     `json:"pool_id"`
+	Tranche           TrancheType   `json:"tranche"`
+	Principal         money.Amount  `json:"principal"`
+	Price             money.Amount  `json:"price"`
+	Currency          string        `json:"currency"`
+	Status            ListingStatus `json:"status"`
+	BuyerID           *uuid.UUID    `json:"buyer_id,omitempty"`
+	BuyerInvestmentID *uuid.UUID    `json:"buyer_investment_id,omitempty"` // Investment the buyer received
+	SoldAt            *time.Time    `json:"sold_at,omitempty"`
+	CancelledAt       *time.Time    `json:"cancelled_at,omitempty"`
+	CreatedAt         time.Time     `json:"created_at"`
+	UpdatedAt         time.Time     `json:"updated_at"`
+
+	// Filled in for the marketplace view
+	PositionAmount money.Amount `json:"position_amount,omitempty"` // Principal of the whole position
+	ExpectedReturn money.Amount `json:"expected_return,omitempty"` // Share of the position's expected return that comes with the principal
+	InvoiceNumber  string       `json:"invoice_number,omitempty"`
+	DueDate        *time.Time   `json:"due_date,omitempty"`
+}
+
+// PositionShare is the part of amount (an expected or received return of a
+// position) that goes with principal when principal of position is sold. It is
+// rounded to the currency's minor unit; the seller keeps the remainder.
+func PositionShare(amount, principal, position money.Amount, currency string) money.Amount {
+	if principal >= position {
+		return amount
+	}
+	return money.Allocate(amount, []money.Amount{principal, position - principal}, money.MinorUnit(currency))[0]
+}
+
+// CreateListingRequest lists a position for sale. Principal defaults to the
+// whole position.
+type CreateListingRequest struct {
+	InvestmentID uuid.UUID    `json:"investment_id" binding:"required"`
+	Principal    money.Amount `json:"principal" binding:"gte=0"`
+	Price        money.Amount `json:"price" binding:"required,gt=0"`
+}
+
+// BuyListingRequest buys a listed position. Risk consents are required for
+// tranches that take them at investment time.
+type BuyListingRequest struct {
+	TncAccepted      bool              `json:"tnc_accepted" binding:"required"`
+	CatalystConsents *CatalystConsents `json:"catalyst_consents,omitempty"`
+}
+
+// SecondaryListingFilter selects listings for the marketplace view
+type SecondaryListingFilter struct {
+	PoolID  string `form:"pool_id"`
+	Tranche string `form:"tranche"`
+	Page    int    `form:"page"`
+	PerPage int    `form:"per_page"`
+}
+
+// SecondaryListingListResponse is a page of listings
+type SecondaryListingListResponse struct {
+	Listings   []SecondaryListing `json:"listings"`
+	Total      int                `json:"total"`
+	Page       int                `json:"page"`
+	PerPage    int                `json:"per_page"`
+	TotalPages int                `json:"total_pages"`
+}
+
+// SecondaryPurchaseResponse is the outcome of buying a listing
+type SecondaryPurchaseResponse struct {
+	Listing    *SecondaryListing `json:"listing"`
+	Investment *Investment       `json:"investment"` // The buyer's position
+}
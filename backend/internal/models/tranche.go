@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/money"
+)
+
+// MaxPoolTranches caps how many tranches an admin can split a pool into
+const MaxPoolTranches = 5
+
+// PoolTranche is one tranche of a funding pool. Seniority 1 is the most senior:
+// repayments reach it first and it is the last to absorb a loss.
+type PoolTranche struct {
+	ID           uuid.UUID    `json:"id"`
+	PoolID       uuid.UUID    `json:"pool_id"`
+	Name         TrancheType  `json:"name"`
+	Seniority    int          `json:"seniority"`
+	TargetAmount money.Amount `json:"target_amount"`
+	FundedAmount money.Amount `json:"funded_amount"`
+	InterestRate float64      `json:"interest_rate"` // Annual ("p.a")
+
+	// Eligibility
+	RequiresRiskConsent bool         `json:"requires_risk_consent"` // Investor accepts the first-loss risk statements
+	RequiresRiskProfile bool         `json:"requires_risk_profile"` // Investor's risk questionnaire must unlock junior tranches
+	MinInvestment       money.Amount `json:"min_investment"`        // 0 for no minimum
+	MaxInvestment       money.Amount `json:"max_investment"`        // Per investment, 0 for no maximum
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Remaining is the capacity still open for investment
+func (t *PoolTranche) Remaining() money.Amount {
+	return money.Max(t.TargetAmount-t.FundedAmount, 0)
+}
+
+// Tranche returns the pool tranche with the given name, nil when the pool has none
+func (p *FundingPool) Tranche(name TrancheType) *PoolTranche {
+	for i := range p.Tranches {
+		if p.Tranches[i].Name == name {
+			return &p.Tranches[i]
+		}
+	}
+	return nil
+}
+
+// CreatePoolRequest optionally lays out a pool's tranches, most senior first.
+// Without tranches the pool gets the invoice's priority/catalyst split.
+type CreatePoolRequest struct {
+	Tranches []CreateTrancheRequest `json:"tranches" binding:"omitempty,max=5,dive"`
+}
+
+// CreateTrancheRequest is one tranche of a new pool. SizePercent is its share of
+// the pool target; the sizes must add up to 100.
+type CreateTrancheRequest struct {
+	Name                TrancheType  `json:"name" binding:"required,max=30"`
+	SizePercent         float64      `json:"size_percent" binding:"required,gt=0,lte=100"`
+	InterestRate        float64      `json:"interest_rate" binding:"required,gt=0,lte=100"`
+	RequiresRiskConsent bool         `json:"requires_risk_consent"`
+	RequiresRiskProfile bool         `json:"requires_risk_profile"`
+	MinInvestment       money.Amount `json:"min_investment" binding:"gte=0"`
+	MaxInvestment       money.Amount `json:"max_investment" binding:"gte=0"`
+}
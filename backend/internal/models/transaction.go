@@ -4,21 +4,25 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/money"
 )
 
 type TransactionType string
 type TransactionStatus string
 
 const (
-	TxTypeInvestment      TransactionType = "investment"
-	TxTypeAdvancePayment  TransactionType = "advance_payment"
-	TxTypeBuyerRepayment  TransactionType = "buyer_repayment"
-	TxTypeInvestorReturn  TransactionType = "investor_return"
-	TxTypePlatformFee     TransactionType = "platform_fee"
-	TxTypeRefund          TransactionType = "refund"
-	TxTypeDeposit         TransactionType = "deposit"
-	TxTypeWithdrawal      TransactionType = "withdrawal"
-	TxTypeRepaymentExcess TransactionType = "repayment_excess" // Excess from partial funding scenario goes to mitra balance
+	TxTypeInvestment        TransactionType = "investment"
+	TxTypeAdvancePayment    TransactionType = "advance_payment"
+	TxTypeBuyerRepayment    TransactionType = "buyer_repayment"
+	TxTypeInvestorReturn    TransactionType = "investor_return"
+	TxTypePlatformFee       TransactionType = "platform_fee"
+	TxTypeRefund            TransactionType = "refund"
+	TxTypeDeposit           TransactionType = "deposit"
+	TxTypeWithdrawal        TransactionType = "withdrawal"
+	TxTypeRepaymentExcess   TransactionType = "repayment_excess"   // Excess from partial funding scenario goes to mitra balance
+	TxTypeDefaultRecovery   TransactionType = "default_recovery"   // Money collected after a default, paid to investors
+	TxTypeSecondaryPurchase TransactionType = "secondary_purchase" // Price paid for a position bought on the secondary market
+	TxTypeSecondarySale     TransactionType = "secondary_sale"     // Price received for a position sold on the secondary market
 
 	TxStatusPending   TransactionStatus = "pending"
 	TxStatusConfirmed TransactionStatus = "confirmed"
@@ -30,7 +34,7 @@ type Transaction struct {
 	InvoiceID   *uuid.UUID        `json:"invoice_id,omitempty"`
 	UserID      *uuid.UUID        `json:"user_id,omitempty"`
 	Type        TransactionType   `json:"type"`
-	Amount      float64           `json:"amount"`
+	Amount      money.Amount      `json:"amount"`
 	Currency    string            `json:"currency"`
 	TxHash      *string           `json:"tx_hash,omitempty"`
 	Status      TransactionStatus `json:"status"`
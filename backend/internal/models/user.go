@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/money"
 )
 
 type UserRole string
@@ -35,7 +36,7 @@ type User struct {
 	IsActive             bool         `json:"is_active"`
 	CooperativeAgreement bool         `json:"cooperative_agreement"`
 	MemberStatus         MemberStatus `json:"member_status"`
-	BalanceIDR           float64      `json:"balance_idr"`
+	BalanceIDR           money.Amount `json:"balance_idr"`
 	EmailVerified        bool         `json:"email_verified"`
 	ProfileCompleted     bool         `json:"profile_completed"`
 	WalletAddress        *string      `json:"wallet_address,omitempty"`
@@ -98,8 +99,8 @@ type LoginRequest struct {
 
 // UserBalanceResponse represents user balance info
 type UserBalanceResponse struct {
-	BalanceIDR float64 `json:"balance_idr"`
-	Currency   string  `json:"currency"`
+	BalanceIDR money.Amount `json:"balance_idr"`
+	Currency   string       `json:"currency"`
 }
 
 type LoginResponse struct {
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WalletProofMethod is how a wallet signs its ownership challenge
+type WalletProofMethod string
+
+const (
+	WalletProofEIP191 WalletProofMethod = "eip191" // personal_sign of the challenge message
+	WalletProofEIP712 WalletProofMethod = "eip712" // eth_signTypedData_v4 of the challenge typed data
+)
+
+// WalletChallenge is a one-time nonce a user signs to prove they control a wallet
+type WalletChallenge struct {
+	ID            uuid.UUID         `json:"id"`
+	UserID        uuid.UUID         `json:"user_id"`
+	WalletAddress string            `json:"wallet_address"`
+	Method        WalletProofMethod `json:"method"`
+	Nonce         string            `json:"nonce"`
+	Message       string            `json:"message"`
+	IssuedAt      time.Time         `json:"issued_at"`
+	ExpiresAt     time.Time         `json:"expires_at"`
+	UsedAt        *time.Time        `json:"used_at,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+}
+
+// WalletLink is a wallet a user proved ownership of. The current wallet is the
+// link without unlinked_at; earlier ones are kept as history.
+type WalletLink struct {
+	ID            uuid.UUID         `json:"id"`
+	UserID        uuid.UUID         `json:"user_id"`
+	WalletAddress string            `json:"wallet_address"`
+	ChallengeID   *uuid.UUID        `json:"challenge_id,omitempty"`
+	Method        WalletProofMethod `json:"method"`
+	Signature     string            `json:"signature"`
+	LinkedAt      time.Time         `json:"linked_at"`
+	UnlinkedAt    *time.Time        `json:"unlinked_at,omitempty"`
+}
+
+// WalletChallengeRequest asks for a challenge for the given wallet
+type WalletChallengeRequest struct {
+	WalletAddress string            `json:"wallet_address" binding:"required"`
+	Method        WalletProofMethod `json:"method" binding:"omitempty,oneof=eip191 eip712"` // Defaults to eip191
+}
+
+// WalletChallengeResponse is what the wallet has to sign: Message for eip191,
+// TypedData for eip712
+type WalletChallengeResponse struct {
+	ChallengeID uuid.UUID         `json:"challenge_id"`
+	Method      WalletProofMethod `json:"method"`
+	Message     string            `json:"message"`
+	TypedData   json.RawMessage   `json:"typed_data,omitempty"`
+	ExpiresAt   time.Time         `json:"expires_at"`
+}
+
+// LinkWalletRequest submits the signed challenge
+type LinkWalletRequest struct {
+	ChallengeID uuid.UUID `json:"challenge_id" binding:"required"`
+	Signature   string    `json:"signature" binding:"required"` // 65-byte hex signature
+}
@@ -0,0 +1,72 @@
+package money
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// DayCount is the day-count convention used to accrue an annual interest rate
+// over a period
+type DayCount string
+
+const (
+	Actual365 DayCount = "actual/365" // Calendar days elapsed over a 365-day year
+	Thirty360 DayCount = "30/360"     // 30-day months over a 360-day year (bond basis)
+)
+
+// ParseDayCount reads a convention name such as "actual/365", "ACT/365" or "30/360"
+func ParseDayCount(s string) (DayCount, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "actual/365", "act/365", "actual/365f", "act/365f":
+		return Actual365, nil
+	case "30/360", "30u/360", "bond":
+		return Thirty360, nil
+	}
+	return "", fmt.Errorf("unsupported day count convention %q", s)
+}
+
+// Days counts the days from start to end under the convention. Only the calendar
+// dates (in UTC) matter, and the count is zero when end is not after start.
+func (c DayCount) Days(start, end time.Time) int64 {
+	y1, m1, d1 := start.UTC().Date()
+	y2, m2, d2 := end.UTC().Date()
+
+	var days int64
+	switch c {
+	case Thirty360:
+		// A 31st counts as the 30th; the end date only when the start is a 30th or 31st too
+		if d1 == 31 {
+			d1 = 30
+		}
+		if d2 == 31 && d1 == 30 {
+			d2 = 30
+		}
+		days = 360*int64(y2-y1) + 30*int64(m2-m1) + int64(d2-d1)
+	default:
+		from := time.Date(y1, m1, d1, 0, 0, 0, 0, time.UTC)
+		to := time.Date(y2, m2, d2, 0, 0, 0, 0, time.UTC)
+		days = int64(to.Sub(from).Hours() / 24)
+	}
+	if days < 0 {
+		return 0
+	}
+	return days
+}
+
+// YearFraction is the exact share of a year from start to end
+func (c DayCount) YearFraction(start, end time.Time) *big.Rat {
+	basis := int64(365)
+	if c == Thirty360 {
+		basis = 360
+	}
+	return big.NewRat(c.Days(start, end), basis)
+}
+
+// SimpleInterest accrues an annual percent rate on principal from start to end,
+// rounded half to even to the hundredth. The rate is taken at its shortest
+// decimal form, as in MulRate.
+func SimpleInterest(principal Amount, annualPercent float64, c DayCount, start, end time.Time) Amount {
+	return principal.MulRat(new(big.Rat).Mul(PercentRat(annualPercent), c.YearFraction(start, end)))
+}
@@ -0,0 +1,388 @@
+// Package money provides an exact decimal amount type and currency-aware
+// rounding and allocation helpers. Amounts never pass through float64 when
+// they are stored, summed or split between investors.
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Amount is an exact decimal amount held as an integer number of hundredths,
+// matching the DECIMAL(20,2) columns used for money in Postgres.
+// Use FromInt, FromFloat or Parse to build one; Amount(5) is 0.05, not 5.
+type Amount int64
+
+// Scale is the number of Amount units in one whole currency unit
+const Scale = 100
+
+// Whole is one whole currency unit (e.g. Rp 1)
+const Whole Amount = Scale
+
+var (
+	ErrInvalidAmount = errors.New("invalid amount")
+	ErrPrecisionLoss = errors.New("amount does not fit the token precision")
+)
+
+// FromInt returns an amount of n whole currency units
+func FromInt(n int64) Amount {
+	return Amount(n * Scale)
+}
+
+// FromFloat converts a float to the nearest hundredth using banker's rounding.
+// Only use it at the edges (config values, external APIs); keep arithmetic in Amount.
+func FromFloat(f float64) Amount {
+	a, _ := Parse(strconv.FormatFloat(f, 'f', -1, 64))
+	return a
+}
+
+// Parse reads a decimal string such as "1500000", "12.5" or "1e6".
+// Digits beyond the hundredths are rounded half to even. Values outside the
+// range of an Amount are rejected with ErrInvalidAmount.
+func Parse(s string) (Amount, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	r.Mul(r, big.NewRat(Scale, 1))
+	n := quoHalfEven(r.Num(), r.Denom())
+	if !n.IsInt64() {
+		return 0, fmt.Errorf("%w: %q is out of range", ErrInvalidAmount, s)
+	}
+	return Amount(n.Int64()), nil
+}
+
+// Float64 returns the amount as a float for display or ratios only
+func (a Amount) Float64() float64 {
+	return float64(a) / Scale
+}
+
+// WholeUnits returns the amount in whole currency units, truncating any fraction
+func (a Amount) WholeUnits() int64 {
+	return int64(a) / Scale
+}
+
+// String formats the amount as a plain decimal without trailing zeros
+func (a Amount) String() string {
+	sign := ""
+	v := int64(a)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	whole, frac := v/Scale, v%Scale
+	if frac == 0 {
+		return fmt.Sprintf("%s%d", sign, whole)
+	}
+	return strings.TrimRight(fmt.Sprintf("%s%d.%02d", sign, whole, frac), "0")
+}
+
+// Rat returns the exact amount as a rational number in whole currency units
+func (a Amount) Rat() *big.Rat {
+	return big.NewRat(int64(a), Scale)
+}
+
+// MulRat multiplies by an exact rational factor, rounding half to even to the hundredth
+func (a Amount) MulRat(factor *big.Rat) Amount {
+	r := new(big.Rat).Mul(big.NewRat(int64(a), 1), factor)
+	return Amount(roundHalfEven(r))
+}
+
+// MulRate returns percent% of the amount, rounded half to even to the hundredth.
+// The rate is taken at its shortest decimal representation, so 10.5 means exactly 10.5%.
+func (a Amount) MulRate(percent float64) Amount {
+	return a.MulRat(PercentRat(percent))
+}
+
+// MulFactor multiplies by a plain factor such as an exchange rate, rounding half
+// to even to the hundredth. Like MulRate, the factor is taken at its shortest decimal form.
+func (a Amount) MulFactor(f float64) Amount {
+	return a.MulRat(factorRat(f))
+}
+
+// Round rounds half to even to a multiple of unit (e.g. Whole for whole rupiah)
+func (a Amount) Round(unit Amount) Amount {
+	if unit <= 1 {
+		return a
+	}
+	return Amount(roundHalfEven(big.NewRat(int64(a), int64(unit)))) * unit
+}
+
+// RoundTo applies the rounding rule of the given currency
+func (a Amount) RoundTo(currency string) Amount {
+	return a.Round(MinorUnit(currency))
+}
+
+// Value implements driver.Valuer so amounts are written to DECIMAL columns exactly
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+// Scan implements sql.Scanner for DECIMAL, integer and float columns
+func (a *Amount) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*a = 0
+		return nil
+	case []byte:
+		parsed, err := Parse(string(v))
+		if err != nil {
+			return err
+		}
+		*a = parsed
+		return nil
+	case string:
+		parsed, err := Parse(v)
+		if err != nil {
+			return err
+		}
+		*a = parsed
+		return nil
+	case int64:
+		*a = FromInt(v)
+		return nil
+	case float64:
+		*a = FromFloat(v)
+		return nil
+	}
+	return fmt.Errorf("cannot scan %T into money.Amount", src)
+}
+
+// MarshalJSON writes the amount as a plain JSON number so API payloads keep their shape
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a quoted decimal string
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	parsed, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// MinorUnit is the smallest amount a currency is rounded to.
+// Rupiah (and the IDRX stablecoin) settle in whole rupiah; other currencies in cents.
+func MinorUnit(currency string) Amount {
+	switch strings.ToUpper(currency) {
+	case "IDR", "IDRX", "":
+		return Whole
+	default:
+		return 1
+	}
+}
+
+// PercentRat converts a percentage to an exact rational fraction (10.5 -> 21/200)
+func PercentRat(percent float64) *big.Rat {
+	r := factorRat(percent)
+	return r.Quo(r, big.NewRat(100, 1))
+}
+
+// factorRat reads a float at its shortest decimal representation
+func factorRat(f float64) *big.Rat {
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(f, 'f', -1, 64))
+	if !ok {
+		return new(big.Rat)
+	}
+	return r
+}
+
+// Sum adds amounts
+func Sum(amounts ...Amount) Amount {
+	var total Amount
+	for _, a := range amounts {
+		total += a
+	}
+	return total
+}
+
+// Min returns the smaller amount
+func Min(a, b Amount) Amount {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Max returns the larger amount
+func Max(a, b Amount) Amount {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Ratio returns part/whole as a float for percentages and progress bars; 0 when whole is zero
+func Ratio(part, whole Amount) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return float64(part) / float64(whole)
+}
+
+// Allocate splits total in proportion to weights. Each share is rounded down to a
+// multiple of unit and whatever is left over goes to the largest holder (the first
+// one on ties), so the shares always add up to exactly total and the result is
+// deterministic for the same input order.
+func Allocate(total Amount, weights []Amount, unit Amount) []Amount {
+	shares := make([]Amount, len(weights))
+	if len(weights) == 0 {
+		return shares
+	}
+	if unit < 1 {
+		unit = 1
+	}
+
+	largest := 0
+	var weightSum big.Int
+	for i, w := range weights {
+		if w < 0 {
+			w = 0
+		}
+		if w > weights[largest] {
+			largest = i
+		}
+		weightSum.Add(&weightSum, big.NewInt(int64(w)))
+	}
+	if weightSum.Sign() == 0 {
+		shares[largest] = total
+		return shares
+	}
+
+	negative := total < 0
+	abs := total
+	if negative {
+		abs = -total
+	}
+
+	var allocated Amount
+	bigUnit := big.NewInt(int64(unit))
+	for i, w := range weights {
+		if w <= 0 {
+			continue
+		}
+		share := new(big.Int).Mul(big.NewInt(int64(abs)), big.NewInt(int64(w)))
+		share.Quo(share, &weightSum)
+		share.Quo(share, bigUnit)
+		share.Mul(share, bigUnit)
+		shares[i] = Amount(share.Int64())
+		allocated += shares[i]
+	}
+	shares[largest] += abs - allocated
+
+	if negative {
+		for i := range shares {
+			shares[i] = -shares[i]
+		}
+	}
+	return shares
+}
+
+// roundHalfEven rounds a rational number to the nearest integer, ties to even.
+// It is only used on results that fit an int64 (scaled amounts and rates);
+// untrusted input goes through Parse, which checks the range.
+func roundHalfEven(r *big.Rat) int64 {
+	return quoHalfEven(r.Num(), r.Denom()).Int64()
+}
+
+// quoHalfEven divides num by a positive den, rounding the quotient half to even
+func quoHalfEven(num, den *big.Int) *big.Int {
+	q, m := new(big.Int).QuoRem(num, den, new(big.Int))
+	if m.Sign() == 0 {
+		return q
+	}
+	twice := new(big.Int).Abs(m)
+	twice.Lsh(twice, 1)
+	switch twice.Cmp(den) {
+	case 1:
+		q.Add(q, big.NewInt(int64(num.Sign())))
+	case 0:
+		if q.Bit(0) == 1 {
+			q.Add(q, big.NewInt(int64(num.Sign())))
+		}
+	}
+	return q
+}
+
+// scaleDecimals is the number of decimals an Amount holds
+const scaleDecimals = 2
+
+// TokenUnits converts amounts to the integer base units of the token a currency
+// is recorded in on-chain, and back. A currency without its own entry uses the
+// default decimals (IDRX for rupiah pools).
+type TokenUnits struct {
+	defaultDecimals int
+	decimals        map[string]int
+}
+
+// NewTokenUnits returns a converter. perCurrency keys are currency codes such as
+// "IDR" or "USD" and are matched case-insensitively.
+func NewTokenUnits(defaultDecimals int, perCurrency map[string]int) *TokenUnits {
+	u := &TokenUnits{defaultDecimals: defaultDecimals, decimals: make(map[string]int, len(perCurrency))}
+	for currency, d := range perCurrency {
+		u.decimals[strings.ToUpper(currency)] = d
+	}
+	return u
+}
+
+// Decimals returns the token decimals used for a currency
+func (u *TokenUnits) Decimals(currency string) int {
+	if d, ok := u.decimals[strings.ToUpper(currency)]; ok {
+		return d
+	}
+	return u.defaultDecimals
+}
+
+// ToChain returns a in token base units. It fails if the token has fewer
+// decimals than a needs, rather than silently dropping the fraction.
+func (u *TokenUnits) ToChain(a Amount, currency string) (*big.Int, error) {
+	if a < 0 {
+		return nil, fmt.Errorf("%w: negative amount %s", ErrInvalidAmount, a)
+	}
+	v := big.NewInt(int64(a))
+	shift := u.Decimals(currency) - scaleDecimals
+	if shift >= 0 {
+		return v.Mul(v, pow10(shift)), nil
+	}
+
+	q, r := new(big.Int).QuoRem(v, pow10(-shift), new(big.Int))
+	if r.Sign() != 0 {
+		return nil, fmt.Errorf("%w: %s %s with %d decimals", ErrPrecisionLoss, a, currency, u.Decimals(currency))
+	}
+	return q, nil
+}
+
+// FromChain converts token base units to an Amount, rounding digits below the
+// hundredths half to even. It fails if the value does not fit an Amount.
+func (u *TokenUnits) FromChain(v *big.Int, currency string) (Amount, error) {
+	if v == nil {
+		return 0, nil
+	}
+	var scaled *big.Int
+	if shift := u.Decimals(currency) - scaleDecimals; shift >= 0 {
+		scaled = quoHalfEven(v, pow10(shift))
+	} else {
+		scaled = new(big.Int).Mul(v, pow10(-shift))
+	}
+	if !scaled.IsInt64() {
+		return 0, fmt.Errorf("%w: %s base units overflow", ErrInvalidAmount, v)
+	}
+	return Amount(scaled.Int64()), nil
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
@@ -0,0 +1,526 @@
+package money
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/vessel/backend/internal/contracts"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Amount
+	}{
+		{"0", 0},
+		{"1500000", 150000000},
+		{"12.5", 1250},
+		{"12.05", 1205},
+		{" 7 ", 700},
+		{"1e6", 100000000},
+		{"-3.25", -325},
+		// Digits beyond the hundredths round half to even
+		{"0.005", 0},
+		{"0.015", 2},
+		{"0.025", 2},
+		{"0.0251", 3},
+		{"-0.005", 0},
+		{"-0.015", -2},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q) returned error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, in := range []string{"", "abc", "1,000", "12.5.1", "1e20", "-1e20", "92233720368547758.08", "-92233720368547758.09"} {
+		if _, err := Parse(in); !errors.Is(err, ErrInvalidAmount) {
+			t.Errorf("Parse(%q) error = %v, want ErrInvalidAmount", in, err)
+		}
+	}
+
+	// The largest amounts still fit
+	if a, err := Parse("92233720368547758.07"); err != nil || a != Amount(math.MaxInt64) {
+		t.Errorf("Parse(max) = %d, %v, want %d", a, err, int64(math.MaxInt64))
+	}
+	if a, err := Parse("-92233720368547758.08"); err != nil || a != Amount(math.MinInt64) {
+		t.Errorf("Parse(min) = %d, %v, want %d", a, err, int64(math.MinInt64))
+	}
+
+	// A JSON number out of range is rejected instead of wrapping
+	var a Amount
+	if err := a.UnmarshalJSON([]byte(`1e20`)); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("UnmarshalJSON(1e20) error = %v, want ErrInvalidAmount", err)
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		in   Amount
+		want string
+	}{
+		{0, "0"},
+		{FromInt(1500000), "1500000"},
+		{1250, "12.5"},
+		{1205, "12.05"},
+		{-1205, "-12.05"},
+		{-5, "-0.05"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("Amount(%d).String() = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRoundHalfEven(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"2.5", "2"},
+		{"3.5", "4"},
+		{"2.51", "3"},
+		{"2.49", "2"},
+		{"0.5", "0"},
+		{"1.5", "2"},
+		{"-2.5", "-2"},
+		{"-3.5", "-4"},
+		{"-2.51", "-3"},
+		{"7", "7"},
+	}
+	for _, tt := range tests {
+		a, err := Parse(tt.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := a.Round(Whole).String(); got != tt.want {
+			t.Errorf("Round(%s, Whole) = %s, want %s", tt.in, got, tt.want)
+		}
+		if got := a.RoundTo("IDR").String(); got != tt.want {
+			t.Errorf("RoundTo(%s, IDR) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+
+	// Cent currencies keep the hundredths
+	if got := Amount(1255).RoundTo("USD"); got != 1255 {
+		t.Errorf("RoundTo(12.55, USD) = %s, want 12.55", got)
+	}
+}
+
+func TestMulRate(t *testing.T) {
+	tests := []struct {
+		amount  Amount
+		percent float64
+		want    Amount
+	}{
+		{FromInt(1000000), 10, FromInt(100000)},
+		{FromInt(1000000), 10.5, FromInt(105000)},
+		{FromInt(1), 0.5, 0},   // 0.005 rounds to even
+		{FromInt(3), 0.5, 2},   // 0.015 rounds to even
+		{FromInt(-3), 0.5, -2}, // negatives round the same way
+		{FromInt(-1000), 2, FromInt(-20)},
+	}
+	for _, tt := range tests {
+		if got := tt.amount.MulRate(tt.percent); got != tt.want {
+			t.Errorf("%s.MulRate(%v) = %s, want %s", tt.amount, tt.percent, got, tt.want)
+		}
+	}
+}
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestDayCountDays(t *testing.T) {
+	tests := []struct {
+		name       string
+		convention DayCount
+		start, end time.Time
+		want       int64
+	}{
+		{"30/360 whole months", Thirty360, date(2024, 1, 15), date(2024, 2, 15), 30},
+		{"30/360 half year across year end", Thirty360, date(2023, 12, 15), date(2024, 6, 15), 180},
+		{"30/360 full year from February", Thirty360, date(2023, 2, 28), date(2024, 2, 28), 360},
+		{"30/360 31st start counts as 30th", Thirty360, date(2024, 1, 31), date(2024, 2, 28), 28},
+		{"30/360 31st to 31st", Thirty360, date(2024, 1, 31), date(2024, 3, 31), 60},
+		{"30/360 30th to 31st", Thirty360, date(2024, 1, 30), date(2024, 3, 31), 60},
+		{"30/360 31st end kept after a 28 February start", Thirty360, date(2023, 2, 28), date(2023, 3, 31), 33},
+		{"30/360 31st end kept after a 29 February start", Thirty360, date(2024, 2, 29), date(2024, 3, 31), 32},
+		{"30/360 end of February is not moved", Thirty360, date(2024, 1, 30), date(2024, 2, 29), 29},
+		{"30/360 December 31st to January 31st", Thirty360, date(2023, 12, 31), date(2024, 1, 31), 30},
+		{"30/360 end before start", Thirty360, date(2024, 3, 31), date(2024, 1, 31), 0},
+		{"actual/365 across year end", Actual365, date(2023, 12, 31), date(2024, 1, 1), 1},
+		{"actual/365 month across year end", Actual365, date(2023, 12, 15), date(2024, 1, 15), 31},
+		{"actual/365 over 29 February", Actual365, date(2024, 2, 28), date(2024, 3, 1), 2},
+		{"actual/365 leap year", Actual365, date(2024, 1, 1), date(2025, 1, 1), 366},
+		{"actual/365 time of day is ignored", Actual365, date(2024, 1, 1).Add(23 * time.Hour), date(2024, 1, 2).Add(time.Hour), 1},
+		{"actual/365 end before start", Actual365, date(2024, 1, 2), date(2024, 1, 1), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.convention.Days(tt.start, tt.end); got != tt.want {
+				t.Errorf("%s Days(%s, %s) = %d, want %d", tt.convention, tt.start.Format("2006-01-02"), tt.end.Format("2006-01-02"), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDayCountDaysAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	tests := []struct {
+		name       string
+		start, end time.Time
+		want       int64
+	}{
+		// 47 and 49 hours elapse, but two calendar days pass either way
+		{"clocks forward", time.Date(2024, 3, 9, 12, 0, 0, 0, loc), time.Date(2024, 3, 11, 12, 0, 0, 0, loc), 2},
+		{"clocks back", time.Date(2024, 11, 2, 12, 0, 0, 0, loc), time.Date(2024, 11, 4, 12, 0, 0, 0, loc), 2},
+		{"month over clocks forward", time.Date(2024, 3, 1, 9, 0, 0, 0, loc), time.Date(2024, 4, 1, 9, 0, 0, 0, loc), 31},
+		{"year end in winter time", time.Date(2023, 12, 31, 12, 0, 0, 0, loc), time.Date(2024, 1, 1, 12, 0, 0, 0, loc), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Actual365.Days(tt.start, tt.end); got != tt.want {
+				t.Errorf("Days(%s, %s) = %d, want %d", tt.start, tt.end, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimpleInterest(t *testing.T) {
+	tests := []struct {
+		name       string
+		principal  Amount
+		percent    float64
+		convention DayCount
+		start, end time.Time
+		want       Amount
+	}{
+		{"actual/365 full year", FromInt(1000000), 12, Actual365, date(2023, 1, 1), date(2024, 1, 1), FromInt(120000)},
+		{"actual/365 leap year earns 366 days", FromInt(365000), 10, Actual365, date(2024, 1, 1), date(2025, 1, 1), FromInt(36600)},
+		{"actual/365 rounds half to even", FromInt(1000000), 24, Actual365, date(2024, 1, 1), date(2024, 1, 31), 1972603},
+		{"30/360 quarter", FromInt(100000), 10, Thirty360, date(2024, 1, 31), date(2024, 4, 30), FromInt(2500)},
+		{"30/360 February month", FromInt(360000), 12, Thirty360, date(2024, 1, 31), date(2024, 2, 29), FromInt(3480)},
+		{"no days", FromInt(1000000), 12, Actual365, date(2024, 1, 1), date(2024, 1, 1), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SimpleInterest(tt.principal, tt.percent, tt.convention, tt.start, tt.end); got != tt.want {
+				t.Errorf("SimpleInterest(%s, %v, %s) = %s, want %s", tt.principal, tt.percent, tt.convention, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name    string
+		total   Amount
+		weights []Amount
+		unit    Amount
+		want    []Amount
+	}{
+		{
+			name:    "even split",
+			total:   FromInt(300),
+			weights: []Amount{FromInt(1), FromInt(1), FromInt(1)},
+			unit:    Whole,
+			want:    []Amount{FromInt(100), FromInt(100), FromInt(100)},
+		},
+		{
+			name:    "remainder to largest holder",
+			total:   FromInt(100),
+			weights: []Amount{FromInt(1), FromInt(2), FromInt(1)},
+			unit:    Whole,
+			want:    []Amount{FromInt(25), FromInt(50), FromInt(25)},
+		},
+		{
+			name:    "remainder to largest holder not first",
+			total:   FromInt(10),
+			weights: []Amount{FromInt(1), FromInt(1), FromInt(2)},
+			unit:    Whole,
+			want:    []Amount{FromInt(2), FromInt(2), FromInt(6)},
+		},
+		{
+			name:    "remainder to first on ties",
+			total:   FromInt(100),
+			weights: []Amount{FromInt(1), FromInt(1), FromInt(1)},
+			unit:    Whole,
+			want:    []Amount{FromInt(34), FromInt(33), FromInt(33)},
+		},
+		{
+			name:    "cents",
+			total:   100,
+			weights: []Amount{1, 1, 1},
+			unit:    1,
+			want:    []Amount{34, 33, 33},
+		},
+		{
+			name:    "negative total",
+			total:   FromInt(-10),
+			weights: []Amount{FromInt(1), FromInt(1), FromInt(2)},
+			unit:    Whole,
+			want:    []Amount{FromInt(-2), FromInt(-2), FromInt(-6)},
+		},
+		{
+			name:    "zero and negative weights get nothing",
+			total:   FromInt(10),
+			weights: []Amount{0, FromInt(3), FromInt(-1)},
+			unit:    Whole,
+			want:    []Amount{0, FromInt(10), 0},
+		},
+		{
+			name:    "all zero weights",
+			total:   FromInt(10),
+			weights: []Amount{0, 0},
+			unit:    Whole,
+			want:    []Amount{FromInt(10), 0},
+		},
+		{
+			name:    "no weights",
+			total:   FromInt(10),
+			weights: nil,
+			unit:    Whole,
+			want:    []Amount{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Allocate(tt.total, tt.weights, tt.unit)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Allocate returned %d shares, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("share %d = %s, want %s", i, got[i], tt.want[i])
+				}
+			}
+			if len(got) > 0 && Sum(got...) != tt.total {
+				t.Errorf("shares add up to %s, want %s", Sum(got...), tt.total)
+			}
+		})
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var a Amount
+	for in, want := range map[string]Amount{`12.5`: 1250, `"12.5"`: 1250, `-3`: -300} {
+		if err := a.UnmarshalJSON([]byte(in)); err != nil {
+			t.Errorf("UnmarshalJSON(%s) returned error: %v", in, err)
+			continue
+		}
+		if a != want {
+			t.Errorf("UnmarshalJSON(%s) = %d, want %d", in, a, want)
+		}
+	}
+	if out, _ := Amount(1250).MarshalJSON(); string(out) != "12.5" {
+		t.Errorf("MarshalJSON(12.5) = %s", out)
+	}
+}
+
+func TestTokenUnitsToChain(t *testing.T) {
+	tests := []struct {
+		decimals int
+		amount   string
+		want     string
+	}{
+		{0, "0", "0"},
+		{0, "1500000", "1500000"},
+		{2, "0", "0"},
+		{2, "12.5", "1250"},
+		{2, "1500000", "150000000"},
+		{6, "12.5", "12500000"},
+		{6, "0.01", "10000"},
+		{18, "12.5", "12500000000000000000"},
+		{18, "92233720368547758.07", "92233720368547758070000000000000000"},
+	}
+	for _, tt := range tests {
+		units := NewTokenUnits(tt.decimals, nil)
+		amount, err := Parse(tt.amount)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := units.ToChain(amount, "IDR")
+		if err != nil {
+			t.Errorf("ToChain(%s) with %d decimals returned error: %v", tt.amount, tt.decimals, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("ToChain(%s) with %d decimals = %s, want %s", tt.amount, tt.decimals, got, tt.want)
+		}
+
+		back, err := units.FromChain(got, "IDR")
+		if err != nil {
+			t.Errorf("FromChain(%s) with %d decimals returned error: %v", got, tt.decimals, err)
+			continue
+		}
+		if back != amount {
+			t.Errorf("round trip of %s with %d decimals = %s", tt.amount, tt.decimals, back)
+		}
+	}
+}
+
+func TestTokenUnitsRejectsPrecisionLoss(t *testing.T) {
+	units := NewTokenUnits(0, map[string]int{"usd": 1})
+	for _, tt := range []struct {
+		amount   Amount
+		currency string
+	}{
+		{1250, "IDR"}, // 12.5 with no decimals
+		{1, "IDR"},    // 0.01 with no decimals
+		{1205, "USD"}, // 12.05 with one decimal
+	} {
+		if _, err := units.ToChain(tt.amount, tt.currency); !errors.Is(err, ErrPrecisionLoss) {
+			t.Errorf("ToChain(%s %s) error = %v, want ErrPrecisionLoss", tt.currency, tt.amount, err)
+		}
+	}
+
+	// Whole amounts still fit
+	if got, err := units.ToChain(FromInt(12), "IDR"); err != nil || got.Int64() != 12 {
+		t.Errorf("ToChain(IDR 12) = %v, %v, want 12", got, err)
+	}
+	if got, err := units.ToChain(1250, "USD"); err != nil || got.Int64() != 125 {
+		t.Errorf("ToChain(USD 12.5) = %v, %v, want 125", got, err)
+	}
+	if _, err := units.ToChain(-100, "IDR"); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("ToChain of a negative amount error = %v, want ErrInvalidAmount", err)
+	}
+}
+
+func TestTokenUnitsFromChain(t *testing.T) {
+	units := NewTokenUnits(2, map[string]int{"usd": 6})
+	if d := units.Decimals("usd"); d != 6 {
+		t.Errorf("Decimals(usd) = %d, want 6", d)
+	}
+	if d := units.Decimals("IDR"); d != 2 {
+		t.Errorf("Decimals(IDR) = %d, want 2", d)
+	}
+
+	tests := []struct {
+		value    string
+		currency string
+		want     Amount
+	}{
+		{"1250", "IDR", 1250},
+		{"12500000", "USD", 1250},
+		// Base units below a hundredth round half to even
+		{"5000", "USD", 0},
+		{"15000", "USD", 2},
+		{"25000", "USD", 2},
+		{"25001", "USD", 3},
+	}
+	for _, tt := range tests {
+		v, _ := new(big.Int).SetString(tt.value, 10)
+		got, err := units.FromChain(v, tt.currency)
+		if err != nil {
+			t.Errorf("FromChain(%s %s) returned error: %v", tt.value, tt.currency, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("FromChain(%s %s) = %d, want %d", tt.value, tt.currency, got, tt.want)
+		}
+	}
+
+	if got, err := units.FromChain(nil, "IDR"); err != nil || got != 0 {
+		t.Errorf("FromChain(nil) = %v, %v, want 0", got, err)
+	}
+	huge := new(big.Int).Lsh(big.NewInt(1), 100)
+	if _, err := units.FromChain(huge, "IDR"); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("FromChain(2^100) error = %v, want ErrInvalidAmount", err)
+	}
+}
+
+// TestTokenUnitsABIRoundTrip packs converted amounts the way the InvoicePool
+// binding sends them and reads them back the way the indexer decodes them
+func TestTokenUnitsABIRoundTrip(t *testing.T) {
+	parsed, err := contracts.InvoicePoolMetaData.GetAbi()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenID := big.NewInt(7)
+	investor := common.HexToAddress("0x00000000000000000000000000000000000000a1")
+
+	for _, decimals := range []int{0, 2, 6, 18} {
+		units := NewTokenUnits(decimals, nil)
+		total, returns := FromInt(1050000), []Amount{FromInt(840000), FromInt(210000)}
+
+		totalBig, err := units.ToChain(total, "IDR")
+		if err != nil {
+			t.Fatal(err)
+		}
+		returnsBig := make([]*big.Int, len(returns))
+		for i, r := range returns {
+			if returnsBig[i], err = units.ToChain(r, "IDR"); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// recordRepayment(tokenId, totalAmount, investorReturns)
+		data, err := parsed.Pack("recordRepayment", tokenID, totalBig, returnsBig)
+		if err != nil {
+			t.Fatalf("pack recordRepayment with %d decimals: %v", decimals, err)
+		}
+		args, err := parsed.Methods["recordRepayment"].Inputs.Unpack(data[4:])
+		if err != nil {
+			t.Fatalf("unpack recordRepayment with %d decimals: %v", decimals, err)
+		}
+		if got, err := units.FromChain(args[1].(*big.Int), "IDR"); err != nil || got != total {
+			t.Errorf("recordRepayment total with %d decimals = %v, %v, want %s", decimals, got, err, total)
+		}
+		for i, v := range args[2].([]*big.Int) {
+			if got, err := units.FromChain(v, "IDR"); err != nil || got != returns[i] {
+				t.Errorf("recordRepayment return %d with %d decimals = %v, %v, want %s", i, decimals, got, err, returns[i])
+			}
+		}
+
+		// InvestmentRecorded(tokenId, investor, amount, expectedReturn) as emitted
+		amount, expected := FromInt(500000), FromInt(525000)
+		amountBig, _ := units.ToChain(amount, "IDR")
+		expectedBig, _ := units.ToChain(expected, "IDR")
+		logData, err := parsed.Events["InvestmentRecorded"].Inputs.NonIndexed().Pack(amountBig, expectedBig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var event contracts.InvoicePoolInvestmentRecorded
+		if err := parsed.UnpackIntoInterface(&event, "InvestmentRecorded", logData); err != nil {
+			t.Fatalf("unpack InvestmentRecorded with %d decimals: %v", decimals, err)
+		}
+		if got, err := units.FromChain(event.Amount, "IDR"); err != nil || got != amount {
+			t.Errorf("InvestmentRecorded amount with %d decimals = %v, %v, want %s", decimals, got, err, amount)
+		}
+		if got, err := units.FromChain(event.ExpectedReturn, "IDR"); err != nil || got != expected {
+			t.Errorf("InvestmentRecorded expectedReturn with %d decimals = %v, %v, want %s", decimals, got, err, expected)
+		}
+
+		// recordInvestment(tokenId, investor, amount)
+		data, err = parsed.Pack("recordInvestment", tokenID, investor, amountBig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		args, err = parsed.Methods["recordInvestment"].Inputs.Unpack(data[4:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if args[1].(common.Address) != investor {
+			t.Errorf("recordInvestment investor = %s, want %s", args[1], investor)
+		}
+		if got, err := units.FromChain(args[2].(*big.Int), "IDR"); err != nil || got != amount {
+			t.Errorf("recordInvestment amount with %d decimals = %v, %v, want %s", decimals, got, err, amount)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/vessel/backend/internal/models"
+)
+
+// AnalyticsRepository reads repayment outcomes across invoices, pools,
+// investments and defaults. It only reads.
+type AnalyticsRepository struct {
+	db DBTX
+}
+
+func NewAnalyticsRepository(db *sql.DB) *AnalyticsRepository {
+	return &AnalyticsRepository{db: db}
+}
+
+// FindInvoiceOutcomes lists the invoices whose pool closed repaid or defaulted,
+// oldest due date first, with what each tranche invested and got back
+func (r *AnalyticsRepository) FindInvoiceOutcomes(filter *models.OutcomeFilter) ([]models.InvoiceOutcome, error) {
+	query := `
+		SELECT i.id, i.exporter_id, fp.id, i.grade, i.buyer_country, i.due_date, i.created_at,
+		       fp.status = 'defaulted', CASE WHEN fp.status = 'closed' THEN fp.closed_at END
+		FROM invoices i
+		JOIN funding_pools fp ON fp.invoice_id = i.id
+		WHERE fp.status IN ('closed', 'defaulted')
+		  AND ($1::date IS NULL OR i.due_date >= $1::date)
+		  AND ($2::date IS NULL OR i.due_date <= $2::date)
+		ORDER BY i.due_date ASC, i.id ASC
+	`
+	rows, err := r.db.Query(query, filter.DueFrom, filter.DueTo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var outcomes []models.InvoiceOutcome
+	byPool := make(map[uuid.UUID]int)
+	var poolIDs []string
+	for rows.Next() {
+		var o models.InvoiceOutcome
+		if err := rows.Scan(&o.InvoiceID, &o.ExporterID, &o.PoolID, &o.Grade, &o.BuyerCountry, &o.DueDate, &o.CreatedAt, &o.Defaulted, &o.SettledAt); err != nil {
+			return nil, err
+		}
+		byPool[o.PoolID] = len(outcomes)
+		poolIDs = append(poolIDs, o.PoolID.String())
+		outcomes = append(outcomes, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(outcomes) == 0 {
+		return outcomes, nil
+	}
+
+	// Refunded investments never took part in the outcome
+	trancheQuery := `
+		SELECT inv.pool_id, inv.tranche,
+		       COALESCE(SUM(inv.amount), 0),
+		       COALESCE(SUM(COALESCE(inv.actual_return, 0)), 0),
+		       COALESCE(SUM(da.principal), 0),
+		       COALESCE(SUM(da.loss), 0),
+		       COUNT(da.id) > 0
+		FROM investments inv
+		LEFT JOIN default_allocations da ON da.investment_id = inv.id
+		WHERE inv.pool_id = ANY($1::uuid[]) AND inv.status IN ('repaid', 'defaulted')
+		GROUP BY inv.pool_id, inv.tranche
+		ORDER BY inv.pool_id, inv.tranche
+	`
+	trancheRows, err := r.db.Query(trancheQuery, pq.Array(poolIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer trancheRows.Close()
+
+	for trancheRows.Next() {
+		var poolID uuid.UUID
+		var t models.TrancheOutcome
+		if err := trancheRows.Scan(&poolID, &t.Tranche, &t.Invested, &t.Returned, &t.Exposure, &t.Loss, &t.Defaulted); err != nil {
+			return nil, err
+		}
+		if i, ok := byPool[poolID]; ok {
+			outcomes[i].Tranches = append(outcomes[i].Tranches, t)
+		}
+	}
+	if err := trancheRows.Err(); err != nil {
+		return nil, err
+	}
+	return outcomes, nil
+}
+
+// FindReplayInvoices loads the given invoices with their documents, and the
+// exporter history and credit score each had when it was created, by invoice id
+func (r *AnalyticsRepository) FindReplayInvoices(invoiceIDs []uuid.UUID) (map[uuid.UUID]*models.ReplayInvoice, error) {
+	replays := make(map[uuid.UUID]*models.ReplayInvoice, len(invoiceIDs))
+	if len(invoiceIDs) == 0 {
+		return replays, nil
+	}
+	ids := make([]string, len(invoiceIDs))
+	for i, id := range invoiceIDs {
+		ids[i] = id.String()
+	}
+
+	query := `
+		SELECT i.id, i.exporter_id, i.buyer_id, i.buyer_name, i.buyer_country, i.invoice_number, i.currency, i.amount,
+		       i.issue_date, i.due_date, i.description, i.status, i.interest_rate, i.advance_percentage,
+		       i.advance_amount, i.document_hash, i.created_at, i.updated_at,
+		       h.prior, h.repaid, cs.new_score
+		FROM invoices i
+		CROSS JOIN LATERAL (
+			SELECT COUNT(*) AS prior,
+			       COUNT(*) FILTER (WHERE fp.status = 'closed' AND fp.closed_at < i.created_at) AS repaid
+			FROM invoices o
+			LEFT JOIN funding_pools fp ON fp.invoice_id = o.id
+			WHERE o.exporter_id = i.exporter_id AND o.id <> i.id AND o.created_at < i.created_at
+		) h
+		LEFT JOIN LATERAL (
+			SELECT new_score FROM credit_score_history
+			WHERE user_id = i.exporter_id AND created_at < i.created_at AND new_score IS NOT NULL
+			ORDER BY created_at DESC
+			LIMIT 1
+		) cs ON TRUE
+		WHERE i.id = ANY($1::uuid[])
+	`
+	rows, err := r.db.Query(query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		rp := &models.ReplayInvoice{}
+		inv := &rp.Invoice
+		if err := rows.Scan(
+			&inv.ID, &inv.ExporterID, &inv.BuyerID, &inv.BuyerName, &inv.BuyerCountry, &inv.InvoiceNumber, &inv.Currency, &inv.Amount,
+			&inv.IssueDate, &inv.DueDate, &inv.Description, &inv.Status, &inv.InterestRate, &inv.AdvancePercentage,
+			&inv.AdvanceAmount, &inv.DocumentHash, &inv.CreatedAt, &inv.UpdatedAt,
+			&rp.PriorInvoices, &rp.RepaidInvoices, &rp.CreditScore,
+		); err != nil {
+			return nil, err
+		}
+		replays[inv.ID] = rp
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	docQuery := `
+		SELECT id, invoice_id, document_type, file_name, file_url, file_hash, file_size, uploaded_at
+		FROM invoice_documents
+		WHERE invoice_id = ANY($1::uuid[])
+	`
+	docRows, err := r.db.Query(docQuery, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer docRows.Close()
+
+	for docRows.Next() {
+		var doc models.InvoiceDocument
+		if err := docRows.Scan(&doc.ID, &doc.InvoiceID, &doc.DocumentType, &doc.FileName, &doc.FileURL,
+			&doc.FileHash, &doc.FileSize, &doc.UploadedAt); err != nil {
+			return nil, err
+		}
+		if rp, ok := replays[doc.InvoiceID]; ok {
+			rp.Invoice.Documents = append(rp.Invoice.Documents, doc)
+		}
+	}
+	return replays, docRows.Err()
+}
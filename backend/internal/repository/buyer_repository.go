@@ -0,0 +1,267 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/models"
+)
+
+type BuyerRepository struct {
+	db DBTX
+}
+
+func NewBuyerRepository(db *sql.DB) *BuyerRepository {
+	return &BuyerRepository{db: db}
+}
+
+const buyerColumns = `b.id, b.name, b.normalized_name, b.country, b.merged_into, b.created_at, b.updated_at`
+
+func scanBuyer(row interface{ Scan(...interface{}) error }) (*models.Buyer, error) {
+	b := &models.Buyer{}
+	if err := row.Scan(&b.ID, &b.Name, &b.NormalizedName, &b.Country, &b.MergedInto, &b.CreatedAt, &b.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (r *BuyerRepository) queryBuyers(query string, args ...interface{}) ([]models.Buyer, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var buyers []models.Buyer
+	for rows.Next() {
+		b, err := scanBuyer(rows)
+		if err != nil {
+			return nil, err
+		}
+		buyers = append(buyers, *b)
+	}
+	return buyers, rows.Err()
+}
+
+func (r *BuyerRepository) FindByID(id uuid.UUID) (*models.Buyer, error) {
+	b, err := scanBuyer(r.db.QueryRow(`SELECT `+buyerColumns+` FROM buyers b WHERE b.id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return b, err
+}
+
+// FindByName finds the buyer in the country whose normalized name or one of
+// whose aliases matches name. A merged buyer resolves to the buyer it was merged into.
+func (r *BuyerRepository) FindByName(name, country string) (*models.Buyer, error) {
+	query := `
+		SELECT ` + buyerColumns + ` FROM buyers b
+		WHERE b.merged_into IS NULL AND b.id = (
+			SELECT COALESCE(m.merged_into, m.id) FROM buyers m
+			WHERE m.country = $2 AND (
+				m.normalized_name = normalize_buyer_name($1)
+				OR EXISTS (SELECT 1 FROM buyer_aliases a WHERE a.buyer_id = m.id AND a.normalized_alias = normalize_buyer_name($1))
+			)
+			ORDER BY m.merged_into IS NULL DESC
+			LIMIT 1
+		)
+	`
+	b, err := scanBuyer(r.db.QueryRow(query, name, country))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return b, err
+}
+
+// Create adds a buyer, normalizing its name. It returns false without writing
+// when a buyer with the same normalized name is already in the country.
+func (r *BuyerRepository) Create(b *models.Buyer) (bool, error) {
+	query := `
+		INSERT INTO buyers (name, normalized_name, country)
+		VALUES ($1, normalize_buyer_name($1), $2)
+		ON CONFLICT (normalized_name, country) DO NOTHING
+		RETURNING id, normalized_name, created_at, updated_at
+	`
+	err := r.db.QueryRow(query, b.Name, b.Country).Scan(&b.ID, &b.NormalizedName, &b.CreatedAt, &b.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// FindAll lists buyers that were not merged away, by name. search matches the
+// name, normalized name or an alias; an empty search or country matches all.
+func (r *BuyerRepository) FindAll(filter *models.BuyerFilter) ([]models.Buyer, int, error) {
+	where := ` WHERE b.merged_into IS NULL AND ($2 = '' OR b.country = $2) AND (
+		$1 = '' OR b.name ILIKE '%' || $1 || '%'
+		OR b.normalized_name LIKE '%' || normalize_buyer_name($1) || '%'
+		OR EXISTS (SELECT 1 FROM buyer_aliases a WHERE a.buyer_id = b.id AND a.normalized_alias LIKE '%' || normalize_buyer_name($1) || '%')
+	)`
+
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM buyers b`+where, filter.Search, filter.Country).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	offset := (filter.Page - 1) * filter.PerPage
+	buyers, err := r.queryBuyers(`SELECT `+buyerColumns+` FROM buyers b`+where+` ORDER BY b.name ASC, b.id ASC LIMIT $3 OFFSET $4`,
+		filter.Search, filter.Country, filter.PerPage, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	return buyers, total, nil
+}
+
+// FindActive lists every buyer that was not merged away, by country; an empty
+// country matches all
+func (r *BuyerRepository) FindActive(country string) ([]models.Buyer, error) {
+	return r.queryBuyers(`SELECT `+buyerColumns+` FROM buyers b
+		WHERE b.merged_into IS NULL AND ($1 = '' OR b.country = $1)
+		ORDER BY b.country ASC, b.normalized_name ASC`, country)
+}
+
+// CreateAlias records another name of a buyer. It returns false without
+// writing when the buyer already has an alias that normalizes the same.
+func (r *BuyerRepository) CreateAlias(a *models.BuyerAlias) (bool, error) {
+	query := `
+		INSERT INTO buyer_aliases (buyer_id, alias, normalized_alias)
+		VALUES ($1, $2, normalize_buyer_name($2))
+		ON CONFLICT (buyer_id, normalized_alias) DO NOTHING
+		RETURNING id, normalized_alias, created_at
+	`
+	err := r.db.QueryRow(query, a.BuyerID, a.Alias).Scan(&a.ID, &a.NormalizedAlias, &a.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *BuyerRepository) FindAliases(buyerID uuid.UUID) ([]models.BuyerAlias, error) {
+	rows, err := r.db.Query(`
+		SELECT id, buyer_id, alias, normalized_alias, created_at
+		FROM buyer_aliases WHERE buyer_id = $1 ORDER BY created_at ASC
+	`, buyerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var aliases []models.BuyerAlias
+	for rows.Next() {
+		var a models.BuyerAlias
+		if err := rows.Scan(&a.ID, &a.BuyerID, &a.Alias, &a.NormalizedAlias, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		aliases = append(aliases, a)
+	}
+	return aliases, rows.Err()
+}
+
+// AddContact records a contact email of a buyer; an email already recorded is kept
+func (r *BuyerRepository) AddContact(buyerID uuid.UUID, email string) error {
+	query := `
+		INSERT INTO buyer_contacts (buyer_id, email) VALUES ($1, LOWER(TRIM($2)))
+		ON CONFLICT (buyer_id, email) DO NOTHING
+	`
+	_, err := r.db.Exec(query, buyerID, email)
+	return err
+}
+
+func (r *BuyerRepository) FindContacts(buyerID uuid.UUID) ([]models.BuyerContact, error) {
+	rows, err := r.db.Query(`
+		SELECT id, buyer_id, email, created_at
+		FROM buyer_contacts WHERE buyer_id = $1 ORDER BY created_at ASC
+	`, buyerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contacts []models.BuyerContact
+	for rows.Next() {
+		var c models.BuyerContact
+		if err := rows.Scan(&c.ID, &c.BuyerID, &c.Email, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		contacts = append(contacts, c)
+	}
+	return contacts, rows.Err()
+}
+
+// PaymentHistory aggregates the buyer's invoices from every mitra. Lateness is
+// measured from the due date to the pool closing, as in loss-rate analytics.
+func (r *BuyerRepository) PaymentHistory(buyerID uuid.UUID) (*models.BuyerPaymentHistory, error) {
+	query := `
+		SELECT COUNT(*),
+		       COUNT(*) FILTER (WHERE i.status IN ('pending_review', 'approved', 'tokenized', 'funding', 'funded', 'matured')),
+		       COUNT(*) FILTER (WHERE i.status = 'repaid'),
+		       COUNT(*) FILTER (WHERE i.status = 'defaulted'),
+		       COUNT(DISTINCT i.exporter_id),
+		       COALESCE(SUM(i.amount), 0),
+		       COALESCE(SUM(i.amount) FILTER (WHERE i.status = 'repaid'), 0),
+		       COUNT(*) FILTER (WHERE i.status = 'repaid' AND fp.closed_at::date > i.due_date::date),
+		       COALESCE(AVG(GREATEST(fp.closed_at::date - i.due_date::date, 0)) FILTER (WHERE i.status = 'repaid' AND fp.closed_at IS NOT NULL), 0),
+		       MAX(fp.closed_at) FILTER (WHERE i.status = 'repaid')
+		FROM invoices i
+		LEFT JOIN funding_pools fp ON fp.invoice_id = i.id
+		WHERE i.buyer_id = $1 AND i.status NOT IN ('draft', 'rejected')
+	`
+	h := &models.BuyerPaymentHistory{}
+	err := r.db.QueryRow(query, buyerID).Scan(&h.Invoices, &h.Outstanding, &h.Repaid, &h.Defaulted, &h.Mitras,
+		&h.Volume, &h.RepaidVolume, &h.RepaidLate, &h.AvgDaysLate, &h.LastRepaidAt)
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// CountRepaidWithExporter counts the exporter's invoices to the buyer that were repaid
+func (r *BuyerRepository) CountRepaidWithExporter(buyerID, exporterID uuid.UUID) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM invoices WHERE buyer_id = $1 AND exporter_id = $2 AND status = 'repaid'`
+	err := r.db.QueryRow(query, buyerID, exporterID).Scan(&count)
+	return count, err
+}
+
+// Merge moves the invoices, importer payments, aliases and contacts of source
+// to target, keeps source's name as an alias of target and marks source merged
+func (r *BuyerRepository) Merge(sourceID, targetID uuid.UUID) error {
+	tx, err := begin(r.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{`UPDATE invoices SET buyer_id = $2 WHERE buyer_id = $1`, []interface{}{sourceID, targetID}},
+		{`UPDATE importer_payments SET buyer_id = $2 WHERE buyer_id = $1`, []interface{}{sourceID, targetID}},
+		{`INSERT INTO buyer_aliases (buyer_id, alias, normalized_alias)
+		SELECT $2::uuid, name, normalized_name FROM buyers WHERE id = $1
+		UNION ALL
+		SELECT $2::uuid, alias, normalized_alias FROM buyer_aliases WHERE buyer_id = $1
+		ON CONFLICT (buyer_id, normalized_alias) DO NOTHING`, []interface{}{sourceID, targetID}},
+		{`DELETE FROM buyer_aliases WHERE buyer_id = $1`, []interface{}{sourceID}},
+		{`INSERT INTO buyer_contacts (buyer_id, email)
+		SELECT $2, email FROM buyer_contacts WHERE buyer_id = $1
+		ON CONFLICT (buyer_id, email) DO NOTHING`, []interface{}{sourceID, targetID}},
+		{`DELETE FROM buyer_contacts WHERE buyer_id = $1`, []interface{}{sourceID}},
+		{`UPDATE buyers SET merged_into = $2, updated_at = NOW() WHERE id = $1 OR merged_into = $1`, []interface{}{sourceID, targetID}},
+		{`UPDATE buyers SET updated_at = NOW() WHERE id = $1`, []interface{}{targetID}},
+	}
+	for _, st := range statements {
+		if _, err := tx.Exec(st.query, st.args...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
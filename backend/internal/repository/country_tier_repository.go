@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/models"
+)
+
+type CountryTierRepository struct {
+	db DBTX
+}
+
+func NewCountryTierRepository(db *sql.DB) *CountryTierRepository {
+	return &CountryTierRepository{db: db}
+}
+
+const countryTierColumns = `country_code, country_name, tier, flag_emoji, created_at, updated_at`
+
+func scanCountryTier(row interface{ Scan(...interface{}) error }) (*models.CountryTier, error) {
+	t := &models.CountryTier{}
+	if err := row.Scan(&t.CountryCode, &t.CountryName, &t.Tier, &t.FlagEmoji, &t.CreatedAt, &t.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// FindAll lists every country tier, lowest risk first
+func (r *CountryTierRepository) FindAll() ([]models.CountryTier, error) {
+	rows, err := r.db.Query(`SELECT ` + countryTierColumns + ` FROM country_tiers ORDER BY tier ASC, country_name ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tiers []models.CountryTier
+	for rows.Next() {
+		t, err := scanCountryTier(rows)
+		if err != nil {
+			return nil, err
+		}
+		tiers = append(tiers, *t)
+	}
+	return tiers, rows.Err()
+}
+
+func (r *CountryTierRepository) FindByCode(code string) (*models.CountryTier, error) {
+	t, err := scanCountryTier(r.db.QueryRow(`SELECT `+countryTierColumns+` FROM country_tiers WHERE country_code = $1`, code))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return t, err
+}
+
+// Create adds a country tier and records the change
+func (r *CountryTierRepository) Create(t *models.CountryTier, changedBy uuid.UUID, reason *string) error {
+	tx, err := begin(r.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO country_tiers (country_code, country_name, tier, flag_emoji)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at, updated_at
+	`
+	if err := tx.QueryRow(query, t.CountryCode, t.CountryName, t.Tier, t.FlagEmoji).Scan(&t.CreatedAt, &t.UpdatedAt); err != nil {
+		return err
+	}
+	if err := recordCountryTierChange(tx, t.CountryCode, models.CountryTierCreated, nil, t, changedBy, reason); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Update saves a country tier and records the change from its previous state
+func (r *CountryTierRepository) Update(t *models.CountryTier, changedBy uuid.UUID, reason *string) error {
+	tx, err := begin(r.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	old, err := scanCountryTier(tx.QueryRow(`SELECT `+countryTierColumns+` FROM country_tiers WHERE country_code = $1 FOR UPDATE`, t.CountryCode))
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE country_tiers SET country_name = $1, tier = $2, flag_emoji = $3, updated_at = NOW()
+		WHERE country_code = $4
+		RETURNING created_at, updated_at
+	`
+	if err := tx.QueryRow(query, t.CountryName, t.Tier, t.FlagEmoji, t.CountryCode).Scan(&t.CreatedAt, &t.UpdatedAt); err != nil {
+		return err
+	}
+	if err := recordCountryTierChange(tx, t.CountryCode, models.CountryTierUpdated, old, t, changedBy, reason); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete removes a country tier and records what it was
+func (r *CountryTierRepository) Delete(code string, changedBy uuid.UUID, reason *string) error {
+	tx, err := begin(r.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	old, err := scanCountryTier(tx.QueryRow(`SELECT `+countryTierColumns+` FROM country_tiers WHERE country_code = $1 FOR UPDATE`, code))
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM country_tiers WHERE country_code = $1`, code); err != nil {
+		return err
+	}
+	if err := recordCountryTierChange(tx, code, models.CountryTierDeleted, old, nil, changedBy, reason); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func recordCountryTierChange(db DBTX, code string, action models.CountryTierAction, before, after *models.CountryTier, changedBy uuid.UUID, reason *string) error {
+	oldData, err := marshalCountryTier(before)
+	if err != nil {
+		return err
+	}
+	newData, err := marshalCountryTier(after)
+	if err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO country_tier_history (country_code, action, old_data, new_data, reason, changed_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err = db.Exec(query, code, action, oldData, newData, reason, changedBy)
+	return err
+}
+
+// marshalCountryTier encodes a tier for the history, SQL NULL for none
+func marshalCountryTier(t *models.CountryTier) ([]byte, error) {
+	if t == nil {
+		return nil, nil
+	}
+	return json.Marshal(t)
+}
+
+// FindHistory lists the changes to a country's tier, newest first
+func (r *CountryTierRepository) FindHistory(code string, page, perPage int) ([]models.CountryTierChange, int, error) {
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM country_tier_history WHERE country_code = $1`, code).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, country_code, action, old_data, new_data, reason, changed_by, created_at
+		FROM country_tier_history
+		WHERE country_code = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.db.Query(query, code, perPage, (page-1)*perPage)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var changes []models.CountryTierChange
+	for rows.Next() {
+		var c models.CountryTierChange
+		var oldData, newData []byte
+		if err := rows.Scan(&c.ID, &c.CountryCode, &c.Action, &oldData, &newData, &c.Reason, &c.ChangedBy, &c.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		if oldData != nil {
+			if err := json.Unmarshal(oldData, &c.OldData); err != nil {
+				return nil, 0, err
+			}
+		}
+		if newData != nil {
+			if err := json.Unmarshal(newData, &c.NewData); err != nil {
+				return nil, 0, err
+			}
+		}
+		changes = append(changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return changes, total, nil
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/models"
+)
+
+type CreditScoreRepository struct {
+	db DBTX
+}
+
+func NewCreditScoreRepository(db *sql.DB) *CreditScoreRepository {
+	return &CreditScoreRepository{db: db}
+}
+
+const creditScoreColumns = `
+	id, user_id, score, total_invoices, successful_invoices, defaulted_invoices,
+	total_volume, avg_payment_delay, last_updated, created_at
+`
+
+func scanCreditScore(row interface{ Scan(...interface{}) error }) (*models.CreditScore, error) {
+	cs := &models.CreditScore{}
+	err := row.Scan(
+		&cs.ID,
+		&cs.UserID,
+		&cs.Score,
+		&cs.TotalInvoices,
+		&cs.SuccessfulInvoices,
+		&cs.DefaultedInvoices,
+		&cs.TotalVolume,
+		&cs.AvgPaymentDelay,
+		&cs.LastUpdated,
+		&cs.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+func (r *CreditScoreRepository) FindByUserID(userID uuid.UUID) (*models.CreditScore, error) {
+	cs, err := scanCreditScore(r.db.QueryRow(`SELECT `+creditScoreColumns+` FROM credit_scores WHERE user_id = $1`, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return cs, err
+}
+
+// FindByUserIDForUpdate locks the mitra's credit score, creating it at the
+// default score first when the mitra has none
+func (r *CreditScoreRepository) FindByUserIDForUpdate(userID uuid.UUID) (*models.CreditScore, error) {
+	query := `INSERT INTO credit_scores (user_id, score) VALUES ($1, $2) ON CONFLICT (user_id) DO NOTHING`
+	if _, err := r.db.Exec(query, userID, models.DefaultCreditScore); err != nil {
+		return nil, err
+	}
+	return scanCreditScore(r.db.QueryRow(`SELECT `+creditScoreColumns+` FROM credit_scores WHERE user_id = $1 FOR UPDATE`, userID))
+}
+
+func (r *CreditScoreRepository) Update(cs *models.CreditScore) error {
+	query := `
+		UPDATE credit_scores
+		SET score = $1, total_invoices = $2, successful_invoices = $3, defaulted_invoices = $4,
+		    total_volume = $5, avg_payment_delay = $6, last_updated = NOW()
+		WHERE id = $7
+		RETURNING last_updated
+	`
+	return r.db.QueryRow(query, cs.Score, cs.TotalInvoices, cs.SuccessfulInvoices, cs.DefaultedInvoices,
+		cs.TotalVolume, cs.AvgPaymentDelay, cs.ID).Scan(&cs.LastUpdated)
+}
+
+// CreateHistory records a score change. It returns false without writing when
+// the event was already recorded for the invoice.
+func (r *CreditScoreRepository) CreateHistory(h *models.CreditScoreHistory) (bool, error) {
+	query := `
+		INSERT INTO credit_score_history (user_id, previous_score, new_score, reason, event, invoice_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, invoice_id, event) DO NOTHING
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRow(query, h.UserID, h.PreviousScore, h.NewScore, h.Reason, h.Event, h.InvoiceID).Scan(&h.ID, &h.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// FindHistory lists the mitra's latest score changes, newest first
+func (r *CreditScoreRepository) FindHistory(userID uuid.UUID, limit int) ([]models.CreditScoreHistory, error) {
+	query := `
+		SELECT id, user_id, COALESCE(previous_score, 0), COALESCE(new_score, 0), COALESCE(reason, ''), event, invoice_id, created_at
+		FROM credit_score_history
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+	rows, err := r.db.Query(query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []models.CreditScoreHistory
+	for rows.Next() {
+		var h models.CreditScoreHistory
+		if err := rows.Scan(&h.ID, &h.UserID, &h.PreviousScore, &h.NewScore, &h.Reason, &h.Event, &h.InvoiceID, &h.CreatedAt); err != nil {
+			return nil, err
+		}
+		history = append(history, h)
+	}
+	return history, rows.Err()
+}
+
+// Percentile is the share of mitra credit scores below score, 0-100
+func (r *CreditScoreRepository) Percentile(score int) (float64, error) {
+	var below, total int
+	query := `SELECT COUNT(*) FILTER (WHERE score < $1), COUNT(*) FROM credit_scores`
+	if err := r.db.QueryRow(query, score).Scan(&below, &total); err != nil {
+		return 0, err
+	}
+	if total == 0 {
+		return 0, nil
+	}
+	return float64(below) / float64(total) * 100, nil
+}
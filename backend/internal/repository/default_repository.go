@@ -0,0 +1,248 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/models"
+)
+
+type DefaultRepository struct {
+	db DBTX
+}
+
+func NewDefaultRepository(db *sql.DB) *DefaultRepository {
+	return &DefaultRepository{db: db}
+}
+
+const poolDefaultColumns = `
+	id, pool_id, invoice_id, status, currency, due_date, overdue_since, detected_at, declared_at,
+	declared_by, reason, total_claim, total_recovered, total_loss, created_at, updated_at
+`
+
+func scanPoolDefault(row interface{ Scan(...interface{}) error }) (*models.PoolDefault, error) {
+	d := &models.PoolDefault{}
+	err := row.Scan(
+		&d.ID,
+		&d.PoolID,
+		&d.InvoiceID,
+		&d.Status,
+		&d.Currency,
+		&d.DueDate,
+		&d.OverdueSince,
+		&d.DetectedAt,
+		&d.DeclaredAt,
+		&d.DeclaredBy,
+		&d.Reason,
+		&d.TotalClaim,
+		&d.TotalRecovered,
+		&d.TotalLoss,
+		&d.CreatedAt,
+		&d.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *DefaultRepository) queryDefaults(query string, args ...interface{}) ([]models.PoolDefault, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var defaults []models.PoolDefault
+	for rows.Next() {
+		d, err := scanPoolDefault(rows)
+		if err != nil {
+			return nil, err
+		}
+		defaults = append(defaults, *d)
+	}
+	return defaults, rows.Err()
+}
+
+// DetectOverdue opens an overdue case for every disbursed pool whose invoice is
+// still unpaid graceDays after its due date. Pools that already have a case are
+// left alone, so only newly overdue pools are returned.
+func (r *DefaultRepository) DetectOverdue(now time.Time, graceDays int) ([]models.PoolDefault, error) {
+	query := `
+		INSERT INTO pool_defaults (pool_id, invoice_id, status, currency, due_date, overdue_since, detected_at)
+		SELECT fp.id, fp.invoice_id, 'overdue', COALESCE(fp.pool_currency, 'IDR'), i.due_date,
+		       i.due_date + make_interval(days => $2), $1
+		FROM funding_pools fp
+		JOIN invoices i ON i.id = fp.invoice_id
+		WHERE fp.status = 'disbursed' AND i.due_date + make_interval(days => $2) < $1
+		ON CONFLICT (pool_id) DO NOTHING
+		RETURNING ` + poolDefaultColumns
+	return r.queryDefaults(query, now, graceDays)
+}
+
+func (r *DefaultRepository) FindByPoolID(poolID uuid.UUID) (*models.PoolDefault, error) {
+	d, err := scanPoolDefault(r.db.QueryRow(`SELECT `+poolDefaultColumns+` FROM pool_defaults WHERE pool_id = $1`, poolID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return d, err
+}
+
+// FindByPoolIDForUpdate locks the pool's default case; use inside a unit of work
+func (r *DefaultRepository) FindByPoolIDForUpdate(poolID uuid.UUID) (*models.PoolDefault, error) {
+	d, err := scanPoolDefault(r.db.QueryRow(`SELECT `+poolDefaultColumns+` FROM pool_defaults WHERE pool_id = $1 FOR UPDATE`, poolID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return d, err
+}
+
+// FindByStatus lists default cases, all of them when status is empty
+func (r *DefaultRepository) FindByStatus(status string) ([]models.PoolDefault, error) {
+	if status == "" {
+		return r.queryDefaults(`SELECT ` + poolDefaultColumns + ` FROM pool_defaults ORDER BY overdue_since ASC`)
+	}
+	return r.queryDefaults(`SELECT `+poolDefaultColumns+` FROM pool_defaults WHERE status = $1 ORDER BY overdue_since ASC`, status)
+}
+
+// Save inserts the pool's default case or, when an overdue case exists, turns
+// it into d
+func (r *DefaultRepository) Save(d *models.PoolDefault) error {
+	query := `
+		INSERT INTO pool_defaults (pool_id, invoice_id, status, currency, due_date, overdue_since, detected_at,
+			declared_at, declared_by, reason, total_claim, total_recovered, total_loss)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (pool_id) DO UPDATE SET
+			status = EXCLUDED.status,
+			declared_at = EXCLUDED.declared_at,
+			declared_by = EXCLUDED.declared_by,
+			reason = EXCLUDED.reason,
+			total_claim = EXCLUDED.total_claim,
+			total_recovered = EXCLUDED.total_recovered,
+			total_loss = EXCLUDED.total_loss,
+			updated_at = NOW()
+		RETURNING id, detected_at, created_at, updated_at
+	`
+	return r.db.QueryRow(
+		query,
+		d.PoolID,
+		d.InvoiceID,
+		d.Status,
+		d.Currency,
+		d.DueDate,
+		d.OverdueSince,
+		d.DetectedAt,
+		d.DeclaredAt,
+		d.DeclaredBy,
+		d.Reason,
+		d.TotalClaim,
+		d.TotalRecovered,
+		d.TotalLoss,
+	).Scan(&d.ID, &d.DetectedAt, &d.CreatedAt, &d.UpdatedAt)
+}
+
+// UpdateTotals stores the case status and totals after a recovery
+func (r *DefaultRepository) UpdateTotals(d *models.PoolDefault) error {
+	query := `
+		UPDATE pool_defaults SET status = $1, total_recovered = $2, total_loss = $3, updated_at = NOW()
+		WHERE id = $4
+	`
+	_, err := r.db.Exec(query, d.Status, d.TotalRecovered, d.TotalLoss, d.ID)
+	return err
+}
+
+func (r *DefaultRepository) CreateAllocation(a *models.DefaultAllocation) error {
+	query := `
+		INSERT INTO default_allocations (default_id, investment_id, investor_id, tranche, principal, claim, recovered, loss)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`
+	return r.db.QueryRow(
+		query,
+		a.DefaultID,
+		a.InvestmentID,
+		a.InvestorID,
+		a.Tranche,
+		a.Principal,
+		a.Claim,
+		a.Recovered,
+		a.Loss,
+	).Scan(&a.ID)
+}
+
+// FindAllocations returns the allocations of a case, most senior tranche first
+func (r *DefaultRepository) FindAllocations(defaultID uuid.UUID) ([]models.DefaultAllocation, error) {
+	query := `
+		SELECT da.id, da.default_id, da.investment_id, da.investor_id, da.tranche, da.principal, da.claim, da.recovered, da.loss
+		FROM default_allocations da
+		JOIN pool_defaults pd ON pd.id = da.default_id
+		LEFT JOIN pool_tranches pt ON pt.pool_id = pd.pool_id AND pt.name = da.tranche
+		WHERE da.default_id = $1
+		ORDER BY COALESCE(pt.seniority, 2147483647), da.investment_id
+	`
+	rows, err := r.db.Query(query, defaultID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var allocations []models.DefaultAllocation
+	for rows.Next() {
+		var a models.DefaultAllocation
+		if err := rows.Scan(&a.ID, &a.DefaultID, &a.InvestmentID, &a.InvestorID, &a.Tranche,
+			&a.Principal, &a.Claim, &a.Recovered, &a.Loss); err != nil {
+			return nil, err
+		}
+		allocations = append(allocations, a)
+	}
+	return allocations, rows.Err()
+}
+
+func (r *DefaultRepository) UpdateAllocation(a *models.DefaultAllocation) error {
+	_, err := r.db.Exec(`UPDATE default_allocations SET recovered = $1, loss = $2 WHERE id = $3`, a.Recovered, a.Loss, a.ID)
+	return err
+}
+
+func (r *DefaultRepository) CreateRecovery(rec *models.DefaultRecovery) error {
+	query := `
+		INSERT INTO default_recoveries (default_id, amount, to_investors, excess_to_mitra, note, recorded_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+	return r.db.QueryRow(
+		query,
+		rec.DefaultID,
+		rec.Amount,
+		rec.ToInvestors,
+		rec.ExcessToMitra,
+		rec.Note,
+		rec.RecordedBy,
+	).Scan(&rec.ID, &rec.CreatedAt)
+}
+
+func (r *DefaultRepository) FindRecoveries(defaultID uuid.UUID) ([]models.DefaultRecovery, error) {
+	query := `
+		SELECT id, default_id, amount, to_investors, excess_to_mitra, note, recorded_by, created_at
+		FROM default_recoveries
+		WHERE default_id = $1
+		ORDER BY created_at ASC
+	`
+	rows, err := r.db.Query(query, defaultID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recoveries []models.DefaultRecovery
+	for rows.Next() {
+		var rec models.DefaultRecovery
+		if err := rows.Scan(&rec.ID, &rec.DefaultID, &rec.Amount, &rec.ToInvestors, &rec.ExcessToMitra,
+			&rec.Note, &rec.RecordedBy, &rec.CreatedAt); err != nil {
+			return nil, err
+		}
+		recoveries = append(recoveries, rec)
+	}
+	return recoveries, rows.Err()
+}
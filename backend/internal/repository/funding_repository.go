@@ -2,15 +2,17 @@ package repository
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/money"
 )
 
 type FundingRepository struct {
-	db *sql.DB
+	db DBTX
 }
 
 func NewFundingRepository(db *sql.DB) *FundingRepository {
@@ -29,7 +31,7 @@ func (r *FundingRepository) CreatePool(pool *models.FundingPool) error {
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING id, funded_amount, investor_count, created_at, updated_at
 	`
-	return r.db.QueryRow(
+	err := r.db.QueryRow(
 		query,
 		pool.InvoiceID,
 		pool.TargetAmount,
@@ -44,9 +46,93 @@ func (r *FundingRepository) CreatePool(pool *models.FundingPool) error {
 		pool.CatalystInterestRate,
 		pool.PoolCurrency,
 	).Scan(&pool.ID, &pool.FundedAmount, &pool.InvestorCount, &pool.CreatedAt, &pool.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	trancheQuery := `
+		INSERT INTO pool_tranches (
+			pool_id, name, seniority, target_amount, funded_amount, interest_rate,
+			requires_risk_consent, requires_risk_profile, min_investment, max_investment
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at
+	`
+	for i := range pool.Tranches {
+		t := &pool.Tranches[i]
+		t.PoolID = pool.ID
+		if err := r.db.QueryRow(
+			trancheQuery,
+			t.PoolID,
+			t.Name,
+			t.Seniority,
+			t.TargetAmount,
+			t.FundedAmount,
+			t.InterestRate,
+			t.RequiresRiskConsent,
+			t.RequiresRiskProfile,
+			t.MinInvestment,
+			t.MaxInvestment,
+		).Scan(&t.ID, &t.CreatedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindPoolTranches returns a pool's tranches, most senior first
+func (r *FundingRepository) FindPoolTranches(poolID uuid.UUID) ([]models.PoolTranche, error) {
+	query := `
+		SELECT id, pool_id, name, seniority, target_amount, funded_amount, interest_rate,
+		       requires_risk_consent, requires_risk_profile, min_investment, max_investment, created_at
+		FROM pool_tranches
+		WHERE pool_id = $1
+		ORDER BY seniority ASC
+	`
+	rows, err := r.db.Query(query, poolID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tranches []models.PoolTranche
+	for rows.Next() {
+		var t models.PoolTranche
+		if err := rows.Scan(&t.ID, &t.PoolID, &t.Name, &t.Seniority, &t.TargetAmount, &t.FundedAmount,
+			&t.InterestRate, &t.RequiresRiskConsent, &t.RequiresRiskProfile, &t.MinInvestment,
+			&t.MaxInvestment, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		tranches = append(tranches, t)
+	}
+	return tranches, rows.Err()
+}
+
+// attachTranches loads the tranches of pools read by a list query. It runs
+// after the list rows are closed, since a transaction cannot hold two open
+// result sets.
+func (r *FundingRepository) attachTranches(pools []models.FundingPool) error {
+	for i := range pools {
+		tranches, err := r.FindPoolTranches(pools[i].ID)
+		if err != nil {
+			return err
+		}
+		pools[i].Tranches = tranches
+	}
+	return nil
 }
 
 func (r *FundingRepository) FindPoolByID(id uuid.UUID) (*models.FundingPool, error) {
+	return r.findPoolByID(id, false)
+}
+
+// FindPoolByIDForUpdate reads the pool and holds a row lock on it until the
+// surrounding unit of work ends, so tranche capacity checks cannot race
+func (r *FundingRepository) FindPoolByIDForUpdate(id uuid.UUID) (*models.FundingPool, error) {
+	return r.findPoolByID(id, true)
+}
+
+func (r *FundingRepository) findPoolByID(id uuid.UUID, forUpdate bool) (*models.FundingPool, error) {
 	pool := &models.FundingPool{}
 	query := `
 		SELECT id, invoice_id, target_amount, funded_amount, investor_count, status,
@@ -58,6 +144,9 @@ func (r *FundingRepository) FindPoolByID(id uuid.UUID) (*models.FundingPool, err
 		FROM funding_pools
 		WHERE id = $1
 	`
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
 	err := r.db.QueryRow(query, id).Scan(
 		&pool.ID,
 		&pool.InvoiceID,
@@ -86,6 +175,9 @@ func (r *FundingRepository) FindPoolByID(id uuid.UUID) (*models.FundingPool, err
 		}
 		return nil, err
 	}
+	if pool.Tranches, err = r.FindPoolTranches(pool.ID); err != nil {
+		return nil, err
+	}
 	return pool, nil
 }
 
@@ -129,6 +221,9 @@ func (r *FundingRepository) FindPoolByInvoiceID(invoiceID uuid.UUID) (*models.Fu
 		}
 		return nil, err
 	}
+	if pool.Tranches, err = r.FindPoolTranches(pool.ID); err != nil {
+		return nil, err
+	}
 	return pool, nil
 }
 
@@ -187,10 +282,75 @@ func (r *FundingRepository) FindOpenPools(page, perPage int) ([]models.FundingPo
 		}
 		pools = append(pools, pool)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	rows.Close()
+	if err := r.attachTranches(pools); err != nil {
+		return nil, 0, err
+	}
 	return pools, total, nil
 }
 
-func (r *FundingRepository) UpdatePoolFunding(id uuid.UUID, amount float64) error {
+// FindExpiredOpenPools returns open pools whose funding deadline is before now
+func (r *FundingRepository) FindExpiredOpenPools(now time.Time) ([]models.FundingPool, error) {
+	query := `
+		SELECT fp.id, fp.invoice_id, fp.target_amount, fp.funded_amount, fp.investor_count, fp.status,
+		       fp.opened_at, fp.deadline, fp.filled_at, fp.disbursed_at, fp.closed_at, fp.created_at, fp.updated_at,
+		       COALESCE(fp.priority_target, 0), COALESCE(fp.priority_funded, 0),
+		       COALESCE(fp.catalyst_target, 0), COALESCE(fp.catalyst_funded, 0),
+		       COALESCE(fp.priority_interest_rate, 0), COALESCE(fp.catalyst_interest_rate, 0),
+		       COALESCE(fp.pool_currency, 'IDR')
+		FROM funding_pools fp
+		WHERE fp.status = 'open' AND fp.deadline IS NOT NULL AND fp.deadline < $1
+		ORDER BY fp.deadline ASC
+	`
+	rows, err := r.db.Query(query, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pools []models.FundingPool
+	for rows.Next() {
+		var pool models.FundingPool
+		if err := rows.Scan(
+			&pool.ID,
+			&pool.InvoiceID,
+			&pool.TargetAmount,
+			&pool.FundedAmount,
+			&pool.InvestorCount,
+			&pool.Status,
+			&pool.OpenedAt,
+			&pool.Deadline,
+			&pool.FilledAt,
+			&pool.DisbursedAt,
+			&pool.ClosedAt,
+			&pool.CreatedAt,
+			&pool.UpdatedAt,
+			&pool.PriorityTarget,
+			&pool.PriorityFunded,
+			&pool.CatalystTarget,
+			&pool.CatalystFunded,
+			&pool.PriorityInterestRate,
+			&pool.CatalystInterestRate,
+			&pool.PoolCurrency,
+		); err != nil {
+			return nil, err
+		}
+		pools = append(pools, pool)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	if err := r.attachTranches(pools); err != nil {
+		return nil, err
+	}
+	return pools, nil
+}
+
+func (r *FundingRepository) UpdatePoolFunding(id uuid.UUID, amount money.Amount) error {
 	query := `
 		UPDATE funding_pools
 		SET funded_amount = funded_amount + $1, investor_count = investor_count + 1, updated_at = $2
@@ -200,30 +360,29 @@ func (r *FundingRepository) UpdatePoolFunding(id uuid.UUID, amount float64) erro
 	return err
 }
 
-// UpdatePoolTrancheFunding updates funding for a specific tranche
-func (r *FundingRepository) UpdatePoolTrancheFunding(id uuid.UUID, amount float64, tranche models.TrancheType) error {
+// UpdatePoolTrancheFunding adds an investment to a tranche and to the pool
+// totals. The legacy priority/catalyst columns are kept in step.
+func (r *FundingRepository) UpdatePoolTrancheFunding(id uuid.UUID, amount money.Amount, tranche models.TrancheType) error {
 	now := time.Now()
-	var query string
-	if tranche == models.TranchePriority {
-		query = `
-			UPDATE funding_pools
-			SET funded_amount = funded_amount + $1,
-			    priority_funded = COALESCE(priority_funded, 0) + $1,
-			    investor_count = investor_count + 1,
-			    updated_at = $2
-			WHERE id = $3
-		`
-	} else {
-		query = `
-			UPDATE funding_pools
-			SET funded_amount = funded_amount + $1,
-			    catalyst_funded = COALESCE(catalyst_funded, 0) + $1,
-			    investor_count = investor_count + 1,
-			    updated_at = $2
-			WHERE id = $3
-		`
-	}
-	_, err := r.db.Exec(query, amount, now, id)
+	trancheQuery := `
+		UPDATE pool_tranches
+		SET funded_amount = funded_amount + $1
+		WHERE pool_id = $2 AND name = $3
+	`
+	if _, err := r.db.Exec(trancheQuery, amount, id, tranche); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE funding_pools
+		SET funded_amount = funded_amount + $1,
+		    priority_funded = COALESCE(priority_funded, 0) + CASE WHEN $4 = 'priority' THEN $1 ELSE 0 END,
+		    catalyst_funded = COALESCE(catalyst_funded, 0) + CASE WHEN $4 = 'catalyst' THEN $1 ELSE 0 END,
+		    investor_count = investor_count + 1,
+		    updated_at = $2
+		WHERE id = $3
+	`
+	_, err := r.db.Exec(query, amount, now, id, string(tranche))
 	return err
 }
 
@@ -236,7 +395,7 @@ func (r *FundingRepository) UpdatePoolStatus(id uuid.UUID, status models.PoolSta
 		query += `, filled_at = $2`
 	case models.PoolStatusDisbursed:
 		query += `, disbursed_at = $2`
-	case models.PoolStatusClosed:
+	case models.PoolStatusClosed, models.PoolStatusExpired:
 		query += `, closed_at = $2`
 	}
 
@@ -296,6 +455,52 @@ func (r *FundingRepository) FindInvestmentByID(id uuid.UUID) (*models.Investment
 	return inv, nil
 }
 
+// FindInvestmentByIDForUpdate locks the investment; use inside a unit of work
+func (r *FundingRepository) FindInvestmentByIDForUpdate(id uuid.UUID) (*models.Investment, error) {
+	inv := &models.Investment{}
+	query := `
+		SELECT id, pool_id, investor_id, amount, expected_return, actual_return, status,
+		       COALESCE(tranche, 'priority'), tx_hash, invested_at, repaid_at, created_at, updated_at
+		FROM investments
+		WHERE id = $1
+		FOR UPDATE
+	`
+	err := r.db.QueryRow(query, id).Scan(
+		&inv.ID,
+		&inv.PoolID,
+		&inv.InvestorID,
+		&inv.Amount,
+		&inv.ExpectedReturn,
+		&inv.ActualReturn,
+		&inv.Status,
+		&inv.Tranche,
+		&inv.TxHash,
+		&inv.InvestedAt,
+		&inv.RepaidAt,
+		&inv.CreatedAt,
+		&inv.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return inv, nil
+}
+
+// UpdateInvestmentPosition stores who holds an investment and how large it is
+// after a secondary market sale
+func (r *FundingRepository) UpdateInvestmentPosition(inv *models.Investment) error {
+	query := `
+		UPDATE investments
+		SET investor_id = $1, amount = $2, expected_return = $3, actual_return = $4, updated_at = NOW()
+		WHERE id = $5
+	`
+	_, err := r.db.Exec(query, inv.InvestorID, inv.Amount, inv.ExpectedReturn, inv.ActualReturn, inv.ID)
+	return err
+}
+
 func (r *FundingRepository) FindInvestmentsByInvestor(investorID uuid.UUID, page, perPage int) ([]models.Investment, int, error) {
 	var total int
 	countQuery := `SELECT COUNT(*) FROM investments WHERE investor_id = $1`
@@ -469,13 +674,169 @@ func (r *FundingRepository) FindInvestmentsByPoolAndTranche(poolID uuid.UUID, tr
 	return investments, nil
 }
 
-func (r *FundingRepository) UpdateInvestmentStatus(id uuid.UUID, status models.InvestmentStatus, actualReturn *float64) error {
+func (r *FundingRepository) UpdateInvestmentStatus(id uuid.UUID, status models.InvestmentStatus, actualReturn *money.Amount) error {
 	now := time.Now()
 	query := `UPDATE investments SET status = $1, actual_return = $2, repaid_at = $3, updated_at = $3 WHERE id = $4`
 	_, err := r.db.Exec(query, status, actualReturn, now, id)
 	return err
 }
 
+// UpdateInvestmentExpectedReturn stores the return accrued to the due date
+func (r *FundingRepository) UpdateInvestmentExpectedReturn(id uuid.UUID, expectedReturn money.Amount) error {
+	query := `UPDATE investments SET expected_return = $1, updated_at = NOW() WHERE id = $2`
+	_, err := r.db.Exec(query, expectedReturn, id)
+	return err
+}
+
+// AddInvestmentReturn adds an installment to what an active investment has been
+// paid so far, leaving its status alone until the pool is settled
+func (r *FundingRepository) AddInvestmentReturn(id uuid.UUID, amount money.Amount) error {
+	query := `UPDATE investments SET actual_return = COALESCE(actual_return, 0) + $1, updated_at = NOW() WHERE id = $2`
+	_, err := r.db.Exec(query, amount, id)
+	return err
+}
+
+func (r *FundingRepository) CreateRepaymentInstallment(inst *models.RepaymentInstallment) error {
+	query := `
+		INSERT INTO repayment_installments (pool_id, invoice_id, importer_payment_id, amount, platform_fee,
+			to_priority, to_catalyst, to_tranches, late_charges, excess_to_mitra, settled)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, created_at
+	`
+	toTranches, err := json.Marshal(inst.ToTranches)
+	if err != nil {
+		return err
+	}
+	return r.db.QueryRow(
+		query,
+		inst.PoolID,
+		inst.InvoiceID,
+		inst.ImporterPaymentID,
+		inst.Amount,
+		inst.PlatformFee,
+		inst.ToPriority,
+		inst.ToCatalyst,
+		toTranches,
+		inst.LateCharges,
+		inst.ExcessToMitra,
+		inst.Settled,
+	).Scan(&inst.ID, &inst.CreatedAt)
+}
+
+// FindRepaymentInstallments returns the installments received for a pool, oldest first
+func (r *FundingRepository) FindRepaymentInstallments(poolID uuid.UUID) ([]models.RepaymentInstallment, error) {
+	query := `
+		SELECT id, pool_id, invoice_id, importer_payment_id, amount, platform_fee,
+		       to_priority, to_catalyst, to_tranches, late_charges, excess_to_mitra, settled, created_at
+		FROM repayment_installments
+		WHERE pool_id = $1
+		ORDER BY created_at ASC
+	`
+	rows, err := r.db.Query(query, poolID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var installments []models.RepaymentInstallment
+	for rows.Next() {
+		var inst models.RepaymentInstallment
+		var toTranches []byte
+		if err := rows.Scan(&inst.ID, &inst.PoolID, &inst.InvoiceID, &inst.ImporterPaymentID, &inst.Amount,
+			&inst.PlatformFee, &inst.ToPriority, &inst.ToCatalyst, &toTranches, &inst.LateCharges, &inst.ExcessToMitra,
+			&inst.Settled, &inst.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(toTranches, &inst.ToTranches); err != nil {
+			return nil, err
+		}
+		installments = append(installments, inst)
+	}
+	return installments, rows.Err()
+}
+
+// CreateConsentNonce issues a single-use nonce for an investor's consent payload
+func (r *FundingRepository) CreateConsentNonce(investorID uuid.UUID, expiresAt time.Time) (uuid.UUID, error) {
+	var nonce uuid.UUID
+	err := r.db.QueryRow(
+		`INSERT INTO investment_consent_nonces (investor_id, expires_at) VALUES ($1, $2) RETURNING nonce`,
+		investorID, expiresAt,
+	).Scan(&nonce)
+	return nonce, err
+}
+
+// ConsumeConsentNonce marks an unexpired nonce of the investor as used. It
+// reports false when the nonce is unknown, expired or already used.
+func (r *FundingRepository) ConsumeConsentNonce(nonce, investorID uuid.UUID) (bool, error) {
+	query := `
+		UPDATE investment_consent_nonces SET consumed_at = NOW()
+		WHERE nonce = $1 AND investor_id = $2 AND consumed_at IS NULL AND expires_at > NOW()
+		RETURNING nonce
+	`
+	var consumed uuid.UUID
+	err := r.db.QueryRow(query, nonce, investorID).Scan(&consumed)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ErrConsentAlreadyUsed is returned when a signed consent payload already backs
+// another investment
+var ErrConsentAlreadyUsed = errors.New("signed consent was already used for another investment")
+
+// CreateInvestmentConsent stores the signed consent for an investment. The
+// unique digest keeps one signature from backing two investments.
+func (r *FundingRepository) CreateInvestmentConsent(consent *models.InvestmentConsent) error {
+	query := `
+		INSERT INTO investment_consents (investment_id, investor_id, signer_address, tnc_version,
+			consent_texts_hash, typed_data, digest, signature, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (digest) DO NOTHING
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRow(
+		query,
+		consent.InvestmentID,
+		consent.InvestorID,
+		consent.SignerAddress,
+		consent.TncVersion,
+		consent.ConsentTextsHash,
+		[]byte(consent.TypedData),
+		consent.Digest,
+		consent.Signature,
+		consent.ExpiresAt,
+	).Scan(&consent.ID, &consent.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrConsentAlreadyUsed
+	}
+	return err
+}
+
+func (r *FundingRepository) FindInvestmentConsent(investmentID uuid.UUID) (*models.InvestmentConsent, error) {
+	consent := &models.InvestmentConsent{}
+	var typedData []byte
+	query := `
+		SELECT id, investment_id, investor_id, signer_address, tnc_version, consent_texts_hash,
+		       typed_data, digest, signature, expires_at, created_at
+		FROM investment_consents
+		WHERE investment_id = $1
+	`
+	err := r.db.QueryRow(query, investmentID).Scan(
+		&consent.ID, &consent.InvestmentID, &consent.InvestorID, &consent.SignerAddress,
+		&consent.TncVersion, &consent.ConsentTextsHash, &typedData, &consent.Digest,
+		&consent.Signature, &consent.ExpiresAt, &consent.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	consent.TypedData = typedData
+	return consent, nil
+}
+
 // GetInvestorPortfolio calculates portfolio summary for an investor
 func (r *FundingRepository) GetInvestorPortfolio(investorID uuid.UUID) (*models.InvestorPortfolio, error) {
 	portfolio := &models.InvestorPortfolio{}
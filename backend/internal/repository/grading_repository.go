@@ -0,0 +1,265 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/vessel/backend/internal/models"
+)
+
+type GradingRepository struct {
+	db DBTX
+}
+
+func NewGradingRepository(db *sql.DB) *GradingRepository {
+	return &GradingRepository{db: db}
+}
+
+const scorecardColumns = `
+	id, version, name, description, status, grade_thresholds, created_by,
+	activated_at, retired_at, created_at, updated_at
+`
+
+func scanScorecard(row interface{ Scan(...interface{}) error }) (*models.Scorecard, error) {
+	sc := &models.Scorecard{}
+	var thresholds []byte
+	err := row.Scan(
+		&sc.ID,
+		&sc.Version,
+		&sc.Name,
+		&sc.Description,
+		&sc.Status,
+		&thresholds,
+		&sc.CreatedBy,
+		&sc.ActivatedAt,
+		&sc.RetiredAt,
+		&sc.CreatedAt,
+		&sc.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(thresholds, &sc.GradeThresholds); err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+// CreateScorecard stores a draft scorecard and its factors as the next version
+func (r *GradingRepository) CreateScorecard(sc *models.Scorecard) error {
+	thresholds, err := json.Marshal(sc.GradeThresholds)
+	if err != nil {
+		return err
+	}
+
+	tx, err := begin(r.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO grading_scorecards (version, name, description, status, grade_thresholds, created_by)
+		SELECT COALESCE(MAX(version), 0) + 1, $1, $2, $3, $4, $5 FROM grading_scorecards
+		RETURNING id, version, created_at, updated_at
+	`
+	if err := tx.QueryRow(query, sc.Name, sc.Description, sc.Status, thresholds, sc.CreatedBy).
+		Scan(&sc.ID, &sc.Version, &sc.CreatedAt, &sc.UpdatedAt); err != nil {
+		return err
+	}
+
+	for i := range sc.Factors {
+		f := &sc.Factors[i]
+		bands, err := json.Marshal(f.Bands)
+		if err != nil {
+			return err
+		}
+		f.ScorecardID = sc.ID
+		f.Position = i + 1
+		if err := tx.QueryRow(
+			`INSERT INTO grading_scorecard_factors (scorecard_id, factor, weight, bands, position) VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+			f.ScorecardID, f.Factor, f.Weight, bands, f.Position,
+		).Scan(&f.ID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *GradingRepository) FindScorecardByID(id uuid.UUID) (*models.Scorecard, error) {
+	sc, err := scanScorecard(r.db.QueryRow(`SELECT `+scorecardColumns+` FROM grading_scorecards WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadFactors([]*models.Scorecard{sc}); err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+// FindScorecards lists every scorecard with its factors, newest version first
+func (r *GradingRepository) FindScorecards() ([]models.Scorecard, error) {
+	return r.queryScorecards(`SELECT ` + scorecardColumns + ` FROM grading_scorecards ORDER BY version DESC`)
+}
+
+// FindLiveScorecards returns the active scorecard and those in shadow, oldest version first
+func (r *GradingRepository) FindLiveScorecards() ([]models.Scorecard, error) {
+	return r.queryScorecards(`SELECT ` + scorecardColumns + ` FROM grading_scorecards WHERE status IN ('active', 'shadow') ORDER BY version ASC`)
+}
+
+func (r *GradingRepository) queryScorecards(query string, args ...interface{}) ([]models.Scorecard, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refs []*models.Scorecard
+	for rows.Next() {
+		sc, err := scanScorecard(rows)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, sc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.loadFactors(refs); err != nil {
+		return nil, err
+	}
+
+	scorecards := make([]models.Scorecard, 0, len(refs))
+	for _, sc := range refs {
+		scorecards = append(scorecards, *sc)
+	}
+	return scorecards, nil
+}
+
+// loadFactors fills in the factors of the scorecards, in position order
+func (r *GradingRepository) loadFactors(scorecards []*models.Scorecard) error {
+	if len(scorecards) == 0 {
+		return nil
+	}
+	byID := make(map[uuid.UUID]*models.Scorecard, len(scorecards))
+	ids := make([]string, 0, len(scorecards))
+	for _, sc := range scorecards {
+		byID[sc.ID] = sc
+		ids = append(ids, sc.ID.String())
+	}
+
+	query := `
+		SELECT id, scorecard_id, factor, weight, bands, position
+		FROM grading_scorecard_factors
+		WHERE scorecard_id = ANY($1::uuid[])
+		ORDER BY position ASC
+	`
+	rows, err := r.db.Query(query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var f models.ScorecardFactor
+		var bands []byte
+		if err := rows.Scan(&f.ID, &f.ScorecardID, &f.Factor, &f.Weight, &bands, &f.Position); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(bands, &f.Bands); err != nil {
+			return err
+		}
+		sc := byID[f.ScorecardID]
+		sc.Factors = append(sc.Factors, f)
+	}
+	return rows.Err()
+}
+
+// SetScorecardStatus moves a scorecard to shadow or retired. Activating goes
+// through ActivateScorecard so only one scorecard is ever active.
+func (r *GradingRepository) SetScorecardStatus(id uuid.UUID, status models.ScorecardStatus) error {
+	query := `
+		UPDATE grading_scorecards
+		SET status = $1::text,
+		    retired_at = CASE WHEN $1::text = 'retired' THEN NOW() ELSE retired_at END,
+		    updated_at = NOW()
+		WHERE id = $2
+	`
+	_, err := r.db.Exec(query, status, id)
+	return err
+}
+
+// ActivateScorecard retires the active scorecard and activates id in its place
+func (r *GradingRepository) ActivateScorecard(id uuid.UUID) error {
+	tx, err := begin(r.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	retire := `UPDATE grading_scorecards SET status = 'retired', retired_at = NOW(), updated_at = NOW() WHERE status = 'active' AND id <> $1`
+	if _, err := tx.Exec(retire, id); err != nil {
+		return err
+	}
+	activate := `UPDATE grading_scorecards SET status = 'active', activated_at = NOW(), retired_at = NULL, updated_at = NOW() WHERE id = $1`
+	if _, err := tx.Exec(activate, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SaveInvoiceGradeScore stores an invoice's score under a scorecard, replacing
+// the previous one
+func (r *GradingRepository) SaveInvoiceGradeScore(s *models.InvoiceGradeScore) error {
+	factors, err := json.Marshal(s.Factors)
+	if err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO invoice_grade_scores (invoice_id, scorecard_id, scorecard_version, mode, score, grade, factors)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (invoice_id, scorecard_id) DO UPDATE
+		SET mode = EXCLUDED.mode, score = EXCLUDED.score, grade = EXCLUDED.grade,
+		    factors = EXCLUDED.factors, graded_at = NOW()
+		RETURNING id, graded_at
+	`
+	return r.db.QueryRow(query, s.InvoiceID, s.ScorecardID, s.ScorecardVersion, s.Mode, s.Score, s.Grade, factors).
+		Scan(&s.ID, &s.GradedAt)
+}
+
+// FindInvoiceGradeScores lists an invoice's stored scores, newest scorecard first
+func (r *GradingRepository) FindInvoiceGradeScores(invoiceID uuid.UUID) ([]models.InvoiceGradeScore, error) {
+	query := `
+		SELECT id, invoice_id, scorecard_id, scorecard_version, mode, score, grade, factors, graded_at
+		FROM invoice_grade_scores
+		WHERE invoice_id = $1
+		ORDER BY scorecard_version DESC
+	`
+	rows, err := r.db.Query(query, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var scores []models.InvoiceGradeScore
+	for rows.Next() {
+		var s models.InvoiceGradeScore
+		var factors []byte
+		if err := rows.Scan(&s.ID, &s.InvoiceID, &s.ScorecardID, &s.ScorecardVersion, &s.Mode, &s.Score,
+			&s.Grade, &factors, &s.GradedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(factors, &s.Factors); err != nil {
+			return nil, err
+		}
+		scores = append(scores, s)
+	}
+	return scores, rows.Err()
+}
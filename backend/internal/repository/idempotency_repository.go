@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/models"
+)
+
+type IdempotencyRepository struct {
+	db DBTX
+}
+
+func NewIdempotencyRepository(db *sql.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Reserve claims an idempotency key for a new request. It returns nil when the key
+// was free (or had expired) and is now held by the caller in processing state;
+// otherwise it returns the live record that already owns the key.
+func (r *IdempotencyRepository) Reserve(record *models.IdempotencyKey) (*models.IdempotencyKey, error) {
+	query := `
+		INSERT INTO idempotency_keys (scope, idempotency_key, method, path, request_hash, status, expires_at)
+		VALUES ($1, $2, $3, $4, $5, 'processing', $6)
+		ON CONFLICT (scope, idempotency_key) DO UPDATE SET
+			method = EXCLUDED.method,
+			path = EXCLUDED.path,
+			request_hash = EXCLUDED.request_hash,
+			status = 'processing',
+			response_status = NULL,
+			response_content_type = NULL,
+			response_body = NULL,
+			created_at = NOW(),
+			completed_at = NULL,
+			expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at < NOW()
+		RETURNING id, status, created_at
+	`
+	err := r.db.QueryRow(query, record.Scope, record.Key, record.Method, record.Path, record.RequestHash, record.ExpiresAt).
+		Scan(&record.ID, &record.Status, &record.CreatedAt)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	existing, err := r.FindByKey(record.Scope, record.Key)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		// The holder released the key between our insert and lookup
+		return nil, errors.New("idempotency key changed concurrently, please retry")
+	}
+	return existing, nil
+}
+
+func (r *IdempotencyRepository) FindByKey(scope, key string) (*models.IdempotencyKey, error) {
+	record := &models.IdempotencyKey{}
+	query := `
+		SELECT id, scope, idempotency_key, method, path, request_hash, status,
+		       response_status, response_content_type, response_body, created_at, completed_at, expires_at
+		FROM idempotency_keys
+		WHERE scope = $1 AND idempotency_key = $2
+	`
+	err := r.db.QueryRow(query, scope, key).Scan(
+		&record.ID, &record.Scope, &record.Key, &record.Method, &record.Path, &record.RequestHash, &record.Status,
+		&record.ResponseStatus, &record.ResponseContentType, &record.ResponseBody, &record.CreatedAt, &record.CompletedAt, &record.ExpiresAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// Complete stores the response so retries with the same key replay it
+func (r *IdempotencyRepository) Complete(id uuid.UUID, status int, contentType string, body []byte) error {
+	query := `
+		UPDATE idempotency_keys
+		SET status = 'completed', response_status = $1, response_content_type = $2, response_body = $3, completed_at = $4
+		WHERE id = $5
+	`
+	_, err := r.db.Exec(query, status, contentType, body, time.Now(), id)
+	return err
+}
+
+// Release frees a key that is still processing so the client can retry,
+// used when the handler failed before producing a replayable response
+func (r *IdempotencyRepository) Release(id uuid.UUID) error {
+	query := `DELETE FROM idempotency_keys WHERE id = $1 AND status = 'processing'`
+	_, err := r.db.Exec(query, id)
+	return err
+}
+
+// DeleteExpired removes keys whose retention window has passed
+func (r *IdempotencyRepository) DeleteExpired() (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM idempotency_keys WHERE expires_at < NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
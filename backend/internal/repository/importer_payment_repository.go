@@ -7,25 +7,27 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/money"
 )
 
 type ImporterPaymentRepository struct {
-	db *sql.DB
+	db DBTX
 }
 
 func NewImporterPaymentRepository(db *sql.DB) *ImporterPaymentRepository {
 	return &ImporterPaymentRepository{db: db}
 }
 
-// Create creates a new importer payment record
+// Create creates a new importer payment record, linked to the buyer of its
+// invoice unless BuyerID is set
 func (r *ImporterPaymentRepository) Create(payment *models.ImporterPayment) error {
 	query := `
 		INSERT INTO importer_payments (
 			invoice_id, pool_id, buyer_email, buyer_name, amount_due, currency, 
-			payment_status, due_date
+			payment_status, due_date, buyer_id
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		RETURNING id, amount_paid, created_at, updated_at
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, COALESCE($9, (SELECT buyer_id FROM invoices WHERE id = $1)))
+		RETURNING id, buyer_id, amount_paid, created_at, updated_at
 	`
 	return r.db.QueryRow(
 		query,
@@ -37,14 +39,15 @@ func (r *ImporterPaymentRepository) Create(payment *models.ImporterPayment) erro
 		payment.Currency,
 		payment.PaymentStatus,
 		payment.DueDate,
-	).Scan(&payment.ID, &payment.AmountPaid, &payment.CreatedAt, &payment.UpdatedAt)
+		payment.BuyerID,
+	).Scan(&payment.ID, &payment.BuyerID, &payment.AmountPaid, &payment.CreatedAt, &payment.UpdatedAt)
 }
 
 // FindByID finds an importer payment by ID
 func (r *ImporterPaymentRepository) FindByID(id uuid.UUID) (*models.ImporterPayment, error) {
 	payment := &models.ImporterPayment{}
 	query := `
-		SELECT id, invoice_id, pool_id, buyer_email, buyer_name, amount_due, amount_paid,
+		SELECT id, invoice_id, pool_id, buyer_id, buyer_email, buyer_name, amount_due, amount_paid,
 		       currency, payment_status, due_date, paid_at, tx_hash, created_at, updated_at
 		FROM importer_payments
 		WHERE id = $1
@@ -53,6 +56,7 @@ func (r *ImporterPaymentRepository) FindByID(id uuid.UUID) (*models.ImporterPaym
 		&payment.ID,
 		&payment.InvoiceID,
 		&payment.PoolID,
+		&payment.BuyerID,
 		&payment.BuyerEmail,
 		&payment.BuyerName,
 		&payment.AmountDue,
@@ -78,7 +82,7 @@ func (r *ImporterPaymentRepository) FindByID(id uuid.UUID) (*models.ImporterPaym
 func (r *ImporterPaymentRepository) FindByInvoiceID(invoiceID uuid.UUID) (*models.ImporterPayment, error) {
 	payment := &models.ImporterPayment{}
 	query := `
-		SELECT id, invoice_id, pool_id, buyer_email, buyer_name, amount_due, amount_paid,
+		SELECT id, invoice_id, pool_id, buyer_id, buyer_email, buyer_name, amount_due, amount_paid,
 		       currency, payment_status, due_date, paid_at, tx_hash, created_at, updated_at
 		FROM importer_payments
 		WHERE invoice_id = $1
@@ -87,6 +91,7 @@ func (r *ImporterPaymentRepository) FindByInvoiceID(invoiceID uuid.UUID) (*model
 		&payment.ID,
 		&payment.InvoiceID,
 		&payment.PoolID,
+		&payment.BuyerID,
 		&payment.BuyerEmail,
 		&payment.BuyerName,
 		&payment.AmountDue,
@@ -108,8 +113,95 @@ func (r *ImporterPaymentRepository) FindByInvoiceID(invoiceID uuid.UUID) (*model
 	return payment, nil
 }
 
+// FindByIDForUpdate locks an importer payment; use inside a unit of work
+func (r *ImporterPaymentRepository) FindByIDForUpdate(id uuid.UUID) (*models.ImporterPayment, error) {
+	payment := &models.ImporterPayment{}
+	query := `
+		SELECT id, invoice_id, pool_id, buyer_id, buyer_email, buyer_name, amount_due, amount_paid,
+		       currency, payment_status, due_date, paid_at, tx_hash, created_at, updated_at
+		FROM importer_payments
+		WHERE id = $1
+		FOR UPDATE
+	`
+	err := r.db.QueryRow(query, id).Scan(
+		&payment.ID,
+		&payment.InvoiceID,
+		&payment.PoolID,
+		&payment.BuyerID,
+		&payment.BuyerEmail,
+		&payment.BuyerName,
+		&payment.AmountDue,
+		&payment.AmountPaid,
+		&payment.Currency,
+		&payment.PaymentStatus,
+		&payment.DueDate,
+		&payment.PaidAt,
+		&payment.TxHash,
+		&payment.CreatedAt,
+		&payment.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return payment, nil
+}
+
+// AddLateCharges raises the amount due of the pool's open importer payment by
+// newly accrued late charges
+func (r *ImporterPaymentRepository) AddLateCharges(poolID uuid.UUID, amount money.Amount) error {
+	query := `
+		UPDATE importer_payments
+		SET amount_due = amount_due + $1, updated_at = NOW()
+		WHERE pool_id = $2 AND payment_status IN ($3, $4, $5)
+	`
+	_, err := r.db.Exec(query, amount, poolID,
+		models.ImporterPaymentStatusPending, models.ImporterPaymentStatusPartial, models.ImporterPaymentStatusOverdue)
+	return err
+}
+
+// AddPayment adds an installment to the amount paid. The payment becomes
+// partial, or paid once the amount due is covered.
+func (r *ImporterPaymentRepository) AddPayment(id uuid.UUID, amount money.Amount, txHash string) (*models.ImporterPayment, error) {
+	payment := &models.ImporterPayment{}
+	query := `
+		UPDATE importer_payments
+		SET amount_paid = amount_paid + $1,
+		    payment_status = CASE WHEN amount_paid + $1 >= amount_due THEN $2 ELSE $3 END,
+		    paid_at = CASE WHEN amount_paid + $1 >= amount_due THEN $4 ELSE paid_at END,
+		    tx_hash = $5,
+		    updated_at = $4
+		WHERE id = $6
+		RETURNING id, invoice_id, pool_id, buyer_id, buyer_email, buyer_name, amount_due, amount_paid,
+		          currency, payment_status, due_date, paid_at, tx_hash, created_at, updated_at
+	`
+	err := r.db.QueryRow(query, amount, models.ImporterPaymentStatusPaid, models.ImporterPaymentStatusPartial, time.Now(), txHash, id).Scan(
+		&payment.ID,
+		&payment.InvoiceID,
+		&payment.PoolID,
+		&payment.BuyerID,
+		&payment.BuyerEmail,
+		&payment.BuyerName,
+		&payment.AmountDue,
+		&payment.AmountPaid,
+		&payment.Currency,
+		&payment.PaymentStatus,
+		&payment.DueDate,
+		&payment.PaidAt,
+		&payment.TxHash,
+		&payment.CreatedAt,
+		&payment.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
 // UpdatePayment updates payment after importer pays
-func (r *ImporterPaymentRepository) UpdatePayment(id uuid.UUID, amountPaid float64, txHash string) error {
+func (r *ImporterPaymentRepository) UpdatePayment(id uuid.UUID, amountPaid money.Amount, txHash string) error {
 	now := time.Now()
 	query := `
 		UPDATE importer_payments
@@ -127,13 +219,13 @@ func (r *ImporterPaymentRepository) UpdateStatus(id uuid.UUID, status models.Imp
 	return err
 }
 
-// FindPendingByDueDate finds pending payments that are overdue
+// FindPendingByDueDate finds unpaid and partially paid payments that are overdue
 func (r *ImporterPaymentRepository) FindPendingByDueDate(before time.Time) ([]models.ImporterPayment, error) {
 	query := `
-		SELECT id, invoice_id, pool_id, buyer_email, buyer_name, amount_due, amount_paid,
+		SELECT id, invoice_id, pool_id, buyer_id, buyer_email, buyer_name, amount_due, amount_paid,
 		       currency, payment_status, due_date, paid_at, tx_hash, created_at, updated_at
 		FROM importer_payments
-		WHERE payment_status = 'pending' AND due_date < $1
+		WHERE payment_status IN ('pending', 'partial') AND due_date < $1
 	`
 	rows, err := r.db.Query(query, before)
 	if err != nil {
@@ -145,7 +237,7 @@ func (r *ImporterPaymentRepository) FindPendingByDueDate(before time.Time) ([]mo
 	for rows.Next() {
 		var p models.ImporterPayment
 		if err := rows.Scan(
-			&p.ID, &p.InvoiceID, &p.PoolID, &p.BuyerEmail, &p.BuyerName,
+			&p.ID, &p.InvoiceID, &p.PoolID, &p.BuyerID, &p.BuyerEmail, &p.BuyerName,
 			&p.AmountDue, &p.AmountPaid, &p.Currency, &p.PaymentStatus,
 			&p.DueDate, &p.PaidAt, &p.TxHash, &p.CreatedAt, &p.UpdatedAt,
 		); err != nil {
@@ -1,8 +1,11 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/money"
 )
 
 // UserRepositoryInterface defines the contract for user data operations
@@ -12,9 +15,9 @@ type UserRepositoryInterface interface {
 	FindByUsername(username string) (*models.User, error)
 	FindByEmailOrUsername(identifier string) (*models.User, error)
 	FindByID(id uuid.UUID) (*models.User, error)
+	FindByIDForUpdate(id uuid.UUID) (*models.User, error)
 	FindProfileByUserID(userID uuid.UUID) (*models.UserProfile, error)
 	UpdateProfile(userID uuid.UUID, req *models.UpdateProfileRequest) error
-	UpdateBalance(userID uuid.UUID, amount float64) error
 	SetVerified(userID uuid.UUID, verified bool) error
 	SetEmailVerified(userID uuid.UUID, verified bool) error
 	UpdateMemberStatus(userID uuid.UUID, status models.MemberStatus) error
@@ -37,9 +40,6 @@ type UserRepositoryInterface interface {
 	// Password methods
 	UpdatePassword(userID uuid.UUID, hashedPassword string) error
 
-	// Wallet methods
-	UpdateWalletAddress(userID uuid.UUID, walletAddress string) error
-
 	// Complete Registration
 	CompleteUserRegistration(userID uuid.UUID, profile *models.UserProfile, identity *models.UserIdentity, bankAccount *models.BankAccount) error
 }
@@ -68,7 +68,7 @@ type InvoiceRepositoryInterface interface {
 	Update(invoice *models.Invoice) error
 	UpdateStatus(id uuid.UUID, status models.InvoiceStatus) error
 	SetInterestRate(id uuid.UUID, rate float64) error
-	SetAdvanceAmount(id uuid.UUID, amount float64) error
+	SetAdvanceAmount(id uuid.UUID, amount money.Amount) error
 	SetDocumentHash(id uuid.UUID, hash string) error
 	Delete(id uuid.UUID) error
 
@@ -80,11 +80,13 @@ type InvoiceRepositoryInterface interface {
 	// NFT methods
 	CreateNFT(nft *models.InvoiceNFT) error
 	FindNFTByInvoiceID(invoiceID uuid.UUID) (*models.InvoiceNFT, error)
+	FindMintedNFTs() ([]models.InvoiceNFT, error)
 	UpdateNFTOwner(id uuid.UUID, owner string) error
+	VerifyNFTToken(mintTxHash string, tokenID int64) (bool, error)
 	BurnNFT(id uuid.UUID, txHash string) error
 
 	// Transaction methods
-	ApproveWithTransaction(id uuid.UUID, interestRate, advanceAmount float64) error
+	ApproveWithTransaction(id uuid.UUID, interestRate float64, advanceAmount money.Amount) error
 }
 
 // FundingRepositoryInterface defines the contract for funding data operations
@@ -92,20 +94,37 @@ type FundingRepositoryInterface interface {
 	// Pool methods
 	CreatePool(pool *models.FundingPool) error
 	FindPoolByID(id uuid.UUID) (*models.FundingPool, error)
+	FindPoolByIDForUpdate(id uuid.UUID) (*models.FundingPool, error)
 	FindPoolByInvoiceID(invoiceID uuid.UUID) (*models.FundingPool, error)
+	FindPoolTranches(poolID uuid.UUID) ([]models.PoolTranche, error)
 	FindOpenPools(page, perPage int) ([]models.FundingPool, int, error)
-	UpdatePoolFunding(id uuid.UUID, amount float64) error
-	UpdatePoolTrancheFunding(id uuid.UUID, amount float64, tranche models.TrancheType) error
+	FindExpiredOpenPools(now time.Time) ([]models.FundingPool, error)
+	UpdatePoolFunding(id uuid.UUID, amount money.Amount) error
+	UpdatePoolTrancheFunding(id uuid.UUID, amount money.Amount, tranche models.TrancheType) error
 	UpdatePoolStatus(id uuid.UUID, status models.PoolStatus) error
 
 	// Investment methods
 	CreateInvestment(inv *models.Investment) error
 	FindInvestmentByID(id uuid.UUID) (*models.Investment, error)
+	FindInvestmentByIDForUpdate(id uuid.UUID) (*models.Investment, error)
 	FindInvestmentsByInvestor(investorID uuid.UUID, page, perPage int) ([]models.Investment, int, error)
 	FindActiveInvestmentsByInvestor(investorID uuid.UUID, page, perPage int) ([]models.Investment, int, error)
 	FindInvestmentsByPool(poolID uuid.UUID) ([]models.Investment, error)
 	FindInvestmentsByPoolAndTranche(poolID uuid.UUID, tranche models.TrancheType) ([]models.Investment, error)
-	UpdateInvestmentStatus(id uuid.UUID, status models.InvestmentStatus, actualReturn *float64) error
+	UpdateInvestmentStatus(id uuid.UUID, status models.InvestmentStatus, actualReturn *money.Amount) error
+	UpdateInvestmentPosition(inv *models.Investment) error
+	UpdateInvestmentExpectedReturn(id uuid.UUID, expectedReturn money.Amount) error
+	AddInvestmentReturn(id uuid.UUID, amount money.Amount) error
+
+	// Repayment methods
+	CreateRepaymentInstallment(inst *models.RepaymentInstallment) error
+	FindRepaymentInstallments(poolID uuid.UUID) ([]models.RepaymentInstallment, error)
+
+	// Signed consent methods
+	CreateConsentNonce(investorID uuid.UUID, expiresAt time.Time) (uuid.UUID, error)
+	ConsumeConsentNonce(nonce, investorID uuid.UUID) (bool, error)
+	CreateInvestmentConsent(consent *models.InvestmentConsent) error
+	FindInvestmentConsent(investmentID uuid.UUID) (*models.InvestmentConsent, error)
 
 	// Portfolio methods
 	GetInvestorPortfolio(investorID uuid.UUID) (*models.InvestorPortfolio, error)
@@ -120,6 +139,7 @@ type TransactionRepositoryInterface interface {
 	FindByInvoice(invoiceID uuid.UUID) ([]models.Transaction, error)
 	UpdateStatus(id uuid.UUID, status models.TransactionStatus) error
 	UpdateBlockInfo(id uuid.UUID, blockNumber, gasUsed int64) error
+	SetTxHash(id uuid.UUID, txHash string) error
 }
 
 // RiskQuestionnaireRepositoryInterface defines the contract for risk questionnaire data operations
@@ -130,6 +150,184 @@ type RiskQuestionnaireRepositoryInterface interface {
 	IsCatalystUnlocked(userID uuid.UUID) (bool, error)
 }
 
+// LedgerRepositoryInterface defines the contract for double-entry ledger operations
+type LedgerRepositoryInterface interface {
+	PostEntry(entry *models.JournalEntry) error
+	GetAccountBalance(code string) (money.Amount, error)
+	GetTrialBalance() (*models.TrialBalance, error)
+	FindEntriesByReference(referenceType string, referenceID uuid.UUID) ([]models.JournalEntry, error)
+}
+
+// IdempotencyRepositoryInterface defines the contract for Idempotency-Key storage
+type IdempotencyRepositoryInterface interface {
+	Reserve(record *models.IdempotencyKey) (*models.IdempotencyKey, error)
+	FindByKey(scope, key string) (*models.IdempotencyKey, error)
+	Complete(id uuid.UUID, status int, contentType string, body []byte) error
+	Release(id uuid.UUID) error
+	DeleteExpired() (int64, error)
+}
+
+// VirtualAccountRepositoryInterface defines the contract for mitra repayment VA storage
+type VirtualAccountRepositoryInterface interface {
+	ReplaceActive(va *models.VirtualAccount) error
+	FindByID(id uuid.UUID) (*models.VirtualAccount, error)
+	FindActiveByPool(poolID uuid.UUID) (*models.VirtualAccount, error)
+	UpdateStatus(id uuid.UUID, status models.VAStatus) error
+	MarkPaid(id uuid.UUID) error
+	ExpireOverdue(now time.Time) (int64, error)
+}
+
+// PaymentGatewayRepositoryInterface defines the contract for gateway charges and webhook deduplication
+type PaymentGatewayRepositoryInterface interface {
+	CreatePayment(p *models.GatewayPayment) error
+	FindPaymentByExternalID(provider, externalID string) (*models.GatewayPayment, error)
+	FindLatestPaymentByReference(purpose models.GatewayPaymentPurpose, referenceID uuid.UUID) (*models.GatewayPayment, error)
+	UpdatePaymentStatus(id uuid.UUID, status models.GatewayPaymentStatus) (bool, error)
+	ClaimPaid(id uuid.UUID) (bool, error)
+	RecordWebhookEvent(event *models.PaymentWebhookEvent) (bool, error)
+	MarkWebhookEventProcessed(id uuid.UUID) error
+}
+
+// OnchainOutboxRepositoryInterface defines the contract for queued InvoicePool writes
+type OnchainOutboxRepositoryInterface interface {
+	Enqueue(e *models.OnchainOutboxEntry) error
+	ClaimDue(limit int, lease time.Duration) ([]models.OnchainOutboxEntry, error)
+	MarkSubmitted(id uuid.UUID, sub *models.OnchainSubmission) error
+	MarkReplaced(id uuid.UUID, sub *models.OnchainSubmission) error
+	MarkConfirmed(id uuid.UUID, txHash string, blockNumber, gasUsed *int64) error
+	FindSubmitted(limit int) ([]models.OnchainOutboxEntry, error)
+	MarkRetry(id uuid.UUID, lastError string, nextAttempt time.Time) error
+	MarkFailed(id uuid.UUID, lastError string) error
+	Requeue(id uuid.UUID) (bool, error)
+	Cancel(id uuid.UUID, reason string) (bool, error)
+	FindByID(id uuid.UUID) (*models.OnchainOutboxEntry, error)
+	List(status string, stuckAfter time.Time, page, perPage int) ([]models.OnchainOutboxEntry, int, error)
+	CountByStatus() (map[string]int, error)
+	CountOpenByInvoice(invoiceID uuid.UUID) (int, error)
+}
+
+// OnchainEventRepositoryInterface defines the contract for indexed contract events
+type OnchainEventRepositoryInterface interface {
+	GetCursor(name string, chainID int64) (lastBlock int64, found bool, err error)
+	FindIndexedBlocks(name string, limit int) ([]models.IndexedBlock, error)
+	SaveBatch(name string, chainID int64, events []models.OnchainEvent, blocks []models.IndexedBlock, lastBlock, pruneBelow int64) error
+	Rewind(name string, chainID int64, ancestor int64) (int64, error)
+	FindByTokenID(chainID int64, tokenID int64) ([]models.OnchainEvent, error)
+}
+
+// WalletRepositoryInterface defines the contract for wallet ownership proofs
+type WalletRepositoryInterface interface {
+	CreateChallenge(c *models.WalletChallenge) error
+	FindChallengeByID(id uuid.UUID) (*models.WalletChallenge, error)
+	FindUserByWallet(address string) (*uuid.UUID, error)
+	Link(challengeID uuid.UUID, link *models.WalletLink) (bool, error)
+	FindLinksByUser(userID uuid.UUID) ([]models.WalletLink, error)
+}
+
+// DefaultRepositoryInterface defines the contract for pool default cases
+type DefaultRepositoryInterface interface {
+	DetectOverdue(now time.Time, graceDays int) ([]models.PoolDefault, error)
+	FindByPoolID(poolID uuid.UUID) (*models.PoolDefault, error)
+	FindByPoolIDForUpdate(poolID uuid.UUID) (*models.PoolDefault, error)
+	FindByStatus(status string) ([]models.PoolDefault, error)
+	Save(d *models.PoolDefault) error
+	UpdateTotals(d *models.PoolDefault) error
+	CreateAllocation(a *models.DefaultAllocation) error
+	FindAllocations(defaultID uuid.UUID) ([]models.DefaultAllocation, error)
+	UpdateAllocation(a *models.DefaultAllocation) error
+	CreateRecovery(rec *models.DefaultRecovery) error
+	FindRecoveries(defaultID uuid.UUID) ([]models.DefaultRecovery, error)
+}
+
+// ImporterPaymentRepositoryInterface defines the importer payment operations used
+// inside a unit of work
+type ImporterPaymentRepositoryInterface interface {
+	FindByID(id uuid.UUID) (*models.ImporterPayment, error)
+	FindByIDForUpdate(id uuid.UUID) (*models.ImporterPayment, error)
+	AddPayment(id uuid.UUID, amount money.Amount, txHash string) (*models.ImporterPayment, error)
+	AddLateCharges(poolID uuid.UUID, amount money.Amount) error
+}
+
+// LateChargeRepositoryInterface defines late payment charge operations
+type LateChargeRepositoryInterface interface {
+	FindByPoolID(poolID uuid.UUID) (*models.LateCharge, error)
+	FindByPoolIDForUpdate(poolID uuid.UUID) (*models.LateCharge, error)
+	FindOverduePoolIDs(now time.Time) ([]uuid.UUID, error)
+	Save(c *models.LateCharge) error
+	AddPaid(id uuid.UUID, amount money.Amount) error
+}
+
+// SecondaryMarketRepositoryInterface defines secondary market listing operations
+type SecondaryMarketRepositoryInterface interface {
+	Create(l *models.SecondaryListing) error
+	FindByID(id uuid.UUID) (*models.SecondaryListing, error)
+	FindByIDForUpdate(id uuid.UUID) (*models.SecondaryListing, error)
+	FindOpenByInvestment(investmentID uuid.UUID) (*models.SecondaryListing, error)
+	FindOpen(poolID *uuid.UUID, tranche string, page, perPage int) ([]models.SecondaryListing, int, error)
+	FindBySeller(sellerID uuid.UUID, page, perPage int) ([]models.SecondaryListing, int, error)
+	MarkSold(id, buyerID, buyerInvestmentID uuid.UUID) error
+	Cancel(id uuid.UUID) error
+}
+
+// GradingRepositoryInterface defines grading scorecard and invoice score operations
+type GradingRepositoryInterface interface {
+	CreateScorecard(sc *models.Scorecard) error
+	FindScorecardByID(id uuid.UUID) (*models.Scorecard, error)
+	FindScorecards() ([]models.Scorecard, error)
+	FindLiveScorecards() ([]models.Scorecard, error)
+	SetScorecardStatus(id uuid.UUID, status models.ScorecardStatus) error
+	ActivateScorecard(id uuid.UUID) error
+	SaveInvoiceGradeScore(s *models.InvoiceGradeScore) error
+	FindInvoiceGradeScores(invoiceID uuid.UUID) ([]models.InvoiceGradeScore, error)
+}
+
+// CountryTierRepositoryInterface defines country risk tier operations
+type CountryTierRepositoryInterface interface {
+	FindAll() ([]models.CountryTier, error)
+	FindByCode(code string) (*models.CountryTier, error)
+	Create(t *models.CountryTier, changedBy uuid.UUID, reason *string) error
+	Update(t *models.CountryTier, changedBy uuid.UUID, reason *string) error
+	Delete(code string, changedBy uuid.UUID, reason *string) error
+	FindHistory(code string, page, perPage int) ([]models.CountryTierChange, int, error)
+}
+
+// AnalyticsRepositoryInterface defines repayment outcome reads for grade analytics
+type AnalyticsRepositoryInterface interface {
+	FindInvoiceOutcomes(filter *models.OutcomeFilter) ([]models.InvoiceOutcome, error)
+	FindReplayInvoices(invoiceIDs []uuid.UUID) (map[uuid.UUID]*models.ReplayInvoice, error)
+}
+
+// CreditScoreRepositoryInterface defines mitra credit score operations
+type CreditScoreRepositoryInterface interface {
+	FindByUserID(userID uuid.UUID) (*models.CreditScore, error)
+	FindByUserIDForUpdate(userID uuid.UUID) (*models.CreditScore, error)
+	Update(cs *models.CreditScore) error
+	CreateHistory(h *models.CreditScoreHistory) (bool, error)
+	FindHistory(userID uuid.UUID, limit int) ([]models.CreditScoreHistory, error)
+	Percentile(score int) (float64, error)
+}
+
+// BuyerRepositoryInterface defines buyer registry operations
+type BuyerRepositoryInterface interface {
+	FindByID(id uuid.UUID) (*models.Buyer, error)
+	FindByName(name, country string) (*models.Buyer, error)
+	Create(b *models.Buyer) (bool, error)
+	FindAll(filter *models.BuyerFilter) ([]models.Buyer, int, error)
+	FindActive(country string) ([]models.Buyer, error)
+	CreateAlias(a *models.BuyerAlias) (bool, error)
+	FindAliases(buyerID uuid.UUID) ([]models.BuyerAlias, error)
+	AddContact(buyerID uuid.UUID, email string) error
+	FindContacts(buyerID uuid.UUID) ([]models.BuyerContact, error)
+	PaymentHistory(buyerID uuid.UUID) (*models.BuyerPaymentHistory, error)
+	CountRepaidWithExporter(buyerID, exporterID uuid.UUID) (int, error)
+	Merge(sourceID, targetID uuid.UUID) error
+}
+
+// UnitOfWorkInterface runs repository calls in one database transaction
+type UnitOfWorkInterface interface {
+	Do(fn func(repos *Repositories) error) error
+}
+
 // Ensure implementations satisfy interfaces
 var _ UserRepositoryInterface = (*UserRepository)(nil)
 var _ KYCRepositoryInterface = (*KYCRepository)(nil)
@@ -138,3 +336,19 @@ var _ InvoiceRepositoryInterface = (*InvoiceRepository)(nil)
 var _ FundingRepositoryInterface = (*FundingRepository)(nil)
 var _ TransactionRepositoryInterface = (*TransactionRepository)(nil)
 var _ RiskQuestionnaireRepositoryInterface = (*RiskQuestionnaireRepository)(nil)
+var _ LedgerRepositoryInterface = (*LedgerRepository)(nil)
+var _ IdempotencyRepositoryInterface = (*IdempotencyRepository)(nil)
+var _ VirtualAccountRepositoryInterface = (*VirtualAccountRepository)(nil)
+var _ PaymentGatewayRepositoryInterface = (*PaymentGatewayRepository)(nil)
+var _ OnchainOutboxRepositoryInterface = (*OnchainOutboxRepository)(nil)
+var _ OnchainEventRepositoryInterface = (*OnchainEventRepository)(nil)
+var _ WalletRepositoryInterface = (*WalletRepository)(nil)
+var _ DefaultRepositoryInterface = (*DefaultRepository)(nil)
+var _ ImporterPaymentRepositoryInterface = (*ImporterPaymentRepository)(nil)
+var _ LateChargeRepositoryInterface = (*LateChargeRepository)(nil)
+var _ GradingRepositoryInterface = (*GradingRepository)(nil)
+var _ CountryTierRepositoryInterface = (*CountryTierRepository)(nil)
+var _ AnalyticsRepositoryInterface = (*AnalyticsRepository)(nil)
+var _ CreditScoreRepositoryInterface = (*CreditScoreRepository)(nil)
+var _ BuyerRepositoryInterface = (*BuyerRepository)(nil)
+var _ UnitOfWorkInterface = (*UnitOfWork)(nil)
@@ -7,10 +7,11 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/money"
 )
 
 type InvoiceRepository struct {
-	db *sql.DB
+	db DBTX
 }
 
 func NewInvoiceRepository(db *sql.DB) *InvoiceRepository {
@@ -19,8 +20,8 @@ func NewInvoiceRepository(db *sql.DB) *InvoiceRepository {
 
 func (r *InvoiceRepository) Create(invoice *models.Invoice) error {
 	query := `
-		INSERT INTO invoices (exporter_id, buyer_name, buyer_country, invoice_number, currency, amount, issue_date, due_date, description, status, advance_percentage)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		INSERT INTO invoices (exporter_id, buyer_name, buyer_country, invoice_number, currency, amount, issue_date, due_date, description, status, advance_percentage, buyer_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING id, created_at, updated_at
 	`
 	return r.db.QueryRow(
@@ -36,13 +37,14 @@ func (r *InvoiceRepository) Create(invoice *models.Invoice) error {
 		invoice.Description,
 		invoice.Status,
 		invoice.AdvancePercentage,
+		invoice.BuyerID,
 	).Scan(&invoice.ID, &invoice.CreatedAt, &invoice.UpdatedAt)
 }
 
 func (r *InvoiceRepository) FindByID(id uuid.UUID) (*models.Invoice, error) {
 	invoice := &models.Invoice{}
 	query := `
-		SELECT id, exporter_id, buyer_name, buyer_country, invoice_number, currency, amount, issue_date, due_date,
+		SELECT id, exporter_id, buyer_id, buyer_name, buyer_country, invoice_number, currency, amount, issue_date, due_date,
 		       description, status, interest_rate, advance_percentage, advance_amount, document_hash,
 		       created_at, updated_at
 		FROM invoices
@@ -51,6 +53,7 @@ func (r *InvoiceRepository) FindByID(id uuid.UUID) (*models.Invoice, error) {
 	err := r.db.QueryRow(query, id).Scan(
 		&invoice.ID,
 		&invoice.ExporterID,
+		&invoice.BuyerID,
 		&invoice.BuyerName,
 		&invoice.BuyerCountry,
 		&invoice.InvoiceNumber,
@@ -103,7 +106,7 @@ func (r *InvoiceRepository) FindByExporter(exporterID uuid.UUID, filter *models.
 
 	offset := (filter.Page - 1) * filter.PerPage
 	query := `
-		SELECT id, exporter_id, buyer_name, buyer_country, invoice_number, currency, amount, issue_date, due_date,
+		SELECT id, exporter_id, buyer_id, buyer_name, buyer_country, invoice_number, currency, amount, issue_date, due_date,
 		       description, status, interest_rate, advance_percentage, advance_amount, document_hash,
 		       created_at, updated_at
 		FROM invoices
@@ -129,6 +132,7 @@ func (r *InvoiceRepository) FindByExporter(exporterID uuid.UUID, filter *models.
 		if err := rows.Scan(
 			&invoice.ID,
 			&invoice.ExporterID,
+			&invoice.BuyerID,
 			&invoice.BuyerName,
 			&invoice.BuyerCountry,
 			&invoice.InvoiceNumber,
@@ -161,7 +165,7 @@ func (r *InvoiceRepository) FindFundable(page, perPage int) ([]models.Invoice, i
 
 	offset := (page - 1) * perPage
 	query := `
-		SELECT id, exporter_id, buyer_name, buyer_country, invoice_number, currency, amount, issue_date, due_date,
+		SELECT id, exporter_id, buyer_id, buyer_name, buyer_country, invoice_number, currency, amount, issue_date, due_date,
 		       description, status, interest_rate, advance_percentage, advance_amount, document_hash,
 		       created_at, updated_at
 		FROM invoices
@@ -181,6 +185,7 @@ func (r *InvoiceRepository) FindFundable(page, perPage int) ([]models.Invoice, i
 		if err := rows.Scan(
 			&invoice.ID,
 			&invoice.ExporterID,
+			&invoice.BuyerID,
 			&invoice.BuyerName,
 			&invoice.BuyerCountry,
 			&invoice.InvoiceNumber,
@@ -246,7 +251,7 @@ func (r *InvoiceRepository) SetInterestRate(id uuid.UUID, rate float64) error {
 	return err
 }
 
-func (r *InvoiceRepository) SetAdvanceAmount(id uuid.UUID, amount float64) error {
+func (r *InvoiceRepository) SetAdvanceAmount(id uuid.UUID, amount money.Amount) error {
 	query := `UPDATE invoices SET advance_amount = $1, updated_at = $2 WHERE id = $3`
 	_, err := r.db.Exec(query, amount, time.Now(), id)
 	return err
@@ -323,14 +328,15 @@ func (r *InvoiceRepository) DeleteDocument(id uuid.UUID) error {
 // NFT methods
 func (r *InvoiceRepository) CreateNFT(nft *models.InvoiceNFT) error {
 	query := `
-		INSERT INTO invoice_nfts (invoice_id, token_id, contract_address, chain_id, owner_address, mint_tx_hash, metadata_uri, minted_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO invoice_nfts (invoice_id, token_id, token_verified, contract_address, chain_id, owner_address, mint_tx_hash, metadata_uri, minted_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		RETURNING id, created_at, updated_at
 	`
 	return r.db.QueryRow(
 		query,
 		nft.InvoiceID,
 		nft.TokenID,
+		nft.TokenVerified,
 		nft.ContractAddress,
 		nft.ChainID,
 		nft.OwnerAddress,
@@ -343,7 +349,7 @@ func (r *InvoiceRepository) CreateNFT(nft *models.InvoiceNFT) error {
 func (r *InvoiceRepository) FindNFTByInvoiceID(invoiceID uuid.UUID) (*models.InvoiceNFT, error) {
 	nft := &models.InvoiceNFT{}
 	query := `
-		SELECT id, invoice_id, token_id, contract_address, chain_id, owner_address, mint_tx_hash, metadata_uri,
+		SELECT id, invoice_id, token_id, token_verified, contract_address, chain_id, owner_address, mint_tx_hash, metadata_uri,
 		       minted_at, burned_at, burn_tx_hash, created_at, updated_at
 		FROM invoice_nfts
 		WHERE invoice_id = $1
@@ -352,6 +358,7 @@ func (r *InvoiceRepository) FindNFTByInvoiceID(invoiceID uuid.UUID) (*models.Inv
 		&nft.ID,
 		&nft.InvoiceID,
 		&nft.TokenID,
+		&nft.TokenVerified,
 		&nft.ContractAddress,
 		&nft.ChainID,
 		&nft.OwnerAddress,
@@ -372,12 +379,72 @@ func (r *InvoiceRepository) FindNFTByInvoiceID(invoiceID uuid.UUID) (*models.Inv
 	return nft, nil
 }
 
+// FindMintedNFTs returns every NFT that was not burned, by token id. NFTs whose
+// token id is not known yet come last.
+func (r *InvoiceRepository) FindMintedNFTs() ([]models.InvoiceNFT, error) {
+	query := `
+		SELECT id, invoice_id, token_id, token_verified, contract_address, chain_id, owner_address, mint_tx_hash, metadata_uri,
+		       minted_at, burned_at, burn_tx_hash, created_at, updated_at
+		FROM invoice_nfts
+		WHERE burned_at IS NULL
+		ORDER BY token_id NULLS LAST, created_at
+	`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var nfts []models.InvoiceNFT
+	for rows.Next() {
+		var nft models.InvoiceNFT
+		if err := rows.Scan(
+			&nft.ID,
+			&nft.InvoiceID,
+			&nft.TokenID,
+			&nft.TokenVerified,
+			&nft.ContractAddress,
+			&nft.ChainID,
+			&nft.OwnerAddress,
+			&nft.MintTxHash,
+			&nft.MetadataURI,
+			&nft.MintedAt,
+			&nft.BurnedAt,
+			&nft.BurnTxHash,
+			&nft.CreatedAt,
+			&nft.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		nfts = append(nfts, nft)
+	}
+	return nfts, rows.Err()
+}
+
 func (r *InvoiceRepository) UpdateNFTOwner(id uuid.UUID, owner string) error {
 	query := `UPDATE invoice_nfts SET owner_address = $1, updated_at = $2 WHERE id = $3`
 	_, err := r.db.Exec(query, owner, time.Now(), id)
 	return err
 }
 
+// VerifyNFTToken stores the token id minted by mintTxHash, as read from the
+// chain. It reports false when no NFT was minted by that transaction.
+func (r *InvoiceRepository) VerifyNFTToken(mintTxHash string, tokenID int64) (bool, error) {
+	query := `
+		UPDATE invoice_nfts SET token_id = $1, token_verified = TRUE, updated_at = $2
+		WHERE LOWER(mint_tx_hash) = LOWER($3)
+	`
+	result, err := r.db.Exec(query, tokenID, time.Now(), mintTxHash)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
 func (r *InvoiceRepository) BurnNFT(id uuid.UUID, txHash string) error {
 	now := time.Now()
 	query := `UPDATE invoice_nfts SET burned_at = $1, burn_tx_hash = $2, updated_at = $1 WHERE id = $3`
@@ -385,8 +452,8 @@ func (r *InvoiceRepository) BurnNFT(id uuid.UUID, txHash string) error {
 	return err
 }
 
-func (r *InvoiceRepository) ApproveWithTransaction(id uuid.UUID, interestRate, advanceAmount float64) error {
-	tx, err := r.db.Begin()
+func (r *InvoiceRepository) ApproveWithTransaction(id uuid.UUID, interestRate float64, advanceAmount money.Amount) error {
+	tx, err := begin(r.db)
 	if err != nil {
 		return err
 	}
@@ -450,6 +517,11 @@ func (r *InvoiceRepository) FindAll(filter *models.InvoiceFilter) ([]models.Invo
 		countQuery += ` AND exporter_id = $` + string(rune('0'+argCount))
 		args = append(args, *filter.ExporterID)
 	}
+	if filter.BuyerID != nil {
+		argCount++
+		countQuery += ` AND buyer_id = $` + string(rune('0'+argCount))
+		args = append(args, *filter.BuyerID)
+	}
 
 	if err := r.db.QueryRow(countQuery, args...).Scan(&total); err != nil {
 		return nil, 0, err
@@ -457,7 +529,7 @@ func (r *InvoiceRepository) FindAll(filter *models.InvoiceFilter) ([]models.Invo
 
 	offset := (filter.Page - 1) * filter.PerPage
 	query := `
-		SELECT id, exporter_id, buyer_name, buyer_country, invoice_number, currency, amount, issue_date, due_date,
+		SELECT id, exporter_id, buyer_id, buyer_name, buyer_country, invoice_number, currency, amount, issue_date, due_date,
 		       description, status, interest_rate, advance_percentage, advance_amount, document_hash,
 		       created_at, updated_at
 		FROM invoices
@@ -489,6 +561,11 @@ func (r *InvoiceRepository) FindAll(filter *models.InvoiceFilter) ([]models.Invo
 		query += ` AND exporter_id = $` + string(rune('0'+queryArgCount))
 		queryArgs = append(queryArgs, *filter.ExporterID)
 	}
+	if filter.BuyerID != nil {
+		queryArgCount++
+		query += ` AND buyer_id = $` + string(rune('0'+queryArgCount))
+		queryArgs = append(queryArgs, *filter.BuyerID)
+	}
 
 	queryArgCount++
 	query += ` ORDER BY created_at DESC LIMIT $` + string(rune('0'+queryArgCount))
@@ -510,6 +587,7 @@ func (r *InvoiceRepository) FindAll(filter *models.InvoiceFilter) ([]models.Invo
 		if err := rows.Scan(
 			&invoice.ID,
 			&invoice.ExporterID,
+			&invoice.BuyerID,
 			&invoice.BuyerName,
 			&invoice.BuyerCountry,
 			&invoice.InvoiceNumber,
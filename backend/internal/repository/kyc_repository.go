@@ -10,7 +10,7 @@ import (
 )
 
 type KYCRepository struct {
-	db *sql.DB
+	db DBTX
 }
 
 func NewKYCRepository(db *sql.DB) *KYCRepository {
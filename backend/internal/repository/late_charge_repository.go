@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/money"
+)
+
+type LateChargeRepository struct {
+	db DBTX
+}
+
+func NewLateChargeRepository(db *sql.DB) *LateChargeRepository {
+	return &LateChargeRepository{db: db}
+}
+
+const lateChargeColumns = `
+	id, pool_id, invoice_id, grade, currency, due_date, late_fee_percent, penalty_rate,
+	late_fee, penalty_interest, paid, accrued_through, accrual_balance, accrual_start, created_at, updated_at
+`
+
+func scanLateCharge(row interface{ Scan(...interface{}) error }) (*models.LateCharge, error) {
+	c := &models.LateCharge{}
+	err := row.Scan(
+		&c.ID,
+		&c.PoolID,
+		&c.InvoiceID,
+		&c.Grade,
+		&c.Currency,
+		&c.DueDate,
+		&c.LateFeePercent,
+		&c.PenaltyRate,
+		&c.LateFee,
+		&c.PenaltyInterest,
+		&c.Paid,
+		&c.AccruedThrough,
+		&c.AccrualBalance,
+		&c.AccrualStart,
+		&c.CreatedAt,
+		&c.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (r *LateChargeRepository) FindByPoolID(poolID uuid.UUID) (*models.LateCharge, error) {
+	c, err := scanLateCharge(r.db.QueryRow(`SELECT `+lateChargeColumns+` FROM late_charges WHERE pool_id = $1`, poolID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return c, err
+}
+
+// FindByPoolIDForUpdate locks the pool's late charges; use inside a unit of work
+func (r *LateChargeRepository) FindByPoolIDForUpdate(poolID uuid.UUID) (*models.LateCharge, error) {
+	c, err := scanLateCharge(r.db.QueryRow(`SELECT `+lateChargeColumns+` FROM late_charges WHERE pool_id = $1 FOR UPDATE`, poolID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return c, err
+}
+
+// FindOverduePoolIDs lists disbursed pools whose invoice is past its due date
+func (r *LateChargeRepository) FindOverduePoolIDs(now time.Time) ([]uuid.UUID, error) {
+	query := `
+		SELECT fp.id
+		FROM funding_pools fp
+		JOIN invoices i ON i.id = fp.invoice_id
+		WHERE fp.status = 'disbursed' AND i.due_date < $1
+		ORDER BY i.due_date ASC
+	`
+	rows, err := r.db.Query(query, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Save inserts the pool's late charges or stores what has accrued since
+func (r *LateChargeRepository) Save(c *models.LateCharge) error {
+	query := `
+		INSERT INTO late_charges (pool_id, invoice_id, grade, currency, due_date, late_fee_percent, penalty_rate,
+			late_fee, penalty_interest, paid, accrued_through, accrual_balance, accrual_start)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (pool_id) DO UPDATE SET
+			late_fee = EXCLUDED.late_fee,
+			penalty_interest = EXCLUDED.penalty_interest,
+			accrued_through = EXCLUDED.accrued_through,
+			accrual_balance = EXCLUDED.accrual_balance,
+			accrual_start = EXCLUDED.accrual_start,
+			updated_at = NOW()
+		RETURNING id, created_at, updated_at
+	`
+	return r.db.QueryRow(
+		query,
+		c.PoolID,
+		c.InvoiceID,
+		c.Grade,
+		c.Currency,
+		c.DueDate,
+		c.LateFeePercent,
+		c.PenaltyRate,
+		c.LateFee,
+		c.PenaltyInterest,
+		c.Paid,
+		c.AccruedThrough,
+		c.AccrualBalance,
+		c.AccrualStart,
+	).Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt)
+}
+
+// AddPaid records late charges collected from a repayment
+func (r *LateChargeRepository) AddPaid(id uuid.UUID, amount money.Amount) error {
+	_, err := r.db.Exec(`UPDATE late_charges SET paid = paid + $1, updated_at = NOW() WHERE id = $2`, amount, id)
+	return err
+}
@@ -0,0 +1,305 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/money"
+)
+
+var (
+	ErrUnbalancedEntry     = errors.New("journal entry is not balanced")
+	ErrInsufficientBalance = errors.New("insufficient balance")
+)
+
+type LedgerRepository struct {
+	db DBTX
+}
+
+func NewLedgerRepository(db *sql.DB) *LedgerRepository {
+	return &LedgerRepository{db: db}
+}
+
+// PostEntry writes a balanced journal entry in a single database transaction
+// (a savepoint when the repository belongs to a unit of work).
+// Accounts are created on first use. Accounts whose balance is checked (those
+// that may not go negative, and user wallets) are locked in code order so
+// concurrent postings serialise per account; platform accounts such as escrow
+// are not locked and their balances are not recomputed. Checked accounts are
+// verified after the lines are written, and the cached users.balance_idr is
+// refreshed from the ledger for user wallets.
+func (r *LedgerRepository) PostEntry(entry *models.JournalEntry) error {
+	var lines []models.JournalLine
+	var sum money.Amount
+	for _, line := range entry.Lines {
+		if line.Amount == 0 {
+			continue
+		}
+		if line.Account == nil {
+			return errors.New("journal line has no account")
+		}
+		lines = append(lines, line)
+		sum += line.Amount
+	}
+	if len(lines) < 2 || sum != 0 {
+		return ErrUnbalancedEntry
+	}
+
+	accounts := make(map[string]*models.LedgerAccount)
+	var codes []string
+	for _, line := range lines {
+		if _, ok := accounts[line.Account.Code]; !ok {
+			accounts[line.Account.Code] = line.Account
+			codes = append(codes, line.Account.Code)
+		}
+	}
+	sort.Strings(codes)
+
+	tx, err := begin(r.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock owning user rows before ledger accounts so every flow takes locks
+	// in the same order (pool -> users -> ledger accounts) and cannot deadlock
+	for _, code := range codes {
+		account := accounts[code]
+		if isUserAccount(account) {
+			if _, err := tx.Exec(`SELECT id FROM users WHERE id = $1 FOR UPDATE`, *account.OwnerID); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, code := range codes {
+		if err := r.lockAccount(tx, accounts[code]); err != nil {
+			return err
+		}
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO journal_entries (reference_type, reference_id, description)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`, entry.ReferenceType, entry.ReferenceID, entry.Description).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	for i := range lines {
+		lines[i].Account = accounts[lines[i].Account.Code]
+		lines[i].EntryID = entry.ID
+		lines[i].AccountID = lines[i].Account.ID
+		err := tx.QueryRow(`
+			INSERT INTO journal_lines (entry_id, account_id, amount)
+			VALUES ($1, $2, $3)
+			RETURNING id
+		`, entry.ID, lines[i].AccountID, lines[i].Amount).Scan(&lines[i].ID)
+		if err != nil {
+			return err
+		}
+	}
+	entry.Lines = lines
+
+	for _, code := range codes {
+		account := accounts[code]
+		if !balanceChecked(account) {
+			continue
+		}
+		balance, err := accountBalance(tx, account)
+		if err != nil {
+			return err
+		}
+		if !account.AllowNegative && balance < 0 {
+			return ErrInsufficientBalance
+		}
+		if isUserAccount(account) {
+			query := `UPDATE users SET balance_idr = $1, updated_at = $2 WHERE id = $3`
+			if _, err := tx.Exec(query, balance, time.Now(), *account.OwnerID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// isUserAccount reports whether the account is a user wallet
+func isUserAccount(account *models.LedgerAccount) bool {
+	return account.OwnerType != nil && *account.OwnerType == "user" && account.OwnerID != nil
+}
+
+// balanceChecked reports whether postings must lock the account and verify its
+// balance: accounts that may not go negative, and user wallets whose cached
+// balance is refreshed
+func balanceChecked(account *models.LedgerAccount) bool {
+	return !account.AllowNegative || isUserAccount(account)
+}
+
+// lockAccount creates the account if needed, then takes a row lock on it when
+// its balance is checked. Other accounts are only looked up, so postings to
+// shared platform accounts such as escrow do not serialise on one row.
+func (r *LedgerRepository) lockAccount(tx DBTX, account *models.LedgerAccount) error {
+	_, err := tx.Exec(`
+		INSERT INTO ledger_accounts (code, name, account_type, owner_type, owner_id, currency, allow_negative)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (code) DO NOTHING
+	`, account.Code, account.Name, account.Type, account.OwnerType, account.OwnerID, account.Currency, account.AllowNegative)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		SELECT id, account_type, allow_negative, created_at
+		FROM ledger_accounts
+		WHERE code = $1
+	`
+	if balanceChecked(account) {
+		query += ` FOR UPDATE`
+	}
+	return tx.QueryRow(query, account.Code).Scan(&account.ID, &account.Type, &account.AllowNegative, &account.CreatedAt)
+}
+
+// accountBalance returns the account balance on its normal side
+func accountBalance(tx DBTX, account *models.LedgerAccount) (money.Amount, error) {
+	var sum money.Amount
+	query := `SELECT COALESCE(SUM(amount), 0) FROM journal_lines WHERE account_id = $1`
+	if err := tx.QueryRow(query, account.ID).Scan(&sum); err != nil {
+		return 0, err
+	}
+	if account.Type.DebitNormal() {
+		return sum, nil
+	}
+	return -sum, nil
+}
+
+// GetAccountBalance returns the derived balance of an account, or 0 if it has never been used
+func (r *LedgerRepository) GetAccountBalance(code string) (money.Amount, error) {
+	var accountType models.LedgerAccountType
+	var sum money.Amount
+	query := `
+		SELECT a.account_type, COALESCE(SUM(l.amount), 0)
+		FROM ledger_accounts a
+		LEFT JOIN journal_lines l ON l.account_id = a.id
+		WHERE a.code = $1
+		GROUP BY a.id
+	`
+	err := r.db.QueryRow(query, code).Scan(&accountType, &sum)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if accountType.DebitNormal() {
+		return sum, nil
+	}
+	return -sum, nil
+}
+
+// GetTrialBalance returns per-account debit/credit totals plus integrity checks
+func (r *LedgerRepository) GetTrialBalance() (*models.TrialBalance, error) {
+	query := `
+		SELECT a.id, a.code, a.name, a.account_type,
+		       COALESCE(SUM(CASE WHEN l.amount > 0 THEN l.amount ELSE 0 END), 0),
+		       COALESCE(SUM(CASE WHEN l.amount < 0 THEN -l.amount ELSE 0 END), 0)
+		FROM ledger_accounts a
+		LEFT JOIN journal_lines l ON l.account_id = a.id
+		GROUP BY a.id
+		ORDER BY a.code
+	`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tb := &models.TrialBalance{Accounts: []models.TrialBalanceLine{}}
+	for rows.Next() {
+		var line models.TrialBalanceLine
+		if err := rows.Scan(&line.AccountID, &line.Code, &line.Name, &line.Type, &line.TotalDebit, &line.TotalCredit); err != nil {
+			return nil, err
+		}
+		if line.Type.DebitNormal() {
+			line.Balance = line.TotalDebit - line.TotalCredit
+		} else {
+			line.Balance = line.TotalCredit - line.TotalDebit
+		}
+		tb.TotalDebit += line.TotalDebit
+		tb.TotalCredit += line.TotalCredit
+		tb.Accounts = append(tb.Accounts, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	unbalancedQuery := `
+		SELECT COUNT(*) FROM (
+			SELECT entry_id FROM journal_lines GROUP BY entry_id HAVING SUM(amount) <> 0
+		) unbalanced
+	`
+	if err := r.db.QueryRow(unbalancedQuery).Scan(&tb.UnbalancedEntries); err != nil {
+		return nil, err
+	}
+
+	mismatchQuery := `
+		SELECT COUNT(*)
+		FROM users u
+		LEFT JOIN ledger_accounts a ON a.owner_type = 'user' AND a.owner_id = u.id
+		LEFT JOIN (
+			SELECT account_id, SUM(amount) AS total FROM journal_lines GROUP BY account_id
+		) b ON b.account_id = a.id
+		WHERE COALESCE(u.balance_idr, 0) <> -COALESCE(b.total, 0)
+	`
+	if err := r.db.QueryRow(mismatchQuery).Scan(&tb.BalanceMismatches); err != nil {
+		return nil, err
+	}
+
+	tb.Balanced = tb.TotalDebit == tb.TotalCredit && tb.UnbalancedEntries == 0 && tb.BalanceMismatches == 0
+	tb.GeneratedAt = time.Now()
+	return tb, nil
+}
+
+// FindEntriesByReference returns journal entries posted for a business reference
+func (r *LedgerRepository) FindEntriesByReference(referenceType string, referenceID uuid.UUID) ([]models.JournalEntry, error) {
+	query := `
+		SELECT e.id, e.reference_type, e.reference_id, COALESCE(e.description, ''), e.created_at,
+		       l.id, l.account_id, l.amount, a.code, a.name, a.account_type
+		FROM journal_entries e
+		JOIN journal_lines l ON l.entry_id = e.id
+		JOIN ledger_accounts a ON a.id = l.account_id
+		WHERE e.reference_type = $1 AND e.reference_id = $2
+		ORDER BY e.created_at, e.id, l.amount DESC
+	`
+	rows, err := r.db.Query(query, referenceType, referenceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.JournalEntry
+	for rows.Next() {
+		var entry models.JournalEntry
+		line := models.JournalLine{Account: &models.LedgerAccount{}}
+		if err := rows.Scan(
+			&entry.ID, &entry.ReferenceType, &entry.ReferenceID, &entry.Description, &entry.CreatedAt,
+			&line.ID, &line.AccountID, &line.Amount, &line.Account.Code, &line.Account.Name, &line.Account.Type,
+		); err != nil {
+			return nil, err
+		}
+		line.EntryID = entry.ID
+		line.Account.ID = line.AccountID
+		if n := len(entries); n > 0 && entries[n-1].ID == entry.ID {
+			entries[n-1].Lines = append(entries[n-1].Lines, line)
+			continue
+		}
+		entry.Lines = []models.JournalLine{line}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
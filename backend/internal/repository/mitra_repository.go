@@ -11,7 +11,7 @@ import (
 )
 
 type MitraRepository struct {
-	db *sql.DB
+	db DBTX
 }
 
 func NewMitraRepository(db *sql.DB) *MitraRepository {
@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/vessel/backend/internal/models"
+)
+
+type OnchainEventRepository struct {
+	db DBTX
+}
+
+func NewOnchainEventRepository(db *sql.DB) *OnchainEventRepository {
+	return &OnchainEventRepository{db: db}
+}
+
+const onchainEventColumns = `
+	id, chain_id, contract, contract_address, event_name, token_id::text, block_number,
+	block_hash, tx_hash, log_index, args, created_at
+`
+
+func scanOnchainEvent(row interface{ Scan(...interface{}) error }) (*models.OnchainEvent, error) {
+	e := &models.OnchainEvent{}
+	var args []byte
+	err := row.Scan(
+		&e.ID,
+		&e.ChainID,
+		&e.Contract,
+		&e.ContractAddress,
+		&e.EventName,
+		&e.TokenID,
+		&e.BlockNumber,
+		&e.BlockHash,
+		&e.TxHash,
+		&e.LogIndex,
+		&args,
+		&e.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	e.Args = args
+	return e, nil
+}
+
+// GetCursor returns the last block the named indexer processed on chainID.
+// found is false before the first batch.
+func (r *OnchainEventRepository) GetCursor(name string, chainID int64) (lastBlock int64, found bool, err error) {
+	query := `SELECT last_block FROM onchain_indexer_cursors WHERE name = $1 AND chain_id = $2`
+	err = r.db.QueryRow(query, name, chainID).Scan(&lastBlock)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return lastBlock, true, nil
+}
+
+// FindIndexedBlocks returns the indexer's remembered block hashes, newest first
+func (r *OnchainEventRepository) FindIndexedBlocks(name string, limit int) ([]models.IndexedBlock, error) {
+	query := `
+		SELECT block_number, block_hash FROM onchain_indexed_blocks
+		WHERE name = $1
+		ORDER BY block_number DESC
+		LIMIT $2
+	`
+	rows, err := r.db.Query(query, name, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var blocks []models.IndexedBlock
+	for rows.Next() {
+		var b models.IndexedBlock
+		if err := rows.Scan(&b.Number, &b.Hash); err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, b)
+	}
+	return blocks, rows.Err()
+}
+
+// SaveBatch stores the events of a block range, remembers its block hashes and
+// moves the cursor to lastBlock in one transaction. Hashes of blocks below
+// pruneBelow are dropped.
+func (r *OnchainEventRepository) SaveBatch(name string, chainID int64, events []models.OnchainEvent, blocks []models.IndexedBlock, lastBlock, pruneBelow int64) error {
+	tx, err := begin(r.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	insertEvent := `
+		INSERT INTO onchain_events (
+			chain_id, contract, contract_address, event_name, token_id, block_number,
+			block_hash, tx_hash, log_index, args
+		)
+		VALUES ($1, $2, $3, $4, $5::numeric, $6, $7, $8, $9, $10)
+		ON CONFLICT (block_hash, log_index) DO NOTHING
+	`
+	for _, e := range events {
+		if _, err := tx.Exec(
+			insertEvent,
+			e.ChainID,
+			e.Contract,
+			e.ContractAddress,
+			e.EventName,
+			e.TokenID,
+			e.BlockNumber,
+			e.BlockHash,
+			e.TxHash,
+			e.LogIndex,
+			[]byte(e.Args),
+		); err != nil {
+			return err
+		}
+	}
+
+	upsertBlock := `
+		INSERT INTO onchain_indexed_blocks (name, block_number, block_hash)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (name, block_number) DO UPDATE SET block_hash = EXCLUDED.block_hash
+	`
+	for _, b := range blocks {
+		if _, err := tx.Exec(upsertBlock, name, b.Number, b.Hash); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM onchain_indexed_blocks WHERE name = $1 AND block_number < $2`, name, pruneBelow); err != nil {
+		return err
+	}
+
+	if err := upsertCursor(tx, name, chainID, lastBlock); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Rewind forgets everything the indexer stored above ancestor, the last block
+// still on the canonical chain after a reorg
+func (r *OnchainEventRepository) Rewind(name string, chainID int64, ancestor int64) (int64, error) {
+	tx, err := begin(r.db)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM onchain_events WHERE chain_id = $1 AND block_number > $2`, chainID, ancestor)
+	if err != nil {
+		return 0, err
+	}
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`DELETE FROM onchain_indexed_blocks WHERE name = $1 AND block_number > $2`, name, ancestor); err != nil {
+		return 0, err
+	}
+	if err := upsertCursor(tx, name, chainID, ancestor); err != nil {
+		return 0, err
+	}
+	return removed, tx.Commit()
+}
+
+func upsertCursor(tx Tx, name string, chainID, lastBlock int64) error {
+	query := `
+		INSERT INTO onchain_indexer_cursors (name, chain_id, last_block, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (name) DO UPDATE SET chain_id = EXCLUDED.chain_id, last_block = EXCLUDED.last_block, updated_at = EXCLUDED.updated_at
+	`
+	_, err := tx.Exec(query, name, chainID, lastBlock, time.Now())
+	return err
+}
+
+// FindByTokenID returns the events of one invoice token in chain order
+func (r *OnchainEventRepository) FindByTokenID(chainID int64, tokenID int64) ([]models.OnchainEvent, error) {
+	query := `
+		SELECT ` + onchainEventColumns + ` FROM onchain_events
+		WHERE chain_id = $1 AND token_id = $2
+		ORDER BY block_number, log_index
+	`
+	rows, err := r.db.Query(query, chainID, tokenID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.OnchainEvent
+	for rows.Next() {
+		e, err := scanOnchainEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *e)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,350 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/vessel/backend/internal/models"
+)
+
+type OnchainOutboxRepository struct {
+	db DBTX
+}
+
+func NewOnchainOutboxRepository(db *sql.DB) *OnchainOutboxRepository {
+	return &OnchainOutboxRepository{db: db}
+}
+
+const onchainOutboxColumns = `
+	id, seq, action, invoice_id, pool_id, transaction_ids, payload, status, attempts, next_attempt_at,
+	locked_until, last_error, tx_hash, nonce, gas_price, gas_limit, previous_tx_hashes,
+	block_number, gas_used, submitted_at, confirmed_at, created_at, updated_at
+`
+
+func scanOnchainOutboxEntry(row interface{ Scan(...interface{}) error }) (*models.OnchainOutboxEntry, error) {
+	e := &models.OnchainOutboxEntry{}
+	var payload []byte
+	var transactionIDs []string
+	err := row.Scan(
+		&e.ID,
+		&e.Seq,
+		&e.Action,
+		&e.InvoiceID,
+		&e.PoolID,
+		pq.Array(&transactionIDs),
+		&payload,
+		&e.Status,
+		&e.Attempts,
+		&e.NextAttemptAt,
+		&e.LockedUntil,
+		&e.LastError,
+		&e.TxHash,
+		&e.Nonce,
+		&e.GasPrice,
+		&e.GasLimit,
+		pq.Array(&e.PreviousTxHashes),
+		&e.BlockNumber,
+		&e.GasUsed,
+		&e.SubmittedAt,
+		&e.ConfirmedAt,
+		&e.CreatedAt,
+		&e.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	e.Payload = payload
+	e.TransactionIDs = make([]uuid.UUID, 0, len(transactionIDs))
+	for _, id := range transactionIDs {
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			return nil, err
+		}
+		e.TransactionIDs = append(e.TransactionIDs, parsed)
+	}
+	if e.PreviousTxHashes == nil {
+		e.PreviousTxHashes = []string{}
+	}
+	return e, nil
+}
+
+// Enqueue stores a pending entry. Call it through a unit of work so the entry
+// commits or rolls back together with the business change it records.
+func (r *OnchainOutboxRepository) Enqueue(e *models.OnchainOutboxEntry) error {
+	if len(e.Payload) == 0 {
+		e.Payload = []byte("{}")
+	}
+	transactionIDs := make([]string, len(e.TransactionIDs))
+	for i, id := range e.TransactionIDs {
+		transactionIDs[i] = id.String()
+	}
+	query := `
+		INSERT INTO onchain_outbox (action, invoice_id, pool_id, transaction_ids, payload)
+		VALUES ($1, $2, $3, $4::uuid[], $5)
+		RETURNING id, seq, status, attempts, next_attempt_at, created_at, updated_at
+	`
+	return r.db.QueryRow(query, e.Action, e.InvoiceID, e.PoolID, pq.Array(transactionIDs), []byte(e.Payload)).Scan(
+		&e.ID, &e.Seq, &e.Status, &e.Attempts, &e.NextAttemptAt, &e.CreatedAt, &e.UpdatedAt,
+	)
+}
+
+// ClaimDue leases up to limit due entries to the caller and counts the attempt.
+// Only the oldest unsent entry of each invoice is eligible, so writes for one
+// invoice reach the chain in the order they were committed. Entries whose lease
+// ran out (the worker died mid-send) become due again.
+func (r *OnchainOutboxRepository) ClaimDue(limit int, lease time.Duration) ([]models.OnchainOutboxEntry, error) {
+	tx, err := begin(r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	query := `
+		SELECT ` + onchainOutboxColumns + ` FROM onchain_outbox o
+		WHERE ((o.status = 'pending' AND o.next_attempt_at <= $1)
+			OR (o.status = 'processing' AND o.locked_until < $1))
+		AND NOT EXISTS (
+			SELECT 1 FROM onchain_outbox p
+			WHERE p.invoice_id = o.invoice_id AND p.seq < o.seq AND p.status NOT IN ('submitted', 'confirmed', 'cancelled')
+		)
+		ORDER BY o.seq
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`
+	rows, err := tx.Query(query, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	var entries []models.OnchainOutboxEntry
+	for rows.Next() {
+		e, err := scanOnchainOutboxEntry(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		entries = append(entries, *e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	lockedUntil := now.Add(lease)
+	for i := range entries {
+		_, err := tx.Exec(
+			`UPDATE onchain_outbox SET status = 'processing', attempts = attempts + 1, locked_until = $1, updated_at = $2 WHERE id = $3`,
+			lockedUntil, now, entries[i].ID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entries[i].Status = models.OutboxStatusProcessing
+		entries[i].Attempts++
+		entries[i].LockedUntil = &lockedUntil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// MarkSubmitted records a broadcast transaction for a claimed entry
+func (r *OnchainOutboxRepository) MarkSubmitted(id uuid.UUID, sub *models.OnchainSubmission) error {
+	query := `
+		UPDATE onchain_outbox
+		SET status = 'submitted', tx_hash = $1, nonce = $2, gas_price = $3, gas_limit = $4,
+		    submitted_at = $5, locked_until = NULL, last_error = NULL, updated_at = $5
+		WHERE id = $6
+	`
+	_, err := r.db.Exec(query, sub.TxHash, sub.Nonce, sub.GasPrice, sub.GasLimit, time.Now(), id)
+	return err
+}
+
+// MarkReplaced records a replacement of the entry's unmined transaction. The old
+// hash is kept, since either transaction may still be the one that gets mined.
+func (r *OnchainOutboxRepository) MarkReplaced(id uuid.UUID, sub *models.OnchainSubmission) error {
+	query := `
+		UPDATE onchain_outbox
+		SET previous_tx_hashes = array_append(previous_tx_hashes, tx_hash::text),
+		    tx_hash = $1, gas_price = $2, gas_limit = $3, submitted_at = $4, updated_at = $4
+		WHERE id = $5 AND status = 'submitted'
+	`
+	_, err := r.db.Exec(query, sub.TxHash, sub.GasPrice, sub.GasLimit, time.Now(), id)
+	return err
+}
+
+// MarkConfirmed records the mined transaction of a submitted entry. Block info is
+// nil when the chain is simulated.
+func (r *OnchainOutboxRepository) MarkConfirmed(id uuid.UUID, txHash string, blockNumber, gasUsed *int64) error {
+	now := time.Now()
+	query := `
+		UPDATE onchain_outbox
+		SET status = 'confirmed', tx_hash = $1, block_number = $2, gas_used = $3, confirmed_at = $4, updated_at = $4
+		WHERE id = $5 AND status = 'submitted'
+	`
+	_, err := r.db.Exec(query, txHash, blockNumber, gasUsed, now, id)
+	return err
+}
+
+// FindSubmitted returns entries waiting for a receipt, oldest first
+func (r *OnchainOutboxRepository) FindSubmitted(limit int) ([]models.OnchainOutboxEntry, error) {
+	query := `SELECT ` + onchainOutboxColumns + ` FROM onchain_outbox WHERE status = 'submitted' ORDER BY seq LIMIT $1`
+	rows, err := r.db.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.OnchainOutboxEntry
+	for rows.Next() {
+		e, err := scanOnchainOutboxEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *e)
+	}
+	return entries, rows.Err()
+}
+
+// MarkRetry returns a claimed entry to pending until nextAttempt
+func (r *OnchainOutboxRepository) MarkRetry(id uuid.UUID, lastError string, nextAttempt time.Time) error {
+	query := `
+		UPDATE onchain_outbox
+		SET status = 'pending', last_error = $1, next_attempt_at = $2, locked_until = NULL, updated_at = $3
+		WHERE id = $4
+	`
+	_, err := r.db.Exec(query, lastError, nextAttempt, time.Now(), id)
+	return err
+}
+
+// MarkFailed parks an entry that ran out of attempts until an admin retries it
+func (r *OnchainOutboxRepository) MarkFailed(id uuid.UUID, lastError string) error {
+	query := `
+		UPDATE onchain_outbox
+		SET status = 'failed', last_error = $1, locked_until = NULL, updated_at = $2
+		WHERE id = $3
+	`
+	_, err := r.db.Exec(query, lastError, time.Now(), id)
+	return err
+}
+
+// Requeue makes a failed entry due now with a fresh attempt budget. A reverted
+// transaction is forgotten so the entry is sent again under a new nonce. It
+// reports false when the entry is not in the failed state.
+func (r *OnchainOutboxRepository) Requeue(id uuid.UUID) (bool, error) {
+	now := time.Now()
+	query := `
+		UPDATE onchain_outbox
+		SET status = 'pending', attempts = 0, next_attempt_at = $1, updated_at = $1,
+		    tx_hash = NULL, nonce = NULL, gas_price = NULL, gas_limit = NULL, previous_tx_hashes = '{}',
+		    block_number = NULL, gas_used = NULL, submitted_at = NULL
+		WHERE id = $2 AND status = 'failed'
+	`
+	result, err := r.db.Exec(query, now, id)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// Cancel gives up on a failed entry so later entries of the same invoice are
+// submitted again. The reason is kept as the entry's last error. It reports
+// false when the entry is not in the failed state.
+func (r *OnchainOutboxRepository) Cancel(id uuid.UUID, reason string) (bool, error) {
+	query := `
+		UPDATE onchain_outbox
+		SET status = 'cancelled', last_error = $1, locked_until = NULL, updated_at = $2
+		WHERE id = $3 AND status = 'failed'
+	`
+	result, err := r.db.Exec(query, reason, time.Now(), id)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+func (r *OnchainOutboxRepository) FindByID(id uuid.UUID) (*models.OnchainOutboxEntry, error) {
+	query := `SELECT ` + onchainOutboxColumns + ` FROM onchain_outbox WHERE id = $1`
+	e, err := scanOnchainOutboxEntry(r.db.QueryRow(query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return e, err
+}
+
+// List returns entries by status, oldest first. The "stuck" status selects
+// unconfirmed entries older than stuckAfter, including failed but not
+// cancelled ones.
+func (r *OnchainOutboxRepository) List(status string, stuckAfter time.Time, page, perPage int) ([]models.OnchainOutboxEntry, int, error) {
+	where := ` WHERE status = $1`
+	arg := interface{}(status)
+	if status == "stuck" {
+		where = ` WHERE status NOT IN ('confirmed', 'cancelled') AND created_at < $1`
+		arg = stuckAfter
+	}
+
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM onchain_outbox`+where, arg).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * perPage
+	query := `SELECT ` + onchainOutboxColumns + ` FROM onchain_outbox` + where + ` ORDER BY seq LIMIT $2 OFFSET $3`
+	rows, err := r.db.Query(query, arg, perPage, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var entries []models.OnchainOutboxEntry
+	for rows.Next() {
+		e, err := scanOnchainOutboxEntry(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, *e)
+	}
+	return entries, total, rows.Err()
+}
+
+// CountOpenByInvoice returns how many of an invoice's entries have not been
+// confirmed yet, failed ones included. Cancelled entries are not open.
+func (r *OnchainOutboxRepository) CountOpenByInvoice(invoiceID uuid.UUID) (int, error) {
+	var n int
+	query := `SELECT COUNT(*) FROM onchain_outbox WHERE invoice_id = $1 AND status NOT IN ($2, $3)`
+	err := r.db.QueryRow(query, invoiceID, models.OutboxStatusConfirmed, models.OutboxStatusCancelled).Scan(&n)
+	return n, err
+}
+
+// CountByStatus returns the number of entries in each status
+func (r *OnchainOutboxRepository) CountByStatus() (map[string]int, error) {
+	rows, err := r.db.Query(`SELECT status, COUNT(*) FROM onchain_outbox GROUP BY status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		counts[status] = n
+	}
+	return counts, rows.Err()
+}
@@ -10,7 +10,7 @@ import (
 )
 
 type OTPRepository struct {
-	db *sql.DB
+	db DBTX
 }
 
 func NewOTPRepository(db *sql.DB) *OTPRepository {
@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/models"
+)
+
+type PaymentGatewayRepository struct {
+	db DBTX
+}
+
+func NewPaymentGatewayRepository(db *sql.DB) *PaymentGatewayRepository {
+	return &PaymentGatewayRepository{db: db}
+}
+
+const gatewayPaymentColumns = `
+	id, provider, external_id, purpose, reference_id, user_id, method, amount, currency, status,
+	va_number, payment_url, expires_at, paid_at, created_at, updated_at
+`
+
+func scanGatewayPayment(row interface{ Scan(...interface{}) error }) (*models.GatewayPayment, error) {
+	p := &models.GatewayPayment{}
+	err := row.Scan(
+		&p.ID,
+		&p.Provider,
+		&p.ExternalID,
+		&p.Purpose,
+		&p.ReferenceID,
+		&p.UserID,
+		&p.Method,
+		&p.Amount,
+		&p.Currency,
+		&p.Status,
+		&p.VANumber,
+		&p.PaymentURL,
+		&p.ExpiresAt,
+		&p.PaidAt,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (r *PaymentGatewayRepository) CreatePayment(p *models.GatewayPayment) error {
+	query := `
+		INSERT INTO gateway_payments (
+			provider, external_id, purpose, reference_id, user_id, method, amount, currency, status,
+			va_number, payment_url, expires_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id, created_at, updated_at
+	`
+	return r.db.QueryRow(
+		query,
+		p.Provider,
+		p.ExternalID,
+		p.Purpose,
+		p.ReferenceID,
+		p.UserID,
+		p.Method,
+		p.Amount,
+		p.Currency,
+		p.Status,
+		p.VANumber,
+		p.PaymentURL,
+		p.ExpiresAt,
+	).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+}
+
+func (r *PaymentGatewayRepository) FindPaymentByExternalID(provider, externalID string) (*models.GatewayPayment, error) {
+	query := `SELECT ` + gatewayPaymentColumns + ` FROM gateway_payments WHERE provider = $1 AND external_id = $2`
+	p, err := scanGatewayPayment(r.db.QueryRow(query, provider, externalID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return p, err
+}
+
+// FindLatestPaymentByReference returns the most recent charge created for a local record
+func (r *PaymentGatewayRepository) FindLatestPaymentByReference(purpose models.GatewayPaymentPurpose, referenceID uuid.UUID) (*models.GatewayPayment, error) {
+	query := `
+		SELECT ` + gatewayPaymentColumns + ` FROM gateway_payments
+		WHERE purpose = $1 AND reference_id = $2
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+	p, err := scanGatewayPayment(r.db.QueryRow(query, purpose, referenceID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return p, err
+}
+
+// UpdatePaymentStatus moves a charge out of pending (or a paid charge to refunded).
+// It reports false when the charge was already in a final state.
+func (r *PaymentGatewayRepository) UpdatePaymentStatus(id uuid.UUID, status models.GatewayPaymentStatus) (bool, error) {
+	now := time.Now()
+	query := `UPDATE gateway_payments SET status = $1, updated_at = $2`
+	from := `'pending'`
+	switch status {
+	case models.GatewayPaymentPaid:
+		query += `, paid_at = $2`
+	case models.GatewayPaymentRefunded:
+		from = `'paid'`
+	}
+	query += ` WHERE id = $3 AND status = ` + from
+
+	result, err := r.db.Exec(query, status, now, id)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// ClaimPaid marks a charge paid unless it already was. A charge that expired can
+// still be paid into. Only the caller that gets true may settle the payment, so
+// concurrent webhooks and status syncs settle it once.
+func (r *PaymentGatewayRepository) ClaimPaid(id uuid.UUID) (bool, error) {
+	query := `
+		UPDATE gateway_payments SET status = 'paid', paid_at = $1, updated_at = $1
+		WHERE id = $2 AND status IN ('pending', 'expired')
+		RETURNING id
+	`
+	var claimed uuid.UUID
+	err := r.db.QueryRow(query, time.Now(), id).Scan(&claimed)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RecordWebhookEvent stores a provider callback. It returns false when the
+// provider already delivered an event with the same ID.
+func (r *PaymentGatewayRepository) RecordWebhookEvent(event *models.PaymentWebhookEvent) (bool, error) {
+	query := `
+		INSERT INTO payment_webhook_events (provider, event_id, payload)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (provider, event_id) DO NOTHING
+		RETURNING id, received_at
+	`
+	err := r.db.QueryRow(query, event.Provider, event.EventID, event.Payload).Scan(&event.ID, &event.ReceivedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *PaymentGatewayRepository) MarkWebhookEventProcessed(id uuid.UUID) error {
+	_, err := r.db.Exec(`UPDATE payment_webhook_events SET processed_at = $1 WHERE id = $2`, time.Now(), id)
+	return err
+}
@@ -10,7 +10,7 @@ import (
 )
 
 type RiskQuestionnaireRepository struct {
-	db *sql.DB
+	db DBTX
 }
 
 func NewRiskQuestionnaireRepository(db *sql.DB) *RiskQuestionnaireRepository {
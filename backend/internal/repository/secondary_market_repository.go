@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/money"
+)
+
+type SecondaryMarketRepository struct {
+	db DBTX
+}
+
+func NewSecondaryMarketRepository(db *sql.DB) *SecondaryMarketRepository {
+	return &SecondaryMarketRepository{db: db}
+}
+
+// Listings are read together with their position and invoice for the marketplace view
+const secondaryListingSelect = `
+	SELECT sl.id, sl.investment_id, sl.seller_id, sl.pool_id, sl.tranche, sl.principal, sl.price, sl.currency,
+	       sl.status, sl.buyer_id, sl.buyer_investment_id, sl.sold_at, sl.cancelled_at, sl.created_at, sl.updated_at,
+	       i.amount, i.expected_return, inv.invoice_number, inv.due_date
+	FROM secondary_listings sl
+	JOIN investments i ON i.id = sl.investment_id
+	JOIN funding_pools fp ON fp.id = sl.pool_id
+	JOIN invoices inv ON inv.id = fp.invoice_id
+`
+
+func scanSecondaryListing(row interface{ Scan(...interface{}) error }) (*models.SecondaryListing, error) {
+	l := &models.SecondaryListing{}
+	var positionReturn money.Amount
+	var dueDate time.Time
+	err := row.Scan(
+		&l.ID,
+		&l.InvestmentID,
+		&l.SellerID,
+		&l.PoolID,
+		&l.Tranche,
+		&l.Principal,
+		&l.Price,
+		&l.Currency,
+		&l.Status,
+		&l.BuyerID,
+		&l.BuyerInvestmentID,
+		&l.SoldAt,
+		&l.CancelledAt,
+		&l.CreatedAt,
+		&l.UpdatedAt,
+		&l.PositionAmount,
+		&positionReturn,
+		&l.InvoiceNumber,
+		&dueDate,
+	)
+	if err != nil {
+		return nil, err
+	}
+	l.DueDate = &dueDate
+	// A sold position has already been split, so only open listings show a share
+	if l.Status == models.ListingStatusOpen {
+		l.ExpectedReturn = models.PositionShare(positionReturn, l.Principal, l.PositionAmount, l.Currency)
+	}
+	return l, nil
+}
+
+func (r *SecondaryMarketRepository) queryListings(query string, args ...interface{}) ([]models.SecondaryListing, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var listings []models.SecondaryListing
+	for rows.Next() {
+		l, err := scanSecondaryListing(rows)
+		if err != nil {
+			return nil, err
+		}
+		listings = append(listings, *l)
+	}
+	return listings, rows.Err()
+}
+
+func (r *SecondaryMarketRepository) Create(l *models.SecondaryListing) error {
+	query := `
+		INSERT INTO secondary_listings (investment_id, seller_id, pool_id, tranche, principal, price, currency, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at
+	`
+	return r.db.QueryRow(
+		query,
+		l.InvestmentID,
+		l.SellerID,
+		l.PoolID,
+		l.Tranche,
+		l.Principal,
+		l.Price,
+		l.Currency,
+		l.Status,
+	).Scan(&l.ID, &l.CreatedAt, &l.UpdatedAt)
+}
+
+func (r *SecondaryMarketRepository) FindByID(id uuid.UUID) (*models.SecondaryListing, error) {
+	l, err := scanSecondaryListing(r.db.QueryRow(secondaryListingSelect+` WHERE sl.id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return l, err
+}
+
+// FindByIDForUpdate locks the listing; use inside a unit of work
+func (r *SecondaryMarketRepository) FindByIDForUpdate(id uuid.UUID) (*models.SecondaryListing, error) {
+	l, err := scanSecondaryListing(r.db.QueryRow(secondaryListingSelect+` WHERE sl.id = $1 FOR UPDATE OF sl`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return l, err
+}
+
+// FindOpenByInvestment returns the investment's open listing, nil when it has none
+func (r *SecondaryMarketRepository) FindOpenByInvestment(investmentID uuid.UUID) (*models.SecondaryListing, error) {
+	l, err := scanSecondaryListing(r.db.QueryRow(secondaryListingSelect+` WHERE sl.investment_id = $1 AND sl.status = 'open'`, investmentID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return l, err
+}
+
+// FindOpen lists open listings that can still be bought: the position is active
+// and its pool has not been settled. An empty tranche or nil pool matches all.
+func (r *SecondaryMarketRepository) FindOpen(poolID *uuid.UUID, tranche string, page, perPage int) ([]models.SecondaryListing, int, error) {
+	where := ` WHERE sl.status = 'open' AND i.status = 'active' AND fp.status IN ('filled', 'disbursed')
+		AND ($1::uuid IS NULL OR sl.pool_id = $1) AND ($2 = '' OR sl.tranche = $2)`
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM secondary_listings sl
+		JOIN investments i ON i.id = sl.investment_id
+		JOIN funding_pools fp ON fp.id = sl.pool_id` + where
+	if err := r.db.QueryRow(countQuery, poolID, tranche).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * perPage
+	listings, err := r.queryListings(secondaryListingSelect+where+` ORDER BY sl.created_at DESC LIMIT $3 OFFSET $4`, poolID, tranche, perPage, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	return listings, total, nil
+}
+
+// FindBySeller lists every listing of a seller, newest first
+func (r *SecondaryMarketRepository) FindBySeller(sellerID uuid.UUID, page, perPage int) ([]models.SecondaryListing, int, error) {
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM secondary_listings WHERE seller_id = $1`, sellerID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * perPage
+	listings, err := r.queryListings(secondaryListingSelect+` WHERE sl.seller_id = $1 ORDER BY sl.created_at DESC LIMIT $2 OFFSET $3`, sellerID, perPage, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	return listings, total, nil
+}
+
+// MarkSold records the buyer and the investment they received
+func (r *SecondaryMarketRepository) MarkSold(id, buyerID, buyerInvestmentID uuid.UUID) error {
+	query := `
+		UPDATE secondary_listings
+		SET status = 'sold', buyer_id = $1, buyer_investment_id = $2, sold_at = NOW(), updated_at = NOW()
+		WHERE id = $3 AND status = 'open'
+	`
+	_, err := r.db.Exec(query, buyerID, buyerInvestmentID, id)
+	return err
+}
+
+// Cancel withdraws an open listing
+func (r *SecondaryMarketRepository) Cancel(id uuid.UUID) error {
+	query := `UPDATE secondary_listings SET status = 'cancelled', cancelled_at = NOW(), updated_at = NOW() WHERE id = $1 AND status = 'open'`
+	_, err := r.db.Exec(query, id)
+	return err
+}
@@ -7,10 +7,11 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/money"
 )
 
 type TransactionRepository struct {
-	db *sql.DB
+	db DBTX
 }
 
 func NewTransactionRepository(db *sql.DB) *TransactionRepository {
@@ -208,9 +209,16 @@ func (r *TransactionRepository) UpdateBlockInfo(id uuid.UUID, blockNumber, gasUs
 	return err
 }
 
+// SetTxHash links a transaction to the on-chain transaction that records it
+func (r *TransactionRepository) SetTxHash(id uuid.UUID, txHash string) error {
+	query := `UPDATE transactions SET tx_hash = $1, updated_at = $2 WHERE id = $3`
+	_, err := r.db.Exec(query, txHash, time.Now(), id)
+	return err
+}
+
 // GetTotalPlatformFees returns the total platform fees collected (for admin dashboard)
-func (r *TransactionRepository) GetTotalPlatformFees() (float64, error) {
-	var total float64
+func (r *TransactionRepository) GetTotalPlatformFees() (money.Amount, error) {
+	var total money.Amount
 	query := `SELECT COALESCE(SUM(amount), 0) FROM transactions WHERE type = 'platform_fee' AND status = 'confirmed'`
 	err := r.db.QueryRow(query).Scan(&total)
 	return total, err
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// DBTX is satisfied by both *sql.DB and *sql.Tx, so a repository can run either
+// directly against the pool or inside a unit of work
+type DBTX interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// Tx is a transaction started by a repository method
+type Tx interface {
+	DBTX
+	Commit() error
+	Rollback() error
+}
+
+var savepointSeq uint64
+
+// begin starts a transaction on db. When db is already a transaction (the
+// repository was handed out by a unit of work) a savepoint is used instead,
+// so the method's own rollback stays local and the unit of work owns the commit.
+func begin(db DBTX) (Tx, error) {
+	switch h := db.(type) {
+	case *sql.DB:
+		return h.Begin()
+	case *sql.Tx:
+		name := fmt.Sprintf("sp_%d", atomic.AddUint64(&savepointSeq, 1))
+		if _, err := h.Exec("SAVEPOINT " + name); err != nil {
+			return nil, err
+		}
+		return &savepoint{Tx: h, name: name}, nil
+	}
+	return nil, errors.New("unsupported database handle")
+}
+
+// savepoint is a nested transaction inside a unit of work
+type savepoint struct {
+	*sql.Tx
+	name string
+	done bool
+}
+
+func (s *savepoint) Commit() error {
+	if s.done {
+		return sql.ErrTxDone
+	}
+	s.done = true
+	_, err := s.Tx.Exec("RELEASE SAVEPOINT " + s.name)
+	return err
+}
+
+func (s *savepoint) Rollback() error {
+	if s.done {
+		return sql.ErrTxDone
+	}
+	s.done = true
+	_, err := s.Tx.Exec("ROLLBACK TO SAVEPOINT " + s.name)
+	return err
+}
+
+// Repositories are bound to a single database transaction
+type Repositories struct {
+	Users            UserRepositoryInterface
+	Invoices         InvoiceRepositoryInterface
+	Funding          FundingRepositoryInterface
+	Transactions     TransactionRepositoryInterface
+	Ledger           LedgerRepositoryInterface
+	Outbox           OnchainOutboxRepositoryInterface
+	Defaults         DefaultRepositoryInterface
+	ImporterPayments ImporterPaymentRepositoryInterface
+	LateCharges      LateChargeRepositoryInterface
+	SecondaryMarket  SecondaryMarketRepositoryInterface
+	CreditScores     CreditScoreRepositoryInterface
+	PaymentGateway   PaymentGatewayRepositoryInterface
+	VirtualAccounts  VirtualAccountRepositoryInterface
+
+	afterCommit []func()
+}
+
+// AfterCommit registers fn to run once the unit of work has committed, for side
+// effects such as emails that must not happen when the transaction rolls back
+func (r *Repositories) AfterCommit(fn func()) {
+	r.afterCommit = append(r.afterCommit, fn)
+}
+
+// UnitOfWork runs several repository calls atomically
+type UnitOfWork struct {
+	db *sql.DB
+}
+
+func NewUnitOfWork(db *sql.DB) *UnitOfWork {
+	return &UnitOfWork{db: db}
+}
+
+// Do begins a transaction, hands fn repositories bound to it, and commits when fn
+// returns nil. Any error (or panic) rolls back every write made through repos.
+// Functions registered with repos.AfterCommit run only after a successful commit.
+func (u *UnitOfWork) Do(fn func(repos *Repositories) error) error {
+	tx, err := u.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	repos := &Repositories{
+		Users:            &UserRepository{db: tx},
+		Invoices:         &InvoiceRepository{db: tx},
+		Funding:          &FundingRepository{db: tx},
+		Transactions:     &TransactionRepository{db: tx},
+		Ledger:           &LedgerRepository{db: tx},
+		Outbox:           &OnchainOutboxRepository{db: tx},
+		Defaults:         &DefaultRepository{db: tx},
+		ImporterPayments: &ImporterPaymentRepository{db: tx},
+		LateCharges:      &LateChargeRepository{db: tx},
+		SecondaryMarket:  &SecondaryMarketRepository{db: tx},
+		CreditScores:     &CreditScoreRepository{db: tx},
+		PaymentGateway:   &PaymentGatewayRepository{db: tx},
+		VirtualAccounts:  &VirtualAccountRepository{db: tx},
+	}
+	if err := fn(repos); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for _, after := range repos.afterCommit {
+		after()
+	}
+	return nil
+}
@@ -8,10 +8,11 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/money"
 )
 
 type UserRepository struct {
-	db *sql.DB
+	db DBTX
 }
 
 func NewUserRepository(db *sql.DB) *UserRepository {
@@ -19,7 +20,7 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 }
 
 func (r *UserRepository) Create(user *models.User, profile *models.UserProfile) error {
-	tx, err := r.db.Begin()
+	tx, err := begin(r.db)
 	if err != nil {
 		return err
 	}
@@ -94,7 +95,7 @@ func (r *UserRepository) Create(user *models.User, profile *models.UserProfile)
 }
 
 func (r *UserRepository) CompleteUserRegistration(userID uuid.UUID, profile *models.UserProfile, identity *models.UserIdentity, bankAccount *models.BankAccount) error {
-	tx, err := r.db.Begin()
+	tx, err := begin(r.db)
 	if err != nil {
 		return err
 	}
@@ -286,6 +287,16 @@ func (r *UserRepository) FindByEmailOrUsername(identifier string) (*models.User,
 }
 
 func (r *UserRepository) FindByID(id uuid.UUID) (*models.User, error) {
+	return r.findByID(id, false)
+}
+
+// FindByIDForUpdate reads the user and holds a row lock on it until the
+// surrounding unit of work ends
+func (r *UserRepository) FindByIDForUpdate(id uuid.UUID) (*models.User, error) {
+	return r.findByID(id, true)
+}
+
+func (r *UserRepository) findByID(id uuid.UUID, forUpdate bool) (*models.User, error) {
 	user := &models.User{}
 	query := `
 		SELECT id, email, COALESCE(username, ''), COALESCE(phone_number, ''), password_hash, role,
@@ -295,6 +306,9 @@ func (r *UserRepository) FindByID(id uuid.UUID) (*models.User, error) {
 		FROM users
 		WHERE id = $1
 	`
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
 	var username, phoneNumber string
 	err := r.db.QueryRow(query, id).Scan(
 		&user.ID,
@@ -402,12 +416,6 @@ func (r *UserRepository) UsernameExists(username string) (bool, error) {
 	return exists, err
 }
 
-func (r *UserRepository) UpdateBalance(userID uuid.UUID, amount float64) error {
-	query := `UPDATE users SET balance_idr = $1, updated_at = $2 WHERE id = $3`
-	_, err := r.db.Exec(query, amount, time.Now(), userID)
-	return err
-}
-
 func (r *UserRepository) SetEmailVerified(userID uuid.UUID, verified bool) error {
 	query := `UPDATE users SET email_verified = $1, updated_at = $2 WHERE id = $3`
 	_, err := r.db.Exec(query, verified, time.Now(), userID)
@@ -592,14 +600,6 @@ func (r *UserRepository) UpdatePassword(userID uuid.UUID, hashedPassword string)
 	return err
 }
 
-// ==================== Wallet Methods ====================
-
-func (r *UserRepository) UpdateWalletAddress(userID uuid.UUID, walletAddress string) error {
-	query := `UPDATE users SET wallet_address = $1, updated_at = $2 WHERE id = $3`
-	_, err := r.db.Exec(query, walletAddress, time.Now(), userID)
-	return err
-}
-
 // ==================== Admin Methods ====================
 
 // UserListItem represents a user item in the admin list
@@ -609,7 +609,7 @@ type UserListItem struct {
 	Username        *string             `json:"username,omitempty"`
 	Role            models.UserRole     `json:"role"`
 	MemberStatus    models.MemberStatus `json:"member_status"`
-	BalanceIDR      float64             `json:"balance_idr"`
+	BalanceIDR      money.Amount        `json:"balance_idr"`
 	IsVerified      bool                `json:"is_verified"`
 	ProfileComplete bool                `json:"profile_completed"`
 	FullName        string              `json:"full_name,omitempty"`
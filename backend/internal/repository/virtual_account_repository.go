@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/models"
+)
+
+type VirtualAccountRepository struct {
+	db DBTX
+}
+
+func NewVirtualAccountRepository(db *sql.DB) *VirtualAccountRepository {
+	return &VirtualAccountRepository{db: db}
+}
+
+const virtualAccountColumns = `
+	id, pool_id, user_id, va_number, bank_code, bank_name, amount, status,
+	expires_at, paid_at, created_at, updated_at
+`
+
+func scanVirtualAccount(row interface{ Scan(...interface{}) error }) (*models.VirtualAccount, error) {
+	va := &models.VirtualAccount{}
+	err := row.Scan(
+		&va.ID,
+		&va.PoolID,
+		&va.UserID,
+		&va.VANumber,
+		&va.BankCode,
+		&va.BankName,
+		&va.Amount,
+		&va.Status,
+		&va.ExpiresAt,
+		&va.PaidAt,
+		&va.CreatedAt,
+		&va.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return va, nil
+}
+
+// ReplaceActive stores a new pending VA for the pool. Any VA still pending for the
+// pool is closed first (expired if past its expiry, cancelled otherwise), so a pool
+// never has more than one active VA.
+func (r *VirtualAccountRepository) ReplaceActive(va *models.VirtualAccount) error {
+	tx, err := begin(r.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	closeQuery := `
+		UPDATE virtual_accounts
+		SET status = CASE WHEN expires_at < NOW() THEN 'expired' ELSE 'cancelled' END, updated_at = NOW()
+		WHERE pool_id = $1 AND status = 'pending'
+	`
+	if _, err := tx.Exec(closeQuery, va.PoolID); err != nil {
+		return err
+	}
+
+	insertQuery := `
+		INSERT INTO virtual_accounts (id, pool_id, user_id, va_number, bank_code, bank_name, amount, status, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING created_at, updated_at
+	`
+	if va.ID == uuid.Nil {
+		va.ID = uuid.New()
+	}
+	if err := tx.QueryRow(
+		insertQuery,
+		va.ID,
+		va.PoolID,
+		va.UserID,
+		va.VANumber,
+		va.BankCode,
+		va.BankName,
+		va.Amount,
+		va.Status,
+		va.ExpiresAt,
+	).Scan(&va.CreatedAt, &va.UpdatedAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *VirtualAccountRepository) FindByID(id uuid.UUID) (*models.VirtualAccount, error) {
+	query := `SELECT ` + virtualAccountColumns + ` FROM virtual_accounts WHERE id = $1`
+	va, err := scanVirtualAccount(r.db.QueryRow(query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return va, err
+}
+
+// FindActiveByPool returns the pool's pending VA, if any
+func (r *VirtualAccountRepository) FindActiveByPool(poolID uuid.UUID) (*models.VirtualAccount, error) {
+	query := `SELECT ` + virtualAccountColumns + ` FROM virtual_accounts WHERE pool_id = $1 AND status = 'pending'`
+	va, err := scanVirtualAccount(r.db.QueryRow(query, poolID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return va, err
+}
+
+// UpdateStatus moves a pending VA to a final status, setting paid_at when paid
+func (r *VirtualAccountRepository) UpdateStatus(id uuid.UUID, status models.VAStatus) error {
+	now := time.Now()
+	query := `UPDATE virtual_accounts SET status = $1, updated_at = $2`
+	if status == models.VAStatusPaid {
+		query += `, paid_at = $2`
+	}
+	query += ` WHERE id = $3 AND status = 'pending'`
+
+	result, err := r.db.Exec(query, status, now, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return errors.New("virtual account is no longer pending")
+	}
+	return nil
+}
+
+// MarkPaid records a payment received on a VA. A VA that already expired or was
+// replaced can still be paid into, so any status but paid moves to paid.
+func (r *VirtualAccountRepository) MarkPaid(id uuid.UUID) error {
+	query := `
+		UPDATE virtual_accounts SET status = 'paid', paid_at = $1, updated_at = $1
+		WHERE id = $2 AND status <> 'paid'
+	`
+	_, err := r.db.Exec(query, time.Now(), id)
+	return err
+}
+
+// ExpireOverdue marks pending VAs past their expiry as expired
+func (r *VirtualAccountRepository) ExpireOverdue(now time.Time) (int64, error) {
+	query := `
+		UPDATE virtual_accounts
+		SET status = 'expired', updated_at = $1
+		WHERE status = 'pending' AND expires_at < $1
+	`
+	result, err := r.db.Exec(query, now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/models"
+)
+
+type WalletRepository struct {
+	db DBTX
+}
+
+func NewWalletRepository(db *sql.DB) *WalletRepository {
+	return &WalletRepository{db: db}
+}
+
+func (r *WalletRepository) CreateChallenge(c *models.WalletChallenge) error {
+	query := `
+		INSERT INTO wallet_challenges (user_id, wallet_address, method, nonce, message, issued_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+	return r.db.QueryRow(query, c.UserID, c.WalletAddress, c.Method, c.Nonce, c.Message, c.IssuedAt, c.ExpiresAt).
+		Scan(&c.ID, &c.CreatedAt)
+}
+
+func (r *WalletRepository) FindChallengeByID(id uuid.UUID) (*models.WalletChallenge, error) {
+	c := &models.WalletChallenge{}
+	query := `
+		SELECT id, user_id, wallet_address, method, nonce, message, issued_at, expires_at, used_at, created_at
+		FROM wallet_challenges
+		WHERE id = $1
+	`
+	err := r.db.QueryRow(query, id).Scan(
+		&c.ID,
+		&c.UserID,
+		&c.WalletAddress,
+		&c.Method,
+		&c.Nonce,
+		&c.Message,
+		&c.IssuedAt,
+		&c.ExpiresAt,
+		&c.UsedAt,
+		&c.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// FindUserByWallet returns the id of the user whose current wallet is address
+func (r *WalletRepository) FindUserByWallet(address string) (*uuid.UUID, error) {
+	var id uuid.UUID
+	err := r.db.QueryRow(`SELECT id FROM users WHERE LOWER(wallet_address) = LOWER($1)`, address).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+// Link consumes the challenge and makes link the user's current wallet in one
+// transaction, closing the previous link. It returns false if the challenge was
+// already used or has expired.
+func (r *WalletRepository) Link(challengeID uuid.UUID, link *models.WalletLink) (bool, error) {
+	tx, err := begin(r.db)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	result, err := tx.Exec(`
+		UPDATE wallet_challenges SET used_at = $1
+		WHERE id = $2 AND used_at IS NULL AND expires_at > $1
+	`, now, challengeID)
+	if err != nil {
+		return false, err
+	}
+	consumed, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if consumed == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE user_wallet_links SET unlinked_at = $1
+		WHERE user_id = $2 AND unlinked_at IS NULL
+	`, now, link.UserID); err != nil {
+		return false, err
+	}
+
+	link.ChallengeID = &challengeID
+	link.LinkedAt = now
+	if err := tx.QueryRow(`
+		INSERT INTO user_wallet_links (user_id, wallet_address, challenge_id, method, signature, linked_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, link.UserID, link.WalletAddress, challengeID, link.Method, link.Signature, now).Scan(&link.ID); err != nil {
+		return false, err
+	}
+
+	if _, err := tx.Exec(`UPDATE users SET wallet_address = $1, updated_at = $2 WHERE id = $3`, link.WalletAddress, now, link.UserID); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// FindLinksByUser returns the user's wallets, current one first
+func (r *WalletRepository) FindLinksByUser(userID uuid.UUID) ([]models.WalletLink, error) {
+	query := `
+		SELECT id, user_id, wallet_address, challenge_id, method, signature, linked_at, unlinked_at
+		FROM user_wallet_links
+		WHERE user_id = $1
+		ORDER BY linked_at DESC
+	`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []models.WalletLink
+	for rows.Next() {
+		var l models.WalletLink
+		if err := rows.Scan(
+			&l.ID,
+			&l.UserID,
+			&l.WalletAddress,
+			&l.ChallengeID,
+			&l.Method,
+			&l.Signature,
+			&l.LinkedAt,
+			&l.UnlinkedAt,
+		); err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
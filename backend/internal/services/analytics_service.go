@@ -0,0 +1,278 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/money"
+	"github.com/vessel/backend/internal/repository"
+)
+
+// ungradedGroup collects invoices that were funded without a grade
+const ungradedGroup = "ungraded"
+
+// AnalyticsService measures how graded invoices actually performed: default
+// rate, loss given default, days late and realized yield per grade, buyer
+// country tier and tranche, and how a candidate scorecard would have graded
+// the same invoices.
+type AnalyticsService struct {
+	analyticsRepo  repository.AnalyticsRepositoryInterface
+	gradingService *GradingService
+	countryTiers   *CountryTierService
+}
+
+func NewAnalyticsService(
+	analyticsRepo repository.AnalyticsRepositoryInterface,
+	gradingService *GradingService,
+	countryTiers *CountryTierService,
+) *AnalyticsService {
+	return &AnalyticsService{
+		analyticsRepo:  analyticsRepo,
+		gradingService: gradingService,
+		countryTiers:   countryTiers,
+	}
+}
+
+// outcomeTally accumulates OutcomeStats for one group
+type outcomeTally struct {
+	stats     models.OutcomeStats
+	repaid    int
+	daysLate  int
+	exposure  money.Amount
+	loss      money.Amount
+	invested  money.Amount
+	returned  money.Amount
+	defaulted int
+}
+
+// add counts an invoice, or one tranche of it when tranches holds only that tranche
+func (t *outcomeTally) add(o *models.InvoiceOutcome, tranches []models.TrancheOutcome) {
+	t.stats.Invoices++
+	for _, tr := range tranches {
+		t.invested += tr.Invested
+		t.returned += tr.Returned
+		t.exposure += tr.Exposure
+		t.loss += tr.Loss
+	}
+	if o.Defaulted {
+		t.defaulted++
+		return
+	}
+	t.repaid++
+	t.daysLate += daysLate(o)
+}
+
+func (t *outcomeTally) result() models.OutcomeStats {
+	s := t.stats
+	s.Defaults = t.defaulted
+	s.Exposure = t.exposure
+	s.Loss = t.loss
+	s.Invested = t.invested
+	s.Returned = t.returned
+	if s.Invoices > 0 {
+		s.DefaultRate = roundRate(float64(t.defaulted) / float64(s.Invoices) * 100)
+	}
+	s.LossGivenDefault = roundRate(money.Ratio(t.loss, t.exposure) * 100)
+	if t.repaid > 0 {
+		s.AvgDaysLate = roundRate(float64(t.daysLate) / float64(t.repaid))
+	}
+	s.RealizedYield = roundRate(money.Ratio(t.returned-t.invested, t.invested) * 100)
+	return s
+}
+
+// daysLate is how many days after the due date a repaid invoice's pool closed
+func daysLate(o *models.InvoiceOutcome) int {
+	if o.SettledAt == nil {
+		return 0
+	}
+	return daysPastDue(o.DueDate, *o.SettledAt)
+}
+
+func roundRate(rate float64) float64 {
+	return math.Round(rate*100) / 100
+}
+
+// outcomeGroups tallies outcomes into named groups
+type outcomeGroups map[string]*outcomeTally
+
+func newOutcomeGroups() outcomeGroups {
+	return make(outcomeGroups)
+}
+
+func (g outcomeGroups) get(group string) *outcomeTally {
+	t, ok := g[group]
+	if !ok {
+		t = &outcomeTally{stats: models.OutcomeStats{Group: group}}
+		g[group] = t
+	}
+	return t
+}
+
+// sorted returns the stats of every group, ordered by group name
+func (g outcomeGroups) sorted() []models.OutcomeStats {
+	names := make([]string, 0, len(g))
+	for name := range g {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	stats := make([]models.OutcomeStats, 0, len(names))
+	for _, name := range names {
+		stats = append(stats, g[name].result())
+	}
+	return stats
+}
+
+func outcomeGrade(o *models.InvoiceOutcome) string {
+	if o.Grade == nil || *o.Grade == "" {
+		return ungradedGroup
+	}
+	return *o.Grade
+}
+
+// LossRates reports realized outcomes of every invoice that was repaid or
+// defaulted, by grade, buyer country tier and tranche
+func (s *AnalyticsService) LossRates(filter *models.OutcomeFilter) (*models.LossRateReport, error) {
+	outcomes, err := s.analyticsRepo.FindInvoiceOutcomes(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	overall := &outcomeTally{stats: models.OutcomeStats{Group: "all"}}
+	byGrade, byTier, byTranche := newOutcomeGroups(), newOutcomeGroups(), newOutcomeGroups()
+	for i := range outcomes {
+		o := &outcomes[i]
+		overall.add(o, o.Tranches)
+		byGrade.get(outcomeGrade(o)).add(o, o.Tranches)
+
+		tier, err := s.countryTiers.Tier(o.BuyerCountry)
+		if err != nil {
+			return nil, err
+		}
+		byTier.get(strconv.Itoa(tier)).add(o, o.Tranches)
+
+		for _, tr := range o.Tranches {
+			byTranche.get(tr.Tranche).add(o, []models.TrancheOutcome{tr})
+		}
+	}
+
+	return &models.LossRateReport{
+		Filter:        *filter,
+		Overall:       overall.result(),
+		ByGrade:       byGrade.sorted(),
+		ByCountryTier: byTier.sorted(),
+		ByTranche:     byTranche.sorted(),
+		GeneratedAt:   time.Now(),
+	}, nil
+}
+
+// BacktestScorecard replays past invoices through a stored scorecard
+func (s *AnalyticsService) BacktestScorecard(scorecardID uuid.UUID, filter *models.OutcomeFilter) (*models.BacktestReport, error) {
+	sc, err := s.gradingService.GetScorecard(scorecardID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Backtest(sc, filter)
+}
+
+// Backtest grades every invoice with a known outcome with the candidate
+// scorecard, using the exporter history each invoice had when it was created,
+// and compares outcomes by the candidate's grades with outcomes by the grades
+// the invoices were funded with. Nothing is stored.
+func (s *AnalyticsService) Backtest(sc *models.Scorecard, filter *models.OutcomeFilter) (*models.BacktestReport, error) {
+	outcomes, err := s.analyticsRepo.FindInvoiceOutcomes(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	current, candidate := newOutcomeGroups(), newOutcomeGroups()
+	migrations := make(map[[2]string]*models.GradeMigration)
+	report := &models.BacktestReport{
+		ScorecardVersion: sc.Version,
+		ScorecardName:    sc.Name,
+		Filter:           *filter,
+	}
+	if sc.ID != uuid.Nil {
+		id := sc.ID
+		report.ScorecardID = &id
+	}
+
+	// Invoices, documents and exporter history are loaded for every outcome at once
+	invoiceIDs := make([]uuid.UUID, len(outcomes))
+	for i := range outcomes {
+		invoiceIDs[i] = outcomes[i].InvoiceID
+	}
+	replays, err := s.analyticsRepo.FindReplayInvoices(invoiceIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range outcomes {
+		o := &outcomes[i]
+		replay, ok := replays[o.InvoiceID]
+		if !ok {
+			continue
+		}
+		report.Invoices++
+		input, err := s.gradingService.ReplayInput(replay)
+		if err != nil {
+			return nil, err
+		}
+		score := Evaluate(sc, input)
+
+		from, to := outcomeGrade(o), score.Grade
+		current.get(from).add(o, o.Tranches)
+		candidate.get(to).add(o, o.Tranches)
+		if from == to {
+			continue
+		}
+		report.Regraded++
+		m, ok := migrations[[2]string{from, to}]
+		if !ok {
+			m = &models.GradeMigration{From: from, To: to}
+			migrations[[2]string{from, to}] = m
+		}
+		m.Invoices++
+		if o.Defaulted {
+			m.Defaults++
+		}
+	}
+
+	report.Current = current.sorted()
+	report.Candidate = candidate.sorted()
+	report.CurrentOrdered = defaultRatesOrdered(report.Current)
+	report.CandidateOrdered = defaultRatesOrdered(report.Candidate)
+	report.Migrations = make([]models.GradeMigration, 0, len(migrations))
+	for _, m := range migrations {
+		report.Migrations = append(report.Migrations, *m)
+	}
+	sort.Slice(report.Migrations, func(i, j int) bool {
+		a, b := report.Migrations[i], report.Migrations[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.To < b.To
+	})
+	report.GeneratedAt = time.Now()
+
+	return report, nil
+}
+
+// defaultRatesOrdered reports whether default rates never fall from one grade
+// to the next worse one (A, B, C), ignoring ungraded invoices
+func defaultRatesOrdered(stats []models.OutcomeStats) bool {
+	last := -1.0
+	for _, st := range stats {
+		if st.Group == ungradedGroup {
+			continue
+		}
+		if st.DefaultRate < last {
+			return false
+		}
+		last = st.DefaultRate
+	}
+	return true
+}
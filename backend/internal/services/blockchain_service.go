@@ -8,17 +8,28 @@ import (
 	"math/big"
 	"time"
 
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/google/uuid"
 	"github.com/vessel/backend/internal/config"
 	"github.com/vessel/backend/internal/contracts" // Generated bindings
 	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/money"
 	"github.com/vessel/backend/internal/repository"
 )
 
+// ErrOnchainRecordMissing means a queued write refers to a pool or NFT that is
+// not in the database, so retrying it cannot succeed
+var ErrOnchainRecordMissing = errors.New("onchain record missing")
+
+// ErrTokenNotVerified means an invoice NFT's mint has not been mined yet, so its
+// token id is not known. Writes that need it are retried later.
+var ErrTokenNotVerified = errors.New("invoice NFT token id not verified yet")
+
 type BlockchainService struct {
 	client       *ethclient.Client
 	privateKey   *ecdsa.PrivateKey
@@ -30,6 +41,15 @@ type BlockchainService struct {
 	fundingRepo  repository.FundingRepositoryInterface
 	pinata       PinataServiceInterface
 	cfg          *config.Config
+	nonces       *NonceManager
+	replacement  *txReplacement
+	units        *money.TokenUnits
+}
+
+// txReplacement resends an earlier transaction with the same nonce at a higher gas price
+type txReplacement struct {
+	nonce    uint64
+	gasPrice *big.Int
 }
 
 func NewBlockchainService(cfg *config.Config, invoiceRepo repository.InvoiceRepositoryInterface, fundingRepo repository.FundingRepositoryInterface, pinata PinataServiceInterface) (*BlockchainService, error) {
@@ -39,6 +59,7 @@ func NewBlockchainService(cfg *config.Config, invoiceRepo repository.InvoiceRepo
 			fundingRepo: fundingRepo,
 			pinata:      pinata,
 			cfg:         cfg,
+			units:       newTokenUnits(cfg),
 		}, nil
 	}
 
@@ -84,35 +105,141 @@ func NewBlockchainService(cfg *config.Config, invoiceRepo repository.InvoiceRepo
 		fundingRepo:  fundingRepo,
 		pinata:       pinata,
 		cfg:          cfg,
+		nonces:       NewNonceManager(client, fromAddress),
+		units:        newTokenUnits(cfg),
 	}, nil
 }
 
-func (s *BlockchainService) GetTransactOpts(ctx context.Context) (*bind.TransactOpts, error) {
+// invoiceCurrency is the currency an invoice's face value is in
+func invoiceCurrency(invoice *models.Invoice) string {
+	if invoice.Currency == "" {
+		return models.DefaultPoolCurrency
+	}
+	return invoice.Currency
+}
+
+// newTokenUnits builds the amount converter shared by contract calls, the
+// indexer and reconciliation
+func newTokenUnits(cfg *config.Config) *money.TokenUnits {
+	return money.NewTokenUnits(cfg.OnchainTokenDecimals, cfg.OnchainTokenDecimalsByCurrency)
+}
+
+// Enabled reports whether a chain client is configured. Without one, contract
+// calls are skipped and return simulated transaction hashes.
+func (s *BlockchainService) Enabled() bool {
+	return s.client != nil
+}
+
+// WithReplacement returns a copy of the service whose contract calls replace the
+// transaction sent with nonce, at gasPrice, instead of using a new nonce
+func (s *BlockchainService) WithReplacement(nonce uint64, gasPrice *big.Int) *BlockchainService {
+	copied := *s
+	copied.replacement = &txReplacement{nonce: nonce, gasPrice: gasPrice}
+	return &copied
+}
+
+// sendTx sends a contract call from the platform account. The call is first built
+// without broadcasting so the node estimates its gas, then sent with the estimate
+// plus the configured buffer. Sends are serialized by the nonce manager.
+func (s *BlockchainService) sendTx(ctx context.Context, call func(opts *bind.TransactOpts) (*types.Transaction, error)) (*types.Transaction, error) {
 	if s.client == nil {
 		return nil, errors.New("blockchain client not initialized")
 	}
 
-	nonce, err := s.client.PendingNonceAt(ctx, s.fromAddress)
+	auth, err := bind.NewKeyedTransactorWithChainID(s.privateKey, s.chainID)
 	if err != nil {
 		return nil, err
 	}
+	auth.Context = ctx
+	auth.Value = big.NewInt(0)
+
+	var release func(sent bool)
+	if s.replacement != nil {
+		unlock := s.nonces.Lock()
+		release = func(bool) { unlock() }
+		auth.Nonce = new(big.Int).SetUint64(s.replacement.nonce)
+		auth.GasPrice = s.replacement.gasPrice
+	} else {
+		nonce, acquired, err := s.nonces.Acquire(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get nonce: %w", err)
+		}
+		release = acquired
+		auth.Nonce = new(big.Int).SetUint64(nonce)
+
+		gasPrice, err := s.client.SuggestGasPrice(ctx)
+		if err != nil {
+			release(false)
+			return nil, err
+		}
+		auth.GasPrice = gasPrice
+	}
 
-	gasPrice, err := s.client.SuggestGasPrice(ctx)
+	auth.NoSend = true
+	estimated, err := call(auth)
+	if err != nil {
+		release(false)
+		return nil, fmt.Errorf("gas estimation failed: %w", err)
+	}
+	auth.NoSend = false
+	auth.GasLimit = estimated.Gas() * uint64(100+s.cfg.OnchainGasLimitBufferPct) / 100
+
+	tx, err := call(auth)
+	release(err == nil)
 	if err != nil {
 		return nil, err
 	}
+	return tx, nil
+}
 
-	auth, err := bind.NewKeyedTransactorWithChainID(s.privateKey, s.chainID)
+// SuggestGasPrice returns the node's current gas price
+func (s *BlockchainService) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	if s.client == nil {
+		return nil, errors.New("blockchain client not initialized")
+	}
+	return s.client.SuggestGasPrice(ctx)
+}
+
+// TxReceipt is the outcome of a mined transaction
+type TxReceipt struct {
+	TxHash        string
+	BlockNumber   int64
+	GasUsed       int64
+	Success       bool
+	Confirmations uint64 // Blocks since (and including) the receipt's block
+}
+
+// GetReceipt returns the receipt of a mined transaction, or nil while it is not mined
+func (s *BlockchainService) GetReceipt(ctx context.Context, txHash string) (*TxReceipt, error) {
+	if s.client == nil {
+		return nil, errors.New("blockchain client not initialized")
+	}
+
+	receipt, err := s.client.TransactionReceipt(ctx, common.HexToHash(txHash))
+	if errors.Is(err, ethereum.NotFound) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	auth.Nonce = big.NewInt(int64(nonce))
-	auth.Value = big.NewInt(0)
-	auth.GasLimit = uint64(500000) // Increased gas limit
-	auth.GasPrice = gasPrice
+	head, err := s.client.BlockNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mined := receipt.BlockNumber.Uint64()
+	var confirmations uint64
+	if head >= mined {
+		confirmations = head - mined + 1
+	}
 
-	return auth, nil
+	return &TxReceipt{
+		TxHash:        txHash,
+		BlockNumber:   int64(mined),
+		GasUsed:       int64(receipt.GasUsed),
+		Success:       receipt.Status == types.ReceiptStatusSuccessful,
+		Confirmations: confirmations,
+	}, nil
 }
 
 func (s *BlockchainService) GetPlatformAddress() string {
@@ -183,23 +310,26 @@ func (s *BlockchainService) TokenizeInvoice(invoiceID uuid.UUID, ownerAddress st
 	}
 
 	now := time.Now()
-	var tokenID int64
+	var tokenID *int64
 	var txHash string
 
 	// Call Smart Contract
 	if s.client != nil {
-		auth, err := s.GetTransactOpts(context.Background())
+		// The face value is recorded in the invoice currency's token units. The
+		// advance is in the pool currency, since the pool contract takes its
+		// target from advanceAmount.
+		amountBig, err := s.units.ToChain(invoice.Amount, invoiceCurrency(invoice))
 		if err != nil {
-			return nil, fmt.Errorf("failed to get transact opts: %w", err)
+			return nil, err
 		}
-
-		// Convert amounts to BigInt
-		amountBig := new(big.Int).SetInt64(int64(invoice.Amount))
-		var advanceAmount float64 = 0
+		var advanceAmount money.Amount
 		if invoice.AdvanceAmount != nil {
 			advanceAmount = *invoice.AdvanceAmount
 		}
-		advanceBig := new(big.Int).SetInt64(int64(advanceAmount))
+		advanceBig, err := s.units.ToChain(advanceAmount, models.DefaultPoolCurrency)
+		if err != nil {
+			return nil, err
+		}
 		var interestRate float64 = 10
 		if invoice.InterestRate != nil {
 			interestRate = *invoice.InterestRate
@@ -217,19 +347,21 @@ func (s *BlockchainService) TokenizeInvoice(invoiceID uuid.UUID, ownerAddress st
 		}
 
 		// Mint Invoice NFT
-		tx, err := s.nftContract.MintInvoice(
-			auth,
-			exporterAddr,
-			invoice.InvoiceNumber,
-			amountBig,
-			advanceBig,
-			interestBig,
-			issueDateBig,
-			dueDateBig,
-			invoice.BuyerCountry,
-			docHash,
-			metadataURI,
-		)
+		tx, err := s.sendTx(context.Background(), func(auth *bind.TransactOpts) (*types.Transaction, error) {
+			return s.nftContract.MintInvoice(
+				auth,
+				exporterAddr,
+				invoice.InvoiceNumber,
+				amountBig,
+				advanceBig,
+				interestBig,
+				issueDateBig,
+				dueDateBig,
+				invoice.BuyerCountry,
+				docHash,
+				metadataURI,
+			)
+		})
 		if err != nil {
 			return nil, fmt.Errorf("failed to mint NFT: %w", err)
 		}
@@ -237,15 +369,16 @@ func (s *BlockchainService) TokenizeInvoice(invoiceID uuid.UUID, ownerAddress st
 		fmt.Printf("[BLOCKCHAIN] NFT Mint submitted: TxHash=%s\n", tx.Hash().Hex())
 		txHash = tx.Hash().Hex()
 
-		// NOTE: In production, we should wait for event to get TokenID.
-		tokenID = int64(time.Now().UnixNano() % 1000000)
+		// The token id is only known once the mint is mined. It is read from
+		// the InvoiceMinted event by the indexer or by TokenID.
 	} else {
-		tokenID = int64(time.Now().UnixNano() % 1000000)
+		simulated := int64(time.Now().UnixNano() % 1000000)
+		tokenID = &simulated
 	}
 
 	nft := &models.InvoiceNFT{
 		InvoiceID:       invoiceID,
-		TokenID:         &tokenID,
+		TokenID:         tokenID,
 		ContractAddress: &s.cfg.InvoiceNFTContractAddr,
 		ChainID:         int(s.cfg.ChainID),
 		OwnerAddress:    &ownerAddress,
@@ -266,30 +399,70 @@ func (s *BlockchainService) TokenizeInvoice(invoiceID uuid.UUID, ownerAddress st
 	return nft, nil
 }
 
-func (s *BlockchainService) CreatePoolOnChain(tokenID int64) error {
-	if s.client == nil {
-		return nil // Skip if no blockchain client
-	}
+// CreatePoolOnChain creates the InvoicePool pool of a pool's invoice token
+func (s *BlockchainService) CreatePoolOnChain(poolID uuid.UUID) (*BlockchainTransaction, error) {
+	result := &BlockchainTransaction{Action: "pool_created", PoolID: poolID.String()}
 
-	auth, err := s.GetTransactOpts(context.Background())
-	if err != nil {
-		return fmt.Errorf("failed to get transact opts: %w", err)
+	if s.client != nil {
+		_, tokenIDBig, err := s.poolToken(poolID)
+		if err != nil {
+			return nil, err
+		}
+
+		tx, err := s.sendTx(context.Background(), func(auth *bind.TransactOpts) (*types.Transaction, error) {
+			return s.poolContract.CreatePool(auth, tokenIDBig)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create pool on chain via contract: %w", err)
+		}
+		result.setSent(tx)
+		fmt.Printf("[BLOCKCHAIN] Pool creation submitted: TxHash=%s\n", result.TxHash)
+	} else {
+		result.TxHash = generateBlockchainTxHash("create_pool", poolID.String())
 	}
 
-	tokenIDBig := big.NewInt(tokenID)
+	return result, nil
+}
+
+// BurnNFT burns the InvoiceNFT of a repaid invoice. The contract only burns tokens
+// whose invoice is repaid or defaulted, so it is queued after record_repayment.
+func (s *BlockchainService) BurnNFT(invoiceID uuid.UUID) (*BlockchainTransaction, error) {
+	result := &BlockchainTransaction{
+		Action: "nft_burned",
+		PoolID: invoiceID.String(),
+	}
 
-	tx, err := s.poolContract.CreatePool(auth, tokenIDBig)
+	nft, err := s.invoiceRepo.FindNFTByInvoiceID(invoiceID)
 	if err != nil {
-		return fmt.Errorf("failed to create pool on chain via contract: %w", err)
+		return nil, err
+	}
+	if nft == nil {
+		return nil, errors.New("invoice has no minted NFT")
 	}
 
-	fmt.Printf("[BLOCKCHAIN] Pool creation submitted: TxHash=%s\n", tx.Hash().Hex())
-	return nil
-}
+	if s.client != nil {
+		tokenIDBig, err := s.TokenID(context.Background(), nft)
+		if err != nil {
+			return nil, err
+		}
+
+		tx, err := s.sendTx(context.Background(), func(auth *bind.TransactOpts) (*types.Transaction, error) {
+			return s.nftContract.BurnInvoice(auth, tokenIDBig, "Invoice repaid")
+		})
+		if err != nil {
+			return nil, fmt.Errorf("contract call failed: %w", err)
+		}
+		result.setSent(tx)
+		fmt.Printf("[BLOCKCHAIN] NFT burned: TokenID=%s, TxHash=%s\n", tokenIDBig, result.TxHash)
+	} else {
+		result.TxHash = generateBlockchainTxHash("burn", invoiceID.String())
+	}
 
-func (s *BlockchainService) BurnNFT(invoiceID uuid.UUID) error {
-	// Implementation pending update
-	return nil
+	// Recorded on submission; a gas price replacement records its new hash
+	if err := s.invoiceRepo.BurnNFT(nft.ID, result.TxHash); err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
 func (s *BlockchainService) GetNFTByInvoice(invoiceID uuid.UUID) (*models.InvoiceNFT, error) {
@@ -308,193 +481,393 @@ func (s *BlockchainService) GetBalance(address string) (*big.Int, error) {
 	return s.client.BalanceAt(context.Background(), account, nil)
 }
 
+// OnchainInvoiceState is what the contracts hold for one invoice token
+type OnchainInvoiceState struct {
+	Exists        bool
+	Owner         common.Address
+	InvoiceStatus uint8                     // InvoiceNFT.InvoiceStatus
+	Pool          contracts.InvoicePoolPool // TargetAmount is zero when no pool was created
+	Investments   []contracts.InvoicePoolInvestment
+}
+
+// ReadInvoiceState reads the NFT, pool and investments of a token
+func (s *BlockchainService) ReadInvoiceState(ctx context.Context, tokenID int64) (*OnchainInvoiceState, error) {
+	if s.client == nil {
+		return nil, errors.New("blockchain client not configured")
+	}
+	opts := &bind.CallOpts{Context: ctx}
+	tokenIDBig := big.NewInt(tokenID)
+	state := &OnchainInvoiceState{}
+
+	invoice, err := s.nftContract.GetInvoice(opts, tokenIDBig)
+	if err != nil {
+		return nil, fmt.Errorf("getInvoice failed: %w", err)
+	}
+	// Unminted tokens read as an empty struct
+	if invoice.InvoiceNumber == "" {
+		return state, nil
+	}
+	state.Exists = true
+	state.InvoiceStatus = invoice.Status
+
+	if state.Owner, err = s.nftContract.OwnerOf(opts, tokenIDBig); err != nil {
+		return nil, fmt.Errorf("ownerOf failed: %w", err)
+	}
+	if state.Pool, err = s.poolContract.GetPool(opts, tokenIDBig); err != nil {
+		return nil, fmt.Errorf("getPool failed: %w", err)
+	}
+	if state.Pool.TargetAmount != nil && state.Pool.TargetAmount.Sign() > 0 {
+		if state.Investments, err = s.poolContract.GetPoolInvestments(opts, tokenIDBig); err != nil {
+			return nil, fmt.Errorf("getPoolInvestments failed: %w", err)
+		}
+	}
+	return state, nil
+}
+
 // BlockchainTransaction represents a recorded on-chain transaction
 type BlockchainTransaction struct {
-	TxHash string  `json:"tx_hash"`
-	Action string  `json:"action"`
-	Amount float64 `json:"amount"`
-	PoolID string  `json:"pool_id,omitempty"`
+	TxHash   string       `json:"tx_hash"`
+	Action   string       `json:"action"`
+	Amount   money.Amount `json:"amount"`
+	PoolID   string       `json:"pool_id,omitempty"`
+	Nonce    *uint64      `json:"nonce,omitempty"`
+	GasPrice *big.Int     `json:"gas_price,omitempty"`
+	GasLimit uint64       `json:"gas_limit,omitempty"`
 }
 
-// RecordInvestment records an investment on-chain
-func (s *BlockchainService) RecordInvestment(poolID uuid.UUID, investorWallet string, amount float64) (*BlockchainTransaction, error) {
-	var txHash string
+// setSent records the hash and send parameters of a broadcast transaction
+func (t *BlockchainTransaction) setSent(tx *types.Transaction) {
+	nonce := tx.Nonce()
+	t.TxHash = tx.Hash().Hex()
+	t.Nonce = &nonce
+	t.GasPrice = tx.GasPrice()
+	t.GasLimit = tx.Gas()
+}
 
-	if s.client != nil {
-		auth, err := s.GetTransactOpts(context.Background())
+// poolToken looks up a pool and the token id of its invoice NFT
+func (s *BlockchainService) poolToken(poolID uuid.UUID) (*models.FundingPool, *big.Int, error) {
+	pool, err := s.fundingRepo.FindPoolByID(poolID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if pool == nil {
+		return nil, nil, fmt.Errorf("%w: pool %s not found", ErrOnchainRecordMissing, poolID)
+	}
+	nft, err := s.invoiceRepo.FindNFTByInvoiceID(pool.InvoiceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if nft == nil {
+		return nil, nil, fmt.Errorf("%w: invoice %s has no minted NFT", ErrOnchainRecordMissing, pool.InvoiceID)
+	}
+	tokenID, err := s.TokenID(context.Background(), nft)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pool, tokenID, nil
+}
+
+// TokenID returns the on-chain token id of an invoice NFT. An id that has not
+// been verified yet is read from the InvoiceMinted event in the mint receipt
+// and stored. Without a chain client the stored id is returned as is.
+func (s *BlockchainService) TokenID(ctx context.Context, nft *models.InvoiceNFT) (*big.Int, error) {
+	if s.client == nil || nft.TokenVerified {
+		if nft.TokenID == nil {
+			return nil, fmt.Errorf("%w: invoice %s has no token id", ErrOnchainRecordMissing, nft.InvoiceID)
+		}
+		return big.NewInt(*nft.TokenID), nil
+	}
+	if nft.MintTxHash == nil || *nft.MintTxHash == "" {
+		return nil, fmt.Errorf("%w: invoice %s has no mint transaction", ErrOnchainRecordMissing, nft.InvoiceID)
+	}
+
+	receipt, err := s.client.TransactionReceipt(ctx, common.HexToHash(*nft.MintTxHash))
+	if errors.Is(err, ethereum.NotFound) {
+		return nil, fmt.Errorf("%w: mint %s of invoice %s is not mined", ErrTokenNotVerified, *nft.MintTxHash, nft.InvoiceID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return nil, fmt.Errorf("%w: mint %s of invoice %s reverted", ErrOnchainRecordMissing, *nft.MintTxHash, nft.InvoiceID)
+	}
+
+	nftAddress := common.HexToAddress(s.cfg.InvoiceNFTContractAddr)
+	for _, l := range receipt.Logs {
+		if l.Address != nftAddress {
+			continue
+		}
+		minted, err := s.nftContract.ParseInvoiceMinted(*l)
 		if err != nil {
+			continue
+		}
+		if !minted.TokenId.IsInt64() {
+			return nil, fmt.Errorf("token id %s of invoice %s is out of range", minted.TokenId, nft.InvoiceID)
+		}
+		tokenID := minted.TokenId.Int64()
+		if _, err := s.invoiceRepo.VerifyNFTToken(*nft.MintTxHash, tokenID); err != nil {
 			return nil, err
 		}
+		nft.TokenID = &tokenID
+		nft.TokenVerified = true
+		return minted.TokenId, nil
+	}
+	return nil, fmt.Errorf("%w: mint %s of invoice %s has no InvoiceMinted event", ErrOnchainRecordMissing, *nft.MintTxHash, nft.InvoiceID)
+}
 
-		// Get TokenID for pool
-		// We need to fetch pool details or assume poolID mapping
-		// For MVP, if we stored TokenID in DB, we use it.
-		// Assuming we can get TokenID from pool repo (not passed here).
-		// Let's assume passed poolID is NOT the token ID. We need to look it up.
-		pool, err := s.fundingRepo.FindPoolByID(poolID)
-		if err != nil || pool == nil {
-			return nil, fmt.Errorf("pool not found for blockchain record")
-		}
+// RecordInvestment records an investment on-chain
+func (s *BlockchainService) RecordInvestment(poolID uuid.UUID, investorWallet string, amount money.Amount) (*BlockchainTransaction, error) {
+	result := &BlockchainTransaction{
+		Action: "investment_recorded",
+		Amount: amount,
+		PoolID: poolID.String(),
+	}
 
-		// In models, Pool has InvoiceID. Invoice has NFT.
-		nft, err := s.invoiceRepo.FindNFTByInvoiceID(pool.InvoiceID)
-		if err != nil || nft == nil {
-			return nil, fmt.Errorf("nft not found")
+	if s.client != nil {
+		pool, tokenIDBig, err := s.poolToken(poolID)
+		if err != nil {
+			return nil, err
+		}
+		amountBig, err := s.units.ToChain(amount, pool.PoolCurrency)
+		if err != nil {
+			return nil, err
 		}
-
-		tokenIDBig := big.NewInt(*nft.TokenID)
-		amountBig := new(big.Int).SetInt64(int64(amount))
 		investorAddr := common.HexToAddress(investorWallet)
 
-		tx, err := s.poolContract.RecordInvestment(auth, tokenIDBig, investorAddr, amountBig)
+		tx, err := s.sendTx(context.Background(), func(auth *bind.TransactOpts) (*types.Transaction, error) {
+			return s.poolContract.RecordInvestment(auth, tokenIDBig, investorAddr, amountBig)
+		})
 		if err != nil {
 			return nil, fmt.Errorf("contract call failed: %w", err)
 		}
-		txHash = tx.Hash().Hex()
-		fmt.Printf("[BLOCKCHAIN] Investment recorded: TxHash=%s\n", txHash)
+		result.setSent(tx)
+		fmt.Printf("[BLOCKCHAIN] Investment recorded: TxHash=%s\n", result.TxHash)
 	} else {
-		txHash = generateBlockchainTxHash("investment", poolID.String())
+		result.TxHash = generateBlockchainTxHash("investment", poolID.String())
 	}
 
-	return &BlockchainTransaction{
-		TxHash: txHash,
-		Action: "investment_recorded",
-		Amount: amount,
-		PoolID: poolID.String(),
-	}, nil
+	return result, nil
 }
 
 // RecordDisbursement records disbursement to mitra on-chain
-func (s *BlockchainService) RecordDisbursement(poolID uuid.UUID, amount float64) (*BlockchainTransaction, error) {
-	var txHash string
+func (s *BlockchainService) RecordDisbursement(poolID uuid.UUID, amount money.Amount) (*BlockchainTransaction, error) {
+	result := &BlockchainTransaction{
+		Action: "disbursement_recorded",
+		Amount: amount,
+		PoolID: poolID.String(),
+	}
 
 	if s.client != nil {
-		auth, err := s.GetTransactOpts(context.Background())
+		_, tokenIDBig, err := s.poolToken(poolID)
 		if err != nil {
 			return nil, err
 		}
 
-		pool, err := s.fundingRepo.FindPoolByID(poolID)
+		tx, err := s.sendTx(context.Background(), func(auth *bind.TransactOpts) (*types.Transaction, error) {
+			return s.poolContract.RecordDisbursement(auth, tokenIDBig)
+		})
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("contract call failed: %w", err)
 		}
-		nft, err := s.invoiceRepo.FindNFTByInvoiceID(pool.InvoiceID)
+		result.setSent(tx)
+		fmt.Printf("[BLOCKCHAIN] Disbursement recorded: TxHash=%s\n", result.TxHash)
+	} else {
+		result.TxHash = generateBlockchainTxHash("disbursement", poolID.String())
+	}
+
+	return result, nil
+}
+
+// RecordRepayment records repayment and investor returns on-chain
+func (s *BlockchainService) RecordRepayment(poolID uuid.UUID, totalAmount money.Amount) (*BlockchainTransaction, error) {
+	result := &BlockchainTransaction{
+		Action: "repayment_recorded",
+		Amount: totalAmount,
+		PoolID: poolID.String(),
+	}
+
+	if s.client != nil {
+		pool, tokenIDBig, err := s.poolToken(poolID)
 		if err != nil {
 			return nil, err
 		}
 
-		tokenIDBig := big.NewInt(*nft.TokenID)
+		// Find investments to build returns array
+		investments, err := s.fundingRepo.FindInvestmentsByPool(poolID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get investments: %w", err)
+		}
 
-		tx, err := s.poolContract.RecordDisbursement(auth, tokenIDBig)
+		var returns []*big.Int
+		for _, inv := range investments {
+			// Assuming ActualReturn is populated by now
+			var ret money.Amount
+			if inv.ActualReturn != nil {
+				ret = *inv.ActualReturn
+			}
+			retBig, err := s.units.ToChain(ret, pool.PoolCurrency)
+			if err != nil {
+				return nil, err
+			}
+			returns = append(returns, retBig)
+		}
+
+		totalAmountBig, err := s.units.ToChain(totalAmount, pool.PoolCurrency)
+		if err != nil {
+			return nil, err
+		}
+
+		tx, err := s.sendTx(context.Background(), func(auth *bind.TransactOpts) (*types.Transaction, error) {
+			return s.poolContract.RecordRepayment(auth, tokenIDBig, totalAmountBig, returns)
+		})
 		if err != nil {
 			return nil, fmt.Errorf("contract call failed: %w", err)
 		}
-		txHash = tx.Hash().Hex()
-		fmt.Printf("[BLOCKCHAIN] Disbursement recorded: TxHash=%s\n", txHash)
+		result.setSent(tx)
+		fmt.Printf("[BLOCKCHAIN] Repayment recorded: TxHash=%s\n", result.TxHash)
 	} else {
-		txHash = generateBlockchainTxHash("disbursement", poolID.String())
+		result.TxHash = generateBlockchainTxHash("repayment", poolID.String())
 	}
 
-	return &BlockchainTransaction{
-		TxHash: txHash,
-		Action: "disbursement_recorded",
-		Amount: amount,
+	return result, nil
+}
+
+// MarkDefaulted marks a disbursed pool and its InvoiceNFT as defaulted on-chain
+func (s *BlockchainService) MarkDefaulted(poolID uuid.UUID) (*BlockchainTransaction, error) {
+	result := &BlockchainTransaction{
+		Action: "pool_defaulted",
 		PoolID: poolID.String(),
-	}, nil
+	}
+
+	if s.client != nil {
+		_, tokenIDBig, err := s.poolToken(poolID)
+		if err != nil {
+			return nil, err
+		}
+
+		tx, err := s.sendTx(context.Background(), func(auth *bind.TransactOpts) (*types.Transaction, error) {
+			return s.poolContract.MarkDefaulted(auth, tokenIDBig)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("contract call failed: %w", err)
+		}
+		result.setSent(tx)
+		fmt.Printf("[BLOCKCHAIN] Default recorded: TxHash=%s\n", result.TxHash)
+	} else {
+		result.TxHash = generateBlockchainTxHash("default", poolID.String())
+	}
+
+	return result, nil
 }
 
-// RecordRepayment records repayment and investor returns on-chain
-func (s *BlockchainService) RecordRepayment(poolID uuid.UUID, totalAmount float64) (*BlockchainTransaction, error) {
-	var txHash string
+// TransferInvestment moves amount of a seller's position to the buyer. The
+// contract addresses positions by index, so the seller's record is located by
+// its unclaimed principal before the sale.
+func (s *BlockchainService) TransferInvestment(poolID uuid.UUID, sellerWallet, buyerWallet string, position, amount money.Amount) (*BlockchainTransaction, error) {
+	result := &BlockchainTransaction{
+		Action: "investment_transferred",
+		Amount: amount,
+		PoolID: poolID.String(),
+	}
 
 	if s.client != nil {
-		auth, err := s.GetTransactOpts(context.Background())
+		pool, tokenIDBig, err := s.poolToken(poolID)
 		if err != nil {
 			return nil, err
 		}
 
-		pool, err := s.fundingRepo.FindPoolByID(poolID)
+		ctx := context.Background()
+		state, err := s.ReadInvoiceState(ctx, tokenIDBig.Int64())
 		if err != nil {
 			return nil, err
 		}
-		nft, err := s.invoiceRepo.FindNFTByInvoiceID(pool.InvoiceID)
+		positionBig, err := s.units.ToChain(position, pool.PoolCurrency)
 		if err != nil {
 			return nil, err
 		}
-
-		// Find investments to build returns array
-		investments, err := s.fundingRepo.FindInvestmentsByPool(poolID)
+		amountBig, err := s.units.ToChain(amount, pool.PoolCurrency)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get investments: %w", err)
+			return nil, err
 		}
 
-		var returns []*big.Int
-		for _, inv := range investments {
-			// Assuming ActualReturn is populated by now
-			var ret int64 = 0
-			if inv.ActualReturn != nil {
-				ret = int64(*inv.ActualReturn)
+		seller := common.HexToAddress(sellerWallet)
+		index := -1
+		for i, inv := range state.Investments {
+			if inv.Investor == seller && !inv.Claimed && inv.Amount.Cmp(positionBig) == 0 {
+				index = i
+				break
 			}
-			returns = append(returns, big.NewInt(ret))
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("no on-chain position of %s for %s", position, sellerWallet)
 		}
 
-		tokenIDBig := big.NewInt(*nft.TokenID)
-		totalAmountBig := new(big.Int).SetInt64(int64(totalAmount))
-
-		tx, err := s.poolContract.RecordRepayment(auth, tokenIDBig, totalAmountBig, returns)
+		buyerAddr := common.HexToAddress(buyerWallet)
+		tx, err := s.sendTx(ctx, func(auth *bind.TransactOpts) (*types.Transaction, error) {
+			return s.poolContract.TransferInvestment(auth, tokenIDBig, big.NewInt(int64(index)), buyerAddr, amountBig)
+		})
 		if err != nil {
 			return nil, fmt.Errorf("contract call failed: %w", err)
 		}
-		txHash = tx.Hash().Hex()
-		fmt.Printf("[BLOCKCHAIN] Repayment recorded: TxHash=%s\n", txHash)
+		result.setSent(tx)
+		fmt.Printf("[BLOCKCHAIN] Investment transferred: TxHash=%s\n", result.TxHash)
 	} else {
-		txHash = generateBlockchainTxHash("repayment", poolID.String())
+		result.TxHash = generateBlockchainTxHash("transfer", poolID.String())
 	}
 
-	return &BlockchainTransaction{
-		TxHash: txHash,
-		Action: "repayment_recorded",
-		Amount: totalAmount,
-		PoolID: poolID.String(),
-	}, nil
+	return result, nil
 }
 
 // RecordMitraBalanceCredit records excess payment to mitra
-func (s *BlockchainService) RecordMitraBalanceCredit(invoiceID uuid.UUID, mitraWallet string, amount float64) (*BlockchainTransaction, error) {
-	var txHash string
+func (s *BlockchainService) RecordMitraBalanceCredit(invoiceID uuid.UUID, mitraWallet string, amount money.Amount) (*BlockchainTransaction, error) {
+	result := &BlockchainTransaction{
+		Action: "mitra_balance_credited",
+		Amount: amount,
+		PoolID: invoiceID.String(),
+	}
 
 	if s.client != nil {
-		auth, err := s.GetTransactOpts(context.Background())
+		nft, err := s.invoiceRepo.FindNFTByInvoiceID(invoiceID)
+		if err != nil {
+			return nil, err
+		}
+		if nft == nil {
+			return nil, fmt.Errorf("%w: invoice %s has no minted NFT", ErrOnchainRecordMissing, invoiceID)
+		}
+		tokenIDBig, err := s.TokenID(context.Background(), nft)
 		if err != nil {
 			return nil, err
 		}
 
-		nft, err := s.invoiceRepo.FindNFTByInvoiceID(invoiceID)
-		if err != nil || nft == nil {
-			return nil, fmt.Errorf("nft needs to exist")
+		currency := models.DefaultPoolCurrency
+		pool, err := s.fundingRepo.FindPoolByInvoiceID(invoiceID)
+		if err != nil {
+			return nil, err
+		}
+		if pool != nil {
+			currency = pool.PoolCurrency
 		}
 
-		tokenIDBig := big.NewInt(*nft.TokenID)
-		amountBig := new(big.Int).SetInt64(int64(amount))
+		amountBig, err := s.units.ToChain(amount, currency)
+		if err != nil {
+			return nil, err
+		}
 		mitraAddr := common.HexToAddress(mitraWallet)
 
 		// New function we added to contract
-		tx, err := s.poolContract.RecordExcessRepayment(auth, tokenIDBig, mitraAddr, amountBig)
+		tx, err := s.sendTx(context.Background(), func(auth *bind.TransactOpts) (*types.Transaction, error) {
+			return s.poolContract.RecordExcessRepayment(auth, tokenIDBig, mitraAddr, amountBig)
+		})
 		if err != nil {
 			return nil, fmt.Errorf("contract call failed: %w", err)
 		}
-		txHash = tx.Hash().Hex()
-		fmt.Printf("[BLOCKCHAIN] Mitra credit recorded: TxHash=%s\n", txHash)
+		result.setSent(tx)
+		fmt.Printf("[BLOCKCHAIN] Mitra credit recorded: TxHash=%s\n", result.TxHash)
 	} else {
-		txHash = generateBlockchainTxHash("mitra_credit", invoiceID.String())
+		result.TxHash = generateBlockchainTxHash("mitra_credit", invoiceID.String())
 	}
 
-	return &BlockchainTransaction{
-		TxHash: txHash,
-		Action: "mitra_balance_credited",
-		Amount: amount,
-		PoolID: invoiceID.String(),
-	}, nil
+	return result, nil
 }
 
 // Removed deprecated single RecordInvestorReturn as it's handled in batch RecordRepayment
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/repository"
+)
+
+var (
+	ErrBuyerNotFound        = errors.New("buyer not found")
+	ErrBuyerMerged          = errors.New("buyer was merged into another buyer")
+	ErrInvalidBuyerName     = errors.New("buyer name must contain a letter or digit")
+	ErrBuyerMergeSelf       = errors.New("cannot merge a buyer into itself")
+	ErrBuyerCountryMismatch = errors.New("buyers are in different countries")
+	ErrBuyerAliasTaken      = errors.New("name already belongs to another buyer; merge the buyers instead")
+)
+
+// buyerDuplicateThreshold is the name similarity from which two buyers in the
+// same country are reported as possible duplicates
+const buyerDuplicateThreshold = 0.8
+
+// BuyerService keeps the buyer registry. Invoices resolve their buyer by
+// normalized name or alias within the buyer country, creating it on first use;
+// similar names are only listed as duplicates, admins decide whether to merge them.
+type BuyerService struct {
+	repo repository.BuyerRepositoryInterface
+}
+
+func NewBuyerService(repo repository.BuyerRepositoryInterface) *BuyerService {
+	return &BuyerService{repo: repo}
+}
+
+func validBuyerName(name string) bool {
+	return strings.IndexFunc(name, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0
+}
+
+// Find returns the registered buyer with the name or alias in the country, or nil
+func (s *BuyerService) Find(name, country string) (*models.Buyer, error) {
+	name = strings.TrimSpace(name)
+	if !validBuyerName(name) {
+		return nil, ErrInvalidBuyerName
+	}
+	return s.repo.FindByName(name, country)
+}
+
+// Resolve returns the buyer with the name or alias in the country, registering
+// it when there is none, and records the contact email when one is given
+func (s *BuyerService) Resolve(name, country, email string) (*models.Buyer, error) {
+	buyer, err := s.Find(name, country)
+	if err != nil {
+		return nil, err
+	}
+	if buyer == nil {
+		buyer = &models.Buyer{Name: strings.TrimSpace(name), Country: country}
+		created, err := s.repo.Create(buyer)
+		if err != nil {
+			return nil, err
+		}
+		if !created {
+			if buyer, err = s.repo.FindByName(name, country); err != nil {
+				return nil, err
+			}
+			if buyer == nil {
+				return nil, ErrBuyerNotFound
+			}
+		}
+	}
+
+	if email = strings.TrimSpace(email); email != "" {
+		if err := s.repo.AddContact(buyer.ID, email); err != nil {
+			return nil, err
+		}
+	}
+	return buyer, nil
+}
+
+// Get returns a buyer with its aliases, contacts and payment history
+func (s *BuyerService) Get(id uuid.UUID) (*models.Buyer, error) {
+	buyer, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if buyer == nil {
+		return nil, ErrBuyerNotFound
+	}
+	if buyer.Aliases, err = s.repo.FindAliases(id); err != nil {
+		return nil, err
+	}
+	if buyer.Contacts, err = s.repo.FindContacts(id); err != nil {
+		return nil, err
+	}
+	if buyer.History, err = s.repo.PaymentHistory(id); err != nil {
+		return nil, err
+	}
+	return buyer, nil
+}
+
+// List returns a page of buyers that were not merged away
+func (s *BuyerService) List(filter *models.BuyerFilter) (*models.BuyerListResponse, error) {
+	if filter.Country != "" {
+		country, ok := NormalizeCountry(filter.Country)
+		if !ok {
+			return nil, ErrUnknownCountry
+		}
+		filter.Country = country
+	}
+	buyers, total, err := s.repo.FindAll(filter)
+	if err != nil {
+		return nil, err
+	}
+	if buyers == nil {
+		buyers = []models.Buyer{}
+	}
+	return &models.BuyerListResponse{
+		Buyers:     buyers,
+		Total:      total,
+		Page:       filter.Page,
+		PerPage:    filter.PerPage,
+		TotalPages: models.CalculateTotalPages(total, filter.PerPage),
+	}, nil
+}
+
+// activeBuyer loads a buyer that can still be changed
+func (s *BuyerService) activeBuyer(id uuid.UUID) (*models.Buyer, error) {
+	buyer, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if buyer == nil {
+		return nil, ErrBuyerNotFound
+	}
+	if buyer.MergedInto != nil {
+		return nil, ErrBuyerMerged
+	}
+	return buyer, nil
+}
+
+// AddAlias records another name the buyer trades under. A name that already
+// resolves to a different buyer is refused: the two are duplicates to merge.
+func (s *BuyerService) AddAlias(buyerID uuid.UUID, alias string) (*models.Buyer, error) {
+	buyer, err := s.activeBuyer(buyerID)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.Find(alias, buyer.Country)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && existing.ID != buyer.ID {
+		return nil, ErrBuyerAliasTaken
+	}
+	if existing == nil {
+		if _, err := s.repo.CreateAlias(&models.BuyerAlias{BuyerID: buyer.ID, Alias: strings.TrimSpace(alias)}); err != nil {
+			return nil, err
+		}
+	}
+	return s.Get(buyer.ID)
+}
+
+// AddContact records a contact email of the buyer
+func (s *BuyerService) AddContact(buyerID uuid.UUID, email string) (*models.Buyer, error) {
+	buyer, err := s.activeBuyer(buyerID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.AddContact(buyer.ID, email); err != nil {
+		return nil, err
+	}
+	return s.Get(buyer.ID)
+}
+
+// Duplicates lists pairs of buyers in the same country whose names are similar
+// enough to be the same importer, most similar first. An empty country checks all.
+func (s *BuyerService) Duplicates(country string) ([]models.BuyerDuplicate, error) {
+	if country != "" {
+		code, ok := NormalizeCountry(country)
+		if !ok {
+			return nil, ErrUnknownCountry
+		}
+		country = code
+	}
+	buyers, err := s.repo.FindActive(country)
+	if err != nil {
+		return nil, err
+	}
+
+	duplicates := []models.BuyerDuplicate{}
+	for i := range buyers {
+		for j := i + 1; j < len(buyers) && buyers[j].Country == buyers[i].Country; j++ {
+			similarity := buyerNameSimilarity(buyers[i].NormalizedName, buyers[j].NormalizedName)
+			if similarity >= buyerDuplicateThreshold {
+				duplicates = append(duplicates, models.BuyerDuplicate{
+					Buyer:      buyers[i],
+					Candidate:  buyers[j],
+					Similarity: roundRate(similarity),
+				})
+			}
+		}
+	}
+	sort.SliceStable(duplicates, func(i, j int) bool { return duplicates[i].Similarity > duplicates[j].Similarity })
+	return duplicates, nil
+}
+
+// Merge folds a duplicate buyer into the buyer that is kept. Its invoices,
+// importer payments, aliases and contacts move over and its name becomes an alias.
+func (s *BuyerService) Merge(sourceID, targetID uuid.UUID) (*models.Buyer, error) {
+	if sourceID == targetID {
+		return nil, ErrBuyerMergeSelf
+	}
+	source, err := s.activeBuyer(sourceID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.activeBuyer(targetID)
+	if err != nil {
+		return nil, err
+	}
+	if source.Country != target.Country {
+		return nil, ErrBuyerCountryMismatch
+	}
+	if err := s.repo.Merge(source.ID, target.ID); err != nil {
+		return nil, err
+	}
+	return s.Get(target.ID)
+}
+
+// RepaidWithExporter counts the exporter's invoices to the buyer that were repaid
+func (s *BuyerService) RepaidWithExporter(buyerID, exporterID uuid.UUID) (int, error) {
+	return s.repo.CountRepaidWithExporter(buyerID, exporterID)
+}
+
+// buyerNameSimilarity compares two normalized buyer names, 0-1: the better of
+// their edit-distance similarity and the overlap of their words
+func buyerNameSimilarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ar, br := []rune(a), []rune(b)
+	longest := max(len(ar), len(br))
+	if longest == 0 {
+		return 0
+	}
+	edit := 1 - float64(levenshtein(ar, br))/float64(longest)
+
+	words := make(map[string]bool)
+	for _, w := range strings.Fields(a) {
+		words[w] = true
+	}
+	shared, union := 0, len(words)
+	seen := make(map[string]bool)
+	for _, w := range strings.Fields(b) {
+		if seen[w] {
+			continue
+		}
+		seen[w] = true
+		if words[w] {
+			shared++
+		} else {
+			union++
+		}
+	}
+	overlap := 0.0
+	if union > 0 {
+		overlap = float64(shared) / float64(union)
+	}
+	return max(edit, overlap)
+}
+
+// levenshtein is the number of single-rune edits that turn a into b
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(min(prev[j]+1, curr[j-1]+1), prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
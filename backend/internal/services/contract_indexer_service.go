@@ -0,0 +1,420 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/config"
+	"github.com/vessel/backend/internal/contracts"
+	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/money"
+	"github.com/vessel/backend/internal/repository"
+)
+
+const (
+	contractIndexerName  = "invoice_contracts"
+	indexerReorgWindow   = 128 // Recent block hashes kept to find the common ancestor after a reorg
+	indexerDefaultBatch  = 2000
+	contractInvoiceNFT   = "InvoiceNFT"
+	contractInvoicePool  = "InvoicePool"
+	indexerTokenIDArgKey = "tokenId"
+)
+
+// indexerAmountArgs are the event arguments that hold token amounts
+var indexerAmountArgs = []string{"amount", "expectedReturn", "targetAmount", "totalAmount"}
+
+var ErrPoolNotFound = errors.New("pool not found")
+
+// indexedContract is one contract whose logs the indexer decodes
+type indexedContract struct {
+	name   string
+	events map[common.Hash]abi.Event
+}
+
+// ContractIndexerService copies InvoiceNFT and InvoicePool events into Postgres.
+// It backfills from the configured start block, then follows the chain head and
+// rewinds to the common ancestor when a block it stored is no longer canonical.
+type ContractIndexerService struct {
+	eventRepo   repository.OnchainEventRepositoryInterface
+	fundingRepo repository.FundingRepositoryInterface
+	invoiceRepo repository.InvoiceRepositoryInterface
+	blockchain  *BlockchainService
+	cfg         *config.Config
+	contracts   map[common.Address]indexedContract
+	units       *money.TokenUnits
+	startBlock  int64
+	batchBlocks int64
+}
+
+func NewContractIndexerService(
+	eventRepo repository.OnchainEventRepositoryInterface,
+	fundingRepo repository.FundingRepositoryInterface,
+	invoiceRepo repository.InvoiceRepositoryInterface,
+	blockchain *BlockchainService,
+	cfg *config.Config,
+) (*ContractIndexerService, error) {
+	nftABI, err := contracts.InvoiceNFTMetaData.GetAbi()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse InvoiceNFT ABI: %w", err)
+	}
+	poolABI, err := contracts.InvoicePoolMetaData.GetAbi()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse InvoicePool ABI: %w", err)
+	}
+
+	s := &ContractIndexerService{
+		eventRepo:   eventRepo,
+		fundingRepo: fundingRepo,
+		invoiceRepo: invoiceRepo,
+		blockchain:  blockchain,
+		cfg:         cfg,
+		contracts:   make(map[common.Address]indexedContract),
+		units:       newTokenUnits(cfg),
+		startBlock:  cfg.OnchainIndexerStartBlock,
+		batchBlocks: cfg.OnchainIndexerBatchBlocks,
+	}
+	if s.batchBlocks <= 0 {
+		s.batchBlocks = indexerDefaultBatch
+	}
+	s.addContract(cfg.InvoiceNFTContractAddr, contractInvoiceNFT, nftABI)
+	s.addContract(cfg.InvoicePoolContractAddr, contractInvoicePool, poolABI)
+	return s, nil
+}
+
+func (s *ContractIndexerService) addContract(address, name string, parsed *abi.ABI) {
+	if !common.IsHexAddress(address) {
+		return
+	}
+	events := make(map[common.Hash]abi.Event, len(parsed.Events))
+	for _, event := range parsed.Events {
+		events[event.ID] = event
+	}
+	s.contracts[common.HexToAddress(address)] = indexedContract{name: name, events: events}
+}
+
+// Sync indexes every block between the cursor and the chain head and returns how
+// many events were stored
+func (s *ContractIndexerService) Sync(ctx context.Context) (int, error) {
+	if s.blockchain == nil || !s.blockchain.Enabled() || len(s.contracts) == 0 {
+		return 0, nil
+	}
+	client := s.blockchain.client
+	chainID := s.cfg.ChainID
+
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get chain head: %w", err)
+	}
+
+	last, found, err := s.eventRepo.GetCursor(contractIndexerName, chainID)
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		last = s.startBlock - 1
+	} else {
+		ancestor, reorged, err := s.findCommonAncestor(ctx)
+		if err != nil {
+			return 0, err
+		}
+		if reorged {
+			removed, err := s.eventRepo.Rewind(contractIndexerName, chainID, ancestor)
+			if err != nil {
+				return 0, err
+			}
+			fmt.Printf("[INDEXER] Reorg detected: rewound from block %d to %d, removed %d events\n", last, ancestor, removed)
+			last = ancestor
+		}
+	}
+
+	addresses := make([]common.Address, 0, len(s.contracts))
+	for address := range s.contracts {
+		addresses = append(addresses, address)
+	}
+
+	stored := 0
+	for from := last + 1; from <= int64(head); {
+		to := from + s.batchBlocks - 1
+		if to > int64(head) {
+			to = int64(head)
+		}
+
+		logs, err := client.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: big.NewInt(from),
+			ToBlock:   big.NewInt(to),
+			Addresses: addresses,
+		})
+		if err != nil {
+			return stored, fmt.Errorf("failed to fetch logs for blocks %d-%d: %w", from, to, err)
+		}
+
+		// Only blocks close to the head can still be reorganised
+		reorgFloor := int64(head) - indexerReorgWindow
+		seen := make(map[int64]string)
+		var events []models.OnchainEvent
+		for _, l := range logs {
+			if l.Removed {
+				continue
+			}
+			event, err := s.decodeLog(l)
+			if err != nil {
+				return stored, err
+			}
+			if event == nil {
+				continue
+			}
+			events = append(events, *event)
+			if event.BlockNumber > reorgFloor {
+				seen[event.BlockNumber] = event.BlockHash
+			}
+		}
+		if to > reorgFloor {
+			header, err := client.HeaderByNumber(ctx, big.NewInt(to))
+			if err != nil {
+				return stored, fmt.Errorf("failed to get block %d: %w", to, err)
+			}
+			seen[to] = header.Hash().Hex()
+		}
+
+		// Token ids are written back before the batch is saved, so a failed save
+		// indexes the blocks again and repeats the idempotent update
+		if err := s.verifyMintedTokens(events); err != nil {
+			return stored, err
+		}
+
+		blocks := make([]models.IndexedBlock, 0, len(seen))
+		for number, hash := range seen {
+			blocks = append(blocks, models.IndexedBlock{Number: number, Hash: hash})
+		}
+
+		if err := s.eventRepo.SaveBatch(contractIndexerName, chainID, events, blocks, to, reorgFloor); err != nil {
+			return stored, err
+		}
+		stored += len(events)
+		from = to + 1
+	}
+
+	return stored, nil
+}
+
+// verifyMintedTokens stores the real token id of every invoice NFT whose mint
+// transaction emitted one of the given InvoiceMinted events
+func (s *ContractIndexerService) verifyMintedTokens(events []models.OnchainEvent) error {
+	for _, event := range events {
+		if event.Contract != contractInvoiceNFT || event.EventName != "InvoiceMinted" || event.TokenID == nil {
+			continue
+		}
+		tokenID, ok := new(big.Int).SetString(*event.TokenID, 10)
+		if !ok || !tokenID.IsInt64() {
+			return fmt.Errorf("invalid token id %s minted in tx %s", *event.TokenID, event.TxHash)
+		}
+		if _, err := s.invoiceRepo.VerifyNFTToken(event.TxHash, tokenID.Int64()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findCommonAncestor compares the remembered block hashes with the canonical
+// chain, newest first. reorged is false when the newest one still matches.
+func (s *ContractIndexerService) findCommonAncestor(ctx context.Context) (ancestor int64, reorged bool, err error) {
+	blocks, err := s.eventRepo.FindIndexedBlocks(contractIndexerName, indexerReorgWindow)
+	if err != nil {
+		return 0, false, err
+	}
+	if len(blocks) == 0 {
+		return 0, false, nil
+	}
+
+	for i, b := range blocks {
+		header, err := s.blockchain.client.HeaderByNumber(ctx, big.NewInt(b.Number))
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to get block %d: %w", b.Number, err)
+		}
+		if header.Hash().Hex() == b.Hash {
+			return b.Number, i > 0, nil
+		}
+	}
+
+	// The reorg is deeper than the window; reindex from below the oldest block we kept
+	return blocks[len(blocks)-1].Number - 1, true, nil
+}
+
+// decodeLog turns a raw log into an OnchainEvent, or nil if it is not an event
+// of an indexed contract
+func (s *ContractIndexerService) decodeLog(l types.Log) (*models.OnchainEvent, error) {
+	contract, ok := s.contracts[l.Address]
+	if !ok || len(l.Topics) == 0 {
+		return nil, nil
+	}
+	event, ok := contract.events[l.Topics[0]]
+	if !ok {
+		return nil, nil
+	}
+
+	values := make(map[string]interface{})
+	if len(l.Data) > 0 {
+		if err := event.Inputs.UnpackIntoMap(values, l.Data); err != nil {
+			return nil, fmt.Errorf("failed to decode %s data in tx %s: %w", event.Name, l.TxHash.Hex(), err)
+		}
+	}
+	var indexed abi.Arguments
+	for _, input := range event.Inputs {
+		if input.Indexed {
+			indexed = append(indexed, input)
+		}
+	}
+	if err := abi.ParseTopicsIntoMap(values, indexed, l.Topics[1:]); err != nil {
+		return nil, fmt.Errorf("failed to decode %s topics in tx %s: %w", event.Name, l.TxHash.Hex(), err)
+	}
+
+	args := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		args[key] = normalizeEventArg(value)
+	}
+	encoded, err := json.Marshal(args)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.OnchainEvent{
+		ChainID:         s.cfg.ChainID,
+		Contract:        contract.name,
+		ContractAddress: l.Address.Hex(),
+		EventName:       event.Name,
+		BlockNumber:     int64(l.BlockNumber),
+		BlockHash:       l.BlockHash.Hex(),
+		TxHash:          l.TxHash.Hex(),
+		LogIndex:        int(l.Index),
+		Args:            encoded,
+	}
+	if tokenID, ok := values[indexerTokenIDArgKey].(*big.Int); ok {
+		str := tokenID.String()
+		result.TokenID = &str
+	}
+	return result, nil
+}
+
+// normalizeEventArg makes decoded values JSON friendly: integers become decimal
+// strings so uint256 amounts keep full precision
+func normalizeEventArg(value interface{}) interface{} {
+	switch v := value.(type) {
+	case *big.Int:
+		return v.String()
+	case common.Address:
+		return v.Hex()
+	case [32]byte:
+		return hexutil.Encode(v[:])
+	case []byte:
+		return hexutil.Encode(v)
+	case []*big.Int:
+		out := make([]string, len(v))
+		for i, n := range v {
+			out[i] = n.String()
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// GetPoolEvents returns the indexed contract events of a pool's invoice token
+func (s *ContractIndexerService) GetPoolEvents(poolID uuid.UUID) (*models.PoolOnchainEventsResponse, error) {
+	pool, err := s.fundingRepo.FindPoolByID(poolID)
+	if err != nil {
+		return nil, err
+	}
+	if pool == nil {
+		return nil, ErrPoolNotFound
+	}
+
+	response := &models.PoolOnchainEventsResponse{
+		PoolID:       pool.ID,
+		InvoiceID:    pool.InvoiceID,
+		ChainID:      s.cfg.ChainID,
+		Currency:     pool.PoolCurrency,
+		NFTContract:  s.cfg.InvoiceNFTContractAddr,
+		PoolContract: s.cfg.InvoicePoolContractAddr,
+		Events:       []models.OnchainEvent{},
+	}
+
+	lastBlock, _, err := s.eventRepo.GetCursor(contractIndexerName, s.cfg.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	response.IndexedBlock = lastBlock
+
+	nft, err := s.invoiceRepo.FindNFTByInvoiceID(pool.InvoiceID)
+	if err != nil {
+		return nil, err
+	}
+	// Until the indexer has seen the mint the stored token id is not the one
+	// on-chain, and its events would belong to another token
+	if nft == nil || nft.TokenID == nil || !nft.TokenVerified {
+		return response, nil
+	}
+	response.TokenID = nft.TokenID
+
+	invoice, err := s.invoiceRepo.FindByID(pool.InvoiceID)
+	if err != nil {
+		return nil, err
+	}
+	if invoice == nil {
+		return nil, errors.New("invoice not found")
+	}
+
+	events, err := s.eventRepo.FindByTokenID(s.cfg.ChainID, *nft.TokenID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range events {
+		// The minted face value is in the invoice currency, everything else in the pool's
+		currency := pool.PoolCurrency
+		if events[i].EventName == "InvoiceMinted" {
+			currency = invoiceCurrency(invoice)
+		}
+		if err := s.convertAmounts(&events[i], currency); err != nil {
+			return nil, err
+		}
+	}
+	if events != nil {
+		response.Events = events
+	}
+	return response, nil
+}
+
+// convertAmounts fills an event's Amounts from its token amount arguments
+func (s *ContractIndexerService) convertAmounts(event *models.OnchainEvent, currency string) error {
+	var args map[string]interface{}
+	if err := json.Unmarshal(event.Args, &args); err != nil {
+		return err
+	}
+	for _, key := range indexerAmountArgs {
+		raw, ok := args[key].(string)
+		if !ok {
+			continue
+		}
+		value, ok := new(big.Int).SetString(raw, 10)
+		if !ok {
+			continue
+		}
+		amount, err := s.units.FromChain(value, currency)
+		if err != nil {
+			return err
+		}
+		if event.Amounts == nil {
+			event.Amounts = make(map[string]money.Amount)
+		}
+		event.Amounts[key] = amount
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/config"
+	"github.com/vessel/backend/internal/iso3166"
+	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/repository"
+)
+
+var (
+	ErrUnknownCountry      = errors.New("not an ISO 3166 country")
+	ErrCountryTierNotFound = errors.New("country has no tier")
+	ErrCountryTierExists   = errors.New("country already has a tier")
+)
+
+// unratedFlag is shown for a country that cannot be resolved
+const unratedFlag = "🏳️"
+
+// CountryTierService is the source of buyer country risk. Tiers live in the
+// country_tiers table and are cached in process; the cache is dropped whenever
+// an admin changes a tier, and reloaded after the TTL so other instances catch up.
+type CountryTierService struct {
+	repo repository.CountryTierRepositoryInterface
+	ttl  time.Duration
+
+	mu       sync.RWMutex
+	tiers    map[string]models.CountryTier // By alpha-3 code
+	loadedAt time.Time
+}
+
+func NewCountryTierService(repo repository.CountryTierRepositoryInterface, cfg *config.Config) *CountryTierService {
+	ttl := time.Duration(cfg.CountryTierCacheTTLMinutes) * time.Minute
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+	return &CountryTierService{repo: repo, ttl: ttl}
+}
+
+// NormalizeCountry resolves an ISO alpha-2 or alpha-3 code or an English
+// country name to the ISO 3166-1 alpha-3 code
+func NormalizeCountry(country string) (string, bool) {
+	c, ok := iso3166.Lookup(country)
+	if !ok {
+		return "", false
+	}
+	return c.Alpha3, true
+}
+
+func (s *CountryTierService) cached() (map[string]models.CountryTier, error) {
+	s.mu.RLock()
+	tiers, fresh := s.tiers, s.tiers != nil && time.Since(s.loadedAt) < s.ttl
+	s.mu.RUnlock()
+	if fresh {
+		return tiers, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tiers != nil && time.Since(s.loadedAt) < s.ttl {
+		return s.tiers, nil
+	}
+	list, err := s.repo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	s.tiers = make(map[string]models.CountryTier, len(list))
+	for _, t := range list {
+		s.tiers[t.CountryCode] = t
+	}
+	s.loadedAt = time.Now()
+	return s.tiers, nil
+}
+
+func (s *CountryTierService) invalidate() {
+	s.mu.Lock()
+	s.tiers = nil
+	s.mu.Unlock()
+}
+
+// Lookup returns the tier of a buyer country given as code or name; false
+// when the country is not rated
+func (s *CountryTierService) Lookup(country string) (*models.CountryTier, bool, error) {
+	code, ok := NormalizeCountry(country)
+	if !ok {
+		return nil, false, nil
+	}
+	tiers, err := s.cached()
+	if err != nil {
+		return nil, false, err
+	}
+	t, ok := tiers[code]
+	if !ok {
+		return nil, false, nil
+	}
+	return &t, true, nil
+}
+
+// Tier is the risk tier of a buyer country, DefaultCountryTier when it is not rated
+func (s *CountryTierService) Tier(country string) (int, error) {
+	t, ok, err := s.Lookup(country)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return models.DefaultCountryTier, nil
+	}
+	return t.Tier, nil
+}
+
+// RiskLevel is low, medium or high for a buyer country. It is for display:
+// a failed lookup is logged and reported as the default tier.
+func (s *CountryTierService) RiskLevel(country string) string {
+	tier, err := s.Tier(country)
+	if err != nil {
+		fmt.Printf("[COUNTRY] Failed to load country tiers: %v\n", err)
+		tier = models.DefaultCountryTier
+	}
+	return models.CountryRiskLevel(tier)
+}
+
+// Flag is the emoji flag of a buyer country: the tier's own flag when it has
+// one, otherwise the ISO country's
+func (s *CountryTierService) Flag(country string) string {
+	t, ok, err := s.Lookup(country)
+	if err != nil {
+		fmt.Printf("[COUNTRY] Failed to load country tiers: %v\n", err)
+	}
+	if ok && t.FlagEmoji != nil && *t.FlagEmoji != "" {
+		return *t.FlagEmoji
+	}
+	if c, ok := iso3166.Lookup(country); ok {
+		return c.Flag()
+	}
+	return unratedFlag
+}
+
+// List returns every country tier, lowest risk first
+func (s *CountryTierService) List() ([]models.CountryTier, error) {
+	return s.repo.FindAll()
+}
+
+func (s *CountryTierService) Get(country string) (*models.CountryTier, error) {
+	code, ok := NormalizeCountry(country)
+	if !ok {
+		return nil, ErrUnknownCountry
+	}
+	t, err := s.repo.FindByCode(code)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, ErrCountryTierNotFound
+	}
+	return t, nil
+}
+
+// Create rates a country
+func (s *CountryTierService) Create(adminID uuid.UUID, req *models.CreateCountryTierRequest) (*models.CountryTier, error) {
+	country, ok := iso3166.Lookup(req.CountryCode)
+	if !ok {
+		return nil, ErrUnknownCountry
+	}
+	existing, err := s.repo.FindByCode(country.Alpha3)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrCountryTierExists
+	}
+
+	t := &models.CountryTier{
+		CountryCode: country.Alpha3,
+		CountryName: strings.TrimSpace(req.CountryName),
+		Tier:        req.Tier,
+		FlagEmoji:   req.FlagEmoji,
+	}
+	if t.CountryName == "" {
+		t.CountryName = country.Name
+	}
+	if t.FlagEmoji == nil {
+		flag := country.Flag()
+		t.FlagEmoji = &flag
+	}
+	if err := s.repo.Create(t, adminID, req.Reason); err != nil {
+		return nil, err
+	}
+	s.invalidate()
+
+	return t, nil
+}
+
+// Update changes the fields of a country tier that are set in req
+func (s *CountryTierService) Update(adminID uuid.UUID, country string, req *models.UpdateCountryTierRequest) (*models.CountryTier, error) {
+	t, err := s.Get(country)
+	if err != nil {
+		return nil, err
+	}
+	if req.CountryName != nil {
+		t.CountryName = strings.TrimSpace(*req.CountryName)
+	}
+	if req.Tier != nil {
+		t.Tier = *req.Tier
+	}
+	if req.FlagEmoji != nil {
+		t.FlagEmoji = req.FlagEmoji
+	}
+	if err := s.repo.Update(t, adminID, req.Reason); err != nil {
+		return nil, err
+	}
+	s.invalidate()
+
+	return t, nil
+}
+
+// Delete removes a country's tier; its buyers are then graded with the default tier
+func (s *CountryTierService) Delete(adminID uuid.UUID, country string, reason *string) error {
+	t, err := s.Get(country)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(t.CountryCode, adminID, reason); err != nil {
+		return err
+	}
+	s.invalidate()
+
+	return nil
+}
+
+// History lists the changes to a country's tier, newest first
+func (s *CountryTierService) History(country string, page, perPage int) (*models.CountryTierHistoryResponse, error) {
+	code, ok := NormalizeCountry(country)
+	if !ok {
+		return nil, ErrUnknownCountry
+	}
+	changes, total, err := s.repo.FindHistory(code, page, perPage)
+	if err != nil {
+		return nil, err
+	}
+	if changes == nil {
+		changes = []models.CountryTierChange{}
+	}
+	return &models.CountryTierHistoryResponse{
+		Changes:    changes,
+		Total:      total,
+		Page:       page,
+		PerPage:    perPage,
+		TotalPages: models.CalculateTotalPages(total, perPage),
+	}, nil
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/repository"
+)
+
+// Points each credit event adds to a mitra's score (0-100)
+const (
+	creditPointsRepaid      = 5
+	creditPointsRepaidLate  = 1  // The late payment event has already cost points
+	creditPointsVeryLate    = -5 // Repaid more than creditVeryLateDays after the due date
+	creditPointsLatePayment = -5
+	creditPointsDefault     = -20
+	creditVeryLateDays      = 30
+)
+
+// creditHistoryLimit is how many score changes the credit score endpoints return
+const creditHistoryLimit = 20
+
+// CreditScoreService keeps each mitra's credit score. Repayments, late
+// payments and defaults move the score inside the unit of work that records
+// them; grading and funding limits read it.
+type CreditScoreService struct {
+	repo repository.CreditScoreRepositoryInterface
+}
+
+func NewCreditScoreService(repo repository.CreditScoreRepositoryInterface) *CreditScoreService {
+	return &CreditScoreService{repo: repo}
+}
+
+// daysPastDue counts whole days from the due date to at, 0 when at is not after it
+func daysPastDue(dueDate, at time.Time) int {
+	due := time.Date(dueDate.Year(), dueDate.Month(), dueDate.Day(), 0, 0, 0, 0, time.UTC)
+	at = at.UTC()
+	day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+	return max(int(day.Sub(due).Hours()/24), 0)
+}
+
+// settlementEvent is the credit event of an invoice settled at the given time
+func settlementEvent(invoice *models.Invoice, at time.Time) (models.CreditEvent, int) {
+	if late := daysPastDue(invoice.DueDate, at); late > 0 {
+		return models.CreditEventRepaidLate, late
+	}
+	return models.CreditEventRepaid, 0
+}
+
+// recordCreditEvent moves the credit score of the invoice's mitra and records
+// why. An event already recorded for the invoice is skipped, so callers can
+// run it on every pass. repo is normally bound to the caller's unit of work.
+func recordCreditEvent(repo repository.CreditScoreRepositoryInterface, invoice *models.Invoice, event models.CreditEvent, daysLate int) error {
+	cs, err := repo.FindByUserIDForUpdate(invoice.ExporterID)
+	if err != nil {
+		return err
+	}
+	previous := cs.Score
+
+	var reason string
+	switch event {
+	case models.CreditEventRepaid, models.CreditEventRepaidLate:
+		cs.TotalInvoices++
+		cs.SuccessfulInvoices++
+		cs.TotalVolume += invoice.Amount
+		cs.AvgPaymentDelay = int(math.Round(float64(cs.AvgPaymentDelay*(cs.SuccessfulInvoices-1)+daysLate) / float64(cs.SuccessfulInvoices)))
+		switch {
+		case event == models.CreditEventRepaid:
+			cs.Score += creditPointsRepaid
+			reason = fmt.Sprintf("Invoice %s repaid on time", invoice.InvoiceNumber)
+		case daysLate > creditVeryLateDays:
+			cs.Score += creditPointsVeryLate
+			reason = fmt.Sprintf("Invoice %s repaid %d days late", invoice.InvoiceNumber, daysLate)
+		default:
+			cs.Score += creditPointsRepaidLate
+			reason = fmt.Sprintf("Invoice %s repaid %d days late", invoice.InvoiceNumber, daysLate)
+		}
+	case models.CreditEventLatePayment:
+		cs.Score += creditPointsLatePayment
+		reason = fmt.Sprintf("Invoice %s past due, late charges started", invoice.InvoiceNumber)
+	case models.CreditEventDefault:
+		cs.TotalInvoices++
+		cs.DefaultedInvoices++
+		cs.Score += creditPointsDefault
+		reason = fmt.Sprintf("Invoice %s declared defaulted", invoice.InvoiceNumber)
+	default:
+		return fmt.Errorf("unknown credit event %q", event)
+	}
+	cs.Score = min(max(cs.Score, 0), 100)
+
+	invoiceID := invoice.ID
+	recorded, err := repo.CreateHistory(&models.CreditScoreHistory{
+		UserID:        invoice.ExporterID,
+		PreviousScore: previous,
+		NewScore:      cs.Score,
+		Reason:        reason,
+		Event:         &event,
+		InvoiceID:     &invoiceID,
+	})
+	if err != nil || !recorded {
+		return err
+	}
+	if err := repo.Update(cs); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetCreditScore returns the mitra's score with its latest changes, where it
+// ranks among mitras and its risk level
+func (s *CreditScoreService) GetCreditScore(userID uuid.UUID) (*models.CreditScoreResponse, error) {
+	cs, err := s.repo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if cs == nil {
+		cs = &models.CreditScore{UserID: userID, Score: models.DefaultCreditScore}
+	}
+
+	history, err := s.repo.FindHistory(userID, creditHistoryLimit)
+	if err != nil {
+		return nil, err
+	}
+	if history == nil {
+		history = []models.CreditScoreHistory{}
+	}
+	percentile, err := s.repo.Percentile(cs.Score)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CreditScoreResponse{
+		Score:      *cs,
+		History:    history,
+		Percentile: math.Round(percentile*100) / 100,
+		RiskLevel:  models.CalculateRiskLevel(cs.Score),
+	}, nil
+}
+
+// Score is the mitra's current credit score
+func (s *CreditScoreService) Score(userID uuid.UUID) (int, error) {
+	cs, err := s.repo.FindByUserID(userID)
+	if err != nil {
+		return 0, err
+	}
+	if cs == nil {
+		return models.DefaultCreditScore, nil
+	}
+	return cs.Score, nil
+}
+
+// FundingLimit adjusts the advance limit of a buyer relationship by the
+// mitra's credit score
+func (s *CreditScoreService) FundingLimit(userID uuid.UUID, base float64) (float64, error) {
+	score, err := s.Score(userID)
+	if err != nil {
+		return 0, err
+	}
+	return models.CreditFundingLimit(score, base), nil
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/vessel/backend/internal/config"
 	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/money"
 )
 
 // CurrencyService handles currency conversion with buffer rate
@@ -70,8 +71,8 @@ func (s *CurrencyService) GetLockedExchangeRate(req *models.CurrencyConversionRe
 	// This gives exporter slightly less to protect against rate fluctuation
 	lockedRate := realTimeRate * (1 - bufferPercentage)
 
-	// Convert amount
-	convertedAmount := req.Amount * lockedRate
+	// Convert amount, rounded to whole rupiah
+	convertedAmount := req.Amount.MulFactor(lockedRate).RoundTo("IDR")
 
 	return &models.CurrencyConversionResponse{
 		OriginalCurrency: req.OriginalCurrency,
@@ -98,13 +99,13 @@ func (s *CurrencyService) GetSupportedCurrencies() []models.SupportedCurrency {
 }
 
 // CalculateEstimatedDisbursement calculates net disbursement after platform fee
-func (s *CurrencyService) CalculateEstimatedDisbursement(idrAmount float64) *models.EstimatedDisbursement {
+func (s *CurrencyService) CalculateEstimatedDisbursement(idrAmount money.Amount) *models.EstimatedDisbursement {
 	platformFeePercentage := s.cfg.PlatformFeePercentage
 	if platformFeePercentage == 0 {
 		platformFeePercentage = 2.0 // Default 2%
 	}
 
-	platformFee := idrAmount * (platformFeePercentage / 100)
+	platformFee := idrAmount.MulRate(platformFeePercentage).RoundTo("IDR")
 	netDisbursement := idrAmount - platformFee
 
 	return &models.EstimatedDisbursement{
@@ -0,0 +1,441 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/config"
+	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/money"
+	"github.com/vessel/backend/internal/repository"
+)
+
+var (
+	ErrPoolNotDefaultable = errors.New("only disbursed pools can be declared in default")
+	ErrInvoiceNotPastDue  = errors.New("invoice is not past its due date")
+	ErrDefaultNotDeclared = errors.New("pool has not been declared in default")
+	ErrDefaultNotFound    = errors.New("pool has no default case")
+)
+
+// DefaultService runs the default process of disbursed pools: overdue detection
+// after a grace period, the admin declaration with its tranche loss allocation,
+// and the distribution of anything recovered afterwards
+type DefaultService struct {
+	defaultRepo   repository.DefaultRepositoryInterface
+	fundingRepo   repository.FundingRepositoryInterface
+	invoiceRepo   repository.InvoiceRepositoryInterface
+	userRepo      repository.UserRepositoryInterface
+	ledgerService *LedgerService
+	emailService  *EmailService
+	uow           repository.UnitOfWorkInterface
+	cfg           *config.Config
+}
+
+func NewDefaultService(
+	defaultRepo repository.DefaultRepositoryInterface,
+	fundingRepo repository.FundingRepositoryInterface,
+	invoiceRepo repository.InvoiceRepositoryInterface,
+	userRepo repository.UserRepositoryInterface,
+	ledgerService *LedgerService,
+	emailService *EmailService,
+	uow repository.UnitOfWorkInterface,
+	cfg *config.Config,
+) *DefaultService {
+	return &DefaultService{
+		defaultRepo:   defaultRepo,
+		fundingRepo:   fundingRepo,
+		invoiceRepo:   invoiceRepo,
+		userRepo:      userRepo,
+		ledgerService: ledgerService,
+		emailService:  emailService,
+		uow:           uow,
+		cfg:           cfg,
+	}
+}
+
+// DetectOverdue flags disbursed pools still unpaid after the grace period and
+// reminds their exporters. It returns the number of newly overdue pools.
+func (s *DefaultService) DetectOverdue() (int, error) {
+	overdue, err := s.defaultRepo.DetectOverdue(time.Now(), s.cfg.DefaultGracePeriodDays)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, d := range overdue {
+		if s.emailService == nil {
+			continue
+		}
+		invoice, err := s.invoiceRepo.FindByID(d.InvoiceID)
+		if err != nil || invoice == nil {
+			continue
+		}
+		exporter, err := s.userRepo.FindByID(invoice.ExporterID)
+		if err != nil || exporter == nil {
+			continue
+		}
+		if err := s.emailService.SendRepaymentOverdueEmail(exporter.Email, invoice.InvoiceNumber, invoice.DueDate); err != nil {
+			fmt.Printf("[DEFAULT] Failed to send overdue email for pool %s: %v\n", d.PoolID, err)
+		}
+	}
+	return len(overdue), nil
+}
+
+// ListDefaults returns default cases, optionally filtered by status
+func (s *DefaultService) ListDefaults(status string) ([]models.PoolDefault, error) {
+	defaults, err := s.defaultRepo.FindByStatus(status)
+	if err != nil {
+		return nil, err
+	}
+	if defaults == nil {
+		defaults = []models.PoolDefault{}
+	}
+	return defaults, nil
+}
+
+// GetDefault returns a pool's default case with its allocations and recoveries
+func (s *DefaultService) GetDefault(poolID uuid.UUID) (*models.PoolDefault, error) {
+	d, err := s.defaultRepo.FindByPoolID(poolID)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, ErrDefaultNotFound
+	}
+	if d.Allocations, err = s.defaultRepo.FindAllocations(d.ID); err != nil {
+		return nil, err
+	}
+	if d.Recoveries, err = s.defaultRepo.FindRecoveries(d.ID); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// DeclareDefault declares a disbursed pool in default. Every active investment
+// becomes defaulted with the principal not yet repaid by installments as the
+// loss until money is recovered, and the pool is marked defaulted on-chain.
+func (s *DefaultService) DeclareDefault(poolID, adminID uuid.UUID, req *models.DeclareDefaultRequest) (*models.PoolDefault, error) {
+	var d *models.PoolDefault
+	var invoice *models.Invoice
+	err := s.uow.Do(func(repos *repository.Repositories) error {
+		pool, err := repos.Funding.FindPoolByIDForUpdate(poolID)
+		if err != nil {
+			return err
+		}
+		if pool == nil {
+			return ErrPoolNotFound
+		}
+		if pool.Status != models.PoolStatusDisbursed {
+			return ErrPoolNotDefaultable
+		}
+		invoice, err = repos.Invoices.FindByID(pool.InvoiceID)
+		if err != nil {
+			return err
+		}
+		if invoice == nil {
+			return errors.New("invoice not found")
+		}
+		now := time.Now()
+		if now.Before(invoice.DueDate) {
+			return ErrInvoiceNotPastDue
+		}
+
+		d, err = repos.Defaults.FindByPoolIDForUpdate(poolID)
+		if err != nil {
+			return err
+		}
+		if d == nil {
+			d = &models.PoolDefault{
+				PoolID:       pool.ID,
+				InvoiceID:    pool.InvoiceID,
+				Currency:     pool.PoolCurrency,
+				DueDate:      invoice.DueDate,
+				OverdueSince: invoice.DueDate.AddDate(0, 0, s.cfg.DefaultGracePeriodDays),
+				DetectedAt:   &now,
+			}
+		}
+
+		investments, err := repos.Funding.FindInvestmentsByPool(pool.ID)
+		if err != nil {
+			return err
+		}
+		d.Allocations = nil
+		d.TotalClaim, d.TotalRecovered, d.TotalLoss = 0, 0, 0
+		var paidBefore []*money.Amount
+		for _, inv := range investments {
+			if inv.Status != models.InvestmentStatusActive {
+				continue
+			}
+			// Installments received before the default repay principal first and
+			// are not part of the claim; recoveries start from zero
+			var paid money.Amount
+			if inv.ActualReturn != nil {
+				paid = *inv.ActualReturn
+			}
+			a := models.DefaultAllocation{
+				InvestmentID: inv.ID,
+				InvestorID:   inv.InvestorID,
+				Tranche:      inv.Tranche,
+				Principal:    money.Max(inv.Amount-paid, 0),
+				Claim:        money.Max(inv.ExpectedReturn-paid, 0),
+			}
+			a.Loss = a.Principal
+			d.Allocations = append(d.Allocations, a)
+			paidBefore = append(paidBefore, inv.ActualReturn)
+			d.TotalClaim += a.Claim
+			d.TotalLoss += a.Loss
+		}
+
+		reason := req.Reason
+		d.Status = models.DefaultStatusDeclared
+		d.DeclaredAt = &now
+		d.DeclaredBy = &adminID
+		d.Reason = &reason
+		if err := repos.Defaults.Save(d); err != nil {
+			return err
+		}
+
+		for i := range d.Allocations {
+			a := &d.Allocations[i]
+			a.DefaultID = d.ID
+			if err := repos.Defaults.CreateAllocation(a); err != nil {
+				return err
+			}
+			if err := repos.Funding.UpdateInvestmentStatus(a.InvestmentID, models.InvestmentStatusDefaulted, paidBefore[i]); err != nil {
+				return err
+			}
+		}
+
+		if err := repos.Funding.UpdatePoolStatus(pool.ID, models.PoolStatusDefaulted); err != nil {
+			return err
+		}
+		if err := repos.Invoices.UpdateStatus(pool.InvoiceID, models.StatusDefaulted); err != nil {
+			return err
+		}
+		if err := recordCreditEvent(repos.CreditScores, invoice, models.CreditEventDefault, 0); err != nil {
+			return err
+		}
+
+		// On-Chain Transparency: InvoicePool.markDefaulted also moves the InvoiceNFT
+		return enqueueOnchain(repos, models.OnchainActionMarkDefaulted, pool.InvoiceID, &pool.ID, nil, &models.OnchainPayload{})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyDeclared(invoice, d)
+	return d, nil
+}
+
+// RecordRecovery distributes money collected on a defaulted pool
+func (s *DefaultService) RecordRecovery(poolID, adminID uuid.UUID, req *models.RecordRecoveryRequest) (*models.DefaultRecovery, error) {
+	var recovery *models.DefaultRecovery
+	var credits []recoveryCredit
+	var invoice *models.Invoice
+	var pool *models.FundingPool
+	err := s.uow.Do(func(repos *repository.Repositories) error {
+		var err error
+		pool, err = repos.Funding.FindPoolByIDForUpdate(poolID)
+		if err != nil {
+			return err
+		}
+		if pool == nil {
+			return ErrPoolNotFound
+		}
+		invoice, err = repos.Invoices.FindByID(pool.InvoiceID)
+		if err != nil {
+			return err
+		}
+		if invoice == nil {
+			return errors.New("invoice not found")
+		}
+
+		var note *string
+		if req.Note != "" {
+			note = &req.Note
+		}
+		recovery, credits, err = applyRecovery(repos, s.ledgerService, pool, invoice, req.Amount, note, &adminID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	notifyRecovery(s.emailService, s.userRepo, invoice.InvoiceNumber, pool.PoolCurrency, credits)
+	return recovery, nil
+}
+
+// recoveryCredit is what one investor received from a recovery
+type recoveryCredit struct {
+	allocation models.DefaultAllocation
+	amount     money.Amount
+}
+
+// applyRecovery pays a recovery on a defaulted pool inside the caller's unit of
+// work. The amount is shared over what each claim still lacks, down the
+// tranches in seniority order and pro-rata by claim within a tranche, so the
+// most junior tranche takes losses first.
+// Only what exceeds every remaining claim goes to the exporter.
+func applyRecovery(repos *repository.Repositories, ledger *LedgerService, pool *models.FundingPool, invoice *models.Invoice, amount money.Amount, note *string, recordedBy *uuid.UUID) (*models.DefaultRecovery, []recoveryCredit, error) {
+	d, err := repos.Defaults.FindByPoolIDForUpdate(pool.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if d == nil || d.Status == models.DefaultStatusOverdue {
+		return nil, nil, ErrDefaultNotDeclared
+	}
+	allocations, err := repos.Defaults.FindAllocations(d.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	shares := allocateRecovery(pool, allocations, amount)
+
+	var credits []recoveryCredit
+	var payouts []LedgerPayout
+	var toInvestors money.Amount
+	d.TotalRecovered, d.TotalLoss = 0, 0
+	fullyRecovered := true
+	for i := range allocations {
+		a := &allocations[i]
+		credit := shares[i]
+		a.Recovered += credit
+		a.Loss = money.Max(a.Principal-a.Recovered, 0)
+		d.TotalRecovered += a.Recovered
+		d.TotalLoss += a.Loss
+		if a.Recovered < a.Claim {
+			fullyRecovered = false
+		}
+		if credit <= 0 {
+			continue
+		}
+
+		if err := repos.Defaults.UpdateAllocation(a); err != nil {
+			return nil, nil, err
+		}
+		inv, err := repos.Funding.FindInvestmentByIDForUpdate(a.InvestmentID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if inv == nil {
+			return nil, nil, fmt.Errorf("investment %s not found", a.InvestmentID)
+		}
+		actualReturn := credit
+		if inv.ActualReturn != nil {
+			actualReturn += *inv.ActualReturn
+		}
+		status := models.InvestmentStatusDefaulted
+		if a.Recovered >= a.Claim {
+			status = models.InvestmentStatusRepaid
+		}
+		if err := repos.Funding.UpdateInvestmentStatus(a.InvestmentID, status, &actualReturn); err != nil {
+			return nil, nil, err
+		}
+
+		investorID := a.InvestorID
+		tx := &models.Transaction{
+			InvoiceID: &invoice.ID,
+			UserID:    &investorID,
+			Type:      models.TxTypeDefaultRecovery,
+			Amount:    credit,
+			Currency:  pool.PoolCurrency,
+			Status:    models.TxStatusConfirmed,
+			Notes:     stringPtr(fmt.Sprintf("Recovery on defaulted invoice %s (%s tranche)", invoice.InvoiceNumber, a.Tranche)),
+		}
+		if err := repos.Transactions.Create(tx); err != nil {
+			return nil, nil, err
+		}
+
+		payouts = append(payouts, LedgerPayout{UserID: a.InvestorID, Amount: credit})
+		credits = append(credits, recoveryCredit{allocation: *a, amount: credit})
+		toInvestors += credit
+	}
+
+	// The waterfall leaves money over only once every claim is met
+	excess := amount - toInvestors
+	if excess > 0 {
+		tx := &models.Transaction{
+			InvoiceID: &invoice.ID,
+			UserID:    &invoice.ExporterID,
+			Type:      models.TxTypeRepaymentExcess,
+			Amount:    excess,
+			Currency:  pool.PoolCurrency,
+			Status:    models.TxStatusConfirmed,
+			Notes:     stringPtr("Recovery beyond investor claims on defaulted invoice"),
+		}
+		if err := repos.Transactions.Create(tx); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := ledger.WithRepository(repos.Ledger).RecordRecovery(pool.ID, payouts, invoice.ExporterID, excess); err != nil {
+		return nil, nil, fmt.Errorf("failed to post recovery: %w", err)
+	}
+
+	if fullyRecovered {
+		d.Status = models.DefaultStatusRecovered
+	}
+	if err := repos.Defaults.UpdateTotals(d); err != nil {
+		return nil, nil, err
+	}
+
+	recovery := &models.DefaultRecovery{
+		DefaultID:     d.ID,
+		Amount:        amount,
+		ToInvestors:   toInvestors,
+		ExcessToMitra: excess,
+		Note:          note,
+		RecordedBy:    recordedBy,
+	}
+	if err := repos.Defaults.CreateRecovery(recovery); err != nil {
+		return nil, nil, err
+	}
+
+	return recovery, credits, nil
+}
+
+// allocateRecovery returns each allocation's share of a new recovery, capped at
+// what its claim still lacks. A senior tranche is made whole before the next
+// tranche receives anything.
+func allocateRecovery(pool *models.FundingPool, allocations []models.DefaultAllocation, amount money.Amount) []money.Amount {
+	tranches := make([]models.TrancheType, len(allocations))
+	remaining := make([]money.Amount, len(allocations))
+	for i, a := range allocations {
+		tranches[i] = a.Tranche
+		remaining[i] = money.Max(a.Claim-a.Recovered, 0)
+	}
+	return waterfall(pool, tranches, remaining, amount)
+}
+
+// notifyDeclared emails every investor of a defaulted pool
+func (s *DefaultService) notifyDeclared(invoice *models.Invoice, d *models.PoolDefault) {
+	if s.emailService == nil {
+		return
+	}
+	for _, a := range d.Allocations {
+		investor, err := s.userRepo.FindByID(a.InvestorID)
+		if err != nil || investor == nil {
+			continue
+		}
+		if err := s.emailService.SendDefaultDeclaredEmail(investor.Email, invoice.InvoiceNumber, a.Tranche, a.Principal, d.Currency); err != nil {
+			fmt.Printf("[DEFAULT] Failed to send default email for investment %s: %v\n", a.InvestmentID, err)
+		}
+	}
+}
+
+// notifyRecovery emails every investor credited by a recovery
+func notifyRecovery(emailService *EmailService, userRepo repository.UserRepositoryInterface, invoiceNumber, currency string, credits []recoveryCredit) {
+	if emailService == nil {
+		return
+	}
+	for _, c := range credits {
+		investor, err := userRepo.FindByID(c.allocation.InvestorID)
+		if err != nil || investor == nil {
+			continue
+		}
+		if err := emailService.SendDefaultRecoveryEmail(investor.Email, invoiceNumber, c.amount, c.allocation.Recovered, c.allocation.Loss, currency); err != nil {
+			fmt.Printf("[DEFAULT] Failed to send recovery email for investment %s: %v\n", c.allocation.InvestmentID, err)
+		}
+	}
+}
@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"html/template"
 	"net/smtp"
+	"time"
 
 	"github.com/vessel/backend/internal/config"
 	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/money"
 )
 
 type EmailService struct {
@@ -114,6 +116,160 @@ func (s *EmailService) SendInvoiceApprovalEmail(email, invoiceNumber, grade stri
 	return s.sendEmail(email, subject, body)
 }
 
+// SendPoolRefundEmail notifies an investor that an expired pool refunded their investment
+func (s *EmailService) SendPoolRefundEmail(email, invoiceNumber string, amount money.Amount, currency string) error {
+	subject := fmt.Sprintf("Funding Pool %s Expired - Investment Refunded - VESSEL", invoiceNumber)
+	body := fmt.Sprintf(`
+		<html>
+		<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+			<div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+				<h2 style="color: #2563eb;">Investment Refunded</h2>
+				<p>The funding pool for invoice <strong>%s</strong> reached its deadline without being fully funded.</p>
+				<div style="background-color: #eff6ff; border-left: 4px solid #2563eb; padding: 15px; margin: 20px 0;">
+					<p><strong>Refunded Amount:</strong> %s %s</p>
+				</div>
+				<p>The full amount has been returned to your VESSEL balance and is available to invest again.</p>
+				<hr style="border: none; border-top: 1px solid #eee; margin: 20px 0;">
+				<p style="color: #666; font-size: 12px;">
+					This email was sent by VESSEL Platform.
+				</p>
+			</div>
+		</body>
+		</html>
+	`, invoiceNumber, currency, amount)
+
+	return s.sendEmail(email, subject, body)
+}
+
+// SendPoolExpiredEmail notifies the exporter that their funding pool expired unfilled
+func (s *EmailService) SendPoolExpiredEmail(email, invoiceNumber string, funded, target money.Amount, currency string) error {
+	subject := fmt.Sprintf("Funding Pool %s Expired - VESSEL", invoiceNumber)
+	body := fmt.Sprintf(`
+		<html>
+		<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+			<div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+				<h2 style="color: #dc2626;">Funding Pool Expired</h2>
+				<p>The funding pool for invoice <strong>%s</strong> reached its deadline without enough funding.</p>
+				<div style="background-color: #fef2f2; border-left: 4px solid #dc2626; padding: 15px; margin: 20px 0;">
+					<p><strong>Funded:</strong> %s %s of %s %s</p>
+				</div>
+				<p>All investors have been refunded and no advance will be disbursed for this invoice.</p>
+				<hr style="border: none; border-top: 1px solid #eee; margin: 20px 0;">
+				<p style="color: #666; font-size: 12px;">
+					This email was sent by VESSEL Platform.
+				</p>
+			</div>
+		</body>
+		</html>
+	`, invoiceNumber, currency, funded, currency, target)
+
+	return s.sendEmail(email, subject, body)
+}
+
+// SendPoolPartiallyFundedEmail notifies an investor that an expired pool was
+// disbursed at the amount it had raised instead of its target
+func (s *EmailService) SendPoolPartiallyFundedEmail(email, invoiceNumber string, invested, funded, target money.Amount, currency string) error {
+	subject := fmt.Sprintf("Funding Pool %s Closed Partially Funded - VESSEL", invoiceNumber)
+	body := fmt.Sprintf(`
+		<html>
+		<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+			<div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+				<h2 style="color: #2563eb;">Pool Closed Partially Funded</h2>
+				<p>The funding pool for invoice <strong>%s</strong> reached its deadline before being fully funded and has been disbursed at the amount it raised.</p>
+				<div style="background-color: #eff6ff; border-left: 4px solid #2563eb; padding: 15px; margin: 20px 0;">
+					<p><strong>Your Investment:</strong> %s %s</p>
+					<p><strong>Pool Funded:</strong> %s %s of %s %s</p>
+				</div>
+				<p>Your investment stays active and earns its tranche return until the invoice is repaid.</p>
+				<hr style="border: none; border-top: 1px solid #eee; margin: 20px 0;">
+				<p style="color: #666; font-size: 12px;">
+					This email was sent by VESSEL Platform.
+				</p>
+			</div>
+		</body>
+		</html>
+	`, invoiceNumber, currency, invested, currency, funded, currency, target)
+
+	return s.sendEmail(email, subject, body)
+}
+
+// SendRepaymentOverdueEmail reminds the exporter that an invoice is past its grace period
+func (s *EmailService) SendRepaymentOverdueEmail(email, invoiceNumber string, dueDate time.Time) error {
+	subject := fmt.Sprintf("Invoice %s Repayment Overdue - VESSEL", invoiceNumber)
+	body := fmt.Sprintf(`
+		<html>
+		<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+			<div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+				<h2 style="color: #dc2626;">Repayment Overdue</h2>
+				<p>Invoice <strong>%s</strong> was due on <strong>%s</strong> and has not been repaid.</p>
+				<div style="background-color: #fef2f2; border-left: 4px solid #dc2626; padding: 15px; margin: 20px 0;">
+					<p>The grace period has ended. Please repay immediately or contact us, otherwise the invoice will be declared in default.</p>
+				</div>
+				<hr style="border: none; border-top: 1px solid #eee; margin: 20px 0;">
+				<p style="color: #666; font-size: 12px;">
+					This email was sent by VESSEL Platform.
+				</p>
+			</div>
+		</body>
+		</html>
+	`, invoiceNumber, dueDate.Format("02 Jan 2006"))
+
+	return s.sendEmail(email, subject, body)
+}
+
+// SendDefaultDeclaredEmail tells an investor that a pool they invested in defaulted
+func (s *EmailService) SendDefaultDeclaredEmail(email, invoiceNumber string, tranche models.TrancheType, principal money.Amount, currency string) error {
+	subject := fmt.Sprintf("Invoice %s Declared in Default - VESSEL", invoiceNumber)
+	body := fmt.Sprintf(`
+		<html>
+		<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+			<div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+				<h2 style="color: #dc2626;">Invoice Declared in Default</h2>
+				<p>Invoice <strong>%s</strong> was not repaid and has been declared in default.</p>
+				<div style="background-color: #fef2f2; border-left: 4px solid #dc2626; padding: 15px; margin: 20px 0;">
+					<p><strong>Tranche:</strong> %s</p>
+					<p><strong>Principal at risk:</strong> %s %s</p>
+				</div>
+				<p>We are pursuing recovery. Any money collected is paid to the most senior tranche first, then each junior tranche in turn, and credited to your VESSEL balance.</p>
+				<hr style="border: none; border-top: 1px solid #eee; margin: 20px 0;">
+				<p style="color: #666; font-size: 12px;">
+					This email was sent by VESSEL Platform.
+				</p>
+			</div>
+		</body>
+		</html>
+	`, invoiceNumber, tranche, currency, principal)
+
+	return s.sendEmail(email, subject, body)
+}
+
+// SendDefaultRecoveryEmail tells an investor that a recovery was credited to them
+func (s *EmailService) SendDefaultRecoveryEmail(email, invoiceNumber string, amount, totalRecovered, remainingLoss money.Amount, currency string) error {
+	subject := fmt.Sprintf("Recovery on Invoice %s Credited - VESSEL", invoiceNumber)
+	body := fmt.Sprintf(`
+		<html>
+		<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+			<div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+				<h2 style="color: #2563eb;">Recovery Credited</h2>
+				<p>Money was recovered on defaulted invoice <strong>%s</strong>.</p>
+				<div style="background-color: #eff6ff; border-left: 4px solid #2563eb; padding: 15px; margin: 20px 0;">
+					<p><strong>Credited now:</strong> %s %s</p>
+					<p><strong>Recovered in total:</strong> %s %s</p>
+					<p><strong>Remaining principal loss:</strong> %s %s</p>
+				</div>
+				<p>The amount is available in your VESSEL balance.</p>
+				<hr style="border: none; border-top: 1px solid #eee; margin: 20px 0;">
+				<p style="color: #666; font-size: 12px;">
+					This email was sent by VESSEL Platform.
+				</p>
+			</div>
+		</body>
+		</html>
+	`, invoiceNumber, currency, amount, currency, totalRecovered, currency, remainingLoss)
+
+	return s.sendEmail(email, subject, body)
+}
+
 // SendImporterPaymentNotification sends payment notification to importer (buyer)
 // This is for non-users to pay invoice via payment ID
 func (s *EmailService) SendImporterPaymentNotification(email string, data *models.PaymentNotificationData) error {
@@ -152,7 +308,7 @@ func (s *EmailService) SendImporterPaymentNotification(email string, data *model
 			</div>
 		</body>
 		</html>
-	`, data.BuyerName, data.ExporterName, data.InvoiceNumber, data.Currency, data.AmountDue,
+	`, data.BuyerName, data.ExporterName, data.InvoiceNumber, data.Currency, data.AmountDue.Float64(),
 		data.DueDate.Format("02 January 2006"), data.PaymentID, data.PaymentLink)
 
 	return s.sendEmail(email, subject, body)
@@ -174,7 +330,7 @@ func (s *EmailService) SendExporterPaymentNotification(email string, data *model
 				<td style="padding: 8px; border-bottom: 1px solid #e5e7eb;">%.2f%%</td>
 				<td style="padding: 8px; border-bottom: 1px solid #e5e7eb;">%s %.2f</td>
 			</tr>
-		`, i+1, inv.Tranche, data.Currency, inv.Amount, inv.InterestRate, data.Currency, inv.ExpectedReturn)
+		`, i+1, inv.Tranche, data.Currency, inv.Amount.Float64(), inv.InterestRate, data.Currency, inv.ExpectedReturn.Float64())
 	}
 
 	body := fmt.Sprintf(`
@@ -257,10 +413,10 @@ func (s *EmailService) SendExporterPaymentNotification(email string, data *model
 		</html>
 	`, data.ExporterName, data.BuyerName,
 		data.InvoiceNumber,
-		data.Currency, data.PrincipalAmount,
-		data.Currency, data.TotalInterest,
-		data.Currency, data.PlatformFee,
-		data.Currency, data.TotalAmountDue,
+		data.Currency, data.PrincipalAmount.Float64(),
+		data.Currency, data.TotalInterest.Float64(),
+		data.Currency, data.PlatformFee.Float64(),
+		data.Currency, data.TotalAmountDue.Float64(),
 		data.DueDate.Format("02 January 2006"),
 		data.PaymentID,
 		investorRows,
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/money"
 )
 
 // EscrowService is a dummy escrow service for MVP
@@ -19,53 +20,53 @@ func NewEscrowService() *EscrowService {
 
 // DisbursementTarget represents a target for fund disbursement
 type DisbursementTarget struct {
-	InvestorID     uuid.UUID `json:"investor_id"`
-	WalletAddress  string    `json:"wallet_address"`
-	Amount         float64   `json:"amount"`         // Principal + Return
-	Principal      float64   `json:"principal"`
-	ReturnAmount   float64   `json:"return_amount"`
-	Tranche        string    `json:"tranche"`
-	Currency       string    `json:"currency"`
+	InvestorID    uuid.UUID    `json:"investor_id"`
+	WalletAddress string       `json:"wallet_address"`
+	Amount        money.Amount `json:"amount"` // Principal + Return
+	Principal     money.Amount `json:"principal"`
+	ReturnAmount  money.Amount `json:"return_amount"`
+	Tranche       string       `json:"tranche"`
+	Currency      string       `json:"currency"`
 }
 
 // DisbursementInstruction represents the instruction sent to escrow
 type DisbursementInstruction struct {
-	ID              uuid.UUID            `json:"id"`
-	PoolID          uuid.UUID            `json:"pool_id"`
-	InvoiceID       uuid.UUID            `json:"invoice_id"`
-	TotalAmount     float64              `json:"total_amount"`
-	Currency        string               `json:"currency"`
-	Targets         []DisbursementTarget `json:"targets"`
-	Status          string               `json:"status"` // pending, processing, completed, failed
-	CreatedAt       time.Time            `json:"created_at"`
-	ProcessedAt     *time.Time           `json:"processed_at,omitempty"`
+	ID          uuid.UUID            `json:"id"`
+	PoolID      uuid.UUID            `json:"pool_id"`
+	InvoiceID   uuid.UUID            `json:"invoice_id"`
+	TotalAmount money.Amount         `json:"total_amount"`
+	Currency    string               `json:"currency"`
+	Targets     []DisbursementTarget `json:"targets"`
+	Status      string               `json:"status"` // pending, processing, completed, failed
+	CreatedAt   time.Time            `json:"created_at"`
+	ProcessedAt *time.Time           `json:"processed_at,omitempty"`
 }
 
 // DisbursementResult represents the result from escrow processing
 type DisbursementResult struct {
-	InstructionID   uuid.UUID                  `json:"instruction_id"`
-	Status          string                     `json:"status"`
-	TotalDisbursed  float64                    `json:"total_disbursed"`
-	SuccessCount    int                        `json:"success_count"`
-	FailedCount     int                        `json:"failed_count"`
-	Results         []TargetDisbursementResult `json:"results"`
-	ProcessedAt     time.Time                  `json:"processed_at"`
+	InstructionID  uuid.UUID                  `json:"instruction_id"`
+	Status         string                     `json:"status"`
+	TotalDisbursed money.Amount               `json:"total_disbursed"`
+	SuccessCount   int                        `json:"success_count"`
+	FailedCount    int                        `json:"failed_count"`
+	Results        []TargetDisbursementResult `json:"results"`
+	ProcessedAt    time.Time                  `json:"processed_at"`
 }
 
 // TargetDisbursementResult represents result for each target
 type TargetDisbursementResult struct {
-	InvestorID    uuid.UUID `json:"investor_id"`
-	WalletAddress string    `json:"wallet_address"`
-	Amount        float64   `json:"amount"`
-	Status        string    `json:"status"` // success, failed
-	TxHash        string    `json:"tx_hash,omitempty"`
-	Error         string    `json:"error,omitempty"`
+	InvestorID    uuid.UUID    `json:"investor_id"`
+	WalletAddress string       `json:"wallet_address"`
+	Amount        money.Amount `json:"amount"`
+	Status        string       `json:"status"` // success, failed
+	TxHash        string       `json:"tx_hash,omitempty"`
+	Error         string       `json:"error,omitempty"`
 }
 
 // CreateDisbursementInstruction creates disbursement instruction for escrow
 // In production, this would call actual escrow API
 func (s *EscrowService) CreateDisbursementInstruction(poolID, invoiceID uuid.UUID, targets []DisbursementTarget) (*DisbursementInstruction, error) {
-	totalAmount := 0.0
+	var totalAmount money.Amount
 	for _, t := range targets {
 		totalAmount += t.Amount
 	}
@@ -83,7 +84,7 @@ func (s *EscrowService) CreateDisbursementInstruction(poolID, invoiceID uuid.UUI
 
 	// In production: Send to escrow API
 	fmt.Printf("[ESCROW] Created disbursement instruction %s for pool %s\n", instruction.ID, poolID)
-	fmt.Printf("[ESCROW] Total amount: %.2f IDRX to %d investors\n", totalAmount, len(targets))
+	fmt.Printf("[ESCROW] Total amount: %s IDRX to %d investors\n", totalAmount, len(targets))
 
 	return instruction, nil
 }
@@ -94,7 +95,7 @@ func (s *EscrowService) ProcessDisbursement(instruction *DisbursementInstruction
 	now := time.Now()
 	results := make([]TargetDisbursementResult, 0, len(instruction.Targets))
 	successCount := 0
-	totalDisbursed := 0.0
+	var totalDisbursed money.Amount
 
 	for _, target := range instruction.Targets {
 		// Simulate successful transfer
@@ -111,7 +112,7 @@ func (s *EscrowService) ProcessDisbursement(instruction *DisbursementInstruction
 		successCount++
 		totalDisbursed += target.Amount
 
-		fmt.Printf("[ESCROW] Disbursed %.2f %s to investor %s (wallet: %s)\n",
+		fmt.Printf("[ESCROW] Disbursed %s %s to investor %s (wallet: %s)\n",
 			target.Amount, target.Currency, target.InvestorID, target.WalletAddress)
 	}
 
@@ -131,10 +132,10 @@ func (s *EscrowService) ProcessDisbursement(instruction *DisbursementInstruction
 
 // VerifyExporterDeposit verifies that exporter has deposited required amount
 // In production: Check escrow account balance or blockchain
-func (s *EscrowService) VerifyExporterDeposit(invoiceID uuid.UUID, requiredAmount float64) (bool, float64, error) {
+func (s *EscrowService) VerifyExporterDeposit(invoiceID uuid.UUID, requiredAmount money.Amount) (bool, money.Amount, error) {
 	// DUMMY: Always return true for MVP
 	// In production: Query escrow/bank API for actual deposit
-	fmt.Printf("[ESCROW] Verifying deposit for invoice %s: required %.2f IDRX\n", invoiceID, requiredAmount)
+	fmt.Printf("[ESCROW] Verifying deposit for invoice %s: required %s IDRX\n", invoiceID, requiredAmount)
 
 	// Simulate deposit verified
 	return true, requiredAmount, nil
@@ -142,15 +143,15 @@ func (s *EscrowService) VerifyExporterDeposit(invoiceID uuid.UUID, requiredAmoun
 
 // GetEscrowBalance gets the current escrow balance for an invoice
 // In production: Query actual escrow account
-func (s *EscrowService) GetEscrowBalance(invoiceID uuid.UUID) (float64, error) {
+func (s *EscrowService) GetEscrowBalance(invoiceID uuid.UUID) (money.Amount, error) {
 	// DUMMY: Return 0 for MVP
 	return 0, nil
 }
 
 // RefundToExporter refunds excess amount to exporter
 // In production: Initiate refund via escrow API
-func (s *EscrowService) RefundToExporter(invoiceID uuid.UUID, exporterWallet string, amount float64) error {
-	fmt.Printf("[ESCROW] Refunding %.2f IDRX to exporter wallet %s for invoice %s\n",
+func (s *EscrowService) RefundToExporter(invoiceID uuid.UUID, exporterWallet string, amount money.Amount) error {
+	fmt.Printf("[ESCROW] Refunding %s IDRX to exporter wallet %s for invoice %s\n",
 		amount, exporterWallet, invoiceID)
 	return nil
 }
@@ -1,26 +1,38 @@
 package services
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/vessel/backend/internal/config"
 	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/money"
 	"github.com/vessel/backend/internal/repository"
 )
 
+// ErrPoolNotRepayable means a repayment was applied to a pool whose funds have
+// not been disbursed to the mitra
+var ErrPoolNotRepayable = errors.New("only disbursed pools can be repaid")
+
 type FundingService struct {
-	fundingRepo       repository.FundingRepositoryInterface
-	invoiceRepo       repository.InvoiceRepositoryInterface
-	txRepo            repository.TransactionRepositoryInterface
-	userRepo          repository.UserRepositoryInterface
-	rqRepo            repository.RiskQuestionnaireRepositoryInterface
-	emailService      *EmailService
-	escrowService     *EscrowService
-	blockchainService *BlockchainService
-	cfg               *config.Config
+	fundingRepo   repository.FundingRepositoryInterface
+	invoiceRepo   repository.InvoiceRepositoryInterface
+	txRepo        repository.TransactionRepositoryInterface
+	userRepo      repository.UserRepositoryInterface
+	rqRepo        repository.RiskQuestionnaireRepositoryInterface
+	lateCharges   repository.LateChargeRepositoryInterface
+	countryTiers  *CountryTierService
+	emailService  *EmailService
+	escrowService *EscrowService
+	interest      *InterestEngine
+	ledgerService *LedgerService
+	uow           repository.UnitOfWorkInterface
+	cfg           *config.Config
 }
 
 func NewFundingService(
@@ -29,25 +41,35 @@ func NewFundingService(
 	txRepo repository.TransactionRepositoryInterface,
 	userRepo repository.UserRepositoryInterface,
 	rqRepo repository.RiskQuestionnaireRepositoryInterface,
+	lateCharges repository.LateChargeRepositoryInterface,
+	countryTiers *CountryTierService,
 	emailService *EmailService,
 	escrowService *EscrowService,
-	blockchainService *BlockchainService,
+	ledgerService *LedgerService,
+	interest *InterestEngine,
+	uow repository.UnitOfWorkInterface,
 	cfg *config.Config,
 ) *FundingService {
 	return &FundingService{
-		fundingRepo:       fundingRepo,
-		invoiceRepo:       invoiceRepo,
-		txRepo:            txRepo,
-		userRepo:          userRepo,
-		rqRepo:            rqRepo,
-		emailService:      emailService,
-		escrowService:     escrowService,
-		blockchainService: blockchainService,
-		cfg:               cfg,
+		fundingRepo:   fundingRepo,
+		invoiceRepo:   invoiceRepo,
+		txRepo:        txRepo,
+		userRepo:      userRepo,
+		rqRepo:        rqRepo,
+		lateCharges:   lateCharges,
+		countryTiers:  countryTiers,
+		emailService:  emailService,
+		escrowService: escrowService,
+		ledgerService: ledgerService,
+		interest:      interest,
+		uow:           uow,
+		cfg:           cfg,
 	}
 }
 
-func (s *FundingService) CreatePool(invoiceID uuid.UUID) (*models.FundingPool, error) {
+// CreatePool opens the funding pool for an approved invoice. Without a tranche
+// layout in req the pool gets the invoice's priority/catalyst split.
+func (s *FundingService) CreatePool(invoiceID uuid.UUID, req *models.CreatePoolRequest) (*models.FundingPool, error) {
 	invoice, err := s.invoiceRepo.FindByID(invoiceID)
 	if err != nil {
 		return nil, err
@@ -68,7 +90,60 @@ func (s *FundingService) CreatePool(invoiceID uuid.UUID) (*models.FundingPool, e
 		return nil, errors.New("funding pool already exists for this invoice")
 	}
 
-	// Calculate tranche targets based on invoice config
+	totalTarget := *invoice.AdvanceAmount
+	var tranches []models.PoolTranche
+	if req != nil && len(req.Tranches) > 0 {
+		if tranches, err = buildTranches(totalTarget, invoice.Currency, req.Tranches); err != nil {
+			return nil, err
+		}
+	} else {
+		tranches = defaultTranches(invoice)
+	}
+
+	// Calculate deadline based on funding duration days
+	fundingDurationDays := invoice.FundingDurationDays
+	if fundingDurationDays == 0 {
+		fundingDurationDays = 14 // Default 14 days
+	}
+	deadline := time.Now().AddDate(0, 0, fundingDurationDays)
+
+	pool := &models.FundingPool{
+		InvoiceID:    invoiceID,
+		TargetAmount: totalTarget,
+		Status:       models.PoolStatusOpen,
+		Deadline:     &deadline,
+		PoolCurrency: models.DefaultPoolCurrency,
+		Tranches:     tranches,
+	}
+	// The legacy tranche columns mirror tranches named priority and catalyst
+	if t := pool.Tranche(models.TranchePriority); t != nil {
+		pool.PriorityTarget = t.TargetAmount
+		pool.PriorityInterestRate = t.InterestRate
+	}
+	if t := pool.Tranche(models.TrancheCatalyst); t != nil {
+		pool.CatalystTarget = t.TargetAmount
+		pool.CatalystInterestRate = t.InterestRate
+	}
+
+	// The pool, the invoice status and the on-chain pool creation commit together
+	err = s.uow.Do(func(repos *repository.Repositories) error {
+		if err := repos.Funding.CreatePool(pool); err != nil {
+			return err
+		}
+		if err := repos.Invoices.UpdateStatus(invoiceID, models.StatusFunding); err != nil {
+			return err
+		}
+		return enqueueOnchain(repos, models.OnchainActionCreatePool, invoiceID, &pool.ID, nil, &models.OnchainPayload{})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pool, nil
+}
+
+// defaultTranches splits a pool into the invoice's priority and catalyst tranches
+func defaultTranches(invoice *models.Invoice) []models.PoolTranche {
 	totalTarget := *invoice.AdvanceAmount
 	priorityRatio := invoice.PriorityRatio
 	catalystRatio := invoice.CatalystRatio
@@ -81,8 +156,13 @@ func (s *FundingService) CreatePool(invoiceID uuid.UUID) (*models.FundingPool, e
 		catalystRatio = 20.0
 	}
 
-	priorityTarget := totalTarget * (priorityRatio / 100)
-	catalystTarget := totalTarget * (catalystRatio / 100)
+	// Targets are rounded to whole rupiah; when the ratios cover the whole pool the
+	// catalyst tranche takes the exact remainder so the tranches always sum to the target
+	priorityTarget := totalTarget.MulRate(priorityRatio).RoundTo(invoice.Currency)
+	catalystTarget := totalTarget.MulRate(catalystRatio).RoundTo(invoice.Currency)
+	if priorityRatio+catalystRatio == 100 {
+		catalystTarget = totalTarget - priorityTarget
+	}
 
 	// Get interest rates from invoice or use defaults
 	priorityInterestRate := 10.0
@@ -94,52 +174,10 @@ func (s *FundingService) CreatePool(invoiceID uuid.UUID) (*models.FundingPool, e
 		catalystInterestRate = *invoice.CatalystInterestRate
 	}
 
-	// Calculate deadline based on funding duration days
-	fundingDurationDays := invoice.FundingDurationDays
-	if fundingDurationDays == 0 {
-		fundingDurationDays = 14 // Default 14 days
-	}
-	deadline := time.Now().AddDate(0, 0, fundingDurationDays)
-
-	pool := &models.FundingPool{
-		InvoiceID:            invoiceID,
-		TargetAmount:         totalTarget,
-		Status:               models.PoolStatusOpen,
-		Deadline:             &deadline,
-		PriorityTarget:       priorityTarget,
-		PriorityFunded:       0,
-		CatalystTarget:       catalystTarget,
-		CatalystFunded:       0,
-		PriorityInterestRate: priorityInterestRate,
-		CatalystInterestRate: catalystInterestRate,
-		PoolCurrency:         "IDR",
-	}
-
-	if err := s.fundingRepo.CreatePool(pool); err != nil {
-		return nil, err
-	}
-
-	// Update invoice status
-	if err := s.invoiceRepo.UpdateStatus(invoiceID, models.StatusFunding); err != nil {
-		return nil, err
+	return []models.PoolTranche{
+		{Name: models.TranchePriority, Seniority: 1, TargetAmount: priorityTarget, InterestRate: priorityInterestRate},
+		{Name: models.TrancheCatalyst, Seniority: 2, TargetAmount: catalystTarget, InterestRate: catalystInterestRate, RequiresRiskConsent: true},
 	}
-
-	// Record pool creation on blockchain
-	go func() {
-		if s.blockchainService != nil {
-			// Get NFT to get token ID
-			nft, err := s.invoiceRepo.FindNFTByInvoiceID(invoiceID)
-			if err == nil && nft != nil && nft.TokenID != nil {
-				if err := s.blockchainService.CreatePoolOnChain(*nft.TokenID); err != nil {
-					fmt.Printf("[BLOCKCHAIN] Failed to create pool on-chain: %v\n", err)
-				} else {
-					fmt.Printf("[BLOCKCHAIN] Pool created on-chain for tokenId=%d\n", *nft.TokenID)
-				}
-			}
-		}
-	}()
-
-	return pool, nil
 }
 
 func (s *FundingService) GetPool(poolID uuid.UUID) (*models.FundingPoolResponse, error) {
@@ -156,7 +194,7 @@ func (s *FundingService) GetPool(poolID uuid.UUID) (*models.FundingPoolResponse,
 	remaining := pool.TargetAmount - pool.FundedAmount
 	percentage := 0.0
 	if pool.TargetAmount > 0 {
-		percentage = (pool.FundedAmount / pool.TargetAmount) * 100
+		percentage = money.Ratio(pool.FundedAmount, pool.TargetAmount) * 100
 	}
 
 	priorityRemaining := pool.PriorityTarget - pool.PriorityFunded
@@ -164,10 +202,10 @@ func (s *FundingService) GetPool(poolID uuid.UUID) (*models.FundingPoolResponse,
 	priorityPercentage := 0.0
 	catalystPercentage := 0.0
 	if pool.PriorityTarget > 0 {
-		priorityPercentage = (pool.PriorityFunded / pool.PriorityTarget) * 100
+		priorityPercentage = money.Ratio(pool.PriorityFunded, pool.PriorityTarget) * 100
 	}
 	if pool.CatalystTarget > 0 {
-		catalystPercentage = (pool.CatalystFunded / pool.CatalystTarget) * 100
+		catalystPercentage = money.Ratio(pool.CatalystFunded, pool.CatalystTarget) * 100
 	}
 
 	return &models.FundingPoolResponse{
@@ -201,7 +239,7 @@ func (s *FundingService) GetOpenPools(page, perPage int) (*models.PoolListRespon
 		remaining := pool.TargetAmount - pool.FundedAmount
 		percentage := 0.0
 		if pool.TargetAmount > 0 {
-			percentage = (pool.FundedAmount / pool.TargetAmount) * 100
+			percentage = money.Ratio(pool.FundedAmount, pool.TargetAmount) * 100
 		}
 
 		priorityRemaining := pool.PriorityTarget - pool.PriorityFunded
@@ -209,10 +247,10 @@ func (s *FundingService) GetOpenPools(page, perPage int) (*models.PoolListRespon
 		priorityPercentage := 0.0
 		catalystPercentage := 0.0
 		if pool.PriorityTarget > 0 {
-			priorityPercentage = (pool.PriorityFunded / pool.PriorityTarget) * 100
+			priorityPercentage = money.Ratio(pool.PriorityFunded, pool.PriorityTarget) * 100
 		}
 		if pool.CatalystTarget > 0 {
-			catalystPercentage = (pool.CatalystFunded / pool.CatalystTarget) * 100
+			catalystPercentage = money.Ratio(pool.CatalystFunded, pool.CatalystTarget) * 100
 		}
 
 		responses = append(responses, models.FundingPoolResponse{
@@ -267,11 +305,11 @@ func (s *FundingService) GetMarketplacePools(filter *models.MarketplaceFilter) (
 			total--
 			continue
 		}
-		if filter.MinAmount != nil && pool.TargetAmount < *filter.MinAmount {
+		if filter.MinAmount != nil && pool.TargetAmount < money.FromFloat(*filter.MinAmount) {
 			total--
 			continue
 		}
-		if filter.MaxAmount != nil && pool.TargetAmount > *filter.MaxAmount {
+		if filter.MaxAmount != nil && pool.TargetAmount > money.FromFloat(*filter.MaxAmount) {
 			total--
 			continue
 		}
@@ -280,7 +318,7 @@ func (s *FundingService) GetMarketplacePools(filter *models.MarketplaceFilter) (
 		remaining := pool.TargetAmount - pool.FundedAmount
 		percentage := 0.0
 		if pool.TargetAmount > 0 {
-			percentage = (pool.FundedAmount / pool.TargetAmount) * 100
+			percentage = money.Ratio(pool.FundedAmount, pool.TargetAmount) * 100
 		}
 
 		priorityRemaining := pool.PriorityTarget - pool.PriorityFunded
@@ -288,10 +326,10 @@ func (s *FundingService) GetMarketplacePools(filter *models.MarketplaceFilter) (
 		priorityPercentage := 0.0
 		catalystPercentage := 0.0
 		if pool.PriorityTarget > 0 {
-			priorityPercentage = (pool.PriorityFunded / pool.PriorityTarget) * 100
+			priorityPercentage = money.Ratio(pool.PriorityFunded, pool.PriorityTarget) * 100
 		}
 		if pool.CatalystTarget > 0 {
-			catalystPercentage = (pool.CatalystFunded / pool.CatalystTarget) * 100
+			catalystPercentage = money.Ratio(pool.CatalystFunded, pool.CatalystTarget) * 100
 		}
 
 		// Calculate remaining time
@@ -361,126 +399,185 @@ func (s *FundingService) GetMarketplacePools(filter *models.MarketplaceFilter) (
 }
 
 func (s *FundingService) Invest(investorID uuid.UUID, req *models.InvestRequest) (*models.Investment, error) {
-	pool, err := s.fundingRepo.FindPoolByID(req.PoolID)
-	if err != nil {
-		return nil, err
-	}
-	if pool == nil {
-		return nil, errors.New("pool not found")
-	}
-	if pool.Status != models.PoolStatusOpen {
-		return nil, errors.New("pool is not open for investment")
-	}
-
 	// Validate consent based on tranche type
 	// All investments require T&C acceptance
 	if !req.TncAccepted {
 		return nil, errors.New("you must accept Terms & Conditions to invest")
 	}
 
-	// Catalyst tranche requires additional consents (3 checkboxes)
-	if req.Tranche == models.TrancheCatalyst {
-		if req.CatalystConsents == nil || !req.CatalystConsents.AllAccepted() {
-			return nil, errors.New("untuk Pendanaan Katalis, Anda harus menyetujui semua 3 pernyataan risiko")
-		}
+	signedConsent := req.ConsentSignature != nil && *req.ConsentSignature != ""
+	if s.cfg.InvestConsentSignatureRequired && !signedConsent {
+		return nil, ErrConsentSignatureRequired
 	}
 
-	// Validate tranche-specific quota
-	var trancheRemaining float64
-	var interestRate float64
+	// The whole investment runs in one transaction. The pool row is locked first,
+	// then the investor row, so concurrent investors queue on the pool and the
+	// capacity check below always sees the latest tranche funding.
+	var investment *models.Investment
+	var pool *models.FundingPool
+	poolFilled := false
+	err := s.uow.Do(func(repos *repository.Repositories) error {
+		var err error
+		pool, err = repos.Funding.FindPoolByIDForUpdate(req.PoolID)
+		if err != nil {
+			return err
+		}
+		if pool == nil {
+			return errors.New("pool not found")
+		}
+		if pool.Status != models.PoolStatusOpen {
+			return errors.New("pool is not open for investment")
+		}
+		if pool.Deadline != nil && time.Now().After(*pool.Deadline) {
+			return errors.New("pool funding deadline has passed")
+		}
 
-	if req.Tranche == models.TranchePriority {
-		trancheRemaining = pool.PriorityTarget - pool.PriorityFunded
-		interestRate = pool.PriorityInterestRate
-	} else if req.Tranche == models.TrancheCatalyst {
-		trancheRemaining = pool.CatalystTarget - pool.CatalystFunded
-		interestRate = pool.CatalystInterestRate
-	} else {
-		return nil, errors.New("invalid tranche type, must be 'priority' or 'catalyst'")
-	}
+		investor, err := repos.Users.FindByIDForUpdate(investorID)
+		if err != nil {
+			return err
+		}
+		if investor == nil {
+			return errors.New("investor not found")
+		}
 
-	if req.Amount > trancheRemaining {
-		return nil, errors.New("investment amount exceeds remaining tranche capacity")
-	}
+		// The tranche's eligibility rules and remaining capacity
+		tranche := pool.Tranche(req.Tranche)
+		if tranche == nil {
+			return ErrTrancheNotFound
+		}
+		if err := s.checkTrancheEligibility(tranche, investorID, req); err != nil {
+			return err
+		}
 
-	// Flat interest calculation: Principal + (Principal * Rate/100)
-	// No time factor - interest is calculated flat from total principal
-	expectedReturn := req.Amount + (req.Amount * interestRate / 100)
+		// Signed consent is checked against this exact pool, tranche and amount
+		var consent *models.InvestmentConsent
+		if signedConsent {
+			if consent, err = s.verifyInvestmentConsent(repos, investor, pool, req); err != nil {
+				return err
+			}
+		}
 
-	// Check user balance (Flow 2: Payment Integration)
-	user, err := s.userRepo.FindByID(investorID)
-	if err != nil {
-		return nil, err
-	}
-	if user.BalanceIDR < req.Amount {
-		return nil, errors.New("insufficient balance")
-	}
+		invoice, err := repos.Invoices.FindByID(pool.InvoiceID)
+		if err != nil {
+			return err
+		}
+		if invoice == nil {
+			return errors.New("invoice not found")
+		}
 
-	// Deduct balance
-	newBalance := user.BalanceIDR - req.Amount
-	if err := s.userRepo.UpdateBalance(investorID, newBalance); err != nil {
-		return nil, fmt.Errorf("failed to deduct balance: %w", err)
-	}
+		// The tranche's annual rate accrues from disbursement to the due date. Until
+		// the pool disburses today stands in for that date; DisburseToExporter
+		// accrues every investment again from the actual disbursement.
+		expectedReturn := s.interest.ExpectedReturn(pool, req.Tranche, req.Amount, time.Now(), invoice.DueDate)
 
-	investment := &models.Investment{
-		PoolID:         req.PoolID,
-		InvestorID:     investorID,
-		Amount:         req.Amount,
-		ExpectedReturn: expectedReturn,
-		Status:         models.InvestmentStatusActive,
-		Tranche:        req.Tranche,
-	}
+		// Move funds from investor wallet to the pool (Flow 2: Payment Integration)
+		// The ledger rejects the posting if the wallet would go negative
+		if err := s.ledgerService.WithRepository(repos.Ledger).RecordInvestment(investorID, req.PoolID, req.Amount, req.Tranche); err != nil {
+			if errors.Is(err, repository.ErrInsufficientBalance) {
+				return err
+			}
+			return fmt.Errorf("failed to deduct balance: %w", err)
+		}
 
-	if err := s.fundingRepo.CreateInvestment(investment); err != nil {
-		return nil, err
-	}
+		investment = &models.Investment{
+			PoolID:         req.PoolID,
+			InvestorID:     investorID,
+			Amount:         req.Amount,
+			ExpectedReturn: expectedReturn,
+			Status:         models.InvestmentStatusActive,
+			Tranche:        req.Tranche,
+		}
+		if err := repos.Funding.CreateInvestment(investment); err != nil {
+			return err
+		}
+		if consent != nil {
+			consent.InvestmentID = investment.ID
+			if err := repos.Funding.CreateInvestmentConsent(consent); err != nil {
+				return err
+			}
+			investment.Consent = consent
+		}
 
-	// Update pool funding for specific tranche
-	if err := s.fundingRepo.UpdatePoolTrancheFunding(req.PoolID, req.Amount, req.Tranche); err != nil {
-		return nil, err
-	}
+		// Update pool funding for specific tranche
+		if err := repos.Funding.UpdatePoolTrancheFunding(req.PoolID, req.Amount, req.Tranche); err != nil {
+			return err
+		}
 
-	// Create transaction record (using IDR - abstracted escrow)
-	tx := &models.Transaction{
-		InvoiceID: &pool.InvoiceID,
-		UserID:    &investorID,
-		Type:      models.TxTypeInvestment,
-		Amount:    req.Amount,
-		Currency:  "IDR",
-		Status:    models.TxStatusPending,
-	}
-	s.txRepo.Create(tx)
+		// Create transaction record (using IDR - abstracted escrow)
+		tx := &models.Transaction{
+			InvoiceID: &pool.InvoiceID,
+			UserID:    &investorID,
+			Type:      models.TxTypeInvestment,
+			Amount:    req.Amount,
+			Currency:  "IDR",
+			Status:    models.TxStatusPending,
+		}
+		if err := repos.Transactions.Create(tx); err != nil {
+			return err
+		}
+
+		// On-Chain Transparency: the investment is recorded on-chain once committed
+		if investor.WalletAddress != nil {
+			payload := &models.OnchainPayload{Wallet: *investor.WalletAddress, Amount: req.Amount}
+			if err := enqueueOnchain(repos, models.OnchainActionRecordInvestment, pool.InvoiceID, &pool.ID, []uuid.UUID{tx.ID}, payload); err != nil {
+				return err
+			}
+		}
 
-	// Check if pool is now filled (both tranches) - Flow 7: Auto-Disbursement
-	updatedPool, _ := s.fundingRepo.FindPoolByID(req.PoolID)
-	if updatedPool.FundedAmount >= updatedPool.TargetAmount {
-		s.fundingRepo.UpdatePoolStatus(req.PoolID, models.PoolStatusFilled)
+		// Check if pool is now filled (every tranche) - Flow 7: Auto-Disbursement
+		if pool.FundedAmount+req.Amount >= pool.TargetAmount {
+			if err := repos.Funding.UpdatePoolStatus(req.PoolID, models.PoolStatusFilled); err != nil {
+				return err
+			}
+			poolFilled = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
+	if poolFilled {
 		// Trigger automatic disbursement to mitra (Flow 7)
 		go func() {
 			if _, err := s.DisburseToExporter(req.PoolID); err != nil {
 				// Log error but don't fail investment
 				// In production, this would alert admins
-				_ = err
+				log.Printf("[INVEST] Auto-disbursement failed for pool %s: %v", req.PoolID, err)
 			}
 		}()
 	}
 
-	// On-Chain Transparency: Record investment on blockchain
-	go func() {
-		// Get investor wallet address
-		investor, err := s.userRepo.FindByID(investorID)
-		if err == nil && investor != nil && investor.WalletAddress != nil && s.blockchainService != nil {
-			_, err := s.blockchainService.RecordInvestment(req.PoolID, *investor.WalletAddress, req.Amount)
-			if err != nil {
-				fmt.Printf("Failed to record investment on-chain: %v\n", err)
-			}
-		}
-	}()
-
 	return investment, nil
 }
 
+// checkTrancheEligibility applies a tranche's investor rules and remaining
+// capacity to an investment request
+func (s *FundingService) checkTrancheEligibility(tranche *models.PoolTranche, investorID uuid.UUID, req *models.InvestRequest) error {
+	if tranche.RequiresRiskConsent && (req.CatalystConsents == nil || !req.CatalystConsents.AllAccepted()) {
+		return ErrTrancheRiskConsent
+	}
+	if tranche.RequiresRiskProfile {
+		unlocked, err := s.rqRepo.IsCatalystUnlocked(investorID)
+		if err != nil {
+			return err
+		}
+		if !unlocked {
+			return ErrTrancheRiskProfile
+		}
+	}
+	if tranche.MinInvestment > 0 && req.Amount < tranche.MinInvestment {
+		return ErrTrancheBelowMinimum
+	}
+	if tranche.MaxInvestment > 0 && req.Amount > tranche.MaxInvestment {
+		return ErrTrancheAboveMaximum
+	}
+	if req.Amount > tranche.Remaining() {
+		return ErrTrancheCapacity
+	}
+	return nil
+}
+
 func (s *FundingService) GetInvestmentsByInvestor(investorID uuid.UUID, page, perPage int) (*models.InvestmentListResponse, error) {
 	if page < 1 {
 		page = 1
@@ -509,37 +606,89 @@ func (s *FundingService) GetInvestorPortfolio(investorID uuid.UUID) (*models.Inv
 
 // DisburseToExporter disburses funds to exporter, updates statuses, and sends notification
 func (s *FundingService) DisburseToExporter(poolID uuid.UUID) (*models.ExporterPaymentNotificationData, error) {
-	pool, err := s.fundingRepo.FindPoolByID(poolID)
-	if err != nil {
-		return nil, err
-	}
-	if pool == nil {
-		return nil, errors.New("pool not found")
-	}
-	// Allow Filled (auto) or Open (manual close) status
-	if pool.Status != models.PoolStatusFilled && pool.Status != models.PoolStatusOpen {
-		return nil, errors.New("pool must be open or filled before disbursement")
-	}
+	// The release runs in one transaction with the pool row locked, so auto-disbursement,
+	// a manual close and the expiry job cannot disburse the same pool twice
+	var pool *models.FundingPool
+	var invoice *models.Invoice
+	var platformFee, disbursementAmount money.Amount
+	err := s.uow.Do(func(repos *repository.Repositories) error {
+		var err error
+		pool, err = repos.Funding.FindPoolByIDForUpdate(poolID)
+		if err != nil {
+			return err
+		}
+		if pool == nil {
+			return errors.New("pool not found")
+		}
+		// Allow Filled (auto) or Open (manual close) status
+		if pool.Status != models.PoolStatusFilled && pool.Status != models.PoolStatusOpen {
+			return errors.New("pool must be open or filled before disbursement")
+		}
 
-	// For manual close (Open status), ensure there is some funding
-	if pool.FundedAmount == 0 {
-		return nil, errors.New("pool has no funding")
-	}
+		// For manual close (Open status), ensure there is some funding
+		if pool.FundedAmount == 0 {
+			return errors.New("pool has no funding")
+		}
 
-	// Update pool status
-	if err := s.fundingRepo.UpdatePoolStatus(poolID, models.PoolStatusDisbursed); err != nil {
-		return nil, err
-	}
+		invoice, err = repos.Invoices.FindByID(pool.InvoiceID)
+		if err != nil {
+			return err
+		}
+		if invoice == nil {
+			return errors.New("invoice not found")
+		}
 
-	// Update invoice status
-	if err := s.invoiceRepo.UpdateStatus(pool.InvoiceID, models.StatusFunded); err != nil {
-		return nil, err
-	}
+		// Calculate platform fee
+		platformFee = pool.FundedAmount.MulRate(s.cfg.PlatformFeePercentage).RoundTo(pool.PoolCurrency)
+		disbursementAmount = pool.FundedAmount - platformFee
 
-	// Get invoice for exporter info
-	invoice, _ := s.invoiceRepo.FindByID(pool.InvoiceID)
-	if invoice == nil {
-		return nil, errors.New("invoice not found")
+		// Release pool funds: advance leaves escrow, withheld fee becomes platform revenue
+		if err := s.ledgerService.WithRepository(repos.Ledger).RecordDisbursement(poolID, pool.FundedAmount, platformFee); err != nil {
+			return fmt.Errorf("failed to post disbursement: %w", err)
+		}
+
+		if err := repos.Funding.UpdatePoolStatus(poolID, models.PoolStatusDisbursed); err != nil {
+			return err
+		}
+		if err := repos.Invoices.UpdateStatus(pool.InvoiceID, models.StatusFunded); err != nil {
+			return err
+		}
+
+		// Interest accrues from today, so expected returns are fixed now
+		investments, err := repos.Funding.FindInvestmentsByPool(poolID)
+		if err != nil {
+			return err
+		}
+		disbursedAt := time.Now()
+		for _, inv := range investments {
+			if inv.Status != models.InvestmentStatusActive {
+				continue
+			}
+			expectedReturn := s.interest.ExpectedReturn(pool, inv.Tranche, inv.Amount, disbursedAt, invoice.DueDate)
+			if err := repos.Funding.UpdateInvestmentExpectedReturn(inv.ID, expectedReturn); err != nil {
+				return err
+			}
+		}
+
+		// Create disbursement transaction
+		tx := &models.Transaction{
+			InvoiceID: &pool.InvoiceID,
+			UserID:    &invoice.ExporterID,
+			Type:      models.TxTypeAdvancePayment,
+			Amount:    disbursementAmount,
+			Currency:  "IDRX",
+			Status:    models.TxStatusPending,
+			Notes:     stringPtr("Disbursement to exporter (funding completed)"),
+		}
+		if err := repos.Transactions.Create(tx); err != nil {
+			return err
+		}
+
+		// On-Chain Transparency: Record disbursement
+		return enqueueOnchain(repos, models.OnchainActionRecordDisbursement, pool.InvoiceID, &pool.ID, []uuid.UUID{tx.ID}, &models.OnchainPayload{Amount: disbursementAmount})
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Get exporter details
@@ -557,38 +706,17 @@ func (s *FundingService) DisburseToExporter(poolID uuid.UUID) (*models.ExporterP
 		exporterName = *exporterProfile.CompanyName
 	}
 
-	// Calculate platform fee
-	platformFee := pool.FundedAmount * (s.cfg.PlatformFeePercentage / 100)
-	disbursementAmount := pool.FundedAmount - platformFee
-
-	// Create disbursement transaction
-	tx := &models.Transaction{
-		InvoiceID: &pool.InvoiceID,
-		UserID:    &invoice.ExporterID,
-		Type:      models.TxTypeAdvancePayment,
-		Amount:    disbursementAmount,
-		Currency:  "IDRX",
-		Status:    models.TxStatusPending,
-		Notes:     stringPtr("Disbursement to exporter (funding completed)"),
-	}
-	s.txRepo.Create(tx)
-
 	// --- Prepare Notification Data ---
 
 	// Get investments to calculate interest/repayment plan
 	investments, _ := s.fundingRepo.FindInvestmentsByPool(pool.ID)
 
 	var investorDetails []models.InvestorPaymentDetail
-	totalExpectedReturn := 0.0
+	var totalExpectedReturn money.Amount
 	for _, inv := range investments {
 		totalExpectedReturn += inv.ExpectedReturn
 
-		var interestRate float64
-		if inv.Tranche == models.TranchePriority {
-			interestRate = pool.PriorityInterestRate
-		} else {
-			interestRate = pool.CatalystInterestRate
-		}
+		interestRate := trancheRate(pool, inv.Tranche)
 
 		investorDetails = append(investorDetails, models.InvestorPaymentDetail{
 			InvestorID:     inv.InvestorID.String(),
@@ -623,267 +751,813 @@ func (s *FundingService) DisburseToExporter(poolID uuid.UUID) (*models.ExporterP
 		PaymentLink:     paymentLink,
 	}
 
-	// Send email to exporter
-	if s.emailService != nil {
-		if err := s.emailService.SendExporterPaymentNotification(exporter.Email, notificationData); err != nil {
-			fmt.Printf("Failed to send payment notification email: %v\n", err)
-		}
+	// Send email to exporter
+	if s.emailService != nil {
+		if err := s.emailService.SendExporterPaymentNotification(exporter.Email, notificationData); err != nil {
+			fmt.Printf("Failed to send payment notification email: %v\n", err)
+		}
+	}
+
+	return notificationData, nil
+}
+
+// ProcessRepayment applies a repayment received for an invoice as one installment.
+// Each installment goes through the seniority waterfall against what investors are
+// still owed: the most senior tranche first, then each junior one, and only once every claim
+// is met does the rest go to the mitra's balance (the partial funding scenario: an
+// invoice of 100k with only 10k funded leaves the importer's excess to the mitra).
+// The pool is closed and the NFT burned when the last claim is met, or when final
+// is set, in which case investors still short keep what they were paid.
+func (s *FundingService) ProcessRepayment(invoiceID uuid.UUID, amount money.Amount, final bool) error {
+	var result *repaymentResult
+	err := s.uow.Do(func(repos *repository.Repositories) error {
+		var err error
+		result, err = s.applyRepayment(repos, invoiceID, amount, final, repaymentSource{})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	notifyRecovery(s.emailService, s.userRepo, result.invoiceNumber, result.currency, result.recovered)
+	return nil
+}
+
+// repayPool applies money the mitra paid towards a pool as one installment
+// inside the caller's unit of work. The platform fee was withheld at disbursement,
+// so all of it goes down the waterfall. Money paid once the pool has nothing left
+// to repay is returned to the mitra's balance and leaves no result.
+func (s *FundingService) repayPool(repos *repository.Repositories, poolID uuid.UUID, amount money.Amount) (*repaymentResult, error) {
+	pool, err := repos.Funding.FindPoolByIDForUpdate(poolID)
+	if err != nil {
+		return nil, err
+	}
+	if pool == nil {
+		return nil, ErrPoolNotFound
+	}
+	if pool.Status != models.PoolStatusDisbursed && pool.Status != models.PoolStatusDefaulted {
+		return nil, s.returnRepayment(repos, pool, amount)
+	}
+	result, err := s.applyRepayment(repos, pool.InvoiceID, amount, false, repaymentSource{feeWithheld: true})
+	if err != nil {
+		return nil, err
+	}
+	repos.AfterCommit(func() {
+		notifyRecovery(s.emailService, s.userRepo, result.invoiceNumber, result.currency, result.recovered)
+	})
+	return result, nil
+}
+
+// returnRepayment credits money paid towards a pool with nothing left to repay
+// to the mitra's balance, so a late or duplicate payment is never left unallocated
+func (s *FundingService) returnRepayment(repos *repository.Repositories, pool *models.FundingPool, amount money.Amount) error {
+	invoice, err := repos.Invoices.FindByID(pool.InvoiceID)
+	if err != nil {
+		return err
+	}
+	if invoice == nil {
+		return errors.New("invoice not found")
+	}
+	posting := &RepaymentPosting{InvoiceID: invoice.ID, MitraID: invoice.ExporterID, MitraExcess: amount}
+	if err := s.ledgerService.WithRepository(repos.Ledger).RecordRepayment(posting); err != nil {
+		return fmt.Errorf("failed to post returned repayment: %w", err)
+	}
+	return repos.Transactions.Create(&models.Transaction{
+		InvoiceID: &invoice.ID,
+		UserID:    &invoice.ExporterID,
+		Type:      models.TxTypeRepaymentExcess,
+		Amount:    amount,
+		Currency:  "IDR",
+		Status:    models.TxStatusConfirmed,
+		Notes:     stringPtr(fmt.Sprintf("Repayment received while pool is %s, returned to mitra balance", pool.Status)),
+	})
+}
+
+// checkRepayable returns ErrPoolNotRepayable unless the pool has been disbursed
+// and still has something left to repay
+func (s *FundingService) checkRepayable(poolID uuid.UUID) error {
+	pool, err := s.fundingRepo.FindPoolByID(poolID)
+	if err != nil {
+		return err
+	}
+	if pool == nil {
+		return ErrPoolNotFound
+	}
+	if pool.Status != models.PoolStatusDisbursed && pool.Status != models.PoolStatusDefaulted {
+		return fmt.Errorf("%w: pool is %s", ErrPoolNotRepayable, pool.Status)
+	}
+	return nil
+}
+
+// SettleImporterPayment applies a confirmed gateway payment as an installment of
+// an importer payment, inside the transaction that claimed the gateway payment.
+// The importer payment is locked so installments are added one at a time; the
+// one that covers the amount due settles the pool. Money that arrives once the
+// pool has nothing left to repay, or before it was disbursed, is returned to
+// the mitra's balance as repayPool does, so a confirmed payment always settles.
+func (s *FundingService) SettleImporterPayment(repos *repository.Repositories, paymentID uuid.UUID, amount money.Amount, txHash string) (*models.ImporterPayment, error) {
+	current, err := repos.ImporterPayments.FindByIDForUpdate(paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, errors.New("importer payment not found")
+	}
+	if current.PaymentStatus == models.ImporterPaymentStatusPaid {
+		return current, nil
+	}
+
+	// Late charges accrued since the last run raise the amount due before it is compared
+	pool, err := repos.Funding.FindPoolByIDForUpdate(current.PoolID)
+	if err != nil {
+		return nil, err
+	}
+	if pool == nil {
+		return nil, ErrPoolNotFound
+	}
+	if pool.Status != models.PoolStatusDisbursed && pool.Status != models.PoolStatusDefaulted {
+		if err := s.returnRepayment(repos, pool, amount); err != nil {
+			return nil, err
+		}
+		return repos.ImporterPayments.AddPayment(current.ID, amount, txHash)
+	}
+	if pool.Status == models.PoolStatusDisbursed {
+		if _, err := accruePoolLateCharge(repos, s.interest, pool, time.Now()); err != nil {
+			return nil, err
+		}
+		if current, err = repos.ImporterPayments.FindByIDForUpdate(paymentID); err != nil {
+			return nil, err
+		}
+	}
+
+	final := current.AmountPaid+amount >= current.AmountDue
+	result, err := s.applyRepayment(repos, current.InvoiceID, amount, final, repaymentSource{importerPaymentID: &current.ID})
+	if err != nil {
+		return nil, err
+	}
+	repos.AfterCommit(func() {
+		notifyRecovery(s.emailService, s.userRepo, result.invoiceNumber, result.currency, result.recovered)
+	})
+	return repos.ImporterPayments.AddPayment(current.ID, amount, txHash)
+}
+
+// repaymentResult is what an applied installment leaves for after the commit
+type repaymentResult struct {
+	invoiceNumber string
+	currency      string
+	recovered     []recoveryCredit // Set when the pool was already defaulted
+	shares        []repaymentShare // Each investment's part of the installment, most senior first
+	totalOwed     money.Amount     // Investor claims and late charges owed before the installment
+	settled       bool
+}
+
+// repaymentSource is where an installment came from
+type repaymentSource struct {
+	importerPaymentID *uuid.UUID // Paid by the importer through the gateway
+	feeWithheld       bool       // Paid by the mitra; the platform fee was withheld at disbursement
+}
+
+// applyRepayment distributes one installment inside the caller's unit of work.
+// The pool row is locked so installments are applied one after the other.
+func (s *FundingService) applyRepayment(repos *repository.Repositories, invoiceID uuid.UUID, amount money.Amount, final bool, source repaymentSource) (*repaymentResult, error) {
+	invoice, err := repos.Invoices.FindByID(invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	if invoice == nil {
+		return nil, errors.New("invoice not found")
+	}
+
+	pool, err := repos.Funding.FindPoolByInvoiceID(invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	if pool == nil {
+		return nil, errors.New("pool not found")
+	}
+	pool, err = repos.Funding.FindPoolByIDForUpdate(pool.ID)
+	if err != nil {
+		return nil, err
+	}
+	result := &repaymentResult{invoiceNumber: invoice.InvoiceNumber, currency: pool.PoolCurrency}
+	if pool.Status == models.PoolStatusClosed {
+		return nil, errors.New("pool has already been repaid")
+	}
+	if pool.Status == models.PoolStatusDefaulted {
+		// Money collected after a default is distributed as a recovery
+		_, result.recovered, err = applyRecovery(repos, s.ledgerService, pool, invoice, amount, stringPtr("Repayment received after default"), nil)
+		return result, err
+	}
+	// An open or filled pool still holds the investors' funds in escrow
+	if pool.Status != models.PoolStatusDisbursed {
+		return nil, fmt.Errorf("%w: pool is %s", ErrPoolNotRepayable, pool.Status)
+	}
+
+	// Calculate platform fee
+	var platformFee money.Amount
+	if !source.feeWithheld {
+		platformFee = amount.MulRate(s.cfg.PlatformFeePercentage).RoundTo(pool.PoolCurrency)
+	}
+	remainingAmount := amount - platformFee
+
+	// Investments most senior tranche first
+	investments, err := repos.Funding.FindInvestmentsByPool(pool.ID)
+	if err != nil {
+		return nil, err
+	}
+	sortBySeniority(pool, investments)
+	// Interest is owed up to the day the money comes in, at the latest the due
+	// date; after it the overdue balance accrues late charges up to today
+	now := time.Now()
+	s.interest.AccrueClaims(pool, investments, invoice.DueDate, now)
+	lateCharge, err := accrueLateCharge(repos, s.interest, pool, invoice, investments, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// Steps 1-2: what investors are still owed, paid down the tranches in seniority order
+	owed := outstandingReturns(investments)
+	tranches := make([]models.TrancheType, len(investments))
+	for i, inv := range investments {
+		tranches[i] = inv.Tranche
+	}
+	returns := waterfall(pool, tranches, owed, remainingAmount)
+	shares := make([]repaymentShare, len(investments))
+	toTranches := make(map[models.TrancheType]money.Amount)
+	for i, inv := range investments {
+		shares[i] = repaymentShare{investment: inv, owed: owed[i], amount: returns[i], note: trancheNote(inv.Tranche)}
+		toTranches[inv.Tranche] += returns[i]
+	}
+
+	// Step 3: Late charges once every claim is met. The platform keeps its share
+	// and investors split the rest pro-rata by claim across every tranche.
+	claimsPaid := money.Sum(returns...)
+	var lateOutstanding, lateCollected, latePlatform money.Amount
+	if lateCharge != nil {
+		lateOutstanding = lateCharge.Outstanding()
+		lateCollected = money.Min(money.Max(remainingAmount-claimsPaid, 0), lateOutstanding)
+		claims := make([]money.Amount, len(shares))
+		for i, share := range shares {
+			claims[i] = share.investment.ExpectedReturn
+		}
+		var lateShares []money.Amount
+		latePlatform, lateShares = splitLateCharges(lateCollected, s.cfg.LateChargePlatformSharePercent, claims, pool.PoolCurrency)
+		for i := range shares {
+			shares[i].lateCharge = lateShares[i]
+		}
+		if lateCollected > 0 {
+			if err := repos.LateCharges.AddPaid(lateCharge.ID, lateCollected); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Step 4: PARTIAL FUNDING SCENARIO
+	// Once every investor claim and late charge is met, the rest of the importer's payment goes to mitra's balance
+	totalPaidToInvestors := claimsPaid + lateCollected - latePlatform
+	excessForMitra := money.Max(remainingAmount-claimsPaid-lateCollected, 0)
+	totalOwed := money.Sum(owed...) + lateOutstanding
+	settled := final || remainingAmount >= totalOwed
+	result.shares, result.totalOwed, result.settled = shares, totalOwed, settled
+
+	// Post the whole distribution as one balanced journal entry before touching any status
+	posting := &RepaymentPosting{
+		InvoiceID:   invoiceID,
+		PlatformFee: platformFee + latePlatform,
+		MitraID:     invoice.ExporterID,
+		MitraExcess: excessForMitra,
+	}
+	for _, share := range shares {
+		if paid := share.amount + share.lateCharge; paid > 0 {
+			posting.InvestorPayout = append(posting.InvestorPayout, LedgerPayout{UserID: share.investment.InvestorID, Amount: paid})
+		}
+	}
+	if err := s.ledgerService.WithRepository(repos.Ledger).RecordRepayment(posting); err != nil {
+		return nil, fmt.Errorf("failed to post repayment: %w", err)
+	}
+
+	for _, share := range shares {
+		if paid := share.amount + share.lateCharge; paid > 0 {
+			if err := repos.Funding.AddInvestmentReturn(share.investment.ID, paid); err != nil {
+				return nil, err
+			}
+		}
+
+		// Create return transactions (credited to investor wallet via the ledger)
+		for _, credit := range []struct {
+			amount money.Amount
+			note   string
+		}{{share.amount, share.note}, {share.lateCharge, "Late payment charges"}} {
+			if credit.amount <= 0 {
+				continue
+			}
+			tx := &models.Transaction{
+				InvoiceID: &invoiceID,
+				UserID:    &share.investment.InvestorID,
+				Type:      models.TxTypeInvestorReturn,
+				Amount:    credit.amount,
+				Currency:  "IDR",
+				Status:    models.TxStatusConfirmed,
+				Notes:     stringPtr(credit.note),
+			}
+			if err := repos.Transactions.Create(tx); err != nil {
+				return nil, err
+			}
+		}
+		if !settled {
+			continue
+		}
+
+		// The pool closes with this installment: anything returned counts as repaid,
+		// an investment that received nothing is defaulted
+		actualReturn := share.amount + share.lateCharge
+		if share.investment.ActualReturn != nil {
+			actualReturn += *share.investment.ActualReturn
+		}
+		status := models.InvestmentStatusRepaid
+		if actualReturn == 0 {
+			status = models.InvestmentStatusDefaulted
+		}
+		if err := repos.Funding.UpdateInvestmentStatus(share.investment.ID, status, &actualReturn); err != nil {
+			return nil, err
+		}
+	}
+
+	if excessForMitra > 0 {
+		// Create transaction record for mitra (balance already credited by the ledger posting)
+		tx := &models.Transaction{
+			InvoiceID: &invoiceID,
+			UserID:    &invoice.ExporterID,
+			Type:      models.TxTypeRepaymentExcess,
+			Amount:    excessForMitra,
+			Currency:  "IDR",
+			Status:    models.TxStatusConfirmed,
+			Notes:     stringPtr(fmt.Sprintf("Excess from invoice repayment (partial funding scenario). Installment: %s, Investor returns: %s, Excess to mitra: %s", amount, totalPaidToInvestors, excessForMitra)),
+		}
+		if err := repos.Transactions.Create(tx); err != nil {
+			return nil, err
+		}
+
+		// On-Chain Transparency: Record mitra balance credit
+		mitra, err := repos.Users.FindByID(invoice.ExporterID)
+		if err != nil {
+			return nil, err
+		}
+		if mitra != nil && mitra.WalletAddress != nil {
+			payload := &models.OnchainPayload{Wallet: *mitra.WalletAddress, Amount: excessForMitra}
+			if err := enqueueOnchain(repos, models.OnchainActionRecordMitraCredit, invoiceID, &pool.ID, []uuid.UUID{tx.ID}, payload); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Record platform fee as a transaction for admin tracking
+	if platformFee > 0 {
+		platformFeeTx := &models.Transaction{
+			InvoiceID: &invoiceID,
+			Type:      models.TxTypePlatformFee,
+			Amount:    platformFee,
+			Currency:  "IDR",
+			Status:    models.TxStatusConfirmed,
+			Notes:     stringPtr(fmt.Sprintf("Platform fee (%.1f%%) from mitra repayment - Invoice: %s", s.cfg.PlatformFeePercentage, invoice.InvoiceNumber)),
+		}
+		if err := repos.Transactions.Create(platformFeeTx); err != nil {
+			return nil, err
+		}
+	}
+	if latePlatform > 0 {
+		lateFeeTx := &models.Transaction{
+			InvoiceID: &invoiceID,
+			Type:      models.TxTypePlatformFee,
+			Amount:    latePlatform,
+			Currency:  "IDR",
+			Status:    models.TxStatusConfirmed,
+			Notes:     stringPtr(fmt.Sprintf("Platform share (%.1f%%) of late payment charges - Invoice: %s", s.cfg.LateChargePlatformSharePercent, invoice.InvoiceNumber)),
+		}
+		if err := repos.Transactions.Create(lateFeeTx); err != nil {
+			return nil, err
+		}
+	}
+
+	installment := &models.RepaymentInstallment{
+		PoolID:            pool.ID,
+		InvoiceID:         invoiceID,
+		ImporterPaymentID: source.importerPaymentID,
+		Amount:            amount,
+		PlatformFee:       platformFee,
+		ToPriority:        toTranches[models.TranchePriority],
+		ToCatalyst:        toTranches[models.TrancheCatalyst],
+		ToTranches:        toTranches,
+		LateCharges:       lateCollected,
+		ExcessToMitra:     excessForMitra,
+		Settled:           settled,
+	}
+	if err := repos.Funding.CreateRepaymentInstallment(installment); err != nil {
+		return nil, err
+	}
+
+	if !settled {
+		return result, nil
 	}
 
-	// On-Chain Transparency: Record disbursement
-	go func() {
-		if s.blockchainService != nil {
-			_, err := s.blockchainService.RecordDisbursement(poolID, disbursementAmount)
-			if err != nil {
-				fmt.Printf("Failed to record disbursement on-chain: %v\n", err)
-			}
+	// Close pool and update invoice
+	if err := repos.Funding.UpdatePoolStatus(pool.ID, models.PoolStatusClosed); err != nil {
+		return nil, err
+	}
+	if err := repos.Invoices.UpdateStatus(invoiceID, models.StatusRepaid); err != nil {
+		return nil, err
+	}
+	event, daysLate := settlementEvent(invoice, now)
+	if err := recordCreditEvent(repos.CreditScores, invoice, event, daysLate); err != nil {
+		return nil, err
+	}
+
+	// The contract records a repayment once, so the on-chain record carries the
+	// total of every installment and links every return and fee transaction
+	installments, err := repos.Funding.FindRepaymentInstallments(pool.ID)
+	if err != nil {
+		return nil, err
+	}
+	var totalRepaid money.Amount
+	for _, inst := range installments {
+		totalRepaid += inst.Amount
+	}
+	txs, err := repos.Transactions.FindByInvoice(invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	var repaymentTxIDs []uuid.UUID
+	for _, tx := range txs {
+		if tx.TxHash == nil && (tx.Type == models.TxTypeInvestorReturn || tx.Type == models.TxTypePlatformFee) {
+			repaymentTxIDs = append(repaymentTxIDs, tx.ID)
 		}
-	}()
+	}
 
-	return notificationData, nil
+	// On-Chain Transparency: Record repayment, then burn the settled invoice NFT
+	if err := enqueueOnchain(repos, models.OnchainActionRecordRepayment, invoiceID, &pool.ID, repaymentTxIDs, &models.OnchainPayload{Amount: totalRepaid}); err != nil {
+		return nil, err
+	}
+	return result, enqueueOnchain(repos, models.OnchainActionBurnNFT, invoiceID, &pool.ID, nil, &models.OnchainPayload{})
 }
 
-// ProcessRepayment processes repayment from importer and distributes to investors
-// Following priority-first rule: Priority investors are paid first, then Catalyst
-// IMPORTANT: Handles partial funding scenario - if invoice was 100k but only 10k was funded,
-// and importer pays 100k, investors get their returns and excess goes to mitra's balance
-func (s *FundingService) ProcessRepayment(invoiceID uuid.UUID, amount float64) error {
-	invoice, err := s.invoiceRepo.FindByID(invoiceID)
+// repaymentShare is one investor's computed share of an installment
+type repaymentShare struct {
+	investment models.Investment
+	owed       money.Amount // Claim outstanding before the installment
+	amount     money.Amount // Towards the investment's claim
+	lateCharge money.Amount // Share of late charges collected
+	note       string
+}
+
+// outstandingReturns is what each investment is still owed: its expected return
+// less what earlier installments paid it
+func outstandingReturns(investments []models.Investment) []money.Amount {
+	owed := make([]money.Amount, len(investments))
+	for i, inv := range investments {
+		owed[i] = inv.ExpectedReturn
+		if inv.ActualReturn != nil {
+			owed[i] = money.Max(owed[i]-*inv.ActualReturn, 0)
+		}
+	}
+	return owed
+}
+
+// GetRepaymentSummary returns how far a pool's repayment has come and every
+// installment received so far
+func (s *FundingService) GetRepaymentSummary(poolID uuid.UUID) (*models.RepaymentSummary, error) {
+	pool, err := s.fundingRepo.FindPoolByID(poolID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if pool == nil {
+		return nil, ErrPoolNotFound
+	}
+	investments, err := s.fundingRepo.FindInvestmentsByPool(poolID)
+	if err != nil {
+		return nil, err
+	}
+	invoice, err := s.invoiceRepo.FindByID(pool.InvoiceID)
+	if err != nil {
+		return nil, err
 	}
 	if invoice == nil {
-		return errors.New("invoice not found")
+		return nil, errors.New("invoice not found")
+	}
+	if pool.Status != models.PoolStatusClosed {
+		s.interest.AccrueClaims(pool, investments, invoice.DueDate, time.Now())
+	}
+	var lateCharge *models.LateCharge
+	if pool.Status == models.PoolStatusDisbursed {
+		lateCharge, err = previewLateCharge(s.lateCharges, s.interest, pool, invoice, investments, time.Now())
+	} else {
+		lateCharge, err = s.lateCharges.FindByPoolID(pool.ID)
 	}
-
-	pool, err := s.fundingRepo.FindPoolByInvoiceID(invoiceID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if pool == nil {
-		return errors.New("pool not found")
+	installments, err := s.fundingRepo.FindRepaymentInstallments(poolID)
+	if err != nil {
+		return nil, err
+	}
+	if installments == nil {
+		installments = []models.RepaymentInstallment{}
 	}
 
-	// Calculate platform fee
-	platformFee := amount * (s.cfg.PlatformFeePercentage / 100)
-	remainingAmount := amount - platformFee
+	summary := &models.RepaymentSummary{
+		PoolID:       pool.ID,
+		Currency:     pool.PoolCurrency,
+		Settled:      pool.Status == models.PoolStatusClosed,
+		LateCharge:   lateCharge,
+		Installments: installments,
+	}
+	var claims []models.Investment
+	for _, inv := range investments {
+		if inv.Status == models.InvestmentStatusRefunded {
+			continue
+		}
+		claims = append(claims, inv)
+		summary.TotalClaim += inv.ExpectedReturn
+		if inv.ActualReturn != nil {
+			summary.TotalRepaid += *inv.ActualReturn
+		}
+	}
+	summary.Outstanding = money.Sum(outstandingReturns(claims)...)
+	for _, inst := range installments {
+		summary.TotalPaid += inst.Amount
+	}
+	return summary, nil
+}
+
+// claimShares pays every claim in full when available covers them, and
+// otherwise splits available pro-rata by claim
+func claimShares(claims []money.Amount, available money.Amount, unit money.Amount) []money.Amount {
+	if available >= money.Sum(claims...) {
+		return claims
+	}
+	if available <= 0 {
+		return make([]money.Amount, len(claims))
+	}
+	return money.Allocate(available, claims, unit)
+}
 
-	// Get investments grouped by tranche
-	priorityInvestments, err := s.fundingRepo.FindInvestmentsByPoolAndTranche(pool.ID, models.TranchePriority)
+// enqueueOnchain queues an InvoicePool write in the caller's transaction so it is
+// submitted only if the business change commits. The given transactions receive
+// the block info once the write is confirmed. Invoices that were never tokenized
+// have no pool on-chain and are skipped.
+func enqueueOnchain(repos *repository.Repositories, action models.OnchainAction, invoiceID uuid.UUID, poolID *uuid.UUID, transactionIDs []uuid.UUID, payload *models.OnchainPayload) error {
+	nft, err := repos.Invoices.FindNFTByInvoiceID(invoiceID)
 	if err != nil {
 		return err
 	}
-	catalystInvestments, err := s.fundingRepo.FindInvestmentsByPoolAndTranche(pool.ID, models.TrancheCatalyst)
+	if nft == nil {
+		return nil
+	}
+	raw, err := json.Marshal(payload)
 	if err != nil {
 		return err
 	}
+	return repos.Outbox.Enqueue(&models.OnchainOutboxEntry{
+		Action:         action,
+		InvoiceID:      invoiceID,
+		PoolID:         poolID,
+		TransactionIDs: transactionIDs,
+		Payload:        raw,
+	})
+}
 
-	// Calculate total expected returns for each tranche
-	totalPriorityExpected := 0.0
-	for _, inv := range priorityInvestments {
-		totalPriorityExpected += inv.ExpectedReturn
-	}
-	totalCatalystExpected := 0.0
-	for _, inv := range catalystInvestments {
-		totalCatalystExpected += inv.ExpectedReturn
-	}
+func stringPtr(s string) *string {
+	return &s
+}
 
-	// Total investor returns needed (priority + catalyst) - for logging
-	_ = totalPriorityExpected + totalCatalystExpected // Used for validation, actual calculation happens below
+// ClosePoolAndNotifyExporter closes a funding pool when deadline ends
+// It disburses funds to exporter and sends payment notification to exporter's email
+// containing invoice details for the importer to pay
+func (s *FundingService) ClosePoolAndNotifyExporter(poolID uuid.UUID) (*models.ExporterPaymentNotificationData, error) {
+	// Refactored to reuse DisburseToExporter which now handles notification
+	return s.DisburseToExporter(poolID)
+}
 
-	// Priority-first distribution
-	// Step 1: Pay priority investors first
-	priorityPaid := 0.0
-	for _, inv := range priorityInvestments {
-		// Calculate pro-rata share
-		var actualReturn float64
-		if remainingAmount >= totalPriorityExpected {
-			// Full repayment for priority
-			actualReturn = inv.ExpectedReturn
-		} else {
-			// Partial repayment - distribute proportionally
-			proportion := inv.ExpectedReturn / totalPriorityExpected
-			actualReturn = remainingAmount * proportion
-		}
-		priorityPaid += actualReturn
+// Pool expiry policies applied when a pool reaches its deadline unfilled
+const (
+	PoolExpiryPolicyRefund  = "refund"  // Refund every investor
+	PoolExpiryPolicyPartial = "partial" // Disburse what was raised when above the minimum, otherwise refund
+)
 
-		s.fundingRepo.UpdateInvestmentStatus(inv.ID, models.InvestmentStatusRepaid, &actualReturn)
+// ProcessExpiredPools applies the configured expiry policy to every open pool
+// whose deadline has passed. It returns the number of pools handled.
+func (s *FundingService) ProcessExpiredPools() (int, error) {
+	pools, err := s.fundingRepo.FindExpiredOpenPools(time.Now())
+	if err != nil {
+		return 0, err
+	}
 
-		// Create return transaction
-		tx := &models.Transaction{
-			InvoiceID: &invoiceID,
-			UserID:    &inv.InvestorID,
-			Type:      models.TxTypeInvestorReturn,
-			Amount:    actualReturn,
-			Currency:  "IDR",
-			Status:    models.TxStatusPending,
-			Notes:     stringPtr("Priority tranche repayment"),
+	handled := 0
+	for _, pool := range pools {
+		if err := s.expirePool(&pool); err != nil {
+			fmt.Printf("[POOL_EXPIRY] Failed to expire pool %s: %v\n", pool.ID, err)
+			continue
 		}
-		s.txRepo.Create(tx)
+		handled++
 	}
+	return handled, nil
+}
 
-	// Step 2: Pay catalyst investors with remaining amount (if any)
-	catalystPaid := 0.0
-	remainingAfterPriority := remainingAmount - priorityPaid
-	if remainingAfterPriority > 0 && len(catalystInvestments) > 0 {
-		for _, inv := range catalystInvestments {
-			var actualReturn float64
-			if remainingAfterPriority >= totalCatalystExpected {
-				// Full repayment for catalyst
-				actualReturn = inv.ExpectedReturn
-			} else {
-				// Partial repayment - distribute proportionally
-				proportion := inv.ExpectedReturn / totalCatalystExpected
-				actualReturn = remainingAfterPriority * proportion
-			}
-			catalystPaid += actualReturn
+// expirePool disburses an expired pool under the partial policy when it raised
+// enough, and refunds it otherwise
+func (s *FundingService) expirePool(pool *models.FundingPool) error {
+	if s.cfg.PoolExpiryPolicy == PoolExpiryPolicyPartial && pool.FundedAmount > 0 &&
+		money.Ratio(pool.FundedAmount, pool.TargetAmount)*100 >= s.cfg.PoolMinFundingPercentage {
+		// DisburseToExporter notifies the exporter with the payment details
+		if _, err := s.DisburseToExporter(pool.ID); err != nil {
+			return fmt.Errorf("partial disbursement failed: %w", err)
+		}
+		s.notifyPoolPartiallyFunded(pool.ID)
+		return nil
+	}
+	return s.RefundPool(pool.ID)
+}
 
-			s.fundingRepo.UpdateInvestmentStatus(inv.ID, models.InvestmentStatusRepaid, &actualReturn)
+// notifyPoolPartiallyFunded tells every investor of an expired pool that it was
+// disbursed at the amount it raised rather than its target
+func (s *FundingService) notifyPoolPartiallyFunded(poolID uuid.UUID) {
+	if s.emailService == nil {
+		return
+	}
+	pool, err := s.fundingRepo.FindPoolByID(poolID)
+	if err != nil || pool == nil {
+		fmt.Printf("[POOL_EXPIRY] Pool %s not found for notifications\n", poolID)
+		return
+	}
+	invoice, err := s.invoiceRepo.FindByID(pool.InvoiceID)
+	if err != nil || invoice == nil {
+		fmt.Printf("[POOL_EXPIRY] Invoice %s not found for notifications\n", pool.InvoiceID)
+		return
+	}
+	investments, err := s.fundingRepo.FindInvestmentsByPool(pool.ID)
+	if err != nil {
+		fmt.Printf("[POOL_EXPIRY] Failed to load investments of pool %s for notifications: %v\n", pool.ID, err)
+		return
+	}
 
-			// Create return transaction
-			tx := &models.Transaction{
-				InvoiceID: &invoiceID,
-				UserID:    &inv.InvestorID,
-				Type:      models.TxTypeInvestorReturn,
-				Amount:    actualReturn,
-				Currency:  "IDR",
-				Status:    models.TxStatusPending,
-				Notes:     stringPtr("Catalyst tranche repayment"),
-			}
-			s.txRepo.Create(tx)
+	for _, inv := range investments {
+		if inv.Status != models.InvestmentStatusActive {
+			continue
+		}
+		investor, err := s.userRepo.FindByID(inv.InvestorID)
+		if err != nil || investor == nil {
+			continue
 		}
-	} else if len(catalystInvestments) > 0 {
-		// Mark catalyst as defaulted if no remaining funds
-		for _, inv := range catalystInvestments {
-			zero := 0.0
-			s.fundingRepo.UpdateInvestmentStatus(inv.ID, models.InvestmentStatusDefaulted, &zero)
+		if err := s.emailService.SendPoolPartiallyFundedEmail(investor.Email, invoice.InvoiceNumber, inv.Amount, pool.FundedAmount, pool.TargetAmount, pool.PoolCurrency); err != nil {
+			fmt.Printf("[POOL_EXPIRY] Failed to send partial funding email for investment %s: %v\n", inv.ID, err)
 		}
 	}
+}
 
-	// Step 3: PARTIAL FUNDING SCENARIO
-	// If importer paid more than what investors need (common when only partial funding occurred)
-	// Example: Invoice 100k, only 10k funded (returns 11k), importer pays 100k
-	// Excess: 100k - platformFee - 11k = goes to mitra's balance
-	totalPaidToInvestors := priorityPaid + catalystPaid
-	excessForMitra := remainingAmount - totalPaidToInvestors
+// RefundPool refunds every active investment of an open pool, marks the pool
+// expired and the invoice expired, then notifies the investors and the exporter
+func (s *FundingService) RefundPool(poolID uuid.UUID) error {
+	var pool *models.FundingPool
+	var refunded []models.Investment
+	err := s.uow.Do(func(repos *repository.Repositories) error {
+		var err error
+		pool, err = repos.Funding.FindPoolByIDForUpdate(poolID)
+		if err != nil {
+			return err
+		}
+		if pool == nil {
+			return errors.New("pool not found")
+		}
+		if pool.Status != models.PoolStatusOpen {
+			return errors.New("only open pools can be refunded")
+		}
 
-	if excessForMitra > 0 {
-		// Credit excess to mitra's balance
-		mitra, err := s.userRepo.FindByID(invoice.ExporterID)
-		if err == nil && mitra != nil {
-			newBalance := mitra.BalanceIDR + excessForMitra
-			s.userRepo.UpdateBalance(invoice.ExporterID, newBalance)
+		investments, err := repos.Funding.FindInvestmentsByPool(poolID)
+		if err != nil {
+			return err
+		}
 
-			// Create transaction record for mitra
-			tx := &models.Transaction{
-				InvoiceID: &invoiceID,
-				UserID:    &invoice.ExporterID,
-				Type:      models.TxTypeRepaymentExcess,
-				Amount:    excessForMitra,
-				Currency:  "IDR",
-				Status:    models.TxStatusConfirmed,
-				Notes:     stringPtr(fmt.Sprintf("Excess from invoice repayment (partial funding scenario). Total paid: %.2f, Investor returns: %.2f, Excess to mitra: %.2f", amount, totalPaidToInvestors, excessForMitra)),
+		var payouts []LedgerPayout
+		for _, inv := range investments {
+			if inv.Status != models.InvestmentStatusActive {
+				continue
 			}
-			s.txRepo.Create(tx)
-
-			fmt.Printf("[REPAYMENT] Partial funding scenario: Invoice=%s, TotalPaid=%.2f, InvestorReturns=%.2f, ExcessToMitra=%.2f\n",
-				invoiceID.String(), amount, totalPaidToInvestors, excessForMitra)
-
-			// On-Chain Transparency: Record mitra balance credit
-			go func() {
-				if s.blockchainService != nil && mitra.WalletAddress != nil {
-					_, err := s.blockchainService.RecordMitraBalanceCredit(invoiceID, *mitra.WalletAddress, excessForMitra)
-					if err != nil {
-						fmt.Printf("Failed to record mitra balance credit on-chain: %v\n", err)
-					}
-				}
-			}()
+			payouts = append(payouts, LedgerPayout{UserID: inv.InvestorID, Amount: inv.Amount})
+			refunded = append(refunded, inv)
 		}
-	}
-
-	// Close pool and update invoice
-	s.fundingRepo.UpdatePoolStatus(pool.ID, models.PoolStatusClosed)
-	s.invoiceRepo.UpdateStatus(invoiceID, models.StatusRepaid)
 
-	// Record platform fee as a transaction for admin tracking
-	if platformFee > 0 {
-		platformFeeTx := &models.Transaction{
-			InvoiceID: &invoiceID,
-			Type:      models.TxTypePlatformFee,
-			Amount:    platformFee,
-			Currency:  "IDR",
-			Status:    models.TxStatusConfirmed,
-			Notes:     stringPtr(fmt.Sprintf("Platform fee (%.1f%%) from mitra repayment - Invoice: %s", s.cfg.PlatformFeePercentage, invoice.InvoiceNumber)),
+		// Return pool funds to investor wallets
+		if err := s.ledgerService.WithRepository(repos.Ledger).RecordRefund(poolID, payouts); err != nil {
+			return fmt.Errorf("failed to post refund: %w", err)
 		}
-		s.txRepo.Create(platformFeeTx)
 
-		fmt.Printf("[PLATFORM_FEE] Recorded: Invoice=%s, Amount=%.2f, Rate=%.1f%%\n",
-			invoiceID.String(), platformFee, s.cfg.PlatformFeePercentage)
-	}
-
-	// On-Chain Transparency: Record repayment
-	go func() {
-		if s.blockchainService != nil {
-			_, err := s.blockchainService.RecordRepayment(pool.ID, amount)
-			if err != nil {
-				fmt.Printf("Failed to record repayment on-chain: %v\n", err)
+		for _, inv := range refunded {
+			if err := repos.Funding.UpdateInvestmentStatus(inv.ID, models.InvestmentStatusRefunded, nil); err != nil {
+				return err
+			}
+			investorID := inv.InvestorID
+			tx := &models.Transaction{
+				InvoiceID: &pool.InvoiceID,
+				UserID:    &investorID,
+				Type:      models.TxTypeRefund,
+				Amount:    inv.Amount,
+				Currency:  pool.PoolCurrency,
+				Status:    models.TxStatusConfirmed,
+				Notes:     stringPtr("Refund: funding pool expired before reaching its target"),
+			}
+			if err := repos.Transactions.Create(tx); err != nil {
+				return err
 			}
 		}
-	}()
 
+		if err := repos.Funding.UpdatePoolStatus(poolID, models.PoolStatusExpired); err != nil {
+			return err
+		}
+		return repos.Invoices.UpdateStatus(pool.InvoiceID, models.StatusExpired)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.notifyPoolRefunded(pool, refunded)
 	return nil
 }
 
-func stringPtr(s string) *string {
-	return &s
-}
+// notifyPoolRefunded emails each refunded investor and the exporter
+func (s *FundingService) notifyPoolRefunded(pool *models.FundingPool, refunded []models.Investment) {
+	if s.emailService == nil {
+		return
+	}
+	invoice, err := s.invoiceRepo.FindByID(pool.InvoiceID)
+	if err != nil || invoice == nil {
+		fmt.Printf("[POOL_EXPIRY] Invoice %s not found for notifications\n", pool.InvoiceID)
+		return
+	}
 
-// ClosePoolAndNotifyExporter closes a funding pool when deadline ends
-// It disburses funds to exporter and sends payment notification to exporter's email
-// containing invoice details for the importer to pay
-func (s *FundingService) ClosePoolAndNotifyExporter(poolID uuid.UUID) (*models.ExporterPaymentNotificationData, error) {
-	// Refactored to reuse DisburseToExporter which now handles notification
-	return s.DisburseToExporter(poolID)
+	for _, inv := range refunded {
+		investor, err := s.userRepo.FindByID(inv.InvestorID)
+		if err != nil || investor == nil {
+			continue
+		}
+		if err := s.emailService.SendPoolRefundEmail(investor.Email, invoice.InvoiceNumber, inv.Amount, pool.PoolCurrency); err != nil {
+			fmt.Printf("[POOL_EXPIRY] Failed to send refund email for investment %s: %v\n", inv.ID, err)
+		}
+	}
+
+	exporter, err := s.userRepo.FindByID(invoice.ExporterID)
+	if err != nil || exporter == nil {
+		return
+	}
+	if err := s.emailService.SendPoolExpiredEmail(exporter.Email, invoice.InvoiceNumber, pool.FundedAmount, pool.TargetAmount, pool.PoolCurrency); err != nil {
+		fmt.Printf("[POOL_EXPIRY] Failed to send expiry email for pool %s: %v\n", pool.ID, err)
+	}
 }
 
 // ExporterDisbursementRequest represents request for exporter to disburse to investors
 type ExporterDisbursementRequest struct {
-	PoolID uuid.UUID `json:"pool_id" binding:"required"`
-	Amount float64   `json:"amount" binding:"required,gt=0"` // Amount exporter is paying
+	PoolID uuid.UUID    `json:"pool_id" binding:"required"`
+	Amount money.Amount `json:"amount" binding:"required,gt=0"` // Amount exporter is paying
 }
 
 // ExporterDisbursementResponse represents the disbursement result
 type ExporterDisbursementResponse struct {
 	PoolID                uuid.UUID              `json:"pool_id"`
 	InvoiceID             uuid.UUID              `json:"invoice_id"`
-	TotalRequired         float64                `json:"total_required"`     // Total amount required (principal + interest)
-	TotalPaid             float64                `json:"total_paid"`         // Amount exporter paid
-	TotalDisbursed        float64                `json:"total_disbursed"`    // Amount disbursed to investors
-	PriorityDisbursed     float64                `json:"priority_disbursed"` // Amount to priority investors
-	CatalystDisbursed     float64                `json:"catalyst_disbursed"` // Amount to catalyst investors
+	TotalRequired         money.Amount           `json:"total_required"`     // Total amount still owed (principal + interest + late charges)
+	TotalPaid             money.Amount           `json:"total_paid"`         // Amount exporter paid
+	TotalDisbursed        money.Amount           `json:"total_disbursed"`    // Amount disbursed to investors
+	PriorityDisbursed     money.Amount           `json:"priority_disbursed"` // Amount to priority investors
+	CatalystDisbursed     money.Amount           `json:"catalyst_disbursed"` // Amount to catalyst investors
 	PriorityFullyPaid     bool                   `json:"priority_fully_paid"`
 	CatalystFullyPaid     bool                   `json:"catalyst_fully_paid"`
+	Tranches              []TrancheDisbursement  `json:"tranches"` // Every tranche, most senior first
 	InvestorDisbursements []InvestorDisbursement `json:"investor_disbursements"`
 	Status                string                 `json:"status"`
 	Message               string                 `json:"message"`
 }
 
+// TrancheDisbursement is what one tranche received from the exporter's payment
+type TrancheDisbursement struct {
+	Tranche   string       `json:"tranche"`
+	Disbursed money.Amount `json:"disbursed"`
+	FullyPaid bool         `json:"fully_paid"`
+}
+
 // InvestorDisbursement represents disbursement to a single investor
 type InvestorDisbursement struct {
-	InvestorID     uuid.UUID `json:"investor_id"`
-	WalletAddress  string    `json:"wallet_address"`
-	Tranche        string    `json:"tranche"`
-	Principal      float64   `json:"principal"`
-	ExpectedReturn float64   `json:"expected_return"`
-	ActualReturn   float64   `json:"actual_return"`
-	Status         string    `json:"status"` // full, partial, none
+	InvestorID     uuid.UUID    `json:"investor_id"`
+	WalletAddress  string       `json:"wallet_address"`
+	Tranche        string       `json:"tranche"`
+	Principal      money.Amount `json:"principal"`
+	ExpectedReturn money.Amount `json:"expected_return"`
+	ActualReturn   money.Amount `json:"actual_return"`
+	Status         string       `json:"status"` // full, partial, none
 }
 
 // ExporterDisbursementToInvestors handles exporter paying back investors
-// Flow: Exporter pays escrow -> System calculates shares -> Escrow disburses to investors
-// Priority: Priority tranche gets paid first, Catalyst gets remainder
+// Flow: Exporter pays escrow -> the payment is applied as one installment through
+// the same seniority waterfall as importer payments. A payment short of what is
+// owed leaves the pool disbursed, so overdue detection and default still apply.
 func (s *FundingService) ExporterDisbursementToInvestors(exporterID uuid.UUID, req *ExporterDisbursementRequest) (*ExporterDisbursementResponse, error) {
-	// Get pool
 	pool, err := s.fundingRepo.FindPoolByID(req.PoolID)
 	if err != nil {
 		return nil, err
@@ -897,7 +1571,7 @@ func (s *FundingService) ExporterDisbursementToInvestors(exporterID uuid.UUID, r
 		return nil, errors.New("pool must be in disbursed status for exporter to repay investors")
 	}
 
-	// Get invoice
+	// Verify exporter owns this invoice
 	invoice, err := s.invoiceRepo.FindByID(pool.InvoiceID)
 	if err != nil {
 		return nil, err
@@ -905,239 +1579,96 @@ func (s *FundingService) ExporterDisbursementToInvestors(exporterID uuid.UUID, r
 	if invoice == nil {
 		return nil, errors.New("invoice not found")
 	}
-
-	// Verify exporter owns this invoice
 	if invoice.ExporterID != exporterID {
 		return nil, errors.New("unauthorized: you do not own this invoice")
 	}
 
-	// Get all investments
-	investments, err := s.fundingRepo.FindInvestmentsByPool(pool.ID)
-	if err != nil {
-		return nil, err
-	}
-
-	// Separate by tranche
-	var priorityInvestments, catalystInvestments []models.Investment
-	totalPriorityRequired := 0.0
-	totalCatalystRequired := 0.0
-
-	for _, inv := range investments {
-		if inv.Tranche == models.TranchePriority {
-			priorityInvestments = append(priorityInvestments, inv)
-			totalPriorityRequired += inv.ExpectedReturn
-		} else {
-			catalystInvestments = append(catalystInvestments, inv)
-			totalCatalystRequired += inv.ExpectedReturn
-		}
-	}
-
-	totalRequired := totalPriorityRequired + totalCatalystRequired
-	amountPaid := req.Amount
-	remainingFunds := amountPaid
-
 	// Verify with escrow (dummy)
 	if s.escrowService != nil {
-		verified, _, err := s.escrowService.VerifyExporterDeposit(invoice.ID, amountPaid)
+		verified, _, err := s.escrowService.VerifyExporterDeposit(invoice.ID, req.Amount)
 		if err != nil || !verified {
 			return nil, errors.New("failed to verify exporter deposit in escrow")
 		}
 	}
 
-	var disbursementTargets []DisbursementTarget
-	var investorDisbursements []InvestorDisbursement
-	priorityDisbursed := 0.0
-	catalystDisbursed := 0.0
-	priorityFullyPaid := true
-	catalystFullyPaid := true
-
-	// STEP 1: Pay Priority Tranche First
-	for _, inv := range priorityInvestments {
-		investor, _ := s.userRepo.FindByID(inv.InvestorID)
-		// Note: WalletAddress removed - funds go to registered bank account
-		_ = investor // investor data available for bank account lookup
-
-		var actualReturn float64
-		var status string
-
-		if remainingFunds >= inv.ExpectedReturn {
-			// Full payment
-			actualReturn = inv.ExpectedReturn
-			remainingFunds -= inv.ExpectedReturn
-			status = "full"
-		} else if remainingFunds > 0 {
-			// Partial payment
-			actualReturn = remainingFunds
-			remainingFunds = 0
-			status = "partial"
-			priorityFullyPaid = false
-		} else {
-			// No payment
-			actualReturn = 0
-			status = "none"
-			priorityFullyPaid = false
-		}
-
-		if actualReturn > 0 {
-			disbursementTargets = append(disbursementTargets, DisbursementTarget{
-				InvestorID:    inv.InvestorID,
-				WalletAddress: "", // Deprecated - funds go to bank account
-				Amount:        actualReturn,
-				Principal:     inv.Amount,
-				ReturnAmount:  actualReturn - inv.Amount,
-				Tranche:       string(models.TranchePriority),
-				Currency:      "IDR",
-			})
-			priorityDisbursed += actualReturn
-
-			// Update investment status
-			invStatus := models.InvestmentStatusRepaid
-			if status != "full" {
-				invStatus = models.InvestmentStatusDefaulted
-			}
-			s.fundingRepo.UpdateInvestmentStatus(inv.ID, invStatus, &actualReturn)
+	var result *repaymentResult
+	err = s.uow.Do(func(repos *repository.Repositories) error {
+		var err error
+		result, err = s.repayPool(repos, pool.ID, req.Amount)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		// The pool left disbursed status before the payment was applied
+		return nil, errors.New("pool must be in disbursed status for exporter to repay investors")
+	}
+	return disbursementResponse(pool.ID, invoice.ID, req.Amount, result), nil
+}
 
-			// Create transaction
-			tx := &models.Transaction{
-				InvoiceID: &invoice.ID,
-				UserID:    &inv.InvestorID,
-				Type:      models.TxTypeInvestorReturn,
-				Amount:    actualReturn,
-				Currency:  "IDRX",
-				Status:    models.TxStatusPending,
-				Notes:     stringPtr("Priority tranche return from exporter"),
-			}
-			s.txRepo.Create(tx)
+// disbursementResponse reports an applied installment per tranche and per investor
+func disbursementResponse(poolID, invoiceID uuid.UUID, amount money.Amount, result *repaymentResult) *ExporterDisbursementResponse {
+	response := &ExporterDisbursementResponse{
+		PoolID:                poolID,
+		InvoiceID:             invoiceID,
+		TotalRequired:         result.totalOwed,
+		TotalPaid:             amount,
+		PriorityFullyPaid:     true,
+		CatalystFullyPaid:     true,
+		Tranches:              []TrancheDisbursement{},
+		InvestorDisbursements: []InvestorDisbursement{},
+		Status:                "completed",
+		Message:               "Disbursement completed successfully",
+	}
+	if !result.settled {
+		response.Status = "partial"
+		response.Message = "Partial disbursement completed. Senior tranches paid first, the rest remains owed."
+	}
+
+	trancheIndex := make(map[models.TrancheType]int)
+	for _, share := range result.shares {
+		inv := share.investment
+		idx, ok := trancheIndex[inv.Tranche]
+		if !ok {
+			idx = len(response.Tranches)
+			trancheIndex[inv.Tranche] = idx
+			response.Tranches = append(response.Tranches, TrancheDisbursement{Tranche: string(inv.Tranche), FullyPaid: true})
 		}
 
-		investorDisbursements = append(investorDisbursements, InvestorDisbursement{
-			InvestorID:     inv.InvestorID,
-			WalletAddress:  "", // Deprecated - funds go to bank account
-			Tranche:        string(models.TranchePriority),
-			Principal:      inv.Amount,
-			ExpectedReturn: inv.ExpectedReturn,
-			ActualReturn:   actualReturn,
-			Status:         status,
-		})
-	}
-
-	// STEP 2: Pay Catalyst Tranche (only if there's remaining funds)
-	for _, inv := range catalystInvestments {
-		investor, _ := s.userRepo.FindByID(inv.InvestorID)
-		// Note: WalletAddress removed - funds go to registered bank account
-		_ = investor // investor data available for bank account lookup
-
-		var actualReturn float64
-		var status string
-
-		if remainingFunds >= inv.ExpectedReturn {
-			// Full payment
-			actualReturn = inv.ExpectedReturn
-			remainingFunds -= inv.ExpectedReturn
-			status = "full"
-		} else if remainingFunds > 0 {
-			// Partial payment
-			actualReturn = remainingFunds
-			remainingFunds = 0
-			status = "partial"
-			catalystFullyPaid = false
-		} else {
-			// No payment (catalyst gets nothing if priority didn't get full)
-			actualReturn = 0
+		paid := share.amount + share.lateCharge
+		status := "full"
+		if share.amount < share.owed {
+			response.Tranches[idx].FullyPaid = false
 			status = "none"
-			catalystFullyPaid = false
-		}
-
-		if actualReturn > 0 {
-			disbursementTargets = append(disbursementTargets, DisbursementTarget{
-				InvestorID:    inv.InvestorID,
-				WalletAddress: "", // Deprecated - funds go to bank account
-				Amount:        actualReturn,
-				Principal:     inv.Amount,
-				ReturnAmount:  actualReturn - inv.Amount,
-				Tranche:       string(models.TrancheCatalyst),
-				Currency:      "IDR",
-			})
-			catalystDisbursed += actualReturn
-
-			// Update investment status
-			invStatus := models.InvestmentStatusRepaid
-			if status != "full" {
-				invStatus = models.InvestmentStatusDefaulted
-			}
-			s.fundingRepo.UpdateInvestmentStatus(inv.ID, invStatus, &actualReturn)
-
-			// Create transaction
-			tx := &models.Transaction{
-				InvoiceID: &invoice.ID,
-				UserID:    &inv.InvestorID,
-				Type:      models.TxTypeInvestorReturn,
-				Amount:    actualReturn,
-				Currency:  "IDRX",
-				Status:    models.TxStatusPending,
-				Notes:     stringPtr("Catalyst tranche return from exporter"),
+			if paid > 0 {
+				status = "partial"
 			}
-			s.txRepo.Create(tx)
-		} else {
-			// Mark as defaulted
-			zero := 0.0
-			s.fundingRepo.UpdateInvestmentStatus(inv.ID, models.InvestmentStatusDefaulted, &zero)
 		}
+		response.Tranches[idx].Disbursed += paid
+		response.TotalDisbursed += paid
 
-		investorDisbursements = append(investorDisbursements, InvestorDisbursement{
+		response.InvestorDisbursements = append(response.InvestorDisbursements, InvestorDisbursement{
 			InvestorID:     inv.InvestorID,
 			WalletAddress:  "", // Deprecated - funds go to bank account
-			Tranche:        string(models.TrancheCatalyst),
+			Tranche:        string(inv.Tranche),
 			Principal:      inv.Amount,
 			ExpectedReturn: inv.ExpectedReturn,
-			ActualReturn:   actualReturn,
+			ActualReturn:   paid,
 			Status:         status,
 		})
 	}
 
-	// Process disbursement via escrow (dummy)
-	if s.escrowService != nil && len(disbursementTargets) > 0 {
-		instruction, err := s.escrowService.CreateDisbursementInstruction(pool.ID, invoice.ID, disbursementTargets)
-		if err != nil {
-			return nil, err
-		}
-		_, err = s.escrowService.ProcessDisbursement(instruction)
-		if err != nil {
-			return nil, err
-		}
+	// The legacy fields report the tranches named priority and catalyst
+	if idx, ok := trancheIndex[models.TranchePriority]; ok {
+		response.PriorityDisbursed = response.Tranches[idx].Disbursed
+		response.PriorityFullyPaid = response.Tranches[idx].FullyPaid
 	}
-
-	// Update pool and invoice status
-	s.fundingRepo.UpdatePoolStatus(pool.ID, models.PoolStatusClosed)
-	if priorityFullyPaid && catalystFullyPaid {
-		s.invoiceRepo.UpdateStatus(invoice.ID, models.StatusRepaid)
-	} else {
-		s.invoiceRepo.UpdateStatus(invoice.ID, models.StatusDefaulted)
-	}
-
-	// Build response
-	totalDisbursed := priorityDisbursed + catalystDisbursed
-	status := "completed"
-	message := "Disbursement completed successfully"
-	if amountPaid < totalRequired {
-		status = "partial"
-		message = "Partial disbursement completed. Priority tranche paid first."
-	}
-
-	return &ExporterDisbursementResponse{
-		PoolID:                pool.ID,
-		InvoiceID:             invoice.ID,
-		TotalRequired:         totalRequired,
-		TotalPaid:             amountPaid,
-		TotalDisbursed:        totalDisbursed,
-		PriorityDisbursed:     priorityDisbursed,
-		CatalystDisbursed:     catalystDisbursed,
-		PriorityFullyPaid:     priorityFullyPaid,
-		CatalystFullyPaid:     catalystFullyPaid,
-		InvestorDisbursements: investorDisbursements,
-		Status:                status,
-		Message:               message,
-	}, nil
+	if idx, ok := trancheIndex[models.TrancheCatalyst]; ok {
+		response.CatalystDisbursed = response.Tranches[idx].Disbursed
+		response.CatalystFullyPaid = response.Tranches[idx].FullyPaid
+	}
+	return response
 }
 
 // GetPoolDetail returns comprehensive pool details for investor decision making (Flow 6)
@@ -1168,15 +1699,15 @@ func (s *FundingService) GetPoolDetail(poolID uuid.UUID) (*models.PoolDetailResp
 	// Calculate progress
 	priorityProgress := 0.0
 	if pool.PriorityTarget > 0 {
-		priorityProgress = (pool.PriorityFunded / pool.PriorityTarget) * 100
+		priorityProgress = money.Ratio(pool.PriorityFunded, pool.PriorityTarget) * 100
 	}
 	catalystProgress := 0.0
 	if pool.CatalystTarget > 0 {
-		catalystProgress = (pool.CatalystFunded / pool.CatalystTarget) * 100
+		catalystProgress = money.Ratio(pool.CatalystFunded, pool.CatalystTarget) * 100
 	}
 	totalProgress := 0.0
 	if pool.TargetAmount > 0 {
-		totalProgress = (pool.FundedAmount / pool.TargetAmount) * 100
+		totalProgress = money.Ratio(pool.FundedAmount, pool.TargetAmount) * 100
 	}
 
 	// Calculate remaining time
@@ -1204,8 +1735,8 @@ func (s *FundingService) GetPoolDetail(poolID uuid.UUID) (*models.PoolDetailResp
 	buyerInfo := models.BuyerDetailInfo{
 		CompanyName:  invoice.BuyerName,
 		Country:      invoice.BuyerCountry,
-		CountryFlag:  getCountryFlag(invoice.BuyerCountry),
-		CountryRisk:  getCountryRisk(invoice.BuyerCountry),
+		CountryFlag:  s.countryTiers.Flag(invoice.BuyerCountry),
+		CountryRisk:  countryRiskTitle(s.countryTiers.RiskLevel(invoice.BuyerCountry)),
 		Industry:     "", // Buyer doesn't have industry field
 		IsRepeat:     invoice.IsRepeatBuyer,
 		TotalHistory: 0, // History tracking requires efficient querying by name, omitted for MVP refactor
@@ -1270,6 +1801,35 @@ func (s *FundingService) GetPoolDetail(poolID uuid.UUID) (*models.PoolDetailResp
 		InfoBox:             "Tranche Katalis dibayar setelah Prioritas. Imbal hasil lebih tinggi, namun dalam kondisi gagal bayar berisiko tidak menerima pembayaran penuh.",
 	}
 
+	tranches := make([]models.TrancheInfo, 0, len(pool.Tranches))
+	for i := range pool.Tranches {
+		t := &pool.Tranches[i]
+		var info models.TrancheInfo
+		switch t.Name {
+		case models.TranchePriority:
+			info = priorityTranche
+		case models.TrancheCatalyst:
+			info = catalystTranche
+		default:
+			info = genericTrancheInfo(t, len(pool.Tranches))
+		}
+		info.TargetAmount = t.TargetAmount
+		info.FundedAmount = t.FundedAmount
+		info.RemainingAmount = t.Remaining()
+		info.ProgressPercent = 0
+		if t.TargetAmount > 0 {
+			info.ProgressPercent = money.Ratio(t.FundedAmount, t.TargetAmount) * 100
+		}
+		info.InterestRate = t.InterestRate
+		info.InterestRateDisplay = fmt.Sprintf("%.1f%% p.a", t.InterestRate)
+		info.Seniority = t.Seniority
+		info.RequiresRiskConsent = t.RequiresRiskConsent
+		info.RequiresRiskProfile = t.RequiresRiskProfile
+		info.MinInvestment = t.MinInvestment
+		info.MaxInvestment = t.MaxInvestment
+		tranches = append(tranches, info)
+	}
+
 	// Get grade and score (handle nil pointers)
 	grade := ""
 	if invoice.Grade != nil {
@@ -1311,9 +1871,34 @@ func (s *FundingService) GetPoolDetail(poolID uuid.UUID) (*models.PoolDetailResp
 		Documents:       documents,
 		PriorityTranche: priorityTranche,
 		CatalystTranche: catalystTranche,
+		Tranches:        tranches,
 	}, nil
 }
 
+// genericTrancheInfo describes a tranche by its place in the waterfall: the
+// most senior tranche is the safest and the most junior takes the first loss
+func genericTrancheInfo(t *models.PoolTranche, count int) models.TrancheInfo {
+	info := models.TrancheInfo{
+		Type:             string(t.Name),
+		TypeDisplay:      trancheDisplayName(t.Name),
+		Description:      fmt.Sprintf("Urutan pembayaran ke-%d dari %d tranche.", t.Seniority, count),
+		RiskLevel:        "Medium",
+		RiskLevelDisplay: "Risiko Menengah",
+		InfoBox:          "Tranche ini dibayar setelah tranche yang lebih senior dan sebelum tranche yang lebih junior.",
+	}
+	switch t.Seniority {
+	case 1:
+		info.RiskLevel = "Low"
+		info.RiskLevelDisplay = "Risiko Rendah"
+		info.InfoBox = "Tranche ini mendapat pembayaran terlebih dahulu dari hasil pelunasan."
+	case count:
+		info.RiskLevel = "High"
+		info.RiskLevelDisplay = "Risiko Tinggi"
+		info.InfoBox = "Tranche ini dibayar paling akhir dan pertama menanggung kerugian saat gagal bayar."
+	}
+	return info
+}
+
 // Helper functions
 // Helper functions used to include isRepeatBuyer and getBuyerHistoryCount, now removed.
 
@@ -1327,47 +1912,12 @@ func (s *FundingService) getExporterSuccessRate(exporterID uuid.UUID) float64 {
 	return 100.0
 }
 
-func getCountryFlag(country string) string {
-	flags := map[string]string{
-		"Indonesia":      "🇮🇩",
-		"United States":  "🇺🇸",
-		"Germany":        "🇩🇪",
-		"Japan":          "🇯🇵",
-		"South Korea":    "🇰🇷",
-		"Singapore":      "🇸🇬",
-		"Australia":      "🇦🇺",
-		"Netherlands":    "🇳🇱",
-		"United Kingdom": "🇬🇧",
-		"France":         "🇫🇷",
-		"China":          "🇨🇳",
-		"India":          "🇮🇳",
-		"Malaysia":       "🇲🇾",
-		"Thailand":       "🇹🇭",
-		"Vietnam":        "🇻🇳",
-	}
-	if flag, ok := flags[country]; ok {
-		return flag
-	}
-	return "🏳️"
-}
-
-func getCountryRisk(country string) string {
-	// Tier 1 - Low Risk
-	tier1 := []string{"United States", "Germany", "Japan", "South Korea", "Singapore", "Australia", "Netherlands", "United Kingdom", "France"}
-	for _, c := range tier1 {
-		if c == country {
-			return "Low"
-		}
+// countryRiskTitle capitalizes a risk level for investor-facing pages: Low, Medium, High
+func countryRiskTitle(level string) string {
+	if level == "" {
+		return level
 	}
-	// Tier 2 - Medium Risk
-	tier2 := []string{"China", "India", "Indonesia", "Malaysia", "Thailand", "Vietnam"}
-	for _, c := range tier2 {
-		if c == country {
-			return "Medium"
-		}
-	}
-	// Default - High Risk
-	return "High"
+	return strings.ToUpper(level[:1]) + level[1:]
 }
 
 // CalculateInvestmentReturns calculates projected returns for investment calculator (Flow 6)
@@ -1385,38 +1935,34 @@ func (s *FundingService) CalculateInvestmentReturns(req *models.InvestmentCalcul
 		return nil, err
 	}
 
-	// Get interest rate based on tranche
-	var interestRate float64
-	var maxInvestable float64
-	var trancheDisplay string
-
-	if req.Tranche == string(models.TranchePriority) {
-		interestRate = pool.PriorityInterestRate
-		maxInvestable = pool.PriorityTarget - pool.PriorityFunded
-		trancheDisplay = "Prioritas"
-	} else {
-		interestRate = pool.CatalystInterestRate
-		maxInvestable = pool.CatalystTarget - pool.CatalystFunded
-		trancheDisplay = "Katalis"
-	}
-
-	// Calculate tenor in days (for display only)
-	tenorDays := int(time.Until(invoice.DueDate).Hours() / 24)
-	if tenorDays < 0 {
-		tenorDays = 0
+	// Get interest rate and capacity of the tranche
+	tranche := pool.Tranche(models.TrancheType(req.Tranche))
+	if tranche == nil {
+		return nil, ErrTrancheNotFound
 	}
-	if tenorDays == 0 {
-		tenorDays = 60 // Default
+	interestRate := tranche.InterestRate
+	maxInvestable := tranche.Remaining()
+	if tranche.MaxInvestment > 0 {
+		maxInvestable = money.Min(maxInvestable, tranche.MaxInvestment)
 	}
+	trancheDisplay := trancheDisplayName(tranche.Name)
 
-	// Flat interest calculation: Interest = Principal × (Rate/100)
-	// No time factor - bunga dihitung flat dari total nominal
-	interestAmount := req.Amount * (interestRate / 100)
+	// The annual rate accrues from disbursement (today at the earliest) to the due date
+	start := time.Now()
+	if pool.DisbursedAt != nil {
+		start = *pool.DisbursedAt
+	}
+	tenorDays := s.interest.TenorDays(start, invoice.DueDate)
+	interestAmount := s.interest.Interest(req.Amount, interestRate, start, invoice.DueDate, pool.PoolCurrency)
 	totalReturn := req.Amount + interestAmount
-	effectiveRate := interestRate // Same as nominal rate for flat calculation
+	// Return over the tenor, as opposed to the annual rate
+	var effectiveRate float64
+	if req.Amount > 0 {
+		effectiveRate = money.Ratio(interestAmount, req.Amount) * 100
+	}
 
 	// Platform fee (2% of interest)
-	platformFee := interestAmount * 0.02
+	platformFee := interestAmount.MulRate(2).RoundTo(pool.PoolCurrency)
 	netInterest := interestAmount - platformFee
 	netTotal := req.Amount + netInterest
 
@@ -1434,7 +1980,7 @@ func (s *FundingService) CalculateInvestmentReturns(req *models.InvestmentCalcul
 		NetTotalReturn: netTotal,
 		EffectiveRate:  effectiveRate,
 		MaxInvestable:  maxInvestable,
-		CanInvest:      req.Amount <= maxInvestable && req.Amount > 0,
+		CanInvest:      req.Amount <= maxInvestable && req.Amount > 0 && req.Amount >= tranche.MinInvestment,
 		Message:        fmt.Sprintf("Estimasi imbal hasil untuk %s tranche", trancheDisplay),
 	}, nil
 }
@@ -1490,7 +2036,7 @@ func (s *FundingService) GetActiveInvestments(userID uuid.UUID, page, perPage in
 			dueDate = invoice.DueDate
 			buyerName = invoice.BuyerName
 			buyerCountry = invoice.BuyerCountry
-			buyerFlag = getCountryFlag(invoice.BuyerCountry)
+			buyerFlag = s.countryTiers.Flag(invoice.BuyerCountry)
 		}
 
 		// Calculate days remaining
@@ -1516,17 +2062,10 @@ func (s *FundingService) GetActiveInvestments(userID uuid.UUID, page, perPage in
 		// Get interest rate
 		interestRate := 0.0
 		if pool != nil {
-			if inv.Tranche == models.TranchePriority {
-				interestRate = pool.PriorityInterestRate
-			} else {
-				interestRate = pool.CatalystInterestRate
-			}
+			interestRate = trancheRate(pool, inv.Tranche)
 		}
 
-		trancheDisplay := "Prioritas"
-		if inv.Tranche == models.TrancheCatalyst {
-			trancheDisplay = "Katalis"
-		}
+		trancheDisplay := trancheDisplayName(inv.Tranche)
 
 		activeInvestments = append(activeInvestments, models.InvestorActiveInvestment{
 			InvestmentID:    inv.ID,
@@ -1581,7 +2120,7 @@ func (s *FundingService) GetMitraActiveInvoices(userID uuid.UUID, page, perPage
 
 		// Get pool for funded amount
 		pool, _ := s.fundingRepo.FindPoolByInvoiceID(invoice.ID)
-		fundedAmount := 0.0
+		var fundedAmount money.Amount
 		if pool != nil {
 			fundedAmount = pool.FundedAmount
 		}
@@ -1646,8 +2185,8 @@ func (s *FundingService) GetMitraDashboard(userID uuid.UUID) (*models.MitraDashb
 		return nil, err
 	}
 
-	var totalActiveFinancing float64
-	var totalOwedToInvestors float64
+	var totalActiveFinancing money.Amount
+	var totalOwedToInvestors money.Amount
 	var totalDaysRemaining int
 	var activeCount int
 
@@ -1661,14 +2200,17 @@ func (s *FundingService) GetMitraDashboard(userID uuid.UUID) (*models.MitraDashb
 
 		// Get pool for this invoice
 		pool, _ := s.fundingRepo.FindPoolByInvoiceID(invoice.ID)
-		fundedAmount := 0.0
+		var fundedAmount money.Amount
 		if pool != nil && pool.Status != models.PoolStatusClosed {
 			fundedAmount = pool.FundedAmount
 
-			// Calculate total owed (principal + interest)
-			priorityOwed := pool.PriorityFunded * (1 + pool.PriorityInterestRate/100)
-			catalystOwed := pool.CatalystFunded * (1 + pool.CatalystInterestRate/100)
-			totalOwedToInvestors += priorityOwed + catalystOwed
+			// Calculate total owed (principal + interest accrued to the due date)
+			investments, _ := s.fundingRepo.FindInvestmentsByPool(pool.ID)
+			for _, inv := range investments {
+				if inv.Status == models.InvestmentStatusActive {
+					totalOwedToInvestors += inv.ExpectedReturn
+				}
+			}
 		}
 
 		// Buyer info is now on invoice
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/config"
+	"github.com/vessel/backend/internal/database"
+	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/money"
+	"github.com/vessel/backend/internal/repository"
+)
+
+// TestInvestConcurrentTrancheCapacity fires many parallel investments at one
+// tranche and checks that the pool lock keeps it from being overfilled. It
+// needs a scratch Postgres database in TEST_DATABASE_URL.
+func TestInvestConcurrentTrancheCapacity(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	cfg := &config.Config{DatabaseURL: dsn, InterestDayCount: money.Actual365}
+	db, err := database.NewPostgresConnection(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := database.RunMigrations(db); err != nil {
+		t.Fatal(err)
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	invoiceRepo := repository.NewInvoiceRepository(db)
+	fundingRepo := repository.NewFundingRepository(db)
+	ledgerRepo := repository.NewLedgerRepository(db)
+	ledgerService := NewLedgerService(ledgerRepo)
+	fundingService := NewFundingService(
+		fundingRepo,
+		invoiceRepo,
+		repository.NewTransactionRepository(db),
+		userRepo,
+		repository.NewRiskQuestionnaireRepository(db),
+		repository.NewLateChargeRepository(db),
+		nil,
+		nil,
+		NewEscrowService(),
+		ledgerService,
+		NewInterestEngine(cfg.InterestDayCount, nil),
+		repository.NewUnitOfWork(db),
+		cfg,
+	)
+
+	newUser := func(role models.UserRole, status models.MemberStatus) *models.User {
+		user := &models.User{
+			Email:        fmt.Sprintf("%s-%s@invest-test.local", role, uuid.New()),
+			PasswordHash: "x",
+			Role:         role,
+			MemberStatus: status,
+			IsVerified:   true,
+			IsActive:     true,
+		}
+		if err := userRepo.Create(user, nil); err != nil {
+			t.Fatal(err)
+		}
+		return user
+	}
+
+	// An approved IDR invoice whose pool gets the default 80/20 split
+	mitra := newUser(models.RoleMitra, models.MemberStatusMemberMitra)
+	invoice := &models.Invoice{
+		ExporterID:        mitra.ID,
+		BuyerName:         "Concurrent Buyer",
+		BuyerCountry:      "Singapore",
+		InvoiceNumber:     "INV-" + uuid.NewString()[:8],
+		Currency:          "IDR",
+		Amount:            money.FromInt(125_000_000),
+		IssueDate:         time.Now(),
+		DueDate:           time.Now().AddDate(0, 0, 60),
+		Status:            models.StatusDraft,
+		AdvancePercentage: 80,
+	}
+	if err := invoiceRepo.Create(invoice); err != nil {
+		t.Fatal(err)
+	}
+	if err := invoiceRepo.ApproveWithTransaction(invoice.ID, 10, money.FromInt(100_000_000)); err != nil {
+		t.Fatal(err)
+	}
+	pool, err := fundingService.CreatePool(invoice.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	capacity := pool.Tranche(models.TranchePriority).TargetAmount
+
+	// Every investor can afford all of their attempts, so only tranche capacity
+	// turns investments away. Only the priority tranche is targeted, which keeps
+	// the pool open and auto-disbursement out of the picture.
+	const investors, attemptsPerInvestor = 50, 4
+	amount := money.FromInt(1_000_000)
+	deposit := amount * attemptsPerInvestor
+	users := make([]*models.User, investors)
+	for i := range users {
+		users[i] = newUser(models.RoleInvestor, models.MemberStatusCalonAnggotaPendana)
+		if err := ledgerService.RecordDeposit(users[i].ID, deposit, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if total := amount * investors * attemptsPerInvestor; total <= capacity {
+		t.Fatalf("attempts total %s, need more than the %s tranche capacity", total, capacity)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	invested := make(map[uuid.UUID]money.Amount)
+	var accepted, rejected int
+	for _, user := range users {
+		for j := 0; j < attemptsPerInvestor; j++ {
+			wg.Add(1)
+			go func(investorID uuid.UUID) {
+				defer wg.Done()
+				_, err := fundingService.Invest(investorID, &models.InvestRequest{
+					PoolID:      pool.ID,
+					Amount:      amount,
+					Tranche:     models.TranchePriority,
+					TncAccepted: true,
+				})
+
+				mu.Lock()
+				defer mu.Unlock()
+				switch {
+				case err == nil:
+					accepted++
+					invested[investorID] += amount
+				case errors.Is(err, ErrTrancheCapacity):
+					rejected++
+				default:
+					t.Errorf("Invest returned %v, want nil or ErrTrancheCapacity", err)
+				}
+			}(user.ID)
+		}
+	}
+	wg.Wait()
+
+	if want := int(capacity / amount); accepted != want {
+		t.Errorf("accepted %d investments, want %d", accepted, want)
+	}
+	if accepted+rejected != investors*attemptsPerInvestor {
+		t.Errorf("accepted %d + rejected %d, want %d attempts", accepted, rejected, investors*attemptsPerInvestor)
+	}
+
+	// The tranche, the pool and its investments agree and never exceed capacity
+	stored, err := fundingRepo.FindPoolByID(pool.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if funded := stored.Tranche(models.TranchePriority).FundedAmount; funded != capacity {
+		t.Errorf("priority tranche funded %s, want %s", funded, capacity)
+	}
+	if stored.FundedAmount != capacity {
+		t.Errorf("pool funded %s, want %s", stored.FundedAmount, capacity)
+	}
+	if stored.Status != models.PoolStatusOpen {
+		t.Errorf("pool status %s, want %s", stored.Status, models.PoolStatusOpen)
+	}
+	investments, err := fundingRepo.FindInvestmentsByPool(pool.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var total money.Amount
+	for _, inv := range investments {
+		total += inv.Amount
+	}
+	if len(investments) != accepted || total != capacity {
+		t.Errorf("pool has %d investments totalling %s, want %d totalling %s", len(investments), total, accepted, capacity)
+	}
+
+	// Rejected investments left no postings behind: the pool holds exactly the
+	// accepted funds and each wallet is short only what it invested
+	poolBalance, err := ledgerRepo.GetAccountBalance(models.PoolAccount(pool.ID).Code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if poolBalance != capacity {
+		t.Errorf("pool ledger balance %s, want %s", poolBalance, capacity)
+	}
+	for _, user := range users {
+		balance, err := ledgerService.GetUserBalance(user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := deposit - invested[user.ID]; balance != want {
+			t.Errorf("investor %s balance %s, want %s", user.ID, balance, want)
+		}
+	}
+
+	tb, err := ledgerService.GetTrialBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tb.Balanced || tb.TotalDebit != tb.TotalCredit {
+		t.Errorf("trial balance debit %s credit %s, %d unbalanced entries, %d balance mismatches",
+			tb.TotalDebit, tb.TotalCredit, tb.UnbalancedEntries, tb.BalanceMismatches)
+	}
+}
@@ -1,113 +1,142 @@
 package services
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
 	"github.com/google/uuid"
 	"github.com/vessel/backend/internal/models"
 	"github.com/vessel/backend/internal/repository"
 )
 
-// GradingService handles invoice grading based on risk matrix
+var (
+	ErrNoActiveScorecard   = errors.New("no active grading scorecard")
+	ErrScorecardNotFound   = errors.New("scorecard not found")
+	ErrScorecardTransition = errors.New("scorecard cannot move to that status")
+	ErrInvalidScorecard    = errors.New("invalid scorecard")
+)
+
+// GradingService is the invoice grading engine. Scorecards are data: versioned
+// factors, bands, weights and grade thresholds stored in Postgres. The active
+// scorecard produces the suggested grade; scorecards in shadow are evaluated
+// and stored next to it so a new version can be compared before it goes live.
 type GradingService struct {
-	invoiceRepo repository.InvoiceRepositoryInterface
+	invoiceRepo  repository.InvoiceRepositoryInterface
+	gradingRepo  repository.GradingRepositoryInterface
+	countryTiers *CountryTierService
+	creditScores *CreditScoreService
 }
 
-func NewGradingService(invoiceRepo repository.InvoiceRepositoryInterface) *GradingService {
+func NewGradingService(
+	invoiceRepo repository.InvoiceRepositoryInterface,
+	gradingRepo repository.GradingRepositoryInterface,
+	countryTiers *CountryTierService,
+	creditScores *CreditScoreService,
+) *GradingService {
 	return &GradingService{
-		invoiceRepo: invoiceRepo,
-	}
-}
-
-// CountryRiskTier maps countries to risk tiers
-// Tier 1 (Low Risk): Developed countries with strong payment history
-// Tier 2 (Medium Risk): Emerging markets with moderate risk
-// Tier 3 (High Risk): Countries with high default risk
-var CountryRiskTier = map[string]int{
-	// Tier 1 - Low Risk
-	"USA": 1, "DEU": 1, "JPN": 1, "GBR": 1, "FRA": 1, "CHE": 1, "NLD": 1, "AUS": 1, "CAN": 1, "SGP": 1, "KOR": 1,
-	"United States": 1, "Germany": 1, "Japan": 1, "United Kingdom": 1, "France": 1, "Switzerland": 1,
-	"Netherlands": 1, "Australia": 1, "Canada": 1, "Singapore": 1, "South Korea": 1,
-	// Tier 2 - Medium Risk
-	"CHN": 2, "IND": 2, "BRA": 2, "MEX": 2, "THA": 2, "MYS": 2, "VNM": 2, "PHL": 2, "IDN": 2, "TUR": 2, "SAU": 2, "ARE": 2,
-	"China": 2, "India": 2, "Brazil": 2, "Mexico": 2, "Thailand": 2, "Malaysia": 2, "Vietnam": 2,
-	"Philippines": 2, "Indonesia": 2, "Turkey": 2, "Saudi Arabia": 2, "UAE": 2,
-	// Tier 3 - High Risk
-	"NGA": 3, "PAK": 3, "BGD": 3, "EGY": 3, "KEN": 3, "ZAF": 3, "ARG": 3,
-	"Nigeria": 3, "Pakistan": 3, "Bangladesh": 3, "Egypt": 3, "Kenya": 3, "South Africa": 3, "Argentina": 3,
-}
-
-// GradeInvoice calculates grade based on risk matrix
-// Returns: grade (A/B/C), score (0-100), country risk (low/medium/high)
-func (s *GradingService) GradeInvoice(invoice *models.Invoice, exporterInvoiceCount int, documentCount int) (string, int, string) {
-	score := 0
-
-	// 1. Buyer Country Risk (40 points max)
-	countryRisk := "medium"
-	countryTier := 2 // Default to medium risk
-	if tier, ok := CountryRiskTier[invoice.BuyerCountry]; ok {
-		countryTier = tier
-	}
-
-	switch countryTier {
-	case 1:
-		score += 40
-		countryRisk = "low"
-	case 2:
-		score += 25
-		countryRisk = "medium"
-	case 3:
-		score += 10
-		countryRisk = "high"
-	}
-
-	// 2. Exporter History (30 points max)
-	// Frequent exporter (5+ invoices) = A
-	// Some history (1-4 invoices) = B
-	// First time = C
-	if exporterInvoiceCount >= 5 {
-		score += 30
-	} else if exporterInvoiceCount >= 1 {
-		score += 20
-	} else {
-		score += 10
-	}
-
-	// 3. Document Completeness (30 points max)
-	// Full documents (3+ types) = A
-	// Partial (1-2 types) = B
-	// No documents = C
-	if documentCount >= 3 {
-		score += 30
-	} else if documentCount >= 1 {
-		score += 20
-	} else {
-		score += 5
+		invoiceRepo:  invoiceRepo,
+		gradingRepo:  gradingRepo,
+		countryTiers: countryTiers,
+		creditScores: creditScores,
 	}
+}
 
-	// Determine grade based on score
-	var grade string
-	if score >= 80 {
-		grade = "A"
-	} else if score >= 50 {
-		grade = "B"
-	} else {
-		grade = "C"
-	}
+// Factor categories group contributions in the admin grade suggestion
+const (
+	GradingCategoryCountry   = "country"
+	GradingCategoryHistory   = "history"
+	GradingCategoryDocuments = "documents"
+)
 
-	return grade, score, countryRisk
+// GradingInput is what scorecard factors read about an invoice
+type GradingInput struct {
+	Invoice        *models.Invoice
+	Documents      []models.InvoiceDocument
+	CountryTier    int // Risk tier of the buyer country
+	PriorInvoices  int // The exporter's other invoices
+	RepaidInvoices int // The exporter's invoices repaid in full
+	CreditScore    int // The exporter's credit score
 }
 
-// CalculateDocumentScore calculates document completeness score
-func (s *GradingService) CalculateDocumentScore(documents []models.InvoiceDocument) int {
-	if len(documents) == 0 {
-		return 0
-	}
+// gradingSignal turns an invoice into the number a scorecard factor scores
+type gradingSignal struct {
+	category    string
+	description string
+	value       func(in *GradingInput) float64
+}
 
-	// Check for key document types
-	hasInvoice := false
-	hasBOL := false
-	hasPO := false
-	hasInsurance := false
+// gradingSignals are the factors a scorecard can use. Adding a factor means
+// adding a signal here; its bands and weight live in the scorecard.
+var gradingSignals = map[string]gradingSignal{
+	"buyer_country_tier": {
+		category:    GradingCategoryCountry,
+		description: "Risk tier of the buyer country: 1 (low) to 3 (high), 2 when the country is not rated",
+		value: func(in *GradingInput) float64 {
+			return float64(in.CountryTier)
+		},
+	},
+	"repeat_buyer": {
+		category:    GradingCategoryHistory,
+		description: "1 when the exporter has traded with the buyer before, otherwise 0",
+		value: func(in *GradingInput) float64 {
+			if in.Invoice.IsRepeatBuyer {
+				return 1
+			}
+			return 0
+		},
+	},
+	"exporter_prior_invoices": {
+		category:    GradingCategoryHistory,
+		description: "Number of the exporter's other invoices",
+		value: func(in *GradingInput) float64 {
+			return float64(in.PriorInvoices)
+		},
+	},
+	"exporter_repaid_invoices": {
+		category:    GradingCategoryHistory,
+		description: "Number of the exporter's invoices repaid in full",
+		value: func(in *GradingInput) float64 {
+			return float64(in.RepaidInvoices)
+		},
+	},
+	"exporter_credit_score": {
+		category:    GradingCategoryHistory,
+		description: "The exporter's credit score, 0-100; 50 before any repayment outcome",
+		value: func(in *GradingInput) float64 {
+			return float64(in.CreditScore)
+		},
+	},
+	"document_count": {
+		category:    GradingCategoryDocuments,
+		description: "Number of documents uploaded for the invoice",
+		value: func(in *GradingInput) float64 {
+			return float64(len(in.Documents))
+		},
+	},
+	"document_coverage": {
+		category:    GradingCategoryDocuments,
+		description: "Key documents present, 0-100: invoice 30, bill of lading 30, purchase order 20, insurance 20",
+		value: func(in *GradingInput) float64 {
+			return float64(documentCoverage(in.Documents))
+		},
+	},
+	"insured": {
+		category:    GradingCategoryDocuments,
+		description: "1 when an insurance document is uploaded, otherwise 0",
+		value: func(in *GradingInput) float64 {
+			if hasInsurance(in.Documents) {
+				return 1
+			}
+			return 0
+		},
+	},
+}
 
+// documentCoverage scores which key document types are present, 0-100
+func documentCoverage(documents []models.InvoiceDocument) int {
+	hasInvoice, hasBOL, hasPO := false, false, false
 	for _, doc := range documents {
 		switch doc.DocumentType {
 		case models.DocTypeInvoicePDF, models.DocTypeCommercialInvoice:
@@ -116,8 +145,6 @@ func (s *GradingService) CalculateDocumentScore(documents []models.InvoiceDocume
 			hasBOL = true
 		case models.DocTypePurchaseOrder:
 			hasPO = true
-		case models.DocTypeInsurance:
-			hasInsurance = true
 		}
 	}
 
@@ -131,20 +158,13 @@ func (s *GradingService) CalculateDocumentScore(documents []models.InvoiceDocume
 	if hasPO {
 		score += 20
 	}
-	if hasInsurance {
+	if hasInsurance(documents) {
 		score += 20
 	}
-
-	// Cap at 100
-	if score > 100 {
-		score = 100
-	}
-
 	return score
 }
 
-// CheckInsurance checks if invoice has insurance document
-func (s *GradingService) CheckInsurance(documents []models.InvoiceDocument) bool {
+func hasInsurance(documents []models.InvoiceDocument) bool {
 	for _, doc := range documents {
 		if doc.DocumentType == models.DocTypeInsurance {
 			return true
@@ -153,66 +173,334 @@ func (s *GradingService) CheckInsurance(documents []models.InvoiceDocument) bool
 	return false
 }
 
-// GetExporterInvoiceCount returns the number of completed invoices for an exporter
-func (s *GradingService) GetExporterInvoiceCount(exporterID uuid.UUID) (int, error) {
-	filter := &models.InvoiceFilter{
-		Page:    1,
-		PerPage: 1000,
+// Factors lists the signals a scorecard factor can use
+func (s *GradingService) Factors() []models.GradingFactorInfo {
+	factors := make([]models.GradingFactorInfo, 0, len(gradingSignals))
+	for name, signal := range gradingSignals {
+		factors = append(factors, models.GradingFactorInfo{Name: name, Category: signal.category, Description: signal.description})
 	}
-	invoices, _, err := s.invoiceRepo.FindByExporter(exporterID, filter)
+	sort.Slice(factors, func(i, j int) bool { return factors[i].Name < factors[j].Name })
+	return factors
+}
+
+// GradeResult is an invoice graded by the active scorecard and those in shadow
+type GradeResult struct {
+	Input  *GradingInput
+	Active *models.InvoiceGradeScore
+	Shadow []models.InvoiceGradeScore
+}
+
+// ScoreInvoice scores the invoice with every live scorecard without storing
+// anything. Only the active score counts; shadow scores are for comparison.
+func (s *GradingService) ScoreInvoice(invoice *models.Invoice) (*GradeResult, error) {
+	scorecards, err := s.gradingRepo.FindLiveScorecards()
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	// Count only repaid invoices
-	count := 0
-	for _, inv := range invoices {
-		if inv.Status == models.StatusRepaid {
-			count++
+	input, err := s.buildInput(invoice)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &GradeResult{Input: input}
+	for i := range scorecards {
+		score := Evaluate(&scorecards[i], input)
+		if score.Mode == models.ScorecardStatusActive {
+			result.Active = score
+		} else {
+			result.Shadow = append(result.Shadow, *score)
 		}
 	}
-	return count, nil
+	if result.Active == nil {
+		return nil, ErrNoActiveScorecard
+	}
+
+	return result, nil
 }
 
-// GradeResult represents the grading result
-type GradeResult struct {
-	Grade           string `json:"grade"`
-	Score           int    `json:"score"`
-	CountryRisk     string `json:"country_risk"`
-	IsInsured       bool   `json:"is_insured"`
-	DocumentScore   int    `json:"document_score"`
-	ExporterHistory int    `json:"exporter_history"`
-	IsRepeatBuyer   bool   `json:"is_repeat_buyer"`
-}
-
-// FullGrade performs full grading analysis on an invoice
-func (s *GradingService) FullGrade(invoiceID uuid.UUID) (*GradeResult, error) {
-	invoice, err := s.invoiceRepo.FindByID(invoiceID)
+// GradeInvoice scores the invoice like ScoreInvoice and stores each breakdown.
+// It is called when the invoice's grade is set, so the stored breakdown is the
+// score the grade was approved with.
+func (s *GradingService) GradeInvoice(invoice *models.Invoice) (*GradeResult, error) {
+	result, err := s.ScoreInvoice(invoice)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.gradingRepo.SaveInvoiceGradeScore(result.Active); err != nil {
+		return nil, err
+	}
+	for i := range result.Shadow {
+		if err := s.gradingRepo.SaveInvoiceGradeScore(&result.Shadow[i]); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (s *GradingService) buildInput(invoice *models.Invoice) (*GradingInput, error) {
+	documents, err := s.invoiceRepo.FindDocumentsByInvoiceID(invoice.ID)
+	if err != nil {
+		return nil, err
+	}
+	countryTier, err := s.countryTiers.Tier(invoice.BuyerCountry)
 	if err != nil {
 		return nil, err
 	}
-	documents, err := s.invoiceRepo.FindDocumentsByInvoiceID(invoiceID)
+	exporterInvoices, err := s.invoiceRepo.CountByExporter(invoice.ExporterID)
+	if err != nil {
+		return nil, err
+	}
+	repaid := models.StatusRepaid
+	_, repaidInvoices, err := s.invoiceRepo.FindByExporter(invoice.ExporterID, &models.InvoiceFilter{Status: &repaid, Page: 1, PerPage: 1})
+	if err != nil {
+		return nil, err
+	}
+	creditScore, err := s.creditScores.Score(invoice.ExporterID)
 	if err != nil {
 		return nil, err
 	}
 
-	exporterCount, err := s.GetExporterInvoiceCount(invoice.ExporterID)
+	return &GradingInput{
+		Invoice:        invoice,
+		Documents:      documents,
+		CountryTier:    countryTier,
+		PriorInvoices:  max(exporterInvoices-1, 0),
+		RepaidInvoices: repaidInvoices,
+		CreditScore:    creditScore,
+	}, nil
+}
+
+// ReplayInput builds the grading input of a past invoice with the exporter
+// history and credit score it had when it was created. Documents and the
+// buyer country tier are read as they are now.
+func (s *GradingService) ReplayInput(replay *models.ReplayInvoice) (*GradingInput, error) {
+	countryTier, err := s.countryTiers.Tier(replay.Invoice.BuyerCountry)
 	if err != nil {
-		exporterCount = 0
-	}
-
-	// Calculate scores using grading logic
-	grade, score, countryRisk := s.GradeInvoice(invoice, exporterCount, len(documents))
-	docScore := s.CalculateDocumentScore(documents)
-	isInsured := s.CheckInsurance(documents)
-
-	return &GradeResult{
-		Grade:           grade,
-		Score:           score,
-		CountryRisk:     countryRisk,
-		IsInsured:       isInsured,
-		DocumentScore:   docScore,
-		ExporterHistory: exporterCount,
-		IsRepeatBuyer:   invoice.IsRepeatBuyer,
+		return nil, err
+	}
+	creditScore := models.DefaultCreditScore
+	if replay.CreditScore != nil {
+		creditScore = *replay.CreditScore
+	}
+	return &GradingInput{
+		Invoice:        &replay.Invoice,
+		Documents:      replay.Invoice.Documents,
+		CountryTier:    countryTier,
+		PriorInvoices:  replay.PriorInvoices,
+		RepaidInvoices: replay.RepaidInvoices,
+		CreditScore:    creditScore,
 	}, nil
 }
+
+// Evaluate scores input with a scorecard. The score is rounded to 2 decimals
+// and graded by the first threshold it reaches.
+func Evaluate(sc *models.Scorecard, input *GradingInput) *models.InvoiceGradeScore {
+	result := &models.InvoiceGradeScore{
+		InvoiceID:        input.Invoice.ID,
+		ScorecardID:      sc.ID,
+		ScorecardVersion: sc.Version,
+		Mode:             sc.Status,
+	}
+
+	var total float64
+	for _, factor := range sc.Factors {
+		signal, ok := gradingSignals[factor.Factor]
+		if !ok {
+			// Factors are checked when a scorecard is created; a signal removed
+			// from the code since then scores nothing
+			continue
+		}
+		c := models.FactorContribution{
+			Factor:   factor.Factor,
+			Category: signal.category,
+			Value:    signal.value(input),
+			Weight:   factor.Weight,
+		}
+		for _, band := range factor.Bands {
+			if band.Matches(c.Value) {
+				c.Band = band.Label
+				c.Points = band.Points
+				break
+			}
+		}
+		c.Contribution = roundScore(c.Points * c.Weight)
+		total += c.Contribution
+		result.Factors = append(result.Factors, c)
+	}
+
+	result.Score = roundScore(total)
+	for _, threshold := range sc.GradeThresholds {
+		if result.Score >= threshold.MinScore {
+			result.Grade = threshold.Grade
+			break
+		}
+	}
+	return result
+}
+
+func roundScore(score float64) float64 {
+	return math.Round(score*100) / 100
+}
+
+// GetInvoiceScores returns the stored score breakdowns of an invoice
+func (s *GradingService) GetInvoiceScores(invoiceID uuid.UUID) ([]models.InvoiceGradeScore, error) {
+	return s.gradingRepo.FindInvoiceGradeScores(invoiceID)
+}
+
+// ListScorecards returns every scorecard version, newest first
+func (s *GradingService) ListScorecards() ([]models.Scorecard, error) {
+	return s.gradingRepo.FindScorecards()
+}
+
+func (s *GradingService) GetScorecard(id uuid.UUID) (*models.Scorecard, error) {
+	sc, err := s.gradingRepo.FindScorecardByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if sc == nil {
+		return nil, ErrScorecardNotFound
+	}
+	return sc, nil
+}
+
+// FindScorecardVersion returns the scorecard with the given version number
+func (s *GradingService) FindScorecardVersion(version int) (*models.Scorecard, error) {
+	scorecards, err := s.gradingRepo.FindScorecards()
+	if err != nil {
+		return nil, err
+	}
+	for i := range scorecards {
+		if scorecards[i].Version == version {
+			return &scorecards[i], nil
+		}
+	}
+	return nil, ErrScorecardNotFound
+}
+
+// CandidateScorecard validates req and builds the draft scorecard it describes
+// without storing it, for backtesting
+func CandidateScorecard(req *models.CreateScorecardRequest) (*models.Scorecard, error) {
+	if len(req.Factors) == 0 || len(req.GradeThresholds) == 0 {
+		return nil, fmt.Errorf("%w: factors and grade thresholds are required", ErrInvalidScorecard)
+	}
+	if err := validateScorecard(req); err != nil {
+		return nil, err
+	}
+
+	sc := &models.Scorecard{
+		Name:            req.Name,
+		Description:     req.Description,
+		Status:          models.ScorecardStatusDraft,
+		GradeThresholds: req.GradeThresholds,
+	}
+	for _, f := range req.Factors {
+		sc.Factors = append(sc.Factors, models.ScorecardFactor{Factor: f.Factor, Weight: f.Weight, Bands: f.Bands})
+	}
+	return sc, nil
+}
+
+// CreateScorecard stores a new scorecard version as a draft
+func (s *GradingService) CreateScorecard(adminID uuid.UUID, req *models.CreateScorecardRequest) (*models.Scorecard, error) {
+	sc, err := CandidateScorecard(req)
+	if err != nil {
+		return nil, err
+	}
+	sc.CreatedBy = &adminID
+	if err := s.gradingRepo.CreateScorecard(sc); err != nil {
+		return nil, err
+	}
+
+	return sc, nil
+}
+
+// validateScorecard checks that every factor is known and used once, that
+// bands are well formed, that the best case scores at most 100 and that the
+// thresholds grade every score from 0
+func validateScorecard(req *models.CreateScorecardRequest) error {
+	seen := make(map[string]bool)
+	var maxScore float64
+	for _, f := range req.Factors {
+		if _, ok := gradingSignals[f.Factor]; !ok {
+			return fmt.Errorf("%w: unknown factor %q", ErrInvalidScorecard, f.Factor)
+		}
+		if seen[f.Factor] {
+			return fmt.Errorf("%w: factor %q is used more than once", ErrInvalidScorecard, f.Factor)
+		}
+		seen[f.Factor] = true
+
+		var best float64
+		for _, band := range f.Bands {
+			if band.Min != nil && band.Max != nil && *band.Min > *band.Max {
+				return fmt.Errorf("%w: factor %q has a band with min above max", ErrInvalidScorecard, f.Factor)
+			}
+			best = math.Max(best, band.Points)
+		}
+		maxScore += best * f.Weight
+	}
+	if roundScore(maxScore) > 100 {
+		return fmt.Errorf("%w: the highest possible score is %.2f, above 100", ErrInvalidScorecard, maxScore)
+	}
+
+	grades := make(map[string]bool)
+	for i, t := range req.GradeThresholds {
+		if grades[t.Grade] {
+			return fmt.Errorf("%w: grade %s has more than one threshold", ErrInvalidScorecard, t.Grade)
+		}
+		grades[t.Grade] = true
+		if i > 0 && t.MinScore >= req.GradeThresholds[i-1].MinScore {
+			return fmt.Errorf("%w: grade thresholds must be ordered from the highest min_score", ErrInvalidScorecard)
+		}
+	}
+	if last := req.GradeThresholds[len(req.GradeThresholds)-1]; last.MinScore != 0 {
+		return fmt.Errorf("%w: the lowest grade threshold must start at 0", ErrInvalidScorecard)
+	}
+	return nil
+}
+
+// ActivateScorecard makes the scorecard the one that grades invoices; the
+// previously active scorecard is retired. A retired version can be activated
+// again to roll back.
+func (s *GradingService) ActivateScorecard(id uuid.UUID) (*models.Scorecard, error) {
+	sc, err := s.GetScorecard(id)
+	if err != nil {
+		return nil, err
+	}
+	if sc.Status == models.ScorecardStatusActive {
+		return nil, fmt.Errorf("%w: scorecard is already active", ErrScorecardTransition)
+	}
+	if err := s.gradingRepo.ActivateScorecard(id); err != nil {
+		return nil, err
+	}
+
+	return s.GetScorecard(id)
+}
+
+// ShadowScorecard evaluates the scorecard next to the active one from now on
+func (s *GradingService) ShadowScorecard(id uuid.UUID) (*models.Scorecard, error) {
+	return s.moveScorecard(id, models.ScorecardStatusShadow)
+}
+
+// RetireScorecard stops evaluating a draft or shadow scorecard. The active
+// scorecard is retired by activating another.
+func (s *GradingService) RetireScorecard(id uuid.UUID) (*models.Scorecard, error) {
+	return s.moveScorecard(id, models.ScorecardStatusRetired)
+}
+
+func (s *GradingService) moveScorecard(id uuid.UUID, status models.ScorecardStatus) (*models.Scorecard, error) {
+	sc, err := s.GetScorecard(id)
+	if err != nil {
+		return nil, err
+	}
+	if sc.Status == models.ScorecardStatusActive {
+		return nil, fmt.Errorf("%w: activate another scorecard first", ErrScorecardTransition)
+	}
+	if sc.Status == status {
+		return nil, fmt.Errorf("%w: scorecard is already %s", ErrScorecardTransition, status)
+	}
+	if err := s.gradingRepo.SetScorecardStatus(id, status); err != nil {
+		return nil, err
+	}
+
+	return s.GetScorecard(id)
+}
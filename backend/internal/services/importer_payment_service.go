@@ -0,0 +1,121 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/money"
+	"github.com/vessel/backend/internal/repository"
+)
+
+// ImporterPaymentService collects invoice payments from importers through the
+// payment gateway and distributes them once the gateway confirms payment
+type ImporterPaymentService struct {
+	paymentRepo    *repository.ImporterPaymentRepository
+	fundingService *FundingService
+	gatewayService *PaymentGatewayService
+}
+
+func NewImporterPaymentService(
+	paymentRepo *repository.ImporterPaymentRepository,
+	fundingService *FundingService,
+	gatewayService *PaymentGatewayService,
+) *ImporterPaymentService {
+	return &ImporterPaymentService{
+		paymentRepo:    paymentRepo,
+		fundingService: fundingService,
+		gatewayService: gatewayService,
+	}
+}
+
+// Pay creates a gateway payment link for an installment of an importer payment.
+// Importers may pay in several wires; each confirmed installment is distributed
+// as it arrives. The pool must be disbursed or defaulted. With the simulator's
+// auto-settle the payment is confirmed before this returns.
+func (s *ImporterPaymentService) Pay(paymentID uuid.UUID, amount money.Amount) (*models.ImporterPaymentResponse, error) {
+	payment, err := s.paymentRepo.FindByID(paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment == nil {
+		return nil, errors.New("payment not found")
+	}
+
+	// Check if already paid
+	if payment.PaymentStatus == models.ImporterPaymentStatusPaid {
+		return nil, errors.New("this invoice has already been paid")
+	}
+
+	// Validate amount
+	if amount <= 0 {
+		return nil, errors.New("payment amount must be positive")
+	}
+
+	// Only a disbursed or defaulted pool is owed anything; the mitra may also
+	// have closed it already through its own repayment
+	if err := s.fundingService.checkRepayable(payment.PoolID); err != nil {
+		return nil, err
+	}
+
+	charge, err := s.gatewayService.CreateCharge(&ChargeRequest{
+		Purpose:     models.GatewayPurposeImporterPayment,
+		ReferenceID: payment.ID,
+		Method:      models.GatewayMethodPaymentLink,
+		Amount:      amount,
+		Currency:    payment.Currency,
+		Description: "Invoice payment from " + payment.BuyerName,
+		ExpiresAt:   time.Now().Add(24 * time.Hour),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.gatewayService.AutoSettle(charge); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.paymentRepo.FindByID(paymentID)
+	if err != nil {
+		return nil, err
+	}
+	outstanding := money.Max(updated.AmountDue-updated.AmountPaid, 0)
+	if updated.AmountPaid == payment.AmountPaid {
+		return &models.ImporterPaymentResponse{
+			PaymentID:   paymentID,
+			Status:      string(updated.PaymentStatus),
+			AmountPaid:  updated.AmountPaid,
+			Outstanding: outstanding,
+			PaymentURL:  charge.PaymentURL,
+			Message:     "Complete the payment at the payment link. Funds are distributed to investors once the payment is confirmed.",
+		}, nil
+	}
+
+	message := "Payment processed successfully. Funds have been distributed to investors."
+	if updated.PaymentStatus == models.ImporterPaymentStatusPartial {
+		message = "Installment received and distributed to investors. The remaining balance is still outstanding."
+	}
+	return &models.ImporterPaymentResponse{
+		PaymentID:   paymentID,
+		Status:      string(updated.PaymentStatus),
+		AmountPaid:  updated.AmountPaid,
+		Outstanding: outstanding,
+		TxHash:      updated.TxHash,
+		Message:     message,
+		PaidAt:      updated.PaidAt,
+	}, nil
+}
+
+// SettlePayment distributes a confirmed installment to investors (most senior tranche first)
+// and adds it to the amount paid. The payment is paid once the amount due is covered.
+func (s *ImporterPaymentService) SettlePayment(repos *repository.Repositories, gatewayPayment *models.GatewayPayment) error {
+	// Generate simulated tx hash (in production, this comes from blockchain)
+	_, err := s.fundingService.SettleImporterPayment(repos, gatewayPayment.ReferenceID, gatewayPayment.Amount, generateTxHash())
+	return err
+}
+
+// generateTxHash generates a simulated transaction hash for prototype
+func generateTxHash() string {
+	return "0x" + strings.ReplaceAll(uuid.New().String()+uuid.New().String(), "-", "")
+}
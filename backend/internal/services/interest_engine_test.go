@@ -0,0 +1,89 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/money"
+)
+
+func TestAccrueLateChargeAddsUpToSinglePeriod(t *testing.T) {
+	due := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		dayCount  money.DayCount
+		currency  string
+		overdue   money.Amount
+		rate      float64
+		days      int
+		everyDays int
+	}{
+		{"actual/365 daily in rupiah", money.Actual365, "IDR", money.FromInt(1000000), 24, 30, 1},
+		{"actual/365 daily across year end", money.Actual365, "IDR", money.FromInt(777777), 18, 400, 1},
+		{"actual/365 weekly in cents", money.Actual365, "USD", money.FromInt(12345), 19.5, 90, 7},
+		{"30/360 daily over month ends", money.Thirty360, "IDR", money.FromInt(1000000), 24, 90, 1},
+		{"30/360 every three days in cents", money.Thirty360, "USD", money.FromInt(9999), 36, 120, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewInterestEngine(tt.dayCount, nil)
+			end := due.AddDate(0, 0, tt.days)
+
+			charge := &models.LateCharge{Currency: tt.currency, PenaltyRate: tt.rate, AccruedThrough: due, AccrualBalance: tt.overdue, AccrualStart: due}
+			for asOf := due; asOf.Before(end); {
+				asOf = asOf.AddDate(0, 0, tt.everyDays)
+				if asOf.After(end) {
+					asOf = end
+				}
+				engine.AccrueLateCharge(charge, tt.overdue, asOf)
+			}
+
+			single := &models.LateCharge{Currency: tt.currency, PenaltyRate: tt.rate, AccruedThrough: due, AccrualBalance: tt.overdue, AccrualStart: due}
+			engine.AccrueLateCharge(single, tt.overdue, end)
+
+			if charge.PenaltyInterest != single.PenaltyInterest {
+				t.Errorf("accrued in runs = %s, in one period = %s", charge.PenaltyInterest, single.PenaltyInterest)
+			}
+			if want := engine.Interest(tt.overdue, tt.rate, due, end, tt.currency); single.PenaltyInterest != want {
+				t.Errorf("accrued in one period = %s, want %s", single.PenaltyInterest, want)
+			}
+		})
+	}
+}
+
+func TestAccrueLateChargeBalanceChange(t *testing.T) {
+	engine := NewInterestEngine(money.Actual365, nil)
+	due := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	paidOn := due.AddDate(0, 0, 10)
+	end := due.AddDate(0, 0, 25)
+
+	charge := &models.LateCharge{Currency: "IDR", PenaltyRate: 24, AccruedThrough: due, AccrualBalance: money.FromInt(1000000), AccrualStart: due}
+	for asOf := due.AddDate(0, 0, 1); !asOf.After(end); asOf = asOf.AddDate(0, 0, 1) {
+		overdue := money.FromInt(1000000)
+		if asOf.After(paidOn) {
+			overdue = money.FromInt(400000)
+		}
+		engine.AccrueLateCharge(charge, overdue, asOf)
+	}
+
+	want := engine.Interest(money.FromInt(1000000), 24, due, paidOn, "IDR") +
+		engine.Interest(money.FromInt(400000), 24, paidOn, end, "IDR")
+	if charge.PenaltyInterest != want {
+		t.Errorf("penalty interest = %s, want %s", charge.PenaltyInterest, want)
+	}
+	if !charge.AccrualStart.Equal(paidOn) || charge.AccrualBalance != money.FromInt(400000) {
+		t.Errorf("accrual segment = %s from %s, want 400000 from %s", charge.AccrualBalance, charge.AccrualStart, paidOn)
+	}
+}
+
+func TestAccrueLateChargeIgnoresPastDates(t *testing.T) {
+	engine := NewInterestEngine(money.Actual365, nil)
+	due := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	charge := &models.LateCharge{Currency: "IDR", PenaltyRate: 24, AccruedThrough: due.AddDate(0, 0, 5), AccrualBalance: money.FromInt(1000000), AccrualStart: due, PenaltyInterest: money.FromInt(3288)}
+
+	engine.AccrueLateCharge(charge, money.FromInt(1000000), due.AddDate(0, 0, 3))
+	if charge.PenaltyInterest != money.FromInt(3288) || !charge.AccruedThrough.Equal(due.AddDate(0, 0, 5)) {
+		t.Errorf("charge changed to %s through %s", charge.PenaltyInterest, charge.AccruedThrough)
+	}
+}
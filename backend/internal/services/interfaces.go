@@ -3,6 +3,7 @@ package services
 import (
 	"github.com/google/uuid"
 	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/money"
 )
 
 // AuthServiceInterface defines the contract for authentication operations
@@ -30,20 +31,22 @@ type InvoiceServiceInterface interface {
 
 // FundingServiceInterface defines the contract for funding operations
 type FundingServiceInterface interface {
-	CreatePool(invoiceID uuid.UUID) (*models.FundingPool, error)
+	CreatePool(invoiceID uuid.UUID, req *models.CreatePoolRequest) (*models.FundingPool, error)
 	GetPool(poolID uuid.UUID) (*models.FundingPoolResponse, error)
 	GetOpenPools(page, perPage int) (*models.PoolListResponse, error)
 	Invest(investorID uuid.UUID, req *models.InvestRequest) (*models.Investment, error)
 	GetInvestmentsByInvestor(investorID uuid.UUID, page, perPage int) (*models.InvestmentListResponse, error)
 	DisburseToExporter(poolID uuid.UUID) (*models.ExporterPaymentNotificationData, error)
-	ProcessRepayment(invoiceID uuid.UUID, amount float64) error
+	ProcessRepayment(invoiceID uuid.UUID, amount money.Amount, final bool) error
 	ClosePoolAndNotifyExporter(poolID uuid.UUID) (*models.ExporterPaymentNotificationData, error)
+	ProcessExpiredPools() (int, error)
+	RefundPool(poolID uuid.UUID) error
 }
 
 // BlockchainServiceInterface defines the contract for blockchain operations
 type BlockchainServiceInterface interface {
 	TokenizeInvoice(invoiceID uuid.UUID, ownerAddress string) (*models.InvoiceNFT, error)
-	BurnNFT(invoiceID uuid.UUID) error
+	BurnNFT(invoiceID uuid.UUID) (*BlockchainTransaction, error)
 	GetNFTByInvoice(invoiceID uuid.UUID) (*models.InvoiceNFT, error)
 	TransferNFT(nftID uuid.UUID, newOwner string) error
 }
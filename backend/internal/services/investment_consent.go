@@ -0,0 +1,191 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/repository"
+)
+
+var (
+	ErrConsentSignatureRequired = errors.New("a signed investment consent is required")
+	ErrConsentWalletRequired    = errors.New("link a wallet before signing the investment consent")
+	ErrConsentExpired           = errors.New("signed investment consent has expired")
+	ErrConsentSignatureMismatch = errors.New("investment consent was not signed by your linked wallet")
+	ErrConsentNotFound          = errors.New("investment has no signed consent")
+	ErrConsentNonceUsed         = errors.New("signed investment consent was already used or not issued to you")
+)
+
+// consentTTL is how long a consent payload stays valid after it is issued
+func (s *FundingService) consentTTL() time.Duration {
+	ttl := time.Duration(s.cfg.InvestConsentTTLMinutes) * time.Minute
+	if ttl <= 0 {
+		ttl = 15 * time.Minute
+	}
+	return ttl
+}
+
+// consentTextsHash is keccak256 over the consent statements of a tranche
+func consentTextsHash(pool *models.FundingPool, tranche models.TrancheType) string {
+	return crypto.Keccak256Hash([]byte(models.ConsentTextsDocument(requiresRiskConsent(pool, tranche)))).Hex()
+}
+
+// BuildInvestmentConsent returns the EIP-712 payload the investor's wallet signs
+// before calling Invest with the same pool, tranche and amount
+func (s *FundingService) BuildInvestmentConsent(investorID uuid.UUID, req *models.InvestmentConsentRequest) (*models.InvestmentConsentResponse, error) {
+	pool, err := s.fundingRepo.FindPoolByID(req.PoolID)
+	if err != nil {
+		return nil, err
+	}
+	if pool == nil {
+		return nil, errors.New("pool not found")
+	}
+	investor, err := s.userRepo.FindByID(investorID)
+	if err != nil {
+		return nil, err
+	}
+	if investor == nil {
+		return nil, errors.New("investor not found")
+	}
+	if investor.WalletAddress == nil {
+		return nil, ErrConsentWalletRequired
+	}
+	if pool.Tranche(req.Tranche) == nil {
+		return nil, ErrTrancheNotFound
+	}
+
+	expiresAt := time.Now().Add(s.consentTTL()).Unix()
+	nonce, err := s.fundingRepo.CreateConsentNonce(investorID, time.Unix(expiresAt, 0))
+	if err != nil {
+		return nil, err
+	}
+	typedData := s.consentTypedData(*investor.WalletAddress, pool, req.Tranche, req.Amount.String(), nonce, expiresAt)
+	digest, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(typedData)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.InvestmentConsentResponse{
+		TypedData:        raw,
+		Digest:           hexutil.Encode(digest),
+		ConsentTexts:     models.ConsentTexts(requiresRiskConsent(pool, req.Tranche)),
+		ConsentTextsHash: consentTextsHash(pool, req.Tranche),
+		TncVersion:       s.cfg.TncVersion,
+		Nonce:            nonce,
+		ExpiresAt:        expiresAt,
+	}, nil
+}
+
+// verifyInvestmentConsent rebuilds the signed payload from the investment being
+// made, checks it was signed by the investor's linked wallet and consumes its
+// nonce in the caller's transaction so the signature cannot back a second
+// investment. It returns the record to store once the investment exists.
+func (s *FundingService) verifyInvestmentConsent(repos *repository.Repositories, investor *models.User, pool *models.FundingPool, req *models.InvestRequest) (*models.InvestmentConsent, error) {
+	if investor.WalletAddress == nil {
+		return nil, ErrConsentWalletRequired
+	}
+	if req.ConsentExpiresAt == nil {
+		return nil, errors.New("consent_expires_at is required with consent_signature")
+	}
+	if req.ConsentNonce == nil {
+		return nil, errors.New("consent_nonce is required with consent_signature")
+	}
+	expiresAt := time.Unix(*req.ConsentExpiresAt, 0)
+	now := time.Now()
+	// An expiry further out than a freshly issued payload was not issued by us
+	if now.After(expiresAt) || expiresAt.After(now.Add(s.consentTTL()+time.Minute)) {
+		return nil, ErrConsentExpired
+	}
+
+	typedData := s.consentTypedData(*investor.WalletAddress, pool, req.Tranche, req.Amount.String(), *req.ConsentNonce, *req.ConsentExpiresAt)
+	digest, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := recoverSigner(digest, *req.ConsentSignature)
+	if err != nil {
+		return nil, err
+	}
+	if signer != common.HexToAddress(*investor.WalletAddress) {
+		return nil, ErrConsentSignatureMismatch
+	}
+	consumed, err := repos.Funding.ConsumeConsentNonce(*req.ConsentNonce, investor.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, ErrConsentNonceUsed
+	}
+
+	raw, err := json.Marshal(typedData)
+	if err != nil {
+		return nil, err
+	}
+	return &models.InvestmentConsent{
+		InvestorID:       investor.ID,
+		SignerAddress:    signer.Hex(),
+		TncVersion:       s.cfg.TncVersion,
+		ConsentTextsHash: consentTextsHash(pool, req.Tranche),
+		TypedData:        raw,
+		Digest:           hexutil.Encode(digest),
+		Signature:        strings.ToLower(*req.ConsentSignature),
+		ExpiresAt:        expiresAt,
+	}, nil
+}
+
+func (s *FundingService) consentTypedData(wallet string, pool *models.FundingPool, tranche models.TrancheType, amount string, nonce uuid.UUID, expiresAt int64) apitypes.TypedData {
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": eip712DomainType,
+			"InvestmentConsent": {
+				{Name: "investor", Type: "address"},
+				{Name: "poolId", Type: "string"},
+				{Name: "tranche", Type: "string"},
+				{Name: "amount", Type: "string"},
+				{Name: "currency", Type: "string"},
+				{Name: "consentTextsHash", Type: "bytes32"},
+				{Name: "tncVersion", Type: "string"},
+				{Name: "nonce", Type: "string"},
+				{Name: "expiresAt", Type: "uint256"},
+			},
+		},
+		PrimaryType: "InvestmentConsent",
+		Domain:      eip712Domain(s.cfg.ChainID),
+		Message: apitypes.TypedDataMessage{
+			"investor":         common.HexToAddress(wallet).Hex(),
+			"poolId":           pool.ID.String(),
+			"tranche":          string(tranche),
+			"amount":           amount,
+			"currency":         pool.PoolCurrency,
+			"consentTextsHash": consentTextsHash(pool, tranche),
+			"tncVersion":       s.cfg.TncVersion,
+			"nonce":            nonce.String(),
+			"expiresAt":        fmt.Sprintf("%d", expiresAt),
+		},
+	}
+}
+
+// GetInvestmentConsent returns the stored consent proof for an investment
+func (s *FundingService) GetInvestmentConsent(investmentID uuid.UUID) (*models.InvestmentConsent, error) {
+	consent, err := s.fundingRepo.FindInvestmentConsent(investmentID)
+	if err != nil {
+		return nil, err
+	}
+	if consent == nil {
+		return nil, ErrConsentNotFound
+	}
+	return consent, nil
+}
@@ -2,6 +2,8 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"math"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,13 +12,18 @@ import (
 	"github.com/vessel/backend/internal/repository"
 )
 
+var errUnknownBuyerCountry = errors.New("unknown buyer country (use an ISO 3166 code or English country name)")
+
 type InvoiceService struct {
-	invoiceRepo repository.InvoiceRepositoryInterface
-	fundingRepo repository.FundingRepositoryInterface
-	userRepo    repository.UserRepositoryInterface
-	mitraRepo   *repository.MitraRepository
-	pinata      PinataServiceInterface
-	cfg         *config.Config
+	invoiceRepo    repository.InvoiceRepositoryInterface
+	fundingRepo    repository.FundingRepositoryInterface
+	userRepo       repository.UserRepositoryInterface
+	mitraRepo      *repository.MitraRepository
+	gradingService *GradingService
+	creditScores   *CreditScoreService
+	buyers         *BuyerService
+	pinata         PinataServiceInterface
+	cfg            *config.Config
 }
 
 func NewInvoiceService(
@@ -43,18 +50,65 @@ func (s *InvoiceService) SetMitraRepo(mitraRepo *repository.MitraRepository) {
 	s.mitraRepo = mitraRepo
 }
 
-// CheckRepeatBuyer checks if buyer is a repeat buyer based on transaction history (Flow 4 Pre-condition)
-func (s *InvoiceService) CheckRepeatBuyer(mitraID uuid.UUID, buyerCompanyName string) (*models.RepeatBuyerCheckResponse, error) {
-	// Simplified logic since Buyer table is removed.
-	// In the future, we can query unique buyer names from invoices table with status=repaid
-
-	// Default to treating as new buyer or relying on manual check for now
-	return &models.RepeatBuyerCheckResponse{
-		IsRepeatBuyer:        false,
-		Message:              "⚠️ Untuk kemitraan baru, maksimal pembiayaan yang dapat dicairkan adalah 60% dari nilai tagihan.",
-		PreviousTransactions: 0,
-		FundingLimit:         60.0,
-	}, nil
+// SetGradingService sets the grading engine used for grade suggestions
+func (s *InvoiceService) SetGradingService(gradingService *GradingService) {
+	s.gradingService = gradingService
+}
+
+// SetCreditScoreService sets the mitra credit scores that adjust funding limits
+func (s *InvoiceService) SetCreditScoreService(creditScores *CreditScoreService) {
+	s.creditScores = creditScores
+}
+
+// SetBuyerService sets the buyer registry invoices are linked to
+func (s *InvoiceService) SetBuyerService(buyers *BuyerService) {
+	s.buyers = buyers
+}
+
+// CheckRepeatBuyer checks if buyer is a repeat buyer based on transaction history (Flow 4 Pre-condition).
+// The buyer is looked up in the registry by name or alias within its country; without a country it is new.
+func (s *InvoiceService) CheckRepeatBuyer(mitraID uuid.UUID, buyerCompanyName, buyerCountry string) (*models.RepeatBuyerCheckResponse, error) {
+	var buyer *models.Buyer
+	if buyerCountry != "" {
+		country, ok := NormalizeCountry(buyerCountry)
+		if !ok {
+			return nil, errUnknownBuyerCountry
+		}
+		var err error
+		if buyer, err = s.buyers.Find(buyerCompanyName, country); err != nil {
+			return nil, err
+		}
+	}
+	return s.repeatBuyerCheck(mitraID, buyer)
+}
+
+// repeatBuyerCheck counts the mitra's repaid invoices to the buyer, nil for a
+// buyer not in the registry. The mitra's credit score raises or caps the limit.
+func (s *InvoiceService) repeatBuyerCheck(mitraID uuid.UUID, buyer *models.Buyer) (*models.RepeatBuyerCheckResponse, error) {
+	response := &models.RepeatBuyerCheckResponse{}
+	if buyer != nil {
+		response.BuyerID = &buyer.ID
+		repaid, err := s.buyers.RepaidWithExporter(buyer.ID, mitraID)
+		if err != nil {
+			return nil, err
+		}
+		response.PreviousTransactions = repaid
+		response.IsRepeatBuyer = repaid > 0
+	}
+
+	var err error
+	if response.IsRepeatBuyer {
+		if response.FundingLimit, err = s.creditScores.FundingLimit(mitraID, 100.0); err != nil {
+			return nil, err
+		}
+		response.Message = fmt.Sprintf("✅ Pembeli berulang dengan %d transaksi lunas sebelumnya, maksimal pembiayaan yang dapat dicairkan adalah %.0f%% dari nilai tagihan.", response.PreviousTransactions, response.FundingLimit)
+		return response, nil
+	}
+	if response.FundingLimit, err = s.creditScores.FundingLimit(mitraID, 60.0); err != nil {
+		return nil, err
+	}
+	response.Message = fmt.Sprintf("⚠️ Untuk kemitraan baru, maksimal pembiayaan yang dapat dicairkan adalah %.0f%% dari nilai tagihan.", response.FundingLimit)
+	return response, nil
 }
 
 // CreateFundingRequest creates a new invoice funding request (Flow 4)
@@ -101,29 +155,38 @@ func (s *InvoiceService) CreateFundingRequest(mitraID uuid.UUID, req *models.Cre
 		return nil, errors.New("due date must be in the future")
 	}
 
+	buyerCountry, ok := NormalizeCountry(req.BuyerCountry)
+	if !ok {
+		return nil, errUnknownBuyerCountry
+	}
+
 	// Check repeat buyer and get funding limit
-	repeatCheck, err := s.CheckRepeatBuyer(mitraID, req.BuyerCompanyName)
+	repeatCheck, err := s.CheckRepeatBuyer(mitraID, req.BuyerCompanyName, buyerCountry)
 	if err != nil {
 		return nil, err
 	}
 
 	fundingLimitPercentage := repeatCheck.FundingLimit
-	if !req.IsRepeatBuyer && repeatCheck.IsRepeatBuyer {
-		// System detected repeat buyer
-		fundingLimitPercentage = 100.0
-	} else if req.IsRepeatBuyer && !repeatCheck.IsRepeatBuyer && req.RepeatBuyerProof == "" {
+	if req.IsRepeatBuyer && !repeatCheck.IsRepeatBuyer && req.RepeatBuyerProof == "" {
 		// User claims repeat but system doesn't detect - require proof
 		return nil, errors.New("please upload proof of previous transactions for repeat buyer claim")
 	}
 
+	// Link the invoice to its buyer in the registry, registering a new buyer
+	buyer, err := s.buyers.Resolve(req.BuyerCompanyName, buyerCountry, req.BuyerEmail)
+	if err != nil {
+		return nil, err
+	}
+
 	// Calculate advance amount based on funding limit
-	advanceAmount := req.IDRAmount * (fundingLimitPercentage / 100)
+	advanceAmount := req.IDRAmount.MulRate(fundingLimitPercentage).RoundTo("IDR")
 
 	// Create invoice
 	invoice := &models.Invoice{
 		ExporterID:        mitraID,
+		BuyerID:           &buyer.ID,
 		BuyerName:         req.BuyerCompanyName,
-		BuyerCountry:      req.BuyerCountry,
+		BuyerCountry:      buyerCountry,
 		InvoiceNumber:     req.InvoiceNumber,
 		Currency:          "IDR",
 		Amount:            req.IDRAmount,
@@ -315,7 +378,7 @@ func (s *InvoiceService) Approve(id uuid.UUID, interestRate float64) error {
 		return errors.New("invoice is not pending review")
 	}
 
-	advanceAmount := invoice.Amount * (invoice.AdvancePercentage / 100)
+	advanceAmount := invoice.Amount.MulRate(invoice.AdvancePercentage).RoundTo(invoice.Currency)
 
 	return s.invoiceRepo.ApproveWithTransaction(id, interestRate, advanceAmount)
 }
@@ -386,7 +449,9 @@ func (s *InvoiceService) DeleteDocument(docID, exporterID uuid.UUID) error {
 	return s.invoiceRepo.DeleteDocument(docID)
 }
 
-// GetGradeSuggestion implements BE-ADM-1 logic for grade suggestion
+// GetGradeSuggestion implements BE-ADM-1: the invoice is scored by the active
+// scorecard of the grading engine. Nothing is stored; the breakdown is saved
+// when the grade is set.
 func (s *InvoiceService) GetGradeSuggestion(invoiceID uuid.UUID) (*models.AdminGradeSuggestionResponse, error) {
 	invoice, err := s.invoiceRepo.FindByID(invoiceID)
 	if err != nil {
@@ -396,86 +461,50 @@ func (s *InvoiceService) GetGradeSuggestion(invoiceID uuid.UUID) (*models.AdminG
 		return nil, errors.New("invoice not found")
 	}
 
-	// Calculate scores using grading logic
-	score := 0
-	countryRisk := "medium"
+	result, err := s.gradingService.ScoreInvoice(invoice)
+	if err != nil {
+		return nil, err
+	}
+	score := result.Active
 
-	// Get documents for completeness check
-	docs, _ := s.invoiceRepo.FindDocumentsByInvoiceID(invoiceID)
-	documentCount := len(docs)
-
-	// Get exporter invoice count for history
-	exporterInvoiceCount, _ := s.invoiceRepo.CountByExporter(invoice.ExporterID)
-
-	// 1. Country Risk Score (40 points max)
-	countryScore := 25 // Default medium
-	countryTier := 2
-	if tier, ok := CountryRiskTier[invoice.BuyerCountry]; ok {
-		countryTier = tier
-	}
-
-	switch countryTier {
-	case 1:
-		countryScore = 40
-		countryRisk = "low"
-	case 2:
-		countryScore = 25
-		countryRisk = "medium"
-	case 3:
-		countryScore = 10
-		countryRisk = "high"
-	}
-	score += countryScore
-
-	// 2. History Score (30 points max)
-	historyScore := 10 // Default for first time
-	isRepeatBuyer := invoice.IsRepeatBuyer
-	if isRepeatBuyer {
-		historyScore = 30
-	} else if exporterInvoiceCount >= 1 {
-		historyScore = 20
-	}
-	score += historyScore
-
-	// 3. Document Completeness Score (30 points max)
-	documentScore := 5
-	documentsComplete := false
-	if documentCount >= 3 {
-		documentScore = 30
-		documentsComplete = true
-	} else if documentCount >= 1 {
-		documentScore = 20
-	}
-	score += documentScore
-
-	// Determine grade
-	grade := "C"
-	if score >= 80 {
-		grade = "A"
-	} else if score >= 50 {
-		grade = "B"
-	}
-
-	// Funding limit based on repeat buyer status
+	// Funding limit based on repeat buyer status, adjusted by the exporter's credit score
 	fundingLimit := 60.0
-	if isRepeatBuyer {
+	if invoice.IsRepeatBuyer {
 		fundingLimit = 100.0
 	}
+	fundingLimit = models.CreditFundingLimit(result.Input.CreditScore, fundingLimit)
 
 	return &models.AdminGradeSuggestionResponse{
 		InvoiceID:         invoiceID.String(),
-		SuggestedGrade:    grade,
-		GradeScore:        score,
-		CountryRisk:       countryRisk,
-		CountryScore:      countryScore,
-		HistoryScore:      historyScore,
-		DocumentScore:     documentScore,
-		IsRepeatBuyer:     isRepeatBuyer,
-		DocumentsComplete: documentsComplete,
+		SuggestedGrade:    score.Grade,
+		GradeScore:        int(math.Round(score.Score)),
+		CountryRisk:       models.CountryRiskLevel(result.Input.CountryTier),
+		CountryScore:      int(math.Round(score.CategoryScore(GradingCategoryCountry))),
+		HistoryScore:      int(math.Round(score.CategoryScore(GradingCategoryHistory))),
+		DocumentScore:     int(math.Round(score.CategoryScore(GradingCategoryDocuments))),
+		IsRepeatBuyer:     invoice.IsRepeatBuyer,
+		DocumentsComplete: len(result.Input.Documents) >= 3,
 		FundingLimit:      fundingLimit,
+		CreditScore:       result.Input.CreditScore,
+		CreditRiskLevel:   models.CalculateRiskLevel(result.Input.CreditScore),
+		ScorecardVersion:  score.ScorecardVersion,
+		Factors:           score.Factors,
 	}, nil
 }
 
+// GetExporterCreditScore returns the credit score of the mitra that submitted
+// the invoice, for admins reviewing it
+func (s *InvoiceService) GetExporterCreditScore(invoiceID uuid.UUID) (*models.CreditScoreResponse, error) {
+	invoice, err := s.invoiceRepo.FindByID(invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	if invoice == nil {
+		return nil, errors.New("invoice not found")
+	}
+	return s.creditScores.GetCreditScore(invoice.ExporterID)
+}
+
 // GetInvoiceReviewData gets all data needed for admin review (Flow 5 - Split Screen)
 func (s *InvoiceService) GetInvoiceReviewData(invoiceID uuid.UUID) (*models.InvoiceReviewData, error) {
 	invoice, err := s.invoiceRepo.FindByID(invoiceID)
@@ -509,19 +538,20 @@ func (s *InvoiceService) GetInvoiceReviewData(invoiceID uuid.UUID) (*models.Invo
 	}
 
 	// Get grade suggestion
-	gradeSuggestion, _ := s.GetGradeSuggestion(invoiceID)
-	if gradeSuggestion == nil {
-		gradeSuggestion = &models.AdminGradeSuggestionResponse{
-			SuggestedGrade: "C",
-			GradeScore:     0,
-		}
+	gradeSuggestion, err := s.GetGradeSuggestion(invoiceID)
+	if err != nil {
+		return nil, err
 	}
 
+	// Get the exporter's credit record
+	creditScore, _ := s.creditScores.GetCreditScore(invoice.ExporterID)
+
 	return &models.InvoiceReviewData{
 		Invoice:         *invoice,
 		Exporter:        exporterProfile,
 		Documents:       docStatuses,
 		GradeSuggestion: *gradeSuggestion,
+		CreditScore:     creditScore,
 	}, nil
 }
 
@@ -566,7 +596,7 @@ func (s *InvoiceService) ApproveWithGrade(invoiceID uuid.UUID, req *models.Admin
 	}
 
 	// Calculate advance amount
-	advanceAmount := invoice.Amount * (invoice.AdvancePercentage / 100)
+	advanceAmount := invoice.Amount.MulRate(invoice.AdvancePercentage).RoundTo(invoice.Currency)
 	if invoice.AdvanceAmount != nil {
 		advanceAmount = *invoice.AdvanceAmount
 	}
@@ -579,6 +609,12 @@ func (s *InvoiceService) ApproveWithGrade(invoiceID uuid.UUID, req *models.Admin
 	invoice.AdvanceAmount = &advanceAmount
 	invoice.Status = models.StatusApproved
 
+	// Store the breakdown the grade was set with. It is saved first so an
+	// approved invoice always has one; approving again replaces it.
+	if _, err := s.gradingService.GradeInvoice(invoice); err != nil {
+		return err
+	}
+
 	return s.invoiceRepo.Update(invoice)
 }
 
@@ -612,6 +648,28 @@ func (s *InvoiceService) GetPendingInvoices(page, perPage int) (*models.InvoiceL
 	}, nil
 }
 
+// GetBuyerInvoices gets the invoices of a registered buyer from every mitra, newest first
+func (s *InvoiceService) GetBuyerInvoices(buyerID uuid.UUID, page, perPage int) (*models.InvoiceListResponse, error) {
+	filter := &models.InvoiceFilter{
+		BuyerID: &buyerID,
+		Page:    page,
+		PerPage: perPage,
+	}
+
+	invoices, total, err := s.invoiceRepo.FindAll(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.InvoiceListResponse{
+		Invoices:   invoices,
+		Total:      total,
+		Page:       page,
+		PerPage:    perPage,
+		TotalPages: models.CalculateTotalPages(total, perPage),
+	}, nil
+}
+
 func (s *InvoiceService) GetApprovedInvoices(page, perPage int) (*models.InvoiceListResponse, error) {
 	if page < 1 {
 		page = 1
@@ -640,11 +698,3 @@ func (s *InvoiceService) GetApprovedInvoices(page, perPage int) (*models.Invoice
 		TotalPages: models.CalculateTotalPages(total, perPage),
 	}, nil
 }
-
-// getCountryRiskTier returns the risk tier for a country (uses grading_service.CountryRiskTier)
-func getCountryRiskTier(country string) int {
-	if tier, ok := CountryRiskTier[country]; ok {
-		return tier
-	}
-	return 3 // Default to high risk
-}
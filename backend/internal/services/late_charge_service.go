@@ -0,0 +1,169 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/money"
+	"github.com/vessel/backend/internal/repository"
+)
+
+// LateChargeService accrues late fees and penalty interest on disbursed pools
+// whose invoice is past its due date and still not repaid
+type LateChargeService struct {
+	lateChargeRepo repository.LateChargeRepositoryInterface
+	interest       *InterestEngine
+	uow            repository.UnitOfWorkInterface
+}
+
+func NewLateChargeService(
+	lateChargeRepo repository.LateChargeRepositoryInterface,
+	interest *InterestEngine,
+	uow repository.UnitOfWorkInterface,
+) *LateChargeService {
+	return &LateChargeService{
+		lateChargeRepo: lateChargeRepo,
+		interest:       interest,
+		uow:            uow,
+	}
+}
+
+// AccrueOverdue brings the late charges of every overdue pool up to today and
+// returns how many pools were charged
+func (s *LateChargeService) AccrueOverdue() (int, error) {
+	now := time.Now()
+	poolIDs, err := s.lateChargeRepo.FindOverduePoolIDs(now)
+	if err != nil {
+		return 0, err
+	}
+
+	charged := 0
+	for _, poolID := range poolIDs {
+		err := s.uow.Do(func(repos *repository.Repositories) error {
+			pool, err := repos.Funding.FindPoolByIDForUpdate(poolID)
+			if err != nil {
+				return err
+			}
+			if pool == nil || pool.Status != models.PoolStatusDisbursed {
+				return nil
+			}
+			_, err = accruePoolLateCharge(repos, s.interest, pool, now)
+			return err
+		})
+		if err != nil {
+			fmt.Printf("[LATE_CHARGE] Failed to accrue pool %s: %v\n", poolID, err)
+			continue
+		}
+		charged++
+	}
+	return charged, nil
+}
+
+// accruePoolLateCharge loads what a locked pool's investors are owed and accrues
+// its late charges to asOf
+func accruePoolLateCharge(repos *repository.Repositories, interest *InterestEngine, pool *models.FundingPool, asOf time.Time) (*models.LateCharge, error) {
+	invoice, err := repos.Invoices.FindByID(pool.InvoiceID)
+	if err != nil {
+		return nil, err
+	}
+	if invoice == nil {
+		return nil, errors.New("invoice not found")
+	}
+	investments, err := repos.Funding.FindInvestmentsByPool(pool.ID)
+	if err != nil {
+		return nil, err
+	}
+	interest.AccrueClaims(pool, investments, invoice.DueDate, asOf)
+	return accrueLateCharge(repos, interest, pool, invoice, investments, asOf)
+}
+
+// accrueLateCharge brings a pool's late charges up to asOf. investments carry
+// their claims at the due date; what is still owed on them is the overdue
+// balance. The first accrual past the due date charges the late fee, and every
+// accrued amount is added to the importer's amount due. Returns nil while the
+// invoice is not past due.
+func accrueLateCharge(repos *repository.Repositories, interest *InterestEngine, pool *models.FundingPool, invoice *models.Invoice, investments []models.Investment, asOf time.Time) (*models.LateCharge, error) {
+	charge, err := repos.LateCharges.FindByPoolIDForUpdate(pool.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !asOf.After(invoice.DueDate) {
+		return charge, nil
+	}
+
+	overdue := overdueBalance(investments)
+
+	var before money.Amount
+	started := charge == nil
+	if started {
+		if overdue == 0 {
+			return nil, nil
+		}
+		charge = interest.NewLateCharge(pool, invoice, overdue)
+	} else {
+		before = charge.Total()
+	}
+	interest.AccrueLateCharge(charge, overdue, asOf)
+	if err := repos.LateCharges.Save(charge); err != nil {
+		return nil, err
+	}
+	if started {
+		if err := recordCreditEvent(repos.CreditScores, invoice, models.CreditEventLatePayment, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	if accrued := charge.Total() - before; accrued > 0 {
+		if err := repos.ImporterPayments.AddLateCharges(pool.ID, accrued); err != nil {
+			return nil, err
+		}
+	}
+	return charge, nil
+}
+
+// previewLateCharge is what a pool's late charges would be at asOf, without
+// storing anything
+func previewLateCharge(lateChargeRepo repository.LateChargeRepositoryInterface, interest *InterestEngine, pool *models.FundingPool, invoice *models.Invoice, investments []models.Investment, asOf time.Time) (*models.LateCharge, error) {
+	stored, err := lateChargeRepo.FindByPoolID(pool.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !asOf.After(invoice.DueDate) {
+		return stored, nil
+	}
+
+	overdue := overdueBalance(investments)
+
+	var charge *models.LateCharge
+	if stored == nil {
+		if overdue == 0 {
+			return nil, nil
+		}
+		charge = interest.NewLateCharge(pool, invoice, overdue)
+	} else {
+		copied := *stored
+		charge = &copied
+	}
+	interest.AccrueLateCharge(charge, overdue, asOf)
+	return charge, nil
+}
+
+// overdueBalance is what active investments are still owed on their claims
+func overdueBalance(investments []models.Investment) money.Amount {
+	var active []models.Investment
+	for _, inv := range investments {
+		if inv.Status == models.InvestmentStatusActive {
+			active = append(active, inv)
+		}
+	}
+	return money.Sum(outstandingReturns(active)...)
+}
+
+// splitLateCharges divides collected late charges between the platform and the
+// investors, who share theirs pro-rata by claim across both tranches
+func splitLateCharges(collected money.Amount, platformSharePercent float64, claims []money.Amount, currency string) (money.Amount, []money.Amount) {
+	platform := collected.MulRate(platformSharePercent).RoundTo(currency)
+	return platform, money.Allocate(collected-platform, claims, money.MinorUnit(currency))
+}
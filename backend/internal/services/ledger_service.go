@@ -0,0 +1,180 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/money"
+	"github.com/vessel/backend/internal/repository"
+)
+
+// LedgerService posts every money movement as a balanced double-entry journal entry.
+// User balances are derived from the ledger; nothing writes users.balance_idr directly.
+type LedgerService struct {
+	ledgerRepo repository.LedgerRepositoryInterface
+}
+
+func NewLedgerService(ledgerRepo repository.LedgerRepositoryInterface) *LedgerService {
+	return &LedgerService{ledgerRepo: ledgerRepo}
+}
+
+// WithRepository returns a LedgerService that posts through repo, typically
+// a ledger repository bound to a unit of work
+func (s *LedgerService) WithRepository(repo repository.LedgerRepositoryInterface) *LedgerService {
+	return &LedgerService{ledgerRepo: repo}
+}
+
+// LedgerPayout is a single credit to a user wallet
+type LedgerPayout struct {
+	UserID uuid.UUID
+	Amount money.Amount
+}
+
+// RepaymentPosting describes how an importer repayment received in escrow is split
+type RepaymentPosting struct {
+	InvoiceID      uuid.UUID
+	PlatformFee    money.Amount
+	InvestorPayout []LedgerPayout
+	MitraID        uuid.UUID
+	MitraExcess    money.Amount
+}
+
+func (s *LedgerService) post(refType string, refID *uuid.UUID, description string, lines ...models.JournalLine) error {
+	entry := &models.JournalEntry{
+		ReferenceType: refType,
+		ReferenceID:   refID,
+		Description:   description,
+		Lines:         lines,
+	}
+	return s.ledgerRepo.PostEntry(entry)
+}
+
+// RecordDeposit moves cash received in escrow into the user's wallet
+func (s *LedgerService) RecordDeposit(userID uuid.UUID, amount money.Amount, refID *uuid.UUID) error {
+	return s.post(models.LedgerRefDeposit, refID, "Deposit to user wallet",
+		models.Debit(models.EscrowAccount(), amount),
+		models.Credit(models.UserWalletAccount(userID), amount),
+	)
+}
+
+// RecordWithdrawal pays out of the user's wallet through escrow
+func (s *LedgerService) RecordWithdrawal(userID uuid.UUID, amount money.Amount, refID *uuid.UUID) error {
+	return s.post(models.LedgerRefWithdrawal, refID, "Withdrawal from user wallet",
+		models.Debit(models.UserWalletAccount(userID), amount),
+		models.Credit(models.EscrowAccount(), amount),
+	)
+}
+
+// RecordAdjustment credits (or, for a negative amount, debits) a user wallet against platform equity
+func (s *LedgerService) RecordAdjustment(userID uuid.UUID, amount money.Amount, refID *uuid.UUID) error {
+	return s.post(models.LedgerRefAdjustment, refID, "Admin balance adjustment",
+		models.Debit(models.PlatformEquityAccount(), amount),
+		models.Credit(models.UserWalletAccount(userID), amount),
+	)
+}
+
+// RecordInvestment moves funds from the investor wallet into the pool
+func (s *LedgerService) RecordInvestment(investorID, poolID uuid.UUID, amount money.Amount, tranche models.TrancheType) error {
+	return s.post(models.LedgerRefInvestment, &poolID, fmt.Sprintf("Investment in %s tranche", tranche),
+		models.Debit(models.UserWalletAccount(investorID), amount),
+		models.Credit(models.PoolAccount(poolID), amount),
+	)
+}
+
+// RecordDisbursement releases pool funds: the advance leaves escrow to the exporter
+// and the withheld platform fee is recognised as revenue
+func (s *LedgerService) RecordDisbursement(poolID uuid.UUID, funded, platformFee money.Amount) error {
+	return s.post(models.LedgerRefDisbursement, &poolID, "Pool disbursement to exporter",
+		models.Debit(models.PoolAccount(poolID), funded),
+		models.Credit(models.EscrowAccount(), funded-platformFee),
+		models.Credit(models.PlatformRevenueAccount(), platformFee),
+	)
+}
+
+// RecordRepayment books an importer repayment received in escrow and its split
+// between platform fee, investor returns and any excess owed to the mitra
+func (s *LedgerService) RecordRepayment(p *RepaymentPosting) error {
+	received := p.PlatformFee + p.MitraExcess
+
+	lines := []models.JournalLine{
+		models.Credit(models.PlatformRevenueAccount(), p.PlatformFee),
+		models.Credit(models.UserWalletAccount(p.MitraID), p.MitraExcess),
+	}
+	for _, payout := range p.InvestorPayout {
+		received += payout.Amount
+		lines = append(lines, models.Credit(models.UserWalletAccount(payout.UserID), payout.Amount))
+	}
+	lines = append(lines, models.Debit(models.EscrowAccount(), received))
+
+	return s.post(models.LedgerRefRepayment, &p.InvoiceID, "Importer repayment distribution", lines...)
+}
+
+// RecordInvestorReturns books exporter-funded returns paid from escrow to investor wallets
+func (s *LedgerService) RecordInvestorReturns(invoiceID uuid.UUID, payouts []LedgerPayout) error {
+	var total money.Amount
+	var lines []models.JournalLine
+	for _, payout := range payouts {
+		total += payout.Amount
+		lines = append(lines, models.Credit(models.UserWalletAccount(payout.UserID), payout.Amount))
+	}
+	if total == 0 {
+		return nil
+	}
+	lines = append(lines, models.Debit(models.EscrowAccount(), total))
+
+	return s.post(models.LedgerRefInvestorReturn, &invoiceID, "Exporter repayment to investors", lines...)
+}
+
+// RecordRefund returns pool funds to the investors of an expired pool
+func (s *LedgerService) RecordRefund(poolID uuid.UUID, payouts []LedgerPayout) error {
+	var total money.Amount
+	var lines []models.JournalLine
+	for _, payout := range payouts {
+		total += payout.Amount
+		lines = append(lines, models.Credit(models.UserWalletAccount(payout.UserID), payout.Amount))
+	}
+	if total == 0 {
+		return nil
+	}
+	lines = append(lines, models.Debit(models.PoolAccount(poolID), total))
+
+	return s.post(models.LedgerRefRefund, &poolID, "Refund of expired pool", lines...)
+}
+
+// RecordRecovery books money collected on a defaulted pool: it arrives in escrow
+// and is paid to investor wallets, with anything beyond their claims to the mitra
+func (s *LedgerService) RecordRecovery(poolID uuid.UUID, payouts []LedgerPayout, mitraID uuid.UUID, mitraExcess money.Amount) error {
+	total := mitraExcess
+	lines := []models.JournalLine{models.Credit(models.UserWalletAccount(mitraID), mitraExcess)}
+	for _, payout := range payouts {
+		total += payout.Amount
+		lines = append(lines, models.Credit(models.UserWalletAccount(payout.UserID), payout.Amount))
+	}
+	if total == 0 {
+		return nil
+	}
+	lines = append(lines, models.Debit(models.EscrowAccount(), total))
+
+	return s.post(models.LedgerRefRecovery, &poolID, "Recovery on defaulted pool", lines...)
+}
+
+// RecordSecondaryTrade pays the price of a secondary market position from the
+// buyer's wallet to the seller's. The pool account is untouched: the position
+// changes hands, the money invested in the pool does not.
+func (s *LedgerService) RecordSecondaryTrade(listingID, buyerID, sellerID uuid.UUID, price money.Amount) error {
+	return s.post(models.LedgerRefSecondaryTrade, &listingID, "Secondary market purchase of a position",
+		models.Debit(models.UserWalletAccount(buyerID), price),
+		models.Credit(models.UserWalletAccount(sellerID), price),
+	)
+}
+
+// GetUserBalance returns the user's wallet balance as derived from the ledger
+func (s *LedgerService) GetUserBalance(userID uuid.UUID) (money.Amount, error) {
+	return s.ledgerRepo.GetAccountBalance(models.UserWalletAccount(userID).Code)
+}
+
+// GetTrialBalance returns the trial balance with integrity checks
+func (s *LedgerService) GetTrialBalance() (*models.TrialBalance, error) {
+	return s.ledgerRepo.GetTrialBalance()
+}
@@ -3,7 +3,6 @@ package services
 import (
 	"errors"
 	"fmt"
-	"math/rand"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,23 +21,44 @@ var (
 )
 
 type MitraService struct {
-	mitraRepo     *repository.MitraRepository
-	userRepo      repository.UserRepositoryInterface
-	emailService  *EmailService
-	pinataService *PinataService
+	mitraRepo      *repository.MitraRepository
+	userRepo       repository.UserRepositoryInterface
+	fundingRepo    repository.FundingRepositoryInterface
+	invoiceRepo    repository.InvoiceRepositoryInterface
+	vaRepo         repository.VirtualAccountRepositoryInterface
+	fundingService *FundingService
+	gatewayService *PaymentGatewayService
+	emailService   *EmailService
+	pinataService  *PinataService
+	interest       *InterestEngine
+	lateCharges    repository.LateChargeRepositoryInterface
 }
 
 func NewMitraService(
 	mitraRepo *repository.MitraRepository,
 	userRepo repository.UserRepositoryInterface,
+	fundingRepo repository.FundingRepositoryInterface,
+	invoiceRepo repository.InvoiceRepositoryInterface,
+	vaRepo repository.VirtualAccountRepositoryInterface,
+	fundingService *FundingService,
+	gatewayService *PaymentGatewayService,
 	emailService *EmailService,
 	pinataService *PinataService,
+	interest *InterestEngine,
+	lateCharges repository.LateChargeRepositoryInterface,
 ) *MitraService {
 	return &MitraService{
-		mitraRepo:     mitraRepo,
-		userRepo:      userRepo,
-		emailService:  emailService,
-		pinataService: pinataService,
+		mitraRepo:      mitraRepo,
+		userRepo:       userRepo,
+		fundingRepo:    fundingRepo,
+		invoiceRepo:    invoiceRepo,
+		vaRepo:         vaRepo,
+		fundingService: fundingService,
+		gatewayService: gatewayService,
+		emailService:   emailService,
+		pinataService:  pinataService,
+		interest:       interest,
+		lateCharges:    lateCharges,
 	}
 }
 
@@ -274,38 +294,121 @@ func (s *MitraService) GetActiveInvoicesForRepayment(userID uuid.UUID) ([]models
 	return []models.MitraActiveInvoice{}, nil
 }
 
+// VA validity window for mitra repayment
+const vaExpiryDuration = 24 * time.Hour
+
 // GetRepaymentBreakdown calculates the breakdown of repayment by tranche
+// from the pool's investments. Amounts owed are each investor's principal plus
+// interest accrued from disbursement to today, the day the mitra pays, less what
+// earlier installments already paid; the platform fee was already withheld at
+// disbursement.
 func (s *MitraService) GetRepaymentBreakdown(userID, poolID uuid.UUID) (*models.MitraRepaymentBreakdown, error) {
-	// Get pool and verify ownership
-	// For MVP: abstracted to return sample breakdown
+	pool, err := s.fundingRepo.FindPoolByID(poolID)
+	if err != nil {
+		return nil, err
+	}
+	if pool == nil {
+		return nil, errors.New("pool not found")
+	}
 
-	breakdown := &models.MitraRepaymentBreakdown{
-		PoolID:               poolID,
-		PriorityInterestRate: 10.0,
-		CatalystInterestRate: 15.0,
-		Currency:             "IDR",
+	invoice, err := s.invoiceRepo.FindByID(pool.InvoiceID)
+	if err != nil {
+		return nil, err
+	}
+	if invoice == nil || invoice.ExporterID != userID {
+		return nil, errors.New("pool not found")
 	}
 
-	// Calculate sample values (in production, get from actual investments)
-	// Priority: principal 40M, interest 4M (10%), total 44M
-	// Catalyst: principal 10M, interest 1.5M (15%), total 11.5M
-	breakdown.PriorityPrincipal = 40000000
-	breakdown.CatalystPrincipal = 10000000
-	breakdown.TotalPrincipal = breakdown.PriorityPrincipal + breakdown.CatalystPrincipal
+	investments, err := s.fundingRepo.FindInvestmentsByPool(poolID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	s.interest.AccrueClaims(pool, investments, invoice.DueDate, now)
 
-	// Flat interest: principal × rate / 100
-	breakdown.PriorityInterest = breakdown.PriorityPrincipal * breakdown.PriorityInterestRate / 100
-	breakdown.CatalystInterest = breakdown.CatalystPrincipal * breakdown.CatalystInterestRate / 100
-	breakdown.TotalInterest = breakdown.PriorityInterest + breakdown.CatalystInterest
+	breakdown := &models.MitraRepaymentBreakdown{
+		PoolID:               poolID,
+		InvoiceID:            invoice.ID,
+		InvoiceNo:            invoice.InvoiceNumber,
+		BuyerName:            invoice.BuyerName,
+		DueDate:              invoice.DueDate,
+		PriorityInterestRate: pool.PriorityInterestRate,
+		CatalystInterestRate: pool.CatalystInterestRate,
+		Currency:             pool.PoolCurrency,
+	}
+
+	// One line per pool tranche, most senior first; the legacy fields mirror the
+	// tranches named priority and catalyst
+	byTranche := make(map[models.TrancheType]int)
+	for _, t := range pool.Tranches {
+		byTranche[t.Name] = len(breakdown.Tranches)
+		breakdown.Tranches = append(breakdown.Tranches, models.MitraTrancheBreakdown{
+			Tranche:      t.Name,
+			Seniority:    t.Seniority,
+			InterestRate: t.InterestRate,
+		})
+	}
+	owed := outstandingReturns(investments)
+	for i, inv := range investments {
+		if inv.Status != models.InvestmentStatusActive {
+			continue
+		}
+		idx, ok := byTranche[inv.Tranche]
+		if !ok {
+			idx = len(breakdown.Tranches)
+			byTranche[inv.Tranche] = idx
+			breakdown.Tranches = append(breakdown.Tranches, models.MitraTrancheBreakdown{
+				Tranche:      inv.Tranche,
+				Seniority:    trancheSeniority(pool, inv.Tranche),
+				InterestRate: trancheRate(pool, inv.Tranche),
+			})
+		}
+		line := &breakdown.Tranches[idx]
+		line.Principal += inv.Amount
+		line.Interest += inv.ExpectedReturn - inv.Amount
+		line.Paid += inv.ExpectedReturn - owed[i]
+	}
+	for i := range breakdown.Tranches {
+		line := &breakdown.Tranches[i]
+		line.Total = line.Principal + line.Interest - line.Paid
+		breakdown.TotalPrincipal += line.Principal
+		breakdown.TotalInterest += line.Interest
+		breakdown.TotalPaid += line.Paid
+		switch line.Tranche {
+		case models.TranchePriority:
+			breakdown.PriorityPrincipal = line.Principal
+			breakdown.PriorityInterest = line.Interest
+			breakdown.PriorityTotal = line.Total
+		case models.TrancheCatalyst:
+			breakdown.CatalystPrincipal = line.Principal
+			breakdown.CatalystInterest = line.Interest
+			breakdown.CatalystTotal = line.Total
+		}
+	}
 
-	breakdown.PriorityTotal = breakdown.PriorityPrincipal + breakdown.PriorityInterest
-	breakdown.CatalystTotal = breakdown.CatalystPrincipal + breakdown.CatalystInterest
-	breakdown.GrandTotal = breakdown.TotalPrincipal + breakdown.TotalInterest
+	// Past the due date the VA also collects the late fee and penalty interest to date
+	if pool.Status == models.PoolStatusDisbursed {
+		lateCharge, err := previewLateCharge(s.lateCharges, s.interest, pool, invoice, investments, now)
+		if err != nil {
+			return nil, err
+		}
+		if lateCharge != nil {
+			breakdown.DaysOverdue = s.interest.TenorDays(invoice.DueDate, now)
+			breakdown.LateFee = lateCharge.LateFee
+			breakdown.PenaltyRate = lateCharge.PenaltyRate
+			breakdown.PenaltyInterest = lateCharge.PenaltyInterest
+			breakdown.LateChargesPaid = lateCharge.Paid
+			breakdown.LateCharges = lateCharge.Outstanding()
+		}
+	}
+	breakdown.GrandTotal = breakdown.TotalPrincipal + breakdown.TotalInterest - breakdown.TotalPaid + breakdown.LateCharges + breakdown.PlatformFee
 
 	return breakdown, nil
 }
 
-// CreateVAPayment creates a Virtual Account for mitra to pay
+// CreateVAPayment creates a Virtual Account for mitra to pay. A pool has at most
+// one active VA: an unexpired VA for the same bank and amount is returned as is,
+// otherwise the previous VA is closed and replaced.
 func (s *MitraService) CreateVAPayment(userID uuid.UUID, req *models.CreateVARequest) (*models.VAPaymentResponse, error) {
 	// Validate bank code
 	var bankName string
@@ -324,85 +427,179 @@ func (s *MitraService) CreateVAPayment(userID uuid.UUID, req *models.CreateVAReq
 	if err != nil {
 		return nil, err
 	}
+	if breakdown.GrandTotal <= 0 {
+		return nil, errors.New("tidak ada tagihan yang perlu dibayar untuk pool ini")
+	}
 
-	// Generate VA number (abstracted for MVP)
-	vaNumber := fmt.Sprintf("8%s%d", req.BankCode[:3], rand.Intn(9999999999))
+	pool, err := s.fundingRepo.FindPoolByID(req.PoolID)
+	if err != nil {
+		return nil, err
+	}
+	if pool.Status != models.PoolStatusDisbursed {
+		return nil, errors.New("pool belum dicairkan, VA pembayaran belum dapat dibuat")
+	}
 
-	// Create VA with 24-hour expiry
-	expiresAt := time.Now().Add(24 * time.Hour)
-	va := &models.VirtualAccount{
-		ID:        uuid.New(),
-		PoolID:    req.PoolID,
-		UserID:    userID,
-		VANumber:  vaNumber,
-		BankCode:  req.BankCode,
-		BankName:  bankName,
-		Amount:    breakdown.GrandTotal,
-		Status:    models.VAStatusPending,
-		ExpiresAt: expiresAt,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+	va, err := s.vaRepo.FindActiveByPool(req.PoolID)
+	if err != nil {
+		return nil, err
 	}
+	if va == nil || !va.ExpiresAt.After(time.Now()) || va.BankCode != req.BankCode || va.Amount != breakdown.GrandTotal {
+		va = &models.VirtualAccount{
+			ID:        uuid.New(),
+			PoolID:    req.PoolID,
+			UserID:    userID,
+			BankCode:  req.BankCode,
+			BankName:  bankName,
+			Amount:    breakdown.GrandTotal,
+			Status:    models.VAStatusPending,
+			ExpiresAt: time.Now().Add(vaExpiryDuration),
+		}
 
-	// In production: store VA to database
-	// s.vaRepo.Create(va)
+		// The VA number is issued by the payment gateway
+		charge, err := s.gatewayService.CreateCharge(&ChargeRequest{
+			Purpose:     models.GatewayPurposeMitraRepayment,
+			ReferenceID: va.ID,
+			UserID:      &userID,
+			Method:      models.GatewayMethodVirtualAccount,
+			Amount:      va.Amount,
+			Currency:    breakdown.Currency,
+			BankCode:    req.BankCode,
+			Description: "Repayment " + breakdown.InvoiceNo,
+			ExpiresAt:   va.ExpiresAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+		va.VANumber = *charge.VANumber
 
-	// Calculate remaining time
-	remainingDuration := time.Until(expiresAt)
-	hours := int(remainingDuration.Hours())
-	minutes := int(remainingDuration.Minutes()) % 60
-	seconds := int(remainingDuration.Seconds()) % 60
-	remainingTime := fmt.Sprintf("%02d:%02d:%02d", hours, minutes, seconds)
+		if err := s.vaRepo.ReplaceActive(va); err != nil {
+			return nil, fmt.Errorf("failed to create virtual account: %w", err)
+		}
+	}
 
+	remainingDuration := time.Until(va.ExpiresAt)
 	return &models.VAPaymentResponse{
 		VA:             *va,
 		Breakdown:      *breakdown,
-		RemainingTime:  remainingTime,
-		RemainingHours: hours,
+		RemainingTime:  formatRemainingTime(remainingDuration),
+		RemainingHours: int(remainingDuration.Hours()),
 		Microcopy:      "Selesaikan pembayaran dalam waktu 24 jam. VA akan otomatis kadaluarsa setelah batas waktu.",
 	}, nil
 }
 
 // GetVAPaymentStatus gets VA payment details for payment page
 func (s *MitraService) GetVAPaymentStatus(userID, vaID uuid.UUID) (*models.VAPaymentPageResponse, error) {
-	// In production: fetch from database
-	// va, err := s.vaRepo.FindByID(vaID)
+	va, err := s.vaRepo.FindByID(vaID)
+	if err != nil {
+		return nil, err
+	}
+	if va == nil || va.UserID != userID {
+		return nil, errors.New("virtual account not found")
+	}
 
-	// For MVP: return sample data
-	expiresAt := time.Now().Add(23 * time.Hour)
-	remainingDuration := time.Until(expiresAt)
-	hours := int(remainingDuration.Hours())
-	minutes := int(remainingDuration.Minutes()) % 60
-	seconds := int(remainingDuration.Seconds()) % 60
+	// The expiry job runs periodically; report a lapsed VA as expired right away
+	if va.Status == models.VAStatusPending && !va.ExpiresAt.After(time.Now()) {
+		if err := s.vaRepo.UpdateStatus(va.ID, models.VAStatusExpired); err == nil {
+			va.Status = models.VAStatusExpired
+		}
+	}
+
+	breakdown, err := s.GetRepaymentBreakdown(userID, va.PoolID)
+	if err != nil {
+		return nil, err
+	}
 
-	breakdown, _ := s.GetRepaymentBreakdown(userID, uuid.New())
+	var remaining time.Duration
+	if va.Status == models.VAStatusPending {
+		remaining = time.Until(va.ExpiresAt)
+	}
 
 	return &models.VAPaymentPageResponse{
-		VANumber:        "88001234567890",
-		BankCode:        "bca",
-		BankName:        "Bank Central Asia (BCA)",
-		Amount:          breakdown.GrandTotal,
-		AmountFormatted: fmt.Sprintf("Rp %.0f", breakdown.GrandTotal),
-		Status:          models.VAStatusPending,
-		ExpiresAt:       expiresAt,
-		RemainingTime:   fmt.Sprintf("%02d:%02d:%02d", hours, minutes, seconds),
+		VANumber:        va.VANumber,
+		BankCode:        va.BankCode,
+		BankName:        va.BankName,
+		Amount:          va.Amount,
+		AmountFormatted: fmt.Sprintf("Rp %.0f", va.Amount.Float64()),
+		Status:          va.Status,
+		ExpiresAt:       va.ExpiresAt,
+		RemainingTime:   formatRemainingTime(remaining),
 		Breakdown:       *breakdown,
 		Microcopy:       "Nominal pembayaran bersifat tetap dan tidak dapat diubah.",
 	}, nil
 }
 
-// SimulateVAPayment simulates VA payment for MVP testing
+// ExpireVirtualAccounts marks pending VAs past their expiry as expired
+func (s *MitraService) ExpireVirtualAccounts() (int64, error) {
+	return s.vaRepo.ExpireOverdue(time.Now())
+}
+
+// formatRemainingTime renders a countdown as "HH:MM:SS"
+func formatRemainingTime(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	hours := int(d.Hours())
+	minutes := int(d.Minutes()) % 60
+	seconds := int(d.Seconds()) % 60
+	return fmt.Sprintf("%02d:%02d:%02d", hours, minutes, seconds)
+}
+
+// SimulateVAPayment pays a VA through the simulator gateway for MVP testing.
+// Settlement runs through the same signed webhook path as a real provider.
 func (s *MitraService) SimulateVAPayment(userID, vaID uuid.UUID) (map[string]interface{}, error) {
-	// In production: this would be a webhook from payment gateway
-	// For MVP: directly trigger the disbursement flow
+	va, err := s.vaRepo.FindByID(vaID)
+	if err != nil {
+		return nil, err
+	}
+	if va == nil || va.UserID != userID {
+		return nil, errors.New("virtual account not found")
+	}
+	if va.Status != models.VAStatusPending || !va.ExpiresAt.After(time.Now()) {
+		return nil, errors.New("virtual account is no longer active")
+	}
 
-	// Get VA details and update status to paid
-	// Then trigger ExporterDisbursementToInvestors
+	payment, err := s.gatewayService.FindPaymentByReference(models.GatewayPurposeMitraRepayment, va.ID)
+	if err != nil {
+		return nil, err
+	}
+	if payment == nil {
+		return nil, errors.New("gateway payment not found for virtual account")
+	}
+	if err := s.gatewayService.SimulatePayment(payment); err != nil {
+		return nil, err
+	}
+
+	va, err = s.vaRepo.FindByID(vaID)
+	if err != nil {
+		return nil, err
+	}
 
 	return map[string]interface{}{
-		"status":  "paid",
+		"status":  va.Status,
 		"message": "Pembayaran berhasil! Dana sedang didistribusikan ke investor.",
 		"va_id":   vaID,
-		"paid_at": time.Now(),
+		"paid_at": va.PaidAt,
 	}, nil
 }
+
+// SettleRepaymentVA distributes a confirmed VA payment to the pool's investors,
+// inside the transaction that claims the gateway payment. Money that reaches a
+// VA after it expired or was replaced is applied all the same: the mitra paid
+// it, and the VA is marked paid.
+func (s *MitraService) SettleRepaymentVA(repos *repository.Repositories, payment *models.GatewayPayment) error {
+	va, err := repos.VirtualAccounts.FindByID(payment.ReferenceID)
+	if err != nil {
+		return err
+	}
+	if va == nil {
+		return errors.New("virtual account not found")
+	}
+	if va.Status == models.VAStatusPaid {
+		return nil
+	}
+
+	if _, err := s.fundingService.repayPool(repos, va.PoolID, payment.Amount); err != nil {
+		return err
+	}
+	return repos.VirtualAccounts.MarkPaid(va.ID)
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// nonceSource reads the next nonce an account may use, counting pending transactions
+type nonceSource interface {
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+}
+
+// NonceManager hands out the platform account's nonces one send at a time. The
+// nonce is read from the node once and then counted locally, so concurrent sends
+// never reuse a nonce while the node's pending pool catches up.
+type NonceManager struct {
+	mu      sync.Mutex
+	source  nonceSource
+	address common.Address
+	next    uint64
+	synced  bool
+}
+
+func NewNonceManager(source nonceSource, address common.Address) *NonceManager {
+	return &NonceManager{source: source, address: address}
+}
+
+// Acquire locks the account and returns the nonce to send with. The caller must
+// call release exactly once: with true when the transaction reached the node, so
+// the nonce is consumed, or false to resync from the node on the next send.
+func (m *NonceManager) Acquire(ctx context.Context) (nonce uint64, release func(sent bool), err error) {
+	m.mu.Lock()
+	if !m.synced {
+		pending, err := m.source.PendingNonceAt(ctx, m.address)
+		if err != nil {
+			m.mu.Unlock()
+			return 0, nil, err
+		}
+		m.next = pending
+		m.synced = true
+	}
+
+	nonce = m.next
+	release = func(sent bool) {
+		if sent {
+			m.next++
+		} else {
+			m.synced = false
+		}
+		m.mu.Unlock()
+	}
+	return nonce, release, nil
+}
+
+// Lock serializes a send that reuses an earlier nonce (a replacement) with
+// regular sends, without consuming a new nonce
+func (m *NonceManager) Lock() func() {
+	m.mu.Lock()
+	return m.mu.Unlock
+}
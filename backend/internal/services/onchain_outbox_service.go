@@ -0,0 +1,424 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/config"
+	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/repository"
+)
+
+const (
+	outboxBatchSize    = 20
+	outboxReceiptBatch = 100
+	outboxLease        = 2 * time.Minute // A send that outlives its lease is claimed again
+	outboxMaxBackoff   = time.Hour
+)
+
+var ErrOutboxEntryNotFound = errors.New("outbox entry not found")
+
+// OnchainOutboxService submits queued InvoicePool writes to the chain. Entries are
+// written by the business transaction, so a write is sent only if its change
+// committed and is retried until it reaches the chain or runs out of attempts.
+// Sent transactions are then followed until their receipt is final.
+type OnchainOutboxService struct {
+	repo          repository.OnchainOutboxRepositoryInterface
+	txRepo        repository.TransactionRepositoryInterface
+	blockchain    *BlockchainService
+	maxAttempts   int
+	baseBackoff   time.Duration
+	confirmations uint64
+	stuckAfter    time.Duration
+	gasBumpPct    int64
+	maxGasPrice   *big.Int
+}
+
+func NewOnchainOutboxService(
+	repo repository.OnchainOutboxRepositoryInterface,
+	txRepo repository.TransactionRepositoryInterface,
+	blockchain *BlockchainService,
+	cfg *config.Config,
+) *OnchainOutboxService {
+	s := &OnchainOutboxService{
+		repo:          repo,
+		txRepo:        txRepo,
+		blockchain:    blockchain,
+		maxAttempts:   cfg.OnchainOutboxMaxAttempts,
+		baseBackoff:   time.Duration(cfg.OnchainOutboxBaseBackoffSecs) * time.Second,
+		confirmations: 3,
+		stuckAfter:    time.Duration(cfg.OnchainTxStuckMinutes) * time.Minute,
+		gasBumpPct:    int64(cfg.OnchainGasPriceBumpPct),
+		maxGasPrice:   new(big.Int).Mul(big.NewInt(cfg.OnchainMaxGasPriceGwei), big.NewInt(1e9)),
+	}
+	if s.maxAttempts <= 0 {
+		s.maxAttempts = 10
+	}
+	if s.baseBackoff <= 0 {
+		s.baseBackoff = 30 * time.Second
+	}
+	if cfg.OnchainConfirmations > 0 {
+		s.confirmations = uint64(cfg.OnchainConfirmations)
+	}
+	if s.stuckAfter <= 0 {
+		s.stuckAfter = 10 * time.Minute
+	}
+	// Nodes reject replacements that raise the gas price by less than 10%
+	if s.gasBumpPct < 10 {
+		s.gasBumpPct = 10
+	}
+	return s
+}
+
+// ProcessDue submits every due entry and returns how many were sent
+func (s *OnchainOutboxService) ProcessDue() (int, error) {
+	if s.blockchain == nil {
+		return 0, errors.New("blockchain service not available")
+	}
+
+	submitted := 0
+	for {
+		entries, err := s.repo.ClaimDue(outboxBatchSize, outboxLease)
+		if err != nil {
+			return submitted, err
+		}
+		if len(entries) == 0 {
+			return submitted, nil
+		}
+		for i := range entries {
+			if s.process(&entries[i]) {
+				submitted++
+			}
+		}
+		if len(entries) < outboxBatchSize {
+			return submitted, nil
+		}
+	}
+}
+
+func (s *OnchainOutboxService) process(entry *models.OnchainOutboxEntry) bool {
+	sub, err := s.submit(s.blockchain, entry)
+	if err == nil {
+		if err := s.repo.MarkSubmitted(entry.ID, sub); err != nil {
+			fmt.Printf("[ONCHAIN_OUTBOX] Failed to mark %s submitted (tx %s): %v\n", entry.ID, sub.TxHash, err)
+			return false
+		}
+		s.linkTransactions(entry, sub.TxHash)
+		return true
+	}
+
+	if entry.Attempts >= s.maxAttempts || errors.Is(err, ErrOnchainRecordMissing) {
+		fmt.Printf("[ONCHAIN_OUTBOX] %s for invoice %s failed after %d attempts: %v\n", entry.Action, entry.InvoiceID, entry.Attempts, err)
+		if markErr := s.repo.MarkFailed(entry.ID, err.Error()); markErr != nil {
+			fmt.Printf("[ONCHAIN_OUTBOX] Failed to park %s: %v\n", entry.ID, markErr)
+		}
+		return false
+	}
+
+	next := time.Now().Add(s.backoff(entry.Attempts))
+	fmt.Printf("[ONCHAIN_OUTBOX] %s for invoice %s attempt %d failed, retrying at %s: %v\n",
+		entry.Action, entry.InvoiceID, entry.Attempts, next.Format(time.RFC3339), err)
+	if markErr := s.repo.MarkRetry(entry.ID, err.Error(), next); markErr != nil {
+		fmt.Printf("[ONCHAIN_OUTBOX] Failed to reschedule %s: %v\n", entry.ID, markErr)
+	}
+	return false
+}
+
+// backoff doubles the base delay for every attempt already made, up to an hour
+func (s *OnchainOutboxService) backoff(attempts int) time.Duration {
+	delay := s.baseBackoff
+	for i := 1; i < attempts && delay < outboxMaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > outboxMaxBackoff {
+		delay = outboxMaxBackoff
+	}
+	return delay
+}
+
+// submit sends the contract call for an entry through chain, which is the
+// service itself or a copy that replaces an earlier transaction
+func (s *OnchainOutboxService) submit(chain *BlockchainService, entry *models.OnchainOutboxEntry) (*models.OnchainSubmission, error) {
+	var payload models.OnchainPayload
+	if err := json.Unmarshal(entry.Payload, &payload); err != nil {
+		return nil, fmt.Errorf("invalid outbox payload: %w", err)
+	}
+	if entry.PoolID == nil {
+		return nil, errors.New("outbox entry has no pool")
+	}
+
+	var tx *BlockchainTransaction
+	var err error
+	switch entry.Action {
+	case models.OnchainActionCreatePool:
+		tx, err = chain.CreatePoolOnChain(*entry.PoolID)
+	case models.OnchainActionRecordInvestment:
+		tx, err = chain.RecordInvestment(*entry.PoolID, payload.Wallet, payload.Amount)
+	case models.OnchainActionRecordDisbursement:
+		tx, err = chain.RecordDisbursement(*entry.PoolID, payload.Amount)
+	case models.OnchainActionRecordRepayment:
+		tx, err = chain.RecordRepayment(*entry.PoolID, payload.Amount)
+	case models.OnchainActionRecordMitraCredit:
+		tx, err = chain.RecordMitraBalanceCredit(entry.InvoiceID, payload.Wallet, payload.Amount)
+	case models.OnchainActionMarkDefaulted:
+		tx, err = chain.MarkDefaulted(*entry.PoolID)
+	case models.OnchainActionBurnNFT:
+		tx, err = chain.BurnNFT(entry.InvoiceID)
+	case models.OnchainActionTransferInvestment:
+		tx, err = chain.TransferInvestment(*entry.PoolID, payload.FromWallet, payload.Wallet, payload.Position, payload.Amount)
+	default:
+		return nil, fmt.Errorf("unknown outbox action %q", entry.Action)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &models.OnchainSubmission{TxHash: tx.TxHash}
+	if tx.Nonce != nil {
+		nonce := int64(*tx.Nonce)
+		gasLimit := int64(tx.GasLimit)
+		sub.Nonce = &nonce
+		sub.GasLimit = &gasLimit
+	}
+	if tx.GasPrice != nil {
+		gasPrice := tx.GasPrice.String()
+		sub.GasPrice = &gasPrice
+	}
+	return sub, nil
+}
+
+// linkTransactions points the entry's transactions at the hash that records them
+func (s *OnchainOutboxService) linkTransactions(entry *models.OnchainOutboxEntry, txHash string) {
+	for _, id := range entry.TransactionIDs {
+		if err := s.txRepo.SetTxHash(id, txHash); err != nil {
+			fmt.Printf("[ONCHAIN_OUTBOX] Failed to set tx hash on transaction %s: %v\n", id, err)
+		}
+	}
+}
+
+// TrackReceipts follows submitted entries. An entry is confirmed once its receipt
+// has the configured number of confirmations, and its block number and gas used
+// are written to the linked transactions. A reverted transaction parks the entry as failed.
+// A transaction left unmined for too long is replaced with a higher gas price.
+// It returns how many entries were confirmed.
+func (s *OnchainOutboxService) TrackReceipts() (int, error) {
+	if s.blockchain == nil {
+		return 0, errors.New("blockchain service not available")
+	}
+
+	entries, err := s.repo.FindSubmitted(outboxReceiptBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	confirmed := 0
+	for i := range entries {
+		entry := &entries[i]
+		if entry.TxHash == nil {
+			continue
+		}
+
+		// Simulated sends have nothing to wait for
+		if !s.blockchain.Enabled() {
+			if err := s.repo.MarkConfirmed(entry.ID, *entry.TxHash, nil, nil); err != nil {
+				fmt.Printf("[ONCHAIN_OUTBOX] Failed to confirm %s: %v\n", entry.ID, err)
+				continue
+			}
+			for _, id := range entry.TransactionIDs {
+				if err := s.txRepo.UpdateStatus(id, models.TxStatusConfirmed); err != nil {
+					fmt.Printf("[ONCHAIN_OUTBOX] Failed to confirm transaction %s: %v\n", id, err)
+				}
+			}
+			confirmed++
+			continue
+		}
+
+		done, err := s.track(entry)
+		if err != nil {
+			fmt.Printf("[ONCHAIN_OUTBOX] Failed to track %s (tx %s): %v\n", entry.ID, *entry.TxHash, err)
+			continue
+		}
+		if done {
+			confirmed++
+		}
+	}
+	return confirmed, nil
+}
+
+func (s *OnchainOutboxService) track(entry *models.OnchainOutboxEntry) (bool, error) {
+	ctx := context.Background()
+
+	// A replaced transaction may still be the one that got mined
+	var receipt *TxReceipt
+	hashes := append([]string{*entry.TxHash}, entry.PreviousTxHashes...)
+	for _, hash := range hashes {
+		r, err := s.blockchain.GetReceipt(ctx, hash)
+		if err != nil {
+			return false, err
+		}
+		if r != nil {
+			receipt = r
+			break
+		}
+	}
+
+	if receipt == nil {
+		if entry.SubmittedAt != nil && time.Since(*entry.SubmittedAt) > s.stuckAfter {
+			return false, s.replace(ctx, entry)
+		}
+		return false, nil
+	}
+	if receipt.Confirmations < s.confirmations {
+		return false, nil
+	}
+
+	if !receipt.Success {
+		msg := fmt.Sprintf("transaction %s reverted in block %d", receipt.TxHash, receipt.BlockNumber)
+		fmt.Printf("[ONCHAIN_OUTBOX] %s for invoice %s: %s\n", entry.Action, entry.InvoiceID, msg)
+		return false, s.repo.MarkFailed(entry.ID, msg)
+	}
+
+	if err := s.repo.MarkConfirmed(entry.ID, receipt.TxHash, &receipt.BlockNumber, &receipt.GasUsed); err != nil {
+		return false, err
+	}
+	for _, id := range entry.TransactionIDs {
+		if receipt.TxHash != *entry.TxHash {
+			if err := s.txRepo.SetTxHash(id, receipt.TxHash); err != nil {
+				return false, err
+			}
+		}
+		if err := s.txRepo.UpdateBlockInfo(id, receipt.BlockNumber, receipt.GasUsed); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// replace resends a stuck entry with its original nonce and a gas price raised by
+// the bump percentage, or to the node's current price if that is higher
+func (s *OnchainOutboxService) replace(ctx context.Context, entry *models.OnchainOutboxEntry) error {
+	if entry.Nonce == nil || entry.GasPrice == nil {
+		return errors.New("entry has no send parameters to replace")
+	}
+	oldPrice, ok := new(big.Int).SetString(*entry.GasPrice, 10)
+	if !ok {
+		return fmt.Errorf("invalid gas price %q", *entry.GasPrice)
+	}
+
+	gasPrice := new(big.Int).Mul(oldPrice, big.NewInt(100+s.gasBumpPct))
+	gasPrice.Div(gasPrice, big.NewInt(100))
+	suggested, err := s.blockchain.SuggestGasPrice(ctx)
+	if err != nil {
+		return err
+	}
+	if suggested.Cmp(gasPrice) > 0 {
+		gasPrice = suggested
+	}
+	if gasPrice.Cmp(s.maxGasPrice) > 0 {
+		return fmt.Errorf("replacement gas price %s wei exceeds the configured maximum", gasPrice)
+	}
+
+	sub, err := s.submit(s.blockchain.WithReplacement(uint64(*entry.Nonce), gasPrice), entry)
+	if err != nil {
+		return fmt.Errorf("replacement failed: %w", err)
+	}
+	if err := s.repo.MarkReplaced(entry.ID, sub); err != nil {
+		return err
+	}
+	s.linkTransactions(entry, sub.TxHash)
+	fmt.Printf("[ONCHAIN_OUTBOX] Replaced stuck tx %s for invoice %s with %s at %s wei\n",
+		*entry.TxHash, entry.InvoiceID, sub.TxHash, gasPrice)
+	return nil
+}
+
+// ListEntries returns outbox entries for the admin view, by default those that
+// are stuck: not confirmed after stuck_minutes, including failed ones
+func (s *OnchainOutboxService) ListEntries(filter *models.OnchainOutboxFilter) (*models.OnchainOutboxListResponse, error) {
+	status := filter.Status
+	if status == "" {
+		status = "stuck"
+	}
+	switch models.OutboxStatus(status) {
+	case models.OutboxStatusPending, models.OutboxStatusProcessing, models.OutboxStatusSubmitted,
+		models.OutboxStatusConfirmed, models.OutboxStatusFailed, models.OutboxStatusCancelled, "stuck":
+	default:
+		return nil, errors.New("status must be pending, processing, submitted, confirmed, failed, cancelled or stuck")
+	}
+	stuckMinutes := filter.StuckMinutes
+	if stuckMinutes <= 0 {
+		stuckMinutes = 15
+	}
+	page, perPage := filter.Page, filter.PerPage
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	entries, total, err := s.repo.List(status, time.Now().Add(-time.Duration(stuckMinutes)*time.Minute), page, perPage)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.repo.CountByStatus()
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = []models.OnchainOutboxEntry{}
+	}
+
+	return &models.OnchainOutboxListResponse{
+		Entries:    entries,
+		Counts:     counts,
+		Total:      total,
+		Page:       page,
+		PerPage:    perPage,
+		TotalPages: models.CalculateTotalPages(total, perPage),
+	}, nil
+}
+
+// RetryEntry puts a failed entry back in the queue with a fresh attempt budget
+func (s *OnchainOutboxService) RetryEntry(id uuid.UUID) (*models.OnchainOutboxEntry, error) {
+	entry, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, ErrOutboxEntryNotFound
+	}
+	requeued, err := s.repo.Requeue(id)
+	if err != nil {
+		return nil, err
+	}
+	if !requeued {
+		return nil, fmt.Errorf("only failed entries can be retried (entry is %s)", entry.Status)
+	}
+	return s.repo.FindByID(id)
+}
+
+// CancelEntry skips a failed entry that cannot succeed, for example one whose
+// pool or NFT is missing on-chain, so the invoice's later entries and
+// reconciliation corrections are no longer held behind it
+func (s *OnchainOutboxService) CancelEntry(id uuid.UUID, reason string) (*models.OnchainOutboxEntry, error) {
+	entry, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, ErrOutboxEntryNotFound
+	}
+	cancelled, err := s.repo.Cancel(id, reason)
+	if err != nil {
+		return nil, err
+	}
+	if !cancelled {
+		return nil, fmt.Errorf("only failed entries can be cancelled (entry is %s)", entry.Status)
+	}
+	return s.repo.FindByID(id)
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/money"
+)
+
+// PaymentGateway is a payment provider that collects money into escrow
+type PaymentGateway interface {
+	Provider() string
+	CreateVirtualAccount(req *GatewayChargeRequest) (*GatewayCharge, error)
+	CreatePaymentLink(req *GatewayChargeRequest) (*GatewayCharge, error)
+	GetPaymentStatus(externalID string) (*GatewayCharge, error)
+	Refund(externalID string, amount money.Amount) error
+	// ParseWebhook decodes a callback whose signature was already verified
+	ParseWebhook(payload []byte) (*GatewayWebhookEvent, error)
+}
+
+// GatewayChargeRequest describes a charge to create with the provider
+type GatewayChargeRequest struct {
+	ReferenceID string // Our reference, echoed back by the provider
+	Amount      money.Amount
+	Currency    string
+	BankCode    string // Virtual accounts only
+	Description string
+	ExpiresAt   time.Time
+}
+
+// GatewayCharge is the provider's view of a charge
+type GatewayCharge struct {
+	ExternalID string
+	Status     models.GatewayPaymentStatus
+	Amount     money.Amount
+	VANumber   string
+	PaymentURL string
+	PaidAt     *time.Time
+}
+
+// GatewayWebhookEvent is a decoded provider callback
+type GatewayWebhookEvent struct {
+	EventID    string
+	ExternalID string
+	Status     models.GatewayPaymentStatus
+	Amount     money.Amount
+	PaidAt     *time.Time
+}
+
+// SignWebhookPayload returns the hex HMAC-SHA256 of payload under secret
+func SignWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature checks a hex HMAC-SHA256 signature in constant time
+func VerifyWebhookSignature(secret string, payload []byte, signature string) bool {
+	if secret == "" || signature == "" {
+		return false
+	}
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// ==================== Simulator ====================
+
+const SimulatorProvider = "simulator"
+
+// simulatorWebhook is the callback body sent by the simulator
+type simulatorWebhook struct {
+	EventID    string       `json:"event_id"`
+	ExternalID string       `json:"external_id"`
+	Status     string       `json:"status"`
+	Amount     money.Amount `json:"amount"`
+	PaidAt     *time.Time   `json:"paid_at,omitempty"`
+}
+
+// SimulatorGateway is an in-memory payment provider for local development.
+// Charges are settled with Settle, which produces the same signed webhook a real
+// provider would send.
+type SimulatorGateway struct {
+	secret      string
+	frontendURL string
+	mu          sync.Mutex
+	charges     map[string]*GatewayCharge
+}
+
+func NewSimulatorGateway(secret, frontendURL string) *SimulatorGateway {
+	return &SimulatorGateway{
+		secret:      secret,
+		frontendURL: frontendURL,
+		charges:     make(map[string]*GatewayCharge),
+	}
+}
+
+func (g *SimulatorGateway) Provider() string {
+	return SimulatorProvider
+}
+
+func (g *SimulatorGateway) CreateVirtualAccount(req *GatewayChargeRequest) (*GatewayCharge, error) {
+	if len(req.BankCode) < 3 {
+		return nil, errors.New("bank code is required for a virtual account")
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(1e10))
+	if err != nil {
+		return nil, err
+	}
+	charge := &GatewayCharge{
+		ExternalID: "sim_va_" + uuid.New().String(),
+		Status:     models.GatewayPaymentPending,
+		Amount:     req.Amount,
+		VANumber:   fmt.Sprintf("8%s%010d", req.BankCode[:3], n.Int64()),
+	}
+	return g.store(charge), nil
+}
+
+func (g *SimulatorGateway) CreatePaymentLink(req *GatewayChargeRequest) (*GatewayCharge, error) {
+	externalID := "sim_link_" + uuid.New().String()
+	charge := &GatewayCharge{
+		ExternalID: externalID,
+		Status:     models.GatewayPaymentPending,
+		Amount:     req.Amount,
+		PaymentURL: g.frontendURL + "/simulator/pay/" + externalID,
+	}
+	return g.store(charge), nil
+}
+
+func (g *SimulatorGateway) GetPaymentStatus(externalID string) (*GatewayCharge, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	charge, ok := g.charges[externalID]
+	if !ok {
+		return nil, errors.New("charge not found")
+	}
+	copied := *charge
+	return &copied, nil
+}
+
+func (g *SimulatorGateway) Refund(externalID string, amount money.Amount) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	charge, ok := g.charges[externalID]
+	if !ok {
+		return errors.New("charge not found")
+	}
+	if charge.Status != models.GatewayPaymentPaid {
+		return errors.New("only paid charges can be refunded")
+	}
+	if amount > charge.Amount {
+		return errors.New("refund exceeds charged amount")
+	}
+	charge.Status = models.GatewayPaymentRefunded
+	return nil
+}
+
+func (g *SimulatorGateway) ParseWebhook(payload []byte) (*GatewayWebhookEvent, error) {
+	var body simulatorWebhook
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+	if body.EventID == "" || body.ExternalID == "" {
+		return nil, errors.New("webhook payload is missing event_id or external_id")
+	}
+	return &GatewayWebhookEvent{
+		EventID:    body.EventID,
+		ExternalID: body.ExternalID,
+		Status:     models.GatewayPaymentStatus(body.Status),
+		Amount:     body.Amount,
+		PaidAt:     body.PaidAt,
+	}, nil
+}
+
+// Settle marks a charge as paid in full and returns the signed webhook for it.
+// Charges created before a restart are unknown to the in-memory simulator and
+// are settled for the given amount.
+func (g *SimulatorGateway) Settle(externalID string, amount money.Amount) (payload []byte, signature string, err error) {
+	g.mu.Lock()
+	charge, ok := g.charges[externalID]
+	if !ok {
+		charge = &GatewayCharge{ExternalID: externalID, Amount: amount}
+		g.charges[externalID] = charge
+	}
+	now := time.Now()
+	charge.Status = models.GatewayPaymentPaid
+	charge.PaidAt = &now
+	amount = charge.Amount
+	g.mu.Unlock()
+
+	payload, err = json.Marshal(&simulatorWebhook{
+		EventID:    "sim_evt_" + uuid.New().String(),
+		ExternalID: externalID,
+		Status:     string(models.GatewayPaymentPaid),
+		Amount:     amount,
+		PaidAt:     &now,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return payload, SignWebhookPayload(g.secret, payload), nil
+}
+
+func (g *SimulatorGateway) store(charge *GatewayCharge) *GatewayCharge {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.charges[charge.ExternalID] = charge
+	copied := *charge
+	return &copied
+}
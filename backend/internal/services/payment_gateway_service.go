@@ -0,0 +1,232 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/money"
+	"github.com/vessel/backend/internal/repository"
+)
+
+var (
+	ErrUnknownPaymentProvider   = errors.New("unknown payment provider")
+	ErrInvalidWebhookSignature  = errors.New("invalid webhook signature")
+	ErrDuplicateWebhookEvent    = errors.New("webhook event already processed")
+	ErrGatewaySimulatorDisabled = errors.New("payment simulation is only available with the simulator gateway")
+)
+
+// PaymentSettlementHandler applies a confirmed gateway payment to the local record
+// it references. It runs in the transaction that claims the payment as paid, so
+// each payment is settled exactly once however often the provider reports it.
+type PaymentSettlementHandler func(repos *repository.Repositories, payment *models.GatewayPayment) error
+
+// PaymentGatewayService creates charges with the configured provider and turns
+// signed provider webhooks into settlements of deposits and repayments
+type PaymentGatewayService struct {
+	gateway    PaymentGateway
+	repo       repository.PaymentGatewayRepositoryInterface
+	uow        repository.UnitOfWorkInterface
+	secret     string
+	autoSettle bool
+	handlers   map[models.GatewayPaymentPurpose]PaymentSettlementHandler
+}
+
+func NewPaymentGatewayService(
+	gateway PaymentGateway,
+	repo repository.PaymentGatewayRepositoryInterface,
+	uow repository.UnitOfWorkInterface,
+	webhookSecret string,
+	autoSettle bool,
+) *PaymentGatewayService {
+	return &PaymentGatewayService{
+		gateway:    gateway,
+		repo:       repo,
+		uow:        uow,
+		secret:     webhookSecret,
+		autoSettle: autoSettle,
+		handlers:   make(map[models.GatewayPaymentPurpose]PaymentSettlementHandler),
+	}
+}
+
+// OnSettled registers the handler that settles payments for purpose
+func (s *PaymentGatewayService) OnSettled(purpose models.GatewayPaymentPurpose, handler PaymentSettlementHandler) {
+	s.handlers[purpose] = handler
+}
+
+// ChargeRequest describes a local record to collect payment for
+type ChargeRequest struct {
+	Purpose     models.GatewayPaymentPurpose
+	ReferenceID uuid.UUID
+	UserID      *uuid.UUID
+	Method      models.GatewayPaymentMethod
+	Amount      money.Amount
+	Currency    string
+	BankCode    string
+	Description string
+	ExpiresAt   time.Time
+}
+
+// CreateCharge creates a VA or payment link with the provider and stores it
+func (s *PaymentGatewayService) CreateCharge(req *ChargeRequest) (*models.GatewayPayment, error) {
+	gatewayReq := &GatewayChargeRequest{
+		ReferenceID: req.ReferenceID.String(),
+		Amount:      req.Amount,
+		Currency:    req.Currency,
+		BankCode:    req.BankCode,
+		Description: req.Description,
+		ExpiresAt:   req.ExpiresAt,
+	}
+
+	var charge *GatewayCharge
+	var err error
+	switch req.Method {
+	case models.GatewayMethodVirtualAccount:
+		charge, err = s.gateway.CreateVirtualAccount(gatewayReq)
+	case models.GatewayMethodPaymentLink:
+		charge, err = s.gateway.CreatePaymentLink(gatewayReq)
+	default:
+		return nil, errors.New("unsupported payment method")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("payment gateway error: %w", err)
+	}
+
+	payment := &models.GatewayPayment{
+		Provider:    s.gateway.Provider(),
+		ExternalID:  charge.ExternalID,
+		Purpose:     req.Purpose,
+		ReferenceID: req.ReferenceID,
+		UserID:      req.UserID,
+		Method:      req.Method,
+		Amount:      req.Amount,
+		Currency:    req.Currency,
+		Status:      models.GatewayPaymentPending,
+	}
+	if charge.VANumber != "" {
+		payment.VANumber = &charge.VANumber
+	}
+	if charge.PaymentURL != "" {
+		payment.PaymentURL = &charge.PaymentURL
+	}
+	if !req.ExpiresAt.IsZero() {
+		payment.ExpiresAt = &req.ExpiresAt
+	}
+	if err := s.repo.CreatePayment(payment); err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// AutoSettle immediately confirms a charge when running against the simulator
+// with auto-settle enabled; it is a no-op otherwise. Call it only after the
+// record the charge references has been stored.
+func (s *PaymentGatewayService) AutoSettle(payment *models.GatewayPayment) error {
+	if !s.autoSettle {
+		return nil
+	}
+	if _, ok := s.gateway.(*SimulatorGateway); !ok {
+		return nil
+	}
+	return s.SimulatePayment(payment)
+}
+
+// SimulatePayment has the simulator pay a charge in full and delivers the
+// resulting signed webhook through the regular webhook path
+func (s *PaymentGatewayService) SimulatePayment(payment *models.GatewayPayment) error {
+	simulator, ok := s.gateway.(*SimulatorGateway)
+	if !ok {
+		return ErrGatewaySimulatorDisabled
+	}
+	payload, signature, err := simulator.Settle(payment.ExternalID, payment.Amount)
+	if err != nil {
+		return err
+	}
+	return s.HandleWebhook(simulator.Provider(), payload, signature)
+}
+
+// FindPaymentByReference returns the latest charge for a local record
+func (s *PaymentGatewayService) FindPaymentByReference(purpose models.GatewayPaymentPurpose, referenceID uuid.UUID) (*models.GatewayPayment, error) {
+	return s.repo.FindLatestPaymentByReference(purpose, referenceID)
+}
+
+// HandleWebhook verifies and applies a provider callback. Each provider event ID
+// is processed once; a redelivery returns ErrDuplicateWebhookEvent. The event is
+// recorded in the transaction that applies it, so a redelivery that arrives while
+// the first attempt is in flight waits for it, and a failed attempt leaves no
+// record behind and the provider's retry is processed again.
+func (s *PaymentGatewayService) HandleWebhook(provider string, payload []byte, signature string) error {
+	if provider != s.gateway.Provider() {
+		return ErrUnknownPaymentProvider
+	}
+	if !VerifyWebhookSignature(s.secret, payload, signature) {
+		return ErrInvalidWebhookSignature
+	}
+
+	event, err := s.gateway.ParseWebhook(payload)
+	if err != nil {
+		return err
+	}
+
+	return s.uow.Do(func(repos *repository.Repositories) error {
+		record := &models.PaymentWebhookEvent{
+			Provider: provider,
+			EventID:  event.EventID,
+			Payload:  payload,
+		}
+		inserted, err := repos.PaymentGateway.RecordWebhookEvent(record)
+		if err != nil {
+			return err
+		}
+		if !inserted {
+			return ErrDuplicateWebhookEvent
+		}
+		if err := s.applyEvent(repos, provider, event); err != nil {
+			return err
+		}
+		return repos.PaymentGateway.MarkWebhookEventProcessed(record.ID)
+	})
+}
+
+// applyEvent applies a webhook event inside the transaction that records it
+func (s *PaymentGatewayService) applyEvent(repos *repository.Repositories, provider string, event *GatewayWebhookEvent) error {
+	payment, err := repos.PaymentGateway.FindPaymentByExternalID(provider, event.ExternalID)
+	if err != nil {
+		return err
+	}
+	if payment == nil {
+		return fmt.Errorf("no payment found for %s charge %s", provider, event.ExternalID)
+	}
+
+	switch event.Status {
+	case models.GatewayPaymentPaid:
+		if payment.Status != models.GatewayPaymentPending && payment.Status != models.GatewayPaymentExpired {
+			return nil
+		}
+		// Closed amounts: anything but the exact charge is left for manual review
+		if event.Amount != payment.Amount {
+			return fmt.Errorf("paid amount %s does not match charge amount %s", event.Amount, payment.Amount)
+		}
+		handler, ok := s.handlers[payment.Purpose]
+		if !ok {
+			return fmt.Errorf("no settlement handler for %s payments", payment.Purpose)
+		}
+		// Claiming the payment and settling it commit together: a concurrent
+		// delivery waits on the claim and then finds the payment already paid
+		claimed, err := repos.PaymentGateway.ClaimPaid(payment.ID)
+		if err != nil || !claimed {
+			return err
+		}
+		if err := handler(repos, payment); err != nil {
+			return fmt.Errorf("settlement failed: %w", err)
+		}
+		return nil
+
+	case models.GatewayPaymentExpired, models.GatewayPaymentFailed:
+		_, err := repos.PaymentGateway.UpdatePaymentStatus(payment.ID, event.Status)
+		return err
+	}
+	return fmt.Errorf("unsupported webhook status %q", event.Status)
+}
@@ -6,15 +6,18 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/money"
 	"github.com/vessel/backend/internal/repository"
 )
 
-// PaymentService handles dummy payment gateway operations (prototype)
+// PaymentService handles balance deposits, withdrawals and balance queries
 type PaymentService struct {
-	userRepo    repository.UserRepositoryInterface
-	txRepo      repository.TransactionRepositoryInterface
-	fundingRepo repository.FundingRepositoryInterface
-	invoiceRepo repository.InvoiceRepositoryInterface
+	userRepo       repository.UserRepositoryInterface
+	txRepo         repository.TransactionRepositoryInterface
+	fundingRepo    repository.FundingRepositoryInterface
+	invoiceRepo    repository.InvoiceRepositoryInterface
+	ledgerService  *LedgerService
+	gatewayService *PaymentGatewayService
 }
 
 func NewPaymentService(
@@ -22,57 +25,64 @@ func NewPaymentService(
 	txRepo repository.TransactionRepositoryInterface,
 	fundingRepo repository.FundingRepositoryInterface,
 	invoiceRepo repository.InvoiceRepositoryInterface,
+	ledgerService *LedgerService,
+	gatewayService *PaymentGatewayService,
 ) *PaymentService {
 	return &PaymentService{
-		userRepo:    userRepo,
-		txRepo:      txRepo,
-		fundingRepo: fundingRepo,
-		invoiceRepo: invoiceRepo,
+		userRepo:       userRepo,
+		txRepo:         txRepo,
+		fundingRepo:    fundingRepo,
+		invoiceRepo:    invoiceRepo,
+		ledgerService:  ledgerService,
+		gatewayService: gatewayService,
 	}
 }
 
 // DepositRequest represents a deposit request
 type DepositRequest struct {
-	Amount float64 `json:"amount" binding:"required,gt=0"`
+	Amount money.Amount `json:"amount" binding:"required,gt=0"`
 }
 
 // WithdrawRequest represents a withdrawal request
 type WithdrawRequest struct {
-	Amount float64 `json:"amount" binding:"required,gt=0"`
+	Amount money.Amount `json:"amount" binding:"required,gt=0"`
 }
 
 // PaymentResponse represents a payment operation response
 type PaymentResponse struct {
-	Success       bool      `json:"success"`
-	TransactionID uuid.UUID `json:"transaction_id,omitempty"`
-	Message       string    `json:"message"`
-	NewBalance    float64   `json:"new_balance"`
-	Timestamp     time.Time `json:"timestamp"`
+	Success       bool                     `json:"success"`
+	TransactionID uuid.UUID                `json:"transaction_id,omitempty"`
+	Status        models.TransactionStatus `json:"status,omitempty"`
+	PaymentURL    *string                  `json:"payment_url,omitempty"` // Deposits awaiting payment at the gateway
+	Message       string                   `json:"message"`
+	NewBalance    money.Amount             `json:"new_balance"`
+	Timestamp     time.Time                `json:"timestamp"`
 }
 
 // BalanceResponse represents user balance info with role-specific data (Flow 3)
 type BalanceResponse struct {
-	UserID       uuid.UUID `json:"user_id"`
-	Role         string    `json:"role"`
-	MemberStatus string    `json:"member_status"`
-	BalanceIDR   float64   `json:"balance_idr"`
-	Currency     string    `json:"currency"`
+	UserID       uuid.UUID    `json:"user_id"`
+	Role         string       `json:"role"`
+	MemberStatus string       `json:"member_status"`
+	BalanceIDR   money.Amount `json:"balance_idr"`
+	Currency     string       `json:"currency"`
 
 	// For Investor: funds currently in active investments
-	ActiveFunding  float64 `json:"active_funding,omitempty"`
-	ExpectedReturn float64 `json:"expected_return,omitempty"`
+	ActiveFunding  money.Amount `json:"active_funding,omitempty"`
+	ExpectedReturn money.Amount `json:"expected_return,omitempty"`
 
 	// For Mitra: amount owed to investors
-	TotalOwed     float64 `json:"total_owed,omitempty"`
-	TotalInterest float64 `json:"total_interest,omitempty"`
+	TotalOwed     money.Amount `json:"total_owed,omitempty"`
+	TotalInterest money.Amount `json:"total_interest,omitempty"`
 
 	// Description based on role
 	Description string `json:"description"`
 }
 
-// SimulateDeposit simulates depositing funds to user balance (PROTOTYPE)
-// In production, this would integrate with Midtrans or other payment gateway
-func (s *PaymentService) SimulateDeposit(userID uuid.UUID, amount float64) (*PaymentResponse, error) {
+// Deposit tops up the user balance through the payment gateway. The deposit stays
+// pending until the gateway confirms payment; with the simulator's auto-settle the
+// confirmation arrives before this returns.
+func (s *PaymentService) Deposit(userID uuid.UUID, amount money.Amount) (*PaymentResponse, error) {
 	if amount <= 0 {
 		return nil, errors.New("amount must be greater than 0")
 	}
@@ -86,34 +96,84 @@ func (s *PaymentService) SimulateDeposit(userID uuid.UUID, amount float64) (*Pay
 		return nil, errors.New("user not found")
 	}
 
-	// Update user balance
-	newBalance := user.BalanceIDR + amount
-	if err := s.userRepo.UpdateBalance(userID, newBalance); err != nil {
-		return nil, err
-	}
-
 	// Create transaction record
 	tx := &models.Transaction{
 		UserID:   &userID,
 		Type:     models.TxTypeDeposit,
 		Amount:   amount,
 		Currency: "IDR",
-		Status:   models.TxStatusConfirmed,
-		Notes:    stringPtr("Simulated deposit via prototype payment gateway"),
+		Status:   models.TxStatusPending,
+		Notes:    stringPtr("Deposit via payment gateway"),
+	}
+	if err := s.txRepo.Create(tx); err != nil {
+		return nil, err
 	}
-	s.txRepo.Create(tx)
 
-	return &PaymentResponse{
+	charge, err := s.gatewayService.CreateCharge(&ChargeRequest{
+		Purpose:     models.GatewayPurposeDeposit,
+		ReferenceID: tx.ID,
+		UserID:      &userID,
+		Method:      models.GatewayMethodPaymentLink,
+		Amount:      amount,
+		Currency:    "IDR",
+		Description: "VESSEL balance deposit",
+		ExpiresAt:   time.Now().Add(24 * time.Hour),
+	})
+	if err != nil {
+		s.txRepo.UpdateStatus(tx.ID, models.TxStatusFailed)
+		return nil, err
+	}
+	if err := s.gatewayService.AutoSettle(charge); err != nil {
+		return nil, err
+	}
+
+	tx, err = s.txRepo.FindByID(tx.ID)
+	if err != nil {
+		return nil, err
+	}
+	newBalance, err := s.ledgerService.GetUserBalance(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &PaymentResponse{
 		Success:       true,
 		TransactionID: tx.ID,
-		Message:       "Deposit successful (prototype)",
+		Status:        tx.Status,
+		Message:       "Deposit successful",
 		NewBalance:    newBalance,
 		Timestamp:     time.Now(),
-	}, nil
+	}
+	if tx.Status == models.TxStatusPending {
+		response.PaymentURL = charge.PaymentURL
+		response.Message = "Deposit awaiting payment"
+	}
+	return response, nil
+}
+
+// SettleDeposit credits a deposit once the gateway confirms payment, inside the
+// transaction that claims the gateway payment
+func (s *PaymentService) SettleDeposit(repos *repository.Repositories, payment *models.GatewayPayment) error {
+	tx, err := repos.Transactions.FindByID(payment.ReferenceID)
+	if err != nil {
+		return err
+	}
+	if tx == nil || tx.Type != models.TxTypeDeposit || tx.UserID == nil {
+		return errors.New("deposit transaction not found")
+	}
+	if tx.Status == models.TxStatusConfirmed {
+		return nil
+	}
+
+	// Post to ledger: escrow receives cash, user wallet is credited
+	if err := s.ledgerService.WithRepository(repos.Ledger).RecordDeposit(*tx.UserID, payment.Amount, &tx.ID); err != nil {
+		return err
+	}
+	return repos.Transactions.UpdateStatus(tx.ID, models.TxStatusConfirmed)
 }
 
 // SimulateWithdraw simulates withdrawing funds from user balance (PROTOTYPE)
-func (s *PaymentService) SimulateWithdraw(userID uuid.UUID, amount float64) (*PaymentResponse, error) {
+func (s *PaymentService) SimulateWithdraw(userID uuid.UUID, amount money.Amount) (*PaymentResponse, error) {
 	if amount <= 0 {
 		return nil, errors.New("amount must be greater than 0")
 	}
@@ -127,27 +187,30 @@ func (s *PaymentService) SimulateWithdraw(userID uuid.UUID, amount float64) (*Pa
 		return nil, errors.New("user not found")
 	}
 
-	// Check sufficient balance
-	if user.BalanceIDR < amount {
-		return nil, errors.New("insufficient balance")
-	}
-
-	// Update user balance
-	newBalance := user.BalanceIDR - amount
-	if err := s.userRepo.UpdateBalance(userID, newBalance); err != nil {
-		return nil, err
-	}
-
 	// Create transaction record
 	tx := &models.Transaction{
 		UserID:   &userID,
 		Type:     models.TxTypeWithdrawal,
 		Amount:   amount,
 		Currency: "IDR",
-		Status:   models.TxStatusConfirmed,
+		Status:   models.TxStatusPending,
 		Notes:    stringPtr("Simulated withdrawal via prototype payment gateway"),
 	}
-	s.txRepo.Create(tx)
+	if err := s.txRepo.Create(tx); err != nil {
+		return nil, err
+	}
+
+	// Post to ledger; the balance check happens atomically inside the posting
+	if err := s.ledgerService.RecordWithdrawal(userID, amount, &tx.ID); err != nil {
+		s.txRepo.UpdateStatus(tx.ID, models.TxStatusFailed)
+		return nil, err
+	}
+	s.txRepo.UpdateStatus(tx.ID, models.TxStatusConfirmed)
+
+	newBalance, err := s.ledgerService.GetUserBalance(userID)
+	if err != nil {
+		return nil, err
+	}
 
 	return &PaymentResponse{
 		Success:       true,
@@ -193,18 +256,21 @@ func (s *PaymentService) GetBalance(userID uuid.UUID) (*BalanceResponse, error)
 		}
 		invoices, _, _ := s.invoiceRepo.FindByExporter(userID, filter)
 
-		var totalOwed, totalInterest float64
+		var totalOwed, totalInterest money.Amount
 		for _, invoice := range invoices {
 			// Check for active invoices (funded or funding status)
 			if invoice.Status == models.StatusFunding || invoice.Status == models.StatusFunded || invoice.Status == models.StatusMatured {
 				pool, _ := s.fundingRepo.FindPoolByInvoiceID(invoice.ID)
 				if pool != nil {
-					// Calculate owed amount (principal + interest)
-					priorityOwed := pool.PriorityFunded * (1 + pool.PriorityInterestRate/100)
-					catalystOwed := pool.CatalystFunded * (1 + pool.CatalystInterestRate/100)
-					totalOwed += priorityOwed + catalystOwed
-					totalInterest += (pool.PriorityFunded * pool.PriorityInterestRate / 100) +
-						(pool.CatalystFunded * pool.CatalystInterestRate / 100)
+					// Calculate owed amount (principal + interest accrued to the due date)
+					investments, _ := s.fundingRepo.FindInvestmentsByPool(pool.ID)
+					for _, inv := range investments {
+						if inv.Status != models.InvestmentStatusActive {
+							continue
+						}
+						totalOwed += inv.ExpectedReturn
+						totalInterest += inv.ExpectedReturn - inv.Amount
+					}
 				}
 			}
 		}
@@ -216,78 +282,14 @@ func (s *PaymentService) GetBalance(userID uuid.UUID) (*BalanceResponse, error)
 	return response, nil
 }
 
-// SimulateInvestmentPayment simulates payment for investment (PROTOTYPE)
-// This deducts from user balance for investment
-func (s *PaymentService) SimulateInvestmentPayment(userID uuid.UUID, amount float64, poolID uuid.UUID) (*PaymentResponse, error) {
-	if amount <= 0 {
-		return nil, errors.New("amount must be greater than 0")
-	}
-
-	// Get current user
-	user, err := s.userRepo.FindByID(userID)
-	if err != nil {
-		return nil, err
-	}
-	if user == nil {
-		return nil, errors.New("user not found")
-	}
-
-	// Check sufficient balance
-	if user.BalanceIDR < amount {
-		return nil, errors.New("insufficient balance for investment")
-	}
-
-	// Update user balance
-	newBalance := user.BalanceIDR - amount
-	if err := s.userRepo.UpdateBalance(userID, newBalance); err != nil {
-		return nil, err
-	}
-
-	return &PaymentResponse{
-		Success:    true,
-		Message:    "Investment payment processed (prototype)",
-		NewBalance: newBalance,
-		Timestamp:  time.Now(),
-	}, nil
-}
-
-// SimulateReturnPayment simulates crediting investment returns to user (PROTOTYPE)
-func (s *PaymentService) SimulateReturnPayment(userID uuid.UUID, amount float64) (*PaymentResponse, error) {
-	if amount <= 0 {
-		return nil, errors.New("amount must be greater than 0")
-	}
-
-	// Get current user
-	user, err := s.userRepo.FindByID(userID)
-	if err != nil {
-		return nil, err
-	}
-	if user == nil {
-		return nil, errors.New("user not found")
-	}
-
-	// Update user balance
-	newBalance := user.BalanceIDR + amount
-	if err := s.userRepo.UpdateBalance(userID, newBalance); err != nil {
-		return nil, err
-	}
-
-	return &PaymentResponse{
-		Success:    true,
-		Message:    "Investment return credited (prototype)",
-		NewBalance: newBalance,
-		Timestamp:  time.Now(),
-	}, nil
-}
-
 // AdminGrantBalanceRequest represents admin grant balance request
 type AdminGrantBalanceRequest struct {
-	UserID string  `json:"user_id" binding:"required"`
-	Amount float64 `json:"amount" binding:"required"`
+	UserID string       `json:"user_id" binding:"required"`
+	Amount money.Amount `json:"amount" binding:"required"`
 }
 
 // AdminGrantBalance allows admin to grant balance to any user (MVP ONLY)
-func (s *PaymentService) AdminGrantBalance(targetUserID uuid.UUID, amount float64) (*PaymentResponse, error) {
+func (s *PaymentService) AdminGrantBalance(targetUserID uuid.UUID, amount money.Amount) (*PaymentResponse, error) {
 	// Get target user
 	user, err := s.userRepo.FindByID(targetUserID)
 	if err != nil {
@@ -297,26 +299,33 @@ func (s *PaymentService) AdminGrantBalance(targetUserID uuid.UUID, amount float6
 		return nil, errors.New("user not found")
 	}
 
-	// Update user balance (can be positive or negative)
-	newBalance := user.BalanceIDR + amount
-	if newBalance < 0 {
-		return nil, errors.New("resulting balance cannot be negative")
-	}
-
-	if err := s.userRepo.UpdateBalance(targetUserID, newBalance); err != nil {
-		return nil, err
-	}
-
 	// Create transaction record
 	tx := &models.Transaction{
 		UserID:   &targetUserID,
 		Type:     models.TxTypeDeposit,
 		Amount:   amount,
 		Currency: "IDR",
-		Status:   models.TxStatusConfirmed,
+		Status:   models.TxStatusPending,
 		Notes:    stringPtr("Admin granted balance (MVP)"),
 	}
-	s.txRepo.Create(tx)
+	if err := s.txRepo.Create(tx); err != nil {
+		return nil, err
+	}
+
+	// Post to ledger against platform equity (can be positive or negative)
+	if err := s.ledgerService.RecordAdjustment(targetUserID, amount, &tx.ID); err != nil {
+		s.txRepo.UpdateStatus(tx.ID, models.TxStatusFailed)
+		if errors.Is(err, repository.ErrInsufficientBalance) {
+			return nil, errors.New("resulting balance cannot be negative")
+		}
+		return nil, err
+	}
+	s.txRepo.UpdateStatus(tx.ID, models.TxStatusConfirmed)
+
+	newBalance, err := s.ledgerService.GetUserBalance(targetUserID)
+	if err != nil {
+		return nil, err
+	}
 
 	return &PaymentResponse{
 		Success:       true,
@@ -0,0 +1,486 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/config"
+	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/money"
+	"github.com/vessel/backend/internal/repository"
+)
+
+// InvoiceNFT.InvoiceStatus values
+const (
+	chainInvoiceActive uint8 = iota
+	chainInvoiceFunded
+	chainInvoiceMatured
+	chainInvoiceRepaid
+	chainInvoiceDefaulted
+)
+
+// InvoicePool.PoolStatus values
+const (
+	chainPoolOpen uint8 = iota
+	chainPoolFilled
+	chainPoolDisbursed
+	chainPoolClosed
+	chainPoolDefaulted
+)
+
+var (
+	chainInvoiceStatusNames = []string{"active", "funded", "matured", "repaid", "defaulted"}
+	chainPoolStatusNames    = []string{"open", "filled", "disbursed", "closed", "defaulted"}
+)
+
+var ErrBlockchainUnavailable = errors.New("blockchain client not configured")
+
+// plannedCorrection is an outbox write that brings the chain in line with Postgres
+type plannedCorrection struct {
+	action  models.OnchainAction
+	poolID  uuid.UUID
+	payload *models.OnchainPayload
+}
+
+// ReconciliationService compares every tokenized invoice with the InvoiceNFT and
+// InvoicePool contracts. Postgres is the source of truth: writes missing on-chain
+// can be queued on the outbox, while anything the chain has that Postgres does
+// not is only reported.
+type ReconciliationService struct {
+	invoiceRepo repository.InvoiceRepositoryInterface
+	fundingRepo repository.FundingRepositoryInterface
+	userRepo    repository.UserRepositoryInterface
+	outboxRepo  repository.OnchainOutboxRepositoryInterface
+	uow         repository.UnitOfWorkInterface
+	blockchain  *BlockchainService
+	units       *money.TokenUnits
+	cfg         *config.Config
+}
+
+func NewReconciliationService(
+	invoiceRepo repository.InvoiceRepositoryInterface,
+	fundingRepo repository.FundingRepositoryInterface,
+	userRepo repository.UserRepositoryInterface,
+	outboxRepo repository.OnchainOutboxRepositoryInterface,
+	uow repository.UnitOfWorkInterface,
+	blockchain *BlockchainService,
+	cfg *config.Config,
+) *ReconciliationService {
+	return &ReconciliationService{
+		invoiceRepo: invoiceRepo,
+		fundingRepo: fundingRepo,
+		userRepo:    userRepo,
+		outboxRepo:  outboxRepo,
+		uow:         uow,
+		blockchain:  blockchain,
+		units:       newTokenUnits(cfg),
+		cfg:         cfg,
+	}
+}
+
+// Run walks every minted invoice and returns the differences. With enqueue set,
+// corrective writes are queued for invoices that have no outbox entries still
+// open, so a run never duplicates a write that is already on its way.
+func (s *ReconciliationService) Run(ctx context.Context, enqueue bool) (*models.ReconciliationReport, error) {
+	if s.blockchain == nil || !s.blockchain.Enabled() {
+		return nil, ErrBlockchainUnavailable
+	}
+
+	report := &models.ReconciliationReport{
+		StartedAt: time.Now(),
+		ChainID:   s.cfg.ChainID,
+		Diffs:     []models.ReconciliationDiff{},
+	}
+
+	nfts, err := s.invoiceRepo.FindMintedNFTs()
+	if err != nil {
+		return nil, err
+	}
+
+	wallets := make(map[uuid.UUID]string)
+	for i := range nfts {
+		nft := &nfts[i]
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// Only a token id read from the mint can be compared; anything else
+		// would be checked, and corrected, against an unrelated token
+		if _, err := s.blockchain.TokenID(ctx, nft); err != nil {
+			if errors.Is(err, ErrTokenNotVerified) || errors.Is(err, ErrOnchainRecordMissing) {
+				report.InvoicesUnverified++
+				report.Diffs = append(report.Diffs, unverifiedTokenDiff(nft, err))
+				continue
+			}
+			report.Errors = append(report.Errors, fmt.Sprintf("invoice %s: failed to verify token id: %v", nft.InvoiceID, err))
+			continue
+		}
+
+		diffs, plan, err := s.reconcileInvoice(ctx, nft, wallets)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("token %d (invoice %s): %v", *nft.TokenID, nft.InvoiceID, err))
+			continue
+		}
+		report.InvoicesChecked++
+		if len(diffs) == 0 {
+			continue
+		}
+		report.InvoicesWithDiffs++
+
+		if enqueue && len(plan) > 0 {
+			enqueued, err := s.enqueueCorrections(nft.InvoiceID, plan, diffs)
+			if err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("token %d (invoice %s): failed to enqueue corrections: %v", *nft.TokenID, nft.InvoiceID, err))
+			}
+			report.Enqueued += enqueued
+		}
+		report.Diffs = append(report.Diffs, diffs...)
+	}
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// unverifiedTokenDiff reports an NFT whose token id could not be read from its
+// mint transaction. No correction is planned for it.
+func unverifiedTokenDiff(nft *models.InvoiceNFT, reason error) models.ReconciliationDiff {
+	database := "no token id"
+	if nft.TokenID != nil {
+		database = fmt.Sprintf("unverified token %d", *nft.TokenID)
+	}
+	return models.ReconciliationDiff{
+		InvoiceID: nft.InvoiceID,
+		Field:     models.ReconcileTokenUnverified,
+		Database:  database,
+		Chain:     "unknown",
+		Note:      reason.Error(),
+	}
+}
+
+// reconcileInvoice compares one verified token and plans the outbox writes that
+// would fix the chain, in the order the contract accepts them
+func (s *ReconciliationService) reconcileInvoice(ctx context.Context, nft *models.InvoiceNFT, wallets map[uuid.UUID]string) ([]models.ReconciliationDiff, []plannedCorrection, error) {
+	state, err := s.blockchain.ReadInvoiceState(ctx, *nft.TokenID)
+	if err != nil {
+		return nil, nil, err
+	}
+	invoice, err := s.invoiceRepo.FindByID(nft.InvoiceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if invoice == nil {
+		return nil, nil, errors.New("invoice not found")
+	}
+	pool, err := s.fundingRepo.FindPoolByInvoiceID(nft.InvoiceID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var diffs []models.ReconciliationDiff
+	var plan []plannedCorrection
+	add := func(diff models.ReconciliationDiff) {
+		diff.InvoiceID = nft.InvoiceID
+		diff.TokenID = *nft.TokenID
+		if pool != nil {
+			diff.PoolID = &pool.ID
+		}
+		diffs = append(diffs, diff)
+	}
+	correct := func(action models.OnchainAction, payload *models.OnchainPayload) *models.OnchainAction {
+		plan = append(plan, plannedCorrection{action: action, poolID: pool.ID, payload: payload})
+		return &action
+	}
+
+	if !state.Exists {
+		add(models.ReconciliationDiff{Field: models.ReconcileNFTMissing, Database: "minted", Chain: "missing"})
+		return diffs, nil, nil
+	}
+
+	if nft.OwnerAddress != nil && !strings.EqualFold(*nft.OwnerAddress, state.Owner.Hex()) {
+		add(models.ReconciliationDiff{Field: models.ReconcileNFTOwner, Database: *nft.OwnerAddress, Chain: state.Owner.Hex()})
+	}
+
+	poolOnChain := state.Pool.TargetAmount != nil && state.Pool.TargetAmount.Sign() > 0
+	if pool == nil {
+		if poolOnChain {
+			add(models.ReconciliationDiff{Field: models.ReconcilePoolStatus, Database: "none", Chain: chainStatusName(chainPoolStatusNames, state.Pool.Status)})
+		}
+		s.compareInvoiceStatus(invoice, pool, state, add)
+		return diffs, plan, nil
+	}
+
+	chainStatus := "none"
+	if poolOnChain {
+		chainStatus = chainStatusName(chainPoolStatusNames, state.Pool.Status)
+	} else {
+		add(models.ReconciliationDiff{
+			Field:      models.ReconcilePoolMissing,
+			Database:   string(pool.Status),
+			Chain:      "none",
+			Correction: correct(models.OnchainActionCreatePool, &models.OnchainPayload{}),
+		})
+	}
+
+	// Investments are matched by wallet and token amount, oldest first, the
+	// order in which they were recorded
+	investments, err := s.fundingRepo.FindInvestmentsByPool(pool.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.SliceStable(investments, func(i, j int) bool {
+		return investments[i].InvestedAt.Before(investments[j].InvestedAt)
+	})
+
+	units := s.units
+	currency := pool.PoolCurrency
+	matched := make([]bool, len(state.Investments))
+	var totalReturns money.Amount
+	for i := range investments {
+		inv := &investments[i]
+		amount, err := units.ToChain(inv.Amount, currency)
+		if err != nil {
+			return nil, nil, err
+		}
+		if inv.ActualReturn != nil {
+			totalReturns += *inv.ActualReturn
+		}
+
+		wallet, err := s.walletOf(inv.InvestorID, wallets)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		found := -1
+		for j, onchain := range state.Investments {
+			if !matched[j] && wallet != "" && strings.EqualFold(onchain.Investor.Hex(), wallet) && onchain.Amount.Cmp(amount) == 0 {
+				found = j
+				break
+			}
+		}
+
+		if found < 0 {
+			diff := models.ReconciliationDiff{
+				InvestmentID: &inv.ID,
+				Field:        models.ReconcileInvestmentMissing,
+				Database:     fmt.Sprintf("%s %s", wallet, amount),
+				Chain:        "none",
+			}
+			switch {
+			case wallet == "":
+				diff.Note = "investor has no wallet address"
+			case inv.Status == models.InvestmentStatusRefunded:
+				diff.Note = "investment was refunded"
+			default:
+				diff.Correction = correct(models.OnchainActionRecordInvestment, &models.OnchainPayload{Wallet: wallet, Amount: inv.Amount})
+			}
+			add(diff)
+			continue
+		}
+
+		matched[found] = true
+		onchain := state.Investments[found]
+		if inv.ActualReturn != nil && onchain.Claimed {
+			actualReturn, err := units.ToChain(*inv.ActualReturn, currency)
+			if err != nil {
+				return nil, nil, err
+			}
+			if onchain.ActualReturn.Cmp(actualReturn) != 0 {
+				add(models.ReconciliationDiff{
+					InvestmentID: &inv.ID,
+					Field:        models.ReconcileInvestorReturn,
+					Database:     actualReturn.String(),
+					Chain:        onchain.ActualReturn.String(),
+				})
+			}
+		}
+	}
+
+	for j, onchain := range state.Investments {
+		if !matched[j] {
+			add(models.ReconciliationDiff{
+				Field:    models.ReconcileInvestmentUnknown,
+				Database: "none",
+				Chain:    fmt.Sprintf("%s %s", onchain.Investor.Hex(), onchain.Amount.String()),
+			})
+		}
+	}
+
+	if poolOnChain {
+		funded, err := units.ToChain(pool.FundedAmount, currency)
+		if err != nil {
+			return nil, nil, err
+		}
+		if state.Pool.FundedAmount.Cmp(funded) != 0 {
+			add(models.ReconciliationDiff{
+				Field:    models.ReconcilePoolFundedAmount,
+				Database: funded.String(),
+				Chain:    state.Pool.FundedAmount.String(),
+				Note:     fmt.Sprintf("funding_pools.funded_amount is %s %s", pool.FundedAmount, currency),
+			})
+		}
+		if state.Pool.InvestorCount.Cmp(big.NewInt(int64(len(investments)))) != 0 {
+			add(models.ReconciliationDiff{
+				Field:    models.ReconcilePoolInvestorCount,
+				Database: fmt.Sprintf("%d", len(investments)),
+				Chain:    state.Pool.InvestorCount.String(),
+				Note:     fmt.Sprintf("funding_pools.investor_count is %d", pool.InvestorCount),
+			})
+		}
+	}
+
+	// Status: the chain can only move forward, so a pool that is behind is caught
+	// up with the missing disbursement and repayment records
+	expected, comparable := expectedPoolStatus(pool.Status)
+	if comparable && (!poolOnChain || state.Pool.Status != expected) {
+		diff := models.ReconciliationDiff{Field: models.ReconcilePoolStatus, Database: string(pool.Status), Chain: chainStatus}
+		behind := !poolOnChain || state.Pool.Status < expected
+		if behind && expected >= chainPoolDisbursed && (!poolOnChain || state.Pool.Status < chainPoolDisbursed) {
+			diff.Correction = correct(models.OnchainActionRecordDisbursement, &models.OnchainPayload{Amount: pool.FundedAmount})
+		}
+		if behind && expected == chainPoolClosed {
+			// The contract takes the per-investor returns from Postgres; the total
+			// recorded is what investors received
+			action := correct(models.OnchainActionRecordRepayment, &models.OnchainPayload{Amount: totalReturns})
+			if diff.Correction == nil {
+				diff.Correction = action
+			} else {
+				diff.Note = "followed by record_repayment"
+			}
+		}
+		if behind && expected == chainPoolDefaulted {
+			action := correct(models.OnchainActionMarkDefaulted, &models.OnchainPayload{})
+			if diff.Correction == nil {
+				diff.Correction = action
+			} else {
+				diff.Note = "followed by mark_defaulted"
+			}
+		}
+		add(diff)
+	}
+
+	s.compareInvoiceStatus(invoice, pool, state, add)
+	return diffs, plan, nil
+}
+
+// compareInvoiceStatus checks the InvoiceNFT status. The pool contract moves it,
+// so it has no correction of its own.
+func (s *ReconciliationService) compareInvoiceStatus(invoice *models.Invoice, pool *models.FundingPool, state *OnchainInvoiceState, add func(models.ReconciliationDiff)) {
+	var expected uint8
+	switch {
+	case invoice.Status == models.StatusDefaulted:
+		expected = chainInvoiceDefaulted
+	case pool == nil:
+		expected = chainInvoiceActive
+	case pool.Status == models.PoolStatusExpired:
+		return
+	case pool.Status == models.PoolStatusClosed:
+		expected = chainInvoiceRepaid
+	default:
+		expected = chainInvoiceFunded
+	}
+
+	actual := state.InvoiceStatus
+	if expected == chainInvoiceFunded && actual == chainInvoiceMatured {
+		return
+	}
+	if actual != expected {
+		add(models.ReconciliationDiff{
+			Field:    models.ReconcileNFTStatus,
+			Database: chainStatusName(chainInvoiceStatusNames, expected),
+			Chain:    chainStatusName(chainInvoiceStatusNames, actual),
+			Note:     fmt.Sprintf("invoice status is %s", invoice.Status),
+		})
+	}
+}
+
+// enqueueCorrections queues the planned writes for one invoice in one transaction
+func (s *ReconciliationService) enqueueCorrections(invoiceID uuid.UUID, plan []plannedCorrection, diffs []models.ReconciliationDiff) (int, error) {
+	open, err := s.outboxRepo.CountOpenByInvoice(invoiceID)
+	if err != nil {
+		return 0, err
+	}
+	if open > 0 {
+		for i := range diffs {
+			if diffs[i].Correction != nil {
+				diffs[i].Note = joinNote(diffs[i].Note, fmt.Sprintf("not enqueued: %d outbox entries still open", open))
+			}
+		}
+		return 0, nil
+	}
+
+	err = s.uow.Do(func(repos *repository.Repositories) error {
+		for _, c := range plan {
+			poolID := c.poolID
+			if err := enqueueOnchain(repos, c.action, invoiceID, &poolID, nil, c.payload); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range diffs {
+		if diffs[i].Correction != nil {
+			diffs[i].Enqueued = true
+		}
+	}
+	return len(plan), nil
+}
+
+// walletOf returns an investor's wallet address, or "" if none is linked
+func (s *ReconciliationService) walletOf(userID uuid.UUID, cache map[uuid.UUID]string) (string, error) {
+	if wallet, ok := cache[userID]; ok {
+		return wallet, nil
+	}
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return "", err
+	}
+	wallet := ""
+	if user != nil && user.WalletAddress != nil {
+		wallet = *user.WalletAddress
+	}
+	cache[userID] = wallet
+	return wallet, nil
+}
+
+// expectedPoolStatus maps a Postgres pool status to the InvoicePool status it
+// should have. Expired pools have no on-chain equivalent.
+func expectedPoolStatus(status models.PoolStatus) (uint8, bool) {
+	switch status {
+	case models.PoolStatusOpen:
+		return chainPoolOpen, true
+	case models.PoolStatusFilled:
+		return chainPoolFilled, true
+	case models.PoolStatusDisbursed:
+		return chainPoolDisbursed, true
+	case models.PoolStatusClosed:
+		return chainPoolClosed, true
+	case models.PoolStatusDefaulted:
+		return chainPoolDefaulted, true
+	default:
+		return 0, false
+	}
+}
+
+func chainStatusName(names []string, status uint8) string {
+	if int(status) < len(names) {
+		return names[status]
+	}
+	return fmt.Sprintf("unknown(%d)", status)
+}
+
+func joinNote(note, extra string) string {
+	if note == "" {
+		return extra
+	}
+	return note + "; " + extra
+}
@@ -0,0 +1,378 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/money"
+	"github.com/vessel/backend/internal/repository"
+)
+
+var (
+	ErrListingNotFound         = errors.New("listing not found")
+	ErrListingNotOpen          = errors.New("listing is no longer open")
+	ErrListingOwnPosition      = errors.New("you cannot buy your own listing")
+	ErrPositionNotFound        = errors.New("investment not found")
+	ErrPositionNotTransferable = errors.New("only active investments in filled or disbursed pools can be sold")
+	ErrPositionAlreadyListed   = errors.New("investment already has an open listing")
+	ErrListingPrincipal        = errors.New("principal must be positive and at most the investment amount")
+	ErrSecondaryTncRequired    = errors.New("you must accept Terms & Conditions to buy")
+)
+
+// SecondaryMarketService lets investors exit a position before maturity: a
+// seller lists all or part of an active investment at a price, and another
+// eligible investor buys it from their wallet balance. The position keeps its
+// pool, tranche and seniority; only its holder changes.
+type SecondaryMarketService struct {
+	listingRepo   repository.SecondaryMarketRepositoryInterface
+	fundingRepo   repository.FundingRepositoryInterface
+	rqService     *RiskQuestionnaireService
+	ledgerService *LedgerService
+	uow           repository.UnitOfWorkInterface
+}
+
+func NewSecondaryMarketService(
+	listingRepo repository.SecondaryMarketRepositoryInterface,
+	fundingRepo repository.FundingRepositoryInterface,
+	rqService *RiskQuestionnaireService,
+	ledgerService *LedgerService,
+	uow repository.UnitOfWorkInterface,
+) *SecondaryMarketService {
+	return &SecondaryMarketService{
+		listingRepo:   listingRepo,
+		fundingRepo:   fundingRepo,
+		rqService:     rqService,
+		ledgerService: ledgerService,
+		uow:           uow,
+	}
+}
+
+// transferable reports whether a position can change hands: the pool is funded
+// but not yet settled, and the investment has not been repaid or written off
+func transferable(pool *models.FundingPool, inv *models.Investment) bool {
+	return (pool.Status == models.PoolStatusFilled || pool.Status == models.PoolStatusDisbursed) &&
+		inv.Status == models.InvestmentStatusActive
+}
+
+// CreateListing offers principal of the seller's investment at price
+func (s *SecondaryMarketService) CreateListing(sellerID uuid.UUID, req *models.CreateListingRequest) (*models.SecondaryListing, error) {
+	inv, err := s.fundingRepo.FindInvestmentByID(req.InvestmentID)
+	if err != nil {
+		return nil, err
+	}
+	if inv == nil || inv.InvestorID != sellerID {
+		return nil, ErrPositionNotFound
+	}
+	pool, err := s.fundingRepo.FindPoolByID(inv.PoolID)
+	if err != nil {
+		return nil, err
+	}
+	if pool == nil {
+		return nil, ErrPoolNotFound
+	}
+	if !transferable(pool, inv) {
+		return nil, ErrPositionNotTransferable
+	}
+
+	principal := req.Principal
+	if principal == 0 {
+		principal = inv.Amount
+	}
+	if principal <= 0 || principal > inv.Amount {
+		return nil, ErrListingPrincipal
+	}
+
+	existing, err := s.listingRepo.FindOpenByInvestment(inv.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrPositionAlreadyListed
+	}
+
+	listing := &models.SecondaryListing{
+		InvestmentID: inv.ID,
+		SellerID:     sellerID,
+		PoolID:       pool.ID,
+		Tranche:      inv.Tranche,
+		Principal:    principal,
+		Price:        req.Price,
+		Currency:     pool.PoolCurrency,
+		Status:       models.ListingStatusOpen,
+	}
+	if err := s.listingRepo.Create(listing); err != nil {
+		return nil, err
+	}
+	return s.listingRepo.FindByID(listing.ID)
+}
+
+// CancelListing withdraws the seller's open listing
+func (s *SecondaryMarketService) CancelListing(sellerID, listingID uuid.UUID) error {
+	listing, err := s.listingRepo.FindByID(listingID)
+	if err != nil {
+		return err
+	}
+	if listing == nil || listing.SellerID != sellerID {
+		return ErrListingNotFound
+	}
+	if listing.Status != models.ListingStatusOpen {
+		return ErrListingNotOpen
+	}
+	return s.listingRepo.Cancel(listingID)
+}
+
+// ListOpen returns the listings that can be bought, newest first
+func (s *SecondaryMarketService) ListOpen(poolID *uuid.UUID, tranche string, page, perPage int) (*models.SecondaryListingListResponse, error) {
+	listings, total, err := s.listingRepo.FindOpen(poolID, tranche, page, perPage)
+	if err != nil {
+		return nil, err
+	}
+	return listingPage(listings, total, page, perPage), nil
+}
+
+// ListBySeller returns a seller's listings in every status
+func (s *SecondaryMarketService) ListBySeller(sellerID uuid.UUID, page, perPage int) (*models.SecondaryListingListResponse, error) {
+	listings, total, err := s.listingRepo.FindBySeller(sellerID, page, perPage)
+	if err != nil {
+		return nil, err
+	}
+	return listingPage(listings, total, page, perPage), nil
+}
+
+func listingPage(listings []models.SecondaryListing, total, page, perPage int) *models.SecondaryListingListResponse {
+	if listings == nil {
+		listings = []models.SecondaryListing{}
+	}
+	return &models.SecondaryListingListResponse{
+		Listings:   listings,
+		Total:      total,
+		Page:       page,
+		PerPage:    perPage,
+		TotalPages: models.CalculateTotalPages(total, perPage),
+	}
+}
+
+// Buy pays a listing's price from the buyer's wallet to the seller and moves
+// the listed principal to the buyer. A partial sale splits the investment: the
+// buyer gets a new investment in the same tranche with the matching share of
+// the expected return and of anything already received, and the seller keeps
+// the rest. The pool row is locked first, as for investments and repayments,
+// so a sale never races a settlement of the same pool.
+func (s *SecondaryMarketService) Buy(buyerID, listingID uuid.UUID, req *models.BuyListingRequest) (*models.SecondaryPurchaseResponse, error) {
+	if !req.TncAccepted {
+		return nil, ErrSecondaryTncRequired
+	}
+	found, err := s.listingRepo.FindByID(listingID)
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, ErrListingNotFound
+	}
+
+	var result *models.SecondaryPurchaseResponse
+	err = s.uow.Do(func(repos *repository.Repositories) error {
+		pool, err := repos.Funding.FindPoolByIDForUpdate(found.PoolID)
+		if err != nil {
+			return err
+		}
+		if pool == nil {
+			return ErrPoolNotFound
+		}
+		listing, err := repos.SecondaryMarket.FindByIDForUpdate(listingID)
+		if err != nil {
+			return err
+		}
+		if listing == nil {
+			return ErrListingNotFound
+		}
+		if listing.Status != models.ListingStatusOpen {
+			return ErrListingNotOpen
+		}
+		if listing.SellerID == buyerID {
+			return ErrListingOwnPosition
+		}
+
+		inv, err := repos.Funding.FindInvestmentByIDForUpdate(listing.InvestmentID)
+		if err != nil {
+			return err
+		}
+		if inv == nil || inv.InvestorID != listing.SellerID || inv.Amount < listing.Principal {
+			return ErrListingNotOpen
+		}
+		if !transferable(pool, inv) {
+			return ErrPositionNotTransferable
+		}
+
+		buyer, err := repos.Users.FindByIDForUpdate(buyerID)
+		if err != nil {
+			return err
+		}
+		if buyer == nil {
+			return errors.New("investor not found")
+		}
+		if err := s.checkBuyerEligibility(pool.Tranche(listing.Tranche), buyerID, listing.Principal, req); err != nil {
+			return err
+		}
+
+		invoice, err := repos.Invoices.FindByID(pool.InvoiceID)
+		if err != nil {
+			return err
+		}
+		if invoice == nil {
+			return errors.New("invoice not found")
+		}
+
+		// The ledger rejects the posting if the buyer's wallet would go negative
+		if err := s.ledgerService.WithRepository(repos.Ledger).RecordSecondaryTrade(listing.ID, buyerID, listing.SellerID, listing.Price); err != nil {
+			if errors.Is(err, repository.ErrInsufficientBalance) {
+				return err
+			}
+			return fmt.Errorf("failed to pay seller: %w", err)
+		}
+
+		position := inv.Amount
+		bought, err := transferPosition(repos, pool, inv, buyerID, listing.Principal)
+		if err != nil {
+			return err
+		}
+		if err := repos.SecondaryMarket.MarkSold(listing.ID, buyerID, bought.ID); err != nil {
+			return err
+		}
+
+		purchase := &models.Transaction{
+			InvoiceID: &pool.InvoiceID,
+			UserID:    &buyerID,
+			Type:      models.TxTypeSecondaryPurchase,
+			Amount:    listing.Price,
+			Currency:  pool.PoolCurrency,
+			Status:    models.TxStatusConfirmed,
+			Notes:     stringPtr(fmt.Sprintf("Bought %s of invoice %s (%s tranche)", listing.Principal, invoice.InvoiceNumber, listing.Tranche)),
+		}
+		if err := repos.Transactions.Create(purchase); err != nil {
+			return err
+		}
+		sellerID := listing.SellerID
+		sale := &models.Transaction{
+			InvoiceID: &pool.InvoiceID,
+			UserID:    &sellerID,
+			Type:      models.TxTypeSecondarySale,
+			Amount:    listing.Price,
+			Currency:  pool.PoolCurrency,
+			Status:    models.TxStatusConfirmed,
+			Notes:     stringPtr(fmt.Sprintf("Sold %s of invoice %s (%s tranche)", listing.Principal, invoice.InvoiceNumber, listing.Tranche)),
+		}
+		if err := repos.Transactions.Create(sale); err != nil {
+			return err
+		}
+
+		// On-Chain Transparency: the new holder is recorded on-chain once committed.
+		// Positions of investors without a wallet were never recorded there.
+		seller, err := repos.Users.FindByID(listing.SellerID)
+		if err != nil {
+			return err
+		}
+		if seller != nil && seller.WalletAddress != nil && buyer.WalletAddress != nil {
+			payload := &models.OnchainPayload{
+				FromWallet: *seller.WalletAddress,
+				Wallet:     *buyer.WalletAddress,
+				Amount:     listing.Principal,
+				Position:   position,
+			}
+			if err := enqueueOnchain(repos, models.OnchainActionTransferInvestment, pool.InvoiceID, &pool.ID, []uuid.UUID{purchase.ID, sale.ID}, payload); err != nil {
+				return err
+			}
+		}
+
+		result = &models.SecondaryPurchaseResponse{Investment: bought}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Listing, err = s.listingRepo.FindByID(listingID); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// checkBuyerEligibility applies a tranche's investor rules to a buyer. Junior
+// tranches are open only to investors whose risk questionnaire unlocked them,
+// whatever the pool required at launch, and take the risk statements where the
+// tranche does.
+func (s *SecondaryMarketService) checkBuyerEligibility(tranche *models.PoolTranche, buyerID uuid.UUID, principal money.Amount, req *models.BuyListingRequest) error {
+	if tranche == nil {
+		return ErrTrancheNotFound
+	}
+	if tranche.RequiresRiskConsent && (req.CatalystConsents == nil || !req.CatalystConsents.AllAccepted()) {
+		return ErrTrancheRiskConsent
+	}
+	if tranche.RequiresRiskConsent || tranche.RequiresRiskProfile {
+		unlocked, err := s.rqService.IsCatalystUnlocked(buyerID)
+		if err != nil {
+			return err
+		}
+		if !unlocked {
+			return ErrTrancheRiskProfile
+		}
+	}
+	if tranche.MinInvestment > 0 && principal < tranche.MinInvestment {
+		return ErrTrancheBelowMinimum
+	}
+	if tranche.MaxInvestment > 0 && principal > tranche.MaxInvestment {
+		return ErrTrancheAboveMaximum
+	}
+	return nil
+}
+
+// transferPosition moves principal of inv to the buyer and returns the buyer's
+// investment. Selling the whole position changes its holder; selling part of it
+// appends a new investment, as the contract appends a new record, and the pool
+// counts one more investment.
+func transferPosition(repos *repository.Repositories, pool *models.FundingPool, inv *models.Investment, buyerID uuid.UUID, principal money.Amount) (*models.Investment, error) {
+	if principal >= inv.Amount {
+		inv.InvestorID = buyerID
+		if err := repos.Funding.UpdateInvestmentPosition(inv); err != nil {
+			return nil, err
+		}
+		return inv, nil
+	}
+
+	currency := pool.PoolCurrency
+	bought := &models.Investment{
+		PoolID:         inv.PoolID,
+		InvestorID:     buyerID,
+		Amount:         principal,
+		ExpectedReturn: models.PositionShare(inv.ExpectedReturn, principal, inv.Amount, currency),
+		Status:         models.InvestmentStatusActive,
+		Tranche:        inv.Tranche,
+	}
+	var received money.Amount
+	if inv.ActualReturn != nil {
+		received = models.PositionShare(*inv.ActualReturn, principal, inv.Amount, currency)
+		kept := *inv.ActualReturn - received
+		inv.ActualReturn = &kept
+	}
+	inv.Amount -= principal
+	inv.ExpectedReturn -= bought.ExpectedReturn
+	if err := repos.Funding.UpdateInvestmentPosition(inv); err != nil {
+		return nil, err
+	}
+
+	if err := repos.Funding.CreateInvestment(bought); err != nil {
+		return nil, err
+	}
+	if received > 0 {
+		if err := repos.Funding.AddInvestmentReturn(bought.ID, received); err != nil {
+			return nil, err
+		}
+		bought.ActualReturn = &received
+	}
+	if err := repos.Funding.UpdatePoolFunding(pool.ID, 0); err != nil {
+		return nil, err
+	}
+	return bought, nil
+}
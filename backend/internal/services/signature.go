@@ -0,0 +1,49 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// eip712DomainName is the signing domain shown by wallets for Vessel typed data
+const eip712DomainName = "Vessel"
+
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// eip712Domain returns the typed-data domain bound to the configured chain
+func eip712Domain(chainID int64) apitypes.TypedDataDomain {
+	return apitypes.TypedDataDomain{
+		Name:    eip712DomainName,
+		Version: "1",
+		ChainId: math.NewHexOrDecimal256(chainID),
+	}
+}
+
+// eip712DomainType is the EIP712Domain type matching eip712Domain
+var eip712DomainType = []apitypes.Type{
+	{Name: "name", Type: "string"},
+	{Name: "version", Type: "string"},
+	{Name: "chainId", Type: "uint256"},
+}
+
+// recoverSigner returns the address that produced a 65-byte hex signature over
+// hash. Both the 0/1 and the 27/28 recovery id conventions are accepted.
+func recoverSigner(hash []byte, signature string) (common.Address, error) {
+	sig, err := hexutil.Decode(signature)
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return common.Address{}, ErrInvalidSignature
+	}
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return common.Address{}, ErrInvalidSignature
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/money"
+)
+
+var (
+	ErrTrancheNotFound       = errors.New("pool has no such tranche")
+	ErrTrancheRiskConsent    = errors.New("this tranche requires accepting all 3 risk statements")
+	ErrTrancheRiskProfile    = errors.New("complete the risk questionnaire to unlock this tranche")
+	ErrTrancheBelowMinimum   = errors.New("investment amount is below the tranche minimum")
+	ErrTrancheAboveMaximum   = errors.New("investment amount is above the tranche maximum")
+	ErrTrancheCapacity       = errors.New("investment amount exceeds remaining tranche capacity")
+	ErrInvalidTrancheLayout  = errors.New("tranche sizes must add up to 100 percent")
+	ErrDuplicateTrancheNames = errors.New("tranche names must be unique")
+)
+
+// buildTranches sizes the requested tranches against the pool target, most
+// senior first. Sizes are rounded to the currency's minor unit and the most
+// junior tranche takes the exact remainder, so the targets sum to the pool target.
+func buildTranches(target money.Amount, currency string, specs []models.CreateTrancheRequest) ([]models.PoolTranche, error) {
+	if len(specs) == 0 || len(specs) > models.MaxPoolTranches {
+		return nil, fmt.Errorf("a pool has between 1 and %d tranches", models.MaxPoolTranches)
+	}
+
+	var totalPercent float64
+	names := make([]models.TrancheType, len(specs))
+	seen := make(map[models.TrancheType]bool)
+	for i, spec := range specs {
+		names[i] = models.TrancheType(strings.ToLower(strings.TrimSpace(string(spec.Name))))
+		if names[i] == "" {
+			return nil, errors.New("tranche name is required")
+		}
+		if seen[names[i]] {
+			return nil, ErrDuplicateTrancheNames
+		}
+		seen[names[i]] = true
+		if spec.MaxInvestment > 0 && spec.MinInvestment > spec.MaxInvestment {
+			return nil, fmt.Errorf("tranche %s: min_investment is above max_investment", names[i])
+		}
+		totalPercent += spec.SizePercent
+	}
+	if math.Abs(totalPercent-100) > 1e-9 {
+		return nil, ErrInvalidTrancheLayout
+	}
+
+	tranches := make([]models.PoolTranche, len(specs))
+	var allocated money.Amount
+	for i, spec := range specs {
+		size := target.MulRate(spec.SizePercent).RoundTo(currency)
+		if i == len(specs)-1 {
+			size = target - allocated
+		}
+		allocated += size
+		tranches[i] = models.PoolTranche{
+			Name:                names[i],
+			Seniority:           i + 1,
+			TargetAmount:        size,
+			InterestRate:        spec.InterestRate,
+			RequiresRiskConsent: spec.RequiresRiskConsent,
+			RequiresRiskProfile: spec.RequiresRiskProfile,
+			MinInvestment:       spec.MinInvestment,
+			MaxInvestment:       spec.MaxInvestment,
+		}
+	}
+	return tranches, nil
+}
+
+// requiresRiskConsent reports whether investing in a tranche takes the
+// first-loss risk statements
+func requiresRiskConsent(pool *models.FundingPool, tranche models.TrancheType) bool {
+	if t := pool.Tranche(tranche); t != nil {
+		return t.RequiresRiskConsent
+	}
+	return tranche == models.TrancheCatalyst
+}
+
+// trancheSeniority ranks a tranche for the waterfall. Pools read without their
+// tranches fall back to priority before catalyst; unknown tranches rank last.
+func trancheSeniority(pool *models.FundingPool, tranche models.TrancheType) int {
+	if t := pool.Tranche(tranche); t != nil {
+		return t.Seniority
+	}
+	switch tranche {
+	case models.TranchePriority:
+		return 1
+	case models.TrancheCatalyst:
+		return 2
+	}
+	return math.MaxInt32
+}
+
+// waterfall pays available down the pool's tranches in seniority order. Each
+// tranche is paid in full when funds allow; the first tranche that cannot be
+// shares what is left pro-rata by claim and the tranches below it get nothing.
+// tranches[i] is the tranche claims[i] belongs to; the result is each claim's share.
+func waterfall(pool *models.FundingPool, tranches []models.TrancheType, claims []money.Amount, available money.Amount) []money.Amount {
+	unit := money.MinorUnit(pool.PoolCurrency)
+	bySeniority := make(map[int][]int)
+	var ranks []int
+	for i, tranche := range tranches {
+		rank := trancheSeniority(pool, tranche)
+		if _, ok := bySeniority[rank]; !ok {
+			ranks = append(ranks, rank)
+		}
+		bySeniority[rank] = append(bySeniority[rank], i)
+	}
+	sort.Ints(ranks)
+
+	shares := make([]money.Amount, len(claims))
+	for _, rank := range ranks {
+		idx := bySeniority[rank]
+		group := make([]money.Amount, len(idx))
+		for j, i := range idx {
+			group[j] = claims[i]
+		}
+		paid := claimShares(group, money.Max(available, 0), unit)
+		for j, i := range idx {
+			shares[i] = paid[j]
+		}
+		available -= money.Sum(paid...)
+	}
+	return shares
+}
+
+// sortBySeniority orders investments most senior tranche first, keeping the
+// existing order within a tranche
+func sortBySeniority(pool *models.FundingPool, investments []models.Investment) {
+	sort.SliceStable(investments, func(i, j int) bool {
+		return trancheSeniority(pool, investments[i].Tranche) < trancheSeniority(pool, investments[j].Tranche)
+	})
+}
+
+// trancheNote labels an investor's repayment transaction with its tranche
+func trancheNote(tranche models.TrancheType) string {
+	return capitalize(string(tranche)) + " tranche repayment"
+}
+
+// trancheDisplayName is the name investors see for a tranche
+func trancheDisplayName(tranche models.TrancheType) string {
+	switch tranche {
+	case models.TranchePriority:
+		return "Prioritas"
+	case models.TrancheCatalyst:
+		return "Katalis"
+	}
+	return capitalize(string(tranche))
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
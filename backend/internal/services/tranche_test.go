@@ -0,0 +1,270 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/money"
+)
+
+func threeTranchePool() *models.FundingPool {
+	return &models.FundingPool{
+		PoolCurrency: "IDR",
+		Tranches: []models.PoolTranche{
+			{Name: "senior", Seniority: 1},
+			{Name: "mezzanine", Seniority: 2},
+			{Name: "junior", Seniority: 3},
+		},
+	}
+}
+
+func TestWaterfall(t *testing.T) {
+	tests := []struct {
+		name      string
+		pool      *models.FundingPool
+		tranches  []models.TrancheType
+		claims    []money.Amount
+		available money.Amount
+		want      []money.Amount
+	}{
+		{
+			name:      "every claim paid in full",
+			pool:      threeTranchePool(),
+			tranches:  []models.TrancheType{"senior", "mezzanine", "junior"},
+			claims:    []money.Amount{money.FromInt(100), money.FromInt(50), money.FromInt(30)},
+			available: money.FromInt(200),
+			want:      []money.Amount{money.FromInt(100), money.FromInt(50), money.FromInt(30)},
+		},
+		{
+			name:      "seniority order, not input order",
+			pool:      threeTranchePool(),
+			tranches:  []models.TrancheType{"junior", "senior", "mezzanine"},
+			claims:    []money.Amount{money.FromInt(30), money.FromInt(100), money.FromInt(50)},
+			available: money.FromInt(120),
+			want:      []money.Amount{0, money.FromInt(100), money.FromInt(20)},
+		},
+		{
+			name:      "pro-rata inside a tranche, remainder to largest claim",
+			pool:      threeTranchePool(),
+			tranches:  []models.TrancheType{"senior", "senior"},
+			claims:    []money.Amount{money.FromInt(100), money.FromInt(200)},
+			available: money.FromInt(100),
+			want:      []money.Amount{money.FromInt(33), money.FromInt(67)},
+		},
+		{
+			name:      "short payment stops at the tranche it cannot cover",
+			pool:      threeTranchePool(),
+			tranches:  []models.TrancheType{"senior", "mezzanine", "mezzanine", "junior"},
+			claims:    []money.Amount{money.FromInt(100), money.FromInt(60), money.FromInt(40), money.FromInt(50)},
+			available: money.FromInt(150),
+			want:      []money.Amount{money.FromInt(100), money.FromInt(30), money.FromInt(20), 0},
+		},
+		{
+			name:      "junior tranche gets what is left after seniors are paid",
+			pool:      threeTranchePool(),
+			tranches:  []models.TrancheType{"senior", "mezzanine", "junior"},
+			claims:    []money.Amount{money.FromInt(100), money.FromInt(50), money.FromInt(30)},
+			available: money.FromInt(170),
+			want:      []money.Amount{money.FromInt(100), money.FromInt(50), money.FromInt(20)},
+		},
+		{
+			name:      "nothing available",
+			pool:      threeTranchePool(),
+			tranches:  []models.TrancheType{"senior", "junior"},
+			claims:    []money.Amount{money.FromInt(100), money.FromInt(50)},
+			available: 0,
+			want:      []money.Amount{0, 0},
+		},
+		{
+			name:      "negative available pays nothing",
+			pool:      threeTranchePool(),
+			tranches:  []models.TrancheType{"senior", "junior"},
+			claims:    []money.Amount{money.FromInt(100), money.FromInt(50)},
+			available: money.FromInt(-10),
+			want:      []money.Amount{0, 0},
+		},
+		{
+			name:      "pool without tranches pays priority before catalyst",
+			pool:      &models.FundingPool{PoolCurrency: "IDR"},
+			tranches:  []models.TrancheType{models.TrancheCatalyst, models.TranchePriority},
+			claims:    []money.Amount{money.FromInt(40), money.FromInt(80)},
+			available: money.FromInt(100),
+			want:      []money.Amount{money.FromInt(20), money.FromInt(80)},
+		},
+		{
+			name:      "unknown tranche ranks last",
+			pool:      threeTranchePool(),
+			tranches:  []models.TrancheType{"unknown", "junior"},
+			claims:    []money.Amount{money.FromInt(50), money.FromInt(50)},
+			available: money.FromInt(60),
+			want:      []money.Amount{money.FromInt(10), money.FromInt(50)},
+		},
+		{
+			name:      "cents for currencies with a minor unit",
+			pool:      &models.FundingPool{PoolCurrency: "USD", Tranches: threeTranchePool().Tranches},
+			tranches:  []models.TrancheType{"senior", "senior", "senior"},
+			claims:    []money.Amount{money.FromInt(1), money.FromInt(1), money.FromInt(1)},
+			available: 100,
+			want:      []money.Amount{34, 33, 33},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := waterfall(tt.pool, tt.tranches, tt.claims, tt.available)
+			if len(got) != len(tt.want) {
+				t.Fatalf("waterfall returned %d shares, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("share %d = %s, want %s", i, got[i], tt.want[i])
+				}
+			}
+			if paid := money.Sum(got...); paid > money.Max(tt.available, 0) {
+				t.Errorf("paid %s, more than the %s available", paid, tt.available)
+			}
+		})
+	}
+}
+
+func TestBuildTranches(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   money.Amount
+		currency string
+		specs    []models.CreateTrancheRequest
+		want     []money.Amount
+	}{
+		{
+			name:     "remainder goes to the last tranche",
+			target:   money.FromInt(1000),
+			currency: "IDR",
+			specs: []models.CreateTrancheRequest{
+				{Name: "senior", SizePercent: 33.33, InterestRate: 8},
+				{Name: "mezzanine", SizePercent: 33.33, InterestRate: 10},
+				{Name: "junior", SizePercent: 33.34, InterestRate: 15},
+			},
+			want: []money.Amount{money.FromInt(333), money.FromInt(333), money.FromInt(334)},
+		},
+		{
+			name:     "rounded to whole rupiah",
+			target:   money.FromInt(1000001),
+			currency: "IDR",
+			specs: []models.CreateTrancheRequest{
+				{Name: "priority", SizePercent: 70, InterestRate: 10},
+				{Name: "catalyst", SizePercent: 30, InterestRate: 15},
+			},
+			want: []money.Amount{money.FromInt(700001), money.FromInt(300000)},
+		},
+		{
+			name:     "rounded to cents",
+			target:   money.FromInt(100),
+			currency: "USD",
+			specs: []models.CreateTrancheRequest{
+				{Name: "a", SizePercent: 33.335, InterestRate: 8},
+				{Name: "b", SizePercent: 33.335, InterestRate: 10},
+				{Name: "c", SizePercent: 33.33, InterestRate: 15},
+			},
+			want: []money.Amount{3334, 3334, 3332},
+		},
+		{
+			name:     "single tranche takes the whole target",
+			target:   money.FromInt(500),
+			currency: "IDR",
+			specs:    []models.CreateTrancheRequest{{Name: "Senior ", SizePercent: 100, InterestRate: 9}},
+			want:     []money.Amount{money.FromInt(500)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildTranches(tt.target, tt.currency, tt.specs)
+			if err != nil {
+				t.Fatalf("buildTranches: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("buildTranches returned %d tranches, want %d", len(got), len(tt.want))
+			}
+			var total money.Amount
+			for i, tranche := range got {
+				if tranche.TargetAmount != tt.want[i] {
+					t.Errorf("tranche %d target = %s, want %s", i, tranche.TargetAmount, tt.want[i])
+				}
+				if tranche.Seniority != i+1 {
+					t.Errorf("tranche %d seniority = %d, want %d", i, tranche.Seniority, i+1)
+				}
+				total += tranche.TargetAmount
+			}
+			if total != tt.target {
+				t.Errorf("targets add up to %s, want %s", total, tt.target)
+			}
+		})
+	}
+
+	t.Run("names are trimmed and lowercased", func(t *testing.T) {
+		got, err := buildTranches(money.FromInt(100), "IDR", []models.CreateTrancheRequest{{Name: " Senior ", SizePercent: 100, InterestRate: 9}})
+		if err != nil {
+			t.Fatalf("buildTranches: %v", err)
+		}
+		if got[0].Name != "senior" {
+			t.Errorf("name = %q, want %q", got[0].Name, "senior")
+		}
+	})
+}
+
+func TestBuildTranchesInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		specs   []models.CreateTrancheRequest
+		wantErr error
+	}{
+		{
+			name: "sizes short of 100 percent",
+			specs: []models.CreateTrancheRequest{
+				{Name: "senior", SizePercent: 60, InterestRate: 8},
+				{Name: "junior", SizePercent: 30, InterestRate: 15},
+			},
+			wantErr: ErrInvalidTrancheLayout,
+		},
+		{
+			name: "duplicate names after normalizing",
+			specs: []models.CreateTrancheRequest{
+				{Name: "Senior", SizePercent: 50, InterestRate: 8},
+				{Name: "senior ", SizePercent: 50, InterestRate: 15},
+			},
+			wantErr: ErrDuplicateTrancheNames,
+		},
+		{
+			name:  "no tranches",
+			specs: nil,
+		},
+		{
+			name: "too many tranches",
+			specs: func() []models.CreateTrancheRequest {
+				specs := make([]models.CreateTrancheRequest, models.MaxPoolTranches+1)
+				for i := range specs {
+					specs[i] = models.CreateTrancheRequest{Name: models.TrancheType(string(rune('a' + i))), SizePercent: 100 / float64(len(specs)), InterestRate: 10}
+				}
+				return specs
+			}(),
+		},
+		{
+			name:  "blank name",
+			specs: []models.CreateTrancheRequest{{Name: "  ", SizePercent: 100, InterestRate: 8}},
+		},
+		{
+			name:  "minimum above maximum",
+			specs: []models.CreateTrancheRequest{{Name: "senior", SizePercent: 100, InterestRate: 8, MinInvestment: money.FromInt(10), MaxInvestment: money.FromInt(5)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := buildTranches(money.FromInt(1000), "IDR", tt.specs)
+			if err == nil {
+				t.Fatal("buildTranches succeeded, want an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/google/uuid"
+	"github.com/vessel/backend/internal/config"
+	"github.com/vessel/backend/internal/models"
+	"github.com/vessel/backend/internal/repository"
+)
+
+const walletChallengeStatement = "Vessel wants you to link this wallet to your account."
+
+var (
+	ErrInvalidWalletAddress    = errors.New("invalid wallet address")
+	ErrWalletChallengeNotFound = errors.New("wallet challenge not found")
+	ErrWalletChallengeExpired  = errors.New("wallet challenge has expired or was already used")
+	ErrWalletSignatureMismatch = errors.New("signature was not made by the challenged wallet")
+	ErrWalletInUse             = errors.New("wallet is linked to another account")
+)
+
+// WalletService binds a wallet to a user only after the wallet signs a one-time
+// challenge, since that address is recorded on-chain as the investor
+type WalletService struct {
+	repo    repository.WalletRepositoryInterface
+	chainID int64
+	ttl     time.Duration
+}
+
+func NewWalletService(repo repository.WalletRepositoryInterface, cfg *config.Config) *WalletService {
+	ttl := time.Duration(cfg.WalletChallengeTTLMinutes) * time.Minute
+	if ttl <= 0 {
+		ttl = 10 * time.Minute
+	}
+	return &WalletService{repo: repo, chainID: cfg.ChainID, ttl: ttl}
+}
+
+// IssueChallenge creates a nonce for the wallet and returns what it has to sign
+func (s *WalletService) IssueChallenge(userID uuid.UUID, req *models.WalletChallengeRequest) (*models.WalletChallengeResponse, error) {
+	if !common.IsHexAddress(req.WalletAddress) {
+		return nil, ErrInvalidWalletAddress
+	}
+	method := req.Method
+	if method == "" {
+		method = models.WalletProofEIP191
+	}
+
+	nonceBytes := make([]byte, 16)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	challenge := &models.WalletChallenge{
+		UserID:        userID,
+		WalletAddress: common.HexToAddress(req.WalletAddress).Hex(),
+		Method:        method,
+		Nonce:         hex.EncodeToString(nonceBytes),
+		IssuedAt:      now,
+		ExpiresAt:     now.Add(s.ttl),
+	}
+	challenge.Message = s.challengeMessage(challenge)
+
+	if err := s.repo.CreateChallenge(challenge); err != nil {
+		return nil, err
+	}
+
+	response := &models.WalletChallengeResponse{
+		ChallengeID: challenge.ID,
+		Method:      challenge.Method,
+		Message:     challenge.Message,
+		ExpiresAt:   challenge.ExpiresAt,
+	}
+	if method == models.WalletProofEIP712 {
+		typedData, err := json.Marshal(s.challengeTypedData(challenge))
+		if err != nil {
+			return nil, err
+		}
+		response.TypedData = typedData
+	}
+	return response, nil
+}
+
+// LinkWallet verifies the signed challenge and makes its wallet the user's
+// current one. The previous wallet stays in the history.
+func (s *WalletService) LinkWallet(userID uuid.UUID, req *models.LinkWalletRequest) (*models.WalletLink, error) {
+	challenge, err := s.repo.FindChallengeByID(req.ChallengeID)
+	if err != nil {
+		return nil, err
+	}
+	if challenge == nil || challenge.UserID != userID {
+		return nil, ErrWalletChallengeNotFound
+	}
+	if challenge.UsedAt != nil || time.Now().After(challenge.ExpiresAt) {
+		return nil, ErrWalletChallengeExpired
+	}
+
+	hash, err := s.challengeHash(challenge)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := recoverSigner(hash, req.Signature)
+	if err != nil {
+		return nil, err
+	}
+	if signer != common.HexToAddress(challenge.WalletAddress) {
+		return nil, ErrWalletSignatureMismatch
+	}
+
+	owner, err := s.repo.FindUserByWallet(challenge.WalletAddress)
+	if err != nil {
+		return nil, err
+	}
+	if owner != nil && *owner != userID {
+		return nil, ErrWalletInUse
+	}
+
+	link := &models.WalletLink{
+		UserID:        userID,
+		WalletAddress: challenge.WalletAddress,
+		Method:        challenge.Method,
+		Signature:     req.Signature,
+	}
+	linked, err := s.repo.Link(challenge.ID, link)
+	if err != nil {
+		return nil, err
+	}
+	if !linked {
+		return nil, ErrWalletChallengeExpired
+	}
+
+	return link, nil
+}
+
+// GetWalletHistory returns every wallet the user has linked, current one first
+func (s *WalletService) GetWalletHistory(userID uuid.UUID) ([]models.WalletLink, error) {
+	links, err := s.repo.FindLinksByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if links == nil {
+		links = []models.WalletLink{}
+	}
+	return links, nil
+}
+
+// challengeMessage is the human-readable text signed with personal_sign and
+// shown as the statement of the typed data
+func (s *WalletService) challengeMessage(c *models.WalletChallenge) string {
+	return fmt.Sprintf("%s\n\nWallet: %s\nChain ID: %d\nNonce: %s\nIssued At: %s\nExpiration Time: %s",
+		walletChallengeStatement,
+		c.WalletAddress,
+		s.chainID,
+		c.Nonce,
+		c.IssuedAt.Format(time.RFC3339),
+		c.ExpiresAt.Format(time.RFC3339),
+	)
+}
+
+func (s *WalletService) challengeTypedData(c *models.WalletChallenge) apitypes.TypedData {
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": eip712DomainType,
+			"LinkWallet": {
+				{Name: "wallet", Type: "address"},
+				{Name: "statement", Type: "string"},
+				{Name: "nonce", Type: "string"},
+				{Name: "issuedAt", Type: "uint256"},
+				{Name: "expiresAt", Type: "uint256"},
+			},
+		},
+		PrimaryType: "LinkWallet",
+		Domain:      eip712Domain(s.chainID),
+		Message: apitypes.TypedDataMessage{
+			"wallet":    c.WalletAddress,
+			"statement": walletChallengeStatement,
+			"nonce":     c.Nonce,
+			"issuedAt":  fmt.Sprintf("%d", c.IssuedAt.Unix()),
+			"expiresAt": fmt.Sprintf("%d", c.ExpiresAt.Unix()),
+		},
+	}
+}
+
+// challengeHash is the digest the wallet signed for the challenge's method
+func (s *WalletService) challengeHash(c *models.WalletChallenge) ([]byte, error) {
+	if c.Method == models.WalletProofEIP712 {
+		hash, _, err := apitypes.TypedDataAndHash(s.challengeTypedData(c))
+		return hash, err
+	}
+	return accounts.TextHash([]byte(c.Message)), nil
+}
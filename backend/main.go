@@ -1,14 +1,18 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/vessel/backend/internal/config"
 	"github.com/vessel/backend/internal/database"
 	"github.com/vessel/backend/internal/handlers"
 	"github.com/vessel/backend/internal/middleware"
+	"github.com/vessel/backend/internal/models"
 	"github.com/vessel/backend/internal/repository"
 	"github.com/vessel/backend/internal/services"
 	"github.com/vessel/backend/internal/utils"
@@ -45,7 +49,6 @@ func main() {
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
 	kycRepo := repository.NewKYCRepository(db)
-	// buyerRepo removed
 	invoiceRepo := repository.NewInvoiceRepository(db)
 	fundingRepo := repository.NewFundingRepository(db)
 	txRepo := repository.NewTransactionRepository(db)
@@ -53,6 +56,22 @@ func main() {
 	mitraRepo := repository.NewMitraRepository(db)
 	importerPaymentRepo := repository.NewImporterPaymentRepository(db)
 	rqRepo := repository.NewRiskQuestionnaireRepository(db)
+	ledgerRepo := repository.NewLedgerRepository(db)
+	idempotencyRepo := repository.NewIdempotencyRepository(db)
+	vaRepo := repository.NewVirtualAccountRepository(db)
+	paymentGatewayRepo := repository.NewPaymentGatewayRepository(db)
+	outboxRepo := repository.NewOnchainOutboxRepository(db)
+	onchainEventRepo := repository.NewOnchainEventRepository(db)
+	walletRepo := repository.NewWalletRepository(db)
+	defaultRepo := repository.NewDefaultRepository(db)
+	lateChargeRepo := repository.NewLateChargeRepository(db)
+	secondaryRepo := repository.NewSecondaryMarketRepository(db)
+	gradingRepo := repository.NewGradingRepository(db)
+	countryTierRepo := repository.NewCountryTierRepository(db)
+	analyticsRepo := repository.NewAnalyticsRepository(db)
+	creditScoreRepo := repository.NewCreditScoreRepository(db)
+	buyerRepo := repository.NewBuyerRepository(db)
+	unitOfWork := repository.NewUnitOfWork(db)
 
 	// Initialize JWT Manager
 	jwtManager := utils.NewJWTManager(cfg.JWTSecret, cfg.JWTExpiryHours, cfg.JWTRefreshExpiryHours)
@@ -67,34 +86,223 @@ func main() {
 	}
 
 	emailService := services.NewEmailService(cfg)
+	ledgerService := services.NewLedgerService(ledgerRepo)
+
+	// Payment gateway (only the local simulator is built in)
+	if cfg.PaymentGatewayProvider != services.SimulatorProvider {
+		log.Fatalf("Unsupported payment gateway provider: %s", cfg.PaymentGatewayProvider)
+	}
+	webhookSecret := cfg.PaymentWebhookSecret
+	if webhookSecret == "" {
+		webhookSecret = uuid.New().String()
+		log.Printf("Warning: PAYMENT_WEBHOOK_SECRET not set, using a random secret for this run")
+	}
+	paymentGateway := services.NewSimulatorGateway(webhookSecret, cfg.FrontendURL)
+	paymentGatewayService := services.NewPaymentGatewayService(paymentGateway, paymentGatewayRepo, unitOfWork, webhookSecret, cfg.PaymentSimulatorAutoSettle)
 	escrowService := services.NewEscrowService()
 	otpService := services.NewOTPService(otpRepo, emailService, cfg, jwtManager)
 	authService := services.NewAuthService(userRepo, jwtManager, otpService)
-	mitraService := services.NewMitraService(mitraRepo, userRepo, emailService, pinataService)
+	countryTierService := services.NewCountryTierService(countryTierRepo, cfg)
+	creditScoreService := services.NewCreditScoreService(creditScoreRepo)
+	buyerService := services.NewBuyerService(buyerRepo)
+	gradingService := services.NewGradingService(invoiceRepo, gradingRepo, countryTierService, creditScoreService)
+	analyticsService := services.NewAnalyticsService(analyticsRepo, gradingService, countryTierService)
 	invoiceService := services.NewInvoiceService(invoiceRepo, fundingRepo, pinataService, cfg)
-	invoiceService.SetUserRepo(userRepo)   // Set user repo for grade suggestion
-	invoiceService.SetMitraRepo(mitraRepo) // Set mitra repo for approval check
-	// Pass blockchainService to fundingService
-	fundingService := services.NewFundingService(fundingRepo, invoiceRepo, txRepo, userRepo, rqRepo, emailService, escrowService, blockchainService, cfg)
-	paymentService := services.NewPaymentService(userRepo, txRepo, fundingRepo, invoiceRepo) // Updated with fundingRepo and invoiceRepo for Flow 3
+	invoiceService.SetUserRepo(userRepo)                     // Set user repo for grade suggestion
+	invoiceService.SetMitraRepo(mitraRepo)                   // Set mitra repo for approval check
+	invoiceService.SetGradingService(gradingService)         // Grading engine for grade suggestion
+	invoiceService.SetCreditScoreService(creditScoreService) // Credit score for funding limits and review
+	invoiceService.SetBuyerService(buyerService)             // Buyer registry for repeat buyers
+	// On-chain writes go through the outbox, submitted by the worker below
+	interestEngine := services.NewInterestEngine(cfg.InterestDayCount, cfg.LateChargeRules)
+	fundingService := services.NewFundingService(fundingRepo, invoiceRepo, txRepo, userRepo, rqRepo, lateChargeRepo, countryTierService, emailService, escrowService, ledgerService, interestEngine, unitOfWork, cfg)
+	lateChargeService := services.NewLateChargeService(lateChargeRepo, interestEngine, unitOfWork)
+	defaultService := services.NewDefaultService(defaultRepo, fundingRepo, invoiceRepo, userRepo, ledgerService, emailService, unitOfWork, cfg)
+	outboxService := services.NewOnchainOutboxService(outboxRepo, txRepo, blockchainService, cfg)
+	reconciliationService := services.NewReconciliationService(invoiceRepo, fundingRepo, userRepo, outboxRepo, unitOfWork, blockchainService, cfg)
+	indexerService, err := services.NewContractIndexerService(onchainEventRepo, fundingRepo, invoiceRepo, blockchainService, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize contract indexer: %v", err)
+	}
+	paymentService := services.NewPaymentService(userRepo, txRepo, fundingRepo, invoiceRepo, ledgerService, paymentGatewayService) // Updated with fundingRepo and invoiceRepo for Flow 3
+	mitraService := services.NewMitraService(mitraRepo, userRepo, fundingRepo, invoiceRepo, vaRepo, fundingService, paymentGatewayService, emailService, pinataService, interestEngine, lateChargeRepo)
+	importerPaymentService := services.NewImporterPaymentService(importerPaymentRepo, fundingService, paymentGatewayService)
+
+	// Confirmed gateway payments settle the record they were created for
+	paymentGatewayService.OnSettled(models.GatewayPurposeDeposit, paymentService.SettleDeposit)
+	paymentGatewayService.OnSettled(models.GatewayPurposeMitraRepayment, mitraService.SettleRepaymentVA)
+	paymentGatewayService.OnSettled(models.GatewayPurposeImporterPayment, importerPaymentService.SettlePayment)
 	rqService := services.NewRiskQuestionnaireService(rqRepo)
+	secondaryService := services.NewSecondaryMarketService(secondaryRepo, fundingRepo, rqService, ledgerService, unitOfWork)
+	walletService := services.NewWalletService(walletRepo, cfg)
 	currencyService := services.NewCurrencyService(cfg)
 
+	// CLI subcommands share the wiring above and exit instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "reconcile" {
+		code := runReconcileCommand(reconciliationService, os.Args[2:])
+		db.Close()
+		os.Exit(code)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "backtest" {
+		code := runBacktestCommand(analyticsService, gradingService, os.Args[2:])
+		db.Close()
+		os.Exit(code)
+	}
+
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(authService, otpService)
 	userHandler := handlers.NewUserHandler(userRepo, kycRepo, pinataService)
-	// buyerHandler removed
 	invoiceHandler := handlers.NewInvoiceHandler(invoiceService, blockchainService)
 	fundingHandler := handlers.NewFundingHandler(fundingService)
 	mitraHandler := handlers.NewMitraHandler(mitraService)
 	paymentHandler := handlers.NewPaymentHandler(paymentService, txRepo)
-	importerHandler := handlers.NewImporterHandler(importerPaymentRepo, fundingService, fundingRepo, invoiceRepo)
+	importerHandler := handlers.NewImporterHandler(importerPaymentRepo, importerPaymentService, fundingRepo, invoiceRepo)
+	webhookHandler := handlers.NewWebhookHandler(paymentGatewayService)
 	rqHandler := handlers.NewRiskQuestionnaireHandler(rqService)
 	currencyHandler := handlers.NewCurrencyHandler(currencyService)
+	ledgerHandler := handlers.NewLedgerHandler(ledgerService)
+	outboxHandler := handlers.NewOnchainOutboxHandler(outboxService)
+	onchainEventHandler := handlers.NewOnchainEventHandler(indexerService)
+	walletHandler := handlers.NewWalletHandler(walletService)
+	defaultHandler := handlers.NewDefaultHandler(defaultService)
+	secondaryHandler := handlers.NewSecondaryMarketHandler(secondaryService)
+	gradingHandler := handlers.NewGradingHandler(gradingService)
+	countryTierHandler := handlers.NewCountryTierHandler(countryTierService)
+	analyticsHandler := handlers.NewAnalyticsHandler(analyticsService)
+	creditScoreHandler := handlers.NewCreditScoreHandler(creditScoreService)
+	buyerHandler := handlers.NewBuyerHandler(buyerService, invoiceService)
 
 	// Initialize profile middleware
 	profileMiddleware := middleware.NewProfileMiddleware(userRepo)
 
+	// Idempotency-Key support for money-moving endpoints (Redis is an optional fast path)
+	idempotency := middleware.NewIdempotencyMiddleware(idempotencyRepo, redisClient, time.Duration(cfg.IdempotencyKeyTTLHours)*time.Hour)
+	go func() {
+		for range time.Tick(time.Hour) {
+			if purged, err := idempotencyRepo.DeleteExpired(); err != nil {
+				log.Printf("Warning: failed to purge expired idempotency keys: %v", err)
+			} else if purged > 0 {
+				log.Printf("Purged %d expired idempotency keys", purged)
+			}
+		}
+	}()
+
+	// Pool deadline expiry: refund or partially disburse pools that missed their deadline
+	go func() {
+		interval := time.Duration(cfg.PoolExpiryCheckIntervalMins) * time.Minute
+		if interval <= 0 {
+			interval = 5 * time.Minute
+		}
+		for range time.Tick(interval) {
+			if handled, err := fundingService.ProcessExpiredPools(); err != nil {
+				log.Printf("Warning: failed to process expired pools: %v", err)
+			} else if handled > 0 {
+				log.Printf("Processed %d expired funding pools", handled)
+			}
+		}
+	}()
+
+	// Default workflow: flag disbursed pools still unpaid after the grace period
+	go func() {
+		interval := time.Duration(cfg.DefaultCheckIntervalMins) * time.Minute
+		if interval <= 0 {
+			interval = time.Hour
+		}
+		for range time.Tick(interval) {
+			if overdue, err := defaultService.DetectOverdue(); err != nil {
+				log.Printf("Warning: failed to detect overdue pools: %v", err)
+			} else if overdue > 0 {
+				log.Printf("Flagged %d overdue pools", overdue)
+			}
+		}
+	}()
+
+	// Late payment charges: accrue late fees and penalty interest on overdue pools
+	go func() {
+		interval := time.Duration(cfg.LateChargeAccrualIntervalMins) * time.Minute
+		if interval <= 0 {
+			interval = time.Hour
+		}
+		for range time.Tick(interval) {
+			if charged, err := lateChargeService.AccrueOverdue(); err != nil {
+				log.Printf("Warning: failed to accrue late charges: %v", err)
+			} else if charged > 0 {
+				log.Printf("Accrued late charges on %d overdue pools", charged)
+			}
+		}
+	}()
+
+	// Mitra repayment VAs expire after 24 hours
+	go func() {
+		for range time.Tick(time.Minute) {
+			if expired, err := mitraService.ExpireVirtualAccounts(); err != nil {
+				log.Printf("Warning: failed to expire virtual accounts: %v", err)
+			} else if expired > 0 {
+				log.Printf("Expired %d virtual accounts", expired)
+			}
+		}
+	}()
+
+	// On-chain outbox: submit queued InvoicePool writes, retrying with backoff, then
+	// follow their receipts to the configured confirmation depth
+	if blockchainService != nil {
+		go func() {
+			interval := time.Duration(cfg.OnchainOutboxPollSeconds) * time.Second
+			if interval <= 0 {
+				interval = 5 * time.Second
+			}
+			for range time.Tick(interval) {
+				if submitted, err := outboxService.ProcessDue(); err != nil {
+					log.Printf("Warning: failed to process on-chain outbox: %v", err)
+				} else if submitted > 0 {
+					log.Printf("Submitted %d on-chain outbox entries", submitted)
+				}
+				if confirmed, err := outboxService.TrackReceipts(); err != nil {
+					log.Printf("Warning: failed to track on-chain receipts: %v", err)
+				} else if confirmed > 0 {
+					log.Printf("Confirmed %d on-chain outbox entries", confirmed)
+				}
+			}
+		}()
+	} else {
+		log.Printf("Warning: blockchain service unavailable, on-chain outbox entries will stay queued")
+	}
+
+	// Contract event indexer: backfill InvoiceNFT/InvoicePool events, then follow
+	// the chain head, rolling back blocks dropped by a reorg
+	if blockchainService != nil && blockchainService.Enabled() {
+		go func() {
+			interval := time.Duration(cfg.OnchainIndexerPollSeconds) * time.Second
+			if interval <= 0 {
+				interval = 15 * time.Second
+			}
+			for range time.Tick(interval) {
+				if indexed, err := indexerService.Sync(context.Background()); err != nil {
+					log.Printf("Warning: failed to index contract events: %v", err)
+				} else if indexed > 0 {
+					log.Printf("Indexed %d contract events", indexed)
+				}
+			}
+		}()
+	}
+
+	// On-chain reconciliation: report pools whose contract state disagrees with
+	// Postgres and, if enabled, queue the missing writes
+	if cfg.OnchainReconcileIntervalMins > 0 && blockchainService != nil && blockchainService.Enabled() {
+		go func() {
+			for range time.Tick(time.Duration(cfg.OnchainReconcileIntervalMins) * time.Minute) {
+				report, err := reconciliationService.Run(context.Background(), cfg.OnchainReconcileAutoEnqueue)
+				if err != nil {
+					log.Printf("Warning: on-chain reconciliation failed: %v", err)
+					continue
+				}
+				for _, d := range report.Diffs {
+					log.Printf("Reconciliation: token %d %s database=%q chain=%q", d.TokenID, d.Field, d.Database, d.Chain)
+				}
+			}
+		}()
+	}
+
 	// Initialize Gin router
 	router := gin.Default()
 
@@ -133,7 +341,13 @@ func main() {
 		public := v1.Group("/public")
 		{
 			public.GET("/payments/:payment_id", importerHandler.GetPaymentInfo)
-			public.POST("/payments/:payment_id/pay", importerHandler.Pay)
+			public.POST("/payments/:payment_id/pay", idempotency.Middleware(), importerHandler.Pay)
+		}
+
+		// Payment provider webhooks (authenticated by HMAC signature)
+		webhooks := v1.Group("/webhooks")
+		{
+			webhooks.POST("/payments/:provider", webhookHandler.PaymentWebhook)
 		}
 
 		// Protected routes
@@ -158,7 +372,9 @@ func main() {
 				user.PUT("/profile/bank-account", userHandler.ChangeBankAccount) // Ubah Rekening (OTP required)
 				user.PUT("/profile/password", userHandler.ChangePassword)        // Keamanan - Ubah Password
 				user.GET("/profile/banks", userHandler.GetSupportedBanks)        // List supported banks
-				user.PUT("/wallet", userHandler.UpdateWallet)
+				user.POST("/wallet/challenge", walletHandler.RequestChallenge)   // Nonce to sign with the wallet
+				user.PUT("/wallet", walletHandler.UpdateWallet)                  // Link wallet with the signed challenge
+				user.GET("/wallet/history", walletHandler.GetWalletHistory)
 
 				// MITRA application routes (Flow 2)
 				mitra := user.Group("/mitra")
@@ -181,13 +397,11 @@ func main() {
 			payments := protected.Group("/payments")
 			payments.Use(profileMiddleware.RequireProfileComplete())
 			{
-				payments.POST("/deposit", paymentHandler.Deposit)
-				payments.POST("/withdraw", paymentHandler.Withdraw)
+				payments.POST("/deposit", idempotency.Middleware(), paymentHandler.Deposit)
+				payments.POST("/withdraw", idempotency.Middleware(), paymentHandler.Withdraw)
 				payments.GET("/balance", paymentHandler.GetBalance)
 			}
 
-			// Buyer routes removed (deprecated, information now on Invoice)
-
 			// Invoice routes (exporter/mitra for CRUD)
 			invoices := protected.Group("/invoices")
 			invoices.Use(profileMiddleware.RequireProfileComplete())
@@ -214,6 +428,7 @@ func main() {
 			{
 				pools.GET("", fundingHandler.ListPools)
 				pools.GET("/:id", fundingHandler.GetPool)
+				pools.GET("/:id/onchain-events", onchainEventHandler.GetPoolEvents)
 			}
 
 			// Marketplace routes (with filters) - Flow 6
@@ -238,18 +453,30 @@ func main() {
 			investments := protected.Group("/investments")
 			investments.Use(middleware.InvestorOnly())
 			{
-				investments.POST("", fundingHandler.Invest)
+				investments.POST("", idempotency.Middleware(), fundingHandler.Invest)
 				investments.POST("/confirm", fundingHandler.ConfirmInvestment) // Flow 6 confirmation
+				investments.POST("/consent", fundingHandler.PrepareConsent)    // EIP-712 consent payload
 				investments.GET("", fundingHandler.GetMyInvestments)
 				investments.GET("/portfolio", fundingHandler.GetPortfolio)      // Flow 9
 				investments.GET("/active", fundingHandler.GetActiveInvestments) // Flow 10
 			}
 
+			// Secondary market: investors sell positions to each other before maturity
+			secondaryMarket := protected.Group("/secondary-market")
+			secondaryMarket.Use(middleware.InvestorOnly())
+			{
+				secondaryMarket.GET("/listings", secondaryHandler.ListListings)
+				secondaryMarket.GET("/my-listings", secondaryHandler.ListMyListings)
+				secondaryMarket.POST("/listings", secondaryHandler.CreateListing)
+				secondaryMarket.POST("/listings/:id/cancel", secondaryHandler.CancelListing)
+				secondaryMarket.POST("/listings/:id/buy", idempotency.Middleware(), secondaryHandler.BuyListing)
+			}
+
 			// Exporter/Mitra routes (Flow 8, 11)
 			exporter := protected.Group("/exporter")
 			exporter.Use(middleware.ExporterOnly(), profileMiddleware.RequireProfileComplete())
 			{
-				exporter.POST("/disbursement", fundingHandler.ExporterDisbursement) // Flow 11
+				exporter.POST("/disbursement", idempotency.Middleware(), fundingHandler.ExporterDisbursement) // Flow 11
 			}
 
 			// Mitra Dashboard (Flow 8)
@@ -258,6 +485,7 @@ func main() {
 			{
 				mitraDashboard.GET("/dashboard", fundingHandler.GetMitraDashboard)
 				mitraDashboard.GET("/invoices", fundingHandler.GetMitraActiveInvoices)
+				mitraDashboard.GET("/credit-score", creditScoreHandler.GetMyCreditScore)
 
 				// Mitra Repayment (Flow: MITRA MEMBAYAR HUTANG)
 				mitraDashboard.GET("/invoices/active", mitraHandler.GetActiveInvoices)                // Active invoices needing repayment
@@ -284,14 +512,24 @@ func main() {
 				admin.GET("/invoices/pending", invoiceHandler.GetPendingInvoices)
 				admin.GET("/invoices/approved", invoiceHandler.GetApprovedInvoices)
 				admin.GET("/invoices/:id/grade-suggestion", invoiceHandler.GetGradeSuggestion) // BE-ADM-1 logic
+				admin.GET("/invoices/:id/grade-scores", gradingHandler.GetInvoiceScores)       // Active and shadow breakdowns
 				admin.GET("/invoices/:id/review", invoiceHandler.GetInvoiceReviewData)         // Split-screen data
+				admin.GET("/invoices/:id/credit-score", invoiceHandler.GetInvoiceCreditScore)  // Exporter credit score
 				admin.POST("/invoices/:id/approve", invoiceHandler.Approve)                    // Approve with grade
 				admin.POST("/invoices/:id/reject", invoiceHandler.Reject)
 
 				// Pool management
 				admin.POST("/pools/:id/disburse", fundingHandler.Disburse)
 				admin.POST("/pools/:id/close", fundingHandler.ClosePoolAndNotify)
-				admin.POST("/invoices/:id/repay", fundingHandler.ProcessRepayment)
+				admin.POST("/invoices/:id/repay", idempotency.Middleware(), fundingHandler.ProcessRepayment) // One installment
+				admin.GET("/pools/:id/repayments", fundingHandler.GetRepaymentSummary)
+				admin.GET("/investments/:id/consent", fundingHandler.GetInvestmentConsent) // Signed consent proof
+
+				// Default workflow
+				admin.GET("/defaults", defaultHandler.ListDefaults)
+				admin.GET("/pools/:id/default", defaultHandler.GetDefault)
+				admin.POST("/pools/:id/default", defaultHandler.DeclareDefault)
+				admin.POST("/pools/:id/recoveries", idempotency.Middleware(), defaultHandler.RecordRecovery)
 
 				// Admin Mitra Application routes (Flow 2)
 				admin.GET("/mitra/pending", mitraHandler.GetPendingApplications)
@@ -304,6 +542,44 @@ func main() {
 
 				// Admin Platform Revenue Dashboard
 				admin.GET("/platform/revenue", paymentHandler.GetPlatformRevenue)
+
+				// Admin Ledger (double-entry books)
+				admin.GET("/ledger/trial-balance", ledgerHandler.GetTrialBalance)
+
+				// Admin Grading Scorecards (versioned risk model; one active, others in shadow)
+				admin.GET("/grading/factors", gradingHandler.ListFactors)
+				admin.GET("/grading/scorecards", gradingHandler.ListScorecards)
+				admin.GET("/grading/scorecards/:id", gradingHandler.GetScorecard)
+				admin.POST("/grading/scorecards", gradingHandler.CreateScorecard)
+				admin.POST("/grading/scorecards/:id/activate", gradingHandler.ActivateScorecard)
+				admin.POST("/grading/scorecards/:id/shadow", gradingHandler.ShadowScorecard)
+				admin.POST("/grading/scorecards/:id/retire", gradingHandler.RetireScorecard)
+				admin.GET("/grading/scorecards/:id/backtest", analyticsHandler.BacktestScorecard) // Replay repaid and defaulted invoices
+
+				// Admin Analytics (realized outcomes by grade, country tier and tranche)
+				admin.GET("/analytics/loss-rates", analyticsHandler.GetLossRates)
+
+				// Admin Country Risk Tiers (codes may be ISO alpha-2, alpha-3 or names)
+				admin.GET("/country-tiers", countryTierHandler.ListCountryTiers)
+				admin.GET("/country-tiers/:code", countryTierHandler.GetCountryTier)
+				admin.GET("/country-tiers/:code/history", countryTierHandler.GetCountryTierHistory)
+				admin.POST("/country-tiers", countryTierHandler.CreateCountryTier)
+				admin.PUT("/country-tiers/:code", countryTierHandler.UpdateCountryTier)
+				admin.DELETE("/country-tiers/:code", countryTierHandler.DeleteCountryTier)
+
+				// Admin Buyer Registry (importers across every mitra)
+				admin.GET("/buyers", buyerHandler.ListBuyers)
+				admin.GET("/buyers/duplicates", buyerHandler.GetBuyerDuplicates) // Similar names in the same country
+				admin.GET("/buyers/:id", buyerHandler.GetBuyer)
+				admin.GET("/buyers/:id/invoices", buyerHandler.GetBuyerInvoices)
+				admin.POST("/buyers/:id/aliases", buyerHandler.AddBuyerAlias)
+				admin.POST("/buyers/:id/contacts", buyerHandler.AddBuyerContact)
+				admin.POST("/buyers/:id/merge", buyerHandler.MergeBuyer)
+
+				// Admin On-chain Outbox (stuck or failed InvoicePool writes)
+				admin.GET("/onchain-outbox", outboxHandler.ListEntries)
+				admin.POST("/onchain-outbox/:id/retry", outboxHandler.RetryEntry)
+				admin.POST("/onchain-outbox/:id/cancel", outboxHandler.CancelEntry)
 			}
 		}
 	}
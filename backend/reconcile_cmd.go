@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/vessel/backend/internal/services"
+)
+
+// runReconcileCommand implements `main reconcile [-enqueue] [-json]`. It exits
+// with 0 when Postgres and the chain agree, 1 when differences were found and 2
+// when the run failed.
+func runReconcileCommand(reconciliationService *services.ReconciliationService, args []string) int {
+	fs := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	enqueue := fs.Bool("enqueue", false, "queue on-chain writes that bring the contracts in line with Postgres")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	report, err := reconciliationService.Run(context.Background(), *enqueue)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "reconcile: %v\n", err)
+		return 2
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "reconcile: %v\n", err)
+			return 2
+		}
+	} else {
+		fmt.Printf("Chain %d: checked %d invoices, %d with differences, %d with unverified token ids, %d corrections enqueued\n\n",
+			report.ChainID, report.InvoicesChecked, report.InvoicesWithDiffs, report.InvoicesUnverified, report.Enqueued)
+		if len(report.Diffs) > 0 {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "TOKEN\tINVOICE\tFIELD\tDATABASE\tCHAIN\tCORRECTION\tNOTE")
+			for _, d := range report.Diffs {
+				correction := "-"
+				if d.Correction != nil {
+					correction = string(*d.Correction)
+					if d.Enqueued {
+						correction += " (enqueued)"
+					}
+				}
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", d.TokenID, d.InvoiceID, d.Field, d.Database, d.Chain, correction, d.Note)
+			}
+			w.Flush()
+		}
+		for _, e := range report.Errors {
+			fmt.Fprintf(os.Stderr, "error: %s\n", e)
+		}
+	}
+
+	if len(report.Errors) > 0 {
+		return 2
+	}
+	if len(report.Diffs) > 0 {
+		return 1
+	}
+	return 0
+}
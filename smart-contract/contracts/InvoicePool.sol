@@ -97,6 +97,13 @@ contract InvoicePool is AccessControl, ReentrancyGuard, Pausable {
         address indexed investor,
         uint256 amount
     );
+    event InvestmentTransferred(
+        uint256 indexed tokenId,
+        address indexed from,
+        address indexed to,
+        uint256 amount,
+        uint256 expectedReturn
+    );
     event PoolClosed(uint256 indexed tokenId);
     event PoolDefaulted(uint256 indexed tokenId);
 
@@ -222,6 +229,60 @@ contract InvoicePool is AccessControl, ReentrancyGuard, Pausable {
         emit DisbursementRecorded(tokenId, pool.exporter, pool.fundedAmount);
     }
 
+    /**
+     * @dev Record a secondary market sale of an investment (called after the buyer paid off-chain)
+     * NOTE: No actual token transfer - this only records the new owner on-chain
+     * @param tokenId The pool token ID
+     * @param index Position of the sold investment in the pool's investments
+     * @param to The buyer's wallet address
+     * @param amount Principal sold; less than the investment's amount splits it and
+     * appends the buyer's part with its share of the expected return
+     */
+    function transferInvestment(
+        uint256 tokenId,
+        uint256 index,
+        address to,
+        uint256 amount
+    ) external onlyRole(OPERATOR_ROLE) nonReentrant whenNotPaused {
+        Pool storage pool = pools[tokenId];
+        require(
+            pool.status == PoolStatus.Filled ||
+                pool.status == PoolStatus.Disbursed,
+            "Pool not active"
+        );
+        require(to != address(0), "Invalid address");
+
+        Investment[] storage investments = poolInvestments[tokenId];
+        require(index < investments.length, "Investment does not exist");
+        Investment storage inv = investments[index];
+        require(!inv.claimed, "Investment already claimed");
+        require(amount > 0 && amount <= inv.amount, "Invalid amount");
+
+        address from = inv.investor;
+        uint256 movedReturn = inv.expectedReturn;
+        if (amount == inv.amount) {
+            inv.investor = to;
+        } else {
+            movedReturn = (inv.expectedReturn * amount) / inv.amount;
+            inv.amount -= amount;
+            inv.expectedReturn -= movedReturn;
+            investments.push(
+                Investment({
+                    investor: to,
+                    amount: amount,
+                    expectedReturn: movedReturn,
+                    actualReturn: 0,
+                    claimed: false,
+                    investedAt: block.timestamp
+                })
+            );
+            pool.investorCount++;
+        }
+        investorPools[to].push(tokenId);
+
+        emit InvestmentTransferred(tokenId, from, to, amount, movedReturn);
+    }
+
     /**
      * @dev Record repayment and investor returns (called after importer pays off-chain)
      * NOTE: No actual token transfer - this only records the repayment on-chain
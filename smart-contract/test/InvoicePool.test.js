@@ -278,6 +278,69 @@ describe("InvoicePool", function () {
     });
   });
 
+  describe("Investment Transfer", function () {
+    let investor3;
+
+    beforeEach(async function () {
+      [, , , , investor3] = await ethers.getSigners();
+      await mintAndVerifyInvoice();
+      await invoicePool.createPool(1);
+      await invoicePool.recordInvestment(1, investor1.address, ethers.parseEther("4000"));
+      await invoicePool.recordInvestment(1, investor2.address, ethers.parseEther("4000"));
+      await invoicePool.recordDisbursement(1);
+    });
+
+    it("Should transfer a whole investment to the buyer", async function () {
+      await expect(invoicePool.transferInvestment(1, 0, investor3.address, ethers.parseEther("4000")))
+        .to.emit(invoicePool, "InvestmentTransferred");
+
+      const investments = await invoicePool.getPoolInvestments(1);
+      expect(investments.length).to.equal(2);
+      expect(investments[0].investor).to.equal(investor3.address);
+      expect(investments[0].amount).to.equal(ethers.parseEther("4000"));
+      expect(await invoicePool.getInvestorPools(investor3.address)).to.deep.equal([1n]);
+    });
+
+    it("Should split a partial transfer into a new investment", async function () {
+      const before = (await invoicePool.getPoolInvestments(1))[0];
+      await invoicePool.transferInvestment(1, 0, investor3.address, ethers.parseEther("1000"));
+
+      const investments = await invoicePool.getPoolInvestments(1);
+      expect(investments.length).to.equal(3);
+      expect(investments[0].investor).to.equal(investor1.address);
+      expect(investments[0].amount).to.equal(ethers.parseEther("3000"));
+      expect(investments[2].investor).to.equal(investor3.address);
+      expect(investments[2].amount).to.equal(ethers.parseEther("1000"));
+      expect(investments[0].expectedReturn + investments[2].expectedReturn).to.equal(before.expectedReturn);
+      expect((await invoicePool.getPool(1)).investorCount).to.equal(3);
+    });
+
+    it("Should reject more than the investment's amount", async function () {
+      await expect(
+        invoicePool.transferInvestment(1, 0, investor3.address, ethers.parseEther("4001"))
+      ).to.be.revertedWith("Invalid amount");
+    });
+
+    it("Should reject an unknown investment", async function () {
+      await expect(
+        invoicePool.transferInvestment(1, 2, investor3.address, ethers.parseEther("1000"))
+      ).to.be.revertedWith("Investment does not exist");
+    });
+
+    it("Should not transfer after the pool is closed", async function () {
+      await invoicePool.recordRepayment(1, ethers.parseEther("10000"), [ethers.parseEther("4900"), ethers.parseEther("4900")]);
+      await expect(
+        invoicePool.transferInvestment(1, 0, investor3.address, ethers.parseEther("1000"))
+      ).to.be.revertedWith("Pool not active");
+    });
+
+    it("Should not allow non-operator to transfer", async function () {
+      await expect(
+        invoicePool.connect(investor1).transferInvestment(1, 0, investor3.address, ethers.parseEther("1000"))
+      ).to.be.reverted;
+    });
+  });
+
   describe("Admin Functions", function () {
     it("Should update platform fee", async function () {
       await invoicePool.setPlatformFee(300); // 3%